@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+// apiClient performs authenticated JSON requests against the API
+type apiClient struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+// newAPIClient creates a new apiClient instance
+func newAPIClient(baseURL, apiKey string, timeout time.Duration) *apiClient {
+	return &apiClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		http:    &http.Client{Timeout: timeout},
+	}
+}
+
+// do sends a request and decodes a successful JSON response into out.
+// Error responses are decoded into models.ErrorResponse and returned as errors.
+func (c *apiClient) do(method, path string, body json.RawMessage, out interface{}) error {
+	var reqBody io.Reader
+	contentType := ""
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+		contentType = "application/json"
+	}
+
+	resp, err := c.send(method, path, reqBody, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(content, out); err != nil {
+		return fmt.Errorf("error decoding response: %w", err)
+	}
+	return nil
+}
+
+// send performs a request and returns the response of a successful one,
+// leaving the caller to read and close its body. Error responses are decoded
+// into models.ErrorResponse and returned as errors.
+func (c *apiClient) send(method, path string, body io.Reader, contentType string) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	if resp.StatusCode < http.StatusBadRequest {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+	var errResp models.ErrorResponse
+	if err := json.Unmarshal(content, &errResp); err == nil && errResp.Code != "" {
+		return nil, fmt.Errorf("%s (HTTP %d)", errResp.Error(), resp.StatusCode)
+	}
+	return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(content)))
+}
@@ -0,0 +1,342 @@
+// Package main provides oolioctl, a command-line client for operating the Oolio Food Ordering API
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/data"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/orders"
+	"github.com/ravibandhu/oolio-food-ordering/internal/schema"
+)
+
+const usage = `Usage: oolioctl [flags] <command> [args]
+
+Commands:
+  products list               List all products
+  products get <id>           Show a single product
+  products create <file>      Create a product from a JSON file ("-" reads stdin)
+  coupons check <code>        Check whether a coupon code is valid
+  orders list [flags]         List orders a page at a time (-sort, -limit, -cursor)
+  orders get <id>             Show an order and every event recorded for it
+  reload                      Reload products and coupons on the server
+  backup <file>               Save the catalog and coupons to an archive ("-" writes stdout)
+  restore <file>              Replace the catalog and coupons from an archive ("-" reads stdin)
+  validate-data <file> [dir]  Check a products file, and a coupon directory, before deploying them
+  schema <name>               Print the JSON Schema of a product, coupon, products-file or coupon-manifest
+
+Flags:
+`
+
+func main() {
+	// Parse global flags
+	server := flag.String("server", envOr("OOLIO_SERVER", "http://localhost:8080"), "API base URL (env OOLIO_SERVER)")
+	apiKey := flag.String("api-key", os.Getenv("OOLIO_API_KEY"), "API key sent as X-API-Key (env OOLIO_API_KEY)")
+	output := flag.String("output", "table", "Output format: table or json")
+	timeout := flag.Duration("timeout", 10*time.Second, "Request timeout")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *output != "table" && *output != "json" {
+		fatalf("invalid output format %q: must be table or json", *output)
+	}
+
+	// Create API client and printer
+	client := newAPIClient(*server, *apiKey, *timeout)
+	out := &printer{w: os.Stdout, json: *output == "json"}
+
+	if err := run(client, out, flag.Args()); err != nil {
+		fatalf("%v", err)
+	}
+}
+
+// run dispatches a command line to the matching subcommand
+func run(client *apiClient, out *printer, args []string) error {
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "products":
+		return runProducts(client, out, args[1:])
+	case "coupons":
+		return runCoupons(client, out, args[1:])
+	case "orders":
+		return runOrders(client, out, args[1:])
+	case "reload":
+		var resp map[string]string
+		if err := client.do("POST", "/admin/reload", nil, &resp); err != nil {
+			return err
+		}
+		return out.status(resp)
+	case "backup":
+		if len(args) != 2 {
+			return fmt.Errorf("backup: expected an archive file path")
+		}
+		return runBackup(client, args[1])
+	case "restore":
+		if len(args) != 2 {
+			return fmt.Errorf("restore: expected an archive file path")
+		}
+		return runRestore(client, out, args[1])
+	case "validate-data":
+		if len(args) < 2 || len(args) > 3 {
+			return fmt.Errorf("validate-data: expected a products file path and an optional coupon directory")
+		}
+		return runValidateData(out, args[1], args[2:])
+	case "schema":
+		if len(args) != 2 {
+			return fmt.Errorf("schema: expected one of %s", strings.Join(schema.Names, ", "))
+		}
+		s, ok := schema.Named(args[1])
+		if !ok {
+			return fmt.Errorf("schema: unknown schema %q, expected one of %s", args[1], strings.Join(schema.Names, ", "))
+		}
+		return out.encode(s)
+	default:
+		return fmt.Errorf("unknown command %q (run with -h for usage)", args[0])
+	}
+}
+
+// runProducts handles the products subcommands
+func runProducts(client *apiClient, out *printer, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("products: missing subcommand (list, get, create)")
+	}
+
+	switch args[0] {
+	case "list":
+		var products []models.Product
+		if err := client.do("GET", "/products", nil, &products); err != nil {
+			return err
+		}
+		return out.products(products)
+	case "get":
+		if len(args) != 2 {
+			return fmt.Errorf("products get: expected exactly one product ID")
+		}
+		var product models.Product
+		if err := client.do("GET", "/products/"+args[1], nil, &product); err != nil {
+			return err
+		}
+		return out.products([]models.Product{product})
+	case "create":
+		if len(args) != 2 {
+			return fmt.Errorf("products create: expected a JSON file path")
+		}
+		body, err := readInput(args[1])
+		if err != nil {
+			return err
+		}
+		var product models.Product
+		if err := client.do("POST", "/products", body, &product); err != nil {
+			return err
+		}
+		return out.products([]models.Product{product})
+	default:
+		return fmt.Errorf("products: unknown subcommand %q", args[0])
+	}
+}
+
+// runCoupons handles the coupons subcommands
+func runCoupons(client *apiClient, out *printer, args []string) error {
+	if len(args) != 2 || args[0] != "check" {
+		return fmt.Errorf("coupons: usage is 'coupons check <code>'")
+	}
+
+	var result couponCheck
+	if err := client.do("GET", "/admin/coupons/"+args[1], nil, &result); err != nil {
+		return err
+	}
+	return out.coupon(result)
+}
+
+// runOrders handles the orders subcommands
+func runOrders(client *apiClient, out *printer, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("orders: missing subcommand (list, get)")
+	}
+
+	switch args[0] {
+	case "list":
+		flags := flag.NewFlagSet("orders list", flag.ContinueOnError)
+		sortBy := flags.String("sort", "", "Fields to sort by, e.g. -created_at (default oldest first)")
+		limit := flags.Int("limit", 0, "Most orders to list (default 50)")
+		cursor := flags.String("cursor", "", "Next cursor of the previous page")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() != 0 {
+			return fmt.Errorf("orders list: unexpected arguments %s", strings.Join(flags.Args(), " "))
+		}
+
+		query := url.Values{}
+		if *sortBy != "" {
+			query.Set("sort", *sortBy)
+		}
+		if *limit > 0 {
+			query.Set("limit", strconv.Itoa(*limit))
+		}
+		if *cursor != "" {
+			query.Set("cursor", *cursor)
+		}
+		path := "/admin/orders"
+		if len(query) > 0 {
+			path += "?" + query.Encode()
+		}
+		var page orderList
+		if err := client.do("GET", path, nil, &page); err != nil {
+			return err
+		}
+		return out.orders(page)
+	case "get":
+		if len(args) != 2 {
+			return fmt.Errorf("orders get: expected exactly one order ID")
+		}
+		var history orders.History
+		if err := client.do("GET", "/admin/orders/"+url.PathEscape(args[1]), nil, &history); err != nil {
+			return err
+		}
+		return out.order(history)
+	default:
+		return fmt.Errorf("orders: unknown subcommand %q", args[0])
+	}
+}
+
+// runBackup downloads a backup archive to path, or to stdout when path is "-".
+// A file is only created once the server has accepted the request.
+func runBackup(client *apiClient, path string) error {
+	resp, err := client.send("GET", "/admin/backup", nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if path == "-" {
+		_, err := io.Copy(os.Stdout, resp.Body)
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating file: %w", err)
+	}
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		file.Close()
+		os.Remove(path)
+		return fmt.Errorf("error downloading backup: %w", err)
+	}
+	return file.Close()
+}
+
+// runRestore uploads the backup archive at path, or from stdin when path is "-"
+func runRestore(client *apiClient, out *printer, path string) error {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("error opening file: %w", err)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	resp, err := client.send("POST", "/admin/restore", r, "application/gzip")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var manifest data.Manifest
+	if err := json.NewDecoder(resp.Body).Decode(&manifest); err != nil {
+		return fmt.Errorf("error decoding response: %w", err)
+	}
+	return out.manifest(manifest)
+}
+
+// dataCheck is what validate-data found in the data files it was given
+type dataCheck struct {
+	Products    *data.ProductsCheck `json:"products"`
+	CouponFiles []data.CouponFile   `json:"couponFiles,omitempty"`
+}
+
+// runValidateData checks the products file at path, and the coupon
+// directory if one is given, as the server would load them. It runs without
+// a server, so files can be checked before they are deployed, and fails
+// when any of them would not load in full.
+func runValidateData(out *printer, path string, couponDir []string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening file: %w", err)
+	}
+	defer file.Close()
+
+	var check dataCheck
+	if check.Products, err = data.CheckProducts(file); err != nil {
+		return err
+	}
+	valid := check.Products.Valid
+	if len(couponDir) > 0 {
+		if check.CouponFiles, err = data.CheckCoupons(couponDir[0]); err != nil {
+			return err
+		}
+		for _, f := range check.CouponFiles {
+			valid = valid && f.Error == ""
+		}
+	}
+
+	if err := out.dataCheck(check); err != nil {
+		return err
+	}
+	if !valid {
+		return fmt.Errorf("validate-data: data files are invalid")
+	}
+	return nil
+}
+
+// readInput reads a JSON document from a file, or from stdin when path is "-"
+func readInput(path string) (json.RawMessage, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("error opening file: %w", err)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading input: %w", err)
+	}
+	if !json.Valid(content) {
+		return nil, fmt.Errorf("input is not valid JSON")
+	}
+	return content, nil
+}
+
+// envOr returns the value of an environment variable or a fallback
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// fatalf prints an error and exits
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "oolioctl: "+format+"\n", args...)
+	os.Exit(1)
+}
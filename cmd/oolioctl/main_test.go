@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/orders"
+	"github.com/ravibandhu/oolio-food-ordering/internal/testutil/testserver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunOrders(t *testing.T) {
+	srv := testserver.New(t)
+	client := newAPIClient(srv.URL, testserver.APIKey, 10*time.Second)
+
+	var placed []*models.Order
+	for i := 0; i < 3; i++ {
+		order, resp := srv.PlaceOrder(&models.OrderRequest{
+			Items: []models.OrderItem{{ProductID: "prod-1", Quantity: i + 1}},
+		})
+		require.NotNil(t, order, "body: %s", resp.Body)
+		placed = append(placed, order)
+	}
+
+	// Orders are listed a page at a time, with how to get the next
+	var out bytes.Buffer
+	require.NoError(t, run(client, &printer{w: &out}, []string{"orders", "list", "-limit", "2"}))
+	assert.Contains(t, out.String(), "ID")
+	assert.Contains(t, out.String(), placed[0].ID)
+	assert.Contains(t, out.String(), placed[1].ID)
+	assert.NotContains(t, out.String(), placed[2].ID)
+	assert.Contains(t, out.String(), "More orders follow: orders list -cursor ")
+
+	out.Reset()
+	require.NoError(t, run(client, &printer{w: &out, json: true}, []string{"orders", "list", "-sort", "-created_at"}))
+	var page orderList
+	require.NoError(t, json.Unmarshal(out.Bytes(), &page))
+	require.Len(t, page.Orders, 3)
+	assert.Equal(t, placed[2].ID, page.Orders[0].ID)
+	assert.Empty(t, page.Next)
+
+	// An order is shown with its events
+	out.Reset()
+	require.NoError(t, run(client, &printer{w: &out}, []string{"orders", "get", placed[0].ID}))
+	assert.Contains(t, out.String(), placed[0].ID)
+	assert.Contains(t, out.String(), "placed")
+
+	out.Reset()
+	require.NoError(t, run(client, &printer{w: &out, json: true}, []string{"orders", "get", placed[0].ID}))
+	var history orders.History
+	require.NoError(t, json.Unmarshal(out.Bytes(), &history))
+	assert.Equal(t, placed[0].ID, history.Order.ID)
+	assert.Equal(t, orders.EventPlaced, history.Events[0].Type)
+
+	// Server errors are reported
+	err := run(client, &printer{w: &out}, []string{"orders", "get", "missing"})
+	assert.ErrorContains(t, err, "HTTP 404")
+	err = run(newAPIClient(srv.URL, "", 10*time.Second), &printer{w: &out}, []string{"orders", "list"})
+	assert.ErrorContains(t, err, "HTTP 401")
+
+	// And so are bad command lines
+	assert.Error(t, run(client, &printer{w: &out}, []string{"orders"}))
+	assert.Error(t, run(client, &printer{w: &out}, []string{"orders", "get"}))
+	assert.Error(t, run(client, &printer{w: &out}, []string{"orders", "list", "extra"}))
+	assert.Error(t, run(client, &printer{w: &out}, []string{"orders", "cancel", placed[0].ID}))
+}
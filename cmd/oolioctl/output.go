@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/data"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/orders"
+)
+
+// couponCheck mirrors the response of GET /admin/coupons/{code}
+type couponCheck struct {
+	Code  string `json:"code"`
+	Valid bool   `json:"valid"`
+}
+
+// orderList mirrors the response of GET /admin/orders
+type orderList struct {
+	Orders []*models.Order `json:"orders"`
+	Next   string          `json:"next,omitempty"`
+}
+
+// printer renders command results as a table or as JSON
+type printer struct {
+	w    io.Writer
+	json bool
+}
+
+// products prints a list of products sorted by ID
+func (p *printer) products(products []models.Product) error {
+	if p.json {
+		return p.encode(products)
+	}
+
+	sort.Slice(products, func(i, j int) bool { return products[i].ID < products[j].ID })
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tCATEGORY\tPRICE")
+	for _, product := range products {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%.2f\n", product.ID, product.Name, product.Category, product.Price)
+	}
+	return tw.Flush()
+}
+
+// orders prints a page of orders in the order the server listed them,
+// followed by how to get the next page when more orders follow
+func (p *printer) orders(page orderList) error {
+	if p.json {
+		return p.encode(page)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tPLACED\tSTATUS\tITEMS\tTOTAL")
+	for _, order := range page.Orders {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%.2f\n", order.ID, order.CreatedAt.Format(time.RFC3339), order.Status, len(order.Items), order.TotalAmount)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if page.Next != "" {
+		_, err := fmt.Fprintf(p.w, "\nMore orders follow: orders list -cursor %s\n", page.Next)
+		return err
+	}
+	return nil
+}
+
+// order prints an order as its events leave it, then each event
+func (p *printer) order(history orders.History) error {
+	if p.json {
+		return p.encode(history)
+	}
+
+	order := history.Order
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "id\t%s\n", order.ID)
+	fmt.Fprintf(tw, "placed\t%s\n", order.CreatedAt.Format(time.RFC3339))
+	fmt.Fprintf(tw, "status\t%s\n", order.Status)
+	fmt.Fprintf(tw, "total\t%.2f\n", order.TotalAmount)
+	for _, item := range order.Items {
+		fmt.Fprintf(tw, "item\t%d x %s\n", item.Quantity, item.ProductID)
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "SEQ\tAT\tEVENT")
+	for _, event := range history.Events {
+		what := event.Type
+		if event.Change != nil {
+			what += " " + event.Change.Status + " by " + event.Change.Actor
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", event.Seq, event.At.Format(time.RFC3339), what)
+	}
+	return tw.Flush()
+}
+
+// coupon prints the result of a coupon check
+func (p *printer) coupon(result couponCheck) error {
+	if p.json {
+		return p.encode(result)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CODE\tVALID")
+	fmt.Fprintf(tw, "%s\t%t\n", result.Code, result.Valid)
+	return tw.Flush()
+}
+
+// manifest prints what a restore replaced
+func (p *printer) manifest(m data.Manifest) error {
+	if p.json {
+		return p.encode(m)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "backed up\t%s\n", m.CreatedAt.Format(time.RFC3339))
+	fmt.Fprintf(tw, "products\t%d\n", m.Products)
+	fmt.Fprintf(tw, "coupon files\t%s\n", strings.Join(m.CouponFiles, ", "))
+	return tw.Flush()
+}
+
+// dataCheck prints what validate-data found, listing each invalid product
+// and coupon file
+func (p *printer) dataCheck(check dataCheck) error {
+	if p.json {
+		return p.encode(check)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "schema version\t%d\n", check.Products.SchemaVersion)
+	fmt.Fprintf(tw, "products\t%d valid, %d invalid\n", check.Products.Products, len(check.Products.Errors))
+	for _, invalid := range check.Products.Errors {
+		fmt.Fprintf(tw, "  #%d %s\t%s\n", invalid.Index, invalid.ID, strings.ReplaceAll(invalid.Error, "\n", "; "))
+	}
+	for _, file := range check.CouponFiles {
+		result := "ok"
+		if file.Error != "" {
+			result = file.Error
+		} else if file.Verified {
+			result = "verified"
+		}
+		fmt.Fprintf(tw, "coupon file %s\t%d lines, %s\n", file.Name, file.Lines, result)
+	}
+	return tw.Flush()
+}
+
+// status prints a simple key/value status response
+func (p *printer) status(resp map[string]string) error {
+	if p.json {
+		return p.encode(resp)
+	}
+
+	keys := make([]string, 0, len(resp))
+	for key := range resp {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	for _, key := range keys {
+		fmt.Fprintf(tw, "%s\t%s\n", key, resp[key])
+	}
+	return tw.Flush()
+}
+
+// encode writes v as indented JSON
+func (p *printer) encode(v interface{}) error {
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
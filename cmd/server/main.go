@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -13,9 +14,20 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apikeys"
+	"github.com/ravibandhu/oolio-food-ordering/internal/auth"
+	"github.com/ravibandhu/oolio-food-ordering/internal/blocklist"
+	"github.com/ravibandhu/oolio-food-ordering/internal/catalogsync"
 	"github.com/ravibandhu/oolio-food-ordering/internal/config"
 	"github.com/ravibandhu/oolio-food-ordering/internal/data"
+	"github.com/ravibandhu/oolio-food-ordering/internal/deprecation"
+	"github.com/ravibandhu/oolio-food-ordering/internal/logging"
+	"github.com/ravibandhu/oolio-food-ordering/internal/notify"
 	"github.com/ravibandhu/oolio-food-ordering/internal/router"
+	"github.com/ravibandhu/oolio-food-ordering/internal/startup"
+	"github.com/ravibandhu/oolio-food-ordering/internal/tenant"
+	"github.com/ravibandhu/oolio-food-ordering/internal/usage"
 )
 
 // @title Oolio Food Ordering API
@@ -31,6 +43,10 @@ import (
 // @securityDefinitions.apiKey ApiKeyAuth
 // @in header
 // @name X-API-Key
+// @securityDefinitions.apiKey BearerAuth
+// @in header
+// @name Authorization
+// @description Customer session token, as "Bearer <token>"
 func main() {
 	// Create root context with cancellation
 	ctx, cancel := context.WithCancel(context.Background())
@@ -47,6 +63,21 @@ func main() {
 	}
 	log.Print("Configuration loaded successfully")
 
+	// Write logs, including access logs, to the configured file, rotated as
+	// it grows, and sample the lines busy subsystems log for every event
+	logFile, err := logging.Open(cfg.Logging)
+	if err != nil {
+		log.Fatalf("Failed to open log file: %v", err)
+	}
+	if logFile != nil {
+		log.SetOutput(logFile)
+		gin.DefaultWriter = logFile
+		gin.DefaultErrorWriter = logFile
+		defer logFile.Close()
+		log.Printf("Logging to %s", cfg.Logging.File)
+	}
+	logging.SetSampleLimit(cfg.Logging.SampleLimit)
+
 	// Create data store with context
 	store, err := data.NewStore(ctx, cfg)
 	if err != nil {
@@ -55,8 +86,86 @@ func main() {
 	log.Print("Store created successfully")
 	defer store.Close()
 
+	// Apply product updates pushed by the upstream catalog service
+	catalogSub, err := catalogsync.NewSubscriber(cfg.Catalog)
+	if err != nil {
+		log.Fatalf("Failed to configure catalog sync: %v", err)
+	}
+	if catalogSub != nil {
+		go catalogsync.NewConsumer(store, catalogSub).Run(ctx)
+		log.Printf("Consuming catalog updates published on %s", cfg.Catalog.Subject)
+	}
+
+	// Load additional tenants
+	tenants, err := tenant.NewRegistry(ctx, cfg, store)
+	if err != nil {
+		log.Fatalf("Failed to load tenants: %v", err)
+	}
+	log.Printf("Loaded %d additional tenant(s)", len(cfg.Tenants))
+	tenants.RunDashboards(ctx, cfg.Orders.ProjectionInterval)
+	if cfg.Orders.Retention > 0 {
+		tenants.ArchiveOrders(ctx, cfg.Orders.Retention, time.Hour, cfg.Orders.ArchiveDir)
+		log.Printf("Archiving orders created more than %s ago", cfg.Orders.Retention)
+	}
+
+	// Load blocked callers
+	blocked, err := blocklist.Load(cfg.Auth.BlocklistFile)
+	if err != nil {
+		log.Fatalf("Failed to load blocklist: %v", err)
+	}
+	log.Printf("Loaded %d blocklist entries", len(blocked.Entries()))
+
+	// Load customer accounts
+	accounts, err := auth.Load(cfg.Auth, notify.NewSender(cfg.Email))
+	if err != nil {
+		log.Fatalf("Failed to load customer accounts: %v", err)
+	}
+	if cfg.Auth.JWTSecret == "" {
+		log.Print("JWT_SECRET is not set; customer sessions end when the server restarts")
+	}
+	if cfg.Email.SMTPAddr == "" {
+		log.Print("SMTP_ADDR is not set; emails to customers are written to the log")
+	}
+
+	// Load API keys, importing the configured ones
+	keys, err := apikeys.Load(cfg.Auth)
+	if err != nil {
+		log.Fatalf("Failed to load API keys: %v", err)
+	}
+	log.Printf("Loaded %d API keys", len(keys.List()))
+
+	// Load API key usage, flushed to disk in the background
+	tracker, err := usage.Load(cfg.Usage.File)
+	if err != nil {
+		log.Fatalf("Failed to load API key usage: %v", err)
+	}
+	go tracker.Run(ctx, cfg.Usage.FlushInterval)
+
+	// Load the deprecation notices announced to clients
+	notices, err := deprecation.Load(cfg.Server.DeprecationsFile)
+	if err != nil {
+		log.Fatalf("Failed to load deprecation notices: %v", err)
+	}
+
+	// Check what was loaded, that the port can be bound and that every
+	// dependency answers, and log it all as one line
+	report := startup.New(cfg, tenants, time.Now())
+	var listener net.Listener
+	report.Run("bind", func() (string, error) {
+		listener, err = net.Listen("tcp", cfg.Server.Port)
+		if err != nil {
+			return "", err
+		}
+		return listener.Addr().String(), nil
+	})
+	report.PingDependencies(ctx, cfg)
+	log.Printf("Startup report: %s", report)
+	if listener == nil {
+		log.Fatalf("Failed to bind %s", cfg.Server.Port)
+	}
+
 	// Create router with context
-	r := router.NewRouter(ctx, store)
+	r := router.NewRouter(ctx, cfg, tenants, blocked, accounts, keys, tracker, notices, report)
 	log.Print("Router created successfully")
 
 	// Create HTTP server
@@ -74,7 +183,7 @@ func main() {
 	// Start server in a goroutine
 	go func() {
 		log.Printf("Starting server on port %s", cfg.Server.Port)
-		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			serverErrors <- fmt.Errorf("server error: %w", err)
 		}
 	}()
@@ -98,12 +207,10 @@ func main() {
 	// Initiate graceful shutdown
 	log.Print("Initiating graceful shutdown...")
 
-	// First, shut down the router (and store)
-	if err := r.Shutdown(shutdownCtx); err != nil {
-		log.Printf("Router shutdown error: %v", err)
-	}
+	// First, refuse new writes such as orders, while reads are still served
+	r.Drain()
 
-	// Then, shut down the HTTP server
+	// Then, stop accepting connections and let in-flight requests finish
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		log.Printf("Server shutdown error: %v", err)
 		// If we get here, we exceeded shutdown timeout
@@ -112,8 +219,11 @@ func main() {
 		}
 	}
 
-	// Wait for any in-flight requests to complete
-	<-shutdownCtx.Done()
+	// Finally, shut down the router (and store) once no request can use it
+	if err := r.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Router shutdown error: %v", err)
+	}
+
 	if errors.Is(shutdownCtx.Err(), context.DeadlineExceeded) {
 		log.Print("Shutdown timed out")
 	} else {
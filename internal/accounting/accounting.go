@@ -0,0 +1,258 @@
+// Package accounting exports orders as sales invoices in the CSV import
+// formats of accounting software, so takings can be booked without keying
+// them in.
+package accounting
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+// Export formats
+const (
+	FormatXero       = "xero"       // Xero sales invoice import
+	FormatQuickBooks = "quickbooks" // QuickBooks Online invoice import
+)
+
+// Formats lists the formats orders can be exported in
+var Formats = []string{FormatXero, FormatQuickBooks}
+
+// DefaultContact is who invoices of orders not billed to a business are to
+const DefaultContact = "Online customer"
+
+// ErrUnknownFormat is returned when asked for a format not in Formats
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Line is a line of an invoice, before tax
+type Line struct {
+	Description string
+	Quantity    int
+	UnitAmount  float64
+	Amount      float64
+	Account     string
+	TaxType     string
+	TaxAmount   float64
+}
+
+// Invoice is an order as accounting software books it
+type Invoice struct {
+	Number    string // The order's invoice number, or its ID when it has none
+	Reference string // The order ID
+	Contact   string
+	Date      time.Time
+	Total     float64
+	Lines     []Line
+}
+
+// NewInvoice books an order under the accounts cfg maps it to. Items are
+// booked by product category; promotions and the coupon discount to the
+// sales account; delivery and service fees, which are not taxed, to their
+// own accounts. The order's tax is shared among the taxed lines to the cent.
+func NewInvoice(order *models.Order, cfg config.Accounting) Invoice {
+	inv := Invoice{
+		Number:    order.InvoiceNumber,
+		Reference: order.ID,
+		Contact:   DefaultContact,
+		Date:      order.CreatedAt.UTC(),
+		Total:     roundCents(order.TotalAmount),
+	}
+	if inv.Number == "" {
+		inv.Number = order.ID
+	}
+	if order.Billing != nil {
+		inv.Contact = order.Billing.CompanyName
+	}
+
+	taxType := cfg.TaxType
+	if order.TaxAmount == 0 {
+		taxType = cfg.ExemptTaxType
+	}
+	products := make(map[string]models.Product, len(order.Products))
+	for _, product := range order.Products {
+		products[product.ID] = product
+	}
+	for _, item := range order.Items {
+		product := products[item.ProductID]
+		inv.Lines = append(inv.Lines, newLine(itemDescription(product, item), item.Quantity, item.Price, categoryAccount(cfg, product.Category), taxType))
+	}
+	for _, adjustment := range order.Adjustments {
+		inv.Lines = append(inv.Lines, newLine("Promotion: "+adjustment.Name, 1, adjustment.Amount, cfg.SalesAccount, taxType))
+	}
+	if order.Discount != 0 {
+		inv.Lines = append(inv.Lines, newLine("Coupon: "+order.CouponCode, 1, -order.Discount, cfg.SalesAccount, taxType))
+	}
+	shareTax(inv.Lines, order.TaxAmount)
+
+	if order.DeliveryFee != 0 {
+		description := "Delivery"
+		if order.DeliveryZone != "" {
+			description += ": " + order.DeliveryZone
+		}
+		inv.Lines = append(inv.Lines, newLine(description, 1, order.DeliveryFee, orDefault(cfg.DeliveryAccount, cfg.SalesAccount), cfg.ExemptTaxType))
+	}
+	if order.ServiceFee != 0 {
+		inv.Lines = append(inv.Lines, newLine("Service fee", 1, order.ServiceFee, orDefault(cfg.FeesAccount, cfg.SalesAccount), cfg.ExemptTaxType))
+	}
+	return inv
+}
+
+// Write writes the invoices of orders to w as CSV in format, one row per
+// invoice line
+func Write(w io.Writer, format string, orders []*models.Order, cfg config.Accounting) error {
+	var header []string
+	var row func(inv Invoice, line Line, date string) []string
+	switch format {
+	case FormatXero:
+		header, row = xeroHeader, xeroRow
+	case FormatQuickBooks:
+		header, row = quickBooksHeader, quickBooksRow
+	default:
+		return ErrUnknownFormat
+	}
+
+	out := csv.NewWriter(w)
+	if err := out.Write(header); err != nil {
+		return err
+	}
+	for _, order := range orders {
+		inv := NewInvoice(order, cfg)
+		date := inv.Date.Format(cfg.DateFormat)
+		for _, line := range inv.Lines {
+			if err := out.Write(row(inv, line, date)); err != nil {
+				return err
+			}
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// xeroHeader is the header of Xero's sales invoice import template
+var xeroHeader = []string{
+	"*ContactName", "EmailAddress", "POAddressLine1", "POAddressLine2", "POAddressLine3", "POAddressLine4",
+	"POCity", "PORegion", "POPostalCode", "POCountry", "*InvoiceNumber", "Reference", "*InvoiceDate",
+	"*DueDate", "Total", "InventoryItemCode", "*Description", "*Quantity", "*UnitAmount", "Discount",
+	"*AccountCode", "*TaxType", "TaxAmount", "TrackingName1", "TrackingOption1", "TrackingName2",
+	"TrackingOption2", "Currency", "BrandingTheme",
+}
+
+func xeroRow(inv Invoice, line Line, date string) []string {
+	row := make([]string, len(xeroHeader))
+	row[0] = text(inv.Contact)
+	row[10] = text(inv.Number)
+	row[11] = text(inv.Reference)
+	row[12], row[13] = date, date
+	row[14] = amount(inv.Total)
+	row[16] = text(line.Description)
+	row[17] = strconv.Itoa(line.Quantity)
+	row[18] = amount(line.UnitAmount)
+	row[20] = text(line.Account)
+	row[21] = text(line.TaxType)
+	row[22] = amount(line.TaxAmount)
+	return row
+}
+
+// quickBooksHeader is the header of the QuickBooks Online invoice import
+var quickBooksHeader = []string{
+	"InvoiceNo", "Customer", "InvoiceDate", "DueDate", "Memo", "Item(Product/Service)", "ItemDescription",
+	"ItemQuantity", "ItemRate", "ItemAmount", "ItemTaxCode", "ItemTaxAmount",
+}
+
+func quickBooksRow(inv Invoice, line Line, date string) []string {
+	return []string{
+		text(inv.Number), text(inv.Contact), date, date, text(inv.Reference), text(line.Account), text(line.Description),
+		strconv.Itoa(line.Quantity), amount(line.UnitAmount), amount(line.Amount), text(line.TaxType), amount(line.TaxAmount),
+	}
+}
+
+// newLine returns a line of quantity at unitAmount each
+func newLine(description string, quantity int, unitAmount float64, account, taxType string) Line {
+	return Line{
+		Description: description,
+		Quantity:    quantity,
+		UnitAmount:  roundCents(unitAmount),
+		Amount:      roundCents(unitAmount * float64(quantity)),
+		Account:     account,
+		TaxType:     taxType,
+	}
+}
+
+// shareTax shares tax among lines in proportion to their amounts, giving
+// the cents lost to rounding to the largest line
+func shareTax(lines []Line, tax float64) {
+	var base float64
+	largest := -1
+	for i, line := range lines {
+		base += line.Amount
+		if largest < 0 || line.Amount > lines[largest].Amount {
+			largest = i
+		}
+	}
+	if tax == 0 || base <= 0 {
+		return
+	}
+
+	left := roundCents(tax)
+	for i := range lines {
+		lines[i].TaxAmount = roundCents(tax * lines[i].Amount / base)
+		left -= lines[i].TaxAmount
+	}
+	lines[largest].TaxAmount = roundCents(lines[largest].TaxAmount + left)
+}
+
+// itemDescription names an item's product and variant
+func itemDescription(product models.Product, item models.OrderItem) string {
+	description := product.Name
+	if description == "" {
+		description = item.ProductID
+	}
+	if item.VariantID != "" {
+		description += " (" + item.VariantID + ")"
+	}
+	return description
+}
+
+// categoryAccount returns the account cfg maps a product category to,
+// ignoring case, or the sales account
+func categoryAccount(cfg config.Accounting, category string) string {
+	for name, account := range cfg.Categories {
+		if strings.EqualFold(name, category) {
+			return account
+		}
+	}
+	return cfg.SalesAccount
+}
+
+// orDefault returns value, or fallback when value is empty
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// text guards a cell against being read as a formula by spreadsheets
+func text(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// amount formats an amount to the cent
+func amount(value float64) string {
+	return strconv.FormatFloat(roundCents(value), 'f', 2, 64)
+}
+
+// roundCents rounds an amount to whole cents
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
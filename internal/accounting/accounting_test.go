@@ -0,0 +1,142 @@
+package accounting
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testConfig = config.Accounting{
+	SalesAccount:    "200",
+	Categories:      map[string]string{"drinks": "210"},
+	DeliveryAccount: "220",
+	TaxType:         "GST on Income",
+	ExemptTaxType:   "GST Free Income",
+	DateFormat:      "02/01/2006",
+}
+
+func testOrder() *models.Order {
+	return &models.Order{
+		ID:            "order-1",
+		InvoiceNumber: "INV-000042",
+		Items: []models.OrderItem{
+			{ProductID: "waffle", Quantity: 2, Price: 10},
+			{ProductID: "coffee", VariantID: "large", Quantity: 1, Price: 5},
+		},
+		Products: []models.Product{
+			{ID: "waffle", Name: "Waffle", Category: "Waffle"},
+			{ID: "coffee", Name: "Coffee", Category: "Drinks"},
+		},
+		Adjustments:  []models.PriceAdjustment{{Name: "happy-hour", Percent: -20, Amount: -5}},
+		CouponCode:   "SAVE10",
+		Discount:     2,
+		TaxAmount:    1.8,
+		ServiceFee:   1.5,
+		DeliveryFee:  4.5,
+		DeliveryZone: "cbd",
+		TotalAmount:  25.8,
+		CreatedAt:    time.Date(2024, 3, 1, 23, 30, 0, 0, time.FixedZone("AEDT", 11*60*60)),
+	}
+}
+
+func TestNewInvoice(t *testing.T) {
+	inv := NewInvoice(testOrder(), testConfig)
+
+	assert.Equal(t, "INV-000042", inv.Number)
+	assert.Equal(t, "order-1", inv.Reference)
+	assert.Equal(t, DefaultContact, inv.Contact)
+	assert.Equal(t, "2024-03-01", inv.Date.Format(time.DateOnly))
+	assert.Equal(t, []Line{
+		{Description: "Waffle", Quantity: 2, UnitAmount: 10, Amount: 20, Account: "200", TaxType: "GST on Income", TaxAmount: 2},
+		{Description: "Coffee (large)", Quantity: 1, UnitAmount: 5, Amount: 5, Account: "210", TaxType: "GST on Income", TaxAmount: 0.5},
+		{Description: "Promotion: happy-hour", Quantity: 1, UnitAmount: -5, Amount: -5, Account: "200", TaxType: "GST on Income", TaxAmount: -0.5},
+		{Description: "Coupon: SAVE10", Quantity: 1, UnitAmount: -2, Amount: -2, Account: "200", TaxType: "GST on Income", TaxAmount: -0.2},
+		{Description: "Delivery: cbd", Quantity: 1, UnitAmount: 4.5, Amount: 4.5, Account: "220", TaxType: "GST Free Income"},
+		{Description: "Service fee", Quantity: 1, UnitAmount: 1.5, Amount: 1.5, Account: "200", TaxType: "GST Free Income"},
+	}, inv.Lines)
+
+	// The lines add up to the order total
+	var total float64
+	for _, line := range inv.Lines {
+		total += line.Amount + line.TaxAmount
+	}
+	assert.InDelta(t, 25.8, total, 0.001)
+}
+
+func TestNewInvoice_TaxRounding(t *testing.T) {
+	order := &models.Order{
+		ID: "order-1",
+		Items: []models.OrderItem{
+			{ProductID: "a", Quantity: 1, Price: 1},
+			{ProductID: "b", Quantity: 1, Price: 1},
+			{ProductID: "c", Quantity: 1, Price: 1},
+		},
+		TaxAmount: 0.1,
+	}
+	inv := NewInvoice(order, testConfig)
+
+	var tax float64
+	for _, line := range inv.Lines {
+		tax += line.TaxAmount
+	}
+	assert.InDelta(t, 0.1, tax, 0.0001)
+	assert.Equal(t, "order-1", inv.Number)
+}
+
+func TestNewInvoice_Business(t *testing.T) {
+	order := testOrder()
+	order.Billing = &models.BusinessBilling{CompanyName: "Acme Catering", TaxExempt: true}
+	order.TaxAmount = 0
+	inv := NewInvoice(order, testConfig)
+
+	assert.Equal(t, "Acme Catering", inv.Contact)
+	for _, line := range inv.Lines {
+		assert.Equal(t, "GST Free Income", line.TaxType)
+		assert.Zero(t, line.TaxAmount)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	order := testOrder()
+	order.Products[0].Name = "=HYPERLINK(\"http://example.com\")"
+
+	tests := []struct {
+		format string
+		header []string
+		first  []string
+	}{
+		{
+			format: FormatXero,
+			header: xeroHeader,
+			first: []string{"Online customer", "", "", "", "", "", "", "", "", "", "INV-000042", "order-1", "01/03/2024", "01/03/2024", "25.80",
+				"", "'=HYPERLINK(\"http://example.com\")", "2", "10.00", "", "200", "GST on Income", "2.00", "", "", "", "", "", ""},
+		},
+		{
+			format: FormatQuickBooks,
+			header: quickBooksHeader,
+			first: []string{"INV-000042", "Online customer", "01/03/2024", "01/03/2024", "order-1", "200",
+				"'=HYPERLINK(\"http://example.com\")", "2", "10.00", "20.00", "GST on Income", "2.00"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, Write(&buf, tt.format, []*models.Order{order}, testConfig))
+
+			records, err := csv.NewReader(&buf).ReadAll()
+			require.NoError(t, err)
+			require.Len(t, records, 7)
+			assert.Equal(t, tt.header, records[0])
+			assert.Equal(t, tt.first, records[1])
+		})
+	}
+
+	assert.ErrorIs(t, Write(&bytes.Buffer{}, "sage", nil, testConfig), ErrUnknownFormat)
+}
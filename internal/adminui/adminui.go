@@ -0,0 +1,22 @@
+// Package adminui provides the embedded admin dashboard served at /admin
+package adminui
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed static
+var staticFiles embed.FS
+
+// Handler returns an http.Handler serving the dashboard. Requests for "/"
+// receive index.html and static assets are served from "/assets/".
+func Handler() http.Handler {
+	sub, err := fs.Sub(staticFiles, "static")
+	if err != nil {
+		// The embedded tree is fixed at compile time, so this cannot fail at runtime
+		panic(err)
+	}
+	return http.FileServer(http.FS(sub))
+}
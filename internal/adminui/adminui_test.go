@@ -0,0 +1,58 @@
+package adminui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	handler := Handler()
+
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		contains    string
+	}{
+		{
+			name:        "index",
+			path:        "/",
+			contentType: "text/html",
+			contains:    "Oolio Admin",
+		},
+		{
+			name:        "script",
+			path:        "/assets/app.js",
+			contentType: "javascript",
+			contains:    "/admin/orders",
+		},
+		{
+			name:        "stylesheet",
+			path:        "/assets/app.css",
+			contentType: "text/css",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Contains(t, rec.Header().Get("Content-Type"), tt.contentType)
+			assert.Contains(t, rec.Body.String(), tt.contains)
+		})
+	}
+
+	t.Run("missing asset", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/assets/missing.js", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
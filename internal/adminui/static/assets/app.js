@@ -0,0 +1,121 @@
+// Admin dashboard logic. Requests rely on the browser's cached Basic
+// credentials, which the /admin routes accept in place of X-API-Key. Writes
+// must also send X-Requested-With, which forged cross-site requests cannot.
+(function () {
+  "use strict";
+
+  const statusEl = document.getElementById("status");
+
+  function showStatus(message) {
+    statusEl.textContent = message;
+    statusEl.classList.add("visible");
+    setTimeout(() => statusEl.classList.remove("visible"), 3000);
+  }
+
+  async function api(method, path) {
+    const resp = await fetch(path, {
+      method: method,
+      headers: { Accept: "application/json", "X-Requested-With": "XMLHttpRequest" },
+      credentials: "same-origin",
+    });
+    const body = await resp.json().catch(() => null);
+    if (!resp.ok) {
+      const message = body && body.message ? body.message : resp.statusText;
+      throw new Error(message + " (HTTP " + resp.status + ")");
+    }
+    return body;
+  }
+
+  function cell(text, className) {
+    const td = document.createElement("td");
+    td.textContent = text;
+    if (className) {
+      td.className = className;
+    }
+    return td;
+  }
+
+  async function loadProducts() {
+    const products = await api("GET", "/products");
+    products.sort((a, b) => a.id.localeCompare(b.id, undefined, { numeric: true }));
+
+    const tbody = document.getElementById("products");
+    tbody.replaceChildren();
+    for (const product of products) {
+      const row = document.createElement("tr");
+      row.append(
+        cell(product.id),
+        cell(product.name),
+        cell(product.category),
+        cell(product.price.toFixed(2), "price"),
+      );
+      tbody.append(row);
+    }
+    document.getElementById("product-count").textContent = "(" + products.length + ")";
+  }
+
+  async function loadOrders() {
+    const page = await api("GET", "/admin/orders?sort=-created_at&limit=20");
+
+    const tbody = document.getElementById("orders");
+    tbody.replaceChildren();
+    for (const order of page.orders) {
+      const row = document.createElement("tr");
+      row.append(
+        cell(order.id),
+        cell(new Date(order.created_at).toLocaleString()),
+        cell(order.status || ""),
+        cell(order.total_amount.toFixed(2), "price"),
+      );
+      tbody.append(row);
+    }
+  }
+
+  async function loadCouponStats() {
+    const stats = await api("GET", "/admin/coupons/stats");
+
+    const list = document.getElementById("coupon-stats");
+    list.replaceChildren();
+    for (const [term, value] of [
+      ["Valid codes", stats.codes],
+      ["Lookups found", stats.hits],
+      ["Lookups missed", stats.misses],
+      ["Last loaded", new Date(stats.loadedAt).toLocaleString() + " in " + Math.round(stats.loadDurationMs) + " ms"],
+    ]) {
+      const dt = document.createElement("dt");
+      dt.textContent = term;
+      const dd = document.createElement("dd");
+      dd.textContent = value;
+      list.append(dt, dd);
+    }
+  }
+
+  document.getElementById("reload").addEventListener("click", async () => {
+    try {
+      await api("POST", "/admin/reload");
+      await Promise.all([loadProducts(), loadCouponStats()]);
+      showStatus("Stores reloaded");
+    } catch (err) {
+      showStatus("Reload failed: " + err.message);
+    }
+  });
+
+  document.getElementById("coupon-form").addEventListener("submit", async (event) => {
+    event.preventDefault();
+    const code = document.getElementById("coupon-code").value.trim();
+    const result = document.getElementById("coupon-result");
+    try {
+      const check = await api("GET", "/admin/coupons/" + encodeURIComponent(code));
+      result.textContent = check.code + (check.valid ? " is valid" : " is not valid");
+      result.className = check.valid ? "valid" : "invalid";
+      await loadCouponStats();
+    } catch (err) {
+      result.textContent = err.message;
+      result.className = "invalid";
+    }
+  });
+
+  loadProducts().catch((err) => showStatus("Failed to load products: " + err.message));
+  loadOrders().catch((err) => showStatus("Failed to load orders: " + err.message));
+  loadCouponStats().catch((err) => showStatus("Failed to load coupon stats: " + err.message));
+})();
@@ -0,0 +1,200 @@
+// Package apierrors is the catalog of error codes the API returns and the
+// HTTP status each is served with. Handlers build error responses here
+// rather than choosing a status per call site, so a code always comes with
+// the same status.
+package apierrors
+
+import (
+	"net/http"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+// Request errors
+const (
+	InvalidRequest       = "INVALID_REQUEST"        // Malformed body, path or query
+	ValidationError      = "VALIDATION_ERROR"       // Well-formed body that fails validation
+	Unauthorized         = "UNAUTHORIZED"           // Missing or invalid API key, or missing session token
+	InvalidToken         = "INVALID_TOKEN"          // Invalid or expired ID token or session token
+	Forbidden            = "FORBIDDEN"              // The caller is on the blocklist, or lacks the role the route requires
+	ChallengeFailed      = "CHALLENGE_FAILED"       // Missing or invalid bot challenge token
+	NotFound             = "NOT_FOUND"              // The addressed resource does not exist
+	TenantNotFound       = "TENANT_NOT_FOUND"       // X-Tenant-ID names no configured tenant
+	MethodNotAllowed     = "METHOD_NOT_ALLOWED"     // Known path, unsupported method
+	PayloadTooLarge      = "PAYLOAD_TOO_LARGE"      // JSON body over the configured limit
+	PreconditionFailed   = "PRECONDITION_FAILED"    // If-Match names an outdated version
+	UnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE" // Body is not application/json
+	Gone                 = "GONE"                   // The route was retired at its sunset date
+	RequestInProgress    = "REQUEST_IN_PROGRESS"    // A request with the same Idempotency-Key is still being handled
+	IdempotencyKeyReused = "IDEMPOTENCY_KEY_REUSED" // The Idempotency-Key was used for a different request
+)
+
+// Catalog errors
+const (
+	ProductExists = "PRODUCT_EXISTS" // A product with the ID already exists
+	InvalidImage  = "INVALID_IMAGE"  // Upload is not a decodable image
+	ImageTooLarge = "IMAGE_TOO_LARGE"
+	InvalidMenu   = "INVALID_MENU" // Imported menu does not decode or has no importable items
+)
+
+// Order errors
+const (
+	InvalidProduct        = "INVALID_PRODUCT" // An ordered product does not exist
+	InvalidVariant        = "INVALID_VARIANT" // Missing or unknown variant of an ordered product
+	InvalidCoupon         = "INVALID_COUPON"
+	ConflictingItems      = "CONFLICTING_ITEMS" // Duplicate lines for an item disagree
+	PriceChanged          = "PRICE_CHANGED"     // Client-supplied prices are out of date
+	InvalidTotal          = "INVALID_TOTAL"     // Order total is out of range
+	OrderTooLarge         = "ORDER_TOO_LARGE"   // Order is over one of the restaurant's limits
+	BelowMinimum          = "BELOW_MINIMUM"     // Subtotal is under the restaurant's minimum order
+	TooManyOrders         = "TOO_MANY_ORDERS"   // The client is over a velocity rule
+	OutOfStock            = "OUT_OF_STOCK"      // The POS has too few of an ordered item
+	NotOnMenu             = "NOT_ON_MENU"       // An ordered product is on no menu being served
+	StoreClosed           = "STORE_CLOSED"
+	DeliveryUnavailable   = "DELIVERY_UNAVAILABLE" // The restaurant does not deliver
+	AddressNotServiceable = "ADDRESS_NOT_SERVICEABLE"
+	DineInUnavailable     = "DINE_IN_UNAVAILABLE" // The restaurant takes no table orders
+	InvalidTable          = "INVALID_TABLE"       // The table token was not signed for the restaurant
+	InvalidTaxID          = "INVALID_TAX_ID"      // The billing tax ID is not in its country's format
+	InvalidPayment        = "INVALID_PAYMENT"     // Payment details missing or not suiting the method
+	PaymentDeclined       = "PAYMENT_DECLINED"    // The payment processor refused the payment
+)
+
+// Review errors
+const (
+	NotPurchased    = "NOT_PURCHASED" // The cited order did not include the product
+	AlreadyReviewed = "ALREADY_REVIEWED"
+)
+
+// Group cart errors
+const (
+	CartClosed = "CART_CLOSED" // The cart was checked out, or is being
+	CartFull   = "CART_FULL"   // The cart holds its maximum of lines
+	CartEmpty  = "CART_EMPTY"  // A cart without items cannot be checked out
+)
+
+// Delivery errors
+const (
+	NotDelivery           = "NOT_DELIVERY"            // The order is not delivered, so it has no courier
+	InvalidDeliveryStatus = "INVALID_DELIVERY_STATUS" // The delivery cannot move to the status from the one it is in
+)
+
+// Account errors
+const (
+	AccountExists      = "ACCOUNT_EXISTS"      // A customer already uses the email address
+	InvalidCredentials = "INVALID_CREDENTIALS" // Unknown email address or wrong password
+	AccountLocked      = "ACCOUNT_LOCKED"      // Too many wrong passwords; unlock by email
+)
+
+// Admin errors
+const (
+	InvalidBackup = "INVALID_BACKUP" // Restore archive is malformed or does not load
+	EntryExists   = "ENTRY_EXISTS"   // An identical blocklist entry already exists
+	KeyDisabled   = "KEY_DISABLED"   // A disabled API key cannot be rotated
+)
+
+// Server errors
+const (
+	InternalError        = "INTERNAL_ERROR"
+	OrderFailed          = "ORDER_FAILED"
+	StorageFailed        = "STORAGE_FAILED" // Image storage failed
+	ReloadFailed         = "RELOAD_FAILED"
+	RestoreFailed        = "RESTORE_FAILED"
+	ChallengeUnavailable = "CHALLENGE_UNAVAILABLE" // The challenge provider could not verify a token
+	ProviderUnavailable  = "PROVIDER_UNAVAILABLE"  // An identity provider's signing keys could not be fetched
+	PaymentUnavailable   = "PAYMENT_UNAVAILABLE"   // The payment processor could not be reached
+	Timeout              = "TIMEOUT"               // The request took longer than its route allows
+	ShuttingDown         = "SHUTTING_DOWN"         // The server is draining before it stops and takes no new writes
+	Maintenance          = "MAINTENANCE"           // The route's group is down for maintenance at the restaurant
+	KitchenBusy          = "KITCHEN_BUSY"          // The kitchen has its maximum of active orders
+)
+
+// statuses maps every code to the HTTP status it is returned with
+var statuses = map[string]int{
+	InvalidRequest:        http.StatusBadRequest,
+	ValidationError:       http.StatusUnprocessableEntity,
+	Unauthorized:          http.StatusUnauthorized,
+	InvalidToken:          http.StatusUnauthorized,
+	Forbidden:             http.StatusForbidden,
+	ChallengeFailed:       http.StatusForbidden,
+	NotFound:              http.StatusNotFound,
+	TenantNotFound:        http.StatusNotFound,
+	MethodNotAllowed:      http.StatusMethodNotAllowed,
+	PayloadTooLarge:       http.StatusRequestEntityTooLarge,
+	PreconditionFailed:    http.StatusPreconditionFailed,
+	UnsupportedMediaType:  http.StatusUnsupportedMediaType,
+	Gone:                  http.StatusGone,
+	RequestInProgress:     http.StatusConflict,
+	IdempotencyKeyReused:  http.StatusUnprocessableEntity,
+	ProductExists:         http.StatusConflict,
+	InvalidImage:          http.StatusUnprocessableEntity,
+	ImageTooLarge:         http.StatusRequestEntityTooLarge,
+	InvalidMenu:           http.StatusUnprocessableEntity,
+	InvalidProduct:        http.StatusNotFound,
+	InvalidVariant:        http.StatusUnprocessableEntity,
+	InvalidCoupon:         http.StatusBadRequest,
+	ConflictingItems:      http.StatusBadRequest,
+	PriceChanged:          http.StatusConflict,
+	InvalidTotal:          http.StatusUnprocessableEntity,
+	OrderTooLarge:         http.StatusUnprocessableEntity,
+	BelowMinimum:          http.StatusUnprocessableEntity,
+	TooManyOrders:         http.StatusTooManyRequests,
+	OutOfStock:            http.StatusConflict,
+	NotOnMenu:             http.StatusUnprocessableEntity,
+	StoreClosed:           http.StatusUnprocessableEntity,
+	DeliveryUnavailable:   http.StatusUnprocessableEntity,
+	AddressNotServiceable: http.StatusUnprocessableEntity,
+	DineInUnavailable:     http.StatusUnprocessableEntity,
+	InvalidTable:          http.StatusUnprocessableEntity,
+	InvalidTaxID:          http.StatusUnprocessableEntity,
+	InvalidPayment:        http.StatusUnprocessableEntity,
+	PaymentDeclined:       http.StatusPaymentRequired,
+	NotPurchased:          http.StatusUnprocessableEntity,
+	AlreadyReviewed:       http.StatusConflict,
+	CartClosed:            http.StatusConflict,
+	CartFull:              http.StatusUnprocessableEntity,
+	CartEmpty:             http.StatusUnprocessableEntity,
+	NotDelivery:           http.StatusUnprocessableEntity,
+	InvalidDeliveryStatus: http.StatusConflict,
+	AccountExists:         http.StatusConflict,
+	InvalidCredentials:    http.StatusUnauthorized,
+	AccountLocked:         http.StatusLocked,
+	InvalidBackup:         http.StatusUnprocessableEntity,
+	EntryExists:           http.StatusConflict,
+	KeyDisabled:           http.StatusConflict,
+	InternalError:         http.StatusInternalServerError,
+	OrderFailed:           http.StatusInternalServerError,
+	StorageFailed:         http.StatusInternalServerError,
+	ReloadFailed:          http.StatusInternalServerError,
+	RestoreFailed:         http.StatusInternalServerError,
+	ChallengeUnavailable:  http.StatusServiceUnavailable,
+	ProviderUnavailable:   http.StatusServiceUnavailable,
+	PaymentUnavailable:    http.StatusBadGateway,
+	Timeout:               http.StatusGatewayTimeout,
+	ShuttingDown:          http.StatusServiceUnavailable,
+	Maintenance:           http.StatusServiceUnavailable,
+	KitchenBusy:           http.StatusServiceUnavailable,
+}
+
+// New creates an error response with a code from the catalog
+func New(code, message string) *models.ErrorResponse {
+	return models.NewErrorResponse(code, message)
+}
+
+// Status returns the HTTP status code is returned with. Codes missing from
+// the catalog are server errors.
+func Status(code string) int {
+	if status, ok := statuses[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// Codes returns every code in the catalog
+func Codes() []string {
+	codes := make([]string, 0, len(statuses))
+	for code := range statuses {
+		codes = append(codes, code)
+	}
+	return codes
+}
@@ -0,0 +1,49 @@
+package apierrors
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		code   string
+		status int
+	}{
+		{code: InvalidRequest, status: http.StatusBadRequest},
+		{code: InvalidCoupon, status: http.StatusBadRequest},
+		{code: Unauthorized, status: http.StatusUnauthorized},
+		{code: Forbidden, status: http.StatusForbidden},
+		{code: InvalidProduct, status: http.StatusNotFound},
+		{code: ProductExists, status: http.StatusConflict},
+		{code: AccountLocked, status: http.StatusLocked},
+		{code: PayloadTooLarge, status: http.StatusRequestEntityTooLarge},
+		{code: StoreClosed, status: http.StatusUnprocessableEntity},
+		{code: OrderFailed, status: http.StatusInternalServerError},
+		{code: PaymentUnavailable, status: http.StatusBadGateway},
+		{code: "SOMETHING_NEW", status: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			assert.Equal(t, tt.status, Status(tt.code))
+		})
+	}
+}
+
+func TestCodes(t *testing.T) {
+	for _, code := range Codes() {
+		status := Status(code)
+		assert.GreaterOrEqual(t, status, 400, code)
+		assert.Less(t, status, 600, code)
+	}
+}
+
+func TestNew(t *testing.T) {
+	errResp := New(NotFound, "Product not found").AddDetail("productId", "1")
+	assert.Equal(t, NotFound, errResp.Code)
+	assert.Equal(t, "Product not found", errResp.Message)
+	assert.Equal(t, "1", errResp.Details["productId"])
+}
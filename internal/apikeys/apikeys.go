@@ -0,0 +1,444 @@
+// Package apikeys manages the API keys staff and partner integrations
+// authenticate with: creating them with scopes and an optional expiry,
+// rotating their secret, disabling them, and persisting them to a JSON file
+// with only a hash of each secret.
+package apikeys
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/auth"
+	"github.com/ravibandhu/oolio-food-ordering/internal/blocklist"
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+)
+
+// Key statuses
+const (
+	StatusActive   = "active"   // Accepted
+	StatusExpired  = "expired"  // Refused since its expiry date
+	StatusDisabled = "disabled" // Refused since an admin disabled it
+)
+
+// secretPrefix starts every generated secret, so leaked keys are easy to
+// recognize in logs and by secret scanners
+const secretPrefix = "oolio_"
+
+var (
+	// ErrNotFound is returned when a key does not exist
+	ErrNotFound = errors.New("api key not found")
+	// ErrDisabled is returned when rotating a disabled key
+	ErrDisabled = errors.New("api key disabled")
+	// ErrInvalidKey is returned for keys with an unknown scope or an expiry
+	// date that has passed
+	ErrInvalidKey = errors.New("invalid api key")
+)
+
+// Key is an API key, without its secret
+type Key struct {
+	// The unique identifier of the key, which usage is reported by
+	// @example 9f86d081884c
+	ID string `json:"id"`
+
+	// What the key is for
+	// @example Delivery partner
+	Name string `json:"name"`
+
+	// The first characters of the secret, to recognize the key by. Empty for
+	// keys imported from the configuration.
+	// @example oolio_3fa85f
+	Prefix string `json:"prefix,omitempty"`
+
+	// The roles the key holds: admin, kitchen or support
+	// @example kitchen
+	Scopes []string `json:"scopes"`
+
+	// Whether the key is accepted: active, expired or disabled
+	// @example active
+	Status string `json:"status,omitempty"`
+
+	// The timestamp when the key was created
+	// @example 2024-01-01T00:00:00Z
+	CreatedAt time.Time `json:"created_at"`
+
+	// The timestamp after which the key is refused; never when empty
+	// @example 2025-01-01T00:00:00Z
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// The timestamp when the secret was last rotated
+	// @example 2024-06-01T00:00:00Z
+	RotatedAt *time.Time `json:"rotated_at,omitempty"`
+
+	// The timestamp when the key was disabled
+	// @example 2024-07-01T00:00:00Z
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+}
+
+// status returns whether the key is accepted at now
+func (k *Key) status(now time.Time) string {
+	switch {
+	case k.DisabledAt != nil:
+		return StatusDisabled
+	case k.ExpiresAt != nil && !now.Before(*k.ExpiresAt):
+		return StatusExpired
+	}
+	return StatusActive
+}
+
+// KeyRequest represents the request body for creating a key
+type KeyRequest struct {
+	// What the key is for
+	// @required
+	// @example Delivery partner
+	Name string `json:"name" validate:"required,max=100"`
+
+	// The roles the key holds: admin, kitchen or support
+	// @required
+	// @example kitchen
+	Scopes []string `json:"scopes" validate:"required,min=1"`
+
+	// The timestamp after which the key is refused; never when empty
+	// @example 2025-01-01T00:00:00Z
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Secret is a key along with its secret, returned only when the key is
+// created or rotated
+type Secret struct {
+	Key
+
+	// The secret to send in the X-API-Key header. It is not stored and cannot
+	// be retrieved again.
+	// @example oolio_3fa85f6457174562b3fc2c963f66afa6f0c2b1d4e5a69788
+	Secret string `json:"secret"`
+}
+
+// record is the persisted form of a key
+type record struct {
+	Key
+	Hash string `json:"hash"` // SHA-256 of the secret
+}
+
+// file is the persisted form of a Store
+type file struct {
+	Keys []*record `json:"keys"`
+}
+
+// Store holds the API keys
+type Store struct {
+	mu     sync.RWMutex
+	path   string
+	keys   []*record          // Oldest first
+	byHash map[string]*record // By hash of the secret
+	now    func() time.Time
+}
+
+// Load reads the keys persisted at cfg.KeysFile, then imports the keys
+// configured for each role that are not in it yet. A missing file holds no
+// keys, and an empty path keeps them in memory only.
+func Load(cfg config.Auth) (*Store, error) {
+	s := &Store{path: cfg.KeysFile, byHash: make(map[string]*record), now: time.Now}
+	if s.path != "" {
+		raw, err := os.ReadFile(s.path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return nil, fmt.Errorf("failed to read api keys: %w", err)
+		default:
+			var f file
+			if err := json.Unmarshal(raw, &f); err != nil {
+				return nil, fmt.Errorf("failed to parse api keys: %w", err)
+			}
+			s.keys = f.Keys
+		}
+	}
+	for _, r := range s.keys {
+		s.byHash[r.Hash] = r
+	}
+
+	configured := []struct {
+		env, role string
+		secrets   []string
+	}{
+		{"API_KEYS", auth.RoleAdmin, cfg.APIKeys},
+		{"KITCHEN_API_KEYS", auth.RoleKitchen, cfg.KitchenKeys},
+		{"SUPPORT_API_KEYS", auth.RoleSupport, cfg.SupportKeys},
+	}
+	imported := false
+	for _, c := range configured {
+		for i, secret := range c.secrets {
+			if s.importKey(c.env+" #"+strconv.Itoa(i+1), c.role, secret) {
+				imported = true
+			}
+		}
+	}
+	if imported {
+		if err := s.save(s.keys); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// ConfiguredID returns the ID a key imported from the configuration gets:
+// the first 12 hex digits of its fingerprint, as blocklist audit events
+// identify keys
+func ConfiguredID(secret string) string {
+	return blocklist.Fingerprint(secret)[:12]
+}
+
+// importKey adds a configured key unless it was imported before, even if it
+// was rotated or disabled since, and reports whether it did
+func (s *Store) importKey(name, role, secret string) bool {
+	id := ConfiguredID(secret)
+	if slices.ContainsFunc(s.keys, func(r *record) bool { return r.ID == id }) {
+		return false
+	}
+	r := &record{
+		Key:  Key{ID: id, Name: name, Scopes: []string{role}, CreatedAt: s.now()},
+		Hash: blocklist.Fingerprint(secret),
+	}
+	s.keys = append(s.keys, r)
+	s.byHash[r.Hash] = r
+	return true
+}
+
+// Lookup returns the key a secret belongs to, whatever its status
+func (s *Store) Lookup(secret string) (Key, bool) {
+	if secret == "" {
+		return Key{}, false
+	}
+	hash := blocklist.Fingerprint(secret)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.byHash[hash]
+	if !ok {
+		return Key{}, false
+	}
+	return s.view(r), true
+}
+
+// Authenticate returns the key a secret belongs to if it is active
+func (s *Store) Authenticate(secret string) (Key, bool) {
+	key, ok := s.Lookup(secret)
+	if !ok || key.Status != StatusActive {
+		return Key{}, false
+	}
+	return key, true
+}
+
+// List returns every key, oldest first
+func (s *Store) List() []Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]Key, len(s.keys))
+	for i, r := range s.keys {
+		keys[i] = s.view(r)
+	}
+	return keys
+}
+
+// Get returns a key by ID
+func (s *Store) Get(id string) (Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, err := s.find(id)
+	if err != nil {
+		return Key{}, err
+	}
+	return s.view(r), nil
+}
+
+// Create creates a key with a new secret
+func (s *Store) Create(req KeyRequest) (Secret, error) {
+	scopes, err := auth.ValidateStaffRoles(req.Scopes)
+	if err != nil {
+		return Secret{}, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+	now := s.now()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return Secret{}, fmt.Errorf("%w: expires_at is not in the future", ErrInvalidKey)
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return Secret{}, err
+	}
+
+	r := &record{
+		Key: Key{
+			ID:        ConfiguredID(secret),
+			Name:      req.Name,
+			Prefix:    secret[:len(secretPrefix)+6],
+			Scopes:    scopes,
+			CreatedAt: now,
+			ExpiresAt: req.ExpiresAt,
+		},
+		Hash: blocklist.Fingerprint(secret),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := append(slices.Clone(s.keys), r)
+	if err := s.save(keys); err != nil {
+		return Secret{}, err
+	}
+	s.keys = keys
+	s.byHash[r.Hash] = r
+	return Secret{Key: s.view(r), Secret: secret}, nil
+}
+
+// Rotate replaces the secret of a key. The old secret is refused from then
+// on; the ID, scopes and expiry of the key are kept.
+func (s *Store) Rotate(id string) (Secret, error) {
+	secret, err := newSecret()
+	if err != nil {
+		return Secret{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, err := s.find(id)
+	if err != nil {
+		return Secret{}, err
+	}
+	if r.DisabledAt != nil {
+		return Secret{}, fmt.Errorf("%w: %s", ErrDisabled, id)
+	}
+
+	now := s.now()
+	rotated := *r
+	rotated.Prefix = secret[:len(secretPrefix)+6]
+	rotated.Hash = blocklist.Fingerprint(secret)
+	rotated.RotatedAt = &now
+	if err := s.replace(r, &rotated); err != nil {
+		return Secret{}, err
+	}
+	return Secret{Key: s.view(&rotated), Secret: secret}, nil
+}
+
+// Disable refuses a key from now on. Disabling a disabled key leaves it as
+// it is.
+func (s *Store) Disable(id string) (Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, err := s.find(id)
+	if err != nil {
+		return Key{}, err
+	}
+	if r.DisabledAt != nil {
+		return s.view(r), nil
+	}
+
+	now := s.now()
+	disabled := *r
+	disabled.DisabledAt = &now
+	if err := s.replace(r, &disabled); err != nil {
+		return Key{}, err
+	}
+	return s.view(&disabled), nil
+}
+
+// find returns the record of a key by ID. Callers must hold s.mu.
+func (s *Store) find(id string) (*record, error) {
+	i := slices.IndexFunc(s.keys, func(r *record) bool { return r.ID == id })
+	if i < 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return s.keys[i], nil
+}
+
+// replace saves the keys with old replaced by updated. Callers must hold
+// s.mu for writing.
+func (s *Store) replace(old, updated *record) error {
+	keys := slices.Clone(s.keys)
+	keys[slices.Index(keys, old)] = updated
+	if err := s.save(keys); err != nil {
+		return err
+	}
+	s.keys = keys
+	delete(s.byHash, old.Hash)
+	s.byHash[updated.Hash] = updated
+	return nil
+}
+
+// view returns the key of a record with its current status
+func (s *Store) view(r *record) Key {
+	key := r.Key
+	key.Scopes = slices.Clone(r.Scopes)
+	key.Status = key.status(s.now())
+	return key
+}
+
+// save persists keys, writing to a temporary file first so they are never
+// left half-written. Callers must hold s.mu for writing.
+func (s *Store) save(keys []*record) error {
+	if s.path == "" {
+		return nil
+	}
+
+	raw, err := json.MarshalIndent(file{Keys: keys}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode api keys: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".apikeys-*")
+	if err != nil {
+		return fmt.Errorf("failed to write api keys: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write api keys: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write api keys: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write api keys: %w", err)
+	}
+	return nil
+}
+
+// newSecret returns a random secret
+func newSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	return secretPrefix + hex.EncodeToString(b), nil
+}
+
+// keyKey is the context key of the API key a request authenticated with
+type keyKey struct{}
+
+// WithKey returns a copy of ctx carrying the API key the caller
+// authenticated with
+func WithKey(ctx context.Context, key Key) context.Context {
+	return context.WithValue(ctx, keyKey{}, key)
+}
+
+// FromContext returns the API key carried by ctx, if the caller
+// authenticated with one
+func FromContext(ctx context.Context) (Key, bool) {
+	key, ok := ctx.Value(keyKey{}).(Key)
+	return key, ok
+}
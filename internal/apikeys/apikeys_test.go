@@ -0,0 +1,139 @@
+package apikeys
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/auth"
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_Lifecycle(t *testing.T) {
+	store, err := Load(config.Auth{})
+	require.NoError(t, err)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	expiry := now.Add(24 * time.Hour)
+	created, err := store.Create(KeyRequest{Name: "Partner", Scopes: []string{auth.RoleSupport, auth.RoleKitchen, auth.RoleSupport}, ExpiresAt: &expiry})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(created.Secret, secretPrefix))
+	assert.True(t, strings.HasPrefix(created.Secret, created.Prefix))
+	assert.Equal(t, []string{auth.RoleKitchen, auth.RoleSupport}, created.Scopes)
+	assert.Equal(t, StatusActive, created.Status)
+
+	key, ok := store.Authenticate(created.Secret)
+	require.True(t, ok)
+	assert.Equal(t, created.ID, key.ID)
+	_, ok = store.Authenticate("wrong")
+	assert.False(t, ok)
+
+	rotated, err := store.Rotate(created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, rotated.ID)
+	assert.NotNil(t, rotated.RotatedAt)
+	_, ok = store.Authenticate(created.Secret)
+	assert.False(t, ok, "the old secret is refused once rotated")
+	_, ok = store.Authenticate(rotated.Secret)
+	assert.True(t, ok)
+
+	// Expired keys are known but refused
+	now = expiry
+	key, ok = store.Lookup(rotated.Secret)
+	require.True(t, ok)
+	assert.Equal(t, StatusExpired, key.Status)
+	_, ok = store.Authenticate(rotated.Secret)
+	assert.False(t, ok)
+
+	disabled, err := store.Disable(created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusDisabled, disabled.Status)
+	again, err := store.Disable(created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, disabled.DisabledAt, again.DisabledAt)
+	_, err = store.Rotate(created.ID)
+	assert.ErrorIs(t, err, ErrDisabled)
+
+	_, err = store.Get("missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = store.Rotate("missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = store.Disable("missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestStore_CreateInvalid(t *testing.T) {
+	store, err := Load(config.Auth{})
+	require.NoError(t, err)
+
+	_, err = store.Create(KeyRequest{Name: "Partner", Scopes: []string{"owner"}})
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	assert.ErrorIs(t, err, auth.ErrUnknownRole)
+
+	past := time.Now().Add(-time.Minute)
+	_, err = store.Create(KeyRequest{Name: "Partner", Scopes: []string{auth.RoleKitchen}, ExpiresAt: &past})
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	assert.Empty(t, store.List())
+}
+
+func TestLoad_ImportsConfiguredKeys(t *testing.T) {
+	cfg := config.Auth{
+		APIKeys:     []string{"admin-key"},
+		KitchenKeys: []string{"kitchen-1", "kitchen-2"},
+		KeysFile:    filepath.Join(t.TempDir(), "apikeys.json"),
+	}
+	store, err := Load(cfg)
+	require.NoError(t, err)
+
+	keys := store.List()
+	require.Len(t, keys, 3)
+	assert.Equal(t, ConfiguredID("admin-key"), keys[0].ID)
+	assert.Equal(t, "API_KEYS #1", keys[0].Name)
+	assert.Equal(t, []string{auth.RoleAdmin}, keys[0].Scopes)
+	assert.Equal(t, "KITCHEN_API_KEYS #2", keys[2].Name)
+
+	// Secrets are only stored hashed
+	raw, err := os.ReadFile(cfg.KeysFile)
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "admin-key")
+	assert.NotContains(t, string(raw), `"status"`)
+
+	// A rotated configured key is not imported again
+	rotated, err := store.Rotate(ConfiguredID("kitchen-1"))
+	require.NoError(t, err)
+	created, err := store.Create(KeyRequest{Name: "Partner", Scopes: []string{auth.RoleSupport}})
+	require.NoError(t, err)
+
+	reloaded, err := Load(cfg)
+	require.NoError(t, err)
+	assert.Len(t, reloaded.List(), 4)
+	_, ok := reloaded.Authenticate("kitchen-1")
+	assert.False(t, ok)
+	for _, secret := range []string{"admin-key", "kitchen-2", rotated.Secret, created.Secret} {
+		_, ok := reloaded.Authenticate(secret)
+		assert.True(t, ok, secret)
+	}
+}
+
+func TestLoad_InvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "apikeys.json")
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0644))
+	_, err := Load(config.Auth{KeysFile: path})
+	assert.Error(t, err)
+}
+
+func TestContext(t *testing.T) {
+	_, ok := FromContext(context.Background())
+	assert.False(t, ok)
+
+	ctx := WithKey(context.Background(), Key{ID: "9f86d081884c"})
+	key, ok := FromContext(ctx)
+	require.True(t, ok)
+	assert.Equal(t, "9f86d081884c", key.ID)
+}
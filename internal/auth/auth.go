@@ -0,0 +1,346 @@
+// Package auth signs customers in with third-party identity providers or
+// passwords, keeps their profiles, and issues the session tokens they
+// authenticate with afterwards. It also defines the roles that staff routes
+// require of API keys and session tokens.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/notify"
+)
+
+var (
+	// ErrInvalidToken is returned for ID tokens and session tokens that are
+	// malformed, badly signed, expired or not meant for this API
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrUnknownProvider is returned when signing in with a provider that is
+	// not configured
+	ErrUnknownProvider = errors.New("unknown identity provider")
+	// ErrProviderUnavailable is returned when a provider's signing keys
+	// cannot be fetched
+	ErrProviderUnavailable = errors.New("identity provider unavailable")
+	// ErrCustomerNotFound is returned when a customer does not exist
+	ErrCustomerNotFound = errors.New("customer not found")
+	// ErrAccountExists is returned when registering an email address a
+	// customer already uses
+	ErrAccountExists = errors.New("account already exists")
+	// ErrInvalidCredentials is returned for an unknown email address or a
+	// wrong password
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	// ErrAccountLocked is returned when signing in to an account locked
+	// after too many wrong passwords
+	ErrAccountLocked = errors.New("account locked")
+	// ErrTooManyFavorites is returned when a customer saves a favorite at a
+	// restaurant where they already saved MaxFavorites
+	ErrTooManyFavorites = errors.New("too many favorites")
+)
+
+// Accounts signs customers in and keeps their profiles
+type Accounts struct {
+	Customers *Customers
+	Sessions  *Sessions
+	providers map[string]*Provider
+	passwords config.Passwords
+	appURL    string
+	mailer    notify.Sender
+
+	dummyOnce sync.Once
+	dummyHash string // Checked for unknown email addresses, so they take as long as wrong passwords
+}
+
+// Load creates the Accounts described by cfg, reading the customer profiles
+// persisted at cfg.CustomersFile. Emails to customers are sent with mailer,
+// or written to the log when it is nil.
+func Load(cfg config.Auth, mailer notify.Sender) (*Accounts, error) {
+	if mailer == nil {
+		mailer = notify.NewSender(config.Email{})
+	}
+	customers, err := LoadCustomers(cfg.CustomersFile)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := NewSessions(cfg.JWTSecret, cfg.SessionTTL, cfg.RefreshTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	providers := make(map[string]*Provider)
+	if p := NewProvider(ProviderGoogle, cfg.Google); p != nil {
+		providers[ProviderGoogle] = p
+	}
+	if p := NewProvider(ProviderApple, cfg.Apple); p != nil {
+		providers[ProviderApple] = p
+	}
+
+	return &Accounts{
+		Customers: customers,
+		Sessions:  sessions,
+		providers: providers,
+		passwords: cfg.Passwords,
+		appURL:    cfg.AppURL,
+		mailer:    mailer,
+	}, nil
+}
+
+// SignIn verifies an ID token from the named provider and starts a session
+// for the customer it identifies, creating their profile on first sign-in
+func (a *Accounts) SignIn(ctx context.Context, provider, idToken string) (*Session, error) {
+	p, ok := a.providers[provider]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, provider)
+	}
+	profile, err := p.Verify(ctx, idToken)
+	if err != nil {
+		return nil, err
+	}
+	customer, err := a.Customers.SignIn(profile)
+	if err != nil {
+		return nil, err
+	}
+	return a.Sessions.Issue(customer)
+}
+
+// Register creates a customer who signs in with an email address and
+// password, starts a session for them, and emails them a code that verifies
+// the address
+func (a *Accounts) Register(ctx context.Context, email, password, name string) (*Session, error) {
+	hash, err := hashPassword(password, a.passwords.Hash)
+	if err != nil {
+		return nil, err
+	}
+	customer, err := a.Customers.Register(normalizeEmail(email), strings.TrimSpace(name), hash)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.SendVerification(ctx, customer); err != nil {
+		log.Printf("failed to send verification email to customer %s: %v", customer.ID, err)
+	}
+	return a.Sessions.Issue(customer)
+}
+
+// SendVerification emails a new code that verifies the address of a
+// customer registered with a password. Nothing is sent when the address is
+// already verified.
+func (a *Accounts) SendVerification(ctx context.Context, customer *Customer) error {
+	customer, code, err := a.Customers.issueCode(customer.Email, codeVerify, a.passwords.VerifyTTL)
+	if errors.Is(err, ErrCustomerNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return a.sendCode(ctx, customer, notify.TemplateVerifyEmail, "/verify-email", code, a.passwords.VerifyTTL)
+}
+
+// VerifyEmail marks the address a verification code was emailed to as
+// verified
+func (a *Accounts) VerifyEmail(code string) (*Customer, error) {
+	return a.Customers.verifyEmail(code)
+}
+
+// RequestPasswordReset emails a code that sets a new password to the address
+// of an account. Nothing is sent for addresses without an account, without
+// telling the caller, so addresses with accounts cannot be discovered.
+func (a *Accounts) RequestPasswordReset(ctx context.Context, email string) error {
+	customer, code, err := a.Customers.issueCode(normalizeEmail(email), codeReset, a.passwords.ResetTTL)
+	if errors.Is(err, ErrCustomerNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return a.sendCode(ctx, customer, notify.TemplateResetPassword, "/reset-password", code, a.passwords.ResetTTL)
+}
+
+// ResetPassword sets a new password for the account a reset code was emailed
+// for, and ends the customer's sessions, which may have been started by
+// whoever knew the old password
+func (a *Accounts) ResetPassword(code, password string) error {
+	hash, err := hashPassword(password, a.passwords.Hash)
+	if err != nil {
+		return err
+	}
+	customer, err := a.Customers.resetPassword(code, hash)
+	if err != nil {
+		return err
+	}
+	a.Sessions.RevokeCustomer(customer.ID)
+	return nil
+}
+
+// Login checks an email address and password and starts a session for the
+// customer registered with them. Wrong passwords count towards locking the
+// account; the one that locks it emails the customer a code to unlock it.
+func (a *Accounts) Login(ctx context.Context, email, password string) (*Session, error) {
+	customer, cred, ok := a.Customers.password(normalizeEmail(email))
+	if !ok {
+		a.dummyOnce.Do(func() { a.dummyHash, _ = hashPassword("dummy password", a.passwords.Hash) })
+		checkPassword(a.dummyHash, password)
+		return nil, ErrInvalidCredentials
+	}
+	if cred.locked() {
+		return nil, ErrAccountLocked
+	}
+
+	match, err := checkPassword(cred.Hash, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check password: %w", err)
+	}
+	if !match {
+		code, locked, err := a.Customers.recordFailedLogin(customer.ID, a.passwords.MaxFailedLogins, a.passwords.UnlockTTL)
+		if err != nil {
+			return nil, err
+		}
+		if code != "" {
+			err := a.sendCode(ctx, customer, notify.TemplateUnlock, "/unlock", code, a.passwords.UnlockTTL)
+			if err != nil {
+				// The account stays locked either way, and the customer can ask for another code
+				log.Printf("failed to send unlock email to customer %s: %v", customer.ID, err)
+			}
+		}
+		if locked {
+			return nil, ErrAccountLocked
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	var rehash string
+	if needsRehash(cred.Hash, a.passwords.Hash) {
+		if rehash, err = hashPassword(password, a.passwords.Hash); err != nil {
+			return nil, err
+		}
+	}
+	if err := a.Customers.recordLogin(customer.ID, rehash); err != nil {
+		return nil, err
+	}
+	return a.Sessions.Issue(customer)
+}
+
+// RequestUnlock emails a new unlock code to the address of a locked
+// account. Nothing is sent for addresses without a locked account, without
+// telling the caller, so addresses with accounts cannot be discovered.
+func (a *Accounts) RequestUnlock(ctx context.Context, email string) error {
+	customer, code, err := a.Customers.issueCode(normalizeEmail(email), codeUnlock, a.passwords.UnlockTTL)
+	if errors.Is(err, ErrCustomerNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return a.sendCode(ctx, customer, notify.TemplateUnlock, "/unlock", code, a.passwords.UnlockTTL)
+}
+
+// Unlock unlocks the account an unlock code was emailed for
+func (a *Accounts) Unlock(code string) error {
+	return a.Customers.unlock(code)
+}
+
+// codeEmail is the data of the templates of emails carrying a code
+type codeEmail struct {
+	Name    string
+	Code    string
+	Link    string // Page of the customer app that takes the code, if configured
+	Expires string
+}
+
+// sendCode emails a code to a customer from the named template. With an app
+// URL configured the email links to the app page at path, which takes the
+// code as a query parameter.
+func (a *Accounts) sendCode(ctx context.Context, customer *Customer, template, path, code string, ttl time.Duration) error {
+	data := codeEmail{
+		Name:    customer.Name,
+		Code:    code,
+		Expires: time.Now().Add(ttl).UTC().Format(time.RFC1123),
+	}
+	if a.appURL != "" {
+		data.Link = a.appURL + path + "?" + url.Values{"code": {code}}.Encode()
+	}
+	email, err := notify.Render(customer.Email, template, data)
+	if err != nil {
+		return err
+	}
+	return a.mailer.Send(ctx, email)
+}
+
+// normalizeEmail returns the form email addresses are stored and looked up in
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// SetRoles replaces the staff roles granted to a customer, and ends their
+// sessions so no token carries the roles they held before
+func (a *Accounts) SetRoles(customerID string, roles []string) (*Customer, error) {
+	roles, err := ValidateStaffRoles(roles)
+	if err != nil {
+		return nil, err
+	}
+	customer, err := a.Customers.SetRoles(customerID, roles)
+	if err != nil {
+		return nil, err
+	}
+	a.Sessions.RevokeCustomer(customer.ID)
+	return customer, nil
+}
+
+// Refresh redeems a refresh token for a renewed session
+func (a *Accounts) Refresh(refreshToken string) (*Session, error) {
+	return a.Sessions.Refresh(refreshToken, a.Customers)
+}
+
+// Logout ends the session a token was issued in, so neither its access
+// tokens nor its refresh tokens are accepted again
+func (a *Accounts) Logout(claims *SessionClaims) {
+	a.Sessions.Revoke(claims.SessionID)
+}
+
+// Authenticate verifies a session token and returns the customer it was
+// issued to, along with the token's claims
+func (a *Accounts) Authenticate(token string) (*Customer, *SessionClaims, error) {
+	claims, err := a.Sessions.Verify(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	customer, err := a.Customers.Get(claims.Subject)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: customer no longer exists", ErrInvalidToken)
+	}
+	return customer, claims, nil
+}
+
+// customerKey is the context key of the signed-in customer
+type customerKey struct{}
+
+// sessionKey is the context key of the session token's claims
+type sessionKey struct{}
+
+// NewContext returns a copy of ctx carrying the signed-in customer
+func NewContext(ctx context.Context, customer *Customer) context.Context {
+	return context.WithValue(ctx, customerKey{}, customer)
+}
+
+// FromContext returns the signed-in customer carried by ctx, if any
+func FromContext(ctx context.Context) (*Customer, bool) {
+	customer, ok := ctx.Value(customerKey{}).(*Customer)
+	return customer, ok
+}
+
+// WithSession returns a copy of ctx carrying the claims of the session token
+// the request was authenticated with
+func WithSession(ctx context.Context, claims *SessionClaims) context.Context {
+	return context.WithValue(ctx, sessionKey{}, claims)
+}
+
+// SessionFromContext returns the session claims carried by ctx, if any
+func SessionFromContext(ctx context.Context) (*SessionClaims, bool) {
+	claims, ok := ctx.Value(sessionKey{}).(*SessionClaims)
+	return claims, ok
+}
@@ -0,0 +1,281 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccounts(t *testing.T) {
+	issuer := testutil.NewIdentityProvider(t)
+	accounts, err := Load(config.Auth{SessionTTL: time.Hour, RefreshTTL: 24 * time.Hour, Google: issuer.Config()}, nil)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	session, err := accounts.SignIn(ctx, ProviderGoogle, issuer.Mint(t, issuer.Claims("user-1", nil)))
+	require.NoError(t, err)
+	assert.Equal(t, "jane@example.com", session.Customer.Email)
+
+	customer, claims, err := accounts.Authenticate(session.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, session.Customer.ID, customer.ID)
+	assert.Equal(t, customer.ID, claims.Subject)
+
+	_, err = accounts.SignIn(ctx, ProviderApple, issuer.Mint(t, issuer.Claims("user-1", nil)))
+	assert.True(t, errors.Is(err, ErrUnknownProvider))
+
+	_, _, err = accounts.Authenticate("not-a-token")
+	assert.True(t, errors.Is(err, ErrInvalidToken))
+
+	// Sessions of customers that no longer exist are rejected
+	orphan, err := accounts.Sessions.Issue(&Customer{ID: "customer-missing"})
+	require.NoError(t, err)
+	_, _, err = accounts.Authenticate(orphan.AccessToken)
+	assert.True(t, errors.Is(err, ErrInvalidToken))
+}
+
+func TestAccounts_RefreshAndLogout(t *testing.T) {
+	issuer := testutil.NewIdentityProvider(t)
+	accounts, err := Load(config.Auth{SessionTTL: time.Hour, RefreshTTL: 24 * time.Hour, Google: issuer.Config()}, nil)
+	require.NoError(t, err)
+
+	session, err := accounts.SignIn(context.Background(), ProviderGoogle, issuer.Mint(t, issuer.Claims("user-1", nil)))
+	require.NoError(t, err)
+
+	refreshed, err := accounts.Refresh(session.RefreshToken)
+	require.NoError(t, err)
+	assert.Equal(t, session.Customer.ID, refreshed.Customer.ID)
+
+	_, claims, err := accounts.Authenticate(refreshed.AccessToken)
+	require.NoError(t, err)
+	accounts.Logout(claims)
+
+	// Every token of the session is rejected after logging out
+	_, _, err = accounts.Authenticate(session.AccessToken)
+	assert.True(t, errors.Is(err, ErrInvalidToken))
+	_, _, err = accounts.Authenticate(refreshed.AccessToken)
+	assert.True(t, errors.Is(err, ErrInvalidToken))
+	_, err = accounts.Refresh(refreshed.RefreshToken)
+	assert.True(t, errors.Is(err, ErrInvalidToken))
+}
+
+func TestContext(t *testing.T) {
+	_, ok := FromContext(context.Background())
+	assert.False(t, ok)
+
+	customer := &Customer{ID: "customer-1"}
+	got, ok := FromContext(NewContext(context.Background(), customer))
+	require.True(t, ok)
+	assert.Same(t, customer, got)
+
+	_, ok = SessionFromContext(context.Background())
+	assert.False(t, ok)
+	claims := &SessionClaims{SessionID: "session-1"}
+	gotClaims, ok := SessionFromContext(WithSession(context.Background(), claims))
+	require.True(t, ok)
+	assert.Same(t, claims, gotClaims)
+}
+
+func TestAccounts_Passwords(t *testing.T) {
+	mailbox := &testutil.Mailbox{}
+	accounts, err := Load(config.Auth{
+		SessionTTL: time.Hour,
+		RefreshTTL: time.Hour,
+		Passwords:  config.Passwords{Hash: config.PasswordArgon2id, MaxFailedLogins: 3, UnlockTTL: time.Hour},
+	}, mailbox)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	session, err := accounts.Register(ctx, " Jane@Example.com ", "correct horse", "Jane")
+	require.NoError(t, err)
+	assert.Equal(t, "jane@example.com", session.Customer.Email)
+	require.Len(t, mailbox.Emails(), 1, "verification email")
+
+	signedIn, err := accounts.Login(ctx, "JANE@example.com", "correct horse")
+	require.NoError(t, err)
+	assert.Equal(t, session.Customer.ID, signedIn.Customer.ID)
+
+	_, err = accounts.Login(ctx, "nobody@example.com", "correct horse")
+	assert.True(t, errors.Is(err, ErrInvalidCredentials))
+
+	// The third wrong password in a row locks the account and emails a code
+	for i := 0; i < 2; i++ {
+		_, err = accounts.Login(ctx, "jane@example.com", "wrong horse")
+		assert.True(t, errors.Is(err, ErrInvalidCredentials))
+	}
+	assert.Len(t, mailbox.Emails(), 1)
+	_, err = accounts.Login(ctx, "jane@example.com", "wrong horse")
+	assert.True(t, errors.Is(err, ErrAccountLocked))
+	email := mailbox.Last(t)
+	assert.Equal(t, "jane@example.com", email.To)
+
+	// Even the right password is refused until the account is unlocked
+	_, err = accounts.Login(ctx, "jane@example.com", "correct horse")
+	assert.True(t, errors.Is(err, ErrAccountLocked))
+
+	require.NoError(t, accounts.RequestUnlock(ctx, "nobody@example.com"))
+	assert.Len(t, mailbox.Emails(), 2)
+	require.NoError(t, accounts.RequestUnlock(ctx, "jane@example.com"))
+	require.Len(t, mailbox.Emails(), 3)
+
+	code := emailedCode(t, mailbox.Last(t).Body)
+	require.NoError(t, accounts.Unlock(code))
+	_, err = accounts.Login(ctx, "jane@example.com", "correct horse")
+	assert.NoError(t, err)
+}
+
+func TestAccounts_PasswordRehash(t *testing.T) {
+	cfg := config.Auth{
+		SessionTTL: time.Hour,
+		RefreshTTL: time.Hour,
+		Passwords:  config.Passwords{Hash: config.PasswordBcrypt, UnlockTTL: time.Hour},
+	}
+	accounts, err := Load(cfg, nil)
+	require.NoError(t, err)
+	_, err = accounts.Register(context.Background(), "jane@example.com", "correct horse", "")
+	require.NoError(t, err)
+
+	// Switching algorithms upgrades hashes as customers sign in
+	accounts.passwords.Hash = config.PasswordArgon2id
+	_, err = accounts.Login(context.Background(), "jane@example.com", "correct horse")
+	require.NoError(t, err)
+	_, cred, _ := accounts.Customers.password("jane@example.com")
+	assert.True(t, strings.HasPrefix(cred.Hash, "$argon2id$"))
+
+	_, err = accounts.Login(context.Background(), "jane@example.com", "correct horse")
+	assert.NoError(t, err)
+}
+
+func TestAccounts_SetRoles(t *testing.T) {
+	issuer := testutil.NewIdentityProvider(t)
+	accounts, err := Load(config.Auth{SessionTTL: time.Hour, RefreshTTL: time.Hour, Google: issuer.Config()}, nil)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	session, err := accounts.SignIn(ctx, ProviderGoogle, issuer.Mint(t, issuer.Claims("user-1", nil)))
+	require.NoError(t, err)
+	_, claims, err := accounts.Authenticate(session.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, []string{RoleCustomer}, claims.Roles)
+
+	customer, err := accounts.SetRoles(session.Customer.ID, []string{RoleSupport, RoleKitchen})
+	require.NoError(t, err)
+	assert.Equal(t, []string{RoleKitchen, RoleSupport}, customer.Roles)
+
+	// Tokens carrying the old roles are no longer accepted
+	_, _, err = accounts.Authenticate(session.AccessToken)
+	assert.True(t, errors.Is(err, ErrInvalidToken))
+
+	session, err = accounts.SignIn(ctx, ProviderGoogle, issuer.Mint(t, issuer.Claims("user-1", nil)))
+	require.NoError(t, err)
+	_, claims, err = accounts.Authenticate(session.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, []string{RoleCustomer, RoleKitchen, RoleSupport}, claims.Roles)
+
+	_, err = accounts.SetRoles(session.Customer.ID, []string{"owner"})
+	assert.True(t, errors.Is(err, ErrUnknownRole))
+	_, err = accounts.SetRoles("customer-unknown", []string{RoleAdmin})
+	assert.True(t, errors.Is(err, ErrCustomerNotFound))
+}
+
+func TestAccounts_EmailVerification(t *testing.T) {
+	mailbox := &testutil.Mailbox{}
+	accounts, err := Load(config.Auth{
+		SessionTTL: time.Hour,
+		RefreshTTL: time.Hour,
+		AppURL:     "https://app.example.com",
+		Passwords:  config.Passwords{Hash: config.PasswordBcrypt, UnlockTTL: time.Hour, VerifyTTL: time.Hour},
+	}, mailbox)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	session, err := accounts.Register(ctx, "jane@example.com", "correct horse", "Jane")
+	require.NoError(t, err)
+	assert.False(t, session.Customer.EmailVerified)
+	email := mailbox.Last(t)
+	assert.Equal(t, "jane@example.com", email.To)
+	assert.Contains(t, email.Body, "https://app.example.com/verify-email?code=")
+
+	// Resending replaces the code
+	first := emailedCode(t, email.Body)
+	require.NoError(t, accounts.SendVerification(ctx, session.Customer))
+	require.Len(t, mailbox.Emails(), 2)
+	_, err = accounts.VerifyEmail(first)
+	assert.True(t, errors.Is(err, ErrInvalidToken))
+
+	customer, err := accounts.VerifyEmail(emailedCode(t, mailbox.Last(t).Body))
+	require.NoError(t, err)
+	assert.True(t, customer.EmailVerified)
+
+	// Verified addresses get no more codes
+	require.NoError(t, accounts.SendVerification(ctx, customer))
+	assert.Len(t, mailbox.Emails(), 2)
+}
+
+func TestAccounts_PasswordReset(t *testing.T) {
+	mailbox := &testutil.Mailbox{}
+	accounts, err := Load(config.Auth{
+		SessionTTL: time.Hour,
+		RefreshTTL: time.Hour,
+		Passwords: config.Passwords{
+			Hash: config.PasswordBcrypt, MaxFailedLogins: 1, UnlockTTL: time.Hour,
+			VerifyTTL: time.Hour, ResetTTL: time.Hour,
+		},
+	}, mailbox)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	session, err := accounts.Register(ctx, "jane@example.com", "correct horse", "Jane")
+	require.NoError(t, err)
+	_, err = accounts.Login(ctx, "jane@example.com", "wrong horse")
+	require.True(t, errors.Is(err, ErrAccountLocked))
+	require.Len(t, mailbox.Emails(), 2)
+
+	// Unknown addresses are not told apart
+	require.NoError(t, accounts.RequestPasswordReset(ctx, "nobody@example.com"))
+	assert.Len(t, mailbox.Emails(), 2)
+
+	require.NoError(t, accounts.RequestPasswordReset(ctx, "JANE@example.com"))
+	require.Len(t, mailbox.Emails(), 3)
+	code := emailedCode(t, mailbox.Last(t).Body)
+
+	assert.True(t, errors.Is(accounts.ResetPassword("wrong", "battery staple"), ErrInvalidToken))
+	require.NoError(t, accounts.ResetPassword(code, "battery staple"))
+	assert.True(t, errors.Is(accounts.ResetPassword(code, "battery staple"), ErrInvalidToken))
+
+	// The reset unlocks the account, verifies the address and ends sessions
+	_, _, err = accounts.Authenticate(session.AccessToken)
+	assert.True(t, errors.Is(err, ErrInvalidToken))
+	_, err = accounts.Refresh(session.RefreshToken)
+	assert.True(t, errors.Is(err, ErrInvalidToken))
+	signedIn, err := accounts.Login(ctx, "jane@example.com", "battery staple")
+	require.NoError(t, err)
+	assert.True(t, signedIn.Customer.EmailVerified)
+
+	// The old password is wrong now, and locks the account again
+	_, err = accounts.Login(ctx, "jane@example.com", "correct horse")
+	assert.True(t, errors.Is(err, ErrAccountLocked))
+}
+
+// emailedCode returns the code in an email, given on a line of its own or as
+// the code parameter of a link
+func emailedCode(t *testing.T, body string) string {
+	t.Helper()
+	for _, line := range strings.Split(body, "\n") {
+		if _, code, ok := strings.Cut(line, "?code="); ok {
+			return code
+		}
+		if line != "" && !strings.Contains(line, " ") {
+			return line
+		}
+	}
+	t.Fatalf("no code in %q", body)
+	return ""
+}
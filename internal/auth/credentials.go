@@ -0,0 +1,256 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Purposes of the one-time codes emailed to customers
+const (
+	codeUnlock = "unlock" // Unlocks an account locked after too many wrong passwords
+	codeVerify = "verify" // Verifies the email address of a registered customer
+	codeReset  = "reset"  // Sets a new password
+)
+
+// oneTimeCode is a code emailed to a customer, stored by its hash
+type oneTimeCode struct {
+	Hash    string    `json:"hash"`
+	Expires time.Time `json:"expires"`
+}
+
+// credential is the password of a customer who registered with an email
+// address, the state of its lockout, and the codes emailed to the customer
+type credential struct {
+	Hash           string                 `json:"hash"`
+	FailedAttempts int                    `json:"failed_attempts,omitempty"`
+	LockedAt       time.Time              `json:"locked_at,omitzero"`
+	Codes          map[string]oneTimeCode `json:"codes,omitempty"` // By purpose; issuing a code replaces the last one
+}
+
+// locked reports whether the account the credential belongs to is locked
+func (c *credential) locked() bool {
+	return !c.LockedAt.IsZero()
+}
+
+// clone returns a copy of c that shares no maps with it
+func (c *credential) clone() *credential {
+	out := *c
+	out.Codes = maps.Clone(c.Codes)
+	return &out
+}
+
+// newCode stores a new code for purpose on the credential, replacing any
+// earlier one, and returns the code
+func (c *credential) newCode(purpose string, expires time.Time) (string, error) {
+	code, err := randomToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate %s code: %w", purpose, err)
+	}
+	if c.Codes == nil {
+		c.Codes = make(map[string]oneTimeCode)
+	}
+	c.Codes[purpose] = oneTimeCode{Hash: hashToken(code), Expires: expires}
+	return code, nil
+}
+
+// Register creates a customer who signs in with an email address and a
+// password, given as its hash. It returns ErrAccountExists when a customer
+// already uses the email address.
+func (c *Customers) Register(email, name, hash string) (*Customer, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, existing := range c.customers {
+		if existing.Email == email {
+			return nil, fmt.Errorf("%w: %s", ErrAccountExists, email)
+		}
+	}
+
+	now := c.now()
+	customer := &Customer{
+		ID:         fmt.Sprintf("customer-%s", uuid.New().String()),
+		Email:      email,
+		Name:       name,
+		Identities: []Identity{{Provider: ProviderPassword, Subject: email}},
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := c.saveLocked(customer, &credential{Hash: hash}); err != nil {
+		return nil, err
+	}
+	return customer.clone(), nil
+}
+
+// passwordLocked returns the customer registered with an email address and
+// their credential. Callers must hold c.mu.
+func (c *Customers) passwordLocked(email string) (*Customer, *credential, bool) {
+	identity := Identity{Provider: ProviderPassword, Subject: email}
+	for _, customer := range c.customers {
+		if slices.Contains(customer.Identities, identity) {
+			cred, ok := c.credentials[customer.ID]
+			return customer, cred, ok
+		}
+	}
+	return nil, nil, false
+}
+
+// password returns the customer registered with an email address and a copy
+// of their credential
+func (c *Customers) password(email string) (*Customer, credential, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	customer, cred, ok := c.passwordLocked(email)
+	if !ok {
+		return nil, credential{}, false
+	}
+	return customer.clone(), *cred.clone(), true
+}
+
+// recordFailedLogin counts a wrong password for the customer. Once
+// maxAttempts wrong passwords were given in a row the account is locked,
+// and the code that unlocks it is returned to be emailed to the customer;
+// maxAttempts of 0 never locks. It reports whether the account is locked.
+func (c *Customers) recordFailedLogin(id string, maxAttempts int, unlockTTL time.Duration) (string, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cred, ok := c.credentials[id]
+	if !ok {
+		return "", false, fmt.Errorf("%w: %s", ErrCustomerNotFound, id)
+	}
+	if cred.locked() {
+		return "", true, nil
+	}
+
+	updated := cred.clone()
+	updated.FailedAttempts++
+	var code string
+	if maxAttempts > 0 && updated.FailedAttempts >= maxAttempts {
+		var err error
+		updated.LockedAt = c.now()
+		if code, err = updated.newCode(codeUnlock, updated.LockedAt.Add(unlockTTL)); err != nil {
+			return "", false, err
+		}
+	}
+	if err := c.saveLocked(c.customers[id], updated); err != nil {
+		return "", false, err
+	}
+	return code, updated.locked(), nil
+}
+
+// recordLogin clears the wrong passwords counted for the customer after the
+// right one was given, and replaces their password hash unless rehash is
+// empty
+func (c *Customers) recordLogin(id, rehash string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cred, ok := c.credentials[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrCustomerNotFound, id)
+	}
+	if cred.FailedAttempts == 0 && rehash == "" {
+		return nil
+	}
+	updated := cred.clone()
+	updated.FailedAttempts = 0
+	if rehash != "" {
+		updated.Hash = rehash
+	}
+	return c.saveLocked(c.customers[id], updated)
+}
+
+// issueCode replaces the code for purpose of the account registered with an
+// email address, returning the customer and the new code. Unlock codes are
+// only issued to locked accounts and verification codes to unverified
+// addresses; ErrCustomerNotFound is returned when no account qualifies.
+func (c *Customers) issueCode(email, purpose string, ttl time.Duration) (*Customer, string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	customer, cred, ok := c.passwordLocked(email)
+	switch {
+	case !ok:
+		return nil, "", fmt.Errorf("%w: no account for %s", ErrCustomerNotFound, email)
+	case purpose == codeUnlock && !cred.locked():
+		return nil, "", fmt.Errorf("%w: no locked account for %s", ErrCustomerNotFound, email)
+	case purpose == codeVerify && customer.EmailVerified:
+		return nil, "", fmt.Errorf("%w: no unverified account for %s", ErrCustomerNotFound, email)
+	}
+
+	updated := cred.clone()
+	code, err := updated.newCode(purpose, c.now().Add(ttl))
+	if err != nil {
+		return nil, "", err
+	}
+	if err := c.saveLocked(customer, updated); err != nil {
+		return nil, "", err
+	}
+	return customer.clone(), code, nil
+}
+
+// redeemCode finds the account a code for purpose was issued to, removes
+// the code, and lets update change the customer and their credential before
+// both are saved. It returns ErrInvalidToken for unknown or expired codes.
+func (c *Customers) redeemCode(purpose, code string, update func(*Customer, *credential)) (*Customer, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	hash := hashToken(code)
+	for id, cred := range c.credentials {
+		issued, ok := cred.Codes[purpose]
+		if !ok || subtle.ConstantTimeCompare([]byte(issued.Hash), []byte(hash)) != 1 {
+			continue
+		}
+		if !c.now().Before(issued.Expires) {
+			return nil, fmt.Errorf("%w: %s code expired", ErrInvalidToken, purpose)
+		}
+
+		customer, updated := c.customers[id].clone(), cred.clone()
+		delete(updated.Codes, purpose)
+		update(customer, updated)
+		if !customerEqual(customer, c.customers[id]) {
+			customer.UpdatedAt = c.now()
+		}
+		if err := c.saveLocked(customer, updated); err != nil {
+			return nil, err
+		}
+		return customer.clone(), nil
+	}
+	return nil, fmt.Errorf("%w: unknown %s code", ErrInvalidToken, purpose)
+}
+
+// unlock unlocks the account an unlock code was issued for, clearing its
+// wrong passwords
+func (c *Customers) unlock(code string) error {
+	_, err := c.redeemCode(codeUnlock, code, func(_ *Customer, cred *credential) {
+		cred.FailedAttempts, cred.LockedAt = 0, time.Time{}
+	})
+	return err
+}
+
+// verifyEmail marks the address a verification code was sent to as verified
+func (c *Customers) verifyEmail(code string) (*Customer, error) {
+	return c.redeemCode(codeVerify, code, func(customer *Customer, _ *credential) {
+		customer.EmailVerified = true
+	})
+}
+
+// resetPassword replaces the password of the account a reset code was issued
+// for with a new hash. Receiving the code proves the customer owns the
+// address, so it is verified and the account unlocked too.
+func (c *Customers) resetPassword(code, hash string) (*Customer, error) {
+	return c.redeemCode(codeReset, code, func(customer *Customer, cred *credential) {
+		customer.EmailVerified = true
+		cred.Hash = hash
+		cred.FailedAttempts, cred.LockedAt = 0, time.Time{}
+		delete(cred.Codes, codeUnlock)
+		delete(cred.Codes, codeVerify)
+	})
+}
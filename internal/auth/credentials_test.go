@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomers_Register(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "customers.json")
+	customers, err := LoadCustomers(path)
+	require.NoError(t, err)
+
+	jane, err := customers.Register("jane@example.com", "Jane", "hash")
+	require.NoError(t, err)
+	assert.False(t, jane.EmailVerified)
+	assert.Equal(t, []Identity{{Provider: ProviderPassword, Subject: "jane@example.com"}}, jane.Identities)
+
+	_, err = customers.Register("jane@example.com", "Other Jane", "hash")
+	assert.True(t, errors.Is(err, ErrAccountExists))
+
+	// Addresses of customers signed in with a provider are taken too
+	_, err = customers.SignIn(&Profile{Identity: Identity{Provider: ProviderGoogle, Subject: "g-1"}, Email: "john@example.com", EmailVerified: true})
+	require.NoError(t, err)
+	_, err = customers.Register("john@example.com", "", "hash")
+	assert.True(t, errors.Is(err, ErrAccountExists))
+
+	// Credentials are persisted with the profiles
+	reloaded, err := LoadCustomers(path)
+	require.NoError(t, err)
+	customer, cred, ok := reloaded.password("jane@example.com")
+	require.True(t, ok)
+	assert.Equal(t, jane.ID, customer.ID)
+	assert.Equal(t, "hash", cred.Hash)
+}
+
+func TestCustomers_Lockout(t *testing.T) {
+	customers, err := LoadCustomers("")
+	require.NoError(t, err)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	customers.now = func() time.Time { return now }
+	jane, err := customers.Register("jane@example.com", "Jane", "hash")
+	require.NoError(t, err)
+
+	code, locked, err := customers.recordFailedLogin(jane.ID, 2, time.Hour)
+	require.NoError(t, err)
+	assert.False(t, locked)
+	assert.Empty(t, code)
+
+	// A right password resets the count
+	require.NoError(t, customers.recordLogin(jane.ID, "rehashed"))
+	_, cred, _ := customers.password("jane@example.com")
+	assert.Equal(t, 0, cred.FailedAttempts)
+	assert.Equal(t, "rehashed", cred.Hash)
+
+	customers.recordFailedLogin(jane.ID, 2, time.Hour)
+	code, locked, err = customers.recordFailedLogin(jane.ID, 2, time.Hour)
+	require.NoError(t, err)
+	assert.True(t, locked)
+	require.NotEmpty(t, code)
+
+	// Further attempts report the lock without issuing new codes
+	again, locked, err := customers.recordFailedLogin(jane.ID, 2, time.Hour)
+	require.NoError(t, err)
+	assert.True(t, locked)
+	assert.Empty(t, again)
+
+	t.Run("wrong code", func(t *testing.T) {
+		assert.True(t, errors.Is(customers.unlock("wrong"), ErrInvalidToken))
+	})
+
+	t.Run("expired code", func(t *testing.T) {
+		now = now.Add(time.Hour)
+		defer func() { now = now.Add(-time.Hour) }()
+		assert.True(t, errors.Is(customers.unlock(code), ErrInvalidToken))
+	})
+
+	t.Run("new code replaces the old one", func(t *testing.T) {
+		_, _, err := customers.issueCode("nobody@example.com", codeUnlock, time.Hour)
+		assert.True(t, errors.Is(err, ErrCustomerNotFound))
+
+		customer, fresh, err := customers.issueCode("jane@example.com", codeUnlock, time.Hour)
+		require.NoError(t, err)
+		assert.Equal(t, jane.ID, customer.ID)
+		assert.True(t, errors.Is(customers.unlock(code), ErrInvalidToken))
+		code = fresh
+	})
+
+	require.NoError(t, customers.unlock(code))
+	_, cred, _ = customers.password("jane@example.com")
+	assert.False(t, cred.locked())
+	assert.Equal(t, 0, cred.FailedAttempts)
+	assert.Equal(t, "rehashed", cred.Hash)
+
+	// Codes work once, and accounts that are not locked get none
+	assert.True(t, errors.Is(customers.unlock(code), ErrInvalidToken))
+	_, _, err = customers.issueCode("jane@example.com", codeUnlock, time.Hour)
+	assert.True(t, errors.Is(err, ErrCustomerNotFound))
+}
+
+func TestCustomers_NoLockout(t *testing.T) {
+	customers, err := LoadCustomers("")
+	require.NoError(t, err)
+	jane, err := customers.Register("jane@example.com", "Jane", "hash")
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		_, locked, err := customers.recordFailedLogin(jane.ID, 0, time.Hour)
+		require.NoError(t, err)
+		assert.False(t, locked)
+	}
+}
+
+func TestCustomers_VerifyEmail(t *testing.T) {
+	customers, err := LoadCustomers("")
+	require.NoError(t, err)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	customers.now = func() time.Time { return now }
+	jane, err := customers.Register("jane@example.com", "Jane", "hash")
+	require.NoError(t, err)
+
+	_, code, err := customers.issueCode("jane@example.com", codeVerify, time.Hour)
+	require.NoError(t, err)
+
+	// Codes only redeem for their own purpose
+	assert.True(t, errors.Is(customers.unlock(code), ErrInvalidToken))
+
+	now = now.Add(time.Minute)
+	verified, err := customers.verifyEmail(code)
+	require.NoError(t, err)
+	assert.Equal(t, jane.ID, verified.ID)
+	assert.True(t, verified.EmailVerified)
+	assert.Equal(t, now, verified.UpdatedAt)
+
+	_, err = customers.verifyEmail(code)
+	assert.True(t, errors.Is(err, ErrInvalidToken))
+	_, _, err = customers.issueCode("jane@example.com", codeVerify, time.Hour)
+	assert.True(t, errors.Is(err, ErrCustomerNotFound))
+}
+
+func TestCustomers_ResetPassword(t *testing.T) {
+	customers, err := LoadCustomers(filepath.Join(t.TempDir(), "customers.json"))
+	require.NoError(t, err)
+	jane, err := customers.Register("jane@example.com", "Jane", "hash")
+	require.NoError(t, err)
+	unlockCode, locked, err := customers.recordFailedLogin(jane.ID, 1, time.Hour)
+	require.NoError(t, err)
+	require.True(t, locked)
+	_, verifyCode, err := customers.issueCode("jane@example.com", codeVerify, time.Hour)
+	require.NoError(t, err)
+
+	_, code, err := customers.issueCode("jane@example.com", codeReset, time.Hour)
+	require.NoError(t, err)
+	reset, err := customers.resetPassword(code, "new hash")
+	require.NoError(t, err)
+	assert.True(t, reset.EmailVerified)
+
+	_, cred, _ := customers.password("jane@example.com")
+	assert.Equal(t, "new hash", cred.Hash)
+	assert.False(t, cred.locked())
+	assert.Empty(t, cred.Codes)
+
+	// Codes issued before the reset are spent with it
+	assert.True(t, errors.Is(customers.unlock(unlockCode), ErrInvalidToken))
+	_, err = customers.verifyEmail(verifyCode)
+	assert.True(t, errors.Is(err, ErrInvalidToken))
+	_, err = customers.resetPassword(code, "newer hash")
+	assert.True(t, errors.Is(err, ErrInvalidToken))
+
+	// The new password survives a reload, and codes are persisted by hash only
+	reloaded, err := LoadCustomers(customers.path)
+	require.NoError(t, err)
+	_, cred, _ = reloaded.password("jane@example.com")
+	assert.Equal(t, "new hash", cred.Hash)
+	raw, err := os.ReadFile(customers.path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), unlockCode)
+	assert.NotContains(t, string(raw), verifyCode)
+}
@@ -0,0 +1,283 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Customer is the profile of a customer who signed in
+type Customer struct {
+	// The unique identifier of the customer
+	// @example customer-0000-0000-0000-0000
+	ID string `json:"id"`
+
+	// The customer's email address, as asserted by their identity provider
+	// @example jane@example.com
+	Email string `json:"email,omitempty"`
+
+	// Whether the identity provider verified the email address
+	// @example true
+	EmailVerified bool `json:"email_verified"`
+
+	// The customer's name
+	// @example Jane Doe
+	Name string `json:"name,omitempty"`
+
+	// The identity provider accounts the customer signs in with
+	Identities []Identity `json:"identities"`
+
+	// The staff roles granted to the customer, on top of the customer role
+	// @example ["kitchen"]
+	Roles []string `json:"roles,omitempty"`
+
+	// The timestamp when the customer first signed in
+	// @example 2024-01-01T00:00:00Z
+	CreatedAt time.Time `json:"created_at"`
+
+	// The timestamp when the profile last changed
+	// @example 2024-01-01T00:00:00Z
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// clone returns a copy of c that shares no slices with it
+func (c *Customer) clone() *Customer {
+	out := *c
+	out.Identities = slices.Clone(c.Identities)
+	out.Roles = slices.Clone(c.Roles)
+	return &out
+}
+
+// roles returns every role the customer holds
+func (c *Customer) roles() []string {
+	return append([]string{RoleCustomer}, c.Roles...)
+}
+
+// customersFile is the persisted form of Customers
+type customersFile struct {
+	Customers   []*Customer            `json:"customers"`
+	Credentials map[string]*credential `json:"credentials,omitempty"` // By customer ID
+	Favorites   map[string]favorites   `json:"favorites,omitempty"`   // By customer ID
+}
+
+// Customers holds customer profiles and passwords, persisted to a JSON file
+type Customers struct {
+	mu          sync.RWMutex
+	path        string
+	customers   map[string]*Customer
+	credentials map[string]*credential
+	favorites   map[string]favorites // By customer ID
+	now         func() time.Time
+}
+
+// LoadCustomers reads the profiles persisted at path. A missing file holds
+// no customers, and an empty path keeps profiles in memory only.
+func LoadCustomers(path string) (*Customers, error) {
+	c := &Customers{
+		path:        path,
+		customers:   make(map[string]*Customer),
+		credentials: make(map[string]*credential),
+		favorites:   make(map[string]favorites),
+		now:         time.Now,
+	}
+	if path == "" {
+		return c, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read customers: %w", err)
+	}
+	var f customersFile
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse customers: %w", err)
+	}
+	for _, customer := range f.Customers {
+		c.customers[customer.ID] = customer
+	}
+	for id, cred := range f.Credentials {
+		c.credentials[id] = cred
+	}
+	for id, saved := range f.Favorites {
+		c.favorites[id] = saved
+	}
+	return c, nil
+}
+
+// Get returns the customer with the given ID
+func (c *Customers) Get(id string) (*Customer, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	customer, ok := c.customers[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrCustomerNotFound, id)
+	}
+	return customer.clone(), nil
+}
+
+// SignIn returns the customer a verified profile belongs to, refreshing their
+// email address and name from it. An identity seen for the first time is
+// linked to the customer with the same verified email address, or else
+// creates a new customer.
+func (c *Customers) SignIn(profile *Profile) (*Customer, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	customer := c.findLocked(profile)
+	var updated *Customer
+	if customer == nil {
+		updated = &Customer{
+			ID:        fmt.Sprintf("customer-%s", uuid.New().String()),
+			CreatedAt: now,
+		}
+	} else {
+		updated = customer.clone()
+	}
+
+	if !slices.Contains(updated.Identities, profile.Identity) {
+		updated.Identities = append(updated.Identities, profile.Identity)
+	}
+	if profile.Email != "" && (profile.EmailVerified || !updated.EmailVerified) {
+		updated.Email, updated.EmailVerified = profile.Email, profile.EmailVerified
+	}
+	if profile.Name != "" {
+		updated.Name = profile.Name
+	}
+	if customer != nil && customerEqual(customer, updated) {
+		return customer.clone(), nil
+	}
+	updated.UpdatedAt = now
+
+	if err := c.saveLocked(updated, nil); err != nil {
+		return nil, err
+	}
+	return updated.clone(), nil
+}
+
+// SetRoles replaces the staff roles granted to a customer
+func (c *Customers) SetRoles(id string, roles []string) (*Customer, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	customer, ok := c.customers[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrCustomerNotFound, id)
+	}
+	updated := customer.clone()
+	updated.Roles = roles
+	if customerEqual(customer, updated) {
+		return customer.clone(), nil
+	}
+	updated.UpdatedAt = c.now()
+	if err := c.saveLocked(updated, nil); err != nil {
+		return nil, err
+	}
+	return updated.clone(), nil
+}
+
+// findLocked returns the customer with the profile's identity or, failing
+// that, its verified email address. Callers must hold c.mu.
+func (c *Customers) findLocked(profile *Profile) *Customer {
+	var byEmail *Customer
+	for _, customer := range c.customers {
+		if slices.Contains(customer.Identities, profile.Identity) {
+			return customer
+		}
+		if profile.EmailVerified && profile.Email != "" && customer.EmailVerified && customer.Email == profile.Email {
+			byEmail = customer
+		}
+	}
+	return byEmail
+}
+
+// saveLocked stores customer and, unless nil, the customer's credential, and
+// persists every profile, writing to a temporary file first so profiles are
+// never left half-written. Callers must hold c.mu for writing.
+func (c *Customers) saveLocked(customer *Customer, cred *credential) error {
+	customers := make(map[string]*Customer, len(c.customers)+1)
+	for id, existing := range c.customers {
+		customers[id] = existing
+	}
+	customers[customer.ID] = customer
+	credentials := make(map[string]*credential, len(c.credentials)+1)
+	for id, existing := range c.credentials {
+		credentials[id] = existing
+	}
+	if cred != nil {
+		credentials[customer.ID] = cred
+	}
+
+	if err := c.writeLocked(customers, credentials, c.favorites); err != nil {
+		return err
+	}
+	c.customers = customers
+	c.credentials = credentials
+	return nil
+}
+
+// writeLocked persists the given profiles, credentials and favorites, unless
+// profiles are kept in memory only. Callers must hold c.mu for writing.
+func (c *Customers) writeLocked(customers map[string]*Customer, credentials map[string]*credential, saved map[string]favorites) error {
+	if c.path == "" {
+		return nil
+	}
+
+	f := customersFile{Customers: make([]*Customer, 0, len(customers)), Credentials: credentials, Favorites: saved}
+	for _, existing := range customers {
+		f.Customers = append(f.Customers, existing)
+	}
+	sort.Slice(f.Customers, func(i, j int) bool {
+		if !f.Customers[i].CreatedAt.Equal(f.Customers[j].CreatedAt) {
+			return f.Customers[i].CreatedAt.Before(f.Customers[j].CreatedAt)
+		}
+		return f.Customers[i].ID < f.Customers[j].ID
+	})
+	if err := writeJSONFile(c.path, f); err != nil {
+		return fmt.Errorf("failed to save customers: %w", err)
+	}
+	return nil
+}
+
+// customerEqual reports whether two profiles have the same content
+func customerEqual(a, b *Customer) bool {
+	return a.Email == b.Email && a.EmailVerified == b.EmailVerified && a.Name == b.Name &&
+		slices.Equal(a.Identities, b.Identities) && slices.Equal(a.Roles, b.Roles)
+}
+
+// writeJSONFile writes v to path as indented JSON, through a temporary file
+// renamed into place
+func writeJSONFile(path string, v interface{}) error {
+	raw, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomers_SignIn(t *testing.T) {
+	customers, err := LoadCustomers("")
+	require.NoError(t, err)
+
+	google := &Profile{
+		Identity:      Identity{Provider: ProviderGoogle, Subject: "g-1"},
+		Email:         "jane@example.com",
+		EmailVerified: true,
+		Name:          "Jane Doe",
+	}
+	jane, err := customers.SignIn(google)
+	require.NoError(t, err)
+	assert.Equal(t, "jane@example.com", jane.Email)
+	assert.Equal(t, []Identity{google.Identity}, jane.Identities)
+
+	t.Run("same identity", func(t *testing.T) {
+		again, err := customers.SignIn(google)
+		require.NoError(t, err)
+		assert.Equal(t, jane, again)
+	})
+
+	t.Run("linked by verified email", func(t *testing.T) {
+		apple := &Profile{Identity: Identity{Provider: ProviderApple, Subject: "a-1"}, Email: "jane@example.com", EmailVerified: true}
+		linked, err := customers.SignIn(apple)
+		require.NoError(t, err)
+		assert.Equal(t, jane.ID, linked.ID)
+		assert.Equal(t, []Identity{google.Identity, apple.Identity}, linked.Identities)
+		assert.Equal(t, "Jane Doe", linked.Name)
+	})
+
+	t.Run("unverified email is not linked", func(t *testing.T) {
+		other := &Profile{Identity: Identity{Provider: ProviderApple, Subject: "a-2"}, Email: "jane@example.com"}
+		customer, err := customers.SignIn(other)
+		require.NoError(t, err)
+		assert.NotEqual(t, jane.ID, customer.ID)
+		assert.False(t, customer.EmailVerified)
+	})
+
+	t.Run("profile refreshed", func(t *testing.T) {
+		renamed := *google
+		renamed.Name = "Jane Smith"
+		customer, err := customers.SignIn(&renamed)
+		require.NoError(t, err)
+		assert.Equal(t, "Jane Smith", customer.Name)
+	})
+
+	_, err = customers.Get("missing")
+	assert.True(t, errors.Is(err, ErrCustomerNotFound))
+}
+
+func TestLoadCustomers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "customers.json")
+
+	customers, err := LoadCustomers(path)
+	require.NoError(t, err)
+	jane, err := customers.SignIn(&Profile{Identity: Identity{Provider: ProviderGoogle, Subject: "g-1"}, Name: "Jane"})
+	require.NoError(t, err)
+
+	reloaded, err := LoadCustomers(path)
+	require.NoError(t, err)
+	got, err := reloaded.Get(jane.ID)
+	require.NoError(t, err)
+	assert.Equal(t, jane.Name, got.Name)
+	assert.True(t, jane.CreatedAt.Equal(got.CreatedAt))
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"customers":`), 0600))
+	_, err = LoadCustomers(path)
+	assert.Error(t, err)
+}
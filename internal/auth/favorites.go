@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// MaxFavorites is how many products a customer can save at each restaurant
+const MaxFavorites = 100
+
+// favorites holds the IDs of the products a customer saved, by restaurant,
+// in the order they were saved
+type favorites map[string][]string
+
+// Favorites returns the IDs of the products a customer saved at a
+// restaurant, in the order they were saved
+func (c *Customers) Favorites(customerID, tenantID string) ([]string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if _, ok := c.customers[customerID]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrCustomerNotFound, customerID)
+	}
+	return slices.Clone(c.favorites[customerID][tenantID]), nil
+}
+
+// AddFavorite saves a product as a favorite of a customer at a restaurant,
+// after the ones saved before. Saving a favorite again changes nothing. It
+// returns ErrTooManyFavorites when the customer already saved MaxFavorites
+// there.
+func (c *Customers) AddFavorite(customerID, tenantID, productID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.customers[customerID]; !ok {
+		return fmt.Errorf("%w: %s", ErrCustomerNotFound, customerID)
+	}
+	saved := c.favorites[customerID][tenantID]
+	if slices.Contains(saved, productID) {
+		return nil
+	}
+	if len(saved) >= MaxFavorites {
+		return fmt.Errorf("%w: %d saved", ErrTooManyFavorites, len(saved))
+	}
+	return c.saveFavoritesLocked(customerID, tenantID, append(slices.Clone(saved), productID))
+}
+
+// RemoveFavorite forgets a product a customer saved at a restaurant.
+// Removing a product that is not saved changes nothing.
+func (c *Customers) RemoveFavorite(customerID, tenantID, productID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.customers[customerID]; !ok {
+		return fmt.Errorf("%w: %s", ErrCustomerNotFound, customerID)
+	}
+	saved := c.favorites[customerID][tenantID]
+	if !slices.Contains(saved, productID) {
+		return nil
+	}
+	return c.saveFavoritesLocked(customerID, tenantID, slices.DeleteFunc(slices.Clone(saved), func(id string) bool { return id == productID }))
+}
+
+// saveFavoritesLocked replaces the favorites of a customer at a restaurant
+// and persists them. Callers must hold c.mu for writing.
+func (c *Customers) saveFavoritesLocked(customerID, tenantID string, saved []string) error {
+	byTenant := maps.Clone(c.favorites[customerID])
+	if byTenant == nil {
+		byTenant = make(favorites)
+	}
+	if len(saved) == 0 {
+		delete(byTenant, tenantID)
+	} else {
+		byTenant[tenantID] = saved
+	}
+
+	all := maps.Clone(c.favorites)
+	if len(byTenant) == 0 {
+		delete(all, customerID)
+	} else {
+		all[customerID] = byTenant
+	}
+
+	if err := c.writeLocked(c.customers, c.credentials, all); err != nil {
+		return err
+	}
+	c.favorites = all
+	return nil
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomers_Favorites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "customers.json")
+	customers, err := LoadCustomers(path)
+	require.NoError(t, err)
+	jane, err := customers.SignIn(&Profile{Identity: Identity{Provider: ProviderGoogle, Subject: "g-1"}, Name: "Jane"})
+	require.NoError(t, err)
+
+	// Favorites are kept in the order saved, once each, per restaurant
+	require.NoError(t, customers.AddFavorite(jane.ID, "default", "prod-2"))
+	require.NoError(t, customers.AddFavorite(jane.ID, "default", "prod-1"))
+	require.NoError(t, customers.AddFavorite(jane.ID, "default", "prod-2"))
+	require.NoError(t, customers.AddFavorite(jane.ID, "cafe", "prod-9"))
+	saved, err := customers.Favorites(jane.ID, "default")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"prod-2", "prod-1"}, saved)
+
+	require.NoError(t, customers.RemoveFavorite(jane.ID, "default", "prod-2"))
+	require.NoError(t, customers.RemoveFavorite(jane.ID, "default", "missing"))
+
+	// Favorites are persisted with the profiles
+	reloaded, err := LoadCustomers(path)
+	require.NoError(t, err)
+	saved, err = reloaded.Favorites(jane.ID, "default")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"prod-1"}, saved)
+	saved, err = reloaded.Favorites(jane.ID, "cafe")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"prod-9"}, saved)
+
+	_, err = customers.Favorites("missing", "default")
+	assert.ErrorIs(t, err, ErrCustomerNotFound)
+	assert.ErrorIs(t, customers.AddFavorite("missing", "default", "prod-1"), ErrCustomerNotFound)
+}
+
+func TestCustomers_TooManyFavorites(t *testing.T) {
+	customers, err := LoadCustomers("")
+	require.NoError(t, err)
+	jane, err := customers.SignIn(&Profile{Identity: Identity{Provider: ProviderGoogle, Subject: "g-1"}})
+	require.NoError(t, err)
+
+	for i := range MaxFavorites {
+		require.NoError(t, customers.AddFavorite(jane.ID, "default", fmt.Sprintf("prod-%d", i)))
+	}
+	assert.ErrorIs(t, customers.AddFavorite(jane.ID, "default", "one-more"), ErrTooManyFavorites)
+	assert.NoError(t, customers.AddFavorite(jane.ID, "default", "prod-0"), "saving a favorite again is not one more")
+	assert.NoError(t, customers.AddFavorite(jane.ID, "cafe", "one-more"))
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// JWT signing algorithms
+const (
+	algHS256 = "HS256" // Customer session tokens
+	algRS256 = "RS256" // ID tokens of Google and Apple
+)
+
+// jwtHeader is the header of a JSON Web Token
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// jwt is a JSON Web Token split into its parts
+type jwt struct {
+	header       jwtHeader
+	payload      []byte
+	signingInput string
+	signature    []byte
+}
+
+// signHS256 encodes claims as a JWT signed with key
+func signHS256(claims interface{}, key []byte) (string, error) {
+	header, err := json.Marshal(jwtHeader{Alg: algHS256, Typ: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := encodeSegment(header) + "." + encodeSegment(payload)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(signingInput))
+	return signingInput + "." + encodeSegment(mac.Sum(nil)), nil
+}
+
+// parseJWT splits a compact JWT into its parts without verifying it
+func parseJWT(token string) (*jwt, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	rawHeader, err := decodeSegment(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidToken)
+	}
+	var header jwtHeader
+	if err := json.Unmarshal(rawHeader, &header); err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidToken)
+	}
+	payload, err := decodeSegment(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed payload", ErrInvalidToken)
+	}
+	signature, err := decodeSegment(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalidToken)
+	}
+
+	return &jwt{
+		header:       header,
+		payload:      payload,
+		signingInput: parts[0] + "." + parts[1],
+		signature:    signature,
+	}, nil
+}
+
+// verifyHS256 checks that the token was signed with key
+func (t *jwt) verifyHS256(key []byte) error {
+	if t.header.Alg != algHS256 {
+		return fmt.Errorf("%w: unexpected algorithm %q", ErrInvalidToken, t.header.Alg)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(t.signingInput))
+	if !hmac.Equal(t.signature, mac.Sum(nil)) {
+		return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+	return nil
+}
+
+// verifyRS256 checks that the token was signed with the private half of key
+func (t *jwt) verifyRS256(key *rsa.PublicKey) error {
+	if t.header.Alg != algRS256 {
+		return fmt.Errorf("%w: unexpected algorithm %q", ErrInvalidToken, t.header.Alg)
+	}
+	digest := sha256.Sum256([]byte(t.signingInput))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], t.signature); err != nil {
+		return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+	return nil
+}
+
+// encodeSegment encodes a JWT segment as unpadded base64url
+func encodeSegment(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeSegment decodes an unpadded base64url JWT segment
+func decodeSegment(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
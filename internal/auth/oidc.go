@@ -0,0 +1,260 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+)
+
+// Identity provider names
+const (
+	ProviderGoogle   = "google"
+	ProviderApple    = "apple"
+	ProviderPassword = "password" // Registered with an email address and password; the subject is the address
+)
+
+const (
+	// clockSkew is how far the provider's clock may be ahead of ours
+	clockSkew = time.Minute
+	// keysMaxAge is how long fetched signing keys are used before refetching
+	keysMaxAge = time.Hour
+	// keysMinInterval is how often an unknown key ID may trigger a refetch
+	keysMinInterval = time.Minute
+	// fetchTimeout is how long to wait for a provider's signing keys
+	fetchTimeout = 10 * time.Second
+)
+
+// Identity is an account at an identity provider
+type Identity struct {
+	// The identity provider: google or apple
+	// @example google
+	Provider string `json:"provider"`
+
+	// The provider's ID of the account
+	// @example 110169484474386276334
+	Subject string `json:"subject"`
+}
+
+// Profile is what an identity provider asserts about a signed-in user
+type Profile struct {
+	Identity
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Provider verifies the ID tokens of an OpenID Connect identity provider
+type Provider struct {
+	name      string
+	issuer    string
+	clientIDs []string
+	keys      *keySet
+	now       func() time.Time
+}
+
+// NewProvider creates the Provider described by cfg. It returns nil when the
+// provider has no client IDs and is disabled.
+func NewProvider(name string, cfg config.OIDCProvider) *Provider {
+	if len(cfg.ClientIDs) == 0 {
+		return nil
+	}
+	return &Provider{
+		name:      name,
+		issuer:    cfg.Issuer,
+		clientIDs: cfg.ClientIDs,
+		keys: &keySet{
+			url:    cfg.JWKSURL,
+			client: &http.Client{Timeout: fetchTimeout},
+			now:    time.Now,
+		},
+		now: time.Now,
+	}
+}
+
+// idClaims are the claims of an ID token the provider verifies
+type idClaims struct {
+	Issuer        string   `json:"iss"`
+	Subject       string   `json:"sub"`
+	Audience      audience `json:"aud"`
+	ExpiresAt     int64    `json:"exp"`
+	IssuedAt      int64    `json:"iat"`
+	Email         string   `json:"email"`
+	EmailVerified flexBool `json:"email_verified"`
+	Name          string   `json:"name"`
+}
+
+// Verify checks an ID token's signature, issuer, audience and expiry and
+// returns the profile it asserts. It returns ErrInvalidToken for tokens that
+// are not valid, and ErrProviderUnavailable when the provider's signing keys
+// cannot be fetched.
+func (p *Provider) Verify(ctx context.Context, idToken string) (*Profile, error) {
+	parsed, err := parseJWT(idToken)
+	if err != nil {
+		return nil, err
+	}
+	key, err := p.keys.key(ctx, parsed.header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := parsed.verifyRS256(key); err != nil {
+		return nil, err
+	}
+
+	var claims idClaims
+	if err := json.Unmarshal(parsed.payload, &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed claims", ErrInvalidToken)
+	}
+	// Google also issues tokens naming itself without the scheme
+	if strings.TrimPrefix(claims.Issuer, "https://") != strings.TrimPrefix(p.issuer, "https://") {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, claims.Issuer)
+	}
+	if !slices.ContainsFunc(claims.Audience, func(aud string) bool { return slices.Contains(p.clientIDs, aud) }) {
+		return nil, fmt.Errorf("%w: not issued for this client", ErrInvalidToken)
+	}
+	now := p.now()
+	if !now.Add(-clockSkew).Before(time.Unix(claims.ExpiresAt, 0)) {
+		return nil, fmt.Errorf("%w: expired", ErrInvalidToken)
+	}
+	if now.Add(clockSkew).Before(time.Unix(claims.IssuedAt, 0)) {
+		return nil, fmt.Errorf("%w: issued in the future", ErrInvalidToken)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+
+	return &Profile{
+		Identity:      Identity{Provider: p.name, Subject: claims.Subject},
+		Email:         strings.ToLower(claims.Email),
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          claims.Name,
+	}, nil
+}
+
+// audience is the aud claim, which is a single string or an array
+type audience []string
+
+// UnmarshalJSON implements json.Unmarshaler
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// flexBool is a boolean claim Apple sends as the string "true" or "false"
+type flexBool bool
+
+// UnmarshalJSON implements json.Unmarshaler
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	var value bool
+	if err := json.Unmarshal(data, &value); err == nil {
+		*b = flexBool(value)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*b = flexBool(s == "true")
+	return nil
+}
+
+// keySet caches the RSA signing keys a provider publishes as a JWK set
+type keySet struct {
+	url    string
+	client *http.Client
+	now    func() time.Time
+
+	mu      sync.Mutex
+	keys    map[string]*rsa.PublicKey
+	fetched time.Time
+}
+
+// jwk is the part of a JSON Web Key the key set reads
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// key returns the signing key with the given ID, fetching the key set when
+// it is stale or the ID is unknown, as happens after the provider rotates keys
+func (k *keySet) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := k.now()
+	key, ok := k.keys[kid]
+	stale := now.Sub(k.fetched) > keysMaxAge
+	if stale || (!ok && now.Sub(k.fetched) > keysMinInterval) {
+		keys, err := k.fetch(ctx)
+		if err != nil {
+			return nil, err
+		}
+		k.keys, k.fetched = keys, now
+		key, ok = k.keys[kid]
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidToken, kid)
+	}
+	return key, nil
+}
+
+// fetch downloads and parses the key set, skipping keys that are not RSA
+// signing keys
+func (k *keySet) fetch(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrProviderUnavailable, err)
+	}
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrProviderUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: signing keys returned %s", ErrProviderUnavailable, resp.Status)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("%w: malformed signing keys: %v", ErrProviderUnavailable, err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, key := range set.Keys {
+		if key.Kty != "RSA" || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+		n, errN := decodeSegment(key.N)
+		e, errE := decodeSegment(key.E)
+		if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+			continue
+		}
+		keys[key.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
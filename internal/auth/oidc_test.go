@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewProvider_Disabled(t *testing.T) {
+	assert.Nil(t, NewProvider(ProviderGoogle, config.OIDCProvider{Issuer: "https://accounts.google.com"}))
+}
+
+func TestProvider_Verify(t *testing.T) {
+	issuer := testutil.NewIdentityProvider(t)
+	provider := NewProvider(ProviderGoogle, issuer.Config())
+	ctx := context.Background()
+
+	t.Run("valid token", func(t *testing.T) {
+		profile, err := provider.Verify(ctx, issuer.Mint(t, issuer.Claims("user-1", nil)))
+		require.NoError(t, err)
+		assert.Equal(t, &Profile{
+			Identity:      Identity{Provider: ProviderGoogle, Subject: "user-1"},
+			Email:         "jane@example.com",
+			EmailVerified: true,
+			Name:          "Jane Doe",
+		}, profile)
+	})
+
+	t.Run("audience array and string booleans", func(t *testing.T) {
+		profile, err := provider.Verify(ctx, issuer.Mint(t, issuer.Claims("user-1", map[string]interface{}{
+			"aud":            []string{"other-client", "ios-client"},
+			"iss":            strings.TrimPrefix(testutil.IdentityIssuer, "https://"),
+			"email_verified": "false",
+		})))
+		require.NoError(t, err)
+		assert.False(t, profile.EmailVerified)
+	})
+
+	invalid := []struct {
+		name      string
+		overrides map[string]interface{}
+	}{
+		{name: "wrong issuer", overrides: map[string]interface{}{"iss": "https://evil.example.com"}},
+		{name: "wrong audience", overrides: map[string]interface{}{"aud": "other-client"}},
+		{name: "expired", overrides: map[string]interface{}{"exp": time.Now().Add(-2 * time.Minute).Unix()}},
+		{name: "issued in the future", overrides: map[string]interface{}{"iat": time.Now().Add(time.Hour).Unix()}},
+		{name: "no subject", overrides: map[string]interface{}{"sub": ""}},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := provider.Verify(ctx, issuer.Mint(t, issuer.Claims("user-1", tt.overrides)))
+			assert.True(t, errors.Is(err, ErrInvalidToken), "got %v", err)
+		})
+	}
+
+	t.Run("tampered token", func(t *testing.T) {
+		token := issuer.Mint(t, issuer.Claims("user-1", nil))
+		tampered := token[:len(token)-4] + "AAAA"
+		_, err := provider.Verify(ctx, tampered)
+		assert.True(t, errors.Is(err, ErrInvalidToken))
+	})
+
+	t.Run("session token", func(t *testing.T) {
+		sessions, err := NewSessions("", time.Hour, time.Hour)
+		require.NoError(t, err)
+		session, err := sessions.Issue(&Customer{ID: "customer-1"})
+		require.NoError(t, err)
+		_, err = provider.Verify(ctx, session.AccessToken)
+		assert.True(t, errors.Is(err, ErrInvalidToken))
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		_, err := provider.Verify(ctx, "not-a-token")
+		assert.True(t, errors.Is(err, ErrInvalidToken))
+	})
+}
+
+func TestProvider_KeyRotation(t *testing.T) {
+	issuer := testutil.NewIdentityProvider(t)
+	provider := NewProvider(ProviderApple, issuer.Config())
+	now := time.Now()
+	provider.keys.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, err := provider.Verify(ctx, issuer.Mint(t, issuer.Claims("user-1", nil)))
+	require.NoError(t, err)
+	_, err = provider.Verify(ctx, issuer.Mint(t, issuer.Claims("user-1", nil)))
+	require.NoError(t, err)
+	assert.Equal(t, 1, issuer.Fetches(), "keys are cached")
+
+	// An unknown key ID refetches, but not more than once a minute
+	issuer.Rotate(t)
+	now = now.Add(2 * keysMinInterval)
+	_, err = provider.Verify(ctx, issuer.Mint(t, issuer.Claims("user-1", nil)))
+	require.NoError(t, err)
+	assert.Equal(t, 2, issuer.Fetches())
+
+	issuer.Rotate(t)
+	_, err = provider.Verify(ctx, issuer.Mint(t, issuer.Claims("user-1", nil)))
+	assert.True(t, errors.Is(err, ErrInvalidToken))
+	assert.Equal(t, 2, issuer.Fetches())
+}
+
+func TestProvider_Unavailable(t *testing.T) {
+	issuer := testutil.NewIdentityProvider(t)
+	provider := NewProvider(ProviderGoogle, issuer.Config())
+	token := issuer.Mint(t, issuer.Claims("user-1", nil))
+	issuer.Close()
+
+	_, err := provider.Verify(context.Background(), token)
+	assert.True(t, errors.Is(err, ErrProviderUnavailable), "got %v", err)
+}
@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// argon2idParams are the cost parameters of an argon2id hash
+type argon2idParams struct {
+	memory  uint32 // KiB
+	time    uint32
+	threads uint8
+}
+
+// Cost of new hashes, following the OWASP recommendations. Tests lower them.
+var (
+	argon2idCost = argon2idParams{memory: 64 * 1024, time: 3, threads: 2}
+	bcryptCost   = bcrypt.DefaultCost
+)
+
+const (
+	argon2idSaltLen = 16
+	argon2idKeyLen  = 32
+)
+
+// errMalformedHash is returned for stored hashes in no supported format
+var errMalformedHash = errors.New("malformed password hash")
+
+// hashPassword hashes password with the named algorithm, encoding the
+// algorithm and its parameters along with the hash so it can be checked
+// after the configuration changes
+func hashPassword(password, algorithm string) (string, error) {
+	switch algorithm {
+	case config.PasswordBcrypt:
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
+		if err != nil {
+			return "", fmt.Errorf("failed to hash password: %w", err)
+		}
+		return string(hash), nil
+	case config.PasswordArgon2id:
+		salt := make([]byte, argon2idSaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return "", fmt.Errorf("failed to generate salt: %w", err)
+		}
+		p := argon2idCost
+		key := argon2.IDKey([]byte(password), salt, p.time, p.memory, p.threads, argon2idKeyLen)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.memory, p.time, p.threads,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+	default:
+		return "", fmt.Errorf("unknown password hash %q", algorithm)
+	}
+}
+
+// checkPassword reports whether password matches a hash made by hashPassword
+func checkPassword(hash, password string) (bool, error) {
+	if isBcrypt(hash) {
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	p, salt, key, err := parseArgon2id(hash)
+	if err != nil {
+		return false, err
+	}
+	got := argon2.IDKey([]byte(password), salt, p.time, p.memory, p.threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(got, key) == 1, nil
+}
+
+// needsRehash reports whether a hash was made with another algorithm, or
+// with a lower cost, than new passwords are hashed with
+func needsRehash(hash, algorithm string) bool {
+	switch algorithm {
+	case config.PasswordBcrypt:
+		cost, err := bcrypt.Cost([]byte(hash))
+		return err != nil || cost < bcryptCost
+	case config.PasswordArgon2id:
+		p, _, _, err := parseArgon2id(hash)
+		return err != nil || p.memory < argon2idCost.memory || p.time < argon2idCost.time
+	}
+	return false
+}
+
+// isBcrypt reports whether hash is in the modular crypt format of bcrypt
+func isBcrypt(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+// parseArgon2id decodes a hash in the PHC string format,
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+func parseArgon2id(hash string) (argon2idParams, []byte, []byte, error) {
+	var p argon2idParams
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, nil, nil, errMalformedHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, errMalformedHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil || p.time == 0 || p.threads == 0 {
+		return p, nil, nil, errMalformedHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, errMalformedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, errMalformedHash
+	}
+	return p, salt, key, nil
+}
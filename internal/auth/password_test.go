@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashPassword(t *testing.T) {
+	for _, algorithm := range []string{config.PasswordArgon2id, config.PasswordBcrypt} {
+		t.Run(algorithm, func(t *testing.T) {
+			hash, err := hashPassword("correct horse", algorithm)
+			require.NoError(t, err)
+			assert.NotContains(t, hash, "correct horse")
+
+			ok, err := checkPassword(hash, "correct horse")
+			require.NoError(t, err)
+			assert.True(t, ok)
+			ok, err = checkPassword(hash, "wrong horse")
+			require.NoError(t, err)
+			assert.False(t, ok)
+
+			// Hashes are salted
+			again, err := hashPassword("correct horse", algorithm)
+			require.NoError(t, err)
+			assert.NotEqual(t, hash, again)
+		})
+	}
+
+	_, err := hashPassword("correct horse", "md5")
+	assert.Error(t, err)
+}
+
+func TestHashPassword_Argon2idFormat(t *testing.T) {
+	hash, err := hashPassword("correct horse", config.PasswordArgon2id)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=65536,t=3,p=2$"), hash)
+}
+
+func TestCheckPassword_Malformed(t *testing.T) {
+	for _, hash := range []string{"", "plaintext", "$argon2id$v=19$m=1,t=0,p=1$c2FsdA$a2V5", "$argon2i$v=19$m=1,t=1,p=1$c2FsdA$a2V5", "$2b$10$short"} {
+		ok, err := checkPassword(hash, "correct horse")
+		assert.False(t, ok, hash)
+		assert.Error(t, err, hash)
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	argon, err := hashPassword("correct horse", config.PasswordArgon2id)
+	require.NoError(t, err)
+	bcryptHash, err := hashPassword("correct horse", config.PasswordBcrypt)
+	require.NoError(t, err)
+	cheap, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	assert.False(t, needsRehash(argon, config.PasswordArgon2id))
+	assert.True(t, needsRehash(bcryptHash, config.PasswordArgon2id))
+	assert.False(t, needsRehash(bcryptHash, config.PasswordBcrypt))
+	assert.True(t, needsRehash(argon, config.PasswordBcrypt))
+	assert.True(t, needsRehash(string(cheap), config.PasswordBcrypt))
+}
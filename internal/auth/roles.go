@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Roles of the callers of the API. API keys hold the role they are
+// configured for, and session tokens the roles of the customer they were
+// issued to.
+const (
+	RoleAdmin    = "admin"    // Manages the catalog, the data files and the blocklist; holds every other role
+	RoleKitchen  = "kitchen"  // Works the kitchen queue and moves orders along
+	RoleSupport  = "support"  // Helps customers: moderates reviews and checks coupons
+	RolePOS      = "pos"      // The restaurant's point-of-sale system: syncs stock levels and collects orders
+	RoleCourier  = "courier"  // Delivers orders: reports picking them up, where they are and delivering them
+	RoleCustomer = "customer" // Held by every signed-in customer
+)
+
+// StaffRoles are the roles that can be granted to a customer, on top of the
+// customer role every customer holds
+var StaffRoles = []string{RoleAdmin, RoleKitchen, RoleSupport, RolePOS, RoleCourier}
+
+// ErrUnknownRole is returned when granting a role that is not a staff role
+var ErrUnknownRole = errors.New("unknown role")
+
+// HasRole reports whether a caller holding the held roles holds any of
+// roles. Admins hold every role.
+func HasRole(held []string, roles ...string) bool {
+	if slices.Contains(held, RoleAdmin) {
+		return true
+	}
+	for _, role := range roles {
+		if slices.Contains(held, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateStaffRoles returns roles sorted and without duplicates, or
+// ErrUnknownRole if any of them is not a staff role
+func ValidateStaffRoles(roles []string) ([]string, error) {
+	for _, role := range roles {
+		if !slices.Contains(StaffRoles, role) {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownRole, role)
+		}
+	}
+	out := slices.Clone(roles)
+	slices.Sort(out)
+	return slices.Compact(out), nil
+}
+
+// rolesKey is the context key of the roles the caller holds
+type rolesKey struct{}
+
+// WithRoles returns a copy of ctx carrying the roles the caller holds
+func WithRoles(ctx context.Context, roles []string) context.Context {
+	return context.WithValue(ctx, rolesKey{}, roles)
+}
+
+// RolesFromContext returns the roles carried by ctx, if the caller was
+// authenticated
+func RolesFromContext(ctx context.Context) ([]string, bool) {
+	roles, ok := ctx.Value(rolesKey{}).([]string)
+	return roles, ok
+}
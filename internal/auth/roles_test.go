@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHasRole(t *testing.T) {
+	tests := []struct {
+		name  string
+		held  []string
+		roles []string
+		want  bool
+	}{
+		{name: "held role", held: []string{RoleCustomer, RoleKitchen}, roles: []string{RoleKitchen}, want: true},
+		{name: "any of the roles", held: []string{RoleSupport}, roles: []string{RoleKitchen, RoleSupport}, want: true},
+		{name: "other role", held: []string{RoleCustomer, RoleKitchen}, roles: []string{RoleSupport}, want: false},
+		{name: "admin holds every role", held: []string{RoleAdmin}, roles: []string{RoleKitchen}, want: true},
+		{name: "no roles held", roles: []string{RoleCustomer}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, HasRole(tt.held, tt.roles...))
+		})
+	}
+}
+
+func TestValidateStaffRoles(t *testing.T) {
+	roles, err := ValidateStaffRoles([]string{RoleSupport, RoleKitchen, RoleSupport})
+	require.NoError(t, err)
+	assert.Equal(t, []string{RoleKitchen, RoleSupport}, roles)
+
+	roles, err = ValidateStaffRoles(nil)
+	require.NoError(t, err)
+	assert.Empty(t, roles)
+
+	for _, role := range []string{RoleCustomer, "owner", "Admin"} {
+		_, err = ValidateStaffRoles([]string{role})
+		assert.True(t, errors.Is(err, ErrUnknownRole), role)
+	}
+}
+
+func TestRolesContext(t *testing.T) {
+	_, ok := RolesFromContext(context.Background())
+	assert.False(t, ok)
+
+	roles, ok := RolesFromContext(WithRoles(context.Background(), []string{RoleKitchen}))
+	assert.True(t, ok)
+	assert.Equal(t, []string{RoleKitchen}, roles)
+}
@@ -0,0 +1,306 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// sessionIssuer is the issuer of customer session tokens, so tokens from
+// other systems signed with the same key are not mistaken for sessions
+const sessionIssuer = "oolio-food-ordering"
+
+// TokenTypeBearer is the type of session access tokens
+const TokenTypeBearer = "Bearer"
+
+// SessionClaims are the claims of a customer session token
+type SessionClaims struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`   // The customer ID
+	SessionID string   `json:"sid"`   // Shared by every token issued since sign-in
+	Roles     []string `json:"roles"` // Every role the customer held when the token was issued
+	ID        string   `json:"jti"`
+	IssuedAt  int64    `json:"iat"`
+	ExpiresAt int64    `json:"exp"`
+}
+
+// Session is the access token of a signed-in customer and the refresh token
+// that renews it
+type Session struct {
+	// Token to send in the Authorization header as "Bearer <token>"
+	// @example eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9...
+	AccessToken string `json:"access_token"`
+
+	// Always Bearer
+	// @example Bearer
+	TokenType string `json:"token_type"`
+
+	// Seconds until the access token expires
+	// @example 3600
+	ExpiresIn int `json:"expires_in"`
+
+	// Single-use token that renews the session through POST /auth/refresh
+	// @example 3q2-7wEAAAB4oR8sY0n1dF9Lz8e0vL3nQnq0m5kH2bA
+	RefreshToken string `json:"refresh_token"`
+
+	// Seconds until the refresh token expires
+	// @example 2592000
+	RefreshExpiresIn int `json:"refresh_expires_in"`
+
+	// The signed-in customer
+	Customer *Customer `json:"customer"`
+}
+
+// Revocations records sessions that were ended before their access tokens
+// expired. Servers sharing customer sessions must share their Revocations.
+type Revocations interface {
+	// Revoke rejects the session's access tokens until the given time, by
+	// which they have all expired
+	Revoke(sessionID string, until time.Time)
+	// Revoked reports whether the session was revoked
+	Revoked(sessionID string) bool
+}
+
+// refreshGrant is an issued refresh token, stored by the hash of the token
+type refreshGrant struct {
+	customerID string
+	sessionID  string
+	expiresAt  time.Time
+	used       bool // Set once redeemed; redeeming again ends the session
+}
+
+// Sessions issues and verifies customer session tokens, JWTs signed with
+// HMAC-SHA256, and the refresh tokens that renew them
+type Sessions struct {
+	key         []byte
+	ttl         time.Duration
+	refreshTTL  time.Duration
+	revocations Revocations
+	now         func() time.Time
+
+	mu      sync.Mutex
+	refresh map[string]*refreshGrant
+}
+
+// NewSessions creates a new Sessions signing with secret, with its
+// revocations kept in memory. An empty secret signs with a random key, so
+// sessions end when the server restarts.
+func NewSessions(secret string, ttl, refreshTTL time.Duration) (*Sessions, error) {
+	key := []byte(secret)
+	if secret == "" {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("failed to generate session key: %w", err)
+		}
+	}
+	return &Sessions{
+		key:         key,
+		ttl:         ttl,
+		refreshTTL:  refreshTTL,
+		revocations: NewMemoryRevocations(),
+		now:         time.Now,
+		refresh:     make(map[string]*refreshGrant),
+	}, nil
+}
+
+// Issue starts a new session for customer
+func (s *Sessions) Issue(customer *Customer) (*Session, error) {
+	return s.issue(customer, uuid.New().String())
+}
+
+// Refresh redeems a refresh token for a new access token and refresh token
+// of the same session. Each refresh token is redeemed once: redeeming one
+// again, as happens when a stolen token is replayed, ends the session.
+func (s *Sessions) Refresh(refreshToken string, customers *Customers) (*Session, error) {
+	s.mu.Lock()
+	grant, ok := s.refresh[hashToken(refreshToken)]
+	switch {
+	case !ok || !s.now().Before(grant.expiresAt):
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w: unknown or expired refresh token", ErrInvalidToken)
+	case grant.used:
+		s.revokeLocked(grant.sessionID)
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w: refresh token reused, session ended", ErrInvalidToken)
+	}
+	grant.used = true
+	s.mu.Unlock()
+
+	customer, err := customers.Get(grant.customerID)
+	if err != nil {
+		s.Revoke(grant.sessionID)
+		return nil, fmt.Errorf("%w: customer no longer exists", ErrInvalidToken)
+	}
+	return s.issue(customer, grant.sessionID)
+}
+
+// Revoke ends a session: its refresh tokens are dropped and its access
+// tokens are rejected until they expire
+func (s *Sessions) Revoke(sessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.revokeLocked(sessionID)
+}
+
+// RevokeCustomer ends every session of a customer that can still be
+// refreshed
+func (s *Sessions) RevokeCustomer(customerID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessionIDs := make(map[string]bool)
+	for _, grant := range s.refresh {
+		if grant.customerID == customerID {
+			sessionIDs[grant.sessionID] = true
+		}
+	}
+	for sessionID := range sessionIDs {
+		s.revokeLocked(sessionID)
+	}
+}
+
+// Verify checks a session token's signature and expiry, and that its session
+// was not revoked, and returns its claims
+func (s *Sessions) Verify(token string) (*SessionClaims, error) {
+	parsed, err := parseJWT(token)
+	if err != nil {
+		return nil, err
+	}
+	if err := parsed.verifyHS256(s.key); err != nil {
+		return nil, err
+	}
+
+	var claims SessionClaims
+	if err := json.Unmarshal(parsed.payload, &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed claims", ErrInvalidToken)
+	}
+	if claims.Issuer != sessionIssuer || claims.Subject == "" || claims.SessionID == "" {
+		return nil, fmt.Errorf("%w: not a session token", ErrInvalidToken)
+	}
+	if !s.now().Before(time.Unix(claims.ExpiresAt, 0)) {
+		return nil, fmt.Errorf("%w: expired", ErrInvalidToken)
+	}
+	if s.revocations.Revoked(claims.SessionID) {
+		return nil, fmt.Errorf("%w: session ended", ErrInvalidToken)
+	}
+	return &claims, nil
+}
+
+// issue signs an access token and creates a refresh token for customer's
+// session
+func (s *Sessions) issue(customer *Customer, sessionID string) (*Session, error) {
+	now := s.now()
+	claims := SessionClaims{
+		Issuer:    sessionIssuer,
+		Subject:   customer.ID,
+		SessionID: sessionID,
+		Roles:     customer.roles(),
+		ID:        uuid.New().String(),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(s.ttl).Unix(),
+	}
+	token, err := signHS256(claims, s.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign session: %w", err)
+	}
+
+	refreshToken, err := randomToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	s.mu.Lock()
+	s.pruneLocked(now)
+	s.refresh[hashToken(refreshToken)] = &refreshGrant{
+		customerID: customer.ID,
+		sessionID:  sessionID,
+		expiresAt:  now.Add(s.refreshTTL),
+	}
+	s.mu.Unlock()
+
+	return &Session{
+		AccessToken:      token,
+		TokenType:        TokenTypeBearer,
+		ExpiresIn:        int(s.ttl.Seconds()),
+		RefreshToken:     refreshToken,
+		RefreshExpiresIn: int(s.refreshTTL.Seconds()),
+		Customer:         customer,
+	}, nil
+}
+
+// revokeLocked ends a session. Callers must hold s.mu.
+func (s *Sessions) revokeLocked(sessionID string) {
+	for hash, grant := range s.refresh {
+		if grant.sessionID == sessionID {
+			delete(s.refresh, hash)
+		}
+	}
+	s.revocations.Revoke(sessionID, s.now().Add(s.ttl))
+}
+
+// pruneLocked drops expired refresh tokens. Callers must hold s.mu.
+func (s *Sessions) pruneLocked(now time.Time) {
+	for hash, grant := range s.refresh {
+		if !now.Before(grant.expiresAt) {
+			delete(s.refresh, hash)
+		}
+	}
+}
+
+// randomToken returns an unguessable opaque token
+func randomToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return encodeSegment(raw), nil
+}
+
+// hashToken returns the key an opaque token is stored under, so a leak of
+// the store does not leak usable tokens
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// MemoryRevocations keeps revoked sessions in memory, for a single server
+type MemoryRevocations struct {
+	mu      sync.Mutex
+	revoked map[string]time.Time
+	now     func() time.Time
+}
+
+// NewMemoryRevocations creates a new, empty MemoryRevocations
+func NewMemoryRevocations() *MemoryRevocations {
+	return &MemoryRevocations{revoked: make(map[string]time.Time), now: time.Now}
+}
+
+// Revoke implements Revocations
+func (m *MemoryRevocations) Revoke(sessionID string, until time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	for id, expires := range m.revoked {
+		if !now.Before(expires) {
+			delete(m.revoked, id)
+		}
+	}
+	if until.After(m.revoked[sessionID]) {
+		m.revoked[sessionID] = until
+	}
+}
+
+// Revoked implements Revocations
+func (m *MemoryRevocations) Revoked(sessionID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	until, ok := m.revoked[sessionID]
+	return ok && m.now().Before(until)
+}
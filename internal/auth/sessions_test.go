@@ -0,0 +1,209 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessions(t *testing.T) {
+	sessions, err := NewSessions(strings.Repeat("s", 32), time.Hour, 24*time.Hour)
+	require.NoError(t, err)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	sessions.now = func() time.Time { return now }
+
+	customer := &Customer{ID: "customer-1"}
+	session, err := sessions.Issue(customer)
+	require.NoError(t, err)
+	assert.Equal(t, TokenTypeBearer, session.TokenType)
+	assert.Equal(t, 3600, session.ExpiresIn)
+	assert.Equal(t, 86400, session.RefreshExpiresIn)
+	assert.NotEmpty(t, session.RefreshToken)
+	assert.Same(t, customer, session.Customer)
+
+	claims, err := sessions.Verify(session.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "customer-1", claims.Subject)
+	assert.NotEmpty(t, claims.ID)
+	assert.NotEmpty(t, claims.SessionID)
+
+	t.Run("other key", func(t *testing.T) {
+		other, err := NewSessions(strings.Repeat("o", 32), time.Hour, 24*time.Hour)
+		require.NoError(t, err)
+		_, err = other.Verify(session.AccessToken)
+		assert.True(t, errors.Is(err, ErrInvalidToken))
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		parts := strings.Split(session.AccessToken, ".")
+		forged, err := signHS256(SessionClaims{Issuer: sessionIssuer, Subject: "customer-2", SessionID: claims.SessionID, ExpiresAt: now.Add(time.Hour).Unix()}, []byte("guess"))
+		require.NoError(t, err)
+		forgedParts := strings.Split(forged, ".")
+		_, err = sessions.Verify(parts[0] + "." + forgedParts[1] + "." + parts[2])
+		assert.True(t, errors.Is(err, ErrInvalidToken))
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		parts := strings.Split(session.AccessToken, ".")
+		none := encodeSegment([]byte(`{"alg":"none"}`))
+		_, err := sessions.Verify(none + "." + parts[1] + ".")
+		assert.True(t, errors.Is(err, ErrInvalidToken))
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		now = now.Add(time.Hour)
+		defer func() { now = now.Add(-time.Hour) }()
+		_, err := sessions.Verify(session.AccessToken)
+		assert.True(t, errors.Is(err, ErrInvalidToken))
+	})
+}
+
+func TestNewSessions_RandomKey(t *testing.T) {
+	a, err := NewSessions("", time.Hour, time.Hour)
+	require.NoError(t, err)
+	b, err := NewSessions("", time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	session, err := a.Issue(&Customer{ID: "customer-1"})
+	require.NoError(t, err)
+	_, err = b.Verify(session.AccessToken)
+	assert.True(t, errors.Is(err, ErrInvalidToken))
+}
+
+func newTestSessions(t *testing.T) (*Sessions, *Customers, *time.Time) {
+	sessions, err := NewSessions(strings.Repeat("s", 32), time.Hour, 24*time.Hour)
+	require.NoError(t, err)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	sessions.now = func() time.Time { return now }
+	sessions.revocations.(*MemoryRevocations).now = sessions.now
+
+	customers, err := LoadCustomers("")
+	require.NoError(t, err)
+	return sessions, customers, &now
+}
+
+func TestSessions_Refresh(t *testing.T) {
+	sessions, customers, now := newTestSessions(t)
+	customer, err := customers.SignIn(&Profile{Identity: Identity{Provider: ProviderGoogle, Subject: "g-1"}})
+	require.NoError(t, err)
+
+	session, err := sessions.Issue(customer)
+	require.NoError(t, err)
+	first, err := sessions.Verify(session.AccessToken)
+	require.NoError(t, err)
+
+	*now = now.Add(2 * time.Hour)
+	refreshed, err := sessions.Refresh(session.RefreshToken, customers)
+	require.NoError(t, err)
+	assert.NotEqual(t, session.RefreshToken, refreshed.RefreshToken)
+	assert.Equal(t, customer.ID, refreshed.Customer.ID)
+
+	claims, err := sessions.Verify(refreshed.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, first.SessionID, claims.SessionID)
+
+	// Refresh tokens slide: each refresh is valid for the full TTL again
+	*now = now.Add(23 * time.Hour)
+	refreshed, err = sessions.Refresh(refreshed.RefreshToken, customers)
+	require.NoError(t, err)
+
+	t.Run("expired", func(t *testing.T) {
+		*now = now.Add(24 * time.Hour)
+		defer func() { *now = now.Add(-24 * time.Hour) }()
+		_, err := sessions.Refresh(refreshed.RefreshToken, customers)
+		assert.True(t, errors.Is(err, ErrInvalidToken))
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		_, err := sessions.Refresh("not-a-token", customers)
+		assert.True(t, errors.Is(err, ErrInvalidToken))
+	})
+}
+
+func TestSessions_RefreshReuseEndsSession(t *testing.T) {
+	sessions, customers, _ := newTestSessions(t)
+	customer, err := customers.SignIn(&Profile{Identity: Identity{Provider: ProviderGoogle, Subject: "g-1"}})
+	require.NoError(t, err)
+
+	session, err := sessions.Issue(customer)
+	require.NoError(t, err)
+	refreshed, err := sessions.Refresh(session.RefreshToken, customers)
+	require.NoError(t, err)
+
+	// Replaying a redeemed token ends the session for everyone holding it
+	_, err = sessions.Refresh(session.RefreshToken, customers)
+	assert.True(t, errors.Is(err, ErrInvalidToken))
+	_, err = sessions.Refresh(refreshed.RefreshToken, customers)
+	assert.True(t, errors.Is(err, ErrInvalidToken))
+	_, err = sessions.Verify(refreshed.AccessToken)
+	assert.True(t, errors.Is(err, ErrInvalidToken))
+
+	// Other sessions of the customer are unaffected
+	other, err := sessions.Issue(customer)
+	require.NoError(t, err)
+	_, err = sessions.Verify(other.AccessToken)
+	assert.NoError(t, err)
+}
+
+func TestSessions_Revoke(t *testing.T) {
+	sessions, customers, now := newTestSessions(t)
+	customer := &Customer{ID: "customer-1"}
+
+	session, err := sessions.Issue(customer)
+	require.NoError(t, err)
+	claims, err := sessions.Verify(session.AccessToken)
+	require.NoError(t, err)
+
+	sessions.Revoke(claims.SessionID)
+	_, err = sessions.Verify(session.AccessToken)
+	assert.True(t, errors.Is(err, ErrInvalidToken))
+	_, err = sessions.Refresh(session.RefreshToken, customers)
+	assert.True(t, errors.Is(err, ErrInvalidToken))
+
+	// Revocations are forgotten once the session's access tokens expire
+	*now = now.Add(time.Hour)
+	assert.False(t, sessions.revocations.Revoked(claims.SessionID))
+}
+
+func TestSessions_RevokeCustomer(t *testing.T) {
+	sessions, customers, _ := newTestSessions(t)
+	jane, john := &Customer{ID: "customer-1"}, &Customer{ID: "customer-2"}
+
+	phone, err := sessions.Issue(jane)
+	require.NoError(t, err)
+	laptop, err := sessions.Issue(jane)
+	require.NoError(t, err)
+	other, err := sessions.Issue(john)
+	require.NoError(t, err)
+
+	sessions.RevokeCustomer(jane.ID)
+	for _, session := range []*Session{phone, laptop} {
+		_, err = sessions.Verify(session.AccessToken)
+		assert.True(t, errors.Is(err, ErrInvalidToken))
+		_, err = sessions.Refresh(session.RefreshToken, customers)
+		assert.True(t, errors.Is(err, ErrInvalidToken))
+	}
+	_, err = sessions.Verify(other.AccessToken)
+	assert.NoError(t, err)
+}
+
+func TestMemoryRevocations(t *testing.T) {
+	revocations := NewMemoryRevocations()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	revocations.now = func() time.Time { return now }
+
+	revocations.Revoke("session-1", now.Add(time.Hour))
+	revocations.Revoke("session-1", now.Add(time.Minute))
+	assert.True(t, revocations.Revoked("session-1"))
+	assert.False(t, revocations.Revoked("session-2"))
+
+	// A later revocation prunes expired ones
+	now = now.Add(time.Hour)
+	assert.False(t, revocations.Revoked("session-1"))
+	revocations.Revoke("session-2", now.Add(time.Hour))
+	assert.Len(t, revocations.revoked, 1)
+}
@@ -0,0 +1,351 @@
+// Package blocklist keeps the IP ranges and API keys that are refused
+// access, the ones exempted from refusal, and an audit trail of every
+// change, persisted to a JSON file.
+package blocklist
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Entry kinds
+const (
+	KindBlock = "block" // Refuse matching callers
+	KindAllow = "allow" // Exempt matching callers from block entries
+)
+
+// Entry types
+const (
+	TypeIP     = "ip"      // An IP address or CIDR range
+	TypeAPIKey = "api_key" // An API key, stored by fingerprint
+)
+
+// Audit actions
+const (
+	ActionAdded   = "added"
+	ActionRemoved = "removed"
+)
+
+var (
+	// ErrNotFound is returned when an entry does not exist
+	ErrNotFound = errors.New("entry not found")
+	// ErrExists is returned when an identical entry already exists
+	ErrExists = errors.New("entry already exists")
+	// ErrInvalidEntry is returned for entries with an unknown kind or type,
+	// or a value that does not parse
+	ErrInvalidEntry = errors.New("invalid entry")
+)
+
+// Entry blocks or allows the callers matching its value
+type Entry struct {
+	// The unique identifier of the entry
+	// @example 0b6f8a9e-6d1c-4d5e-9b8a-1f2e3d4c5b6a
+	ID string `json:"id"`
+
+	// Whether matching callers are blocked or allowed: block or allow
+	// @example block
+	Kind string `json:"kind"`
+
+	// What the value matches: ip or api_key
+	// @example ip
+	Type string `json:"type"`
+
+	// The CIDR range of ip entries, or the fingerprint of api_key entries
+	// @example 203.0.113.0/24
+	Value string `json:"value"`
+
+	// Why the entry was added
+	// @example Coupon abuse
+	Reason string `json:"reason,omitempty"`
+
+	// The timestamp when the entry was added
+	// @example 2024-01-01T00:00:00Z
+	CreatedAt time.Time `json:"created_at"`
+
+	prefix netip.Prefix
+}
+
+// EntryRequest represents the request body for adding an entry
+type EntryRequest struct {
+	// Whether matching callers are blocked or allowed: block (default) or allow
+	// @example block
+	Kind string `json:"kind,omitempty" validate:"omitempty,oneof=block allow"`
+
+	// What the value matches: ip or api_key
+	// @required
+	// @example ip
+	Type string `json:"type" validate:"required,oneof=ip api_key"`
+
+	// An IP address or CIDR range for ip entries, or the API key itself for
+	// api_key entries, which is only stored as a fingerprint
+	// @required
+	// @example 203.0.113.0/24
+	Value string `json:"value" validate:"required"`
+
+	// Why the entry is added
+	// @example Coupon abuse
+	Reason string `json:"reason,omitempty" validate:"omitempty,max=200"`
+}
+
+// AuditEvent records a change to the list
+type AuditEvent struct {
+	// What happened to the entry: added or removed
+	// @example added
+	Action string `json:"action"`
+
+	// The entry that changed
+	Entry Entry `json:"entry"`
+
+	// ID of the API key that made the change, or the customer ID of the
+	// signed-in admin who did
+	// @example 9f86d081884c
+	Actor string `json:"actor"`
+
+	// The IP address the change was made from
+	// @example 192.0.2.10
+	IP string `json:"ip"`
+
+	// When the change was made
+	// @example 2024-01-01T00:00:00Z
+	At time.Time `json:"at"`
+}
+
+// file is the persisted form of a List
+type file struct {
+	Entries []Entry      `json:"entries"`
+	Audit   []AuditEvent `json:"audit"`
+}
+
+// List holds the block and allow entries. A nil List blocks no one.
+type List struct {
+	mu      sync.RWMutex
+	path    string
+	entries []Entry
+	audit   []AuditEvent
+	now     func() time.Time
+}
+
+// Load reads the list persisted at path. A missing file is an empty list,
+// and an empty path keeps the list in memory only.
+func Load(path string) (*List, error) {
+	l := &List{path: path, now: time.Now}
+	if path == "" {
+		return l, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blocklist: %w", err)
+	}
+	var f file
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse blocklist: %w", err)
+	}
+	for i := range f.Entries {
+		if err := f.Entries[i].parse(); err != nil {
+			return nil, fmt.Errorf("invalid blocklist entry %s: %w", f.Entries[i].ID, err)
+		}
+	}
+	l.entries, l.audit = f.Entries, f.Audit
+	return l, nil
+}
+
+// Fingerprint identifies an API key without revealing it
+func Fingerprint(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Blocked reports whether a caller with the given IP address and API key is
+// refused, and the entry refusing it. Allow entries take precedence over
+// block entries. An invalid address or empty key matches no entries.
+func (l *List) Blocked(ip netip.Addr, apiKey string) (Entry, bool) {
+	if l == nil {
+		return Entry{}, false
+	}
+
+	var fingerprint string
+	if apiKey != "" {
+		fingerprint = Fingerprint(apiKey)
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var blocked *Entry
+	for i := range l.entries {
+		e := &l.entries[i]
+		if !e.matches(ip, fingerprint) {
+			continue
+		}
+		if e.Kind == KindAllow {
+			return Entry{}, false
+		}
+		if blocked == nil {
+			blocked = e
+		}
+	}
+	if blocked == nil {
+		return Entry{}, false
+	}
+	return *blocked, true
+}
+
+// Entries returns every entry, oldest first
+func (l *List) Entries() []Entry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return slices.Clone(l.entries)
+}
+
+// Audit returns every change to the list, oldest first
+func (l *List) Audit() []AuditEvent {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return slices.Clone(l.audit)
+}
+
+// Add adds an entry on behalf of actor, the ID of the admin's API key or
+// their customer ID, making the change from ip
+func (l *List) Add(req EntryRequest, actor, ip string) (Entry, error) {
+	e := Entry{
+		ID:     uuid.New().String(),
+		Kind:   req.Kind,
+		Type:   req.Type,
+		Value:  strings.TrimSpace(req.Value),
+		Reason: req.Reason,
+	}
+	if e.Kind == "" {
+		e.Kind = KindBlock
+	}
+	if e.Type == TypeAPIKey {
+		e.Value = Fingerprint(e.Value)
+	}
+	if err := e.parse(); err != nil {
+		return Entry{}, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, other := range l.entries {
+		if other.Kind == e.Kind && other.Type == e.Type && other.Value == e.Value {
+			return Entry{}, fmt.Errorf("%w: %s", ErrExists, other.ID)
+		}
+	}
+
+	e.CreatedAt = l.now()
+	entries := append(slices.Clone(l.entries), e)
+	audit := append(slices.Clone(l.audit), AuditEvent{Action: ActionAdded, Entry: e, Actor: actor, IP: ip, At: e.CreatedAt})
+	if err := l.save(entries, audit); err != nil {
+		return Entry{}, err
+	}
+	l.entries, l.audit = entries, audit
+	return e, nil
+}
+
+// Remove removes an entry on behalf of actor, making the change from ip
+func (l *List) Remove(id, actor, ip string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	i := slices.IndexFunc(l.entries, func(e Entry) bool { return e.ID == id })
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+
+	removed := l.entries[i]
+	entries := slices.Delete(slices.Clone(l.entries), i, i+1)
+	audit := append(slices.Clone(l.audit), AuditEvent{Action: ActionRemoved, Entry: removed, Actor: actor, IP: ip, At: l.now()})
+	if err := l.save(entries, audit); err != nil {
+		return err
+	}
+	l.entries, l.audit = entries, audit
+	return nil
+}
+
+// save persists entries and audit, writing to a temporary file first so the
+// list is never left half-written. Callers must hold l.mu for writing.
+func (l *List) save(entries []Entry, audit []AuditEvent) error {
+	if l.path == "" {
+		return nil
+	}
+
+	raw, err := json.MarshalIndent(file{Entries: entries, Audit: audit}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode blocklist: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), ".blocklist-*")
+	if err != nil {
+		return fmt.Errorf("failed to write blocklist: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write blocklist: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write blocklist: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), l.path); err != nil {
+		return fmt.Errorf("failed to write blocklist: %w", err)
+	}
+	return nil
+}
+
+// parse checks the entry and prepares it for matching, normalizing single
+// IP addresses to ranges
+func (e *Entry) parse() error {
+	if e.Kind != KindBlock && e.Kind != KindAllow {
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidEntry, e.Kind)
+	}
+	switch e.Type {
+	case TypeIP:
+		prefix, err := netip.ParsePrefix(e.Value)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(e.Value)
+			if addrErr != nil {
+				return fmt.Errorf("%w: %q is not an IP address or CIDR range", ErrInvalidEntry, e.Value)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		e.prefix = prefix.Masked()
+		e.Value = e.prefix.String()
+	case TypeAPIKey:
+		if e.Value == "" {
+			return fmt.Errorf("%w: empty API key", ErrInvalidEntry)
+		}
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidEntry, e.Type)
+	}
+	return nil
+}
+
+// matches reports whether the entry applies to a caller
+func (e *Entry) matches(ip netip.Addr, fingerprint string) bool {
+	switch e.Type {
+	case TypeIP:
+		return ip.IsValid() && e.prefix.Contains(ip.Unmap())
+	case TypeAPIKey:
+		return fingerprint != "" && e.Value == fingerprint
+	}
+	return false
+}
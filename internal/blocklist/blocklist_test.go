@@ -0,0 +1,170 @@
+package blocklist
+
+import (
+	"errors"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestList_Blocked(t *testing.T) {
+	list, err := Load("")
+	require.NoError(t, err)
+
+	rangeBlock, err := list.Add(EntryRequest{Type: TypeIP, Value: "203.0.113.0/24", Reason: "Coupon abuse"}, "admin", "192.0.2.1")
+	require.NoError(t, err)
+	_, err = list.Add(EntryRequest{Kind: KindAllow, Type: TypeIP, Value: "203.0.113.10"}, "admin", "192.0.2.1")
+	require.NoError(t, err)
+	keyBlock, err := list.Add(EntryRequest{Type: TypeAPIKey, Value: "stolen-key"}, "admin", "192.0.2.1")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		ip      string
+		key     string
+		blocked string
+	}{
+		{name: "unlisted address", ip: "192.0.2.50"},
+		{name: "address in blocked range", ip: "203.0.113.7", blocked: rangeBlock.ID},
+		{name: "IPv4-mapped address in blocked range", ip: "::ffff:203.0.113.7", blocked: rangeBlock.ID},
+		{name: "allowed address in blocked range", ip: "203.0.113.10"},
+		{name: "allowed address with blocked key", ip: "203.0.113.10", key: "stolen-key"},
+		{name: "blocked key", ip: "192.0.2.50", key: "stolen-key", blocked: keyBlock.ID},
+		{name: "other key", ip: "192.0.2.50", key: "good-key"},
+		{name: "blocked key without address", key: "stolen-key", blocked: keyBlock.ID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ip netip.Addr
+			if tt.ip != "" {
+				ip = netip.MustParseAddr(tt.ip)
+			}
+			entry, blocked := list.Blocked(ip, tt.key)
+			assert.Equal(t, tt.blocked != "", blocked)
+			assert.Equal(t, tt.blocked, entry.ID)
+		})
+	}
+}
+
+func TestList_NilBlocksNothing(t *testing.T) {
+	var list *List
+	_, blocked := list.Blocked(netip.MustParseAddr("203.0.113.7"), "stolen-key")
+	assert.False(t, blocked)
+}
+
+func TestList_Add(t *testing.T) {
+	list, err := Load("")
+	require.NoError(t, err)
+
+	t.Run("normalizes addresses", func(t *testing.T) {
+		entry, err := list.Add(EntryRequest{Type: TypeIP, Value: " 198.51.100.7/16 "}, "admin", "")
+		require.NoError(t, err)
+		assert.Equal(t, KindBlock, entry.Kind)
+		assert.Equal(t, "198.51.0.0/16", entry.Value)
+
+		entry, err = list.Add(EntryRequest{Type: TypeIP, Value: "2001:db8::1"}, "admin", "")
+		require.NoError(t, err)
+		assert.Equal(t, "2001:db8::1/128", entry.Value)
+	})
+
+	t.Run("stores API keys as fingerprints", func(t *testing.T) {
+		entry, err := list.Add(EntryRequest{Type: TypeAPIKey, Value: "secret-key"}, "admin", "")
+		require.NoError(t, err)
+		assert.Equal(t, Fingerprint("secret-key"), entry.Value)
+		assert.NotContains(t, entry.Value, "secret")
+	})
+
+	t.Run("rejects duplicates", func(t *testing.T) {
+		_, err := list.Add(EntryRequest{Type: TypeIP, Value: "198.51.0.0/16"}, "admin", "")
+		assert.True(t, errors.Is(err, ErrExists))
+
+		// The same value may still be allowed
+		_, err = list.Add(EntryRequest{Kind: KindAllow, Type: TypeIP, Value: "198.51.0.0/16"}, "admin", "")
+		assert.NoError(t, err)
+	})
+
+	t.Run("rejects invalid entries", func(t *testing.T) {
+		for _, req := range []EntryRequest{
+			{Type: TypeIP, Value: "not-an-ip"},
+			{Type: "customer", Value: "cust-1"},
+			{Kind: "ignore", Type: TypeIP, Value: "10.0.0.1"},
+		} {
+			_, err := list.Add(req, "admin", "")
+			assert.True(t, errors.Is(err, ErrInvalidEntry), "%+v", req)
+		}
+	})
+}
+
+func TestList_Remove(t *testing.T) {
+	list, err := Load("")
+	require.NoError(t, err)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	list.now = func() time.Time { return now }
+
+	entry, err := list.Add(EntryRequest{Type: TypeIP, Value: "203.0.113.0/24"}, "admin-1", "192.0.2.1")
+	require.NoError(t, err)
+
+	assert.True(t, errors.Is(list.Remove("missing", "admin-2", "192.0.2.2"), ErrNotFound))
+	require.NoError(t, list.Remove(entry.ID, "admin-2", "192.0.2.2"))
+	assert.Empty(t, list.Entries())
+	_, blocked := list.Blocked(netip.MustParseAddr("203.0.113.7"), "")
+	assert.False(t, blocked)
+
+	audit := list.Audit()
+	require.Len(t, audit, 2)
+	assert.Equal(t, AuditEvent{Action: ActionAdded, Entry: entry, Actor: "admin-1", IP: "192.0.2.1", At: now}, audit[0])
+	assert.Equal(t, ActionRemoved, audit[1].Action)
+	assert.Equal(t, entry.ID, audit[1].Entry.ID)
+	assert.Equal(t, "admin-2", audit[1].Actor)
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "blocklist.json")
+
+	t.Run("missing file is empty", func(t *testing.T) {
+		list, err := Load(path)
+		require.NoError(t, err)
+		assert.Empty(t, list.Entries())
+
+		_, err = list.Add(EntryRequest{Type: TypeIP, Value: "203.0.113.0/24"}, "admin", "")
+		require.NoError(t, err)
+		_, err = list.Add(EntryRequest{Type: TypeAPIKey, Value: "stolen-key"}, "admin", "")
+		require.NoError(t, err)
+	})
+
+	t.Run("entries persist", func(t *testing.T) {
+		list, err := Load(path)
+		require.NoError(t, err)
+		assert.Len(t, list.Entries(), 2)
+		assert.Len(t, list.Audit(), 2)
+
+		_, blocked := list.Blocked(netip.MustParseAddr("203.0.113.7"), "")
+		assert.True(t, blocked)
+		_, blocked = list.Blocked(netip.Addr{}, "stolen-key")
+		assert.True(t, blocked)
+
+		raw, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.NotContains(t, string(raw), "stolen-key")
+	})
+
+	t.Run("malformed file", func(t *testing.T) {
+		bad := filepath.Join(t.TempDir(), "blocklist.json")
+		require.NoError(t, os.WriteFile(bad, []byte(`{"entries":`), 0644))
+		_, err := Load(bad)
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid entry", func(t *testing.T) {
+		bad := filepath.Join(t.TempDir(), "blocklist.json")
+		require.NoError(t, os.WriteFile(bad, []byte(`{"entries":[{"id":"1","kind":"block","type":"ip","value":"nope"}]}`), 0644))
+		_, err := Load(bad)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,424 @@
+// Package carts keeps group carts: carts shared by link, where several
+// people add their own items before the host checks out one order for all
+// of them.
+package carts
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+// Cart statuses
+const (
+	StatusOpen        = "open"         // Taking items
+	StatusCheckingOut = "checking_out" // The host is placing the order
+	StatusCheckedOut  = "checked_out"  // The order was placed
+)
+
+// TTL is how long a cart is kept after it was created
+const TTL = 24 * time.Hour
+
+// MaxItems is the most lines a cart holds
+const MaxItems = 100
+
+var (
+	// ErrNotFound is returned when a cart does not exist or has expired
+	ErrNotFound = errors.New("cart not found")
+	// ErrItemNotFound is returned when a cart has no item with an ID
+	ErrItemNotFound = errors.New("item not found")
+	// ErrClosed is returned when a cart no longer takes changes
+	ErrClosed = errors.New("cart is closed")
+	// ErrFull is returned when lines would take a cart past MaxItems
+	ErrFull = errors.New("cart is full")
+	// ErrEmpty is returned when checking out a cart without items
+	ErrEmpty = errors.New("cart is empty")
+	// ErrForbidden is returned when a change needs the host token and the
+	// caller does not hold it
+	ErrForbidden = errors.New("only the host can do this")
+)
+
+// Participant is someone adding to a cart: by name, and by account when
+// signed in
+type Participant struct {
+	// The participant's name, as shown to the others
+	// @example Jane
+	Name string `json:"name"`
+
+	// The participant's account, when signed in
+	// @example customer-0000-0000-0000-0000
+	CustomerID string `json:"customer_id,omitempty"`
+}
+
+// Item is a line a participant added to a cart
+type Item struct {
+	// The unique identifier of the line
+	// @example item-0000-0000-0000-0000
+	ID string `json:"id"`
+
+	// Who added the line
+	Participant Participant `json:"participant"`
+
+	// The ID of the product
+	// @example 1
+	ProductID string `json:"product_id"`
+
+	// The variant of the product, if any
+	// @example large
+	VariantID string `json:"variant_id,omitempty"`
+
+	// How many to order
+	// @example 2
+	Quantity int `json:"quantity"`
+
+	// Instructions for the kitchen about this item
+	// @example no onions
+	Notes string `json:"notes,omitempty"`
+}
+
+// CartRequest represents the request body for opening a group cart
+type CartRequest struct {
+	// The host's name, as shown to participants. Defaults to the signed-in
+	// customer's name.
+	// @example Jane
+	Name string `json:"name" validate:"max=60"`
+}
+
+// ItemRequest represents the request body for adding to a group cart
+type ItemRequest struct {
+	// The participant's name, as shown to the others. Defaults to the
+	// signed-in customer's name.
+	// @example Sam
+	Name string `json:"name" validate:"max=60"`
+
+	// Items to add
+	// @required
+	Items []models.OrderItem `json:"items" validate:"required,min=1,dive"`
+}
+
+// CheckoutRequest represents the request body for checking out a group
+// cart. It takes the fields of an order request other than its items.
+type CheckoutRequest struct {
+	// Optional coupon code to apply to the order
+	// @example SAVE20
+	CouponCode string `json:"couponCode"`
+
+	// Instructions about the whole order, up to 500 characters
+	// @example leave at door
+	Notes string `json:"notes,omitempty" validate:"omitempty,max=500"`
+
+	// Address to deliver the order to. Orders without one are for pickup,
+	// or served at the table when they have a table token.
+	DeliveryAddress *models.Address `json:"deliveryAddress,omitempty" validate:"omitempty,excluded_with=TableToken"`
+
+	// Token from the QR code on the table to serve the order to
+	// @example 12.mZ0zT2dOv7bVq1b3Yk0Jm1r0aL3oT8oM0bXy4u6YvVc
+	TableToken string `json:"tableToken,omitempty" validate:"omitempty,max=128"`
+
+	// The business to invoice the order to
+	Billing *models.BusinessBilling `json:"billing,omitempty" validate:"omitempty"`
+
+	// How the host pays for the order
+	Payment *models.Payment `json:"payment,omitempty" validate:"omitempty"`
+}
+
+// Share is what a participant ordered from a checked-out cart, priced as
+// the order was
+type Share struct {
+	Participant
+
+	// The participant's lines, at the prices the order charged
+	Items []models.OrderItem `json:"items"`
+
+	// Price times quantity over the participant's lines
+	// @example 19.98
+	Subtotal float64 `json:"subtotal"`
+
+	// The participant's part of the order total, in proportion to their
+	// subtotal, after any adjustments, discount, tax and fees
+	// @example 21.98
+	Total float64 `json:"total"`
+}
+
+// Cart is a group cart
+type Cart struct {
+	// The unique identifier of the cart, shared with participants
+	// @example cart-0000-0000-0000-0000
+	ID string `json:"id"`
+
+	// The participant who opened the cart and checks it out
+	Host Participant `json:"host"`
+
+	// The status of the cart: open, checking_out or checked_out
+	// @example open
+	Status string `json:"status"`
+
+	// The lines of every participant, in the order they were added
+	Items []Item `json:"items"`
+
+	// The order placed from the cart, once checked out
+	// @example order-0000-0000-0000-0000
+	OrderID string `json:"order_id,omitempty"`
+
+	// What each participant ordered, once checked out
+	Shares []Share `json:"shares,omitempty"`
+
+	// The timestamp when the cart was opened
+	// @example 2024-01-01T00:00:00Z
+	CreatedAt time.Time `json:"created_at"`
+
+	// The timestamp when the cart is discarded
+	// @example 2024-01-02T00:00:00Z
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// entry is a cart with the token of its host
+type entry struct {
+	cart  Cart
+	token string
+}
+
+// Store holds the group carts of a restaurant
+type Store struct {
+	mu    sync.Mutex
+	carts map[string]*entry
+	now   func() time.Time
+}
+
+// NewStore creates a new, empty Store
+func NewStore() *Store {
+	return &Store{
+		carts: make(map[string]*entry),
+		now:   time.Now,
+	}
+}
+
+// Create opens a cart hosted by host, returning it with the token the host
+// checks out with
+func (s *Store) Create(host Participant) (Cart, string, error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return Cart{}, "", fmt.Errorf("failed to generate host token: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune()
+
+	now := s.now()
+	e := &entry{
+		cart: Cart{
+			ID:        "cart-" + uuid.New().String(),
+			Host:      host,
+			Status:    StatusOpen,
+			Items:     []Item{},
+			CreatedAt: now,
+			ExpiresAt: now.Add(TTL),
+		},
+		token: hex.EncodeToString(raw),
+	}
+	s.carts[e.cart.ID] = e
+	return e.cart.clone(), e.token, nil
+}
+
+// Get returns a cart
+func (s *Store) Get(id string) (Cart, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := s.get(id)
+	if err != nil {
+		return Cart{}, err
+	}
+	return e.cart.clone(), nil
+}
+
+// Add adds lines for participant to an open cart. When hold is given, it
+// is called with every line of the cart, the new ones included, before they
+// are added; if it fails, nothing is added.
+func (s *Store) Add(id string, participant Participant, items []models.OrderItem, hold func(items []models.OrderItem) error) (Cart, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := s.get(id)
+	if err != nil {
+		return Cart{}, err
+	}
+	if e.cart.Status != StatusOpen {
+		return Cart{}, ErrClosed
+	}
+	if len(e.cart.Items)+len(items) > MaxItems {
+		return Cart{}, ErrFull
+	}
+	if hold != nil {
+		if err := hold(append(e.cart.OrderItems(), items...)); err != nil {
+			return Cart{}, err
+		}
+	}
+	for _, item := range items {
+		e.cart.Items = append(e.cart.Items, Item{
+			ID:          "item-" + uuid.New().String(),
+			Participant: participant,
+			ProductID:   item.ProductID,
+			VariantID:   item.VariantID,
+			Quantity:    item.Quantity,
+			Notes:       item.Notes,
+		})
+	}
+	return e.cart.clone(), nil
+}
+
+// Remove takes a line out of an open cart. The host, holding token, can
+// remove any line; signed-in participants can remove their own.
+func (s *Store) Remove(id, itemID, token, customerID string) (Cart, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := s.get(id)
+	if err != nil {
+		return Cart{}, err
+	}
+	i := slices.IndexFunc(e.cart.Items, func(item Item) bool { return item.ID == itemID })
+	if i < 0 {
+		return Cart{}, fmt.Errorf("%w: %s", ErrItemNotFound, itemID)
+	}
+	own := customerID != "" && e.cart.Items[i].Participant.CustomerID == customerID
+	if !own && !e.hostedBy(token) {
+		return Cart{}, ErrForbidden
+	}
+	if e.cart.Status != StatusOpen {
+		return Cart{}, ErrClosed
+	}
+	e.cart.Items = slices.Delete(e.cart.Items, i, i+1)
+	return e.cart.clone(), nil
+}
+
+// Checkout places the order of a cart with place, for the host holding
+// token. The cart takes no changes while the order is placed, and reopens
+// if place fails. Once placed, the cart records the order and what each
+// participant ordered.
+func (s *Store) Checkout(id, token string, place func(items []models.OrderItem) (*models.Order, error)) (Cart, *models.Order, error) {
+	s.mu.Lock()
+	e, err := s.get(id)
+	switch {
+	case err != nil:
+	case !e.hostedBy(token):
+		err = ErrForbidden
+	case e.cart.Status != StatusOpen:
+		err = ErrClosed
+	case len(e.cart.Items) == 0:
+		err = ErrEmpty
+	}
+	if err != nil {
+		s.mu.Unlock()
+		return Cart{}, nil, err
+	}
+	e.cart.Status = StatusCheckingOut
+	cart := e.cart.clone()
+	s.mu.Unlock()
+
+	order, placeErr := place(cart.OrderItems())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if placeErr != nil {
+		e.cart.Status = StatusOpen
+		return Cart{}, nil, placeErr
+	}
+	e.cart.Status = StatusCheckedOut
+	e.cart.OrderID = order.ID
+	e.cart.Shares = shares(cart.Items, order)
+	return e.cart.clone(), order, nil
+}
+
+// get returns an unexpired cart. Callers must hold s.mu.
+func (s *Store) get(id string) (*entry, error) {
+	e, ok := s.carts[id]
+	if !ok || !s.now().Before(e.cart.ExpiresAt) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return e, nil
+}
+
+// prune drops expired carts. Callers must hold s.mu.
+func (s *Store) prune() {
+	now := s.now()
+	for id, e := range s.carts {
+		if !now.Before(e.cart.ExpiresAt) {
+			delete(s.carts, id)
+		}
+	}
+}
+
+// hostedBy reports whether token is the host token of the cart
+func (e *entry) hostedBy(token string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(e.token)) == 1
+}
+
+// OrderItems returns the lines of c as the items of an order
+func (c Cart) OrderItems() []models.OrderItem {
+	items := make([]models.OrderItem, len(c.Items))
+	for i, item := range c.Items {
+		items[i] = models.OrderItem{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+			Notes:     item.Notes,
+		}
+	}
+	return items
+}
+
+// clone returns a copy of c that shares no slices with it
+func (c Cart) clone() Cart {
+	c.Items = slices.Clone(c.Items)
+	c.Shares = slices.Clone(c.Shares)
+	return c
+}
+
+// shares splits order among the participants who added items, in the order
+// they first added one. Lines are priced at the unit prices the order
+// charged, and the order total is split in proportion to each subtotal.
+func shares(items []Item, order *models.Order) []Share {
+	type priceKey struct{ productID, variantID string }
+	prices := make(map[priceKey]float64, len(order.Items))
+	for _, item := range order.Items {
+		prices[priceKey{item.ProductID, item.VariantID}] = item.Price
+	}
+
+	var result []Share
+	var subtotal float64
+	for _, item := range items {
+		i := slices.IndexFunc(result, func(s Share) bool { return s.Participant == item.Participant })
+		if i < 0 {
+			result = append(result, Share{Participant: item.Participant})
+			i = len(result) - 1
+		}
+		price := prices[priceKey{item.ProductID, item.VariantID}]
+		result[i].Items = append(result[i].Items, models.OrderItem{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+			Price:     price,
+			Notes:     item.Notes,
+		})
+		result[i].Subtotal += price * float64(item.Quantity)
+		subtotal += price * float64(item.Quantity)
+	}
+	for i := range result {
+		if subtotal > 0 {
+			result[i].Total = math.Round(order.TotalAmount*result[i].Subtotal/subtotal*100) / 100
+		}
+		result[i].Subtotal = math.Round(result[i].Subtotal*100) / 100
+	}
+	return result
+}
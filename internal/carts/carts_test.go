@@ -0,0 +1,134 @@
+package carts
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_AddAndRemove(t *testing.T) {
+	s := NewStore()
+	jane := Participant{Name: "Jane", CustomerID: "customer-1"}
+	sam := Participant{Name: "Sam"}
+
+	cart, token, err := s.Create(jane)
+	require.NoError(t, err)
+	assert.Equal(t, StatusOpen, cart.Status)
+	assert.NotEmpty(t, token)
+
+	cart, err = s.Add(cart.ID, jane, []models.OrderItem{{ProductID: "prod-1", Quantity: 2}}, nil)
+	require.NoError(t, err)
+	cart, err = s.Add(cart.ID, sam, []models.OrderItem{{ProductID: "prod-2", Quantity: 1}}, nil)
+	require.NoError(t, err)
+	require.Len(t, cart.Items, 2)
+
+	// Participants remove their own lines, the host any line
+	_, err = s.Remove(cart.ID, cart.Items[1].ID, "", "customer-1")
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = s.Remove(cart.ID, cart.Items[0].ID, "", "customer-1")
+	require.NoError(t, err)
+	cart, err = s.Remove(cart.ID, cart.Items[1].ID, token, "")
+	require.NoError(t, err)
+	assert.Empty(t, cart.Items)
+
+	_, err = s.Remove(cart.ID, "missing", token, "")
+	assert.ErrorIs(t, err, ErrItemNotFound)
+	_, err = s.Add("missing", sam, []models.OrderItem{{ProductID: "prod-1", Quantity: 1}}, nil)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Carts hold at most MaxItems lines
+	_, err = s.Add(cart.ID, sam, make([]models.OrderItem, MaxItems+1), nil)
+	assert.ErrorIs(t, err, ErrFull)
+
+	// Nothing is added when the cart's lines cannot be held
+	cart, err = s.Add(cart.ID, sam, []models.OrderItem{{ProductID: "prod-1", Quantity: 1}}, nil)
+	require.NoError(t, err)
+	short := errors.New("out of stock")
+	var held []models.OrderItem
+	_, err = s.Add(cart.ID, jane, []models.OrderItem{{ProductID: "prod-2", Quantity: 3}}, func(items []models.OrderItem) error {
+		held = items
+		return short
+	})
+	assert.ErrorIs(t, err, short)
+	assert.Equal(t, []models.OrderItem{{ProductID: "prod-1", Quantity: 1}, {ProductID: "prod-2", Quantity: 3}}, held)
+	cart, err = s.Get(cart.ID)
+	require.NoError(t, err)
+	assert.Len(t, cart.Items, 1)
+}
+
+func TestStore_Checkout(t *testing.T) {
+	s := NewStore()
+	jane := Participant{Name: "Jane"}
+	sam := Participant{Name: "Sam"}
+	cart, token, err := s.Create(jane)
+	require.NoError(t, err)
+
+	place := func(items []models.OrderItem) (*models.Order, error) {
+		order := &models.Order{ID: "order-1", Items: items, TotalAmount: 33.3}
+		for i := range order.Items {
+			order.Items[i].Price = 10
+		}
+		return order, nil
+	}
+
+	_, _, err = s.Checkout(cart.ID, token, place)
+	assert.ErrorIs(t, err, ErrEmpty)
+
+	_, err = s.Add(cart.ID, jane, []models.OrderItem{{ProductID: "prod-1", Quantity: 1}}, nil)
+	require.NoError(t, err)
+	_, err = s.Add(cart.ID, sam, []models.OrderItem{{ProductID: "prod-2", Quantity: 1}}, nil)
+	require.NoError(t, err)
+	_, err = s.Add(cart.ID, jane, []models.OrderItem{{ProductID: "prod-2", Quantity: 1}}, nil)
+	require.NoError(t, err)
+
+	_, _, err = s.Checkout(cart.ID, "wrong", place)
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	// A failed order reopens the cart
+	failed := errors.New("store closed")
+	_, _, err = s.Checkout(cart.ID, token, func([]models.OrderItem) (*models.Order, error) { return nil, failed })
+	assert.ErrorIs(t, err, failed)
+	reopened, err := s.Get(cart.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusOpen, reopened.Status)
+
+	checkedOut, order, err := s.Checkout(cart.ID, token, place)
+	require.NoError(t, err)
+	assert.Equal(t, "order-1", order.ID)
+	assert.Equal(t, StatusCheckedOut, checkedOut.Status)
+	assert.Equal(t, "order-1", checkedOut.OrderID)
+	require.Len(t, checkedOut.Shares, 2)
+	assert.Equal(t, jane, checkedOut.Shares[0].Participant)
+	assert.Len(t, checkedOut.Shares[0].Items, 2)
+	assert.Equal(t, 20.0, checkedOut.Shares[0].Subtotal)
+	assert.Equal(t, 22.2, checkedOut.Shares[0].Total)
+	assert.Equal(t, sam, checkedOut.Shares[1].Participant)
+	assert.Equal(t, 11.1, checkedOut.Shares[1].Total)
+
+	_, err = s.Add(cart.ID, sam, []models.OrderItem{{ProductID: "prod-1", Quantity: 1}}, nil)
+	assert.ErrorIs(t, err, ErrClosed)
+	_, _, err = s.Checkout(cart.ID, token, place)
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestStore_Expiry(t *testing.T) {
+	s := NewStore()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	cart, _, err := s.Create(Participant{Name: "Jane"})
+	require.NoError(t, err)
+
+	now = now.Add(TTL)
+	_, err = s.Get(cart.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Expired carts are dropped as new ones are opened
+	_, _, err = s.Create(Participant{Name: "Sam"})
+	require.NoError(t, err)
+	assert.Len(t, s.carts, 1)
+}
@@ -0,0 +1,100 @@
+// Package catalogsync applies product updates pushed by an upstream catalog
+// service over a message broker, so the catalog stays in sync without
+// dropping new product files and reloading them.
+package catalogsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/data"
+	"github.com/ravibandhu/oolio-food-ordering/internal/logging"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+// Event is a product update published by the upstream catalog service. The
+// products it upserts and deletes are applied together: readers see either
+// none of them or all of them.
+type Event struct {
+	// Identifies the event in logs
+	ID string `json:"id"`
+
+	// Products to add, or to replace when they exist
+	Upsert []*models.Product `json:"upsert"`
+
+	// IDs of products to delete
+	Delete []string `json:"delete"`
+}
+
+// Subscriber delivers the payload of each message published on a subject to
+// handle, in order, until ctx is done. Messages arriving while the broker is
+// unreachable may be lost; Subscribe reconnects rather than returning.
+type Subscriber interface {
+	Subscribe(ctx context.Context, handle func(payload []byte)) error
+}
+
+// NewSubscriber creates the Subscriber described by cfg. It returns nil when
+// catalog sync is disabled.
+func NewSubscriber(cfg config.CatalogSync) (Subscriber, error) {
+	if cfg.URL == "" {
+		return nil, nil
+	}
+	return NewNATS(cfg.URL, cfg.Subject)
+}
+
+// Consumer applies the events a Subscriber delivers to a store
+type Consumer struct {
+	store *data.Store
+	sub   Subscriber
+}
+
+// NewConsumer creates a Consumer applying the events sub delivers to store
+func NewConsumer(store *data.Store, sub Subscriber) *Consumer {
+	return &Consumer{
+		store: store,
+		sub:   sub,
+	}
+}
+
+// Run applies events until ctx is done. Events that do not decode or hold
+// an invalid product are logged and skipped, leaving the catalog unchanged.
+// Busy feeds log a sample of the events applied and skipped.
+func (c *Consumer) Run(ctx context.Context) error {
+	return c.sub.Subscribe(ctx, func(payload []byte) {
+		event, err := decodeEvent(payload)
+		if err != nil {
+			logging.Sampled("catalog-skip", "Skipping catalog event: %v", err)
+			return
+		}
+		if err := c.Apply(event); err != nil {
+			logging.Sampled("catalog-skip", "Skipping catalog event %s: %v", event.ID, err)
+			return
+		}
+		logging.Sampled("catalog-apply", "Applied catalog event %s: %d upserted, %d deleted", event.ID, len(event.Upsert), len(event.Delete))
+	})
+}
+
+// Apply applies an event to the store atomically
+func (c *Consumer) Apply(event Event) error {
+	return c.store.ApplyProducts(event.Upsert, event.Delete)
+}
+
+// decodeEvent parses an event, refusing unknown fields so a renamed field
+// upstream is noticed rather than silently ignored
+func decodeEvent(payload []byte) (Event, error) {
+	var event Event
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&event); err != nil {
+		return Event{}, fmt.Errorf("malformed event: %w", err)
+	}
+	for i, product := range event.Upsert {
+		if product == nil {
+			return Event{}, fmt.Errorf("malformed event %s: upsert %d is null", event.ID, i)
+		}
+	}
+	return event, nil
+}
@@ -0,0 +1,65 @@
+package catalogsync
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/data"
+	"github.com/ravibandhu/oolio-food-ordering/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSubscriber delivers a fixed list of payloads, then returns
+type fakeSubscriber struct {
+	payloads []string
+}
+
+func (f fakeSubscriber) Subscribe(ctx context.Context, handle func(payload []byte)) error {
+	for _, payload := range f.payloads {
+		handle([]byte(payload))
+	}
+	return nil
+}
+
+func TestConsumer_Run(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+	store, err := data.NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+
+	image := `"image":{"thumbnail":"https://example.com/t.jpg","mobile":"https://example.com/m.jpg","tablet":"https://example.com/t.jpg","desktop":"https://example.com/d.jpg"}`
+	sub := fakeSubscriber{payloads: []string{
+		`{"id":"evt-1","upsert":[{"id":"prod-9","name":"Ramen","price":12.5,"category":"Noodles",` + image + `}],"delete":["prod-2"]}`,
+		// An invalid product skips the whole event
+		`{"id":"evt-2","upsert":[{"id":"prod-10","name":"Soup","price":4,"category":"Soup",` + image + `},{"id":"prod-1","name":"Free","price":0,"category":"Soup",` + image + `}]}`,
+		`{"id":"evt-3","upsert":[null]}`,
+		`{"id":"evt-4","upserted":[]}`,
+		`not json`,
+	}}
+	require.NoError(t, NewConsumer(store, sub).Run(context.Background()))
+
+	product, err := store.GetProduct("prod-9")
+	require.NoError(t, err)
+	assert.Equal(t, "Ramen", product.Name)
+	deleted, err := store.GetProduct("prod-2")
+	require.NoError(t, err)
+	assert.NotNil(t, deleted.DeletedAt)
+
+	_, err = store.GetProduct("prod-10")
+	assert.ErrorIs(t, err, data.ErrProductNotFound)
+	product, err = store.GetProduct("prod-1")
+	require.NoError(t, err)
+	assert.NotZero(t, product.Price)
+}
+
+func TestNewSubscriber(t *testing.T) {
+	sub, err := NewSubscriber(config.CatalogSync{})
+	require.NoError(t, err)
+	assert.Nil(t, sub)
+
+	sub, err = NewSubscriber(config.CatalogSync{URL: "nats://localhost:4222", Subject: "catalog.products"})
+	require.NoError(t, err)
+	assert.IsType(t, &NATS{}, sub)
+}
@@ -0,0 +1,208 @@
+package catalogsync
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// natsDefaultPort is the port NATS servers listen on unless the URL names one
+const natsDefaultPort = "4222"
+
+// Reconnection delays, doubling from the first to the last
+const (
+	natsRetryMin = time.Second
+	natsRetryMax = 30 * time.Second
+)
+
+// maxPayload bounds the messages accepted, like the default of NATS servers
+const maxPayload = 1 << 20
+
+// NATS subscribes to a subject on a NATS server using the core NATS text
+// protocol. Messages are delivered at most once: those published while it
+// is disconnected are missed, so upstream should publish full products
+// rather than field-level changes.
+type NATS struct {
+	addr     string
+	subject  string
+	user     string
+	pass     string
+	token    string
+	retryMin time.Duration
+	retryMax time.Duration
+}
+
+// natsConnect is the CONNECT message. Without verbose mode the server does
+// not acknowledge each operation with +OK.
+type natsConnect struct {
+	Verbose   bool   `json:"verbose"`
+	Pedantic  bool   `json:"pedantic"`
+	Name      string `json:"name"`
+	Lang      string `json:"lang"`
+	User      string `json:"user,omitempty"`
+	Pass      string `json:"pass,omitempty"`
+	AuthToken string `json:"auth_token,omitempty"`
+}
+
+// NewNATS creates a NATS subscriber for a nats://[user:pass@]host[:port]
+// URL. A user without a password is sent as an auth token.
+func NewNATS(rawURL, subject string) (*NATS, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "nats" || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid NATS URL: %s", rawURL)
+	}
+	port := u.Port()
+	if port == "" {
+		port = natsDefaultPort
+	}
+
+	n := &NATS{
+		addr:     net.JoinHostPort(u.Hostname(), port),
+		subject:  subject,
+		retryMin: natsRetryMin,
+		retryMax: natsRetryMax,
+	}
+	if u.User != nil {
+		if pass, ok := u.User.Password(); ok {
+			n.user, n.pass = u.User.Username(), pass
+		} else {
+			n.token = u.User.Username()
+		}
+	}
+	return n, nil
+}
+
+// Subscribe delivers every message published on the subject to handle until
+// ctx is done, reconnecting with increasing delays whenever the connection
+// fails
+func (n *NATS) Subscribe(ctx context.Context, handle func(payload []byte)) error {
+	delay := n.retryMin
+	for {
+		delivered, err := n.session(ctx, handle)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if delivered {
+			delay = n.retryMin
+		}
+		log.Printf("NATS subscription to %s on %s failed, reconnecting in %s: %v", n.subject, n.addr, delay, err)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil
+		}
+		delay = min(2*delay, n.retryMax)
+	}
+}
+
+// session connects, subscribes and delivers messages until the connection
+// fails or ctx is done. It reports whether any message was delivered.
+func (n *NATS) session(ctx context.Context, handle func(payload []byte)) (bool, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", n.addr)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	// Unblock reads when ctx is done
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	r := bufio.NewReader(conn)
+	line, err := readLine(r)
+	if err != nil {
+		return false, err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return false, fmt.Errorf("unexpected greeting: %q", line)
+	}
+
+	connect, err := json.Marshal(natsConnect{
+		Name:      "oolio-food-ordering",
+		Lang:      "go",
+		User:      n.user,
+		Pass:      n.pass,
+		AuthToken: n.token,
+	})
+	if err != nil {
+		return false, err
+	}
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\nSUB %s 1\r\nPING\r\n", connect, n.subject); err != nil {
+		return false, err
+	}
+
+	delivered := false
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return delivered, err
+		}
+		op, args, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(op) {
+		case "MSG":
+			payload, err := readPayload(r, args)
+			if err != nil {
+				return delivered, err
+			}
+			handle(payload)
+			delivered = true
+		case "PING":
+			if _, err := io.WriteString(conn, "PONG\r\n"); err != nil {
+				return delivered, err
+			}
+		case "-ERR":
+			return delivered, fmt.Errorf("server error: %s", args)
+		case "PONG", "+OK", "INFO":
+		default:
+			return delivered, fmt.Errorf("unexpected message: %q", line)
+		}
+	}
+}
+
+// readLine reads a protocol line without its CRLF
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readPayload reads the payload of a MSG, whose arguments are the subject,
+// the subscription ID, an optional reply subject and the payload size
+func readPayload(r *bufio.Reader, args string) ([]byte, error) {
+	fields := strings.Fields(args)
+	if len(fields) < 3 || len(fields) > 4 {
+		return nil, fmt.Errorf("malformed MSG: %q", args)
+	}
+	size, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil || size < 0 || size > maxPayload {
+		return nil, fmt.Errorf("malformed MSG size: %q", args)
+	}
+	payload := make([]byte, size+2)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	if string(payload[size:]) != "\r\n" {
+		return nil, errors.New("MSG payload not terminated by CRLF")
+	}
+	return payload[:size], nil
+}
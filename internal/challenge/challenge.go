@@ -0,0 +1,113 @@
+// Package challenge verifies the bot challenges, such as CAPTCHAs, that
+// clients solve before placing orders without an API key.
+package challenge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+)
+
+// Verification endpoints of the supported providers
+const (
+	TurnstileURL = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+	RecaptchaURL = "https://www.google.com/recaptcha/api/siteverify"
+)
+
+var (
+	// ErrMissingToken is returned when the client sent no token
+	ErrMissingToken = errors.New("challenge token missing")
+	// ErrRejected is returned when the provider does not accept the token
+	ErrRejected = errors.New("challenge token rejected")
+)
+
+// Verifier checks the token of a solved challenge. It returns ErrMissingToken
+// or ErrRejected when the client failed the challenge, and any other error
+// when the token could not be checked.
+type Verifier interface {
+	Verify(ctx context.Context, token, remoteIP string) error
+}
+
+// NewVerifier creates the Verifier described by cfg. It returns nil when
+// orders are not challenged.
+func NewVerifier(cfg config.Challenge) (Verifier, error) {
+	endpoint := cfg.VerifyURL
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case config.ChallengeTurnstile:
+		if endpoint == "" {
+			endpoint = TurnstileURL
+		}
+	case config.ChallengeRecaptcha:
+		if endpoint == "" {
+			endpoint = RecaptchaURL
+		}
+	default:
+		return nil, fmt.Errorf("unknown challenge provider %q", cfg.Provider)
+	}
+
+	return &siteVerify{
+		endpoint: endpoint,
+		secret:   cfg.Secret,
+		client:   &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+// siteVerify checks tokens with a siteverify endpoint, the API Turnstile and
+// reCAPTCHA share
+type siteVerify struct {
+	endpoint string
+	secret   string
+	client   *http.Client
+}
+
+// siteVerifyResponse is the part of a siteverify response the verifier reads
+type siteVerifyResponse struct {
+	Success    bool     `json:"success"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+// Verify implements Verifier
+func (s *siteVerify) Verify(ctx context.Context, token, remoteIP string) error {
+	if token == "" {
+		return ErrMissingToken
+	}
+
+	form := url.Values{"secret": {s.secret}, "response": {token}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create verification request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to verify challenge: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to verify challenge: provider returned %s", resp.Status)
+	}
+
+	var result siteVerifyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to parse verification response: %w", err)
+	}
+	if !result.Success {
+		if len(result.ErrorCodes) > 0 {
+			return fmt.Errorf("%w: %s", ErrRejected, strings.Join(result.ErrorCodes, ", "))
+		}
+		return ErrRejected
+	}
+	return nil
+}
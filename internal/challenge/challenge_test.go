@@ -0,0 +1,73 @@
+package challenge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewVerifier(t *testing.T) {
+	verifier, err := NewVerifier(config.Challenge{})
+	require.NoError(t, err)
+	assert.Nil(t, verifier)
+
+	verifier, err = NewVerifier(config.Challenge{Provider: config.ChallengeTurnstile, Secret: "secret", Timeout: time.Second})
+	require.NoError(t, err)
+	assert.Equal(t, TurnstileURL, verifier.(*siteVerify).endpoint)
+
+	verifier, err = NewVerifier(config.Challenge{Provider: config.ChallengeRecaptcha, Secret: "secret", Timeout: time.Second})
+	require.NoError(t, err)
+	assert.Equal(t, RecaptchaURL, verifier.(*siteVerify).endpoint)
+
+	_, err = NewVerifier(config.Challenge{Provider: "hcaptcha"})
+	assert.Error(t, err)
+}
+
+func TestSiteVerify(t *testing.T) {
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "secret", r.PostForm.Get("secret"))
+
+		switch r.PostForm.Get("response") {
+		case "solved":
+			assert.Equal(t, "192.0.2.10", r.PostForm.Get("remoteip"))
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+		case "broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error-codes": []string{"invalid-input-response"}})
+		}
+	}))
+	t.Cleanup(provider.Close)
+
+	verifier, err := NewVerifier(config.Challenge{
+		Provider:  config.ChallengeTurnstile,
+		Secret:    "secret",
+		VerifyURL: provider.URL,
+		Timeout:   time.Second,
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	assert.NoError(t, verifier.Verify(ctx, "solved", "192.0.2.10"))
+
+	err = verifier.Verify(ctx, "", "192.0.2.10")
+	assert.True(t, errors.Is(err, ErrMissingToken))
+
+	err = verifier.Verify(ctx, "forged", "192.0.2.10")
+	assert.True(t, errors.Is(err, ErrRejected))
+	assert.Contains(t, err.Error(), "invalid-input-response")
+
+	// Provider failures are not the client's fault
+	err = verifier.Verify(ctx, "broken", "192.0.2.10")
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, ErrRejected))
+}
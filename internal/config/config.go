@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"log"
+	"net/url"
 	"os"
 	"strings"
 	"time"
@@ -10,12 +11,33 @@ import (
 	"github.com/spf13/viper"
 )
 
+// DefaultTenantID identifies the tenant served from the top-level files
+const DefaultTenantID = "default"
+
+// DefaultLocale is the catalog language used when none is configured
+const DefaultLocale = "en"
+
+// DefaultInvoicePrefix starts invoice numbers when no prefix is configured
+const DefaultInvoicePrefix = "INV-"
+
+// maxInvoicePrefix bounds invoice prefixes, as invoices print them in full
+const maxInvoicePrefix = 16
+
 // Server represents server configuration
 type Server struct {
-	Port         string        `mapstructure:"port"`
-	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
-	WriteTimeout time.Duration `mapstructure:"write_timeout"`
-	IdleTimeout  time.Duration `mapstructure:"idle_timeout"`
+	Port             string        `mapstructure:"port"`
+	ReadTimeout      time.Duration `mapstructure:"read_timeout"`
+	WriteTimeout     time.Duration `mapstructure:"write_timeout"`
+	IdleTimeout      time.Duration `mapstructure:"idle_timeout"`
+	MaxBodySize      int64         `mapstructure:"max_body_size"`     // Largest JSON request body accepted, in bytes
+	StrictJSON       bool          `mapstructure:"strict_json"`       // Reject JSON request bodies with unknown fields
+	Links            bool          `mapstructure:"links"`             // Add _links to order and product responses
+	Envelope         bool          `mapstructure:"envelope"`          // Envelope JSON responses unless clients opt out
+	DeprecationsFile string        `mapstructure:"deprecations_file"` // JSON file of deprecated routes and fields; empty deprecates nothing
+	CatalogTimeout   time.Duration `mapstructure:"catalog_timeout"`   // Longest a /products request may take; zero for no limit
+	OrderTimeout     time.Duration `mapstructure:"order_timeout"`     // Longest an /orders request may take; zero for no limit
+	CORSOrigins      []string      `mapstructure:"cors_origins"`      // Origins browsers may call the API from; "*" for any, none for same-origin only
+	CORSMaxAge       time.Duration `mapstructure:"cors_max_age"`      // How long browsers may cache a preflight answer
 }
 
 // Files represents file paths configuration
@@ -26,15 +48,397 @@ type Files struct {
 
 // LoggingConfig holds logging configuration.
 type LoggingConfig struct {
-	Level  string `mapstructure:"level"`  // Logging level (e.g., "info", "debug", "warn")
-	Format string `mapstructure:"format"` // Log format (e.g., "json", "text")
+	Level        string        `mapstructure:"level"`         // Logging level (e.g., "info", "debug", "warn")
+	Format       string        `mapstructure:"format"`        // Log format (e.g., "json", "text")
+	AccessFormat string        `mapstructure:"access_format"` // Access log format: "text", "json" or "combined" (Apache's Combined Log Format)
+	File         string        `mapstructure:"file"`          // File logs are written to and rotated; empty logs to stderr
+	MaxSize      int64         `mapstructure:"max_size"`      // Size in bytes the log file is rotated at; zero never rotates by size
+	MaxAge       time.Duration `mapstructure:"max_age"`       // How long the log file is written to before it is rotated; zero never rotates by age
+	MaxBackups   int           `mapstructure:"max_backups"`   // Rotated log files kept, newest first; zero keeps them all
+	SampleLimit  int           `mapstructure:"sample_limit"`  // Lines a minute each high-volume message may log before the rest are only counted; zero logs every line
+}
+
+// Auth represents authentication configuration
+type Auth struct {
+	APIKeys       []string      `mapstructure:"api_keys"`       // Keys of the admin role, imported into KeysFile at startup
+	KitchenKeys   []string      `mapstructure:"kitchen_keys"`   // Keys of the kitchen role, imported into KeysFile at startup
+	SupportKeys   []string      `mapstructure:"support_keys"`   // Keys of the support role, imported into KeysFile at startup
+	KeysFile      string        `mapstructure:"keys_file"`      // JSON file API keys are persisted to, hashed; empty keeps them in memory
+	BlocklistFile string        `mapstructure:"blocklist_file"` // JSON file blocked callers are persisted to; empty keeps them in memory
+	CustomersFile string        `mapstructure:"customers_file"` // JSON file customer profiles are persisted to; empty keeps them in memory
+	JWTSecret     string        `mapstructure:"jwt_secret"`     // Key customer session tokens are signed with; a random key is used when empty
+	SessionTTL    time.Duration `mapstructure:"session_ttl"`    // How long customer session tokens stay valid
+	RefreshTTL    time.Duration `mapstructure:"refresh_ttl"`    // How long an unused refresh token stays valid
+	Google        OIDCProvider  `mapstructure:"google"`         // Sign in with Google
+	Apple         OIDCProvider  `mapstructure:"apple"`          // Sign in with Apple
+	Passwords     Passwords     `mapstructure:"passwords"`      // Sign in with an email address and password
+	AppURL        string        `mapstructure:"app_url"`        // Customer app that links in account emails open; emails hold bare codes when empty
+}
+
+// Password hashing algorithms
+const (
+	PasswordArgon2id = "argon2id"
+	PasswordBcrypt   = "bcrypt"
+)
+
+// Passwords represents how customer passwords are stored, how many wrong
+// guesses lock an account, and how long emailed codes stay valid
+type Passwords struct {
+	Hash            string        `mapstructure:"hash"`              // Algorithm new passwords are hashed with; older hashes are upgraded at sign-in
+	MaxFailedLogins int           `mapstructure:"max_failed_logins"` // Wrong passwords in a row that lock the account; 0 never locks
+	UnlockTTL       time.Duration `mapstructure:"unlock_ttl"`        // How long the code in an unlock email stays valid
+	VerifyTTL       time.Duration `mapstructure:"verify_ttl"`        // How long the code in an address verification email stays valid
+	ResetTTL        time.Duration `mapstructure:"reset_ttl"`         // How long the code in a password reset email stays valid
+}
+
+// Email represents how emails to customers are sent
+type Email struct {
+	SMTPAddr     string `mapstructure:"smtp_addr"`     // host:port of the SMTP server; emails are logged when empty
+	SMTPUsername string `mapstructure:"smtp_username"` // Username for PLAIN authentication; none when empty
+	SMTPPassword string `mapstructure:"smtp_password"`
+	From         string `mapstructure:"from"` // Sender address
+}
+
+// Usage represents where the usage of each API key is persisted
+type Usage struct {
+	File          string        `mapstructure:"file"`           // JSON file usage counts are persisted to; empty keeps them in memory
+	FlushInterval time.Duration `mapstructure:"flush_interval"` // How often counts are written to the file
+}
+
+// Invoices represents where the invoice numbers issued to each tenant's
+// orders are recorded
+type Invoices struct {
+	Dir    string `mapstructure:"dir"`    // Directory each tenant's numbers are journaled to, as <tenant>.jsonl; empty keeps them in memory
+	Prefix string `mapstructure:"prefix"` // Text the default tenant's invoice numbers start with, e.g. INV-
+}
+
+// Orders represents where each tenant's orders are kept
+type Orders struct {
+	EventsDir          string        `mapstructure:"events_dir"`          // Directory each tenant's order events are logged to, as <tenant>.jsonl; empty keeps them in memory
+	ProjectionInterval time.Duration `mapstructure:"projection_interval"` // How often dashboards catch up with the order events
+	PaymentLinkURL     string        `mapstructure:"payment_link_url"`    // Payment page each person's part of a split bill links to; no links are made when empty
+	TableSecret        string        `mapstructure:"table_secret"`        // Key table QR tokens are signed with; dine-in orders are refused when empty
+	HoldTTL            time.Duration `mapstructure:"hold_ttl"`            // How long stock is held for a group cart after it last changed; 0 holds none
+	Retention          time.Duration `mapstructure:"retention"`           // How long orders are kept after they were created; 0 keeps them forever
+	ArchiveDir         string        `mapstructure:"archive_dir"`         // Directory orders past retention are archived to, as <tenant>.jsonl; empty discards them
+}
+
+// Accounting represents how orders are exported to accounting software.
+// Accounts are account codes for Xero and product/service names for
+// QuickBooks.
+type Accounting struct {
+	SalesAccount    string            `mapstructure:"sales_account"`    // Account of sales in categories not mapped
+	Categories      map[string]string `mapstructure:"categories"`       // Product category to account, matched ignoring case
+	DeliveryAccount string            `mapstructure:"delivery_account"` // Account of delivery fees; the sales account when empty
+	FeesAccount     string            `mapstructure:"fees_account"`     // Account of service fees; the sales account when empty
+	TaxType         string            `mapstructure:"tax_type"`         // Tax rate of taxed lines; the account's default when empty
+	ExemptTaxType   string            `mapstructure:"exempt_tax_type"`  // Tax rate of untaxed lines; the account's default when empty
+	DateFormat      string            `mapstructure:"date_format"`      // Go layout of invoice dates, as the software's region expects
+}
+
+// SLO is a service level objective of a named route group, such as
+// "ordering". Requests answered with a 5xx status count against its
+// availability, and requests slower than Latency against its latency.
+type SLO struct {
+	Group         string        `mapstructure:"group"`          // Route group the objective covers
+	Availability  float64       `mapstructure:"availability"`   // Share of requests to answer without a 5xx status, e.g. 0.999
+	Latency       time.Duration `mapstructure:"latency"`        // Time requests are to be answered within; zero for no latency objective
+	LatencyTarget float64       `mapstructure:"latency_target"` // Share of requests to answer within Latency, e.g. 0.99
+	Window        time.Duration `mapstructure:"window"`         // Period the error budget is spent over, at least 6h
+}
+
+// Default SLO settings
+const (
+	DefaultSLOLatencyTarget = 0.99
+	DefaultSLOWindow        = 30 * 24 * time.Hour
+	minSLOWindow            = 6 * time.Hour // The longest window burn rates are reported over
+)
+
+// validate checks the objective's targets and window
+func (s SLO) validate() error {
+	if s.Group == "" {
+		return fmt.Errorf("invalid slos: group is required")
+	}
+	if s.Availability <= 0 || s.Availability >= 1 {
+		return fmt.Errorf("invalid slos.%s: availability must be between 0 and 1", s.Group)
+	}
+	if s.Latency < 0 {
+		return fmt.Errorf("invalid slos.%s: latency must not be negative", s.Group)
+	}
+	if s.Latency > 0 && (s.LatencyTarget <= 0 || s.LatencyTarget >= 1) {
+		return fmt.Errorf("invalid slos.%s: latencytarget must be between 0 and 1", s.Group)
+	}
+	if s.Window < minSLOWindow {
+		return fmt.Errorf("invalid slos.%s: window must be at least %s", s.Group, minSLOWindow)
+	}
+	return nil
+}
+
+// Rounding modes
+const (
+	RoundingHalfUp   = "half-up"   // Ties round away from zero
+	RoundingHalfEven = "half-even" // Ties round to the even increment, as banker's rounding does
+)
+
+// DefaultRoundingIncrement is the increment of rounding rules that leave it
+// out, and of currencies without a rule
+const DefaultRoundingIncrement = 0.01
+
+// Rounding is how the amounts charged in a currency are rounded
+type Rounding struct {
+	Currency  string  `mapstructure:"currency"`  // ISO 4217 code, e.g. CHF
+	Mode      string  `mapstructure:"mode"`      // half-up or half-even
+	Increment float64 `mapstructure:"increment"` // Smallest amount charged, e.g. 0.05
+}
+
+// validate checks that the rule names a currency and rounds to a positive
+// increment in a known mode
+func (r Rounding) validate() error {
+	if !isCurrency(r.Currency) {
+		return fmt.Errorf("invalid rounding currency: %q (must be an ISO 4217 code)", r.Currency)
+	}
+	if r.Mode != RoundingHalfUp && r.Mode != RoundingHalfEven {
+		return fmt.Errorf("invalid rounding.%s: mode must be %s or %s", r.Currency, RoundingHalfUp, RoundingHalfEven)
+	}
+	if r.Increment <= 0 || r.Increment > 1 {
+		return fmt.Errorf("invalid rounding.%s: increment must be above 0 and at most 1", r.Currency)
+	}
+	return nil
+}
+
+// isCurrency reports whether code looks like an ISO 4217 currency code
+func isCurrency(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// RoundingFor returns the rule amounts charged in currency are rounded by:
+// its rule in Rounding, or half-up to DefaultRoundingIncrement. It reports
+// false, as amounts are not rounded, when currency is empty.
+func (c *Config) RoundingFor(currency string) (Rounding, bool) {
+	if currency == "" {
+		return Rounding{}, false
+	}
+	for _, rule := range c.Rounding {
+		if rule.Currency == currency {
+			return rule, true
+		}
+	}
+	return Rounding{Currency: currency, Mode: RoundingHalfUp, Increment: DefaultRoundingIncrement}, true
+}
+
+// CatalogSync represents the upstream catalog service that pushes product
+// updates. Updates are not consumed when URL is empty.
+type CatalogSync struct {
+	URL     string `mapstructure:"url"`     // NATS server the events are published to, e.g. nats://localhost:4222
+	Subject string `mapstructure:"subject"` // Subject product update events are published on
+}
+
+// Cache represents how long the results of store lookups are kept in
+// memory
+type Cache struct {
+	ProductTTL time.Duration `mapstructure:"product_ttl"` // How long a product lookup, found or not, is remembered; zero disables caching
+}
+
+// Products represents how the products file is loaded
+type Products struct {
+	Lenient bool `mapstructure:"lenient"` // Skip invalid products rather than failing the load
+}
+
+// Coupon file integrity policies, for a coupon file that is not read in
+// full or does not match the coupon directory's manifest
+const (
+	CouponIntegrityFail       = "fail"       // Refuse to load the coupon set
+	CouponIntegrityWarn       = "warn"       // Log the file and use the codes read from it
+	CouponIntegrityQuarantine = "quarantine" // Log the file and leave all its codes out
+)
+
+// Coupons represents how coupon files are loaded
+type Coupons struct {
+	Integrity string `mapstructure:"integrity"` // Policy for incomplete coupon files
+}
+
+// OIDCProvider represents an OpenID Connect identity provider customers can
+// sign in with. A provider without client IDs is disabled.
+type OIDCProvider struct {
+	ClientIDs []string `mapstructure:"client_ids"` // Audiences accepted in ID tokens, one per app or website
+	Issuer    string   `mapstructure:"issuer"`     // Issuer ID tokens must name
+	JWKSURL   string   `mapstructure:"jwks_url"`   // Where the provider publishes its signing keys
+}
+
+// Images represents where uploaded product images are stored and served from
+type Images struct {
+	Dir     string        `mapstructure:"dir"`      // Directory renditions are written to and served from
+	BaseURL string        `mapstructure:"base_url"` // Absolute URL the directory is served at
+	MaxAge  time.Duration `mapstructure:"max_age"`  // How long clients may cache served images
+	Signing ImageSigning  `mapstructure:"signing"`
+}
+
+// Image URL signing schemes
+const (
+	SigningCloudFront = "cloudfront" // CloudFront canned policy, signed with an RSA key pair
+	SigningCloudCDN   = "cloudcdn"   // Google Cloud CDN, signed with an HMAC key
+)
+
+// ImageSigning represents how image URLs are signed when a CDN serves them
+// from a private bucket
+type ImageSigning struct {
+	Type      string        `mapstructure:"type"`       // Empty to serve URLs unsigned, or a signing scheme
+	URLPrefix string        `mapstructure:"url_prefix"` // Only URLs starting with this prefix are signed
+	KeyID     string        `mapstructure:"key_id"`     // CloudFront key pair ID or Cloud CDN key name
+	KeyFile   string        `mapstructure:"key_file"`   // CloudFront RSA private key (PEM) or Cloud CDN key (base64url)
+	TTL       time.Duration `mapstructure:"ttl"`        // How long signed URLs stay valid
+}
+
+// Challenge providers
+const (
+	ChallengeTurnstile = "turnstile" // Cloudflare Turnstile
+	ChallengeRecaptcha = "recaptcha" // Google reCAPTCHA
+)
+
+// Challenge represents the bot challenge unauthenticated orders must pass
+type Challenge struct {
+	Provider  string        `mapstructure:"provider"`   // Empty to accept orders unchallenged, or a challenge provider
+	Secret    string        `mapstructure:"secret"`     // Secret key tokens are verified with
+	VerifyURL string        `mapstructure:"verify_url"` // Overrides the provider's verification endpoint
+	Timeout   time.Duration `mapstructure:"timeout"`    // How long to wait for the provider to verify a token
+}
+
+// Charges represents the tax and fees added to order totals
+type Charges struct {
+	TaxRate    float64 `mapstructure:"tax_rate"`    // Fraction of the discounted subtotal, e.g. 0.1 for 10%
+	ServiceFee float64 `mapstructure:"service_fee"` // Flat fee added to every order
+}
+
+// Limits represents the smallest and largest orders a restaurant accepts.
+// Zero disables a limit.
+type Limits struct {
+	MaxQuantity int     `mapstructure:"max_quantity"` // Largest quantity of a single line item
+	MaxItems    int     `mapstructure:"max_items"`    // Most distinct products (and variants) in an order
+	MaxTotal    float64 `mapstructure:"max_total"`    // Largest order total, after discounts, charges and delivery
+	MinPickup   float64 `mapstructure:"min_pickup"`   // Smallest subtotal of pickup orders, after discounts
+	MinDelivery float64 `mapstructure:"min_delivery"` // Smallest subtotal of delivery orders, after discounts
+}
+
+// Velocity represents how many orders, and how much, a single client may
+// order before further orders are held for review or refused. Zero disables
+// a rule.
+type Velocity struct {
+	HoldOrdersPerHour int     `mapstructure:"hold_orders_per_hour"` // Orders per client per hour above which orders are held
+	MaxOrdersPerHour  int     `mapstructure:"max_orders_per_hour"`  // Orders per client per hour above which orders are refused
+	HoldTotalPerDay   float64 `mapstructure:"hold_total_per_day"`   // Spend per client per day above which orders are held
+	MaxTotalPerDay    float64 `mapstructure:"max_total_per_day"`    // Spend per client per day above which orders are refused
+}
+
+// Hours represents weekly opening hours. With no weekly hours the
+// restaurant is always open.
+type Hours struct {
+	Timezone   string              `mapstructure:"timezone"`    // IANA zone the hours are in, UTC by default
+	Weekly     map[string][]string `mapstructure:"weekly"`      // Day name to "HH:MM-HH:MM" ranges
+	OrderAhead time.Duration       `mapstructure:"order_ahead"` // How long before opening orders are accepted
+}
+
+// Zone represents an area a restaurant delivers to. An address is in the zone
+// when its postcode is listed or its coordinates fall inside the polygon.
+type Zone struct {
+	Name      string       `mapstructure:"name"`
+	Postcodes []string     `mapstructure:"postcodes"`
+	Polygon   [][2]float64 `mapstructure:"polygon"` // Latitude/longitude vertices
+	Fee       float64      `mapstructure:"fee"`     // Delivery fee added to orders in the zone
+}
+
+// Promotion represents a time-based price modifier, such as a happy hour
+// discount or a late-night surcharge
+type Promotion struct {
+	Name       string              `mapstructure:"name"`
+	Percent    float64             `mapstructure:"percent"`    // Price change: negative for a discount, positive for a surcharge
+	Categories []string            `mapstructure:"categories"` // Product categories it applies to, matched ignoring case; all when empty
+	Weekly     map[string][]string `mapstructure:"weekly"`     // Day name -> "HH:MM-HH:MM" ranges it is in effect, in the hours time zone
+}
+
+// Menu represents a named menu, such as breakfast, served at set times of
+// the week. Products are on the menu by category or ID.
+type Menu struct {
+	Name       string              `mapstructure:"name"`
+	Categories []string            `mapstructure:"categories"` // Product categories on the menu, matched ignoring case
+	Products   []string            `mapstructure:"products"`   // IDs of further products on the menu
+	Weekly     map[string][]string `mapstructure:"weekly"`     // Day name -> "HH:MM-HH:MM" ranges it is served, in the hours time zone
+}
+
+// Kitchen busy policies, for an order placed while the kitchen has its
+// maximum of active orders
+const (
+	KitchenBusyReject = "reject" // Refuse the order, suggesting when to retry
+	KitchenBusyExtend = "extend" // Take the order, quoting it BusyDelay later
+)
+
+// Kitchen represents how long orders take to prepare and deliver
+type Kitchen struct {
+	Stations        int                      `mapstructure:"stations"`          // Orders prepared in parallel
+	PrepTimes       map[string]time.Duration `mapstructure:"prep_times"`        // Preparation time per product category, matched ignoring case
+	DefaultPrepTime time.Duration            `mapstructure:"default_prep_time"` // Preparation time of unlisted categories
+	DeliveryTime    time.Duration            `mapstructure:"delivery_time"`     // Travel time added for delivery orders
+	MaxActive       int                      `mapstructure:"max_active"`        // Orders queued or being prepared at once; 0 for no limit
+	WhenBusy        string                   `mapstructure:"when_busy"`         // Policy for orders over MaxActive
+	BusyDelay       time.Duration            `mapstructure:"busy_delay"`        // Time added to orders taken over MaxActive with the extend policy
+}
+
+// Tenant represents a restaurant served from its own catalog and coupon set
+type Tenant struct {
+	ID         string      `mapstructure:"id"`
+	Hosts      []string    `mapstructure:"hosts"` // Hostnames routed to this tenant
+	Files      Files       `mapstructure:"files"`
+	Charges    Charges     `mapstructure:"charges"`
+	Limits     Limits      `mapstructure:"limits"`
+	Velocity   Velocity    `mapstructure:"velocity"`
+	Hours      Hours       `mapstructure:"hours"`
+	Zones      []Zone      `mapstructure:"zones"`
+	Promotions []Promotion `mapstructure:"promotions"`
+	Menus      []Menu      `mapstructure:"menus"`
+	Kitchen    Kitchen     `mapstructure:"kitchen"`
+	Locale     string      `mapstructure:"locale"`   // Language of the catalog's untranslated fields
+	Currency   string      `mapstructure:"currency"` // Currency the tenant charges in; amounts are not rounded when empty
+
+	InvoicePrefix string `mapstructure:"invoice_prefix"` // Text the tenant's invoice numbers start with
 }
 
 // Config represents the application configuration
 type Config struct {
-	Server  Server        `mapstructure:"server"`
-	Files   Files         `mapstructure:"files"`
-	Logging LoggingConfig `mapstructure:"logging"`
+	Server     Server        `mapstructure:"server"`
+	Files      Files         `mapstructure:"files"`
+	Logging    LoggingConfig `mapstructure:"logging"`
+	Auth       Auth          `mapstructure:"auth"`
+	Images     Images        `mapstructure:"images"`
+	Challenge  Challenge     `mapstructure:"challenge"`
+	Email      Email         `mapstructure:"email"`
+	Usage      Usage         `mapstructure:"usage"`
+	Invoices   Invoices      `mapstructure:"invoices"`
+	Orders     Orders        `mapstructure:"orders"`
+	Accounting Accounting    `mapstructure:"accounting"`
+	Catalog    CatalogSync   `mapstructure:"catalog"`
+	SLOs       []SLO         `mapstructure:"slos"` // Objectives of route groups
+	Cache      Cache         `mapstructure:"cache"`
+	Products   Products      `mapstructure:"products"`
+	Coupons    Coupons       `mapstructure:"coupons"`
+	Charges    Charges       `mapstructure:"charges"`    // Charges of the default tenant
+	Limits     Limits        `mapstructure:"limits"`     // Order limits of the default tenant
+	Velocity   Velocity      `mapstructure:"velocity"`   // Velocity rules of the default tenant
+	Hours      Hours         `mapstructure:"hours"`      // Opening hours of the default tenant
+	Zones      []Zone        `mapstructure:"zones"`      // Delivery zones of the default tenant
+	Promotions []Promotion   `mapstructure:"promotions"` // Price modifiers of the default tenant
+	Menus      []Menu        `mapstructure:"menus"`      // Menus of the default tenant
+	Kitchen    Kitchen       `mapstructure:"kitchen"`    // Kitchen of the default tenant
+	Locale     string        `mapstructure:"locale"`     // Language of the default tenant's untranslated catalog fields
+	Currency   string        `mapstructure:"currency"`   // Currency the default tenant charges in; amounts are not rounded when empty
+	Rounding   []Rounding    `mapstructure:"rounding"`   // How amounts are rounded, by currency
+	Tenants    []Tenant      `mapstructure:"tenants"`    // Additional tenants besides the default one
 }
 
 // Load loads the configuration from the specified file and environment variables
@@ -63,18 +467,155 @@ func Load() (*Config, error) {
 	v.BindEnv("server.readtimeout", "SERVER_READ_TIMEOUT")
 	v.BindEnv("server.writetimeout", "SERVER_WRITE_TIMEOUT")
 	v.BindEnv("server.idletimeout", "SERVER_IDLE_TIMEOUT")
+	v.BindEnv("server.maxbodysize", "SERVER_MAX_BODY_SIZE")
+	v.BindEnv("server.strictjson", "SERVER_STRICT_JSON")
+	v.BindEnv("server.links", "SERVER_LINKS")
+	v.BindEnv("server.envelope", "SERVER_ENVELOPE")
+	v.BindEnv("server.deprecationsfile", "DEPRECATIONS_FILE")
+	v.BindEnv("server.catalogtimeout", "SERVER_CATALOG_TIMEOUT")
+	v.BindEnv("server.ordertimeout", "SERVER_ORDER_TIMEOUT")
+	v.BindEnv("server.corsorigins", "SERVER_CORS_ORIGINS")
+	v.BindEnv("server.corsmaxage", "SERVER_CORS_MAX_AGE")
 	v.BindEnv("files.productsfile", "PRODUCTS_FILE")
 	v.BindEnv("files.couponsdir", "COUPONS_DIR")
 	v.BindEnv("logging.level", "LOG_LEVEL")
 	v.BindEnv("logging.format", "LOG_FORMAT")
+	v.BindEnv("logging.accessformat", "LOG_ACCESS_FORMAT")
+	v.BindEnv("logging.file", "LOG_FILE")
+	v.BindEnv("logging.maxsize", "LOG_MAX_SIZE")
+	v.BindEnv("logging.maxage", "LOG_MAX_AGE")
+	v.BindEnv("logging.maxbackups", "LOG_MAX_BACKUPS")
+	v.BindEnv("logging.samplelimit", "LOG_SAMPLE_LIMIT")
+	v.BindEnv("auth.apikeys", "API_KEYS")
+	v.BindEnv("auth.kitchenkeys", "KITCHEN_API_KEYS")
+	v.BindEnv("auth.supportkeys", "SUPPORT_API_KEYS")
+	v.BindEnv("auth.keysfile", "API_KEYS_FILE")
+	v.BindEnv("auth.blocklistfile", "BLOCKLIST_FILE")
+	v.BindEnv("auth.customersfile", "CUSTOMERS_FILE")
+	v.BindEnv("auth.jwtsecret", "JWT_SECRET")
+	v.BindEnv("auth.sessionttl", "SESSION_TTL")
+	v.BindEnv("auth.refreshttl", "REFRESH_TTL")
+	v.BindEnv("auth.google.clientids", "GOOGLE_CLIENT_IDS")
+	v.BindEnv("auth.google.issuer", "GOOGLE_ISSUER")
+	v.BindEnv("auth.google.jwksurl", "GOOGLE_JWKS_URL")
+	v.BindEnv("auth.apple.clientids", "APPLE_CLIENT_IDS")
+	v.BindEnv("auth.apple.issuer", "APPLE_ISSUER")
+	v.BindEnv("auth.apple.jwksurl", "APPLE_JWKS_URL")
+	v.BindEnv("auth.passwords.hash", "PASSWORD_HASH")
+	v.BindEnv("auth.passwords.maxfailedlogins", "MAX_FAILED_LOGINS")
+	v.BindEnv("auth.passwords.unlockttl", "UNLOCK_TTL")
+	v.BindEnv("auth.passwords.verifyttl", "VERIFY_EMAIL_TTL")
+	v.BindEnv("auth.passwords.resetttl", "PASSWORD_RESET_TTL")
+	v.BindEnv("auth.appurl", "APP_URL")
+	v.BindEnv("charges.taxrate", "TAX_RATE")
+	v.BindEnv("charges.servicefee", "SERVICE_FEE")
+	v.BindEnv("limits.maxquantity", "ORDER_MAX_QUANTITY")
+	v.BindEnv("limits.maxitems", "ORDER_MAX_ITEMS")
+	v.BindEnv("limits.maxtotal", "ORDER_MAX_TOTAL")
+	v.BindEnv("limits.minpickup", "ORDER_MIN_PICKUP")
+	v.BindEnv("limits.mindelivery", "ORDER_MIN_DELIVERY")
+	v.BindEnv("velocity.holdordersperhour", "VELOCITY_HOLD_ORDERS_PER_HOUR")
+	v.BindEnv("velocity.maxordersperhour", "VELOCITY_MAX_ORDERS_PER_HOUR")
+	v.BindEnv("velocity.holdtotalperday", "VELOCITY_HOLD_TOTAL_PER_DAY")
+	v.BindEnv("velocity.maxtotalperday", "VELOCITY_MAX_TOTAL_PER_DAY")
+	v.BindEnv("hours.timezone", "HOURS_TIMEZONE")
+	v.BindEnv("locale", "CATALOG_LOCALE")
+	v.BindEnv("currency", "CURRENCY")
+	v.BindEnv("images.dir", "IMAGES_DIR")
+	v.BindEnv("images.baseurl", "IMAGES_BASE_URL")
+	v.BindEnv("images.maxage", "IMAGES_MAX_AGE")
+	v.BindEnv("images.signing.type", "IMAGES_SIGNING_TYPE")
+	v.BindEnv("images.signing.urlprefix", "IMAGES_SIGNING_URL_PREFIX")
+	v.BindEnv("images.signing.keyid", "IMAGES_SIGNING_KEY_ID")
+	v.BindEnv("images.signing.keyfile", "IMAGES_SIGNING_KEY_FILE")
+	v.BindEnv("images.signing.ttl", "IMAGES_SIGNING_TTL")
+	v.BindEnv("challenge.provider", "CHALLENGE_PROVIDER")
+	v.BindEnv("challenge.secret", "CHALLENGE_SECRET")
+	v.BindEnv("challenge.verifyurl", "CHALLENGE_VERIFY_URL")
+	v.BindEnv("challenge.timeout", "CHALLENGE_TIMEOUT")
+	v.BindEnv("email.smtpaddr", "SMTP_ADDR")
+	v.BindEnv("email.smtpusername", "SMTP_USERNAME")
+	v.BindEnv("email.smtppassword", "SMTP_PASSWORD")
+	v.BindEnv("email.from", "EMAIL_FROM")
+	v.BindEnv("usage.file", "USAGE_FILE")
+	v.BindEnv("usage.flushinterval", "USAGE_FLUSH_INTERVAL")
+	v.BindEnv("invoices.dir", "INVOICES_DIR")
+	v.BindEnv("invoices.prefix", "INVOICE_PREFIX")
+	v.BindEnv("orders.eventsdir", "ORDER_EVENTS_DIR")
+	v.BindEnv("orders.projectioninterval", "ORDER_PROJECTION_INTERVAL")
+	v.BindEnv("orders.paymentlinkurl", "ORDER_PAYMENT_LINK_URL")
+	v.BindEnv("orders.tablesecret", "ORDER_TABLE_SECRET")
+	v.BindEnv("orders.holdttl", "ORDER_HOLD_TTL")
+	v.BindEnv("orders.retention", "ORDER_RETENTION")
+	v.BindEnv("orders.archivedir", "ORDER_ARCHIVE_DIR")
+	v.BindEnv("accounting.salesaccount", "ACCOUNTING_SALES_ACCOUNT")
+	v.BindEnv("accounting.deliveryaccount", "ACCOUNTING_DELIVERY_ACCOUNT")
+	v.BindEnv("accounting.feesaccount", "ACCOUNTING_FEES_ACCOUNT")
+	v.BindEnv("accounting.taxtype", "ACCOUNTING_TAX_TYPE")
+	v.BindEnv("accounting.exempttaxtype", "ACCOUNTING_EXEMPT_TAX_TYPE")
+	v.BindEnv("accounting.dateformat", "ACCOUNTING_DATE_FORMAT")
+	v.BindEnv("catalog.url", "CATALOG_SYNC_URL")
+	v.BindEnv("catalog.subject", "CATALOG_SYNC_SUBJECT")
+	v.BindEnv("cache.productttl", "PRODUCT_CACHE_TTL")
+	v.BindEnv("products.lenient", "PRODUCTS_LENIENT")
+	v.BindEnv("coupons.integrity", "COUPON_INTEGRITY")
 
 	// Set defaults
 	v.SetDefault("server.port", ":8080")
 	v.SetDefault("server.readtimeout", "15s")
 	v.SetDefault("server.writetimeout", "15s")
 	v.SetDefault("server.idletimeout", "60s")
+	v.SetDefault("server.catalogtimeout", "2s")
+	v.SetDefault("server.ordertimeout", "5s")
+	v.SetDefault("server.corsmaxage", "10m")
+	v.SetDefault("server.maxbodysize", 1<<20)
+	v.SetDefault("server.strictjson", true)
+	v.SetDefault("server.links", false)
+	v.SetDefault("server.envelope", false)
 	v.SetDefault("logging.level", "info")
 	v.SetDefault("logging.format", "json")
+	v.SetDefault("logging.accessformat", "text")
+	v.SetDefault("logging.maxsize", 100<<20)
+	v.SetDefault("logging.maxage", "24h")
+	v.SetDefault("logging.maxbackups", 7)
+	v.SetDefault("logging.samplelimit", 10)
+	v.SetDefault("locale", DefaultLocale)
+	v.SetDefault("auth.keysfile", "./data/apikeys.json")
+	v.SetDefault("auth.blocklistfile", "./data/blocklist.json")
+	v.SetDefault("auth.customersfile", "./data/customers.json")
+	v.SetDefault("auth.sessionttl", "1h")
+	v.SetDefault("auth.refreshttl", "720h")
+	v.SetDefault("auth.google.issuer", "https://accounts.google.com")
+	v.SetDefault("auth.google.jwksurl", "https://www.googleapis.com/oauth2/v3/certs")
+	v.SetDefault("auth.apple.issuer", "https://appleid.apple.com")
+	v.SetDefault("auth.apple.jwksurl", "https://appleid.apple.com/auth/keys")
+	v.SetDefault("auth.passwords.hash", PasswordArgon2id)
+	v.SetDefault("auth.passwords.maxfailedlogins", 5)
+	v.SetDefault("auth.passwords.unlockttl", "24h")
+	v.SetDefault("auth.passwords.verifyttl", "48h")
+	v.SetDefault("auth.passwords.resetttl", "1h")
+	v.SetDefault("images.dir", "./data/images")
+	v.SetDefault("images.baseurl", "http://localhost:8080/public/images")
+	v.SetDefault("images.maxage", "24h")
+	v.SetDefault("images.signing.ttl", "1h")
+	v.SetDefault("challenge.timeout", "5s")
+	v.SetDefault("email.from", "no-reply@oolio.com")
+	v.SetDefault("usage.file", "./data/usage.json")
+	v.SetDefault("usage.flushinterval", "1m")
+	v.SetDefault("orders.eventsdir", "./data/orders")
+	v.SetDefault("orders.projectioninterval", "10s")
+	v.SetDefault("orders.holdttl", "10m")
+	v.SetDefault("orders.retention", "0")
+	v.SetDefault("accounting.salesaccount", "200")
+	v.SetDefault("accounting.dateformat", "02/01/2006")
+	v.SetDefault("invoices.dir", "./data/invoices")
+	v.SetDefault("invoices.prefix", DefaultInvoicePrefix)
+	v.SetDefault("catalog.subject", "catalog.products")
+	v.SetDefault("cache.productttl", "5s")
+	v.SetDefault("products.lenient", false)
+	v.SetDefault("coupons.integrity", CouponIntegrityWarn)
+	setKitchenDefaults(v)
+	setLimitsDefaults(v)
 
 	// Try to read config file (ignore error if not found)
 	_ = v.ReadInConfig()
@@ -92,22 +633,240 @@ func Load() (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("invalid server.idletimeout: %w", err)
 	}
+	catalogTimeout, err := time.ParseDuration(v.GetString("server.catalogtimeout"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid server.catalogtimeout: %w", err)
+	}
+	orderTimeout, err := time.ParseDuration(v.GetString("server.ordertimeout"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid server.ordertimeout: %w", err)
+	}
+	corsMaxAge, err := time.ParseDuration(v.GetString("server.corsmaxage"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid server.corsmaxage: %w", err)
+	}
+
+	imagesMaxAge, err := time.ParseDuration(v.GetString("images.maxage"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid images.maxage: %w", err)
+	}
+	signingTTL, err := time.ParseDuration(v.GetString("images.signing.ttl"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid images.signing.ttl: %w", err)
+	}
+	challengeTimeout, err := time.ParseDuration(v.GetString("challenge.timeout"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid challenge.timeout: %w", err)
+	}
+	logMaxAge, err := time.ParseDuration(v.GetString("logging.maxage"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid logging.maxage: %w", err)
+	}
+	usageFlushInterval, err := time.ParseDuration(v.GetString("usage.flushinterval"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid usage.flushinterval: %w", err)
+	}
+	projectionInterval, err := time.ParseDuration(v.GetString("orders.projectioninterval"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid orders.projectioninterval: %w", err)
+	}
+	holdTTL, err := time.ParseDuration(v.GetString("orders.holdttl"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid orders.holdttl: %w", err)
+	}
+	retention, err := time.ParseDuration(v.GetString("orders.retention"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid orders.retention: %w", err)
+	}
+	productTTL, err := time.ParseDuration(v.GetString("cache.productttl"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid cache.productttl: %w", err)
+	}
+	sessionTTL, err := time.ParseDuration(v.GetString("auth.sessionttl"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth.sessionttl: %w", err)
+	}
+	refreshTTL, err := time.ParseDuration(v.GetString("auth.refreshttl"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth.refreshttl: %w", err)
+	}
+	unlockTTL, err := time.ParseDuration(v.GetString("auth.passwords.unlockttl"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth.passwords.unlockttl: %w", err)
+	}
+	verifyTTL, err := time.ParseDuration(v.GetString("auth.passwords.verifyttl"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth.passwords.verifyttl: %w", err)
+	}
+	resetTTL, err := time.ParseDuration(v.GetString("auth.passwords.resetttl"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth.passwords.resetttl: %w", err)
+	}
+
+	hours, err := parseHours(v)
+	if err != nil {
+		return nil, err
+	}
+	zones, err := parseZones(v.Get("zones"))
+	if err != nil {
+		return nil, err
+	}
+	promotions, err := parsePromotions(v.Get("promotions"))
+	if err != nil {
+		return nil, err
+	}
+	menus, err := parseMenus(v.Get("menus"))
+	if err != nil {
+		return nil, err
+	}
+	slos, err := parseSLOs(v.Get("slos"))
+	if err != nil {
+		return nil, err
+	}
+	rounding, err := parseRounding(v.Get("rounding"))
+	if err != nil {
+		return nil, err
+	}
+	kitchen, err := parseKitchen(v)
+	if err != nil {
+		return nil, err
+	}
+	tenants, err := parseTenants(v.Get("tenants"))
+	if err != nil {
+		return nil, err
+	}
 
 	cfg := &Config{
 		Server: Server{
-			Port:         v.GetString("server.port"),
-			ReadTimeout:  readTimeout,
-			WriteTimeout: writeTimeout,
-			IdleTimeout:  idleTimeout,
+			Port:             v.GetString("server.port"),
+			ReadTimeout:      readTimeout,
+			WriteTimeout:     writeTimeout,
+			IdleTimeout:      idleTimeout,
+			MaxBodySize:      v.GetInt64("server.maxbodysize"),
+			StrictJSON:       v.GetBool("server.strictjson"),
+			Links:            v.GetBool("server.links"),
+			Envelope:         v.GetBool("server.envelope"),
+			DeprecationsFile: v.GetString("server.deprecationsfile"),
+			CatalogTimeout:   catalogTimeout,
+			OrderTimeout:     orderTimeout,
+			CORSOrigins:      parseList(v.GetStringSlice("server.corsorigins")),
+			CORSMaxAge:       corsMaxAge,
 		},
 		Files: Files{
 			ProductsFile: v.GetString("files.productsfile"),
 			CouponsDir:   v.GetString("files.couponsdir"),
 		},
 		Logging: LoggingConfig{
-			Level:  v.GetString("logging.level"),
-			Format: v.GetString("logging.format"),
+			Level:        v.GetString("logging.level"),
+			Format:       v.GetString("logging.format"),
+			AccessFormat: v.GetString("logging.accessformat"),
+			File:         v.GetString("logging.file"),
+			MaxSize:      v.GetInt64("logging.maxsize"),
+			MaxAge:       logMaxAge,
+			MaxBackups:   v.GetInt("logging.maxbackups"),
+			SampleLimit:  v.GetInt("logging.samplelimit"),
+		},
+		Auth: Auth{
+			APIKeys:       parseList(v.GetStringSlice("auth.apikeys")),
+			KitchenKeys:   parseList(v.GetStringSlice("auth.kitchenkeys")),
+			SupportKeys:   parseList(v.GetStringSlice("auth.supportkeys")),
+			KeysFile:      v.GetString("auth.keysfile"),
+			BlocklistFile: v.GetString("auth.blocklistfile"),
+			CustomersFile: v.GetString("auth.customersfile"),
+			JWTSecret:     v.GetString("auth.jwtsecret"),
+			SessionTTL:    sessionTTL,
+			RefreshTTL:    refreshTTL,
+			Google:        parseOIDCProvider(v, "auth.google"),
+			Apple:         parseOIDCProvider(v, "auth.apple"),
+			Passwords: Passwords{
+				Hash:            strings.ToLower(v.GetString("auth.passwords.hash")),
+				MaxFailedLogins: v.GetInt("auth.passwords.maxfailedlogins"),
+				UnlockTTL:       unlockTTL,
+				VerifyTTL:       verifyTTL,
+				ResetTTL:        resetTTL,
+			},
+			AppURL: strings.TrimSuffix(v.GetString("auth.appurl"), "/"),
+		},
+		Images: Images{
+			Dir:     v.GetString("images.dir"),
+			BaseURL: v.GetString("images.baseurl"),
+			MaxAge:  imagesMaxAge,
+			Signing: ImageSigning{
+				Type:      strings.ToLower(v.GetString("images.signing.type")),
+				URLPrefix: v.GetString("images.signing.urlprefix"),
+				KeyID:     v.GetString("images.signing.keyid"),
+				KeyFile:   v.GetString("images.signing.keyfile"),
+				TTL:       signingTTL,
+			},
+		},
+		Challenge: Challenge{
+			Provider:  strings.ToLower(v.GetString("challenge.provider")),
+			Secret:    v.GetString("challenge.secret"),
+			VerifyURL: v.GetString("challenge.verifyurl"),
+			Timeout:   challengeTimeout,
+		},
+		Email: Email{
+			SMTPAddr:     v.GetString("email.smtpaddr"),
+			SMTPUsername: v.GetString("email.smtpusername"),
+			SMTPPassword: v.GetString("email.smtppassword"),
+			From:         v.GetString("email.from"),
+		},
+		Usage: Usage{
+			File:          v.GetString("usage.file"),
+			FlushInterval: usageFlushInterval,
+		},
+		Invoices: Invoices{
+			Dir:    v.GetString("invoices.dir"),
+			Prefix: v.GetString("invoices.prefix"),
+		},
+		Orders: Orders{
+			EventsDir:          v.GetString("orders.eventsdir"),
+			ProjectionInterval: projectionInterval,
+			PaymentLinkURL:     v.GetString("orders.paymentlinkurl"),
+			TableSecret:        v.GetString("orders.tablesecret"),
+			HoldTTL:            holdTTL,
+			Retention:          retention,
+			ArchiveDir:         v.GetString("orders.archivedir"),
+		},
+		Accounting: Accounting{
+			SalesAccount:    v.GetString("accounting.salesaccount"),
+			Categories:      v.GetStringMapString("accounting.categories"),
+			DeliveryAccount: v.GetString("accounting.deliveryaccount"),
+			FeesAccount:     v.GetString("accounting.feesaccount"),
+			TaxType:         v.GetString("accounting.taxtype"),
+			ExemptTaxType:   v.GetString("accounting.exempttaxtype"),
+			DateFormat:      v.GetString("accounting.dateformat"),
+		},
+		Catalog: CatalogSync{
+			URL:     v.GetString("catalog.url"),
+			Subject: v.GetString("catalog.subject"),
+		},
+		SLOs: slos,
+		Cache: Cache{
+			ProductTTL: productTTL,
+		},
+		Products: Products{
+			Lenient: v.GetBool("products.lenient"),
+		},
+		Coupons: Coupons{
+			Integrity: strings.ToLower(v.GetString("coupons.integrity")),
+		},
+
+		Charges: Charges{
+			TaxRate:    v.GetFloat64("charges.taxrate"),
+			ServiceFee: v.GetFloat64("charges.servicefee"),
 		},
+		Limits:     parseLimits(v),
+		Velocity:   parseVelocity(v),
+		Hours:      hours,
+		Zones:      zones,
+		Promotions: promotions,
+		Menus:      menus,
+		Kitchen:    kitchen,
+		Locale:     strings.ToLower(v.GetString("locale")),
+		Currency:   strings.ToUpper(v.GetString("currency")),
+		Rounding:   rounding,
+		Tenants:    tenants,
 	}
 
 	// Validate required fields
@@ -118,6 +877,150 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// validate checks that a signing scheme, when enabled, has everything it needs
+func (s ImageSigning) validate() error {
+	switch s.Type {
+	case "":
+		return nil
+	case SigningCloudFront, SigningCloudCDN:
+	default:
+		return fmt.Errorf("invalid IMAGES_SIGNING_TYPE: %s", s.Type)
+	}
+	if s.URLPrefix == "" {
+		return fmt.Errorf("IMAGES_SIGNING_URL_PREFIX is required when signing image URLs")
+	}
+	if s.KeyID == "" {
+		return fmt.Errorf("IMAGES_SIGNING_KEY_ID is required when signing image URLs")
+	}
+	if s.KeyFile == "" {
+		return fmt.Errorf("IMAGES_SIGNING_KEY_FILE is required when signing image URLs")
+	}
+	if s.TTL <= 0 {
+		return fmt.Errorf("invalid IMAGES_SIGNING_TTL: must be positive")
+	}
+	return nil
+}
+
+// validate checks that customer sessions can be signed securely and that
+// enabled identity providers can be verified against
+func (a Auth) validate() error {
+	if a.JWTSecret != "" && len(a.JWTSecret) < 32 {
+		return fmt.Errorf("invalid JWT_SECRET: must be at least 32 bytes")
+	}
+	if a.SessionTTL <= 0 {
+		return fmt.Errorf("invalid SESSION_TTL: must be positive")
+	}
+	if a.RefreshTTL <= 0 {
+		return fmt.Errorf("invalid REFRESH_TTL: must be positive")
+	}
+	if err := a.Google.validate("GOOGLE"); err != nil {
+		return err
+	}
+	if err := a.Apple.validate("APPLE"); err != nil {
+		return err
+	}
+	if a.AppURL != "" {
+		if u, err := url.Parse(a.AppURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid APP_URL: %s", a.AppURL)
+		}
+	}
+	return a.Passwords.validate()
+}
+
+// validate checks that passwords are hashed with a supported algorithm and
+// that the lockout policy is usable
+func (p Passwords) validate() error {
+	switch p.Hash {
+	case PasswordArgon2id, PasswordBcrypt:
+	default:
+		return fmt.Errorf("invalid PASSWORD_HASH: %s", p.Hash)
+	}
+	if p.MaxFailedLogins < 0 {
+		return fmt.Errorf("invalid MAX_FAILED_LOGINS: must not be negative")
+	}
+	if p.UnlockTTL <= 0 {
+		return fmt.Errorf("invalid UNLOCK_TTL: must be positive")
+	}
+	if p.VerifyTTL <= 0 {
+		return fmt.Errorf("invalid VERIFY_EMAIL_TTL: must be positive")
+	}
+	if p.ResetTTL <= 0 {
+		return fmt.Errorf("invalid PASSWORD_RESET_TTL: must be positive")
+	}
+	return nil
+}
+
+// validate checks that an enabled identity provider names its issuer and
+// signing keys. name prefixes the settings in error messages.
+func (p OIDCProvider) validate(name string) error {
+	if len(p.ClientIDs) == 0 {
+		return nil
+	}
+	if p.Issuer == "" {
+		return fmt.Errorf("%s_ISSUER is required when %s_CLIENT_IDS is set", name, name)
+	}
+	if u, err := url.Parse(p.JWKSURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid %s_JWKS_URL: %s", name, p.JWKSURL)
+	}
+	return nil
+}
+
+// validate checks that a challenge provider, when enabled, has everything it
+// needs
+func (ch Challenge) validate() error {
+	switch ch.Provider {
+	case "":
+		return nil
+	case ChallengeTurnstile, ChallengeRecaptcha:
+	default:
+		return fmt.Errorf("invalid CHALLENGE_PROVIDER: %s", ch.Provider)
+	}
+	if ch.Secret == "" {
+		return fmt.Errorf("CHALLENGE_SECRET is required when challenging orders")
+	}
+	if ch.VerifyURL != "" {
+		if u, err := url.Parse(ch.VerifyURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid CHALLENGE_VERIFY_URL: %s", ch.VerifyURL)
+		}
+	}
+	if ch.Timeout <= 0 {
+		return fmt.Errorf("invalid CHALLENGE_TIMEOUT: must be positive")
+	}
+	return nil
+}
+
+// validate checks that every exported line has an account and that invoice
+// dates can be written
+func (a Accounting) validate() error {
+	if a.SalesAccount == "" {
+		return fmt.Errorf("ACCOUNTING_SALES_ACCOUNT is required")
+	}
+	for category, account := range a.Categories {
+		if account == "" {
+			return fmt.Errorf("invalid accounting.categories.%s: account is empty", category)
+		}
+	}
+	if a.DateFormat == "" || time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC).Format(a.DateFormat) == a.DateFormat {
+		return fmt.Errorf("invalid ACCOUNTING_DATE_FORMAT: %q has no date in it", a.DateFormat)
+	}
+	return nil
+}
+
+// validate checks that catalog sync, when enabled, names a NATS server and
+// a subject
+func (cs CatalogSync) validate() error {
+	if cs.URL == "" {
+		return nil
+	}
+	if u, err := url.Parse(cs.URL); err != nil || u.Scheme != "nats" || u.Host == "" {
+		return fmt.Errorf("invalid CATALOG_SYNC_URL: %s", cs.URL)
+	}
+	if cs.Subject == "" || strings.ContainsAny(cs.Subject, " \t\r\n") {
+		return fmt.Errorf("invalid CATALOG_SYNC_SUBJECT: %q", cs.Subject)
+	}
+	return nil
+}
+
 // validate checks if all required configuration fields are set and valid.
 func (c *Config) validate() error {
 	if c.Files.ProductsFile == "" {
@@ -126,6 +1029,26 @@ func (c *Config) validate() error {
 	if c.Files.CouponsDir == "" {
 		return fmt.Errorf("COUPONS_DIR is required")
 	}
+	if c.Server.MaxBodySize <= 0 {
+		return fmt.Errorf("invalid SERVER_MAX_BODY_SIZE: must be a positive number of bytes")
+	}
+	if c.Server.CatalogTimeout < 0 {
+		return fmt.Errorf("invalid SERVER_CATALOG_TIMEOUT: must not be negative")
+	}
+	if c.Server.OrderTimeout < 0 {
+		return fmt.Errorf("invalid SERVER_ORDER_TIMEOUT: must not be negative")
+	}
+	if c.Server.CORSMaxAge < 0 {
+		return fmt.Errorf("invalid SERVER_CORS_MAX_AGE: must not be negative")
+	}
+	if c.Cache.ProductTTL < 0 {
+		return fmt.Errorf("invalid PRODUCT_CACHE_TTL: must not be negative")
+	}
+	switch c.Coupons.Integrity {
+	case CouponIntegrityFail, CouponIntegrityWarn, CouponIntegrityQuarantine:
+	default:
+		return fmt.Errorf("invalid COUPON_INTEGRITY: %s", c.Coupons.Integrity)
+	}
 
 	// Validate log level
 	switch strings.ToLower(c.Logging.Level) {
@@ -143,9 +1066,636 @@ func (c *Config) validate() error {
 		return fmt.Errorf("invalid LOG_FORMAT: %s", c.Logging.Format)
 	}
 
+	// Validate access log format
+	switch c.Logging.AccessFormat {
+	case "text", "json", "combined":
+		// Valid formats
+	default:
+		return fmt.Errorf("invalid LOG_ACCESS_FORMAT: %s", c.Logging.AccessFormat)
+	}
+	if c.Logging.MaxSize < 0 {
+		return fmt.Errorf("invalid LOG_MAX_SIZE: must not be negative")
+	}
+	if c.Logging.MaxAge < 0 {
+		return fmt.Errorf("invalid LOG_MAX_AGE: must not be negative")
+	}
+	if c.Logging.MaxBackups < 0 {
+		return fmt.Errorf("invalid LOG_MAX_BACKUPS: must not be negative")
+	}
+	if c.Logging.SampleLimit < 0 {
+		return fmt.Errorf("invalid LOG_SAMPLE_LIMIT: must not be negative")
+	}
+
+	if err := c.Charges.validate(); err != nil {
+		return err
+	}
+	if err := c.Limits.validate(); err != nil {
+		return err
+	}
+	if err := c.Velocity.validate(); err != nil {
+		return err
+	}
+
+	// Image URLs are stored on products, which require absolute URLs
+	if c.Images.BaseURL != "" {
+		if u, err := url.Parse(c.Images.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid IMAGES_BASE_URL: %s", c.Images.BaseURL)
+		}
+	}
+
+	if c.Images.MaxAge < 0 {
+		return fmt.Errorf("invalid IMAGES_MAX_AGE: must not be negative")
+	}
+	if err := c.Images.Signing.validate(); err != nil {
+		return err
+	}
+	if err := c.Challenge.validate(); err != nil {
+		return err
+	}
+	if err := c.Auth.validate(); err != nil {
+		return err
+	}
+	if c.Email.From == "" {
+		return fmt.Errorf("EMAIL_FROM is required")
+	}
+	if c.Usage.FlushInterval <= 0 {
+		return fmt.Errorf("invalid USAGE_FLUSH_INTERVAL: must be positive")
+	}
+	if c.Orders.ProjectionInterval <= 0 {
+		return fmt.Errorf("invalid ORDER_PROJECTION_INTERVAL: must be positive")
+	}
+	if c.Orders.HoldTTL < 0 {
+		return fmt.Errorf("invalid ORDER_HOLD_TTL: must not be negative")
+	}
+	if c.Orders.Retention < 0 {
+		return fmt.Errorf("invalid ORDER_RETENTION: must not be negative")
+	}
+	if c.Orders.PaymentLinkURL != "" {
+		if u, err := url.Parse(c.Orders.PaymentLinkURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid ORDER_PAYMENT_LINK_URL: %s", c.Orders.PaymentLinkURL)
+		}
+	}
+	if c.Orders.TableSecret != "" && len(c.Orders.TableSecret) < 32 {
+		return fmt.Errorf("invalid ORDER_TABLE_SECRET: must be at least 32 bytes")
+	}
+	if err := c.Accounting.validate(); err != nil {
+		return err
+	}
+	if len(c.Invoices.Prefix) > maxInvoicePrefix {
+		return fmt.Errorf("invalid INVOICE_PREFIX: must be at most %d characters", maxInvoicePrefix)
+	}
+	if err := c.Catalog.validate(); err != nil {
+		return err
+	}
+
+	// Validate SLOs
+	groups := make(map[string]bool)
+	for _, slo := range c.SLOs {
+		if err := slo.validate(); err != nil {
+			return err
+		}
+		if groups[slo.Group] {
+			return fmt.Errorf("duplicate slos group: %s", slo.Group)
+		}
+		groups[slo.Group] = true
+	}
+
+	// Validate rounding rules and the currencies they are picked by
+	currencies := make(map[string]bool)
+	for _, rule := range c.Rounding {
+		if err := rule.validate(); err != nil {
+			return err
+		}
+		if currencies[rule.Currency] {
+			return fmt.Errorf("duplicate rounding currency: %s", rule.Currency)
+		}
+		currencies[rule.Currency] = true
+	}
+	if c.Currency != "" && !isCurrency(c.Currency) {
+		return fmt.Errorf("invalid CURRENCY: %q (must be an ISO 4217 code)", c.Currency)
+	}
+
+	// Validate tenants
+	ids := make(map[string]bool)
+	hosts := make(map[string]string)
+	for _, tenant := range c.Tenants {
+		// Tenant IDs name files, such as the tenant's invoice journal
+		if tenant.ID == "" || tenant.ID == DefaultTenantID || strings.ContainsAny(tenant.ID, `/\`) {
+			return fmt.Errorf("invalid tenant id: %q", tenant.ID)
+		}
+		if ids[tenant.ID] {
+			return fmt.Errorf("duplicate tenant id: %s", tenant.ID)
+		}
+		ids[tenant.ID] = true
+
+		if tenant.Files.ProductsFile == "" || tenant.Files.CouponsDir == "" {
+			return fmt.Errorf("tenant %s: productsfile and couponsdir are required", tenant.ID)
+		}
+		if err := tenant.Charges.validate(); err != nil {
+			return fmt.Errorf("tenant %s: %w", tenant.ID, err)
+		}
+		if err := tenant.Limits.validate(); err != nil {
+			return fmt.Errorf("tenant %s: %w", tenant.ID, err)
+		}
+		if err := tenant.Velocity.validate(); err != nil {
+			return fmt.Errorf("tenant %s: %w", tenant.ID, err)
+		}
+		if tenant.Currency != "" && !isCurrency(tenant.Currency) {
+			return fmt.Errorf("tenant %s: invalid currency: %q (must be an ISO 4217 code)", tenant.ID, tenant.Currency)
+		}
+		if len(tenant.InvoicePrefix) > maxInvoicePrefix {
+			return fmt.Errorf("tenant %s: invalid invoice_prefix: must be at most %d characters", tenant.ID, maxInvoicePrefix)
+		}
+		for _, host := range tenant.Hosts {
+			if other, exists := hosts[host]; exists {
+				return fmt.Errorf("host %s is assigned to tenants %s and %s", host, other, tenant.ID)
+			}
+			hosts[host] = tenant.ID
+		}
+	}
+
+	return nil
+}
+
+// validate checks that tax and fees are within range
+func (c Charges) validate() error {
+	if c.TaxRate < 0 || c.TaxRate > 1 {
+		return fmt.Errorf("invalid TAX_RATE: %v (must be between 0 and 1)", c.TaxRate)
+	}
+	if c.ServiceFee < 0 {
+		return fmt.Errorf("invalid SERVICE_FEE: %v (must not be negative)", c.ServiceFee)
+	}
+	return nil
+}
+
+// validate checks that order limits are not negative
+func (l Limits) validate() error {
+	if l.MinPickup < 0 {
+		return fmt.Errorf("invalid ORDER_MIN_PICKUP: %v (must not be negative)", l.MinPickup)
+	}
+	if l.MinDelivery < 0 {
+		return fmt.Errorf("invalid ORDER_MIN_DELIVERY: %v (must not be negative)", l.MinDelivery)
+	}
+	if l.MaxQuantity < 0 {
+		return fmt.Errorf("invalid ORDER_MAX_QUANTITY: %d (must not be negative)", l.MaxQuantity)
+	}
+	if l.MaxItems < 0 {
+		return fmt.Errorf("invalid ORDER_MAX_ITEMS: %d (must not be negative)", l.MaxItems)
+	}
+	if l.MaxTotal < 0 {
+		return fmt.Errorf("invalid ORDER_MAX_TOTAL: %v (must not be negative)", l.MaxTotal)
+	}
+	return nil
+}
+
+// setLimitsDefaults sets the order limit defaults on v
+func setLimitsDefaults(v *viper.Viper) {
+	v.SetDefault("limits.maxquantity", 100)
+	v.SetDefault("limits.maxitems", 50)
+}
+
+// parseLimits reads the limits section of v
+func parseLimits(v *viper.Viper) Limits {
+	return Limits{
+		MaxQuantity: v.GetInt("limits.maxquantity"),
+		MaxItems:    v.GetInt("limits.maxitems"),
+		MaxTotal:    v.GetFloat64("limits.maxtotal"),
+		MinPickup:   v.GetFloat64("limits.minpickup"),
+		MinDelivery: v.GetFloat64("limits.mindelivery"),
+	}
+}
+
+// validate checks that velocity rules are not negative and that orders are
+// held before they are refused
+func (r Velocity) validate() error {
+	if r.HoldOrdersPerHour < 0 {
+		return fmt.Errorf("invalid VELOCITY_HOLD_ORDERS_PER_HOUR: %d (must not be negative)", r.HoldOrdersPerHour)
+	}
+	if r.MaxOrdersPerHour < 0 {
+		return fmt.Errorf("invalid VELOCITY_MAX_ORDERS_PER_HOUR: %d (must not be negative)", r.MaxOrdersPerHour)
+	}
+	if r.HoldTotalPerDay < 0 {
+		return fmt.Errorf("invalid VELOCITY_HOLD_TOTAL_PER_DAY: %v (must not be negative)", r.HoldTotalPerDay)
+	}
+	if r.MaxTotalPerDay < 0 {
+		return fmt.Errorf("invalid VELOCITY_MAX_TOTAL_PER_DAY: %v (must not be negative)", r.MaxTotalPerDay)
+	}
+	if r.HoldOrdersPerHour > 0 && r.MaxOrdersPerHour > 0 && r.HoldOrdersPerHour >= r.MaxOrdersPerHour {
+		return fmt.Errorf("invalid VELOCITY_HOLD_ORDERS_PER_HOUR: %d (must be below VELOCITY_MAX_ORDERS_PER_HOUR)", r.HoldOrdersPerHour)
+	}
+	if r.HoldTotalPerDay > 0 && r.MaxTotalPerDay > 0 && r.HoldTotalPerDay >= r.MaxTotalPerDay {
+		return fmt.Errorf("invalid VELOCITY_HOLD_TOTAL_PER_DAY: %v (must be below VELOCITY_MAX_TOTAL_PER_DAY)", r.HoldTotalPerDay)
+	}
 	return nil
 }
 
+// parseOIDCProvider reads the identity provider settings under key
+func parseOIDCProvider(v *viper.Viper, key string) OIDCProvider {
+	return OIDCProvider{
+		ClientIDs: parseList(v.GetStringSlice(key + ".clientids")),
+		Issuer:    v.GetString(key + ".issuer"),
+		JWKSURL:   v.GetString(key + ".jwksurl"),
+	}
+}
+
+// parseVelocity reads the velocity section of v
+func parseVelocity(v *viper.Viper) Velocity {
+	return Velocity{
+		HoldOrdersPerHour: v.GetInt("velocity.holdordersperhour"),
+		MaxOrdersPerHour:  v.GetInt("velocity.maxordersperhour"),
+		HoldTotalPerDay:   v.GetFloat64("velocity.holdtotalperday"),
+		MaxTotalPerDay:    v.GetFloat64("velocity.maxtotalperday"),
+	}
+}
+
+// parseHours reads the hours section of v
+func parseHours(v *viper.Viper) (Hours, error) {
+	var orderAhead time.Duration
+	if raw := v.GetString("hours.orderahead"); raw != "" {
+		var err error
+		if orderAhead, err = time.ParseDuration(raw); err != nil {
+			return Hours{}, fmt.Errorf("invalid hours.orderahead: %w", err)
+		}
+	}
+
+	return Hours{
+		Timezone:   v.GetString("hours.timezone"),
+		Weekly:     v.GetStringMapStringSlice("hours.weekly"),
+		OrderAhead: orderAhead,
+	}, nil
+}
+
+// setKitchenDefaults sets the kitchen defaults on v
+func setKitchenDefaults(v *viper.Viper) {
+	v.SetDefault("kitchen.stations", 1)
+	v.SetDefault("kitchen.defaultpreptime", "15m")
+	v.SetDefault("kitchen.deliverytime", "30m")
+	v.SetDefault("kitchen.whenbusy", KitchenBusyReject)
+	v.SetDefault("kitchen.busydelay", "10m")
+}
+
+// parseKitchen reads the kitchen section of v
+func parseKitchen(v *viper.Viper) (Kitchen, error) {
+	defaultPrep, err := time.ParseDuration(v.GetString("kitchen.defaultpreptime"))
+	if err != nil {
+		return Kitchen{}, fmt.Errorf("invalid kitchen.defaultpreptime: %w", err)
+	}
+	deliveryTime, err := time.ParseDuration(v.GetString("kitchen.deliverytime"))
+	if err != nil {
+		return Kitchen{}, fmt.Errorf("invalid kitchen.deliverytime: %w", err)
+	}
+	busyDelay, err := time.ParseDuration(v.GetString("kitchen.busydelay"))
+	if err != nil {
+		return Kitchen{}, fmt.Errorf("invalid kitchen.busydelay: %w", err)
+	}
+
+	var prepTimes map[string]time.Duration
+	for category, raw := range v.GetStringMapString("kitchen.preptimes") {
+		prep, err := time.ParseDuration(raw)
+		if err != nil {
+			return Kitchen{}, fmt.Errorf("invalid kitchen.preptimes.%s: %w", category, err)
+		}
+		if prepTimes == nil {
+			prepTimes = make(map[string]time.Duration)
+		}
+		prepTimes[category] = prep
+	}
+
+	kitchen := Kitchen{
+		Stations:        v.GetInt("kitchen.stations"),
+		PrepTimes:       prepTimes,
+		DefaultPrepTime: defaultPrep,
+		DeliveryTime:    deliveryTime,
+		MaxActive:       v.GetInt("kitchen.maxactive"),
+		WhenBusy:        strings.ToLower(v.GetString("kitchen.whenbusy")),
+		BusyDelay:       busyDelay,
+	}
+	if kitchen.Stations < 1 {
+		return Kitchen{}, fmt.Errorf("invalid kitchen.stations: %d (must be at least 1)", kitchen.Stations)
+	}
+	if kitchen.MaxActive < 0 {
+		return Kitchen{}, fmt.Errorf("invalid kitchen.maxactive: %d (must not be negative)", kitchen.MaxActive)
+	}
+	switch kitchen.WhenBusy {
+	case KitchenBusyReject, KitchenBusyExtend:
+	default:
+		return Kitchen{}, fmt.Errorf("invalid kitchen.whenbusy: %s (must be %s or %s)", kitchen.WhenBusy, KitchenBusyReject, KitchenBusyExtend)
+	}
+	if kitchen.BusyDelay < 0 {
+		return Kitchen{}, fmt.Errorf("invalid kitchen.busydelay: %s (must not be negative)", kitchen.BusyDelay)
+	}
+	return kitchen, nil
+}
+
+// parsePromotions reads a promotions list
+func parsePromotions(raw interface{}) ([]Promotion, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	entries, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid promotions: expected a list")
+	}
+
+	promotions := make([]Promotion, 0, len(entries))
+	for i, entry := range entries {
+		fields, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid promotions[%d]: expected a map", i)
+		}
+
+		pv := viper.New()
+		if err := pv.MergeConfigMap(fields); err != nil {
+			return nil, fmt.Errorf("invalid promotions[%d]: %w", i, err)
+		}
+		promotions = append(promotions, Promotion{
+			Name:       pv.GetString("name"),
+			Percent:    pv.GetFloat64("percent"),
+			Categories: parseList(pv.GetStringSlice("categories")),
+			Weekly:     pv.GetStringMapStringSlice("weekly"),
+		})
+	}
+
+	return promotions, nil
+}
+
+// parseMenus reads a menus list
+func parseMenus(raw interface{}) ([]Menu, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	entries, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid menus: expected a list")
+	}
+
+	menus := make([]Menu, 0, len(entries))
+	for i, entry := range entries {
+		fields, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid menus[%d]: expected a map", i)
+		}
+
+		mv := viper.New()
+		if err := mv.MergeConfigMap(fields); err != nil {
+			return nil, fmt.Errorf("invalid menus[%d]: %w", i, err)
+		}
+		menus = append(menus, Menu{
+			Name:       mv.GetString("name"),
+			Categories: parseList(mv.GetStringSlice("categories")),
+			Products:   parseList(mv.GetStringSlice("products")),
+			Weekly:     mv.GetStringMapStringSlice("weekly"),
+		})
+	}
+
+	return menus, nil
+}
+
+// parseSLOs reads an slos list. Latency targets and windows left out get
+// their defaults.
+func parseSLOs(raw interface{}) ([]SLO, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	entries, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid slos: expected a list")
+	}
+
+	slos := make([]SLO, 0, len(entries))
+	for i, entry := range entries {
+		fields, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid slos[%d]: expected a map", i)
+		}
+
+		sv := viper.New()
+		if err := sv.MergeConfigMap(fields); err != nil {
+			return nil, fmt.Errorf("invalid slos[%d]: %w", i, err)
+		}
+		sv.SetDefault("latency", "0s")
+		sv.SetDefault("latencytarget", DefaultSLOLatencyTarget)
+		sv.SetDefault("window", DefaultSLOWindow.String())
+		latency, err := time.ParseDuration(sv.GetString("latency"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid slos[%d].latency: %w", i, err)
+		}
+		window, err := time.ParseDuration(sv.GetString("window"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid slos[%d].window: %w", i, err)
+		}
+		slos = append(slos, SLO{
+			Group:         sv.GetString("group"),
+			Availability:  sv.GetFloat64("availability"),
+			Latency:       latency,
+			LatencyTarget: sv.GetFloat64("latencytarget"),
+			Window:        window,
+		})
+	}
+
+	return slos, nil
+}
+
+// parseRounding reads a rounding list. Modes left out round half up, and
+// increments left out round to DefaultRoundingIncrement.
+func parseRounding(raw interface{}) ([]Rounding, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	entries, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid rounding: expected a list")
+	}
+
+	rules := make([]Rounding, 0, len(entries))
+	for i, entry := range entries {
+		fields, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid rounding[%d]: expected a map", i)
+		}
+
+		sv := viper.New()
+		if err := sv.MergeConfigMap(fields); err != nil {
+			return nil, fmt.Errorf("invalid rounding[%d]: %w", i, err)
+		}
+		sv.SetDefault("mode", RoundingHalfUp)
+		sv.SetDefault("increment", DefaultRoundingIncrement)
+		rules = append(rules, Rounding{
+			Currency:  strings.ToUpper(sv.GetString("currency")),
+			Mode:      strings.ToLower(sv.GetString("mode")),
+			Increment: sv.GetFloat64("increment"),
+		})
+	}
+
+	return rules, nil
+}
+
+// parseZones reads a delivery zones list. Polygon vertices are
+// [latitude, longitude] pairs.
+func parseZones(raw interface{}) ([]Zone, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	entries, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid zones: expected a list")
+	}
+
+	zones := make([]Zone, 0, len(entries))
+	for i, entry := range entries {
+		fields, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid zones[%d]: expected a map", i)
+		}
+
+		zv := viper.New()
+		if err := zv.MergeConfigMap(fields); err != nil {
+			return nil, fmt.Errorf("invalid zones[%d]: %w", i, err)
+		}
+
+		zone := Zone{
+			Name:      zv.GetString("name"),
+			Postcodes: parseList(zv.GetStringSlice("postcodes")),
+			Fee:       zv.GetFloat64("fee"),
+		}
+
+		if rawPolygon := zv.Get("polygon"); rawPolygon != nil {
+			vertices, ok := rawPolygon.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("invalid zones[%d].polygon: expected a list", i)
+			}
+			for j, rawVertex := range vertices {
+				vertex, ok := parseVertex(rawVertex)
+				if !ok {
+					return nil, fmt.Errorf("invalid zones[%d].polygon[%d]: expected [latitude, longitude]", i, j)
+				}
+				zone.Polygon = append(zone.Polygon, vertex)
+			}
+		}
+
+		zones = append(zones, zone)
+	}
+
+	return zones, nil
+}
+
+// parseVertex reads a [latitude, longitude] pair
+func parseVertex(raw interface{}) ([2]float64, bool) {
+	values, ok := raw.([]interface{})
+	if !ok || len(values) != 2 {
+		return [2]float64{}, false
+	}
+
+	var vertex [2]float64
+	for i, value := range values {
+		switch n := value.(type) {
+		case float64:
+			vertex[i] = n
+		case int:
+			vertex[i] = float64(n)
+		default:
+			return [2]float64{}, false
+		}
+	}
+	return vertex, true
+}
+
+// parseTenants reads the tenants list. Each entry uses the same keys as the
+// top-level files and charges sections, plus an id and the hosts it serves.
+func parseTenants(raw interface{}) ([]Tenant, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	entries, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid tenants: expected a list")
+	}
+
+	tenants := make([]Tenant, 0, len(entries))
+	for i, entry := range entries {
+		fields, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid tenants[%d]: expected a map", i)
+		}
+
+		tv := viper.New()
+		setKitchenDefaults(tv)
+		setLimitsDefaults(tv)
+		tv.SetDefault("locale", DefaultLocale)
+		tv.SetDefault("invoice_prefix", DefaultInvoicePrefix)
+		if err := tv.MergeConfigMap(fields); err != nil {
+			return nil, fmt.Errorf("invalid tenants[%d]: %w", i, err)
+		}
+
+		hours, err := parseHours(tv)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tenants[%d]: %w", i, err)
+		}
+
+		zones, err := parseZones(tv.Get("zones"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid tenants[%d]: %w", i, err)
+		}
+		promotions, err := parsePromotions(tv.Get("promotions"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid tenants[%d]: %w", i, err)
+		}
+		menus, err := parseMenus(tv.Get("menus"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid tenants[%d]: %w", i, err)
+		}
+		kitchen, err := parseKitchen(tv)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tenants[%d]: %w", i, err)
+		}
+
+		var hosts []string
+		for _, host := range parseList(tv.GetStringSlice("hosts")) {
+			hosts = append(hosts, strings.ToLower(host))
+		}
+
+		tenants = append(tenants, Tenant{
+			ID:    tv.GetString("id"),
+			Hosts: hosts,
+			Files: Files{
+				ProductsFile: tv.GetString("files.productsfile"),
+				CouponsDir:   tv.GetString("files.couponsdir"),
+			},
+			Charges: Charges{
+				TaxRate:    tv.GetFloat64("charges.taxrate"),
+				ServiceFee: tv.GetFloat64("charges.servicefee"),
+			},
+			Limits:     parseLimits(tv),
+			Velocity:   parseVelocity(tv),
+			Hours:      hours,
+			Zones:      zones,
+			Promotions: promotions,
+			Menus:      menus,
+			Kitchen:    kitchen,
+			Locale:     strings.ToLower(tv.GetString("locale")),
+			Currency:   strings.ToUpper(tv.GetString("currency")),
+
+			InvoicePrefix: tv.GetString("invoice_prefix"),
+		})
+	}
+
+	return tenants, nil
+}
+
+// parseList flattens comma-separated entries (as supplied via environment
+// variables) and drops empty values.
+func parseList(values []string) []string {
+	var out []string
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
+
 // GetServerTimeouts returns the server timeout configurations.
 func (c *Config) GetServerTimeouts() (read, write, idle time.Duration) {
 	return c.Server.ReadTimeout, c.Server.WriteTimeout, c.Server.IdleTimeout
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 	"time"
 )
@@ -48,6 +49,973 @@ func TestLoad(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "api keys from env var",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":    "./testdata/products.json",
+				"COUPONS_DIR":      "./testdata/coupons",
+				"API_KEYS":         "key-1, key-2,,",
+				"KITCHEN_API_KEYS": "kitchen-1",
+				"SUPPORT_API_KEYS": "support-1,support-2",
+				"API_KEYS_FILE":    "./testdata/apikeys.json",
+				"BLOCKLIST_FILE":   "./testdata/blocklist.json",
+			},
+			wantErr: false,
+			validateCfg: func(t *testing.T, cfg *Config) {
+				if len(cfg.Auth.APIKeys) != 2 || cfg.Auth.APIKeys[0] != "key-1" || cfg.Auth.APIKeys[1] != "key-2" {
+					t.Errorf("expected api keys [key-1 key-2], got %v", cfg.Auth.APIKeys)
+				}
+				if len(cfg.Auth.KitchenKeys) != 1 || len(cfg.Auth.SupportKeys) != 2 {
+					t.Errorf("expected 1 kitchen key and 2 support keys, got %v and %v", cfg.Auth.KitchenKeys, cfg.Auth.SupportKeys)
+				}
+				if cfg.Auth.KeysFile != "./testdata/apikeys.json" {
+					t.Errorf("expected api keys file ./testdata/apikeys.json, got %s", cfg.Auth.KeysFile)
+				}
+				if cfg.Auth.BlocklistFile != "./testdata/blocklist.json" {
+					t.Errorf("expected blocklist file ./testdata/blocklist.json, got %s", cfg.Auth.BlocklistFile)
+				}
+			},
+		},
+		{
+			name: "slos from file",
+			configFile: `files:
+  productsfile: "./data/products.json"
+  couponsdir: "./data/coupons"
+slos:
+  - group: "ordering"
+    availability: 0.999
+    latency: "500ms"
+    latencytarget: 0.95
+    window: "168h"
+  - group: "catalog"
+    availability: 0.99`,
+			validateCfg: func(t *testing.T, cfg *Config) {
+				want := []SLO{
+					{Group: "ordering", Availability: 0.999, Latency: 500 * time.Millisecond, LatencyTarget: 0.95, Window: 168 * time.Hour},
+					{Group: "catalog", Availability: 0.99, LatencyTarget: DefaultSLOLatencyTarget, Window: DefaultSLOWindow},
+				}
+				if !slices.Equal(cfg.SLOs, want) {
+					t.Errorf("expected slos %+v, got %+v", want, cfg.SLOs)
+				}
+			},
+		},
+		{
+			name: "slo window shorter than burn rate windows",
+			configFile: `files:
+  productsfile: "./data/products.json"
+  couponsdir: "./data/coupons"
+slos:
+  - group: "ordering"
+    availability: 0.999
+    window: "1h"`,
+			wantErr: true,
+		},
+		{
+			name: "duplicate slo group",
+			configFile: `files:
+  productsfile: "./data/products.json"
+  couponsdir: "./data/coupons"
+slos:
+  - group: "ordering"
+    availability: 0.999
+  - group: "ordering"
+    availability: 0.99`,
+			wantErr: true,
+		},
+		{
+			name: "rounding from file",
+			configFile: `files:
+  productsfile: "./data/products.json"
+  couponsdir: "./data/coupons"
+currency: "chf"
+rounding:
+  - currency: "CHF"
+    increment: 0.05
+  - currency: "eur"
+    mode: "half-even"
+tenants:
+  - id: "harbour"
+    currency: "EUR"
+    files:
+      productsfile: "./data/harbour/products.json"
+      couponsdir: "./data/harbour/coupons"`,
+			validateCfg: func(t *testing.T, cfg *Config) {
+				want := []Rounding{
+					{Currency: "CHF", Mode: RoundingHalfUp, Increment: 0.05},
+					{Currency: "EUR", Mode: RoundingHalfEven, Increment: DefaultRoundingIncrement},
+				}
+				if cfg.Currency != "CHF" || !slices.Equal(cfg.Rounding, want) {
+					t.Errorf("expected CHF and rounding %+v, got %s and %+v", want, cfg.Currency, cfg.Rounding)
+				}
+				if len(cfg.Tenants) != 1 || cfg.Tenants[0].Currency != "EUR" {
+					t.Errorf("expected the tenant to charge in EUR, got %+v", cfg.Tenants)
+				}
+				if rule, ok := cfg.RoundingFor("AUD"); !ok || rule != (Rounding{Currency: "AUD", Mode: RoundingHalfUp, Increment: DefaultRoundingIncrement}) {
+					t.Errorf("expected AUD to round half up to the cent, got %+v", rule)
+				}
+				if _, ok := cfg.RoundingFor(""); ok {
+					t.Errorf("expected no rounding without a currency")
+				}
+			},
+		},
+		{
+			name: "rounding mode unknown",
+			configFile: `files:
+  productsfile: "./data/products.json"
+  couponsdir: "./data/coupons"
+rounding:
+  - currency: "CHF"
+    mode: "down"`,
+			wantErr: true,
+		},
+		{
+			name: "duplicate rounding currency",
+			configFile: `files:
+  productsfile: "./data/products.json"
+  couponsdir: "./data/coupons"
+rounding:
+  - currency: "CHF"
+  - currency: "chf"
+    increment: 0.05`,
+			wantErr: true,
+		},
+		{
+			name: "invalid currency",
+			envVars: map[string]string{
+				"PRODUCTS_FILE": "./testdata/products.json",
+				"COUPONS_DIR":   "./testdata/coupons",
+				"CURRENCY":      "dollars",
+			},
+			wantErr: true,
+		},
+		{
+			name: "tenants from file",
+			configFile: `files:
+  productsfile: "./data/products.json"
+  couponsdir: "./data/coupons"
+charges:
+  taxrate: 0.1
+kitchen:
+  stations: 3
+  preptimes:
+    Pizza: "20m"
+  deliverytime: "40m"
+  maxactive: 12
+  whenbusy: "Extend"
+zones:
+  - name: "cbd"
+    postcodes: ["2000", "2001"]
+    fee: 3.5
+  - name: "harbour"
+    polygon: [[-33.80, 151.10], [-33.80, 151.30], [-33.95, 151.30]]
+promotions:
+  - name: "happy-hour"
+    percent: -20
+    categories: ["Drinks"]
+    weekly:
+      friday: ["17:00-19:00"]
+menus:
+  - name: "breakfast"
+    categories: ["Waffle"]
+    products: ["7"]
+    weekly:
+      saturday: ["07:00-11:00"]
+tenants:
+  - id: "harbour"
+    hosts: ["Harbour.example.com", "harbour.local"]
+    locale: "FR"
+    files:
+      productsfile: "./harbour/products.json"
+      couponsdir: "./harbour/coupons"
+    charges:
+      taxrate: 0.05
+      servicefee: 1.5
+    limits:
+      maxquantity: 10
+      maxtotal: 500
+    hours:
+      timezone: "Australia/Sydney"
+      orderahead: "30m"
+      weekly:
+        monday: ["09:00-14:00", "17:00-22:00"]`,
+			wantErr: false,
+			validateCfg: func(t *testing.T, cfg *Config) {
+				if cfg.Charges.TaxRate != 0.1 {
+					t.Errorf("expected default tax rate 0.1, got %v", cfg.Charges.TaxRate)
+				}
+				if len(cfg.Zones) != 2 || cfg.Zones[0].Name != "cbd" || cfg.Zones[0].Fee != 3.5 || len(cfg.Zones[0].Postcodes) != 2 {
+					t.Errorf("unexpected zones %+v", cfg.Zones)
+				}
+				if len(cfg.Zones) == 2 && (len(cfg.Zones[1].Polygon) != 3 || cfg.Zones[1].Polygon[0] != [2]float64{-33.80, 151.10}) {
+					t.Errorf("unexpected polygon %+v", cfg.Zones[1].Polygon)
+				}
+				if cfg.Kitchen.Stations != 3 || cfg.Kitchen.PrepTimes["pizza"] != 20*time.Minute || cfg.Kitchen.DeliveryTime != 40*time.Minute {
+					t.Errorf("unexpected kitchen %+v", cfg.Kitchen)
+				}
+				if cfg.Kitchen.DefaultPrepTime != 15*time.Minute {
+					t.Errorf("expected default prep time 15m, got %v", cfg.Kitchen.DefaultPrepTime)
+				}
+				if len(cfg.Promotions) != 1 || cfg.Promotions[0].Name != "happy-hour" || cfg.Promotions[0].Percent != -20 ||
+					len(cfg.Promotions[0].Categories) != 1 || len(cfg.Promotions[0].Weekly["friday"]) != 1 {
+					t.Errorf("unexpected promotions %+v", cfg.Promotions)
+				}
+				if len(cfg.Menus) != 1 || cfg.Menus[0].Name != "breakfast" || len(cfg.Menus[0].Categories) != 1 ||
+					len(cfg.Menus[0].Products) != 1 || len(cfg.Menus[0].Weekly["saturday"]) != 1 {
+					t.Errorf("unexpected menus %+v", cfg.Menus)
+				}
+				if cfg.Kitchen.MaxActive != 12 || cfg.Kitchen.WhenBusy != KitchenBusyExtend || cfg.Kitchen.BusyDelay != 10*time.Minute {
+					t.Errorf("unexpected kitchen capacity %+v", cfg.Kitchen)
+				}
+				if cfg.Locale != DefaultLocale {
+					t.Errorf("expected default locale %s, got %s", DefaultLocale, cfg.Locale)
+				}
+				if len(cfg.Tenants) != 1 {
+					t.Fatalf("expected 1 tenant, got %d", len(cfg.Tenants))
+				}
+				tenant := cfg.Tenants[0]
+				if tenant.Locale != "fr" {
+					t.Errorf("expected lowercased tenant locale fr, got %s", tenant.Locale)
+				}
+				if tenant.ID != "harbour" {
+					t.Errorf("expected tenant id harbour, got %s", tenant.ID)
+				}
+				if len(tenant.Hosts) != 2 || tenant.Hosts[0] != "harbour.example.com" {
+					t.Errorf("expected lowercased hosts, got %v", tenant.Hosts)
+				}
+				if tenant.Files.ProductsFile != "./harbour/products.json" || tenant.Files.CouponsDir != "./harbour/coupons" {
+					t.Errorf("unexpected tenant files %+v", tenant.Files)
+				}
+				if tenant.Charges.TaxRate != 0.05 || tenant.Charges.ServiceFee != 1.5 {
+					t.Errorf("unexpected tenant charges %+v", tenant.Charges)
+				}
+				if tenant.Limits.MaxQuantity != 10 || tenant.Limits.MaxItems != 50 || tenant.Limits.MaxTotal != 500 {
+					t.Errorf("unexpected tenant limits %+v", tenant.Limits)
+				}
+				if tenant.Kitchen.Stations != 1 || tenant.Kitchen.DefaultPrepTime != 15*time.Minute || tenant.Kitchen.MaxActive != 0 || tenant.Kitchen.WhenBusy != KitchenBusyReject {
+					t.Errorf("expected tenant kitchen defaults, got %+v", tenant.Kitchen)
+				}
+				if tenant.Hours.Timezone != "Australia/Sydney" || tenant.Hours.OrderAhead != 30*time.Minute {
+					t.Errorf("unexpected tenant hours %+v", tenant.Hours)
+				}
+				if len(tenant.Hours.Weekly["monday"]) != 2 {
+					t.Errorf("expected 2 monday ranges, got %v", tenant.Hours.Weekly)
+				}
+				if len(cfg.Hours.Weekly) != 0 {
+					t.Errorf("expected default tenant to be always open, got %v", cfg.Hours.Weekly)
+				}
+			},
+		},
+		{
+			name: "duplicate tenant id",
+			configFile: `files:
+  productsfile: "./data/products.json"
+  couponsdir: "./data/coupons"
+tenants:
+  - id: "harbour"
+    files: {productsfile: "./a.json", couponsdir: "./a"}
+  - id: "harbour"
+    files: {productsfile: "./b.json", couponsdir: "./b"}`,
+			wantErr: true,
+		},
+		{
+			name: "invalid zone polygon",
+			configFile: `files:
+  productsfile: "./data/products.json"
+  couponsdir: "./data/coupons"
+zones:
+  - name: "bad"
+    polygon: [[1, 2, 3]]`,
+			wantErr: true,
+		},
+		{
+			name: "tenant without files",
+			configFile: `files:
+  productsfile: "./data/products.json"
+  couponsdir: "./data/coupons"
+tenants:
+  - id: "harbour"`,
+			wantErr: true,
+		},
+		{
+			name: "invalid kitchen stations",
+			envVars: map[string]string{
+				"PRODUCTS_FILE": "./testdata/products.json",
+				"COUPONS_DIR":   "./testdata/coupons",
+			},
+			configFile: `kitchen:
+  stations: 0`,
+			wantErr: true,
+		},
+		{
+			name: "invalid kitchen busy policy",
+			envVars: map[string]string{
+				"PRODUCTS_FILE": "./testdata/products.json",
+				"COUPONS_DIR":   "./testdata/coupons",
+			},
+			configFile: `kitchen:
+  maxactive: 5
+  whenbusy: "queue"`,
+			wantErr: true,
+		},
+		{
+			name: "images from env vars",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":   "./testdata/products.json",
+				"COUPONS_DIR":     "./testdata/coupons",
+				"IMAGES_DIR":      "/var/lib/oolio/images",
+				"IMAGES_BASE_URL": "https://cdn.example.com/images",
+				"IMAGES_MAX_AGE":  "168h",
+			},
+			wantErr: false,
+			validateCfg: func(t *testing.T, cfg *Config) {
+				if cfg.Images.Dir != "/var/lib/oolio/images" || cfg.Images.BaseURL != "https://cdn.example.com/images" || cfg.Images.MaxAge != 168*time.Hour {
+					t.Errorf("unexpected images %+v", cfg.Images)
+				}
+			},
+		},
+		{
+			name: "negative images max age",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":  "./testdata/products.json",
+				"COUPONS_DIR":    "./testdata/coupons",
+				"IMAGES_MAX_AGE": "-1h",
+			},
+			wantErr: true,
+		},
+		{
+			name: "image signing from config file",
+			envVars: map[string]string{
+				"PRODUCTS_FILE": "./testdata/products.json",
+				"COUPONS_DIR":   "./testdata/coupons",
+			},
+			configFile: `images:
+  signing:
+    type: "CloudFront"
+    urlprefix: "https://cdn.example.com/images/"
+    keyid: "K2JCJMDEHXQW5F"
+    keyfile: "/etc/oolio/cloudfront.pem"
+    ttl: "15m"`,
+			wantErr: false,
+			validateCfg: func(t *testing.T, cfg *Config) {
+				want := ImageSigning{
+					Type:      SigningCloudFront,
+					URLPrefix: "https://cdn.example.com/images/",
+					KeyID:     "K2JCJMDEHXQW5F",
+					KeyFile:   "/etc/oolio/cloudfront.pem",
+					TTL:       15 * time.Minute,
+				}
+				if cfg.Images.Signing != want {
+					t.Errorf("expected signing %+v, got %+v", want, cfg.Images.Signing)
+				}
+			},
+		},
+		{
+			name: "unknown image signing type",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":       "./testdata/products.json",
+				"COUPONS_DIR":         "./testdata/coupons",
+				"IMAGES_SIGNING_TYPE": "s3",
+			},
+			wantErr: true,
+		},
+		{
+			name: "image signing without key",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":             "./testdata/products.json",
+				"COUPONS_DIR":               "./testdata/coupons",
+				"IMAGES_SIGNING_TYPE":       "cloudcdn",
+				"IMAGES_SIGNING_URL_PREFIX": "https://cdn.example.com/",
+				"IMAGES_SIGNING_KEY_ID":     "catalog",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid image signing ttl",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":             "./testdata/products.json",
+				"COUPONS_DIR":               "./testdata/coupons",
+				"IMAGES_SIGNING_TYPE":       "cloudcdn",
+				"IMAGES_SIGNING_URL_PREFIX": "https://cdn.example.com/",
+				"IMAGES_SIGNING_KEY_ID":     "catalog",
+				"IMAGES_SIGNING_KEY_FILE":   "/etc/oolio/cdn.key",
+				"IMAGES_SIGNING_TTL":        "0s",
+			},
+			wantErr: true,
+		},
+		{
+			name: "identity providers from env vars",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":     "./testdata/products.json",
+				"COUPONS_DIR":       "./testdata/coupons",
+				"GOOGLE_CLIENT_IDS": "web.apps.googleusercontent.com, ios.apps.googleusercontent.com",
+				"JWT_SECRET":        "0123456789abcdef0123456789abcdef",
+				"SESSION_TTL":       "30m",
+				"REFRESH_TTL":       "168h",
+			},
+			wantErr: false,
+			validateCfg: func(t *testing.T, cfg *Config) {
+				if len(cfg.Auth.Google.ClientIDs) != 2 || cfg.Auth.Google.Issuer != "https://accounts.google.com" {
+					t.Errorf("expected google provider with 2 client ids, got %+v", cfg.Auth.Google)
+				}
+				if len(cfg.Auth.Apple.ClientIDs) != 0 || cfg.Auth.Apple.JWKSURL != "https://appleid.apple.com/auth/keys" {
+					t.Errorf("expected apple provider disabled with default keys, got %+v", cfg.Auth.Apple)
+				}
+				if cfg.Auth.SessionTTL != 30*time.Minute {
+					t.Errorf("expected session ttl 30m, got %v", cfg.Auth.SessionTTL)
+				}
+				if cfg.Auth.RefreshTTL != 168*time.Hour {
+					t.Errorf("expected refresh ttl 168h, got %v", cfg.Auth.RefreshTTL)
+				}
+				if cfg.Auth.Passwords != (Passwords{Hash: PasswordArgon2id, MaxFailedLogins: 5, UnlockTTL: 24 * time.Hour, VerifyTTL: 48 * time.Hour, ResetTTL: time.Hour}) {
+					t.Errorf("expected default password policy, got %+v", cfg.Auth.Passwords)
+				}
+			},
+		},
+		{
+			name: "short jwt secret",
+			envVars: map[string]string{
+				"PRODUCTS_FILE": "./testdata/products.json",
+				"COUPONS_DIR":   "./testdata/coupons",
+				"JWT_SECRET":    "secret",
+			},
+			wantErr: true,
+		},
+		{
+			name: "password policy and email from env vars",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":      "./testdata/products.json",
+				"COUPONS_DIR":        "./testdata/coupons",
+				"PASSWORD_HASH":      "BCRYPT",
+				"MAX_FAILED_LOGINS":  "0",
+				"UNLOCK_TTL":         "2h",
+				"VERIFY_EMAIL_TTL":   "24h",
+				"PASSWORD_RESET_TTL": "30m",
+				"APP_URL":            "https://app.example.com/",
+				"SMTP_ADDR":          "smtp.example.com:587",
+				"EMAIL_FROM":         "orders@example.com",
+			},
+			wantErr: false,
+			validateCfg: func(t *testing.T, cfg *Config) {
+				if cfg.Auth.Passwords != (Passwords{Hash: PasswordBcrypt, MaxFailedLogins: 0, UnlockTTL: 2 * time.Hour, VerifyTTL: 24 * time.Hour, ResetTTL: 30 * time.Minute}) {
+					t.Errorf("expected bcrypt without lockout, got %+v", cfg.Auth.Passwords)
+				}
+				if cfg.Auth.AppURL != "https://app.example.com" {
+					t.Errorf("expected app url without trailing slash, got %s", cfg.Auth.AppURL)
+				}
+				if cfg.Email.SMTPAddr != "smtp.example.com:587" || cfg.Email.From != "orders@example.com" {
+					t.Errorf("expected smtp email settings, got %+v", cfg.Email)
+				}
+			},
+		},
+		{
+			name: "unknown password hash",
+			envVars: map[string]string{
+				"PRODUCTS_FILE": "./testdata/products.json",
+				"COUPONS_DIR":   "./testdata/coupons",
+				"PASSWORD_HASH": "md5",
+			},
+			wantErr: true,
+		},
+		{
+			name: "relative app url",
+			envVars: map[string]string{
+				"PRODUCTS_FILE": "./testdata/products.json",
+				"COUPONS_DIR":   "./testdata/coupons",
+				"APP_URL":       "/app",
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative max failed logins",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":     "./testdata/products.json",
+				"COUPONS_DIR":       "./testdata/coupons",
+				"MAX_FAILED_LOGINS": "-1",
+			},
+			wantErr: true,
+		},
+		{
+			name: "non-positive refresh ttl",
+			envVars: map[string]string{
+				"PRODUCTS_FILE": "./testdata/products.json",
+				"COUPONS_DIR":   "./testdata/coupons",
+				"REFRESH_TTL":   "0s",
+			},
+			wantErr: true,
+		},
+		{
+			name: "identity provider without keys",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":    "./testdata/products.json",
+				"COUPONS_DIR":      "./testdata/coupons",
+				"APPLE_CLIENT_IDS": "com.example.app",
+				"APPLE_JWKS_URL":   "keys.json",
+			},
+			wantErr: true,
+		},
+		{
+			name: "usage from env vars",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":        "./testdata/products.json",
+				"COUPONS_DIR":          "./testdata/coupons",
+				"USAGE_FILE":           "./testdata/usage.json",
+				"USAGE_FLUSH_INTERVAL": "10s",
+			},
+			wantErr: false,
+			validateCfg: func(t *testing.T, cfg *Config) {
+				if cfg.Usage != (Usage{File: "./testdata/usage.json", FlushInterval: 10 * time.Second}) {
+					t.Errorf("expected usage flushed every 10s, got %+v", cfg.Usage)
+				}
+			},
+		},
+		{
+			name: "non-positive usage flush interval",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":        "./testdata/products.json",
+				"COUPONS_DIR":          "./testdata/coupons",
+				"USAGE_FLUSH_INTERVAL": "0s",
+			},
+			wantErr: true,
+		},
+		{
+			name: "order events from env vars",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":             "./testdata/products.json",
+				"COUPONS_DIR":               "./testdata/coupons",
+				"ORDER_EVENTS_DIR":          "./testdata/orders",
+				"ORDER_PROJECTION_INTERVAL": "30s",
+				"ORDER_PAYMENT_LINK_URL":    "https://pay.example.com/split",
+				"ORDER_TABLE_SECRET":        "0123456789abcdef0123456789abcdef",
+				"ORDER_HOLD_TTL":            "5m",
+				"ORDER_RETENTION":           "2160h",
+				"ORDER_ARCHIVE_DIR":         "./testdata/archive",
+			},
+			wantErr: false,
+			validateCfg: func(t *testing.T, cfg *Config) {
+				want := Orders{EventsDir: "./testdata/orders", ProjectionInterval: 30 * time.Second, PaymentLinkURL: "https://pay.example.com/split", TableSecret: "0123456789abcdef0123456789abcdef", HoldTTL: 5 * time.Minute, Retention: 2160 * time.Hour, ArchiveDir: "./testdata/archive"}
+				if cfg.Orders != want {
+					t.Errorf("expected order events projected every 30s, got %+v", cfg.Orders)
+				}
+			},
+		},
+		{
+			name: "accounting from env vars",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":               "./testdata/products.json",
+				"COUPONS_DIR":                 "./testdata/coupons",
+				"ACCOUNTING_SALES_ACCOUNT":    "4000",
+				"ACCOUNTING_DELIVERY_ACCOUNT": "4100",
+				"ACCOUNTING_TAX_TYPE":         "GST on Income",
+				"ACCOUNTING_DATE_FORMAT":      "01/02/2006",
+			},
+			wantErr: false,
+			validateCfg: func(t *testing.T, cfg *Config) {
+				a := cfg.Accounting
+				if a.SalesAccount != "4000" || a.DeliveryAccount != "4100" || a.FeesAccount != "" || a.TaxType != "GST on Income" || a.DateFormat != "01/02/2006" {
+					t.Errorf("expected accounting from env vars, got %+v", a)
+				}
+			},
+		},
+		{
+			name: "accounting date format without a date",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":          "./testdata/products.json",
+				"COUPONS_DIR":            "./testdata/coupons",
+				"ACCOUNTING_DATE_FORMAT": "invoice",
+			},
+			wantErr: true,
+		},
+		{
+			name: "relative payment link url",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":          "./testdata/products.json",
+				"COUPONS_DIR":            "./testdata/coupons",
+				"ORDER_PAYMENT_LINK_URL": "/pay",
+			},
+			wantErr: true,
+		},
+		{
+			name: "log rotation from env vars",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":    "./testdata/products.json",
+				"COUPONS_DIR":      "./testdata/coupons",
+				"LOG_FILE":         "./logs/server.log",
+				"LOG_MAX_SIZE":     "1048576",
+				"LOG_MAX_AGE":      "1h",
+				"LOG_MAX_BACKUPS":  "3",
+				"LOG_SAMPLE_LIMIT": "0",
+			},
+			validateCfg: func(t *testing.T, cfg *Config) {
+				if cfg.Logging.File != "./logs/server.log" {
+					t.Errorf("expected log file ./logs/server.log, got %s", cfg.Logging.File)
+				}
+				if cfg.Logging.MaxSize != 1<<20 || cfg.Logging.MaxAge != time.Hour || cfg.Logging.MaxBackups != 3 {
+					t.Errorf("expected rotation at 1 MiB or 1h keeping 3 files, got %d, %v, %d", cfg.Logging.MaxSize, cfg.Logging.MaxAge, cfg.Logging.MaxBackups)
+				}
+				if cfg.Logging.SampleLimit != 0 {
+					t.Errorf("expected sampling disabled, got %d", cfg.Logging.SampleLimit)
+				}
+			},
+		},
+		{
+			name: "combined access log from env var",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":     "./testdata/products.json",
+				"COUPONS_DIR":       "./testdata/coupons",
+				"LOG_ACCESS_FORMAT": "combined",
+			},
+			validateCfg: func(t *testing.T, cfg *Config) {
+				if cfg.Logging.AccessFormat != "combined" {
+					t.Errorf("expected access log format combined, got %s", cfg.Logging.AccessFormat)
+				}
+			},
+		},
+		{
+			name: "unknown access log format",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":     "./testdata/products.json",
+				"COUPONS_DIR":       "./testdata/coupons",
+				"LOG_ACCESS_FORMAT": "common",
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative log sample limit",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":    "./testdata/products.json",
+				"COUPONS_DIR":      "./testdata/coupons",
+				"LOG_SAMPLE_LIMIT": "-1",
+			},
+			wantErr: true,
+		},
+		{
+			name: "short table secret",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":      "./testdata/products.json",
+				"COUPONS_DIR":        "./testdata/coupons",
+				"ORDER_TABLE_SECRET": "too-short",
+			},
+			wantErr: true,
+		},
+		{
+			name: "non-positive order projection interval",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":             "./testdata/products.json",
+				"COUPONS_DIR":               "./testdata/coupons",
+				"ORDER_PROJECTION_INTERVAL": "0s",
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative hold ttl",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":  "./testdata/products.json",
+				"COUPONS_DIR":    "./testdata/coupons",
+				"ORDER_HOLD_TTL": "-1m",
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative order retention",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":   "./testdata/products.json",
+				"COUPONS_DIR":     "./testdata/coupons",
+				"ORDER_RETENTION": "-24h",
+			},
+			wantErr: true,
+		},
+		{
+			name: "catalog sync from env vars",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":        "./testdata/products.json",
+				"COUPONS_DIR":          "./testdata/coupons",
+				"CATALOG_SYNC_URL":     "nats://localhost:4222",
+				"CATALOG_SYNC_SUBJECT": "upstream.products",
+			},
+			wantErr: false,
+			validateCfg: func(t *testing.T, cfg *Config) {
+				if cfg.Catalog != (CatalogSync{URL: "nats://localhost:4222", Subject: "upstream.products"}) {
+					t.Errorf("expected catalog sync from nats://localhost:4222, got %+v", cfg.Catalog)
+				}
+			},
+		},
+		{
+			name: "catalog sync from an unsupported broker",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":    "./testdata/products.json",
+				"COUPONS_DIR":      "./testdata/coupons",
+				"CATALOG_SYNC_URL": "kafka://localhost:9092",
+			},
+			wantErr: true,
+		},
+		{
+			name: "challenge from env vars",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":      "./testdata/products.json",
+				"COUPONS_DIR":        "./testdata/coupons",
+				"CHALLENGE_PROVIDER": "Turnstile",
+				"CHALLENGE_SECRET":   "0x4AAAAAAA",
+			},
+			wantErr: false,
+			validateCfg: func(t *testing.T, cfg *Config) {
+				want := Challenge{Provider: ChallengeTurnstile, Secret: "0x4AAAAAAA", Timeout: 5 * time.Second}
+				if cfg.Challenge != want {
+					t.Errorf("expected challenge %+v, got %+v", want, cfg.Challenge)
+				}
+			},
+		},
+		{
+			name: "unknown challenge provider",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":      "./testdata/products.json",
+				"COUPONS_DIR":        "./testdata/coupons",
+				"CHALLENGE_PROVIDER": "hcaptcha",
+				"CHALLENGE_SECRET":   "secret",
+			},
+			wantErr: true,
+		},
+		{
+			name: "challenge without secret",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":      "./testdata/products.json",
+				"COUPONS_DIR":        "./testdata/coupons",
+				"CHALLENGE_PROVIDER": "recaptcha",
+			},
+			wantErr: true,
+		},
+		{
+			name: "relative challenge verify url",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":        "./testdata/products.json",
+				"COUPONS_DIR":          "./testdata/coupons",
+				"CHALLENGE_PROVIDER":   "recaptcha",
+				"CHALLENGE_SECRET":     "secret",
+				"CHALLENGE_VERIFY_URL": "/siteverify",
+			},
+			wantErr: true,
+		},
+		{
+			name: "relative images base url",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":   "./testdata/products.json",
+				"COUPONS_DIR":     "./testdata/coupons",
+				"IMAGES_BASE_URL": "/public/images",
+			},
+			wantErr: true,
+		},
+		{
+			name: "body limits from env vars",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":        "./testdata/products.json",
+				"COUPONS_DIR":          "./testdata/coupons",
+				"SERVER_MAX_BODY_SIZE": "65536",
+				"SERVER_STRICT_JSON":   "false",
+				"SERVER_LINKS":         "true",
+				"SERVER_ENVELOPE":      "true",
+				"DEPRECATIONS_FILE":    "./testdata/deprecations.json",
+			},
+			wantErr: false,
+			validateCfg: func(t *testing.T, cfg *Config) {
+				if cfg.Server.MaxBodySize != 65536 || cfg.Server.StrictJSON || !cfg.Server.Links || !cfg.Server.Envelope ||
+					cfg.Server.DeprecationsFile != "./testdata/deprecations.json" {
+					t.Errorf("unexpected server %+v", cfg.Server)
+				}
+			},
+		},
+		{
+			name: "invalid max body size",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":        "./testdata/products.json",
+				"COUPONS_DIR":          "./testdata/coupons",
+				"SERVER_MAX_BODY_SIZE": "0",
+			},
+			wantErr: true,
+		},
+		{
+			name: "route timeouts from env vars",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":          "./testdata/products.json",
+				"COUPONS_DIR":            "./testdata/coupons",
+				"SERVER_CATALOG_TIMEOUT": "500ms",
+				"SERVER_ORDER_TIMEOUT":   "0s",
+			},
+			wantErr: false,
+			validateCfg: func(t *testing.T, cfg *Config) {
+				if cfg.Server.CatalogTimeout != 500*time.Millisecond || cfg.Server.OrderTimeout != 0 {
+					t.Errorf("unexpected route timeouts %v, %v", cfg.Server.CatalogTimeout, cfg.Server.OrderTimeout)
+				}
+			},
+		},
+		{
+			name: "CORS origins from env vars",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":       "./testdata/products.json",
+				"COUPONS_DIR":         "./testdata/coupons",
+				"SERVER_CORS_ORIGINS": "https://shop.example.com, https://admin.example.com",
+				"SERVER_CORS_MAX_AGE": "1h",
+			},
+			wantErr: false,
+			validateCfg: func(t *testing.T, cfg *Config) {
+				if !slices.Equal(cfg.Server.CORSOrigins, []string{"https://shop.example.com", "https://admin.example.com"}) {
+					t.Errorf("unexpected CORS origins %v", cfg.Server.CORSOrigins)
+				}
+				if cfg.Server.CORSMaxAge != time.Hour {
+					t.Errorf("unexpected CORS max age %v", cfg.Server.CORSMaxAge)
+				}
+			},
+		},
+		{
+			name: "negative route timeout",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":        "./testdata/products.json",
+				"COUPONS_DIR":          "./testdata/coupons",
+				"SERVER_ORDER_TIMEOUT": "-1s",
+			},
+			wantErr: true,
+		},
+		{
+			name: "product cache TTL from env var",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":     "./testdata/products.json",
+				"COUPONS_DIR":       "./testdata/coupons",
+				"PRODUCT_CACHE_TTL": "0s",
+			},
+			wantErr: false,
+			validateCfg: func(t *testing.T, cfg *Config) {
+				if cfg.Cache.ProductTTL != 0 {
+					t.Errorf("expected product caching disabled, got %v", cfg.Cache.ProductTTL)
+				}
+			},
+		},
+		{
+			name: "negative product cache TTL",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":     "./testdata/products.json",
+				"COUPONS_DIR":       "./testdata/coupons",
+				"PRODUCT_CACHE_TTL": "-1s",
+			},
+			wantErr: true,
+		},
+		{
+			name: "lenient product loading from env vars",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":    "./testdata/products.json",
+				"COUPONS_DIR":      "./testdata/coupons",
+				"PRODUCTS_LENIENT": "true",
+			},
+			wantErr: false,
+			validateCfg: func(t *testing.T, cfg *Config) {
+				if !cfg.Products.Lenient {
+					t.Error("expected lenient product loading")
+				}
+			},
+		},
+		{
+			name: "coupon integrity policy from env vars",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":    "./testdata/products.json",
+				"COUPONS_DIR":      "./testdata/coupons",
+				"COUPON_INTEGRITY": "Quarantine",
+			},
+			wantErr: false,
+			validateCfg: func(t *testing.T, cfg *Config) {
+				if cfg.Coupons.Integrity != CouponIntegrityQuarantine {
+					t.Errorf("expected coupon integrity %q, got %q", CouponIntegrityQuarantine, cfg.Coupons.Integrity)
+				}
+			},
+		},
+		{
+			name: "invalid coupon integrity policy",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":    "./testdata/products.json",
+				"COUPONS_DIR":      "./testdata/coupons",
+				"COUPON_INTEGRITY": "ignore",
+			},
+			wantErr: true,
+		},
+		{
+			name: "order limits from env vars",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":      "./testdata/products.json",
+				"COUPONS_DIR":        "./testdata/coupons",
+				"ORDER_MAX_QUANTITY": "20",
+				"ORDER_MAX_ITEMS":    "0",
+				"ORDER_MAX_TOTAL":    "250.5",
+				"ORDER_MIN_PICKUP":   "10",
+				"ORDER_MIN_DELIVERY": "25",
+			},
+			wantErr: false,
+			validateCfg: func(t *testing.T, cfg *Config) {
+				if cfg.Limits.MaxQuantity != 20 || cfg.Limits.MaxItems != 0 || cfg.Limits.MaxTotal != 250.5 ||
+					cfg.Limits.MinPickup != 10 || cfg.Limits.MinDelivery != 25 {
+					t.Errorf("unexpected limits %+v", cfg.Limits)
+				}
+			},
+		},
+		{
+			name: "negative minimum order",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":      "./testdata/products.json",
+				"COUPONS_DIR":        "./testdata/coupons",
+				"ORDER_MIN_DELIVERY": "-5",
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative order limit",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":   "./testdata/products.json",
+				"COUPONS_DIR":     "./testdata/coupons",
+				"ORDER_MAX_ITEMS": "-1",
+			},
+			wantErr: true,
+		},
+		{
+			name: "velocity rules from env vars",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":                 "./testdata/products.json",
+				"COUPONS_DIR":                   "./testdata/coupons",
+				"VELOCITY_HOLD_ORDERS_PER_HOUR": "3",
+				"VELOCITY_MAX_ORDERS_PER_HOUR":  "10",
+				"VELOCITY_HOLD_TOTAL_PER_DAY":   "200",
+				"VELOCITY_MAX_TOTAL_PER_DAY":    "1000",
+			},
+			wantErr: false,
+			validateCfg: func(t *testing.T, cfg *Config) {
+				want := Velocity{HoldOrdersPerHour: 3, MaxOrdersPerHour: 10, HoldTotalPerDay: 200, MaxTotalPerDay: 1000}
+				if cfg.Velocity != want {
+					t.Errorf("unexpected velocity %+v", cfg.Velocity)
+				}
+			},
+		},
+		{
+			name: "velocity hold above refusal",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":                 "./testdata/products.json",
+				"COUPONS_DIR":                   "./testdata/coupons",
+				"VELOCITY_HOLD_ORDERS_PER_HOUR": "10",
+				"VELOCITY_MAX_ORDERS_PER_HOUR":  "5",
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative velocity rule",
+			envVars: map[string]string{
+				"PRODUCTS_FILE":              "./testdata/products.json",
+				"COUPONS_DIR":                "./testdata/coupons",
+				"VELOCITY_MAX_TOTAL_PER_DAY": "-1",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid tax rate",
+			envVars: map[string]string{
+				"PRODUCTS_FILE": "./testdata/products.json",
+				"COUPONS_DIR":   "./testdata/coupons",
+				"TAX_RATE":      "1.5",
+			},
+			wantErr: true,
+		},
 		{
 			name: "invalid log level",
 			envVars: map[string]string{
@@ -243,6 +1211,45 @@ func TestDefaultValues(t *testing.T) {
 	if cfg.Logging.Format != "json" {
 		t.Errorf("expected default log format json, got %s", cfg.Logging.Format)
 	}
+	if cfg.Logging.AccessFormat != "text" {
+		t.Errorf("expected default access log format text, got %s", cfg.Logging.AccessFormat)
+	}
+	if cfg.Logging.MaxSize != 100<<20 || cfg.Logging.MaxAge != 24*time.Hour || cfg.Logging.MaxBackups != 7 || cfg.Logging.SampleLimit != 10 {
+		t.Errorf("expected default log rotation at 100 MiB or 24h keeping 7 files sampled at 10, got %+v", cfg.Logging)
+	}
+	if cfg.Server.MaxBodySize != 1<<20 {
+		t.Errorf("expected default max body size 1 MiB, got %d", cfg.Server.MaxBodySize)
+	}
+	if !cfg.Server.StrictJSON {
+		t.Errorf("expected strict JSON decoding by default")
+	}
+	if cfg.Server.Links {
+		t.Errorf("expected responses without links by default")
+	}
+	if cfg.Server.Envelope {
+		t.Errorf("expected bare responses by default")
+	}
+	if cfg.Server.CatalogTimeout != 2*time.Second || cfg.Server.OrderTimeout != 5*time.Second {
+		t.Errorf("unexpected default route timeouts %v, %v", cfg.Server.CatalogTimeout, cfg.Server.OrderTimeout)
+	}
+	if len(cfg.Server.CORSOrigins) != 0 || cfg.Server.CORSMaxAge != 10*time.Minute {
+		t.Errorf("unexpected default CORS policy %v, %v", cfg.Server.CORSOrigins, cfg.Server.CORSMaxAge)
+	}
+	if cfg.Orders.EventsDir != "./data/orders" {
+		t.Errorf("expected orders to be logged by default, got %q", cfg.Orders.EventsDir)
+	}
+	if cfg.Cache.ProductTTL != 5*time.Second {
+		t.Errorf("unexpected default product cache TTL %v", cfg.Cache.ProductTTL)
+	}
+	if cfg.Products.Lenient {
+		t.Error("expected products to be loaded strictly by default")
+	}
+	if cfg.Coupons.Integrity != CouponIntegrityWarn {
+		t.Errorf("unexpected default coupon integrity %q", cfg.Coupons.Integrity)
+	}
+	if cfg.Limits.MaxQuantity != 100 || cfg.Limits.MaxItems != 50 || cfg.Limits.MaxTotal != 0 {
+		t.Errorf("unexpected default limits %+v", cfg.Limits)
+	}
 }
 
 func TestGetServerTimeouts(t *testing.T) {
@@ -0,0 +1,307 @@
+// Package couriers tracks delivery orders from the restaurant to the
+// customer: the courier each order is assigned to, where the courier last
+// reported being, and the delivery statuses the order moves through. The
+// statuses are added to the order's history; couriers and locations are
+// kept in memory and forgotten when the server restarts.
+package couriers
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/kitchen"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/orders"
+)
+
+// Delivery statuses, in the order a delivery moves through them
+const (
+	StatusAssigned  = "assigned"  // A courier is on the way to collect the order
+	StatusPickedUp  = "picked_up" // The courier collected the order
+	StatusEnRoute   = "en_route"  // The courier is on the way to the customer
+	StatusDelivered = "delivered" // The order reached the customer
+)
+
+// Statuses are the delivery statuses, in the order a delivery moves through
+// them
+var Statuses = []string{StatusAssigned, StatusPickedUp, StatusEnRoute, StatusDelivered}
+
+var (
+	// ErrNotDelivery is returned for orders that are not delivered
+	ErrNotDelivery = errors.New("order is not for delivery")
+	// ErrInvalidStatus is returned for a delivery status the order cannot
+	// move to from the one it is in
+	ErrInvalidStatus = errors.New("invalid delivery status")
+)
+
+// Courier is a courier an order is assigned to
+type Courier struct {
+	// The courier's ID, as the restaurant or delivery partner knows them
+	// @required
+	// @example courier-7
+	ID string `json:"id" validate:"required,max=64"`
+
+	// The courier's name, as shown to the customer
+	// @example Sam
+	Name string `json:"name,omitempty" validate:"omitempty,max=100"`
+}
+
+// Location is where a courier reported being
+type Location struct {
+	// Latitude of the courier
+	// @example -33.8688
+	Latitude float64 `json:"latitude"`
+
+	// Longitude of the courier
+	// @example 151.2093
+	Longitude float64 `json:"longitude"`
+
+	// When the courier reported it
+	// @example 2024-01-01T12:40:00Z
+	At time.Time `json:"at"`
+}
+
+// LocationRequest reports where a courier is
+type LocationRequest struct {
+	// Latitude of the courier
+	// @required
+	// @minimum -90
+	// @maximum 90
+	// @example -33.8688
+	Latitude *float64 `json:"latitude" validate:"required,gte=-90,lte=90"`
+
+	// Longitude of the courier
+	// @required
+	// @minimum -180
+	// @maximum 180
+	// @example 151.2093
+	Longitude *float64 `json:"longitude" validate:"required,gte=-180,lte=180"`
+}
+
+// StatusRequest moves a delivery on
+type StatusRequest struct {
+	// The delivery status the order moved to
+	// @required
+	// @example picked_up
+	Status string `json:"status" validate:"required,oneof=picked_up en_route delivered"`
+}
+
+// Tracking is a delivery as the customer follows it
+type Tracking struct {
+	// The order being delivered
+	// @example order-0000-0000-0000-0000
+	OrderID string `json:"order_id"`
+
+	// Delivery status: assigned, picked_up, en_route or delivered; empty
+	// until a courier is assigned
+	// @example en_route
+	Status string `json:"status,omitempty"`
+
+	// The courier delivering the order, until it is delivered
+	Courier *Courier `json:"courier,omitempty"`
+
+	// Where the courier last reported being, until the order is delivered
+	Location *Location `json:"location,omitempty"`
+
+	// Every delivery status the order entered, oldest first
+	History []models.StatusChange `json:"history"`
+}
+
+// delivery is what is known of a delivery beyond the order's history
+type delivery struct {
+	courier  *Courier
+	location *Location
+}
+
+// Tracker follows a restaurant's delivery orders, adding the delivery
+// statuses to the orders' history in store. A nil Tracker knows no orders.
+type Tracker struct {
+	mu         sync.Mutex // Held while a status is checked and recorded
+	store      orders.Store
+	kitchen    *kitchen.Queue
+	now        func() time.Time
+	deliveries map[string]*delivery
+}
+
+// NewTracker creates a new Tracker recording to store. The changes queue
+// made to an order are recorded before its delivery statuses, so they
+// come first in the order's history.
+func NewTracker(store orders.Store, queue *kitchen.Queue) *Tracker {
+	return &Tracker{
+		store:      store,
+		kitchen:    queue,
+		now:        time.Now,
+		deliveries: make(map[string]*delivery),
+	}
+}
+
+// Assign assigns an order to courier, moving it to the assigned status. An
+// order can be given another courier until it is picked up.
+func (t *Tracker) Assign(orderID string, courier Courier) (Tracking, error) {
+	if t == nil {
+		return Tracking{}, fmt.Errorf("%w: %s", orders.ErrNotFound, orderID)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.advance(orderID, StatusAssigned, models.ActorStaff); err != nil {
+		return Tracking{}, err
+	}
+	t.deliveries[orderID] = &delivery{courier: &courier}
+	return t.track(orderID)
+}
+
+// Advance moves an order on to a later delivery status, reported by its
+// courier. Picking an order up marks it ready in the kitchen, if it is not
+// already. Once delivered, its courier and location are forgotten.
+func (t *Tracker) Advance(orderID, status string) (Tracking, error) {
+	if t == nil {
+		return Tracking{}, fmt.Errorf("%w: %s", orders.ErrNotFound, orderID)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.advance(orderID, status, models.ActorCourier); err != nil {
+		return Tracking{}, err
+	}
+	if status == StatusDelivered {
+		delete(t.deliveries, orderID)
+	}
+	return t.track(orderID)
+}
+
+// Locate records where the courier of an order is. Locations are taken
+// from when the order is assigned until it is delivered.
+func (t *Tracker) Locate(orderID string, latitude, longitude float64) error {
+	if t == nil {
+		return fmt.Errorf("%w: %s", orders.ErrNotFound, orderID)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	history, err := t.history(orderID)
+	if err != nil {
+		return err
+	}
+	if status := Current(history); status == "" || status == StatusDelivered {
+		return fmt.Errorf("%w: order is %s", ErrInvalidStatus, describe(status))
+	}
+
+	d, ok := t.deliveries[orderID]
+	if !ok {
+		// The courier was assigned before a restart
+		d = &delivery{}
+		t.deliveries[orderID] = d
+	}
+	d.location = &Location{Latitude: latitude, Longitude: longitude, At: t.now().UTC()}
+	return nil
+}
+
+// Track returns a delivery as the customer follows it
+func (t *Tracker) Track(orderID string) (Tracking, error) {
+	if t == nil {
+		return Tracking{}, fmt.Errorf("%w: %s", orders.ErrNotFound, orderID)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.track(orderID)
+}
+
+// Check returns ErrInvalidStatus unless an order with the status history
+// history can move to the delivery status status. Statuses only move
+// forward, and a courier must be assigned first; an order can be assigned
+// again until it is picked up.
+func Check(history []models.StatusChange, status string) error {
+	current := Current(history)
+	next := slices.Index(Statuses, status)
+	switch {
+	case next < 0:
+		return fmt.Errorf("%w: %q", ErrInvalidStatus, status)
+	case status == StatusAssigned && (current == "" || current == StatusAssigned):
+		return nil
+	case current == "" || next <= slices.Index(Statuses, current):
+		return fmt.Errorf("%w: order is %s, not ready to be %s", ErrInvalidStatus, describe(current), status)
+	}
+	return nil
+}
+
+// Current returns the latest delivery status in history, or "" when no
+// courier was assigned
+func Current(history []models.StatusChange) string {
+	for i := len(history) - 1; i >= 0; i-- {
+		if slices.Contains(Statuses, history[i].Status) {
+			return history[i].Status
+		}
+	}
+	return ""
+}
+
+// describe names a delivery status in errors
+func describe(status string) string {
+	if status == "" {
+		return "not assigned"
+	}
+	return status
+}
+
+// advance checks that an order can move to status and records it, after
+// the changes the kitchen made to the order
+func (t *Tracker) advance(orderID, status, actor string) error {
+	t.kitchen.Flush()
+	history, err := t.history(orderID)
+	if err != nil {
+		return err
+	}
+	if err := Check(history, status); err != nil {
+		return err
+	}
+	if status == StatusPickedUp {
+		t.kitchen.MarkReady(orderID)
+		t.kitchen.Flush()
+	}
+	return t.store.ChangeStatus(orderID, models.StatusChange{Status: status, At: t.now().UTC(), Actor: actor})
+}
+
+// history returns the status history of a delivery order
+func (t *Tracker) history(orderID string) ([]models.StatusChange, error) {
+	if t == nil {
+		return nil, fmt.Errorf("%w: %s", orders.ErrNotFound, orderID)
+	}
+	h, ok := t.store.History(orderID)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", orders.ErrNotFound, orderID)
+	}
+	if h.Order.DeliveryAddress == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotDelivery, orderID)
+	}
+	return h.Order.StatusHistory, nil
+}
+
+// track returns a delivery as the customer follows it
+func (t *Tracker) track(orderID string) (Tracking, error) {
+	history, err := t.history(orderID)
+	if err != nil {
+		return Tracking{}, err
+	}
+
+	tracking := Tracking{OrderID: orderID, Status: Current(history), History: []models.StatusChange{}}
+	for _, change := range history {
+		if slices.Contains(Statuses, change.Status) {
+			tracking.History = append(tracking.History, change)
+		}
+	}
+	if d, ok := t.deliveries[orderID]; ok {
+		if d.courier != nil {
+			courier := *d.courier
+			tracking.Courier = &courier
+		}
+		if d.location != nil {
+			location := *d.location
+			tracking.Location = &location
+		}
+	}
+	return tracking, nil
+}
@@ -0,0 +1,105 @@
+package couriers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/kitchen"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/orders"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	history := func(statuses ...string) []models.StatusChange {
+		changes := []models.StatusChange{{Status: models.OrderStatusPlaced}}
+		for _, status := range statuses {
+			changes = append(changes, models.StatusChange{Status: status})
+		}
+		return changes
+	}
+
+	tests := []struct {
+		name    string
+		history []models.StatusChange
+		status  string
+		wantErr bool
+	}{
+		{name: "assign", history: history(kitchen.StatusPreparing), status: StatusAssigned},
+		{name: "assign again", history: history(StatusAssigned), status: StatusAssigned},
+		{name: "assign picked up", history: history(StatusAssigned, StatusPickedUp), status: StatusAssigned, wantErr: true},
+		{name: "pick up", history: history(StatusAssigned, kitchen.StatusReady), status: StatusPickedUp},
+		{name: "pick up unassigned", history: history(kitchen.StatusReady), status: StatusPickedUp, wantErr: true},
+		{name: "skip en route", history: history(StatusAssigned, StatusPickedUp), status: StatusDelivered},
+		{name: "go back", history: history(StatusAssigned, StatusPickedUp, StatusEnRoute), status: StatusPickedUp, wantErr: true},
+		{name: "deliver twice", history: history(StatusAssigned, StatusDelivered), status: StatusDelivered, wantErr: true},
+		{name: "unknown", history: history(StatusAssigned), status: kitchen.StatusReady, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Check(tt.history, tt.status)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidStatus)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestTracker(t *testing.T) {
+	store, err := orders.Open("")
+	require.NoError(t, err)
+	queue := kitchen.NewQueue(config.Kitchen{})
+	queue.RecordTo(store.ChangeStatus)
+	tracker := NewTracker(store, queue)
+
+	place := func(id string, address *models.Address) {
+		t.Helper()
+		order := &models.Order{ID: id, CreatedAt: time.Now(), DeliveryAddress: address}
+		order.SetStatus(models.OrderStatusPlaced, models.ActorCustomer, order.CreatedAt)
+		_, err := queue.Enqueue(order)
+		require.NoError(t, err)
+		require.NoError(t, store.Place(order))
+		queue.Flush()
+	}
+	place("order-1", &models.Address{Line1: "1 George St", Postcode: "2000"})
+	place("order-2", nil)
+
+	_, err = tracker.Assign("order-1", Courier{ID: "courier-7", Name: "Sam"})
+	require.NoError(t, err)
+	require.NoError(t, tracker.Locate("order-1", -33.8688, 151.2093))
+
+	// Picking the order up finishes it in the kitchen first
+	tracking, err := tracker.Advance("order-1", StatusPickedUp)
+	require.NoError(t, err)
+	assert.Equal(t, StatusPickedUp, tracking.Status)
+	assert.Equal(t, "Sam", tracking.Courier.Name)
+	assert.Equal(t, 151.2093, tracking.Location.Longitude)
+	history, _ := store.History("order-1")
+	statuses := make([]string, len(history.Order.StatusHistory))
+	for i, change := range history.Order.StatusHistory {
+		statuses[i] = change.Status
+	}
+	assert.Equal(t, []string{models.OrderStatusPlaced, kitchen.StatusPreparing, StatusAssigned, kitchen.StatusReady, StatusPickedUp}, statuses)
+
+	// Once delivered, the courier and location are forgotten
+	tracking, err = tracker.Advance("order-1", StatusDelivered)
+	require.NoError(t, err)
+	assert.Nil(t, tracking.Courier)
+	assert.Nil(t, tracking.Location)
+	assert.Len(t, tracking.History, 3)
+	assert.ErrorIs(t, tracker.Locate("order-1", 0, 0), ErrInvalidStatus)
+
+	_, err = tracker.Assign("order-2", Courier{ID: "courier-7"})
+	assert.ErrorIs(t, err, ErrNotDelivery)
+	_, err = tracker.Track("missing")
+	assert.ErrorIs(t, err, orders.ErrNotFound)
+
+	var none *Tracker
+	_, err = none.Track("order-1")
+	assert.ErrorIs(t, err, orders.ErrNotFound)
+}
@@ -0,0 +1,60 @@
+// Package cursor pages through listings by the sort key of the last item
+// seen rather than by offset. The key is handed to clients as an opaque
+// token, so a page never skips or repeats items when items before it are
+// added or removed, and the next page is found without counting through
+// the ones before it.
+package cursor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sort"
+)
+
+// ErrInvalid is returned for tokens that were not issued for the listing
+var ErrInvalid = errors.New("invalid cursor")
+
+// token is what a cursor encodes
+type token[K any] struct {
+	Listing string `json:"l"` // Which listing the token pages through
+	After   K      `json:"a"` // Sort key of the last item seen
+}
+
+// Encode returns the token continuing listing after the item with key
+func Encode[K any](listing string, key K) string {
+	raw, err := json.Marshal(token[K]{Listing: listing, After: key})
+	if err != nil {
+		// Keys are strings, numbers and sort fields, which always marshal
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// Decode returns the sort key a token for listing continues after
+func Decode[K any](listing, s string) (K, error) {
+	var after K
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return after, ErrInvalid
+	}
+	var t token[json.RawMessage]
+	if err := json.Unmarshal(raw, &t); err != nil || t.Listing != listing {
+		return after, ErrInvalid
+	}
+	// Every token carries a key, which a null would leave unset
+	if len(t.After) == 0 || string(t.After) == "null" {
+		return after, ErrInvalid
+	}
+	if err := json.Unmarshal(t.After, &after); err != nil {
+		return after, ErrInvalid
+	}
+	return after, nil
+}
+
+// Start returns the position in items, sorted ascending by key, of the first
+// item after the one with key after. less reports whether one key sorts
+// before another.
+func Start[T, K any](items []T, after K, key func(T) K, less func(a, b K) bool) int {
+	return sort.Search(len(items), func(i int) bool { return less(after, key(items[i])) })
+}
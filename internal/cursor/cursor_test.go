@@ -0,0 +1,43 @@
+package cursor
+
+import (
+	"cmp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	token := Encode("products", "prod-42")
+	after, err := Decode[string]("products", token)
+	require.NoError(t, err)
+	assert.Equal(t, "prod-42", after)
+
+	seq, err := Decode[uint64]("orders", Encode("orders", uint64(7)))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), seq)
+
+	// Tokens only page through the listing they were issued for
+	_, err = Decode[string]("orders", token)
+	assert.ErrorIs(t, err, ErrInvalid)
+	_, err = Decode[uint64]("products", token)
+	assert.ErrorIs(t, err, ErrInvalid)
+	_, err = Decode[string]("products", "not a token")
+	assert.ErrorIs(t, err, ErrInvalid)
+	_, err = Decode[uint64]("orders", Encode("orders", "seven"))
+	assert.ErrorIs(t, err, ErrInvalid)
+	_, err = Decode[*string]("orders", Encode[*string]("orders", nil))
+	assert.ErrorIs(t, err, ErrInvalid, "tokens always carry a key")
+}
+
+func TestStart(t *testing.T) {
+	ids := []string{"a", "c", "e"}
+	self := func(id string) string { return id }
+
+	assert.Equal(t, 0, Start(ids, "", self, cmp.Less[string]))
+	assert.Equal(t, 1, Start(ids, "a", self, cmp.Less[string]))
+	// Keys of items removed since still find their place
+	assert.Equal(t, 2, Start(ids, "d", self, cmp.Less[string]))
+	assert.Equal(t, 3, Start(ids, "e", self, cmp.Less[string]))
+}
@@ -0,0 +1,249 @@
+// Package dashboard builds the order dashboards restaurants watch during
+// service: orders placed per hour, how many orders the kitchen has yet to
+// finish, and how long orders take to be ready. The figures are projected
+// from the order event log in the background and published as a snapshot,
+// so dashboards never wait on orders being placed.
+package dashboard
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/kitchen"
+	"github.com/ravibandhu/oolio-food-ordering/internal/orders"
+)
+
+// MaxHours is how many hours of figures are kept
+const MaxHours = 7 * 24
+
+// Source is the order event log figures are projected from
+type Source interface {
+	EventsAfter(seq uint64) []orders.Event
+}
+
+// Hour is the figures of the orders placed in one hour
+type Hour struct {
+	// Start of the hour, in UTC
+	// @example 2024-01-01T12:00:00Z
+	Start time.Time `json:"start"`
+
+	// Orders placed in the hour
+	// @example 18
+	Orders int `json:"orders"`
+
+	// How many of them are ready
+	// @example 15
+	Ready int `json:"ready"`
+
+	// Average time from being placed to being ready of the orders that are
+	// ready, in seconds
+	// @example 840
+	AvgPrepSeconds float64 `json:"avgPrepSeconds"`
+}
+
+// Summary is the figures of a range of hours up to now
+type Summary struct {
+	// When the figures last caught up with the order events; zero when
+	// orders are not kept
+	// @example 2024-01-01T12:34:56Z
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	// Orders the kitchen is preparing or has yet to start
+	// @example 4
+	QueueDepth int `json:"queueDepth"`
+
+	// Orders placed over the range
+	// @example 120
+	Orders int `json:"orders"`
+
+	// Average time from being placed to being ready over the range, in
+	// seconds
+	// @example 780
+	AvgPrepSeconds float64 `json:"avgPrepSeconds"`
+
+	// Each hour of the range, oldest first
+	Hours []Hour `json:"hours"`
+}
+
+// bucket accumulates the figures of an hour
+type bucket struct {
+	orders int
+	ready  int
+	prep   time.Duration
+}
+
+// pending is an order the kitchen has yet to finish
+type pending struct {
+	placedAt time.Time
+	readyAt  time.Time // As estimated when placed, until marked ready
+}
+
+// snapshot is the figures as of the last refresh, never changed once
+// published
+type snapshot struct {
+	updatedAt  time.Time
+	queueDepth int
+	hours      []Hour // The last MaxHours hours, oldest first
+}
+
+// Projector keeps the figures of one restaurant up to date with its order
+// events. Only Refresh, and Run, change the projection; Summary reads the
+// last published snapshot and can be called from any goroutine. A nil
+// Projector reports no figures.
+type Projector struct {
+	source Source
+	now    func() time.Time
+
+	mu      sync.Mutex // Serializes refreshes
+	seq     uint64     // Last event projected
+	buckets map[time.Time]*bucket
+	pending map[string]pending
+
+	published atomic.Pointer[snapshot]
+}
+
+// New creates a projector of the events of source
+func New(source Source) *Projector {
+	p := &Projector{
+		source:  source,
+		now:     time.Now,
+		buckets: make(map[time.Time]*bucket),
+		pending: make(map[string]pending),
+	}
+	p.published.Store(&snapshot{})
+	return p
+}
+
+// Refresh projects the events logged since the last refresh and publishes
+// the figures as of now
+func (p *Projector) Refresh() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, event := range p.source.EventsAfter(p.seq) {
+		p.apply(event)
+		p.seq = event.Seq
+	}
+
+	// Orders are ready at their estimate unless marked ready before it
+	now := p.now().UTC()
+	for id, order := range p.pending {
+		if !order.readyAt.After(now) {
+			p.finish(id, order.readyAt)
+		}
+	}
+
+	current := now.Truncate(time.Hour)
+	oldest := current.Add(-(MaxHours - 1) * time.Hour)
+	hours := make([]Hour, MaxHours)
+	for i := range hours {
+		start := oldest.Add(time.Duration(i) * time.Hour)
+		hours[i] = Hour{Start: start}
+		if b, ok := p.buckets[start]; ok {
+			hours[i] = b.hour(start)
+		}
+	}
+	for start := range p.buckets {
+		if start.Before(oldest) {
+			delete(p.buckets, start)
+		}
+	}
+	p.published.Store(&snapshot{updatedAt: now, queueDepth: len(p.pending), hours: hours})
+}
+
+// Run refreshes the figures every interval until ctx is done
+func (p *Projector) Run(ctx context.Context, interval time.Duration) {
+	p.Refresh()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.Refresh()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Summary returns the figures of the last hours hours, as of the last
+// refresh. hours is clamped to between 1 and MaxHours.
+func (p *Projector) Summary(hours int) Summary {
+	hours = min(max(hours, 1), MaxHours)
+	if p == nil {
+		return Summary{Hours: []Hour{}}
+	}
+
+	snap := p.published.Load()
+	summary := Summary{UpdatedAt: snap.updatedAt, QueueDepth: snap.queueDepth, Hours: []Hour{}}
+	if len(snap.hours) == 0 {
+		return summary
+	}
+	summary.Hours = slices.Clone(snap.hours[len(snap.hours)-hours:])
+
+	var ready int
+	var prep float64
+	for _, h := range summary.Hours {
+		summary.Orders += h.Orders
+		ready += h.Ready
+		prep += h.AvgPrepSeconds * float64(h.Ready)
+	}
+	if ready > 0 {
+		summary.AvgPrepSeconds = prep / float64(ready)
+	}
+	return summary
+}
+
+// apply projects one event
+func (p *Projector) apply(event orders.Event) {
+	switch event.Type {
+	case orders.EventPlaced:
+		order := event.Order
+		placedAt := order.CreatedAt.UTC()
+		p.bucket(placedAt).orders++
+		// Held orders, and orders of restaurants without a kitchen queue,
+		// are never prepared
+		if order.EstimatedReadyAt != nil {
+			p.pending[order.ID] = pending{placedAt: placedAt, readyAt: order.EstimatedReadyAt.UTC()}
+		}
+	case orders.EventStatusChanged:
+		if event.Change.Status != kitchen.StatusReady {
+			return
+		}
+		if order, ok := p.pending[event.OrderID]; ok && event.Change.At.Before(order.readyAt) {
+			p.finish(event.OrderID, event.Change.At.UTC())
+		}
+	}
+}
+
+// finish records that a pending order was ready at readyAt
+func (p *Projector) finish(orderID string, readyAt time.Time) {
+	order := p.pending[orderID]
+	delete(p.pending, orderID)
+	b := p.bucket(order.placedAt)
+	b.ready++
+	b.prep += readyAt.Sub(order.placedAt)
+}
+
+// bucket returns the figures of the hour containing t
+func (p *Projector) bucket(t time.Time) *bucket {
+	start := t.Truncate(time.Hour)
+	b, ok := p.buckets[start]
+	if !ok {
+		b = &bucket{}
+		p.buckets[start] = b
+	}
+	return b
+}
+
+// hour reports the figures of b as the hour starting at start
+func (b *bucket) hour(start time.Time) Hour {
+	h := Hour{Start: start, Orders: b.orders, Ready: b.ready}
+	if b.ready > 0 {
+		h.AvgPrepSeconds = b.prep.Seconds() / float64(b.ready)
+	}
+	return h
+}
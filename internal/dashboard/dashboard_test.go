@@ -0,0 +1,78 @@
+package dashboard
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/orders"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// placedOrder returns an order placed at placedAt, expected to be ready
+// prep later, or never prepared when prep is zero
+func placedOrder(id string, placedAt time.Time, prep time.Duration) *models.Order {
+	order := &models.Order{ID: id, CreatedAt: placedAt}
+	if prep > 0 {
+		readyAt := placedAt.Add(prep)
+		order.EstimatedReadyAt = &readyAt
+	}
+	return order
+}
+
+func TestProjector(t *testing.T) {
+	store, err := orders.Open("")
+	require.NoError(t, err)
+	now := time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC)
+	p := New(store)
+	p.now = func() time.Time { return now }
+
+	require.NoError(t, store.Place(placedOrder("order-1", now.Add(-90*time.Minute), 20*time.Minute)))
+	require.NoError(t, store.Place(placedOrder("order-2", now.Add(-80*time.Minute), 10*time.Minute)))
+	require.NoError(t, store.Place(placedOrder("order-3", now.Add(-10*time.Minute), 30*time.Minute)))
+	require.NoError(t, store.Place(placedOrder("order-4", now.Add(-5*time.Minute), 30*time.Minute)))
+	require.NoError(t, store.Place(placedOrder("held", now.Add(-5*time.Minute), 0)))
+
+	// Figures only change once refreshed
+	assert.Zero(t, p.Summary(24).Orders)
+	p.Refresh()
+
+	summary := p.Summary(2)
+	assert.Equal(t, now, summary.UpdatedAt)
+	assert.Equal(t, 5, summary.Orders)
+	assert.Equal(t, 2, summary.QueueDepth)
+	assert.Equal(t, float64(15*60), summary.AvgPrepSeconds)
+	require.Len(t, summary.Hours, 2)
+	assert.Equal(t, Hour{Start: now.Add(-90 * time.Minute).Truncate(time.Hour), Orders: 2, Ready: 2, AvgPrepSeconds: 15 * 60}, summary.Hours[0])
+	assert.Equal(t, 3, summary.Hours[1].Orders)
+	assert.Zero(t, summary.Hours[1].Ready)
+
+	// Orders marked ready early finish then; orders marked ready late
+	// finished at their estimate
+	require.NoError(t, store.ChangeStatus("order-3", models.StatusChange{Status: "ready", At: now.Add(-4 * time.Minute), Actor: models.ActorStaff}))
+	now = now.Add(30 * time.Minute)
+	require.NoError(t, store.ChangeStatus("order-4", models.StatusChange{Status: "ready", At: now, Actor: models.ActorStaff}))
+	p.Refresh()
+
+	summary = p.Summary(1)
+	assert.Zero(t, summary.QueueDepth)
+	assert.Equal(t, 0, summary.Orders, "no orders placed in the current hour")
+	summary = p.Summary(2)
+	assert.Equal(t, 2, summary.Hours[0].Ready)
+	assert.Equal(t, float64(18*60), summary.Hours[0].AvgPrepSeconds)
+
+	// Only a week of figures is kept
+	now = now.Add(MaxHours * time.Hour)
+	p.Refresh()
+	summary = p.Summary(MaxHours + 10)
+	assert.Len(t, summary.Hours, MaxHours)
+	assert.Zero(t, summary.Orders)
+}
+
+func TestProjector_Nil(t *testing.T) {
+	var p *Projector
+	summary := p.Summary(24)
+	assert.Zero(t, summary.UpdatedAt)
+	assert.Empty(t, summary.Hours)
+}
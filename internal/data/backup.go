@@ -0,0 +1,308 @@
+package data
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+// BackupVersion is the archive format written by Backup and accepted by Restore
+const BackupVersion = 1
+
+// Names of the entries in a backup archive
+const (
+	manifestEntry = "manifest.json"
+	productsEntry = "products.json"
+	couponsPrefix = "coupons/"
+)
+
+// ErrInvalidBackup is returned when a restore archive is malformed or its
+// data does not load
+var ErrInvalidBackup = errors.New("invalid backup")
+
+// Manifest describes the contents of a backup archive
+type Manifest struct {
+	// Archive format version
+	// @example 1
+	Version int `json:"version"`
+
+	// When the backup was taken
+	// @example 2024-01-01T12:00:00Z
+	CreatedAt time.Time `json:"created_at"`
+
+	// Number of products in the catalog
+	// @example 9
+	Products int `json:"products"`
+
+	// Coupon source files, as found in the coupon directory
+	// @example ["couponbase1.gz","couponbase2.gz","couponbase3.gz"]
+	CouponFiles []string `json:"coupon_files"`
+}
+
+// Backup writes a gzipped tar archive of the current catalog, including
+// products added through the API, in the current products schema version,
+// and the coupon source files. The archive
+// can be restored on another instance with Restore.
+func (s *Store) Backup(w io.Writer) error {
+	// Check if context is cancelled
+	if err := s.ctx.Err(); err != nil {
+		return fmt.Errorf("store is closed: %w", err)
+	}
+
+	products := s.GetAllProducts()
+	sort.Slice(products, func(i, j int) bool { return products[i].ID < products[j].ID })
+	catalog, err := json.MarshalIndent(struct {
+		SchemaVersion int               `json:"schema_version"`
+		Products      []*models.Product `json:"products"`
+	}{ProductsSchemaVersion, products}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode products: %w", err)
+	}
+
+	couponFiles, err := couponSourceFiles(s.config.Files.CouponsDir)
+	if err != nil {
+		return err
+	}
+	manifest, err := json.MarshalIndent(Manifest{
+		Version:     BackupVersion,
+		CreatedAt:   time.Now().UTC(),
+		Products:    len(products),
+		CouponFiles: couponFiles,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	if err := writeEntry(tw, manifestEntry, manifest); err != nil {
+		return err
+	}
+	if err := writeEntry(tw, productsEntry, catalog); err != nil {
+		return err
+	}
+	for _, name := range couponFiles {
+		if err := copyEntry(tw, couponsPrefix+name, filepath.Join(s.config.Files.CouponsDir, name)); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	return nil
+}
+
+// Restore replaces the catalog and coupons with those in a Backup archive.
+// The archive is loaded in full before anything is replaced, so a bad
+// archive leaves the current data untouched. The configured products file
+// and coupon directory are overwritten, so the restored data survives a
+// restart or reload.
+func (s *Store) Restore(r io.Reader) (*Manifest, error) {
+	// Check if context is cancelled
+	if err := s.ctx.Err(); err != nil {
+		return nil, fmt.Errorf("store is closed: %w", err)
+	}
+
+	couponsDir := filepath.Clean(s.config.Files.CouponsDir)
+	staging, err := os.MkdirTemp(filepath.Dir(couponsDir), ".restore-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	productsTmp, err := os.CreateTemp(filepath.Dir(s.config.Files.ProductsFile), ".restore-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging file: %w", err)
+	}
+	productsTmp.Close()
+	defer os.Remove(productsTmp.Name())
+	if err := os.Chmod(productsTmp.Name(), 0644); err != nil {
+		return nil, fmt.Errorf("failed to create staging file: %w", err)
+	}
+
+	stagedCoupons := filepath.Join(staging, "coupons")
+	manifest, err := extractBackup(r, productsTmp.Name(), stagedCoupons)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load everything before touching the live data
+	productStore := NewProductStore()
+	if err := productStore.LoadProducts(productsTmp.Name()); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	// Whatever the integrity policy, an archive is only restored whole
+	couponStore, err := loadCoupons(stagedCoupons, config.CouponIntegrityFail)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Swap the coupon directory, then the products file, undoing the first
+	// step if the second fails
+	previous := filepath.Join(staging, "previous")
+	if err := os.Rename(couponsDir, previous); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to replace coupon directory: %w", err)
+	}
+	if err := os.Rename(stagedCoupons, couponsDir); err != nil {
+		os.Rename(previous, couponsDir)
+		return nil, fmt.Errorf("failed to replace coupon directory: %w", err)
+	}
+	if err := os.Rename(productsTmp.Name(), s.config.Files.ProductsFile); err != nil {
+		os.Rename(couponsDir, stagedCoupons)
+		os.Rename(previous, couponsDir)
+		return nil, fmt.Errorf("failed to replace products file: %w", err)
+	}
+
+	repriced := repricedProducts(s.products, productStore)
+	s.products = productStore
+	s.advance(repriced)
+	s.coupons = couponStore
+	manifest.Products = len(productStore.GetAllProducts())
+	return manifest, nil
+}
+
+// extractBackup unpacks an archive, writing the catalog to productsFile and
+// the coupon files into couponsDir
+func extractBackup(r io.Reader, productsFile, couponsDir string) (*Manifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	defer gz.Close()
+	if err := os.Mkdir(couponsDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+
+	var manifest *Manifest
+	hasProducts := false
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("%w: unexpected entry %s", ErrInvalidBackup, hdr.Name)
+		}
+
+		switch name := hdr.Name; {
+		case name == manifestEntry:
+			manifest = &Manifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, fmt.Errorf("%w: manifest: %v", ErrInvalidBackup, err)
+			}
+			if manifest.Version != BackupVersion {
+				return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidBackup, manifest.Version)
+			}
+		case name == productsEntry:
+			if err := writeFile(productsFile, tr); err != nil {
+				return nil, err
+			}
+			hasProducts = true
+		case strings.HasPrefix(name, couponsPrefix):
+			base := strings.TrimPrefix(name, couponsPrefix)
+			if base == "" || base == "." || base == ".." || strings.ContainsAny(base, `/\`) {
+				return nil, fmt.Errorf("%w: unexpected entry %s", ErrInvalidBackup, name)
+			}
+			if err := writeFile(filepath.Join(couponsDir, base), tr); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("%w: unexpected entry %s", ErrInvalidBackup, name)
+		}
+	}
+
+	if manifest == nil {
+		return nil, fmt.Errorf("%w: missing %s", ErrInvalidBackup, manifestEntry)
+	}
+	if !hasProducts {
+		return nil, fmt.Errorf("%w: missing %s", ErrInvalidBackup, productsEntry)
+	}
+	return manifest, nil
+}
+
+// couponSourceFiles lists the regular files in the coupon directory, which
+// are the files the coupon loader reads
+func couponSourceFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list coupon directory: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// writeEntry adds an in-memory file to a tar archive
+func writeEntry(tw *tar.Writer, name string, content []byte) error {
+	hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), ModTime: time.Now()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// copyEntry adds the file at path to a tar archive
+func copyEntry(tw *tar.Writer, name, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	hdr := &tar.Header{Name: name, Mode: 0644, Size: info.Size(), ModTime: info.ModTime()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := io.Copy(tw, file); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// writeFile writes the contents of r to a new file at path
+func writeFile(path string, r io.Reader) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package data
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tarGz builds a backup-style archive from name/content pairs
+func tarGz(t *testing.T, entries ...string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for i := 0; i < len(entries); i += 2 {
+		require.NoError(t, writeEntry(tw, entries[i], []byte(entries[i+1])))
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return &buf
+}
+
+func TestStore_BackupRestore(t *testing.T) {
+	ctx := context.Background()
+
+	source := testutil.SetupTestData(t)
+	defer source.Cleanup()
+	sourceStore, err := NewIsolatedStore(ctx, source.Config)
+	require.NoError(t, err)
+	defer sourceStore.Close()
+
+	// Products added through the API are part of the backup
+	require.NoError(t, sourceStore.AddProduct(testutil.GetTestProduct()))
+
+	var archive bytes.Buffer
+	require.NoError(t, sourceStore.Backup(&archive))
+
+	// Restore onto an instance with different coupons
+	target := testutil.SetupTestData(t)
+	defer target.Cleanup()
+	for _, name := range []string{"coupons1.txt", "coupons2.txt", "coupons3.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(target.CouponsDir, name), []byte("OTHERCODE\n"), 0644))
+	}
+	targetStore, err := NewIsolatedStore(ctx, target.Config)
+	require.NoError(t, err)
+	defer targetStore.Close()
+	require.False(t, targetStore.ValidateCoupon(testutil.ValidCoupon))
+
+	manifest, err := targetStore.Restore(bytes.NewReader(archive.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, BackupVersion, manifest.Version)
+	assert.Equal(t, 3, manifest.Products)
+	assert.Equal(t, []string{"coupons1.txt", "coupons2.txt", "coupons3.txt"}, manifest.CouponFiles)
+
+	// The restored data is served immediately...
+	assert.Len(t, targetStore.GetAllProducts(), 3)
+	_, err = targetStore.GetProduct("test-prod-1")
+	assert.NoError(t, err)
+	assert.True(t, targetStore.ValidateCoupon(testutil.ValidCoupon))
+	assert.False(t, targetStore.ValidateCoupon("OTHERCODE"))
+
+	// ...and written to the configured files, so it survives a reload
+	require.NoError(t, targetStore.Reload())
+	assert.Len(t, targetStore.GetAllProducts(), 3)
+	assert.True(t, targetStore.ValidateCoupon(testutil.ValidCoupon))
+	info, err := os.Stat(target.ProductsFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	// No staging files are left behind
+	leftovers, err := filepath.Glob(filepath.Join(target.TempDir, ".restore-*"))
+	require.NoError(t, err)
+	assert.Empty(t, leftovers)
+}
+
+func TestStore_Restore_Invalid(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+	store, err := NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+	defer store.Close()
+
+	manifest := `{"version": 1}`
+	products := `[]`
+	tests := []struct {
+		name    string
+		archive *bytes.Buffer
+	}{
+		{name: "not gzip", archive: bytes.NewBufferString("plain text")},
+		{name: "missing manifest", archive: tarGz(t, productsEntry, products)},
+		{name: "missing products", archive: tarGz(t, manifestEntry, manifest)},
+		{name: "unsupported version", archive: tarGz(t, manifestEntry, `{"version": 99}`, productsEntry, products)},
+		{name: "unexpected entry", archive: tarGz(t, manifestEntry, manifest, productsEntry, products, "../products.json", products)},
+		{name: "nested coupon file", archive: tarGz(t, manifestEntry, manifest, productsEntry, products, "coupons/../x", "A")},
+		{name: "invalid products", archive: tarGz(t, manifestEntry, manifest, productsEntry, `[{"id": "x"}]`)},
+		{name: "wrong number of coupon files", archive: tarGz(t, manifestEntry, manifest, productsEntry, products, "coupons/a.txt", "HAPPYHRS\n")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := store.Restore(tt.archive)
+			assert.ErrorIs(t, err, ErrInvalidBackup)
+
+			// The current data is kept
+			assert.Len(t, store.GetAllProducts(), 2)
+			assert.True(t, store.ValidateCoupon(testutil.ValidCoupon))
+			files, err := couponSourceFiles(testData.CouponsDir)
+			require.NoError(t, err)
+			assert.Len(t, files, 3)
+		})
+	}
+}
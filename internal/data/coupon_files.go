@@ -0,0 +1,167 @@
+package data
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// CouponManifestFile is the optional file in a coupon directory that lists
+// what each coupon file should hold. It is not read as a coupon file.
+const CouponManifestFile = "manifest.json"
+
+// CouponFile accounts for what was read from one coupon file
+type CouponFile struct {
+	// @example couponbase1.gz
+	Name string `json:"name"`
+
+	// Lines read from the file
+	// @example 1000000
+	Lines int `json:"lines"`
+
+	// Lines the manifest lists for the file; zero when it lists none
+	// @example 1000000
+	ExpectedLines int `json:"expectedLines,omitempty"`
+
+	// SHA-256 of the file as stored, in hex
+	// @example 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+	SHA256 string `json:"sha256"`
+
+	// Whether the file matched the checksum the manifest lists
+	Verified bool `json:"verified"`
+
+	// Why the file was not read in full or does not match the manifest
+	// @example read 999000 lines, manifest lists 1000000
+	Error string `json:"error,omitempty"`
+
+	// Whether the file's codes were left out of the coupon set
+	Quarantined bool `json:"quarantined,omitempty"`
+}
+
+// couponManifestEntry is what the manifest expects of a coupon file. Either
+// field may be left out.
+type couponManifestEntry struct {
+	Lines  int    `json:"lines"`
+	SHA256 string `json:"sha256"`
+}
+
+// readCouponManifest reads the manifest in dir, by coupon file name. A
+// directory without a manifest expects nothing of its files.
+func readCouponManifest(dir string) (map[string]couponManifestEntry, error) {
+	raw, err := os.ReadFile(filepath.Join(dir, CouponManifestFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read coupon manifest: %w", err)
+	}
+	var manifest map[string]couponManifestEntry
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid coupon manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// scanCouponFile passes every line of the coupon file at path to send,
+// ungzipping files named .gz, and accounts for what was read against what
+// the manifest expects. Only a file that cannot be opened is an error; a
+// file that is corrupt, truncated or does not match is reported in the
+// returned CouponFile's Error.
+func scanCouponFile(path string, expected couponManifestEntry, send func(line string)) (CouponFile, error) {
+	file := CouponFile{Name: filepath.Base(path), ExpectedLines: expected.Lines}
+	f, err := os.Open(path)
+	if err != nil {
+		return file, err
+	}
+	defer f.Close()
+
+	// The checksum covers the file as stored, compressed or not, so it can
+	// be checked against the output of sha256sum
+	hash := sha256.New()
+	raw := io.TeeReader(f, hash)
+	file.Error = scanLines(raw, strings.HasSuffix(strings.ToLower(path), ".gz"), func(line string) {
+		file.Lines++
+		send(line)
+	})
+	if file.Error == "" {
+		// Hash whatever follows the last line, such as gzip trailers
+		if _, err := io.Copy(io.Discard, raw); err != nil {
+			file.Error = err.Error()
+		}
+	}
+	file.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	switch {
+	case file.Error != "":
+		file.Error = fmt.Sprintf("read stopped after %d lines: %s", file.Lines, file.Error)
+	case expected.Lines > 0 && file.Lines != expected.Lines:
+		file.Error = fmt.Sprintf("read %d lines, manifest lists %d", file.Lines, expected.Lines)
+	case expected.SHA256 != "" && !strings.EqualFold(expected.SHA256, file.SHA256):
+		file.Error = fmt.Sprintf("checksum %s does not match manifest", file.SHA256)
+	default:
+		file.Verified = expected.SHA256 != ""
+	}
+	return file, nil
+}
+
+// scanLines passes every line of r to send, returning why reading stopped
+// early, if it did
+func scanLines(r io.Reader, gzipped bool, send func(line string)) string {
+	if gzipped {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return err.Error()
+		}
+		defer gz.Close()
+		r = gz
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		send(scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// CheckCoupons reads the files of a coupon directory as the loader does,
+// accounting for each against the directory's manifest, without building
+// the coupon set. A directory the loader would refuse, such as one without
+// exactly three coupon files or whose manifest lists a file it does not
+// hold, is an error.
+func CheckCoupons(dir string) ([]CouponFile, error) {
+	names, err := couponSourceFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	names = slices.DeleteFunc(names, func(name string) bool { return name == CouponManifestFile })
+	if len(names) != 3 {
+		return nil, fmt.Errorf("expected 3 coupon files in directory '%s', found %d: %v", dir, len(names), names)
+	}
+	manifest, err := readCouponManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	for name := range manifest {
+		if !slices.Contains(names, name) {
+			return nil, fmt.Errorf("coupon manifest lists %s, which is not in the directory", name)
+		}
+	}
+
+	files := make([]CouponFile, len(names))
+	for i, name := range names {
+		if files[i], err = scanCouponFile(filepath.Join(dir, name), manifest[name], func(string) {}); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,122 @@
+package data
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCouponDir writes three coupon files sharing COMMON01, and a
+// manifest listing manifest
+func writeCouponDir(t *testing.T, manifest string) string {
+	dir := t.TempDir()
+	createGzipFile(t, filepath.Join(dir, "coupons1.gz"), "COMMON01\nFIRST001\n")
+	createGzipFile(t, filepath.Join(dir, "coupons2.gz"), "COMMON01\nSHARED01\n")
+	createGzipFile(t, filepath.Join(dir, "coupons3.gz"), "SHARED01\nTHIRD001\n")
+	if manifest != "" {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, CouponManifestFile), []byte(manifest), 0644))
+	}
+	return dir
+}
+
+func TestScanCouponFile(t *testing.T) {
+	dir := writeCouponDir(t, "")
+	path := filepath.Join(dir, "coupons1.gz")
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	sum := sha256.Sum256(raw)
+	checksum := hex.EncodeToString(sum[:])
+
+	var lines []string
+	file, err := scanCouponFile(path, couponManifestEntry{Lines: 2, SHA256: checksum}, func(line string) { lines = append(lines, line) })
+	require.NoError(t, err)
+	assert.Equal(t, []string{"COMMON01", "FIRST001"}, lines)
+	assert.Equal(t, CouponFile{Name: "coupons1.gz", Lines: 2, ExpectedLines: 2, SHA256: checksum, Verified: true}, file)
+
+	file, err = scanCouponFile(path, couponManifestEntry{Lines: 3}, func(string) {})
+	require.NoError(t, err)
+	assert.Equal(t, "read 2 lines, manifest lists 3", file.Error)
+
+	file, err = scanCouponFile(path, couponManifestEntry{SHA256: "00"}, func(string) {})
+	require.NoError(t, err)
+	assert.False(t, file.Verified)
+	assert.Contains(t, file.Error, "does not match manifest")
+
+	// A truncated gzip file is read as far as it goes
+	require.NoError(t, os.WriteFile(path, raw[:len(raw)-4], 0644))
+	file, err = scanCouponFile(path, couponManifestEntry{}, func(string) {})
+	require.NoError(t, err)
+	assert.Contains(t, file.Error, "read stopped after")
+
+	_, err = scanCouponFile(filepath.Join(dir, "missing.gz"), couponManifestEntry{}, func(string) {})
+	assert.Error(t, err)
+}
+
+func TestLoadCoupons_Integrity(t *testing.T) {
+	// coupons2.gz holds fewer lines than the manifest lists
+	manifest := `{"coupons1.gz": {"lines": 2}, "coupons2.gz": {"lines": 5}}`
+
+	t.Run("complete files are accounted for", func(t *testing.T) {
+		store, err := loadCoupons(writeCouponDir(t, `{"coupons1.gz": {"lines": 2}}`), config.CouponIntegrityFail)
+		require.NoError(t, err)
+		files := store.Files()
+		require.Len(t, files, 3)
+		for i, file := range files {
+			assert.Equal(t, fmt.Sprintf("coupons%d.gz", i+1), file.Name)
+			assert.Equal(t, 2, file.Lines)
+			assert.Empty(t, file.Error)
+		}
+		assert.True(t, store.GetCoupon("COMMON01"))
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		_, err := loadCoupons(writeCouponDir(t, manifest), config.CouponIntegrityFail)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "coupons2.gz")
+	})
+
+	t.Run("warn uses what was read", func(t *testing.T) {
+		store, err := loadCoupons(writeCouponDir(t, manifest), config.CouponIntegrityWarn)
+		require.NoError(t, err)
+		assert.True(t, store.GetCoupon("COMMON01"))
+		assert.True(t, store.GetCoupon("SHARED01"))
+		assert.Equal(t, "read 2 lines, manifest lists 5", store.Files()[1].Error)
+		assert.False(t, store.Files()[1].Quarantined)
+	})
+
+	t.Run("quarantine leaves the file out", func(t *testing.T) {
+		store, err := loadCoupons(writeCouponDir(t, manifest), config.CouponIntegrityQuarantine)
+		require.NoError(t, err)
+		assert.False(t, store.GetCoupon("COMMON01"))
+		assert.False(t, store.GetCoupon("SHARED01"))
+		assert.True(t, store.Files()[1].Quarantined)
+	})
+
+	t.Run("invalid manifest", func(t *testing.T) {
+		_, err := loadCoupons(writeCouponDir(t, "{"), config.CouponIntegrityWarn)
+		assert.ErrorContains(t, err, "invalid coupon manifest")
+	})
+}
+
+func TestCheckCoupons(t *testing.T) {
+	files, err := CheckCoupons(writeCouponDir(t, `{"coupons2.gz": {"lines": 5}}`))
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+	assert.Empty(t, files[0].Error)
+	assert.Equal(t, "read 2 lines, manifest lists 5", files[1].Error)
+
+	_, err = CheckCoupons(writeCouponDir(t, `{"coupons4.gz": {"lines": 5}}`))
+	assert.ErrorContains(t, err, "coupons4.gz")
+
+	dir := writeCouponDir(t, "")
+	require.NoError(t, os.Remove(filepath.Join(dir, "coupons3.gz")))
+	_, err = CheckCoupons(dir)
+	assert.ErrorContains(t, err, "expected 3 coupon files")
+}
@@ -1,11 +1,8 @@
 package data
 
 import (
-	"bufio"
-	"compress/gzip"
 	"fmt"
 	"hash/fnv" // For a simple string hashing for sharding
-	"io"
 	"math/bits" // For bits.OnesCount32
 	"os"
 	"path/filepath"
@@ -14,12 +11,22 @@ import (
 	"sync"
 	// "sync/atomic" // No longer needed for sharedBitmaskMap values
 	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
 )
 
 // CouponStoreConcurrent struct remains the same
 type CouponStoreConcurrent struct {
-	coupons map[string]struct{}
-	mu      sync.RWMutex
+	coupons   map[string]struct{}
+	files     []CouponFile // What was read from each file, see Files
+	integrity string       // config.CouponIntegrity* policy for incomplete files; empty warns
+	mu        sync.RWMutex
+
+	// Accounting of the last load, see CouponStats
+	loadedAt     time.Time
+	loadDuration time.Duration
+	codeBytes    int64          // Memory the valid codes' text takes
+	shardSizes   [numShards]int // Codes read into each shard
 }
 
 // Singleton variables remain the same
@@ -38,9 +45,10 @@ func NewCouponStoreConcurrent() *CouponStoreConcurrent {
 	}
 }
 
-func CouponStoreConcurrentInstance(dir string) (*CouponStoreConcurrent, error) {
+func CouponStoreConcurrentInstance(dir, integrity string) (*CouponStoreConcurrent, error) {
 	once.Do(func() {
 		instance = NewCouponStoreConcurrent()
+		instance.integrity = integrity
 		loadDir = dir
 		loadErr = instance.LoadAndFindValidCoupons(dir)
 		if loadErr == nil {
@@ -182,6 +190,9 @@ func (s *CouponStoreConcurrent) LoadAndFindValidCoupons(dir string) (errFinal er
 	if globErr != nil {return fmt.Errorf("error listing files in directory '%s': %w", dir, globErr)}
 	var filePaths []string
 	for _, fp := range globPaths {
+		if filepath.Base(fp) == CouponManifestFile {
+			continue
+		}
 		info, statErr := os.Stat(fp)
 		if statErr != nil {
 			fmt.Fprintf(os.Stderr, "[%s] Warning: Could not stat path '%s', skipping: %v\n", time.Now().Format(time.RFC3339Nano), fp, statErr)
@@ -193,6 +204,11 @@ func (s *CouponStoreConcurrent) LoadAndFindValidCoupons(dir string) (errFinal er
 		return fmt.Errorf("expected 3 coupon files in directory '%s', found %d regular files: %v", dir, len(filePaths), filePaths)
 	}
 	fmt.Printf("[%s] LoadAndFindValidCoupons: Found %d files to process: %v\n", time.Now().Format(time.RFC3339Nano), len(filePaths), filePaths)
+	manifest, manifestErr := readCouponManifest(dir)
+	if manifestErr != nil {
+		return manifestErr
+	}
+	files := make([]CouponFile, len(filePaths)) // Each reader fills in its own file
 
 
 	dataChan := make(chan couponData, 2048*len(filePaths)) // Increased buffer slightly
@@ -207,36 +223,17 @@ func (s *CouponStoreConcurrent) LoadAndFindValidCoupons(dir string) (errFinal er
 			defer readerWg.Done()
 			readerStartTime := time.Now()
 			fileBitmask := uint32(1 << fileIndex)
-			inFile, fileOpenErr := os.Open(fp)
-			if fileOpenErr != nil {
-				errMsg := fmt.Errorf("reader %d failed to open file '%s': %w", readerLogIndex, fp, fileOpenErr)
+			file, openErr := scanCouponFile(fp, manifest[filepath.Base(fp)], func(line string) {
+				dataChan <- couponData{couponString: line, fileBitmask: fileBitmask}
+			})
+			if openErr != nil {
+				errMsg := fmt.Errorf("reader %d failed to open file '%s': %w", readerLogIndex, fp, openErr)
 				fmt.Fprintln(os.Stderr, "["+time.Now().Format(time.RFC3339Nano)+"] "+errMsg.Error())
 				readerErrChan <- errMsg
 				return
 			}
-			defer inFile.Close()
-			var currentReader io.Reader = inFile
-			if strings.HasSuffix(strings.ToLower(fp), ".gz") {
-				gzReader, gzErr := gzip.NewReader(inFile)
-				if gzErr != nil {
-					errMsg := fmt.Errorf("reader %d failed to create gzip reader for '%s': %w", readerLogIndex, fp, gzErr)
-					fmt.Fprintln(os.Stderr, "["+time.Now().Format(time.RFC3339Nano)+"] "+errMsg.Error())
-					readerErrChan <- errMsg
-					return
-				}
-				defer gzReader.Close()
-				currentReader = gzReader
-			}
-			scanner := bufio.NewScanner(currentReader)
-			lineNum := 0
-			for scanner.Scan() {
-				lineNum++
-				dataChan <- couponData{couponString: scanner.Text(), fileBitmask: fileBitmask}
-			}
-			if scanErr := scanner.Err(); scanErr != nil {
-				fmt.Fprintf(os.Stderr, "[%s] Reader %d (%s): Error during scan (at line ~%d): %v\n", time.Now().Format(time.RFC3339Nano), readerLogIndex, filepath.Base(fp), lineNum, scanErr)
-			}
-			fmt.Printf("[%s] Reader %d (%s): Finished. Processed %d lines in %s.\n", time.Now().Format(time.RFC3339Nano), readerLogIndex, filepath.Base(fp), lineNum, time.Since(readerStartTime))
+			files[fileIndex] = file
+			fmt.Printf("[%s] Reader %d (%s): Finished. Processed %d lines in %s.\n", time.Now().Format(time.RFC3339Nano), readerLogIndex, file.Name, file.Lines, time.Since(readerStartTime))
 		}(filePath, i, i+1)
 	}
 
@@ -269,8 +266,30 @@ func (s *CouponStoreConcurrent) LoadAndFindValidCoupons(dir string) (errFinal er
 	}
 	fmt.Printf("[%s] LoadAndFindValidCoupons: No critical reader errors found.\n", time.Now().Format(time.RFC3339Nano))
 
+	// A file that was not read in full, or does not match the manifest,
+	// fails the load, is used as far as it was read, or is left out
+	// altogether, as the integrity policy says
+	var quarantined uint32
+	for i := range files {
+		if files[i].Error == "" {
+			continue
+		}
+		switch s.integrity {
+		case config.CouponIntegrityFail:
+			return fmt.Errorf("coupon file '%s' is incomplete: %s", files[i].Name, files[i].Error)
+		case config.CouponIntegrityQuarantine:
+			files[i].Quarantined = true
+			quarantined |= 1 << i
+			fmt.Fprintf(os.Stderr, "[%s] Warning: Coupon file '%s' is incomplete, leaving its codes out: %s\n", time.Now().Format(time.RFC3339Nano), files[i].Name, files[i].Error)
+		default:
+			fmt.Fprintf(os.Stderr, "[%s] Warning: Coupon file '%s' is incomplete, using the %d lines read: %s\n", time.Now().Format(time.RFC3339Nano), files[i].Name, files[i].Lines, files[i].Error)
+		}
+	}
+
 	s.mu.Lock() // Lock for final write to s.coupons
 	defer s.mu.Unlock()
+	s.files = files
+	s.codeBytes = 0
 	finalCouponCount := 0
 	globallyUniqueCouponCount := 0
 	fmt.Printf("[%s] LoadAndFindValidCoupons: Populating final coupon store from sharded map (%d shards)...\n", time.Now().Format(time.RFC3339Nano), numShards)
@@ -282,8 +301,9 @@ func (s *CouponStoreConcurrent) LoadAndFindValidCoupons(dir string) (errFinal er
 			globallyUniqueCouponCount++ // This will count some coupons multiple times if not careful;
 			                            // better to count unique keys only once globally.
 			                            // For now, this counts total entries across all shard maps.
-			if bits.OnesCount32(mask) >= 2 {
+			if bits.OnesCount32(mask&^quarantined) >= 2 {
 				s.coupons[coupon] = struct{}{}
+				s.codeBytes += stringAllocBytes(coupon)
 				// finalCouponCount++ // This is correctly incremented below from len(s.coupons)
 			}
 		}
@@ -296,15 +316,47 @@ func (s *CouponStoreConcurrent) LoadAndFindValidCoupons(dir string) (errFinal er
 	var totalItemsInShards int
 	for i := 0; i < numShards; i++ {
 		couponShards[i].mu.Lock()
+		s.shardSizes[i] = len(couponShards[i].m)
 		totalItemsInShards += len(couponShards[i].m)
 		couponShards[i].mu.Unlock()
 	}
+	s.loadedAt = startTime
+	s.loadDuration = time.Since(startTime)
 
 	fmt.Printf("[%s] LoadAndFindValidCoupons: Iterated sharded map (approx. %d total items) in %s.\n", time.Now().Format(time.RFC3339Nano), totalItemsInShards, time.Since(iterationStartTime))
 	fmt.Printf("[%s] LoadAndFindValidCoupons: Stored %d valid coupons.\n", time.Now().Format(time.RFC3339Nano), finalCouponCount)
 	return nil
 }
 
+// Count returns how many valid coupon codes were loaded
+func (s *CouponStoreConcurrent) Count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.coupons)
+}
+
+// CouponStats accounts for the coupon set as last loaded. Hits and misses
+// are counted by the Store looking codes up.
+func (s *CouponStoreConcurrent) CouponStats() CouponStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return CouponStats{
+		Codes:          len(s.coupons),
+		MemoryBytes:    int64(len(s.coupons))*couponSlotBytes + s.codeBytes,
+		Shards:         newShardStats(s.shardSizes[:]),
+		LoadedAt:       s.loadedAt,
+		LoadDurationMs: float64(s.loadDuration) / float64(time.Millisecond),
+	}
+}
+
+// Files accounts for what was read from each coupon file, in the order the
+// files are named
+func (s *CouponStoreConcurrent) Files() []CouponFile {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]CouponFile(nil), s.files...)
+}
+
 // GetCoupon method remains the same
 func (s *CouponStoreConcurrent) GetCoupon(code string) bool {
 	codeLen := len(code)
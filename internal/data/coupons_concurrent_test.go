@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
 )
 
 // Helper function to create three gzipped coupon files with different coupon sets in a temporary directory.
@@ -57,7 +59,7 @@ func TestCouponStore_LoadAndFindValidCoupons(t *testing.T) {
 	defer cleanup() // Clean up the files and directory when the test finishes
 
 	// Initialize CouponStore using the Instance method (Singleton)
-	store, err := CouponStoreConcurrentInstance(testDir)
+	store, err := CouponStoreConcurrentInstance(testDir, config.CouponIntegrityWarn)
 	if err != nil {
 		t.Fatalf("Failed to get CouponStoreConcurrent instance: %v", err)
 	}
@@ -131,7 +133,7 @@ func TestCouponStore_LoadAndFindValidCoupons(t *testing.T) {
 	}
 	defer os.RemoveAll(emptyDir)
 
-	store, err = CouponStoreConcurrentInstance(emptyDir) // re-use the instance, singleton
+	store, err = CouponStoreConcurrentInstance(emptyDir, config.CouponIntegrityWarn) // re-use the instance, singleton
 	if err != nil {
 		t.Fatalf("Failed to get CouponStoreConcurrent instance for empty dir: %v", err)
 	}
@@ -0,0 +1,64 @@
+package data
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+// maxCachedLookups bounds how many product lookups are remembered. Once
+// full, expired lookups are dropped, or every lookup when none has expired.
+const maxCachedLookups = 10000
+
+// lookup is the remembered result of looking a product up
+type lookup struct {
+	product  *models.Product // Nil when the product was not found
+	err      error
+	revision uint64 // Catalog revision the lookup was made in
+	expires  time.Time
+}
+
+// lookupCache remembers recent product lookups, including those of IDs
+// that do not exist, so clients asking for the same IDs over and over, such
+// as scrapers or clients stuck retrying, are answered without going to the
+// catalog. Lookups are only served in the catalog revision they were made
+// in, so changes to the catalog show at once. The zero lookupCache is empty
+// and ready to use.
+type lookupCache struct {
+	mu      sync.Mutex
+	entries map[string]lookup
+}
+
+// get returns the lookup of id made in revision, unless it expired by now
+func (lc *lookupCache) get(id string, revision uint64, now time.Time) (lookup, bool) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	hit, ok := lc.entries[id]
+	if !ok || hit.revision != revision || !now.Before(hit.expires) {
+		return lookup{}, false
+	}
+	return hit, true
+}
+
+// put remembers the lookup of id, making room when the cache is full
+func (lc *lookupCache) put(id string, l lookup, now time.Time) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	if lc.entries == nil {
+		lc.entries = make(map[string]lookup)
+	}
+	if _, ok := lc.entries[id]; !ok && len(lc.entries) >= maxCachedLookups {
+		for key, entry := range lc.entries {
+			if !now.Before(entry.expires) {
+				delete(lc.entries, key)
+			}
+		}
+		if len(lc.entries) >= maxCachedLookups {
+			clear(lc.entries)
+		}
+	}
+	lc.entries[id] = l
+}
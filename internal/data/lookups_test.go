@@ -0,0 +1,66 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupCache(t *testing.T) {
+	var cache lookupCache
+	now := time.Now()
+	cache.put("missing", lookup{err: ErrProductNotFound, revision: 1, expires: now.Add(time.Second)}, now)
+
+	hit, ok := cache.get("missing", 1, now)
+	require.True(t, ok)
+	assert.ErrorIs(t, hit.err, ErrProductNotFound)
+
+	// Lookups are not served past their TTL or in later revisions
+	_, ok = cache.get("missing", 1, now.Add(time.Second))
+	assert.False(t, ok)
+	_, ok = cache.get("missing", 2, now)
+	assert.False(t, ok)
+
+	// A full cache drops expired lookups first
+	for i := range maxCachedLookups - 1 {
+		cache.put(fmt.Sprint(i), lookup{revision: 1, expires: now.Add(time.Minute)}, now)
+	}
+	later := now.Add(2 * time.Second)
+	cache.put("new", lookup{revision: 1, expires: later.Add(time.Minute)}, later)
+	assert.Len(t, cache.entries, maxCachedLookups)
+	_, ok = cache.get("0", 1, later)
+	assert.True(t, ok)
+}
+
+func TestStore_GetProduct_Cached(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+	testData.Config.Cache.ProductTTL = time.Minute
+	store, err := NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+	defer store.Close()
+
+	// IDs that do not exist are remembered too
+	_, err = store.GetProduct("test-prod-1")
+	require.ErrorIs(t, err, ErrProductNotFound)
+	_, ok := store.lookups.get("test-prod-1", store.Revision(), time.Now())
+	assert.True(t, ok)
+
+	// Changes to the catalog show at once
+	require.NoError(t, store.AddProduct(testutil.GetTestProduct()))
+	product, err := store.GetProduct("test-prod-1")
+	require.NoError(t, err)
+	assert.Equal(t, "test-prod-1", product.ID)
+
+	renamed := *product
+	renamed.Name = "Renamed"
+	require.NoError(t, store.UpdateProduct(&renamed))
+	product, err = store.GetProduct("test-prod-1")
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", product.Name)
+}
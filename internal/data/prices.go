@@ -0,0 +1,92 @@
+package data
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+// VariantPrice is the price of a product variant
+type VariantPrice struct {
+	// @example large
+	ID string `json:"id"`
+
+	// @example 8.5
+	Price float64 `json:"price"`
+}
+
+// PricePoint is the prices a product was sold at from a point in time on
+type PricePoint struct {
+	// When the prices took effect
+	// @example 2024-05-01T09:30:00Z
+	At time.Time `json:"at"`
+
+	// Catalog revision the prices took effect in
+	// @example 12
+	Revision uint64 `json:"revision"`
+
+	// Price of the product
+	// @example 7.5
+	Price float64 `json:"price"`
+
+	// Prices of the product's variants, when it has any
+	Variants []VariantPrice `json:"variants,omitempty"`
+}
+
+// PriceHistory is every price a product was sold at since the server started
+type PriceHistory struct {
+	// @example 10
+	ProductID string `json:"productId"`
+
+	// Prices in the order they took effect, starting with those loaded
+	// from the products file
+	Prices []PricePoint `json:"prices"`
+}
+
+// PriceHistory returns the prices the product with the given ID was sold at,
+// including after it was deleted
+func (s *Store) PriceHistory(id string) (PriceHistory, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	points, ok := s.history[id]
+	if !ok {
+		return PriceHistory{}, fmt.Errorf("%w: %s", ErrProductNotFound, id)
+	}
+	return PriceHistory{ProductID: id, Prices: append([]PricePoint(nil), points...)}, nil
+}
+
+// recordPrices adds the current prices of the given products to their
+// history as of the current revision. Callers must hold s.mu for writing.
+func (s *Store) recordPrices(ids []string, at time.Time) {
+	if s.history == nil {
+		s.history = make(map[string][]PricePoint)
+	}
+	for _, id := range ids {
+		product, err := s.products.GetProduct(id)
+		if err != nil {
+			continue
+		}
+		s.history[id] = append(s.history[id], pricePoint(product, s.revision, at))
+	}
+}
+
+// pricePoint returns the prices of product as of revision and at
+func pricePoint(product *models.Product, revision uint64, at time.Time) PricePoint {
+	point := PricePoint{At: at, Revision: revision, Price: product.Price}
+	for _, variant := range product.Variants {
+		point.Variants = append(point.Variants, VariantPrice{ID: variant.ID, Price: variant.Price})
+	}
+	return point
+}
+
+// productIDs returns the IDs of every product in products
+func productIDs(products *ProductStore) []string {
+	all := products.GetAllProducts()
+	ids := make([]string, len(all))
+	for i, product := range all {
+		ids[i] = product.ID
+	}
+	return ids
+}
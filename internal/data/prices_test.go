@@ -0,0 +1,95 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_PriceHistory(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+	store, err := NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+	defer store.Close()
+
+	// Products start with the prices they were loaded with
+	history, err := store.PriceHistory("prod-1")
+	require.NoError(t, err)
+	assert.Equal(t, "prod-1", history.ProductID)
+	require.Len(t, history.Prices, 1)
+	assert.Equal(t, 9.99, history.Prices[0].Price)
+	assert.Equal(t, uint64(1), history.Prices[0].Revision)
+	assert.False(t, history.Prices[0].At.IsZero())
+
+	// Changes other than price are not recorded
+	product, err := store.GetProduct("prod-1")
+	require.NoError(t, err)
+	renamed := *product
+	renamed.Name = "Renamed"
+	require.NoError(t, store.UpdateProduct(&renamed))
+
+	reduced := renamed
+	reduced.Price = 7.5
+	require.NoError(t, store.UpdateProduct(&reduced))
+	history, err = store.PriceHistory("prod-1")
+	require.NoError(t, err)
+	require.Len(t, history.Prices, 2)
+	assert.Equal(t, 7.5, history.Prices[1].Price)
+	assert.Equal(t, store.Revision(), history.Prices[1].Revision)
+	assert.False(t, history.Prices[1].At.Before(history.Prices[0].At))
+
+	// Deleted products keep their history
+	require.NoError(t, store.DeleteProduct("prod-1"))
+	history, err = store.PriceHistory("prod-1")
+	require.NoError(t, err)
+	assert.Len(t, history.Prices, 2)
+
+	// Added products start their history when added
+	require.NoError(t, store.AddProduct(testutil.GetTestProduct()))
+	history, err = store.PriceHistory("test-prod-1")
+	require.NoError(t, err)
+	assert.Len(t, history.Prices, 1)
+
+	_, err = store.PriceHistory("missing")
+	assert.True(t, errors.Is(err, ErrProductNotFound))
+}
+
+func TestStore_PriceHistory_Reload(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+	store, err := NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+	defer store.Close()
+
+	var products []map[string]interface{}
+	raw, err := os.ReadFile(testData.ProductsFile)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(raw, &products))
+	for _, product := range products {
+		if product["id"] == "prod-2" {
+			product["price"] = 25
+		}
+	}
+	raw, err = json.Marshal(products)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(testData.ProductsFile, raw, 0644))
+	require.NoError(t, store.Reload())
+
+	// Reloads record the prices that changed on disk, as the reloaded
+	// products see them
+	history, err := store.PriceHistory("prod-2")
+	require.NoError(t, err)
+	require.Len(t, history.Prices, 2)
+	assert.Equal(t, 25.0, history.Prices[1].Price)
+
+	history, err = store.PriceHistory("prod-1")
+	require.NoError(t, err)
+	assert.Len(t, history.Prices, 1)
+}
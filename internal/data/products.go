@@ -1,18 +1,48 @@
 package data
 
 import (
-	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/ravibandhu/oolio-food-ordering/internal/models"
 )
 
+// Errors returned by ProductStore operations
+var (
+	ErrProductNotFound = errors.New("product not found")
+	ErrProductExists   = errors.New("product already exists")
+	ErrProductChanged  = errors.New("product has changed")
+)
+
+// InvalidProduct is a record in the products file that was skipped because
+// it is not a valid product
+type InvalidProduct struct {
+	// Position of the record in the file, counting from 0
+	// @example 3
+	Index int `json:"index"`
+
+	// ID of the product, when the record gives one
+	// @example 10
+	ID string `json:"id,omitempty"`
+
+	// Why the record is invalid
+	// @example price: must be greater than 0
+	Error string `json:"error"`
+
+	// The record as it appears in the file
+	Record json.RawMessage `json:"record" swaggertype:"object"`
+}
+
 // ProductStore represents a file-based store for products
 type ProductStore struct {
 	products map[string]*models.Product
+	invalid  []InvalidProduct // Records skipped by LoadProductsLenient
+	loadedAt time.Time        // When the products file was last loaded
 	mu       sync.RWMutex
 }
 
@@ -23,25 +53,42 @@ func NewProductStore() *ProductStore {
 	}
 }
 
-// LoadProducts reads product data from a JSON file
+// LoadProducts reads product data from a JSON file, failing on the first
+// invalid product
 func (s *ProductStore) LoadProducts(filePath string) error {
+	return s.load(filePath, false)
+}
+
+// LoadProductsLenient reads product data from a JSON file like LoadProducts,
+// but skips invalid products rather than failing, keeping them for
+// InvalidProducts. A file that is not a JSON array still fails the load.
+func (s *ProductStore) LoadProductsLenient(filePath string) error {
+	return s.load(filePath, true)
+}
+
+// load reads product data from a JSON file, skipping invalid products when
+// lenient
+func (s *ProductStore) load(filePath string, lenient bool) error {
 	// Lock for writing
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	// Clear existing products
 	s.products = make(map[string]*models.Product)
+	s.invalid = nil
 
 	// Open and read the file
-	if err := s.loadProductFile(filePath); err != nil {
+	if err := s.loadProductFile(filePath, lenient); err != nil {
 		return fmt.Errorf("error loading file %s: %w", filePath, err)
 	}
+	s.loadedAt = time.Now()
 
 	return nil
 }
 
-// loadProductFile reads and parses a single product file
-func (s *ProductStore) loadProductFile(filename string) error {
+// loadProductFile reads and parses a single product file, converting
+// products of older schema versions as they are read
+func (s *ProductStore) loadProductFile(filename string, lenient bool) error {
 	// Open the file
 	file, err := os.Open(filename)
 	if err != nil {
@@ -49,35 +96,125 @@ func (s *ProductStore) loadProductFile(filename string) error {
 	}
 	defer file.Close()
 
-	// Create a buffered reader
-	reader := bufio.NewReader(file)
-
-	// Create a decoder for JSON
-	decoder := json.NewDecoder(reader)
+	_, err = s.readProducts(file, lenient)
+	return err
+}
 
-	// Read the opening array bracket
-	_, err = decoder.Token()
+// readProducts reads the products in r, returning the schema version they
+// were in
+func (s *ProductStore) readProducts(r io.Reader, lenient bool) (int, error) {
+	// Read the records, in whichever schema version the file is in. A file
+	// that is not valid JSON cannot be read at all, so it fails the load
+	// even when lenient.
+	version, records, err := readProductsFile(r)
 	if err != nil {
-		return fmt.Errorf("error reading opening bracket: %w", err)
+		return 0, err
 	}
 
 	// Read products
-	for decoder.More() {
+	for index, record := range records {
+		migrated, err := migrateProduct(record, version)
+		if err != nil {
+			if !lenient {
+				return 0, err
+			}
+			s.invalid = append(s.invalid, invalidProduct(index, record, err))
+			continue
+		}
+
 		var product models.Product
-		if err := decoder.Decode(&product); err != nil {
-			return fmt.Errorf("error decoding product: %w", err)
+		if err := json.Unmarshal(migrated, &product); err != nil {
+			if !lenient {
+				return 0, fmt.Errorf("error decoding product: %w", err)
+			}
+			s.invalid = append(s.invalid, invalidProduct(index, record, err))
+			continue
 		}
 
 		// Validate the product
 		if err := models.Validate(&product); err != nil {
-			return fmt.Errorf("invalid product data: %w", err)
+			if !lenient {
+				return 0, fmt.Errorf("invalid product data: %w", err)
+			}
+			s.invalid = append(s.invalid, invalidProduct(index, record, err))
+			continue
 		}
 
 		// Store the product
 		s.products[product.ID] = &product
 	}
 
-	return nil
+	return version, nil
+}
+
+// invalidProduct describes the record at index, skipped because of err
+func invalidProduct(index int, record json.RawMessage, err error) InvalidProduct {
+	var id struct {
+		ID any `json:"id"`
+	}
+	json.Unmarshal(record, &id)
+	invalid := InvalidProduct{Index: index, Error: err.Error(), Record: record}
+	if id.ID != nil {
+		invalid.ID = fmt.Sprint(id.ID)
+	}
+	return invalid
+}
+
+// ProductsCheck reports whether a products file would load
+type ProductsCheck struct {
+	// Whether every product in the file is valid
+	// @example false
+	Valid bool `json:"valid"`
+
+	// Schema version the file is in
+	// @example 2
+	SchemaVersion int `json:"schemaVersion"`
+
+	// Number of valid products in the file
+	// @example 9
+	Products int `json:"products"`
+
+	// Invalid products, which fail a strict load and are skipped by a
+	// lenient one, in file order
+	Errors []InvalidProduct `json:"errors"`
+}
+
+// CheckProducts reads a products file from r as the server loads one,
+// reporting every invalid product rather than stopping at the first. Only
+// a file that cannot be read at all, such as one that is not valid JSON or
+// is of an unsupported schema version, is an error.
+func CheckProducts(r io.Reader) (*ProductsCheck, error) {
+	store := NewProductStore()
+	version, err := store.readProducts(r, true)
+	if err != nil {
+		return nil, err
+	}
+	check := &ProductsCheck{
+		Valid:         len(store.invalid) == 0,
+		SchemaVersion: version,
+		Products:      len(store.products),
+		Errors:        store.invalid,
+	}
+	if check.Errors == nil {
+		check.Errors = []InvalidProduct{}
+	}
+	return check, nil
+}
+
+// InvalidProducts returns the records skipped by the last lenient load, in
+// the order they appear in the file
+func (s *ProductStore) InvalidProducts() []InvalidProduct {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]InvalidProduct(nil), s.invalid...)
+}
+
+// LoadedAt returns when the products file was last loaded, or the zero time
+// if it never was
+func (s *ProductStore) LoadedAt() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.loadedAt
 }
 
 // GetProduct retrieves a product by ID
@@ -87,13 +224,39 @@ func (s *ProductStore) GetProduct(id string) (*models.Product, error) {
 
 	product, exists := s.products[id]
 	if !exists {
-		return nil, fmt.Errorf("product not found: %s", id)
+		return nil, fmt.Errorf("%w: %s", ErrProductNotFound, id)
 	}
 
 	return product, nil
 }
 
-// GetAllProducts returns all products
+// GetProducts looks up several products under one lock, returning the
+// products found by ID and the IDs that do not exist, in request order and
+// without duplicates. Deleted products cannot be ordered, so they are
+// reported as missing.
+func (s *ProductStore) GetProducts(ids []string) (map[string]*models.Product, []string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	found := make(map[string]*models.Product, len(ids))
+	var missing []string
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if product, exists := s.products[id]; exists && product.DeletedAt == nil {
+			found[id] = product
+		} else {
+			missing = append(missing, id)
+		}
+	}
+
+	return found, missing
+}
+
+// GetAllProducts returns all products, including deleted ones
 func (s *ProductStore) GetAllProducts() []*models.Product {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -105,3 +268,107 @@ func (s *ProductStore) GetAllProducts() []*models.Product {
 
 	return products
 }
+
+// AddProduct validates and stores a new product
+func (s *ProductStore) AddProduct(product *models.Product) error {
+	if err := models.Validate(product); err != nil {
+		return fmt.Errorf("invalid product data: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.products[product.ID]; exists {
+		return fmt.Errorf("%w: %s", ErrProductExists, product.ID)
+	}
+	s.products[product.ID] = product
+
+	return nil
+}
+
+// UpdateProduct validates and replaces an existing product
+func (s *ProductStore) UpdateProduct(product *models.Product) error {
+	if err := models.Validate(product); err != nil {
+		return fmt.Errorf("invalid product data: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.products[product.ID]; !exists {
+		return fmt.Errorf("%w: %s", ErrProductNotFound, product.ID)
+	}
+	s.products[product.ID] = product
+
+	return nil
+}
+
+// DeleteProduct marks an existing product as deleted at the given time. The
+// product is kept, so it can still be looked up by ID.
+func (s *ProductStore) DeleteProduct(id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	product, exists := s.products[id]
+	if !exists || product.DeletedAt != nil {
+		return fmt.Errorf("%w: %s", ErrProductNotFound, id)
+	}
+	deleted := *product
+	deleted.DeletedAt = &at
+	s.products[id] = &deleted
+
+	return nil
+}
+
+// carryOver keeps the products of previous that s was loaded without: those
+// missing from the file, such as products added through the API, and
+// deletions, so a deleted product the file still lists stays deleted
+func (s *ProductStore) carryOver(previous *ProductStore) {
+	previous.mu.RLock()
+	defer previous.mu.RUnlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, product := range previous.products {
+		loaded, exists := s.products[id]
+		switch {
+		case !exists:
+			s.products[id] = product
+		case product.DeletedAt != nil && loaded.DeletedAt == nil:
+			deleted := *loaded
+			deleted.DeletedAt = product.DeletedAt
+			s.products[id] = &deleted
+		}
+	}
+}
+
+// Apply adds or replaces the upserted products and marks the deleted ones as
+// deleted at the given time, all under one lock. Every product is validated
+// first, so either all changes apply or none do. Upserting a deleted product
+// restores it; deleting a missing or deleted product changes nothing, so
+// applying the same changes twice is harmless.
+func (s *ProductStore) Apply(upserts []*models.Product, deletes []string, at time.Time) error {
+	for _, product := range upserts {
+		if err := models.Validate(product); err != nil {
+			return fmt.Errorf("invalid product data for %s: %w", product.ID, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, product := range upserts {
+		s.products[product.ID] = product
+	}
+	for _, id := range deletes {
+		product, exists := s.products[id]
+		if !exists || product.DeletedAt != nil {
+			continue
+		}
+		deleted := *product
+		deleted.DeletedAt = &at
+		s.products[id] = &deleted
+	}
+
+	return nil
+}
@@ -123,6 +123,116 @@ func TestProductStore(t *testing.T) {
 	})
 }
 
+func TestProductStore_AddProduct(t *testing.T) {
+	store := setupProductStore()
+
+	t.Run("new product", func(t *testing.T) {
+		product := testutil.GetTestProduct()
+		err := store.AddProduct(product)
+		assert.NoError(t, err)
+
+		got, err := store.GetProduct(product.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, product.Name, got.Name)
+	})
+
+	t.Run("duplicate product", func(t *testing.T) {
+		product := testutil.GetTestProduct()
+		product.ID = "prod-1"
+		err := store.AddProduct(product)
+		assert.ErrorIs(t, err, ErrProductExists)
+	})
+
+	t.Run("invalid product", func(t *testing.T) {
+		product := testutil.GetTestProduct()
+		product.ID = "prod-invalid"
+		product.Price = 0
+		err := store.AddProduct(product)
+		assert.Error(t, err)
+
+		_, err = store.GetProduct("prod-invalid")
+		assert.ErrorIs(t, err, ErrProductNotFound)
+	})
+}
+
+func TestProductStore_UpdateProduct(t *testing.T) {
+	store := setupProductStore()
+
+	t.Run("existing product", func(t *testing.T) {
+		product := testutil.GetTestProduct()
+		product.ID = "prod-1"
+		product.Name = "Renamed"
+		assert.NoError(t, store.UpdateProduct(product))
+
+		got, err := store.GetProduct("prod-1")
+		assert.NoError(t, err)
+		assert.Equal(t, "Renamed", got.Name)
+	})
+
+	t.Run("unknown product", func(t *testing.T) {
+		product := testutil.GetTestProduct()
+		product.ID = "prod-missing"
+		assert.ErrorIs(t, store.UpdateProduct(product), ErrProductNotFound)
+
+		_, err := store.GetProduct("prod-missing")
+		assert.ErrorIs(t, err, ErrProductNotFound)
+	})
+
+	t.Run("invalid product", func(t *testing.T) {
+		product := testutil.GetTestProduct()
+		product.ID = "prod-1"
+		product.Price = 0
+		assert.Error(t, store.UpdateProduct(product))
+
+		got, err := store.GetProduct("prod-1")
+		assert.NoError(t, err)
+		assert.Equal(t, "Renamed", got.Name)
+	})
+}
+
+func TestProductStore_Apply(t *testing.T) {
+	store := setupProductStore()
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("invalid product applies nothing", func(t *testing.T) {
+		added := testutil.GetTestProduct()
+		invalid := testutil.GetTestProduct()
+		invalid.ID = "prod-1"
+		invalid.Price = 0
+		assert.Error(t, store.Apply([]*models.Product{added, invalid}, []string{"prod-2"}, at))
+
+		_, err := store.GetProduct(added.ID)
+		assert.ErrorIs(t, err, ErrProductNotFound)
+		got, err := store.GetProduct("prod-2")
+		require.NoError(t, err)
+		assert.Nil(t, got.DeletedAt)
+	})
+
+	t.Run("upserts and deletes", func(t *testing.T) {
+		added := testutil.GetTestProduct()
+		renamed := testutil.GetTestProduct()
+		renamed.ID = "prod-1"
+		renamed.Name = "Renamed"
+		require.NoError(t, store.Apply([]*models.Product{added, renamed}, []string{"prod-2", "prod-missing"}, at))
+
+		got, err := store.GetProduct(added.ID)
+		require.NoError(t, err)
+		assert.Equal(t, added.Name, got.Name)
+		got, err = store.GetProduct("prod-1")
+		require.NoError(t, err)
+		assert.Equal(t, "Renamed", got.Name)
+		got, err = store.GetProduct("prod-2")
+		require.NoError(t, err)
+		assert.Equal(t, &at, got.DeletedAt)
+
+		// Deleting again keeps the original deletion time
+		require.NoError(t, store.Apply(nil, []string{"prod-2"}, at.Add(time.Hour)))
+		got, err = store.GetProduct("prod-2")
+		require.NoError(t, err)
+		assert.Equal(t, &at, got.DeletedAt)
+	})
+}
+
 func TestLoadProducts(t *testing.T) {
 	testData := testutil.SetupTestData(t)
 	defer testData.Cleanup()
@@ -303,3 +413,34 @@ func TestLoadProducts(t *testing.T) {
 		})
 	}
 }
+
+func TestProductStore_LoadProductsLenient(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "products.json")
+	image := `{"thumbnail": "https://example.com/t.jpg", "mobile": "https://example.com/m.jpg", "tablet": "https://example.com/t.jpg", "desktop": "https://example.com/d.jpg"}`
+	require.NoError(t, os.WriteFile(file, []byte(`[
+		{"id": "prod-1", "name": "Waffle", "price": 9.99, "category": "Waffle", "image": `+image+`},
+		{"id": 2, "name": "Typo", "price": "free", "category": "Waffle", "image": `+image+`},
+		{"id": "prod-3", "name": "", "price": 4.5, "category": "Waffle", "image": `+image+`},
+		{"id": "prod-4", "name": "Coffee", "price": 3, "category": "Drinks", "image": `+image+`}
+	]`), 0644))
+
+	// Strict loads fail on the first invalid product
+	store := NewProductStore()
+	require.Error(t, store.LoadProducts(file))
+
+	store = NewProductStore()
+	require.NoError(t, store.LoadProductsLenient(file))
+	assert.Len(t, store.GetAllProducts(), 2)
+	invalid := store.InvalidProducts()
+	require.Len(t, invalid, 2)
+	assert.Equal(t, 1, invalid[0].Index)
+	assert.Equal(t, "2", invalid[0].ID)
+	assert.Equal(t, 2, invalid[1].Index)
+	assert.Equal(t, "prod-3", invalid[1].ID)
+	assert.NotEmpty(t, invalid[1].Error)
+	assert.JSONEq(t, `{"id": "prod-3", "name": "", "price": 4.5, "category": "Waffle", "image": `+image+`}`, string(invalid[1].Record))
+
+	// Records that are not JSON still fail the load
+	require.NoError(t, os.WriteFile(file, []byte(`[{"id": "prod-1"}, {oops]`), 0644))
+	assert.Error(t, store.LoadProductsLenient(file))
+}
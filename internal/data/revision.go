@@ -0,0 +1,88 @@
+package data
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+// ETag returns the entity tag of a stored product, quoted for use in ETag
+// and If-Match headers. It changes whenever any field of the product does.
+func ETag(product *models.Product) string {
+	raw, err := json.Marshal(product)
+	if err != nil {
+		return `""`
+	}
+	sum := sha256.Sum256(raw)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// Revision returns the catalog revision, which increases on every change to
+// the products, including reloads and restores. Clients send back the
+// revision they rendered so the prices they showed can be checked.
+func (s *Store) Revision() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.revision
+}
+
+// RepricedSince returns the IDs whose prices changed after revision. A
+// revision newer than the current one, e.g. from before a restart, cannot be
+// checked, so all IDs are returned.
+func (s *Store) RepricedSince(revision uint64, ids []string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var repriced []string
+	for _, id := range ids {
+		if revision > s.revision || s.priced[id] > revision {
+			repriced = append(repriced, id)
+		}
+	}
+	return repriced
+}
+
+// advance moves to the next revision, recording that the given products
+// were priced in it, and their prices in their history. Callers must hold
+// s.mu for writing and have already changed the products.
+func (s *Store) advance(repriced []string) {
+	s.revision++
+	if s.priced == nil {
+		s.priced = make(map[string]uint64)
+	}
+	for _, id := range repriced {
+		s.priced[id] = s.revision
+	}
+	s.recordPrices(repriced, time.Now())
+}
+
+// repricedProducts returns the IDs of products in next that are new or
+// priced differently from current
+func repricedProducts(current, next *ProductStore) []string {
+	var ids []string
+	for _, product := range next.GetAllProducts() {
+		old, err := current.GetProduct(product.ID)
+		if err != nil || !samePrices(old, product) {
+			ids = append(ids, product.ID)
+		}
+	}
+	return ids
+}
+
+// samePrices reports whether two versions of a product cost the same,
+// including every variant
+func samePrices(a, b *models.Product) bool {
+	if a.Price != b.Price || len(a.Variants) != len(b.Variants) {
+		return false
+	}
+	for _, variant := range a.Variants {
+		other, ok := b.Variant(variant.ID)
+		if !ok || other.Price != variant.Price {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,120 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_Revision(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+	store, err := NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+	defer store.Close()
+
+	ids := []string{"prod-1", "prod-2", "test-prod-1"}
+	loaded := store.Revision()
+	assert.Equal(t, uint64(1), loaded)
+	assert.Empty(t, store.RepricedSince(loaded, ids))
+
+	// Adding a product is a new revision, and the product is new to clients
+	require.NoError(t, store.AddProduct(testutil.GetTestProduct()))
+	added := store.Revision()
+	assert.Greater(t, added, loaded)
+	assert.Equal(t, []string{"test-prod-1"}, store.RepricedSince(loaded, ids))
+	assert.Empty(t, store.RepricedSince(added, ids))
+
+	// Changes other than price are a new revision without repricing
+	product, err := store.GetProduct("prod-1")
+	require.NoError(t, err)
+	renamed := *product
+	renamed.Name = "Renamed"
+	require.NoError(t, store.UpdateProduct(&renamed))
+	assert.Greater(t, store.Revision(), added)
+	assert.Empty(t, store.RepricedSince(added, ids))
+
+	repriced := renamed
+	repriced.Price = 12.5
+	require.NoError(t, store.UpdateProduct(&repriced))
+	assert.Equal(t, []string{"prod-1"}, store.RepricedSince(added, ids))
+
+	// Failed changes keep the revision
+	current := store.Revision()
+	assert.Error(t, store.AddProduct(testutil.GetTestProduct()))
+	assert.Equal(t, current, store.Revision())
+
+	// Revisions from the future cannot be checked
+	assert.Equal(t, ids, store.RepricedSince(current+1, ids))
+}
+
+func TestStore_Revision_Reload(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+	store, err := NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+	defer store.Close()
+
+	// Reloading unchanged data reprices nothing
+	loaded := store.Revision()
+	require.NoError(t, store.Reload())
+	reloaded := store.Revision()
+	assert.Greater(t, reloaded, loaded)
+	assert.Empty(t, store.RepricedSince(loaded, []string{"prod-1", "prod-2"}))
+
+	// Only products whose price changed on disk are repriced
+	var products []map[string]interface{}
+	raw, err := os.ReadFile(testData.ProductsFile)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(raw, &products))
+	for _, product := range products {
+		if product["id"] == "prod-2" {
+			product["price"] = 25
+		}
+	}
+	raw, err = json.Marshal(products)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(testData.ProductsFile, raw, 0644))
+
+	require.NoError(t, store.Reload())
+	assert.Equal(t, []string{"prod-2"}, store.RepricedSince(reloaded, []string{"prod-1", "prod-2"}))
+}
+
+func TestStore_UpdateProductIfMatch(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+	store, err := NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+	defer store.Close()
+
+	product, err := store.GetProduct("prod-1")
+	require.NoError(t, err)
+	etag := ETag(product)
+	assert.Equal(t, etag, ETag(product), "ETags are stable")
+
+	first := *product
+	first.Name = "First"
+	require.NoError(t, store.UpdateProductIfMatch(&first, etag))
+	assert.NotEqual(t, etag, ETag(&first))
+
+	// An edit based on the old product is rejected and changes nothing
+	second := *product
+	second.Name = "Second"
+	err = store.UpdateProductIfMatch(&second, etag)
+	assert.True(t, errors.Is(err, ErrProductChanged))
+	current, err := store.GetProduct("prod-1")
+	require.NoError(t, err)
+	assert.Equal(t, "First", current.Name)
+
+	// Without an ETag the update is unconditional
+	require.NoError(t, store.UpdateProductIfMatch(&second, ""))
+
+	missing := testutil.GetTestProduct()
+	assert.Error(t, store.UpdateProductIfMatch(missing, etag))
+}
@@ -0,0 +1,103 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ProductsSchemaVersion is the version of the products file format this
+// server writes. Files of older versions are converted as they are read,
+// so a catalog does not have to be rewritten when the format changes.
+//
+// Version 1 is the format of files without a schema_version: a bare array
+// of products, whose image may be a single URL. Version 2 files are an
+// object holding the schema_version and the products, whose image is the
+// URL of each rendition.
+const ProductsSchemaVersion = 2
+
+// productsDocument is a products file of version 2 or later
+type productsDocument struct {
+	SchemaVersion int               `json:"schema_version"`
+	Products      []json.RawMessage `json:"products"`
+}
+
+// productMigrations convert a product record from the schema version they
+// are indexed by to the next one
+var productMigrations = map[int]func(record map[string]any) error{
+	1: migrateFlatImage,
+}
+
+// readProductsFile reads the product records of a products file and the
+// schema version they are in. Newer versions than ProductsSchemaVersion are
+// rejected, as they may hold fields this server would drop.
+func readProductsFile(r io.Reader) (int, []json.RawMessage, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	raw = bytes.TrimSpace(raw)
+	if len(raw) > 0 && raw[0] == '[' {
+		var records []json.RawMessage
+		if err := json.Unmarshal(raw, &records); err != nil {
+			return 0, nil, fmt.Errorf("error decoding products: %w", err)
+		}
+		return 1, records, nil
+	}
+
+	var doc productsDocument
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return 0, nil, fmt.Errorf("error decoding products: %w", err)
+	}
+	if doc.SchemaVersion == 0 {
+		doc.SchemaVersion = 1
+	}
+	if doc.SchemaVersion < 1 || doc.SchemaVersion > ProductsSchemaVersion {
+		return 0, nil, fmt.Errorf("unsupported schema_version %d: this server reads versions 1 to %d", doc.SchemaVersion, ProductsSchemaVersion)
+	}
+	return doc.SchemaVersion, doc.Products, nil
+}
+
+// migrateProduct converts a product record of the given schema version to
+// ProductsSchemaVersion
+func migrateProduct(record json.RawMessage, version int) (json.RawMessage, error) {
+	if version == ProductsSchemaVersion {
+		return record, nil
+	}
+
+	// Numbers are kept as written, so prices are not rounded on the way
+	var fields map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(record))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, fmt.Errorf("error decoding product: %w", err)
+	}
+	if fields == nil {
+		return nil, fmt.Errorf("error decoding product: not an object")
+	}
+	for v := version; v < ProductsSchemaVersion; v++ {
+		if err := productMigrations[v](fields); err != nil {
+			return nil, fmt.Errorf("error converting product from schema version %d: %w", v, err)
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// migrateFlatImage converts a version 1 image given as a single URL to the
+// URL of each rendition. Images already given by rendition are kept.
+func migrateFlatImage(record map[string]any) error {
+	switch image := record["image"].(type) {
+	case string:
+		record["image"] = map[string]string{
+			"thumbnail": image,
+			"mobile":    image,
+			"tablet":    image,
+			"desktop":   image,
+		}
+	case map[string]any, nil:
+	default:
+		return fmt.Errorf("image must be a URL or an object of renditions")
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package data
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadProductsFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string
+		wantVersion int
+		wantRecords int
+		wantErr     string
+	}{
+		{name: "bare array", file: `[{"id": "a"}, {"id": "b"}]`, wantVersion: 1, wantRecords: 2},
+		{name: "versioned", file: `{"schema_version": 2, "products": [{"id": "a"}]}`, wantVersion: 2, wantRecords: 1},
+		{name: "products before version", file: `{"products": [], "schema_version": 1}`, wantVersion: 1},
+		{name: "no version", file: `{"products": [{"id": "a"}]}`, wantVersion: 1, wantRecords: 1},
+		{name: "newer version", file: `{"schema_version": 3, "products": []}`, wantErr: "unsupported schema_version 3"},
+		{name: "invalid JSON", file: `[{"id": "a"`, wantErr: "error decoding products"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, records, err := readProductsFile(strings.NewReader(tt.file))
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantVersion, version)
+			assert.Len(t, records, tt.wantRecords)
+		})
+	}
+}
+
+func TestMigrateProduct(t *testing.T) {
+	migrated, err := migrateProduct(json.RawMessage(`{"id": "a", "price": 6.50, "image": "https://example.com/a.jpg"}`), 1)
+	require.NoError(t, err)
+	var product models.Product
+	require.NoError(t, json.Unmarshal(migrated, &product))
+	assert.Equal(t, 6.5, product.Price)
+	assert.Equal(t, &models.ProductImage{
+		Thumbnail: "https://example.com/a.jpg",
+		Mobile:    "https://example.com/a.jpg",
+		Tablet:    "https://example.com/a.jpg",
+		Desktop:   "https://example.com/a.jpg",
+	}, product.Image)
+
+	// Images already given by rendition are kept
+	record := json.RawMessage(`{"id":"a","image":{"thumbnail":"https://example.com/t.jpg"}}`)
+	migrated, err = migrateProduct(record, 1)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(record), string(migrated))
+
+	_, err = migrateProduct(json.RawMessage(`{"id": "a", "image": 7}`), 1)
+	assert.ErrorContains(t, err, "schema version 1")
+	_, err = migrateProduct(json.RawMessage(`"a"`), 1)
+	assert.Error(t, err)
+}
+
+func TestProductStore_LoadProducts_Versions(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "products.json")
+	require.NoError(t, os.WriteFile(file, []byte(`[
+		{"id": "prod-1", "name": "Waffle", "price": 9.99, "category": "Waffle", "image": "https://example.com/waffle.jpg"}
+	]`), 0644))
+
+	store := NewProductStore()
+	require.NoError(t, store.LoadProducts(file))
+	product, err := store.GetProduct("prod-1")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/waffle.jpg", product.Image.Desktop)
+
+	// Files in the current version are read as they are
+	raw, err := json.Marshal(map[string]any{"schema_version": ProductsSchemaVersion, "products": []*models.Product{product}})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(file, raw, 0644))
+	store = NewProductStore()
+	require.NoError(t, store.LoadProducts(file))
+	reloaded, err := store.GetProduct("prod-1")
+	require.NoError(t, err)
+	assert.Equal(t, product, reloaded)
+}
+
+func TestCheckProducts(t *testing.T) {
+	check, err := CheckProducts(strings.NewReader(`{"schema_version": 2, "products": [
+		{"id": "prod-1", "name": "Waffle", "price": 9.99, "category": "Waffle", "image": {"thumbnail": "https://example.com/t.jpg", "mobile": "https://example.com/m.jpg", "tablet": "https://example.com/t.jpg", "desktop": "https://example.com/d.jpg"}},
+		{"id": "prod-2", "name": "Broken", "price": "free"}
+	]}`))
+	require.NoError(t, err)
+	assert.False(t, check.Valid)
+	assert.Equal(t, 2, check.SchemaVersion)
+	assert.Equal(t, 1, check.Products)
+	require.Len(t, check.Errors, 1)
+	assert.Equal(t, 1, check.Errors[0].Index)
+	assert.Equal(t, "prod-2", check.Errors[0].ID)
+
+	check, err = CheckProducts(strings.NewReader(`[]`))
+	require.NoError(t, err)
+	assert.True(t, check.Valid)
+	assert.NotNil(t, check.Errors)
+
+	_, err = CheckProducts(strings.NewReader(`{"schema_version": 9}`))
+	assert.ErrorContains(t, err, "unsupported schema_version")
+}
@@ -0,0 +1,38 @@
+package data
+
+import (
+	"cmp"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/sorting"
+)
+
+// ProductSortFields are the fields products can be listed by
+var ProductSortFields = sorting.Fields[*models.Product]{
+	"id":         CompareProductIDs,
+	"name":       func(a, b *models.Product) int { return cmp.Compare(a.Name, b.Name) },
+	"price":      func(a, b *models.Product) int { return cmp.Compare(a.Price, b.Price) },
+	"category":   func(a, b *models.Product) int { return cmp.Compare(a.Category, b.Category) },
+	"created_at": func(a, b *models.Product) int { return a.CreatedAt.Compare(b.CreatedAt) },
+	"updated_at": func(a, b *models.Product) int { return a.UpdatedAt.Compare(b.UpdatedAt) },
+}
+
+// CompareProductIDs orders products by ID, which breaks ties in every sort
+func CompareProductIDs(a, b *models.Product) int {
+	return cmp.Compare(a.ID, b.ID)
+}
+
+// SortedProducts returns all products, including deleted ones, sorted in
+// order, with ties broken by ID. Each order products are listed in is kept
+// until the catalog next changes, so repeated listings are not sorted
+// again. The list is shared and must not be changed.
+func (s *Store) SortedProducts(order sorting.Order) []*models.Product {
+	// Check if context is cancelled
+	if err := s.ctx.Err(); err != nil {
+		return nil // Return empty slice if store is closed
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sorted.Sorted(s.revision, order, ProductSortFields.Compare(order, CompareProductIDs), s.products.GetAllProducts)
+}
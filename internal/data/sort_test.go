@@ -0,0 +1,42 @@
+package data
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_SortedProducts(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+	store, err := NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+	defer store.Close()
+
+	ids := func(products []*models.Product) []string {
+		var ids []string
+		for _, p := range products {
+			ids = append(ids, p.ID)
+		}
+		return ids
+	}
+	byPrice, err := ProductSortFields.Parse("-price")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"prod-2", "prod-1"}, ids(store.SortedProducts(byPrice)))
+
+	// The sorted products follow changes to the catalog, with ties broken
+	// by ID
+	require.NoError(t, store.AddProduct(testutil.GetTestProduct()))
+	assert.Equal(t, []string{"prod-2", "prod-1", "test-prod-1"}, ids(store.SortedProducts(byPrice)))
+
+	product, err := store.GetProduct("prod-1")
+	require.NoError(t, err)
+	repriced := *product
+	repriced.Price = 25
+	require.NoError(t, store.UpdateProduct(&repriced))
+	assert.Equal(t, []string{"prod-1", "prod-2", "test-prod-1"}, ids(store.SortedProducts(byPrice)))
+}
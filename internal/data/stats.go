@@ -0,0 +1,271 @@
+package data
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Stats describes the catalog and coupon set a store serves
+type Stats struct {
+	Products int    `json:"products"` // Products on the menu
+	Deleted  int    `json:"deleted"`  // Deleted products, kept for order history
+	Coupons  int    `json:"coupons"`  // Valid coupon codes
+	Revision uint64 `json:"revision"` // Catalog revision, see Revision
+	Invalid  int    `json:"invalid"`  // Invalid products skipped when the catalog was loaded
+
+	// What was read from each coupon file
+	CouponFiles []CouponFile `json:"couponFiles,omitempty"`
+}
+
+// couponCounter is a coupon set that can count its codes and account for
+// the files they were read from
+type couponCounter interface {
+	Count() int
+	Files() []CouponFile
+}
+
+// Stats counts the products and coupons the store serves. Coupons are
+// counted as zero, and their files left out, when the coupon set cannot
+// count its codes.
+func (s *Store) Stats() Stats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := Stats{Revision: s.revision, Invalid: len(s.products.InvalidProducts())}
+	for _, product := range s.products.GetAllProducts() {
+		if product.DeletedAt != nil {
+			stats.Deleted++
+		} else {
+			stats.Products++
+		}
+	}
+	if counter, ok := s.coupons.(couponCounter); ok {
+		stats.Coupons = counter.Count()
+		stats.CouponFiles = counter.Files()
+	}
+	return stats
+}
+
+// couponSlotBytes estimates the memory a code's entry in the coupon map
+// takes besides its text: a 16-byte string header and a control byte, at
+// the map's maximum load factor of 7/8
+const couponSlotBytes = 20
+
+// CouponStats describes the coupon set a store serves, for capacity planning
+type CouponStats struct {
+	// Valid coupon codes
+	// @example 250000
+	Codes int `json:"codes"`
+
+	// Rough estimate of the memory the valid codes take, in bytes
+	// @example 9000000
+	MemoryBytes int64 `json:"memoryBytes"`
+
+	// How the codes read at the last load spread over the loader's shards
+	Shards ShardStats `json:"shards"`
+
+	// When the coupon files were last loaded
+	LoadedAt time.Time `json:"loadedAt"`
+
+	// How long the last load took, in milliseconds
+	// @example 850.5
+	LoadDurationMs float64 `json:"loadDurationMs"`
+
+	// Lookups of valid codes since the server started
+	// @example 1200
+	Hits int64 `json:"hits"`
+
+	// Lookups of invalid codes since the server started
+	// @example 80
+	Misses int64 `json:"misses"`
+}
+
+// ShardStats describes how evenly codes spread over shards
+type ShardStats struct {
+	// @example 256
+	Count int `json:"count"`
+
+	// Codes in the emptiest shard
+	// @example 950
+	Min int `json:"min"`
+
+	// Codes in the fullest shard
+	// @example 1080
+	Max int `json:"max"`
+
+	// @example 1000
+	Mean float64 `json:"mean"`
+
+	// Codes in the fullest shard over the mean; 1 is perfectly even
+	// @example 1.08
+	Skew float64 `json:"skew"`
+}
+
+// newShardStats summarizes the number of codes in each shard
+func newShardStats(sizes []int) ShardStats {
+	stats := ShardStats{Count: len(sizes)}
+	if len(sizes) == 0 {
+		return stats
+	}
+	total := 0
+	stats.Min = sizes[0]
+	for _, size := range sizes {
+		total += size
+		stats.Min = min(stats.Min, size)
+		stats.Max = max(stats.Max, size)
+	}
+	stats.Mean = float64(total) / float64(len(sizes))
+	if total > 0 {
+		stats.Skew = float64(stats.Max) / stats.Mean
+	}
+	return stats
+}
+
+// stringAllocBytes returns the memory the text of s takes, rounded up to
+// the 8-byte size classes small strings are allocated in
+func stringAllocBytes(s string) int64 {
+	return int64(len(s)+7) / 8 * 8
+}
+
+// couponStatter is a coupon set that can account for its last load
+type couponStatter interface {
+	CouponStats() CouponStats
+}
+
+// CouponStats accounts for the coupon set the store serves and the lookups
+// made in it. Only lookups are counted when the coupon set cannot account
+// for itself.
+func (s *Store) CouponStats() CouponStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var stats CouponStats
+	if statter, ok := s.coupons.(couponStatter); ok {
+		stats = statter.CouponStats()
+	}
+	stats.Hits = s.couponHits.Load()
+	stats.Misses = s.couponMisses.Load()
+	return stats
+}
+
+// ProductStats describes the catalog a store serves, for catalog and
+// pricing reviews
+type ProductStats struct {
+	// Products on the menu
+	// @example 9
+	Products int `json:"products"`
+
+	// Deleted products, kept for order history
+	// @example 1
+	Deleted int `json:"deleted"`
+
+	// Prices of the products on the menu
+	Prices PriceStats `json:"prices"`
+
+	// Products on the menu in each category, by category name
+	Categories []CategoryStats `json:"categories"`
+
+	// When the products file was last loaded, at startup, by a reload or by
+	// a restore
+	LoadedAt time.Time `json:"loadedAt"`
+
+	// Records of the products file skipped as invalid at the last load,
+	// listed at /admin/products/errors
+	// @example 2
+	Quarantined int `json:"quarantined"`
+}
+
+// CategoryStats describes the products on the menu in a category
+type CategoryStats struct {
+	// @example Waffle
+	Category string `json:"category"`
+
+	// @example 4
+	Products int `json:"products"`
+
+	Prices PriceStats `json:"prices"`
+}
+
+// PriceStats summarizes a set of prices. Every field is zero when the set
+// is empty.
+type PriceStats struct {
+	// @example 4
+	Min float64 `json:"min"`
+
+	// @example 7
+	Max float64 `json:"max"`
+
+	// @example 5.75
+	Mean float64 `json:"mean"`
+
+	// @example 6
+	Median float64 `json:"median"`
+
+	// Price 90% of the set is at or below
+	// @example 7
+	P90 float64 `json:"p90"`
+}
+
+// newPriceStats summarizes prices, which are sorted in place. Percentiles
+// are by nearest rank, so they are always prices of the set.
+func newPriceStats(prices []float64) PriceStats {
+	if len(prices) == 0 {
+		return PriceStats{}
+	}
+	slices.Sort(prices)
+	total := 0.0
+	for _, price := range prices {
+		total += price
+	}
+	return PriceStats{
+		Min:    prices[0],
+		Max:    prices[len(prices)-1],
+		Mean:   math.Round(total/float64(len(prices))*100) / 100,
+		Median: nearestRank(prices, 0.5),
+		P90:    nearestRank(prices, 0.9),
+	}
+}
+
+// nearestRank returns the price the share p of sorted is at or below
+func nearestRank(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+// ProductStats summarizes the products the store serves by category and
+// price. Deleted products are only counted.
+func (s *Store) ProductStats() ProductStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := ProductStats{
+		Categories:  []CategoryStats{},
+		LoadedAt:    s.products.LoadedAt(),
+		Quarantined: len(s.products.InvalidProducts()),
+	}
+	var prices []float64
+	byCategory := map[string][]float64{}
+	for _, product := range s.products.GetAllProducts() {
+		if product.DeletedAt != nil {
+			stats.Deleted++
+			continue
+		}
+		stats.Products++
+		prices = append(prices, product.Price)
+		byCategory[product.Category] = append(byCategory[product.Category], product.Price)
+	}
+	stats.Prices = newPriceStats(prices)
+	for category, prices := range byCategory {
+		stats.Categories = append(stats.Categories, CategoryStats{
+			Category: category,
+			Products: len(prices),
+			Prices:   newPriceStats(prices),
+		})
+	}
+	slices.SortFunc(stats.Categories, func(a, b CategoryStats) int {
+		return strings.Compare(a.Category, b.Category)
+	})
+	return stats
+}
@@ -0,0 +1,92 @@
+package data
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_Stats(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+	store, err := NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+	defer store.Close()
+
+	stats := store.Stats()
+	require.Len(t, stats.CouponFiles, 3)
+	assert.Equal(t, "coupons1.txt", stats.CouponFiles[0].Name)
+	assert.Equal(t, 3, stats.CouponFiles[0].Lines)
+	stats.CouponFiles = nil
+	assert.Equal(t, Stats{Products: 2, Coupons: 1, Revision: 1}, stats)
+
+	require.NoError(t, store.DeleteProduct("prod-1"))
+	stats = store.Stats()
+	stats.CouponFiles = nil
+	assert.Equal(t, Stats{Products: 1, Deleted: 1, Coupons: 1, Revision: 2}, stats)
+}
+
+func TestStore_CouponStats(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+	store, err := NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+	defer store.Close()
+
+	assert.True(t, store.ValidateCoupon(testutil.ValidCoupon))
+	assert.True(t, store.ValidateCoupon(testutil.ValidCoupon))
+	assert.False(t, store.ValidateCoupon("NOTACOUPON"))
+
+	stats := store.CouponStats()
+	assert.Equal(t, 1, stats.Codes)
+	assert.Equal(t, int64(couponSlotBytes+stringAllocBytes(testutil.ValidCoupon)), stats.MemoryBytes)
+	assert.Equal(t, ShardStats{Count: numShards, Max: 1, Mean: 1.0 / numShards, Skew: numShards}, stats.Shards)
+	assert.False(t, stats.LoadedAt.IsZero())
+	assert.Positive(t, stats.LoadDurationMs)
+	assert.Equal(t, int64(2), stats.Hits)
+	assert.Equal(t, int64(1), stats.Misses)
+
+	// Lookups are still counted after the coupons are reloaded
+	require.NoError(t, store.Reload())
+	stats = store.CouponStats()
+	assert.Equal(t, int64(2), stats.Hits)
+	assert.Equal(t, 1, stats.Codes)
+}
+
+func TestNewShardStats(t *testing.T) {
+	assert.Equal(t, ShardStats{Count: 4, Min: 1, Max: 5, Mean: 2.5, Skew: 2}, newShardStats([]int{2, 1, 5, 2}))
+	assert.Equal(t, ShardStats{Count: 2}, newShardStats([]int{0, 0}))
+}
+
+func TestStore_ProductStats(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+	store, err := NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+	defer store.Close()
+
+	stats := store.ProductStats()
+	assert.Equal(t, 2, stats.Products)
+	assert.Equal(t, PriceStats{Min: 9.99, Max: 19.99, Mean: 14.99, Median: 9.99, P90: 19.99}, stats.Prices)
+	require.Len(t, stats.Categories, 1)
+	assert.Equal(t, "Test Category", stats.Categories[0].Category)
+	assert.Equal(t, 2, stats.Categories[0].Products)
+	assert.False(t, stats.LoadedAt.IsZero())
+	assert.Zero(t, stats.Quarantined)
+
+	// Deleted products are counted, but their prices are left out
+	require.NoError(t, store.DeleteProduct("prod-2"))
+	stats = store.ProductStats()
+	assert.Equal(t, 1, stats.Products)
+	assert.Equal(t, 1, stats.Deleted)
+	assert.Equal(t, PriceStats{Min: 9.99, Max: 9.99, Mean: 9.99, Median: 9.99, P90: 9.99}, stats.Prices)
+}
+
+func TestNewPriceStats(t *testing.T) {
+	assert.Equal(t, PriceStats{}, newPriceStats(nil))
+	assert.Equal(t, PriceStats{Min: 1, Max: 10, Mean: 5.5, Median: 5, P90: 9}, newPriceStats([]float64{10, 9, 8, 7, 6, 5, 4, 3, 2, 1}))
+	assert.Equal(t, PriceStats{Min: 2, Max: 4.5, Mean: 3.17, Median: 3, P90: 4.5}, newPriceStats([]float64{4.5, 2, 3}))
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/ravibandhu/oolio-food-ordering/internal/config"
 	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/sorting"
 )
 
 // CouponValidator defines the interface for coupon validation
@@ -14,6 +17,26 @@ type CouponValidator interface {
 	GetCoupon(code string) bool
 }
 
+// Backend defines the storage operations the API is served from. Every
+// implementation must pass the storetest conformance suite.
+type Backend interface {
+	GetProduct(id string) (*models.Product, error)
+	GetProducts(ids []string) (map[string]*models.Product, []string, error)
+	GetAllProducts() []*models.Product
+	AddProduct(product *models.Product) error
+	UpdateProduct(product *models.Product) error
+	DeleteProduct(id string) error
+	ValidateCoupon(code string) bool
+	Close() error
+}
+
+// Ensure Store implements Backend
+var _ Backend = (*Store)(nil)
+
+// couponLoadMu serializes coupon loads outside the singleton, which share the
+// package-level coupon shards
+var couponLoadMu sync.Mutex
+
 // Store represents the data store for products and coupons
 type Store struct {
 	products *ProductStore
@@ -22,6 +45,14 @@ type Store struct {
 	mu       sync.RWMutex
 	ctx      context.Context
 	cancel   context.CancelFunc
+	revision uint64                         // Catalog revision, see Revision
+	priced   map[string]uint64              // Revision each product's prices last changed in
+	sorted   sorting.Index[*models.Product] // Products in each order listed, see SortedProducts
+	lookups  lookupCache                    // Recent lookups by ID, see GetProduct
+	history  map[string][]PricePoint        // Prices of each product over time, see PriceHistory
+
+	couponHits   atomic.Int64 // Lookups of valid coupon codes, see CouponStats
+	couponMisses atomic.Int64 // Lookups of invalid coupon codes
 }
 
 // NewStore creates a new Store instance
@@ -30,18 +61,37 @@ func NewStore(ctx context.Context, cfg *config.Config) (*Store, error) {
 		return nil, fmt.Errorf("config cannot be nil")
 	}
 
+	// Get coupon store instance
+	return newStore(ctx, cfg, func() (CouponValidator, error) {
+		return CouponStoreConcurrentInstance(cfg.Files.CouponsDir, cfg.Coupons.Integrity)
+	})
+}
+
+// NewIsolatedStore creates a Store with its own coupon set rather than the
+// process-wide coupon store, so several stores can serve different data
+func NewIsolatedStore(ctx context.Context, cfg *config.Config) (*Store, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+
+	return newStore(ctx, cfg, func() (CouponValidator, error) {
+		return loadCoupons(cfg.Files.CouponsDir, cfg.Coupons.Integrity)
+	})
+}
+
+// newStore loads products from the configured file and coupons using loadCoupons
+func newStore(ctx context.Context, cfg *config.Config, loadCoupons func() (CouponValidator, error)) (*Store, error) {
 	// Create a child context with cancellation
 	storeCtx, cancel := context.WithCancel(ctx)
 
 	// Create product store
 	productStore := NewProductStore()
-	if err := productStore.LoadProducts(cfg.Files.ProductsFile); err != nil {
+	if err := loadProducts(productStore, cfg); err != nil {
 		cancel() // Clean up context if product loading fails
 		return nil, fmt.Errorf("failed to load products: %w", err)
 	}
 
-	// Get coupon store instance
-	couponStore, err := CouponStoreConcurrentInstance(cfg.Files.CouponsDir)
+	couponStore, err := loadCoupons()
 	if err != nil {
 		cancel() // Clean up context if coupon store initialization fails
 		return nil, fmt.Errorf("failed to initialize coupon store: %w", err)
@@ -54,11 +104,44 @@ func NewStore(ctx context.Context, cfg *config.Config) (*Store, error) {
 		config:   cfg,
 		ctx:      storeCtx,
 		cancel:   cancel,
+		revision: 1,
 	}
+	store.recordPrices(productIDs(productStore), productStore.LoadedAt())
 
 	return store, nil
 }
 
+// loadProducts reads the configured products file into productStore,
+// skipping invalid products when the configuration is lenient
+func loadProducts(productStore *ProductStore, cfg *config.Config) error {
+	if cfg.Products.Lenient {
+		return productStore.LoadProductsLenient(cfg.Files.ProductsFile)
+	}
+	return productStore.LoadProducts(cfg.Files.ProductsFile)
+}
+
+// loadCoupons builds a new coupon store from dir, handling incomplete files
+// as the integrity policy says
+func loadCoupons(dir, integrity string) (*CouponStoreConcurrent, error) {
+	couponLoadMu.Lock()
+	defer couponLoadMu.Unlock()
+
+	couponStore := NewCouponStoreConcurrent()
+	couponStore.integrity = integrity
+	if err := couponStore.LoadAndFindValidCoupons(dir); err != nil {
+		return nil, err
+	}
+	return couponStore, nil
+}
+
+// InvalidProducts returns the records of the products file that were
+// skipped as invalid when it was last loaded or reloaded
+func (s *Store) InvalidProducts() []InvalidProduct {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.products.InvalidProducts()
+}
+
 // Close performs cleanup of the store resources
 func (s *Store) Close() error {
 	s.cancel() // Cancel the store's context
@@ -66,7 +149,9 @@ func (s *Store) Close() error {
 	return nil
 }
 
-// GetProduct retrieves a product by ID
+// GetProduct retrieves a product by ID. Lookups, including those of IDs
+// that do not exist, are remembered for the configured cache TTL or until
+// the catalog changes.
 func (s *Store) GetProduct(id string) (*models.Product, error) {
 	// Check if context is cancelled
 	if err := s.ctx.Err(); err != nil {
@@ -75,10 +160,35 @@ func (s *Store) GetProduct(id string) (*models.Product, error) {
 
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.products.GetProduct(id)
+	ttl := s.config.Cache.ProductTTL
+	if ttl <= 0 {
+		return s.products.GetProduct(id)
+	}
+
+	now := time.Now()
+	if hit, ok := s.lookups.get(id, s.revision, now); ok {
+		return hit.product, hit.err
+	}
+	product, err := s.products.GetProduct(id)
+	s.lookups.put(id, lookup{product: product, err: err, revision: s.revision, expires: now.Add(ttl)}, now)
+	return product, err
 }
 
-// GetAllProducts returns all available products
+// GetProducts retrieves several products at once, returning those found by
+// ID and the IDs that do not exist
+func (s *Store) GetProducts(ids []string) (map[string]*models.Product, []string, error) {
+	// Check if context is cancelled
+	if err := s.ctx.Err(); err != nil {
+		return nil, nil, fmt.Errorf("store is closed: %w", err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	found, missing := s.products.GetProducts(ids)
+	return found, missing, nil
+}
+
+// GetAllProducts returns all products, including deleted ones
 func (s *Store) GetAllProducts() []*models.Product {
 	// Check if context is cancelled
 	if err := s.ctx.Err(); err != nil {
@@ -99,5 +209,140 @@ func (s *Store) ValidateCoupon(code string) bool {
 
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.coupons.GetCoupon(code)
+	valid := s.coupons.GetCoupon(code)
+	if valid {
+		s.couponHits.Add(1)
+	} else {
+		s.couponMisses.Add(1)
+	}
+	return valid
+}
+
+// AddProduct adds a new product to the catalog
+func (s *Store) AddProduct(product *models.Product) error {
+	// Check if context is cancelled
+	if err := s.ctx.Err(); err != nil {
+		return fmt.Errorf("store is closed: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.products.AddProduct(product); err != nil {
+		return err
+	}
+	s.advance([]string{product.ID})
+	return nil
+}
+
+// UpdateProduct replaces an existing product in the catalog
+func (s *Store) UpdateProduct(product *models.Product) error {
+	return s.UpdateProductIfMatch(product, "")
+}
+
+// UpdateProductIfMatch replaces an existing product in the catalog, like
+// UpdateProduct, provided the stored product still has the given ETag, so
+// concurrent edits cannot overwrite each other. An empty etag updates
+// unconditionally.
+func (s *Store) UpdateProductIfMatch(product *models.Product, etag string) error {
+	// Check if context is cancelled
+	if err := s.ctx.Err(); err != nil {
+		return fmt.Errorf("store is closed: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, err := s.products.GetProduct(product.ID)
+	if err != nil {
+		return err
+	}
+	if current.DeletedAt != nil {
+		return fmt.Errorf("%w: %s", ErrProductNotFound, product.ID)
+	}
+	if etag != "" && etag != ETag(current) {
+		return fmt.Errorf("%w: %s", ErrProductChanged, product.ID)
+	}
+	if err := s.products.UpdateProduct(product); err != nil {
+		return err
+	}
+	var repriced []string
+	if !samePrices(current, product) {
+		repriced = []string{product.ID}
+	}
+	s.advance(repriced)
+	return nil
+}
+
+// DeleteProduct marks a product as deleted. Deleted products can still be
+// looked up by ID, so orders that reference them still render, but they
+// cannot be ordered or updated.
+func (s *Store) DeleteProduct(id string) error {
+	// Check if context is cancelled
+	if err := s.ctx.Err(); err != nil {
+		return fmt.Errorf("store is closed: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.products.DeleteProduct(id, time.Now()); err != nil {
+		return err
+	}
+	s.advance(nil)
+	return nil
+}
+
+// ApplyProducts adds or replaces the upserted products and deletes the
+// deleted ones in a single step, so readers see either none of the changes
+// or all of them. If any upserted product is invalid nothing changes. It
+// is how catalog updates pushed by an upstream service are applied.
+func (s *Store) ApplyProducts(upserts []*models.Product, deletes []string) error {
+	// Check if context is cancelled
+	if err := s.ctx.Err(); err != nil {
+		return fmt.Errorf("store is closed: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var repriced []string
+	for _, product := range upserts {
+		current, err := s.products.GetProduct(product.ID)
+		if err != nil || !samePrices(current, product) {
+			repriced = append(repriced, product.ID)
+		}
+	}
+	if err := s.products.Apply(upserts, deletes, time.Now()); err != nil {
+		return err
+	}
+	s.advance(repriced)
+	return nil
+}
+
+// Reload re-reads the products file and coupon directory from the configured
+// locations and swaps them in atomically. Products added or deleted through
+// the API are kept, as the file does not record them. On failure the current
+// data is kept.
+func (s *Store) Reload() error {
+	// Check if context is cancelled
+	if err := s.ctx.Err(); err != nil {
+		return fmt.Errorf("store is closed: %w", err)
+	}
+
+	productStore := NewProductStore()
+	if err := loadProducts(productStore, s.config); err != nil {
+		return fmt.Errorf("failed to reload products: %w", err)
+	}
+
+	couponStore, err := loadCoupons(s.config.Files.CouponsDir, s.config.Coupons.Integrity)
+	if err != nil {
+		return fmt.Errorf("failed to reload coupons: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	productStore.carryOver(s.products)
+	repriced := repricedProducts(s.products, productStore)
+	s.products = productStore
+	s.advance(repriced)
+	s.coupons = couponStore
+
+	return nil
 }
@@ -0,0 +1,50 @@
+package data_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/data"
+	"github.com/ravibandhu/oolio-food-ordering/internal/data/storetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_Conformance(t *testing.T) {
+	storetest.TestBackend(t, newFileStore)
+}
+
+// newFileStore writes the seed to a products file and coupon directory and
+// opens an isolated Store over them
+func newFileStore(t *testing.T, seed storetest.Seed) data.Backend {
+	dir := t.TempDir()
+
+	productsFile := filepath.Join(dir, "products.json")
+	content, err := json.Marshal(seed.Products)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(productsFile, content, 0644))
+
+	// A coupon is valid when it appears in at least two of the three files
+	couponsDir := filepath.Join(dir, "coupons")
+	require.NoError(t, os.MkdirAll(couponsDir, 0755))
+	coupons := strings.Join(seed.Coupons, "\n") + "\n"
+	for _, name := range []string{"coupons1.txt", "coupons2.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(couponsDir, name), []byte(coupons), 0644))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(couponsDir, "coupons3.txt"), []byte("UNKNOWN1\n"), 0644))
+
+	cfg := &config.Config{
+		Files: config.Files{
+			ProductsFile: productsFile,
+			CouponsDir:   couponsDir,
+		},
+	}
+	store, err := data.NewIsolatedStore(context.Background(), cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store
+}
@@ -259,6 +259,83 @@ func TestStore_ConcurrentAccess_WithMockData(t *testing.T) {
 	})
 }
 
+func TestStore_AddProduct_WithMockData(t *testing.T) {
+	ctx := context.Background()
+	store := createTestStore(t, ctx)
+
+	product := testutil.GetTestProduct()
+	require.NoError(t, store.AddProduct(product))
+	assert.Len(t, store.GetAllProducts(), 3)
+
+	// Adding the same product again conflicts
+	assert.ErrorIs(t, store.AddProduct(product), ErrProductExists)
+
+	// Mutations fail once the store is closed
+	require.NoError(t, store.Close())
+	assert.Error(t, store.AddProduct(testutil.GetTestProduct()))
+}
+
+func TestStore_ApplyProducts(t *testing.T) {
+	ctx := context.Background()
+	store := createTestStore(t, ctx)
+	revision := store.Revision()
+
+	added := testutil.GetTestProduct()
+	repriced, err := store.GetProduct("prod-1")
+	require.NoError(t, err)
+	changed := *repriced
+	changed.Price += 1
+	require.NoError(t, store.ApplyProducts([]*models.Product{added, &changed}, []string{"prod-2"}))
+
+	assert.Len(t, store.GetAllProducts(), 3)
+	deleted, err := store.GetProduct("prod-2")
+	require.NoError(t, err)
+	assert.NotNil(t, deleted.DeletedAt)
+	assert.Equal(t, revision+1, store.Revision())
+	assert.ElementsMatch(t, []string{added.ID, "prod-1"}, store.RepricedSince(revision, []string{added.ID, "prod-1", "prod-2"}))
+
+	// Updates fail once the store is closed
+	require.NoError(t, store.Close())
+	assert.Error(t, store.ApplyProducts([]*models.Product{added}, nil))
+}
+
+func TestStore_Reload(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+
+	ctx := context.Background()
+	store := createTestStore(t, ctx)
+	store.config = testData.Config
+
+	// Add a product that is not in the products file, and delete one that is
+	require.NoError(t, store.AddProduct(testutil.GetTestProduct()))
+	assert.Len(t, store.GetAllProducts(), 3)
+	require.NoError(t, store.DeleteProduct("prod-1"))
+
+	// Reloading keeps the changes made through the API
+	resetForTest()
+	require.NoError(t, store.Reload())
+	assert.Len(t, store.GetAllProducts(), 3)
+	_, err := store.GetProduct("test-prod-1")
+	assert.NoError(t, err)
+	deleted, err := store.GetProduct("prod-1")
+	require.NoError(t, err)
+	assert.NotNil(t, deleted.DeletedAt)
+	_, missing, err := store.GetProducts([]string{"prod-1"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"prod-1"}, missing)
+
+	// A failed reload keeps the current data
+	store.config = &config.Config{
+		Files: config.Files{
+			ProductsFile: "nonexistent.json",
+			CouponsDir:   testData.CouponsDir,
+		},
+	}
+	assert.Error(t, store.Reload())
+	assert.Len(t, store.GetAllProducts(), 3)
+}
+
 func TestNewStore(t *testing.T) {
 	// Reset the singleton for this test
 	resetForTest()
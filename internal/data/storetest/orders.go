@@ -0,0 +1,201 @@
+package storetest
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/orders"
+	"github.com/ravibandhu/oolio-food-ordering/internal/sorting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// OrderFactory opens the orders logged at path. Stores that keep orders in
+// memory only ignore path.
+type OrderFactory func(t *testing.T, path string) (orders.Store, error)
+
+// TestOrders runs the conformance suite against order stores opened by
+// factory. Durable stores must also give back the orders logged before they
+// were reopened, drop a last event cut short by a crash and refuse a log
+// that is corrupt before its end.
+func TestOrders(t *testing.T, factory OrderFactory, durable bool) {
+	t.Run("PlaceAndHistory", func(t *testing.T) {
+		store, err := factory(t, filepath.Join(t.TempDir(), "orders.jsonl"))
+		require.NoError(t, err)
+
+		require.NoError(t, store.Place(newOrder("order-1", 12.5, 0)))
+		require.NoError(t, store.ChangeStatus("order-1", readyChange))
+
+		history, ok := store.History("order-1")
+		require.True(t, ok)
+		require.Len(t, history.Events, 2)
+		assert.Equal(t, orders.EventPlaced, history.Events[0].Type)
+		assert.Equal(t, orders.EventStatusChanged, history.Events[1].Type)
+		assert.Less(t, history.Events[0].Seq, history.Events[1].Seq)
+		assert.Equal(t, "ready", history.Order.Status)
+		assert.Equal(t, 12.5, history.Order.TotalAmount)
+
+		// Orders are placed once, and only placed orders change
+		assert.ErrorIs(t, store.Place(newOrder("order-1", 1, 0)), orders.ErrExists)
+		assert.ErrorIs(t, store.ChangeStatus("missing", readyChange), orders.ErrNotFound)
+		_, ok = store.History("missing")
+		assert.False(t, ok)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		store, err := factory(t, filepath.Join(t.TempDir(), "orders.jsonl"))
+		require.NoError(t, err)
+
+		for i, total := range []float64{20, 5, 12.5} {
+			require.NoError(t, store.Place(newOrder(fmt.Sprintf("order-%d", i+1), total, time.Duration(i)*time.Minute)))
+		}
+
+		byTotal := sorting.Order{{Field: "total_amount", Desc: true}}
+		page, more := store.List(byTotal, nil, 2)
+		assert.True(t, more)
+		assert.Equal(t, []string{"order-1", "order-3"}, orderIDs(page))
+		page, more = store.List(byTotal, page[len(page)-1], 2)
+		assert.False(t, more)
+		assert.Equal(t, []string{"order-2"}, orderIDs(page))
+	})
+
+	t.Run("Archive", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "orders.jsonl")
+		archivePath := filepath.Join(t.TempDir(), "archive.jsonl")
+		store, err := factory(t, path)
+		require.NoError(t, err)
+		require.NoError(t, store.Place(newOrder("order-1", 20, 0)))
+		require.NoError(t, store.Place(newOrder("order-2", 5, time.Hour)))
+		require.NoError(t, store.ChangeStatus("order-1", readyChange))
+
+		// Orders created before the cutoff leave the store with their events
+		cutoff := time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC)
+		archived, err := store.Archive(cutoff, archivePath)
+		require.NoError(t, err)
+		assert.Equal(t, 1, archived)
+		_, ok := store.History("order-1")
+		assert.False(t, ok)
+		assert.ErrorIs(t, store.ChangeStatus("order-1", readyChange), orders.ErrNotFound)
+		page, more := store.List(sorting.Order{{Field: "created_at"}}, nil, 10)
+		assert.False(t, more)
+		assert.Equal(t, []string{"order-2"}, orderIDs(page))
+		_, ok = store.History("order-2")
+		assert.True(t, ok)
+
+		// ...and are appended to the archive, every event on a line
+		raw, err := os.ReadFile(archivePath)
+		require.NoError(t, err)
+		lines := bytes.Split(bytes.TrimSuffix(raw, []byte("\n")), []byte("\n"))
+		assert.Len(t, lines, 2)
+		assert.Contains(t, string(lines[0]), `"orderId":"order-1"`)
+
+		// Nothing is left to archive before the same cutoff
+		archived, err = store.Archive(cutoff, archivePath)
+		require.NoError(t, err)
+		assert.Zero(t, archived)
+
+		if durable {
+			reopened, err := factory(t, path)
+			require.NoError(t, err)
+			_, ok = reopened.History("order-1")
+			assert.False(t, ok)
+			_, ok = reopened.History("order-2")
+			assert.True(t, ok)
+			require.NoError(t, reopened.Place(newOrder("order-3", 1, 2*time.Hour)))
+		}
+	})
+
+	if !durable {
+		return
+	}
+
+	t.Run("Replay", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "orders.jsonl")
+		store, err := factory(t, path)
+		require.NoError(t, err)
+		require.NoError(t, store.Place(newOrder("order-1", 12.5, 0)))
+		require.NoError(t, store.ChangeStatus("order-1", readyChange))
+		want, _ := store.History("order-1")
+
+		reopened, err := factory(t, path)
+		require.NoError(t, err)
+		got, ok := reopened.History("order-1")
+		require.True(t, ok)
+		assert.Equal(t, want.Events, got.Events)
+		assert.Equal(t, want.Order.StatusHistory, got.Order.StatusHistory)
+	})
+
+	t.Run("TruncatedLastEvent", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "orders.jsonl")
+		store, err := factory(t, path)
+		require.NoError(t, err)
+		require.NoError(t, store.Place(newOrder("order-1", 12.5, 0)))
+
+		// A crash mid-write leaves part of a line behind
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+		require.NoError(t, err)
+		_, err = f.WriteString(`{"seq":2,"type":"placed","orderId":"order-2","or`)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		store, err = factory(t, path)
+		require.NoError(t, err)
+		_, ok := store.History("order-1")
+		assert.True(t, ok)
+		_, ok = store.History("order-2")
+		assert.False(t, ok)
+
+		// Orders placed after the crash are kept after the events before it
+		require.NoError(t, store.Place(newOrder("order-2", 5, time.Minute)))
+		store, err = factory(t, path)
+		require.NoError(t, err)
+		_, ok = store.History("order-2")
+		assert.True(t, ok)
+	})
+
+	t.Run("CorruptEvent", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "orders.jsonl")
+		store, err := factory(t, path)
+		require.NoError(t, err)
+		require.NoError(t, store.Place(newOrder("order-1", 12.5, 0)))
+		require.NoError(t, store.Place(newOrder("order-2", 5, time.Minute)))
+
+		// A damaged event before the end is not a crash mid-write, so the
+		// log is refused rather than losing the events after it
+		raw, err := os.ReadFile(path)
+		require.NoError(t, err)
+		lines := bytes.SplitAfter(raw, []byte("\n"))
+		corrupt := append([]byte{}, lines[0]...)
+		corrupt = append(corrupt, "not an event\n"...)
+		corrupt = append(corrupt, lines[1]...)
+		require.NoError(t, os.WriteFile(path, corrupt, 0644))
+
+		_, err = factory(t, path)
+		assert.Error(t, err)
+	})
+}
+
+// readyChange is an order marked ready by staff
+var readyChange = models.StatusChange{Status: "ready", At: time.Date(2024, 1, 1, 12, 20, 0, 0, time.UTC), Actor: models.ActorStaff}
+
+// newOrder builds an order as it is placed, the given time after the first
+func newOrder(id string, total float64, after time.Duration) *models.Order {
+	createdAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC).Add(after)
+	order := &models.Order{ID: id, TotalAmount: total, CreatedAt: createdAt}
+	order.SetStatus(models.OrderStatusPlaced, models.ActorCustomer, createdAt)
+	return order
+}
+
+// orderIDs returns the IDs of orders, in order
+func orderIDs(list []*models.Order) []string {
+	ids := make([]string, len(list))
+	for i, order := range list {
+		ids[i] = order.ID
+	}
+	return ids
+}
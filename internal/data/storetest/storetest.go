@@ -0,0 +1,225 @@
+// Package storetest provides conformance suites that every data.Backend and
+// orders.Store implementation must pass, so stores behave identically behind
+// the API.
+package storetest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/data"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Seed is the data a backend must be populated with before the suite runs
+type Seed struct {
+	// Products available in the catalog
+	Products []*models.Product
+
+	// Coupons that must be accepted
+	Coupons []string
+}
+
+// Factory creates a fresh, independent backend populated with seed
+type Factory func(t *testing.T, seed Seed) data.Backend
+
+// DefaultSeed returns the data the suite seeds every backend with
+func DefaultSeed() Seed {
+	return Seed{
+		Products: []*models.Product{
+			newProduct("conf-1", "Waffle", 6.5),
+			newProduct("conf-2", "Pancakes", 7.25),
+		},
+		Coupons: []string{"HAPPYHRS", "FIFTYOFF"},
+	}
+}
+
+// TestBackend runs the conformance suite against backends built by factory
+func TestBackend(t *testing.T, factory Factory) {
+	t.Run("GetProduct", func(t *testing.T) {
+		seed := DefaultSeed()
+		backend := factory(t, seed)
+
+		for _, want := range seed.Products {
+			got, err := backend.GetProduct(want.ID)
+			require.NoError(t, err)
+			assert.Equal(t, want.ID, got.ID)
+			assert.Equal(t, want.Name, got.Name)
+			assert.Equal(t, want.Price, got.Price)
+			assert.Equal(t, want.Category, got.Category)
+			assert.Equal(t, want.Image, got.Image)
+		}
+
+		_, err := backend.GetProduct("missing")
+		assert.True(t, errors.Is(err, data.ErrProductNotFound), "got %v", err)
+	})
+
+	t.Run("GetProducts", func(t *testing.T) {
+		backend := factory(t, DefaultSeed())
+
+		found, missing, err := backend.GetProducts([]string{"conf-2", "missing-b", "conf-1", "missing-a", "conf-2", "missing-b"})
+		require.NoError(t, err)
+		assert.Len(t, found, 2)
+		assert.Equal(t, "conf-1", found["conf-1"].ID)
+		assert.Equal(t, "conf-2", found["conf-2"].ID)
+		assert.Equal(t, []string{"missing-b", "missing-a"}, missing)
+
+		found, missing, err = backend.GetProducts(nil)
+		require.NoError(t, err)
+		assert.Empty(t, found)
+		assert.Empty(t, missing)
+	})
+
+	t.Run("GetAllProducts", func(t *testing.T) {
+		seed := DefaultSeed()
+		backend := factory(t, seed)
+
+		var ids []string
+		for _, product := range backend.GetAllProducts() {
+			ids = append(ids, product.ID)
+		}
+		assert.ElementsMatch(t, []string{"conf-1", "conf-2"}, ids)
+	})
+
+	t.Run("AddProduct", func(t *testing.T) {
+		backend := factory(t, DefaultSeed())
+
+		product := newProduct("conf-3", "French Toast", 8)
+		require.NoError(t, backend.AddProduct(product))
+
+		got, err := backend.GetProduct("conf-3")
+		require.NoError(t, err)
+		assert.Equal(t, "French Toast", got.Name)
+		assert.Len(t, backend.GetAllProducts(), 3)
+
+		// Duplicates are rejected and keep the original
+		err = backend.AddProduct(newProduct("conf-3", "Replacement", 1))
+		assert.True(t, errors.Is(err, data.ErrProductExists), "got %v", err)
+		got, err = backend.GetProduct("conf-3")
+		require.NoError(t, err)
+		assert.Equal(t, "French Toast", got.Name)
+
+		// Invalid products are rejected and not stored
+		assert.Error(t, backend.AddProduct(&models.Product{ID: "conf-4"}))
+		_, err = backend.GetProduct("conf-4")
+		assert.Error(t, err)
+	})
+
+	t.Run("UpdateProduct", func(t *testing.T) {
+		backend := factory(t, DefaultSeed())
+
+		updated := newProduct("conf-1", "Belgian Waffle", 7)
+		require.NoError(t, backend.UpdateProduct(updated))
+		got, err := backend.GetProduct("conf-1")
+		require.NoError(t, err)
+		assert.Equal(t, "Belgian Waffle", got.Name)
+		assert.Equal(t, 7.0, got.Price)
+		assert.Len(t, backend.GetAllProducts(), 2)
+
+		// Unknown products are not created
+		err = backend.UpdateProduct(newProduct("conf-9", "Missing", 1))
+		assert.True(t, errors.Is(err, data.ErrProductNotFound), "got %v", err)
+		_, err = backend.GetProduct("conf-9")
+		assert.Error(t, err)
+
+		// Invalid products are rejected and keep the original
+		assert.Error(t, backend.UpdateProduct(&models.Product{ID: "conf-1"}))
+		got, err = backend.GetProduct("conf-1")
+		require.NoError(t, err)
+		assert.Equal(t, "Belgian Waffle", got.Name)
+	})
+
+	t.Run("DeleteProduct", func(t *testing.T) {
+		backend := factory(t, DefaultSeed())
+
+		require.NoError(t, backend.DeleteProduct("conf-1"))
+
+		// Deleted products are kept, so they can still be looked up by ID
+		got, err := backend.GetProduct("conf-1")
+		require.NoError(t, err)
+		assert.Equal(t, "Waffle", got.Name)
+		assert.NotNil(t, got.DeletedAt)
+		assert.Len(t, backend.GetAllProducts(), 2)
+
+		// ...but cannot be ordered, updated or deleted again
+		found, missing, err := backend.GetProducts([]string{"conf-1", "conf-2"})
+		require.NoError(t, err)
+		assert.Len(t, found, 1)
+		assert.Equal(t, []string{"conf-1"}, missing)
+		err = backend.UpdateProduct(newProduct("conf-1", "Belgian Waffle", 7))
+		assert.True(t, errors.Is(err, data.ErrProductNotFound), "got %v", err)
+		err = backend.DeleteProduct("conf-1")
+		assert.True(t, errors.Is(err, data.ErrProductNotFound), "got %v", err)
+
+		// Their IDs stay taken
+		err = backend.AddProduct(newProduct("conf-1", "Replacement", 1))
+		assert.True(t, errors.Is(err, data.ErrProductExists), "got %v", err)
+
+		err = backend.DeleteProduct("missing")
+		assert.True(t, errors.Is(err, data.ErrProductNotFound), "got %v", err)
+	})
+
+	t.Run("ValidateCoupon", func(t *testing.T) {
+		seed := DefaultSeed()
+		backend := factory(t, seed)
+
+		for _, code := range seed.Coupons {
+			assert.True(t, backend.ValidateCoupon(code), "coupon %s", code)
+		}
+		for _, code := range []string{"", "UNKNOWN1", "SHORT", "WAYTOOLONGCODE", "happyhrs"} {
+			assert.False(t, backend.ValidateCoupon(code), "coupon %q", code)
+		}
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		seed := DefaultSeed()
+		backend := factory(t, seed)
+		require.NoError(t, backend.Close())
+
+		_, err := backend.GetProduct("conf-1")
+		assert.Error(t, err)
+		_, _, err = backend.GetProducts([]string{"conf-1"})
+		assert.Error(t, err)
+		assert.Empty(t, backend.GetAllProducts())
+		assert.False(t, backend.ValidateCoupon(seed.Coupons[0]))
+		assert.Error(t, backend.AddProduct(newProduct("conf-5", "Crepe", 5)))
+		assert.Error(t, backend.UpdateProduct(newProduct("conf-1", "Crepe", 5)))
+		assert.Error(t, backend.DeleteProduct("conf-1"))
+	})
+
+	t.Run("ConcurrentAccess", func(t *testing.T) {
+		seed := DefaultSeed()
+		backend := factory(t, seed)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				id := fmt.Sprintf("conc-%d", i)
+				assert.NoError(t, backend.AddProduct(newProduct(id, "Concurrent", 1)))
+				_, err := backend.GetProduct(id)
+				assert.NoError(t, err)
+				backend.GetAllProducts()
+				backend.ValidateCoupon(seed.Coupons[0])
+			}(i)
+		}
+		wg.Wait()
+
+		assert.Len(t, backend.GetAllProducts(), len(seed.Products)+8)
+	})
+}
+
+// newProduct builds a valid product for the suite
+func newProduct(id, name string, price float64) *models.Product {
+	return models.NewProduct(id, name, price, "Breakfast", &models.ProductImage{
+		Thumbnail: "https://example.com/images/" + id + "-thumb.jpg",
+		Mobile:    "https://example.com/images/" + id + "-mobile.jpg",
+		Tablet:    "https://example.com/images/" + id + "-tablet.jpg",
+		Desktop:   "https://example.com/images/" + id + "-desktop.jpg",
+	})
+}
@@ -0,0 +1,105 @@
+// Package delivery matches delivery addresses to the zones a restaurant serves.
+package delivery
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+// Zone is an area a restaurant delivers to
+type Zone struct {
+	Name string
+	Fee  float64
+
+	postcodes map[string]bool
+	polygon   [][2]float64
+}
+
+// Zones is the set of zones a restaurant delivers to. A nil Zones means the
+// restaurant does not deliver.
+type Zones struct {
+	zones []*Zone
+}
+
+// New creates Zones from their configuration. No zones yields nil.
+func New(cfg []config.Zone) (*Zones, error) {
+	if len(cfg) == 0 {
+		return nil, nil
+	}
+
+	zs := &Zones{}
+	names := make(map[string]bool)
+	for _, zc := range cfg {
+		if zc.Name == "" {
+			return nil, fmt.Errorf("zone name is required")
+		}
+		if names[zc.Name] {
+			return nil, fmt.Errorf("duplicate zone: %s", zc.Name)
+		}
+		names[zc.Name] = true
+
+		if zc.Fee < 0 {
+			return nil, fmt.Errorf("zone %s: fee must not be negative", zc.Name)
+		}
+		if len(zc.Postcodes) == 0 && len(zc.Polygon) == 0 {
+			return nil, fmt.Errorf("zone %s: postcodes or polygon is required", zc.Name)
+		}
+		if len(zc.Polygon) > 0 && len(zc.Polygon) < 3 {
+			return nil, fmt.Errorf("zone %s: polygon needs at least 3 vertices", zc.Name)
+		}
+
+		zone := &Zone{
+			Name:      zc.Name,
+			Fee:       zc.Fee,
+			postcodes: make(map[string]bool, len(zc.Postcodes)),
+			polygon:   zc.Polygon,
+		}
+		for _, postcode := range zc.Postcodes {
+			zone.postcodes[normalizePostcode(postcode)] = true
+		}
+		zs.zones = append(zs.zones, zone)
+	}
+
+	return zs, nil
+}
+
+// Match returns the first zone, in configuration order, that serves addr
+func (zs *Zones) Match(addr *models.Address) (*Zone, bool) {
+	if zs == nil || addr == nil {
+		return nil, false
+	}
+
+	postcode := normalizePostcode(addr.Postcode)
+	for _, zone := range zs.zones {
+		if zone.postcodes[postcode] {
+			return zone, true
+		}
+		if addr.Latitude != nil && addr.Longitude != nil &&
+			len(zone.polygon) > 0 && contains(zone.polygon, *addr.Latitude, *addr.Longitude) {
+			return zone, true
+		}
+	}
+	return nil, false
+}
+
+// contains reports whether a point lies inside polygon, using ray casting
+func contains(polygon [][2]float64, lat, lng float64) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		latI, lngI := polygon[i][0], polygon[i][1]
+		latJ, lngJ := polygon[j][0], polygon[j][1]
+		if (lngI > lng) != (lngJ > lng) &&
+			lat < (latJ-latI)*(lng-lngI)/(lngJ-lngI)+latI {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// normalizePostcode makes postcode comparisons ignore case and spacing
+func normalizePostcode(postcode string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(postcode), " ", ""))
+}
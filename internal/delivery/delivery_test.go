@@ -0,0 +1,78 @@
+package delivery
+
+import (
+	"testing"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		zones   []config.Zone
+		wantErr bool
+		wantNil bool
+	}{
+		{name: "no zones", wantNil: true},
+		{name: "postcodes", zones: []config.Zone{{Name: "cbd", Postcodes: []string{"2000"}}}},
+		{name: "missing name", zones: []config.Zone{{Postcodes: []string{"2000"}}}, wantErr: true},
+		{name: "duplicate name", zones: []config.Zone{{Name: "a", Postcodes: []string{"1"}}, {Name: "a", Postcodes: []string{"2"}}}, wantErr: true},
+		{name: "no area", zones: []config.Zone{{Name: "empty"}}, wantErr: true},
+		{name: "degenerate polygon", zones: []config.Zone{{Name: "line", Polygon: [][2]float64{{0, 0}, {1, 1}}}}, wantErr: true},
+		{name: "negative fee", zones: []config.Zone{{Name: "cbd", Postcodes: []string{"2000"}, Fee: -1}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zones, err := New(tt.zones)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantNil, zones == nil)
+		})
+	}
+}
+
+func TestZones_Match(t *testing.T) {
+	zones, err := New([]config.Zone{
+		{Name: "cbd", Postcodes: []string{"2000", "SW1A 1AA"}, Fee: 3},
+		{Name: "harbour", Polygon: [][2]float64{{-33.80, 151.10}, {-33.80, 151.30}, {-33.95, 151.30}, {-33.95, 151.10}}, Fee: 6},
+	})
+	require.NoError(t, err)
+
+	lat, lng := -33.87, 151.21
+	outLat, outLng := -34.5, 150.0
+
+	tests := []struct {
+		name     string
+		address  *models.Address
+		wantZone string
+	}{
+		{name: "postcode", address: &models.Address{Line1: "1 George St", Postcode: "2000"}, wantZone: "cbd"},
+		{name: "postcode ignores case and spaces", address: &models.Address{Line1: "10 Downing St", Postcode: "sw1a1aa"}, wantZone: "cbd"},
+		{name: "inside polygon", address: &models.Address{Line1: "1 Harbour Rd", Postcode: "2060", Latitude: &lat, Longitude: &lng}, wantZone: "harbour"},
+		{name: "outside polygon", address: &models.Address{Line1: "1 Far Rd", Postcode: "2570", Latitude: &outLat, Longitude: &outLng}},
+		{name: "unknown postcode without coordinates", address: &models.Address{Line1: "1 Far Rd", Postcode: "2570"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zone, ok := zones.Match(tt.address)
+			if tt.wantZone == "" {
+				assert.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			assert.Equal(t, tt.wantZone, zone.Name)
+		})
+	}
+
+	var none *Zones
+	_, ok := none.Match(&models.Address{Postcode: "2000"})
+	assert.False(t, ok)
+}
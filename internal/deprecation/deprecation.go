@@ -0,0 +1,154 @@
+// Package deprecation announces the routes and fields of the API that are
+// being retired. Notices are configured in one file rather than next to
+// each handler, so a migration such as v1 to v2 can be scheduled, and
+// moved, without touching the routes it retires. Deprecated routes answer
+// with Deprecation and Sunset headers, every notice is given as a warning
+// in enveloped responses, and routes past their sunset are refused.
+package deprecation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Notice announces that a route, or a field of it, is deprecated
+type Notice struct {
+	// Method of the route; empty for every method
+	Method string `json:"method,omitempty"`
+
+	// Route as documented, e.g. /orders/{id}/eta, or as registered, e.g.
+	// /orders/:id/eta
+	Route string `json:"route"`
+
+	// Field of the route's requests or responses that is deprecated; empty
+	// when the whole route is
+	Field string `json:"field,omitempty"`
+
+	// When the route or field was deprecated
+	Since time.Time `json:"since"`
+
+	// When the route or field is removed; a route past its sunset is
+	// refused. Zero when no date is set.
+	Sunset time.Time `json:"sunset,omitzero"`
+
+	// Where the replacement or migration is documented
+	Link string `json:"link,omitempty"`
+
+	// What to use instead
+	Message string `json:"message,omitempty"`
+}
+
+// Warning describes the notice to clients
+func (n Notice) Warning() string {
+	var b strings.Builder
+	if n.Field != "" {
+		fmt.Fprintf(&b, "Field %s of ", n.Field)
+	}
+	if n.Method != "" {
+		b.WriteString(n.Method + " ")
+	}
+	fmt.Fprintf(&b, "%s is deprecated since %s", n.Route, n.Since.UTC().Format(time.DateOnly))
+	if !n.Sunset.IsZero() {
+		fmt.Fprintf(&b, " and will be removed on %s", n.Sunset.UTC().Format(time.DateOnly))
+	}
+	b.WriteString(".")
+	if n.Message != "" {
+		b.WriteString(" " + n.Message)
+	}
+	if n.Link != "" {
+		b.WriteString(" See " + n.Link)
+	}
+	return b.String()
+}
+
+// Retired reports whether a route notice is past its sunset at now
+func (n Notice) Retired(now time.Time) bool {
+	return n.Field == "" && !n.Sunset.IsZero() && !now.Before(n.Sunset)
+}
+
+// documentedParam matches path parameters as OpenAPI writes them
+var documentedParam = regexp.MustCompile(`\{([^/{}]+)\}`)
+
+// validate checks a notice and writes its route as registered
+func (n *Notice) validate() error {
+	if !strings.HasPrefix(n.Route, "/") {
+		return errors.New("route must start with /")
+	}
+	n.Route = documentedParam.ReplaceAllString(n.Route, ":$1")
+	n.Method = strings.ToUpper(n.Method)
+	switch n.Method {
+	case "", http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return fmt.Errorf("unsupported method %q", n.Method)
+	}
+	if n.Since.IsZero() {
+		return errors.New("since is required")
+	}
+	if !n.Sunset.IsZero() && !n.Sunset.After(n.Since) {
+		return errors.New("sunset must be after since")
+	}
+	if n.Link != "" {
+		if u, err := url.Parse(n.Link); err != nil || !u.IsAbs() {
+			return errors.New("link must be an absolute URL")
+		}
+	}
+	return nil
+}
+
+// Policy holds the notices of every route. A nil Policy deprecates nothing.
+type Policy struct {
+	byRoute map[string][]Notice
+}
+
+// New checks notices and builds a policy of them
+func New(notices []Notice) (*Policy, error) {
+	p := &Policy{byRoute: make(map[string][]Notice)}
+	for i, n := range notices {
+		if err := n.validate(); err != nil {
+			return nil, fmt.Errorf("invalid deprecation notice %d: %w", i, err)
+		}
+		p.byRoute[n.Route] = append(p.byRoute[n.Route], n)
+	}
+	return p, nil
+}
+
+// Load reads the notices listed in the JSON file at path. An empty path or
+// missing file deprecates nothing.
+func Load(path string) (*Policy, error) {
+	if path == "" {
+		return New(nil)
+	}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return New(nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deprecation notices: %w", err)
+	}
+	var notices []Notice
+	if err := json.Unmarshal(raw, &notices); err != nil {
+		return nil, fmt.Errorf("failed to parse deprecation notices: %w", err)
+	}
+	return New(notices)
+}
+
+// For returns the notices of a route, as registered, requested with method
+func (p *Policy) For(method, route string) []Notice {
+	if p == nil {
+		return nil
+	}
+	var notices []Notice
+	for _, n := range p.byRoute[route] {
+		if n.Method == "" || n.Method == method {
+			notices = append(notices, n)
+		}
+	}
+	return notices
+}
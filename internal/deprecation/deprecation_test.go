@@ -0,0 +1,56 @@
+package deprecation
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deprecations.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[
+		{"method": "get", "route": "/orders/{id}/eta", "since": "2026-01-01T00:00:00Z", "sunset": "2026-07-01T00:00:00Z",
+		 "link": "https://example.com/migrate", "message": "Use the order timeline instead."},
+		{"method": "POST", "route": "/orders", "field": "couponCode", "since": "2026-02-01T00:00:00Z"}
+	]`), 0644))
+
+	policy, err := Load(path)
+	require.NoError(t, err)
+
+	notices := policy.For("GET", "/orders/:id/eta")
+	require.Len(t, notices, 1)
+	assert.Equal(t, "Field couponCode of POST /orders is deprecated since 2026-02-01.", policy.For("POST", "/orders")[0].Warning())
+	assert.Equal(t, "GET /orders/:id/eta is deprecated since 2026-01-01 and will be removed on 2026-07-01. "+
+		"Use the order timeline instead. See https://example.com/migrate", notices[0].Warning())
+	assert.Empty(t, policy.For("POST", "/orders/:id/eta"), "notices only cover their method")
+
+	assert.False(t, notices[0].Retired(time.Date(2026, 6, 30, 0, 0, 0, 0, time.UTC)))
+	assert.True(t, notices[0].Retired(time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)))
+	assert.False(t, policy.For("POST", "/orders")[0].Retired(time.Now().AddDate(10, 0, 0)), "fields are never refused")
+
+	empty, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	require.NoError(t, err)
+	assert.Empty(t, empty.For("GET", "/orders/:id/eta"))
+	assert.Empty(t, (*Policy)(nil).For("GET", "/orders/:id/eta"))
+}
+
+func TestNew_Invalid(t *testing.T) {
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := map[string]Notice{
+		"relative route": {Route: "orders", Since: since},
+		"unknown method": {Route: "/orders", Method: "BREW", Since: since},
+		"no since":       {Route: "/orders"},
+		"sunset before":  {Route: "/orders", Since: since, Sunset: since.AddDate(0, -1, 0)},
+		"relative link":  {Route: "/orders", Since: since, Link: "/docs/migrate"},
+	}
+	for name, notice := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := New([]Notice{notice})
+			assert.Error(t, err)
+		})
+	}
+}
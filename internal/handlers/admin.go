@@ -0,0 +1,497 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/cursor"
+	"github.com/ravibandhu/oolio-food-ordering/internal/dashboard"
+	"github.com/ravibandhu/oolio-food-ordering/internal/data"
+	"github.com/ravibandhu/oolio-food-ordering/internal/menuimport"
+	"github.com/ravibandhu/oolio-food-ordering/internal/middleware"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/orders"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
+)
+
+// CouponCheckResponse reports whether a coupon code is currently accepted
+type CouponCheckResponse struct {
+	// The coupon code that was checked
+	// @example HAPPYHRS
+	Code string `json:"code"`
+
+	// Whether the code would be accepted on an order
+	// @example true
+	Valid bool `json:"valid"`
+}
+
+// MenuImportResponse reports the products a menu import added or replaced
+type MenuImportResponse struct {
+	// The format the menu was read as
+	// @example ubereats
+	Format string `json:"format"`
+
+	// IDs of the products added or replaced, sorted
+	// @example ["item-waffle","item-coffee"]
+	Products []string `json:"products"`
+
+	// Items that were left out or only partly imported, and why
+	// @example ["item item-soup: has no image"]
+	Warnings []string `json:"warnings"`
+}
+
+// ProductErrorsResponse lists the invalid products skipped when the catalog
+// was loaded
+type ProductErrorsResponse struct {
+	// Records of the products file that are not valid products, in file order
+	Errors []data.InvalidProduct `json:"errors"`
+}
+
+// AdminHandler handles operator-facing HTTP requests
+type AdminHandler struct {
+	store *data.Store
+}
+
+// NewAdminHandler creates a new AdminHandler instance
+func NewAdminHandler(store *data.Store) *AdminHandler {
+	return &AdminHandler{
+		store: store,
+	}
+}
+
+// @Operation POST /admin/reload
+// @Summary Reload data stores
+// @Description Re-read the products file and coupon directory without restarting the server. Products added or deleted through the API are kept.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/reload [post]
+func (h *AdminHandler) Reload(c *gin.Context) {
+	if err := tenantStore(c.Request.Context(), h.store).Reload(); err != nil {
+		respond.Error(c, apierrors.New(apierrors.ReloadFailed, "Failed to reload stores").
+			AddDetail("error", err.Error()))
+		return
+	}
+
+	respond.JSON(c, http.StatusOK, gin.H{"status": "reloaded"})
+}
+
+// @Operation GET /admin/products/errors
+// @Summary List invalid products
+// @Description List the records of the products file that were skipped as invalid when it was last loaded or reloaded, with why, so they can be fixed. Products are only skipped when PRODUCTS_LENIENT is set; otherwise an invalid product fails the load and the list is empty.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Success 200 {object} ProductErrorsResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/products/errors [get]
+func (h *AdminHandler) ProductErrors(c *gin.Context) {
+	invalid := tenantStore(c.Request.Context(), h.store).InvalidProducts()
+	if invalid == nil {
+		invalid = []data.InvalidProduct{}
+	}
+	respond.JSON(c, http.StatusOK, ProductErrorsResponse{Errors: invalid})
+}
+
+// @Operation GET /admin/products/stats
+// @Summary Get product catalog statistics
+// @Description Get how many products are on the menu in each category and how their prices spread, overall and by category, with when the products file was last loaded and how many of its records were skipped as invalid
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Success 200 {object} data.ProductStats
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/products/stats [get]
+func (h *AdminHandler) ProductStats(c *gin.Context) {
+	respond.JSON(c, http.StatusOK, tenantStore(c.Request.Context(), h.store).ProductStats())
+}
+
+// @Operation GET /admin/products/{id}/price-history
+// @Summary Get a product's price history
+// @Description Get every price a product, and each of its variants, was sold at since the server started, oldest first, with when and in which catalog revision each took effect, so a discount can be checked against the price before it. The first entry holds the prices loaded from the products file; updates, catalog syncs, reloads and restores that change a price add one. Deleted products keep their history. History is kept in memory and starts over when the server restarts.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "Product ID"
+// @Success 200 {object} data.PriceHistory
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/products/{id}/price-history [get]
+func (h *AdminHandler) PriceHistory(c *gin.Context) {
+	productID := c.Param("id")
+	history, err := tenantStore(c.Request.Context(), h.store).PriceHistory(productID)
+	if err != nil {
+		respond.Error(c, apierrors.New(apierrors.NotFound, "Product not found").AddDetail("productId", productID))
+		return
+	}
+	respond.JSON(c, http.StatusOK, history)
+}
+
+// @Operation POST /admin/products/validate
+// @Summary Validate a products file
+// @Description Check a products file, given as the request body, as the server would load it, without loading it: every invalid product is reported, rather than only the first. Files of older schema versions are converted first, as on load. The JSON Schema of the file can be generated with oolioctl schema products-file.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param file body object true "Products file"
+// @Success 200 {object} data.ProductsCheck
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Router /admin/products/validate [post]
+func (h *AdminHandler) ValidateProducts(c *gin.Context) {
+	file := middleware.Bound[json.RawMessage](c)
+	check, err := data.CheckProducts(bytes.NewReader(*file))
+	if err != nil {
+		respond.Error(c, apierrors.New(apierrors.ValidationError, "Products file cannot be read").
+			AddDetail("error", err.Error()))
+		return
+	}
+	respond.JSON(c, http.StatusOK, check)
+}
+
+// @Operation GET /admin/coupons/{code}
+// @Summary Check a coupon code
+// @Description Report whether a coupon code is currently valid
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param code path string true "Coupon code"
+// @Success 200 {object} CouponCheckResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/coupons/{code} [get]
+func (h *AdminHandler) CheckCoupon(c *gin.Context) {
+	code := c.Param("code")
+	respond.JSON(c, http.StatusOK, CouponCheckResponse{
+		Code:  code,
+		Valid: tenantStore(c.Request.Context(), h.store).ValidateCoupon(code),
+	})
+}
+
+// @Operation GET /admin/coupons/stats
+// @Summary Get coupon set statistics
+// @Description Get how many valid coupon codes are served and a rough estimate of the memory they take, how evenly the codes read at the last load spread over the loader's shards, when the coupons were last loaded and how long it took, and how many lookups found a valid code since the server started, for capacity planning
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Success 200 {object} data.CouponStats
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/coupons/stats [get]
+func (h *AdminHandler) CouponStats(c *gin.Context) {
+	respond.JSON(c, http.StatusOK, tenantStore(c.Request.Context(), h.store).CouponStats())
+}
+
+// @Operation GET /admin/backup
+// @Summary Back up the catalog and coupons
+// @Description Download a gzipped tar archive of the current catalog, including products added through the API, and the coupon source files, for restoring on another instance
+// @Tags admin
+// @Produce application/gzip
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Success 200 {file} file
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/backup [get]
+func (h *AdminHandler) Backup(c *gin.Context) {
+	ctx := c.Request.Context()
+	filename := fmt.Sprintf("oolio-backup-%s-%s.tar.gz", tenantID(ctx), time.Now().UTC().Format("20060102T150405Z"))
+	c.Header("Content-Type", "application/gzip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	// The archive is streamed, so a failure part way through can only cut it
+	// short; the truncated gzip stream is rejected on restore
+	if err := tenantStore(ctx, h.store).Backup(c.Writer); err != nil {
+		log.Printf("backup failed: %v", err)
+	}
+}
+
+// @Operation POST /admin/restore
+// @Summary Restore the catalog and coupons
+// @Description Replace the catalog and coupons with those in an archive from GET /admin/backup, sent as the request body. The archive is loaded in full before anything is replaced, and the configured products file and coupon directory are overwritten.
+// @Tags admin
+// @Accept application/gzip
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Success 200 {object} data.Manifest
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/restore [post]
+func (h *AdminHandler) Restore(c *gin.Context) {
+	manifest, err := tenantStore(c.Request.Context(), h.store).Restore(c.Request.Body)
+	if err != nil {
+		if errors.Is(err, data.ErrInvalidBackup) {
+			respond.Error(c, apierrors.New(apierrors.InvalidBackup, "Backup archive cannot be restored").
+				AddDetail("error", err.Error()))
+			return
+		}
+		respond.Error(c, apierrors.New(apierrors.RestoreFailed, "Failed to restore backup").
+			AddDetail("error", err.Error()))
+		return
+	}
+
+	respond.JSON(c, http.StatusOK, manifest)
+}
+
+// @Operation POST /admin/menu/import
+// @Summary Import a menu
+// @Description Add the items of a menu exported from a delivery platform to the catalog, replacing products with the same IDs. Items are mapped to products by the format's rules; items that cannot be mapped, such as items without an image, are left out and reported as warnings. The products are applied in one step, and like products added through the API they are kept until the catalog is restored or the server restarts.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param format query string true "Format of the menu: deliverect or ubereats"
+// @Param menu body object true "Menu in the given format"
+// @Success 200 {object} MenuImportResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/menu/import [post]
+func (h *AdminHandler) ImportMenu(c *gin.Context) {
+	format := c.Query("format")
+	if !slices.Contains(menuimport.Formats, format) {
+		respond.Error(c, apierrors.New(apierrors.InvalidRequest, "Unknown menu format").
+			AddDetail("format", format).
+			AddDetail("allowed", menuimport.Formats))
+		return
+	}
+
+	menu := middleware.Bound[json.RawMessage](c)
+	ctx := c.Request.Context()
+	result, err := menuimport.Parse(format, *menu, tenantLocale(ctx))
+	if err != nil {
+		respond.Error(c, apierrors.New(apierrors.InvalidMenu, "Menu cannot be imported").
+			AddDetail("error", err.Error()))
+		return
+	}
+
+	// Replaced products keep when they were first created
+	store := tenantStore(ctx, h.store)
+	ids := make([]string, len(result.Products))
+	for i, product := range result.Products {
+		if current, err := store.GetProduct(product.ID); err == nil {
+			product.CreatedAt = current.CreatedAt
+		}
+		ids[i] = product.ID
+	}
+	if err := store.ApplyProducts(result.Products, nil); err != nil {
+		respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to import menu").
+			AddDetail("error", err.Error()))
+		return
+	}
+
+	warnings := result.Warnings
+	if warnings == nil {
+		warnings = []string{}
+	}
+	respond.JSON(c, http.StatusOK, MenuImportResponse{
+		Format:   format,
+		Products: ids,
+		Warnings: warnings,
+	})
+}
+
+// @Operation GET /admin/invoices
+// @Summary Check the invoice sequence
+// @Description Report how many invoice numbers the restaurant has issued, the last and next numbers, and any numbers missing from the journal they are recorded in. Numbers are only issued to orders that are placed, so the sequence has no gaps unless the journal was edited or restored from an older copy.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Success 200 {object} invoices.Status
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/invoices [get]
+func (h *AdminHandler) InvoiceStatus(c *gin.Context) {
+	respond.JSON(c, http.StatusOK, tenantInvoices(c.Request.Context()).Status())
+}
+
+// @Operation GET /admin/reports/payments
+// @Summary Report takings by payment method
+// @Description Get the number and total amount of the orders the restaurant took with each payment method, and overall, optionally limited to a range of days (UTC). Orders placed without a payment are reported as unspecified. Totals are kept in memory and rebuilt from the order event log on restart; without one they start over.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param from query string false "First day to include, as YYYY-MM-DD"
+// @Param to query string false "Last day to include, as YYYY-MM-DD"
+// @Success 200 {object} payments.Report
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/reports/payments [get]
+func (h *AdminHandler) PaymentReport(c *gin.Context) {
+	from, okFrom := parseDate(c.Query("from"))
+	to, okTo := parseDate(c.Query("to"))
+	if !okFrom || !okTo {
+		respond.Error(c, apierrors.New(apierrors.InvalidRequest, "Invalid date range").
+			AddDetail("error", "from and to must be dates formatted as YYYY-MM-DD"))
+		return
+	}
+
+	respond.JSON(c, http.StatusOK, tenantPayments(c.Request.Context()).Report(from, to))
+}
+
+// OrderListResponse is a page of the orders a restaurant kept
+type OrderListResponse struct {
+	// Orders following the previous page in the requested sort, as their
+	// events leave them
+	Orders []*models.Order `json:"orders"`
+
+	// Cursor of the next page; empty when no orders follow
+	// @example eyJsIjoib3JkZXJzIiwiYSI6NDJ9
+	Next string `json:"next,omitempty"`
+}
+
+// defaultOrderLimit is how many orders a page holds unless limit is given
+const defaultOrderLimit = 50
+
+// @Operation GET /admin/orders
+// @Summary List orders
+// @Description List the orders the restaurant kept, oldest first unless sort is given, a page at a time. Pass the next cursor of a page to get the one after it; pages stay stable while orders are placed.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param sort query string false "Comma-separated fields to sort by, each prefixed with - for largest first: created_at, total_amount or status; ties are broken by ID" default(created_at)
+// @Param limit query int false "Most orders to return, up to 1000" default(50)
+// @Param cursor query string false "The next cursor of the previous page"
+// @Success 200 {object} OrderListResponse
+// @Header 200 {string} X-Next-Cursor "Cursor of the next page, also given as next, when more orders follow"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/orders [get]
+func (h *AdminHandler) ListOrders(c *gin.Context) {
+	order, err := parseSort(c.Request.URL.Query(), orders.SortFields, defaultOrderSort)
+	if err != nil {
+		respond.Error(c, apierrors.New(apierrors.InvalidRequest, "Invalid sort").
+			AddDetail("error", err.Error()))
+		return
+	}
+	listing := sortedListing(orderListing, order)
+	pg, err := parsePage[*models.Order](c.Request.URL.Query(), listing, defaultOrderLimit)
+	if err != nil {
+		respond.Error(c, apierrors.New(apierrors.InvalidRequest, "Invalid page").
+			AddDetail("error", err.Error()))
+		return
+	}
+
+	resp := OrderListResponse{Orders: []*models.Order{}}
+	if store := tenantOrders(c.Request.Context()); store != nil {
+		var more bool
+		if resp.Orders, more = store.List(order, pg.after, pg.limit); more {
+			resp.Next = cursor.Encode(listing, orderSortKey(resp.Orders[len(resp.Orders)-1]))
+			c.Header(NextCursorHeader, resp.Next)
+		}
+		for i, listed := range resp.Orders {
+			resp.Orders[i] = withOrderLinks(c.Request.Context(), listed)
+		}
+	}
+	respond.JSON(c, http.StatusOK, resp)
+}
+
+// orderSortKey returns the fields of an order listings can be sorted by,
+// which is all a cursor needs to find its place in any sort
+func orderSortKey(order *models.Order) *models.Order {
+	return &models.Order{
+		ID:          order.ID,
+		CreatedAt:   order.CreatedAt,
+		TotalAmount: order.TotalAmount,
+		Status:      order.Status,
+	}
+}
+
+// @Operation GET /admin/orders/{id}
+// @Summary Get an order's history
+// @Description Get an order as it stands, rebuilt from the events recorded for it, along with every event: the order as placed and each status it moved to since.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "Order ID"
+// @Success 200 {object} orders.History
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/orders/{id} [get]
+func (h *AdminHandler) OrderHistory(c *gin.Context) {
+	orderID := c.Param("id")
+	var history orders.History
+	var ok bool
+	if store := tenantOrders(c.Request.Context()); store != nil {
+		history, ok = store.History(orderID)
+	}
+	if !ok {
+		respond.Error(c, apierrors.New(apierrors.NotFound, "Order not found").AddDetail("id", orderID))
+		return
+	}
+
+	history.Order = withOrderLinks(c.Request.Context(), history.Order)
+	respond.JSON(c, http.StatusOK, history)
+}
+
+// @Operation GET /admin/dashboard/orders
+// @Summary Get the order dashboard
+// @Description Get the orders placed in each of the last hours, how many orders the kitchen has yet to finish, and the average time from being placed to being ready. Figures are projected from the order events in the background, so they may be a few seconds behind.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param hours query int false "Number of hours to report, up to 168" default(24)
+// @Success 200 {object} dashboard.Summary
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/dashboard/orders [get]
+func (h *AdminHandler) OrderDashboard(c *gin.Context) {
+	hours := 24
+	if raw := c.Query("hours"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > dashboard.MaxHours {
+			respond.Error(c, apierrors.New(apierrors.InvalidRequest, "Invalid hours").
+				AddDetail("error", fmt.Sprintf("hours must be a whole number from 1 to %d", dashboard.MaxHours)))
+			return
+		}
+		hours = n
+	}
+
+	respond.JSON(c, http.StatusOK, tenantDashboard(c.Request.Context()).Summary(hours))
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/data"
+	"github.com/ravibandhu/oolio-food-ordering/internal/middleware"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Setup test data
+	_, _, cfg, cleanup := setupTestData(t)
+	defer cleanup()
+
+	// Create store
+	ctx := context.Background()
+	store, err := data.NewStore(ctx, cfg)
+	require.NoError(t, err)
+
+	// Create handler and routes
+	handler := NewAdminHandler(store)
+	engine := gin.New()
+	engine.POST("/admin/reload", handler.Reload)
+	engine.GET("/admin/coupons/:code", handler.CheckCoupon)
+
+	t.Run("reload", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/admin/reload", nil)
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Len(t, store.GetAllProducts(), 2)
+	})
+
+	t.Run("check unknown coupon", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/admin/coupons/UNKNOWN1", nil)
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var got CouponCheckResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+		assert.Equal(t, "UNKNOWN1", got.Code)
+		assert.False(t, got.Valid)
+	})
+}
+
+func TestAdminHandler_BackupRestore(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Setup test data
+	_, _, cfg, cleanup := setupTestData(t)
+	defer cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), cfg)
+	require.NoError(t, err)
+	defer store.Close()
+
+	handler := NewAdminHandler(store)
+	engine := gin.New()
+	engine.GET("/admin/backup", handler.Backup)
+	engine.POST("/admin/restore", handler.Restore)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/backup", nil)
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/gzip", rec.Header().Get("Content-Type"))
+	assert.Regexp(t, `^attachment; filename="oolio-backup-default-\d{8}T\d{6}Z\.tar\.gz"$`, rec.Header().Get("Content-Disposition"))
+	archive := rec.Body.Bytes()
+
+	t.Run("restore", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/admin/restore", bytes.NewReader(archive))
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var got data.Manifest
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+		assert.Equal(t, data.BackupVersion, got.Version)
+		assert.Equal(t, 2, got.Products)
+	})
+
+	t.Run("invalid archive", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/admin/restore", bytes.NewReader(archive[:len(archive)/2]))
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		var errResp models.ErrorResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&errResp))
+		assert.Equal(t, "INVALID_BACKUP", errResp.Code)
+	})
+}
+
+func TestAdminHandler_ImportMenu(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Setup test data
+	_, _, cfg, cleanup := setupTestData(t)
+	defer cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), cfg)
+	require.NoError(t, err)
+	defer store.Close()
+
+	handler := NewAdminHandler(store)
+	engine := gin.New()
+	engine.POST("/admin/menu/import", middleware.Bind[json.RawMessage](), handler.ImportMenu)
+
+	menu := `{"categories": [{"id": "sides", "title": {"translations": {"en": "Sides"}}, "entities": [{"id": "fries"}, {"id": "salad"}]}],
+		"items": [
+			{"id": "fries", "title": {"translations": {"en": "Fries"}}, "image_url": "https://example.com/fries.jpg", "price_info": {"price": 450}},
+			{"id": "salad", "title": {"translations": {"en": "Salad"}}, "price_info": {"price": 500}}
+		]}`
+
+	t.Run("import", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/admin/menu/import?format=ubereats", bytes.NewBufferString(menu))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var got MenuImportResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+		assert.Equal(t, []string{"fries"}, got.Products)
+		assert.Equal(t, []string{"item salad: has no image"}, got.Warnings)
+
+		product, err := store.GetProduct("fries")
+		require.NoError(t, err)
+		assert.Equal(t, "Sides", product.Category)
+		assert.Equal(t, 4.5, product.Price)
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/admin/menu/import?format=toast", bytes.NewBufferString(menu))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("no importable items", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/admin/menu/import?format=deliverect", bytes.NewBufferString(`{"categories": [], "products": []}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		var errResp models.ErrorResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&errResp))
+		assert.Equal(t, "INVALID_MENU", errResp.Code)
+	})
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apikeys"
+	"github.com/ravibandhu/oolio-food-ordering/internal/middleware"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
+	"github.com/ravibandhu/oolio-food-ordering/internal/usage"
+)
+
+// APIKeyHandler handles operator-facing HTTP requests about API keys
+type APIKeyHandler struct {
+	keys  *apikeys.Store
+	usage *usage.Tracker
+}
+
+// NewAPIKeyHandler creates a new APIKeyHandler instance
+func NewAPIKeyHandler(keys *apikeys.Store, tracker *usage.Tracker) *APIKeyHandler {
+	return &APIKeyHandler{
+		keys:  keys,
+		usage: tracker,
+	}
+}
+
+// @Operation GET /admin/apikeys
+// @Summary List API keys
+// @Description Get every API key, oldest first, with its scopes and status. Secrets are only stored hashed and are never returned.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Success 200 {array} apikeys.Key
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/apikeys [get]
+func (h *APIKeyHandler) ListKeys(c *gin.Context) {
+	respond.JSON(c, http.StatusOK, h.keys.List())
+}
+
+// @Operation POST /admin/apikeys
+// @Summary Create an API key
+// @Description Create an API key holding the roles in its scopes, optionally expiring at a given time. The secret is only returned in this response.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param key body apikeys.KeyRequest true "Key to create"
+// @Success 201 {object} apikeys.Secret
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/apikeys [post]
+func (h *APIKeyHandler) CreateKey(c *gin.Context) {
+	req := middleware.Bound[apikeys.KeyRequest](c)
+
+	key, err := h.keys.Create(*req)
+	switch {
+	case errors.Is(err, apikeys.ErrInvalidKey):
+		respond.Error(c, apierrors.New(apierrors.ValidationError, "Invalid API key").
+			AddDetail("error", err.Error()))
+		return
+	case err != nil:
+		respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to create API key").
+			AddDetail("error", err.Error()))
+		return
+	}
+
+	respond.JSON(c, http.StatusCreated, key)
+}
+
+// @Operation GET /admin/apikeys/{id}
+// @Summary Get an API key
+// @Description Get an API key with its scopes and status
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "API key ID"
+// @Success 200 {object} apikeys.Key
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/apikeys/{id} [get]
+func (h *APIKeyHandler) GetKey(c *gin.Context) {
+	id := c.Param("id")
+	key, err := h.keys.Get(id)
+	if errors.Is(err, apikeys.ErrNotFound) {
+		respond.Error(c, apierrors.New(apierrors.NotFound, "API key not found").AddDetail("id", id))
+		return
+	}
+
+	respond.JSON(c, http.StatusOK, key)
+}
+
+// @Operation POST /admin/apikeys/{id}/rotate
+// @Summary Rotate an API key
+// @Description Replace the secret of an API key, keeping its ID, scopes and expiry. The old secret is refused from then on, and the new one is only returned in this response.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "API key ID"
+// @Success 200 {object} apikeys.Secret
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/apikeys/{id}/rotate [post]
+func (h *APIKeyHandler) RotateKey(c *gin.Context) {
+	id := c.Param("id")
+	key, err := h.keys.Rotate(id)
+	switch {
+	case errors.Is(err, apikeys.ErrNotFound):
+		respond.Error(c, apierrors.New(apierrors.NotFound, "API key not found").AddDetail("id", id))
+		return
+	case errors.Is(err, apikeys.ErrDisabled):
+		respond.Error(c, apierrors.New(apierrors.KeyDisabled, "A disabled API key cannot be rotated").AddDetail("id", id))
+		return
+	case err != nil:
+		respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to rotate API key").
+			AddDetail("error", err.Error()))
+		return
+	}
+
+	respond.JSON(c, http.StatusOK, key)
+}
+
+// @Operation POST /admin/apikeys/{id}/disable
+// @Summary Disable an API key
+// @Description Refuse an API key from now on. Disabled keys stay listed, and their usage stays available.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "API key ID"
+// @Success 200 {object} apikeys.Key
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/apikeys/{id}/disable [post]
+func (h *APIKeyHandler) DisableKey(c *gin.Context) {
+	id := c.Param("id")
+	key, err := h.keys.Disable(id)
+	switch {
+	case errors.Is(err, apikeys.ErrNotFound):
+		respond.Error(c, apierrors.New(apierrors.NotFound, "API key not found").AddDetail("id", id))
+		return
+	case err != nil:
+		respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to disable API key").
+			AddDetail("error", err.Error()))
+		return
+	}
+
+	respond.JSON(c, http.StatusOK, key)
+}
+
+// @Operation GET /admin/apikeys/{id}/usage
+// @Summary Get the usage of an API key
+// @Description Get the requests made with an API key, how many got a 4xx or 5xx status, and how many orders it placed, in total and by day (UTC), for monitoring and billing partner integrations
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "API key ID"
+// @Param from query string false "First day to include, as YYYY-MM-DD"
+// @Param to query string false "Last day to include, as YYYY-MM-DD"
+// @Success 200 {object} usage.Report
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/apikeys/{id}/usage [get]
+func (h *APIKeyHandler) GetUsage(c *gin.Context) {
+	from, okFrom := parseDate(c.Query("from"))
+	to, okTo := parseDate(c.Query("to"))
+	if !okFrom || !okTo {
+		respond.Error(c, apierrors.New(apierrors.InvalidRequest, "Invalid date range").
+			AddDetail("error", "from and to must be dates formatted as YYYY-MM-DD"))
+		return
+	}
+
+	id := c.Param("id")
+	report, err := h.usage.Report(id, from, to)
+	if errors.Is(err, usage.ErrNotFound) {
+		respond.Error(c, apierrors.New(apierrors.NotFound, "No usage recorded for API key").AddDetail("id", id))
+		return
+	}
+
+	respond.JSON(c, http.StatusOK, report)
+}
+
+// parseDate parses an optional YYYY-MM-DD query parameter, returning the
+// zero time when it is empty
+func parseDate(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, true
+	}
+	date, err := time.Parse(time.DateOnly, value)
+	return date, err == nil
+}
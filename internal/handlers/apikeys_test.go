@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apikeys"
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/middleware"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/usage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyHandler_GetUsage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tracker, err := usage.Load("")
+	require.NoError(t, err)
+	tracker.Record("9f86d081884c", http.StatusCreated, true)
+	tracker.Record("9f86d081884c", http.StatusBadRequest, false)
+
+	keys, err := apikeys.Load(config.Auth{})
+	require.NoError(t, err)
+
+	handler := NewAPIKeyHandler(keys, tracker)
+	engine := gin.New()
+	engine.GET("/admin/apikeys/:id/usage", handler.GetUsage)
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedCode   string
+		expectedTotal  usage.Counts
+	}{
+		{name: "all time", path: "/admin/apikeys/9f86d081884c/usage", expectedStatus: http.StatusOK, expectedTotal: usage.Counts{Requests: 2, ClientErrors: 1, Orders: 1}},
+		{name: "range without usage", path: "/admin/apikeys/9f86d081884c/usage?from=2000-01-01&to=2000-01-31", expectedStatus: http.StatusOK},
+		{name: "invalid date", path: "/admin/apikeys/9f86d081884c/usage?from=yesterday", expectedStatus: http.StatusBadRequest, expectedCode: "INVALID_REQUEST"},
+		{name: "unknown key", path: "/admin/apikeys/000000000000/usage", expectedStatus: http.StatusNotFound, expectedCode: "NOT_FOUND"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, tt.expectedStatus, rec.Code, "body: %s", rec.Body)
+			if tt.expectedCode != "" {
+				var errResp models.ErrorResponse
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&errResp))
+				assert.Equal(t, tt.expectedCode, errResp.Code)
+				return
+			}
+			var report usage.Report
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+			assert.Equal(t, "9f86d081884c", report.KeyID)
+			assert.Equal(t, tt.expectedTotal, report.Total)
+		})
+	}
+}
+
+func TestAPIKeyHandler_Lifecycle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	keys, err := apikeys.Load(config.Auth{})
+	require.NoError(t, err)
+	tracker, err := usage.Load("")
+	require.NoError(t, err)
+
+	handler := NewAPIKeyHandler(keys, tracker)
+	engine := gin.New()
+	engine.GET("/admin/apikeys", handler.ListKeys)
+	engine.POST("/admin/apikeys", middleware.Bind[apikeys.KeyRequest](), handler.CreateKey)
+	engine.GET("/admin/apikeys/:id", handler.GetKey)
+	engine.POST("/admin/apikeys/:id/rotate", handler.RotateKey)
+	engine.POST("/admin/apikeys/:id/disable", handler.DisableKey)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		return rec
+	}
+	errorCode := func(rec *httptest.ResponseRecorder) string {
+		var errResp models.ErrorResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&errResp))
+		return errResp.Code
+	}
+
+	// Invalid keys are refused
+	rec := send(http.MethodPost, "/admin/apikeys", `{"name": "Partner", "scopes": ["chef"]}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, "VALIDATION_ERROR", errorCode(rec))
+	past := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	rec = send(http.MethodPost, "/admin/apikeys", `{"name": "Partner", "scopes": ["kitchen"], "expires_at": "`+past+`"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	rec = send(http.MethodPost, "/admin/apikeys", `{"scopes": ["kitchen"]}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	// The secret is returned on creation only
+	rec = send(http.MethodPost, "/admin/apikeys", `{"name": "Partner", "scopes": ["kitchen"]}`)
+	require.Equal(t, http.StatusCreated, rec.Code, "body: %s", rec.Body)
+	var created apikeys.Secret
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&created))
+	assert.NotEmpty(t, created.Secret)
+	assert.Equal(t, apikeys.StatusActive, created.Status)
+	assert.True(t, strings.HasPrefix(created.Secret, created.Prefix))
+
+	rec = send(http.MethodGet, "/admin/apikeys", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), created.Secret)
+	var listed []apikeys.Key
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&listed))
+	require.Len(t, listed, 1)
+	assert.Equal(t, created.ID, listed[0].ID)
+
+	rec = send(http.MethodPost, "/admin/apikeys/"+created.ID+"/rotate", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var rotated apikeys.Secret
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&rotated))
+	assert.Equal(t, created.ID, rotated.ID)
+	assert.NotEqual(t, created.Secret, rotated.Secret)
+
+	rec = send(http.MethodPost, "/admin/apikeys/"+created.ID+"/disable", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	rec = send(http.MethodGet, "/admin/apikeys/"+created.ID, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var key apikeys.Key
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&key))
+	assert.Equal(t, apikeys.StatusDisabled, key.Status)
+
+	rec = send(http.MethodPost, "/admin/apikeys/"+created.ID+"/rotate", "")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, "KEY_DISABLED", errorCode(rec))
+
+	for _, path := range []string{"/admin/apikeys/missing", "/admin/apikeys/missing/rotate", "/admin/apikeys/missing/disable"} {
+		method := http.MethodPost
+		if path == "/admin/apikeys/missing" {
+			method = http.MethodGet
+		}
+		rec = send(method, path, "")
+		assert.Equal(t, http.StatusNotFound, rec.Code, path)
+	}
+}
@@ -0,0 +1,473 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/auth"
+	"github.com/ravibandhu/oolio-food-ordering/internal/middleware"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
+)
+
+// SignInRequest represents the request body for signing in with an identity
+// provider
+type SignInRequest struct {
+	// ID token from the provider's sign-in SDK
+	// @required
+	// @example eyJhbGciOiJSUzI1NiIsImtpZCI6IjEifQ...
+	IDToken string `json:"idToken" validate:"required"`
+}
+
+// RefreshRequest represents the request body for renewing a session
+type RefreshRequest struct {
+	// Refresh token from the last sign-in or refresh
+	// @required
+	// @example 3q2-7wEAAAB4oR8sY0n1dF9Lz8e0vL3nQnq0m5kH2bA
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+// RegisterRequest represents the request body for registering with an email
+// address and password
+type RegisterRequest struct {
+	// Email address to sign in with
+	// @required
+	// @example jane@example.com
+	Email string `json:"email" validate:"required,email,max=254"`
+
+	// Password of 8 to 72 characters
+	// @required
+	// @example correct horse battery staple
+	Password string `json:"password" validate:"required,min=8,max=72"`
+
+	// The customer's name
+	// @example Jane Doe
+	Name string `json:"name" validate:"omitempty,max=200"`
+}
+
+// LoginRequest represents the request body for signing in with an email
+// address and password
+type LoginRequest struct {
+	// Email address the customer registered with
+	// @required
+	// @example jane@example.com
+	Email string `json:"email" validate:"required"`
+
+	// The customer's password
+	// @required
+	// @example correct horse battery staple
+	Password string `json:"password" validate:"required"`
+}
+
+// CodeRequest represents the request body carrying a code emailed to a
+// customer, to unlock their account or verify their address
+type CodeRequest struct {
+	// Code from the email
+	// @required
+	// @example 3q2-7wEAAAB4oR8sY0n1dF9Lz8e0vL3nQnq0m5kH2bA
+	Code string `json:"code" validate:"required"`
+}
+
+// EmailRequest represents the request body for asking for an email with a
+// code, to unlock an account or reset its password
+type EmailRequest struct {
+	// Email address of the account
+	// @required
+	// @example jane@example.com
+	Email string `json:"email" validate:"required"`
+}
+
+// ResetPasswordRequest represents the request body for setting a new
+// password
+type ResetPasswordRequest struct {
+	// Code from the password reset email
+	// @required
+	// @example 3q2-7wEAAAB4oR8sY0n1dF9Lz8e0vL3nQnq0m5kH2bA
+	Code string `json:"code" validate:"required"`
+
+	// New password of 8 to 72 characters
+	// @required
+	// @example correct horse battery staple
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+// AuthHandler handles HTTP requests for customer sign-in and sessions
+type AuthHandler struct {
+	accounts *auth.Accounts
+}
+
+// NewAuthHandler creates a new AuthHandler instance
+func NewAuthHandler(accounts *auth.Accounts) *AuthHandler {
+	return &AuthHandler{
+		accounts: accounts,
+	}
+}
+
+// @Operation POST /auth/oidc/{provider}
+// @Summary Sign in with an identity provider
+// @Description Exchange an ID token from Google or Apple sign-in for a session token. Customers are created on first sign-in, and an identity is linked to the customer with the same verified email address.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param provider path string true "Identity provider: google or apple"
+// @Param credentials body SignInRequest true "ID token to exchange"
+// @Success 200 {object} auth.Session
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /auth/oidc/{provider} [post]
+func (h *AuthHandler) SignIn(c *gin.Context) {
+	provider := c.Param("provider")
+
+	req := middleware.Bound[SignInRequest](c)
+
+	session, err := h.accounts.SignIn(c.Request.Context(), provider, req.IDToken)
+	switch {
+	case errors.Is(err, auth.ErrUnknownProvider):
+		respond.Error(c, apierrors.New(apierrors.NotFound, "Identity provider not found").AddDetail("provider", provider))
+		return
+	case errors.Is(err, auth.ErrInvalidToken):
+		respond.Error(c, apierrors.New(apierrors.InvalidToken, "Invalid or expired ID token").
+			AddDetail("error", err.Error()))
+		return
+	case errors.Is(err, auth.ErrProviderUnavailable):
+		respond.Error(c, apierrors.New(apierrors.ProviderUnavailable, "The identity provider could not be reached").
+			AddDetail("provider", provider))
+		return
+	case err != nil:
+		respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to sign in").
+			AddDetail("error", err.Error()))
+		return
+	}
+
+	respond.JSON(c, http.StatusOK, session)
+}
+
+// @Operation POST /auth/register
+// @Summary Register with an email address
+// @Description Create a customer who signs in with an email address and password, and start a session for them. The customer is emailed a code that verifies their address.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param account body RegisterRequest true "Email address, password and name"
+// @Success 201 {object} auth.Session
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /auth/register [post]
+func (h *AuthHandler) Register(c *gin.Context) {
+	req := middleware.Bound[RegisterRequest](c)
+
+	session, err := h.accounts.Register(c.Request.Context(), req.Email, req.Password, req.Name)
+	switch {
+	case errors.Is(err, auth.ErrAccountExists):
+		respond.Error(c, apierrors.New(apierrors.AccountExists, "An account with this email address already exists"))
+		return
+	case err != nil:
+		respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to register").
+			AddDetail("error", err.Error()))
+		return
+	}
+
+	respond.JSON(c, http.StatusCreated, session)
+}
+
+// @Operation POST /auth/login
+// @Summary Sign in with an email address
+// @Description Exchange an email address and password for a session token. Too many wrong passwords in a row lock the account and email the customer a code to unlock it.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body LoginRequest true "Email address and password"
+// @Success 200 {object} auth.Session
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 423 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /auth/login [post]
+func (h *AuthHandler) Login(c *gin.Context) {
+	req := middleware.Bound[LoginRequest](c)
+
+	session, err := h.accounts.Login(c.Request.Context(), req.Email, req.Password)
+	switch {
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		respond.Error(c, apierrors.New(apierrors.InvalidCredentials, "Invalid email address or password"))
+		return
+	case errors.Is(err, auth.ErrAccountLocked):
+		respond.Error(c, apierrors.New(apierrors.AccountLocked, "The account is locked after too many wrong passwords; use the code emailed to unlock it"))
+		return
+	case err != nil:
+		respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to sign in").
+			AddDetail("error", err.Error()))
+		return
+	}
+
+	respond.JSON(c, http.StatusOK, session)
+}
+
+// @Operation POST /auth/unlock
+// @Summary Unlock an account
+// @Description Unlock an account locked after too many wrong passwords, with the code emailed to the customer
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param code body CodeRequest true "Code from the unlock email"
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /auth/unlock [post]
+func (h *AuthHandler) Unlock(c *gin.Context) {
+	req := middleware.Bound[CodeRequest](c)
+
+	err := h.accounts.Unlock(req.Code)
+	switch {
+	case errors.Is(err, auth.ErrInvalidToken):
+		respond.Error(c, apierrors.New(apierrors.InvalidToken, "Invalid or expired unlock code"))
+		return
+	case err != nil:
+		respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to unlock account").
+			AddDetail("error", err.Error()))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Operation POST /auth/unlock/email
+// @Summary Resend the unlock email
+// @Description Email a new unlock code to the address of a locked account. The response is the same whether or not a locked account uses the address.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param account body EmailRequest true "Email address of the locked account"
+// @Success 202
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /auth/unlock/email [post]
+func (h *AuthHandler) SendUnlockEmail(c *gin.Context) {
+	req := middleware.Bound[EmailRequest](c)
+
+	if err := h.accounts.RequestUnlock(c.Request.Context(), req.Email); err != nil {
+		respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to send unlock email").
+			AddDetail("error", err.Error()))
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// @Operation POST /auth/verify-email
+// @Summary Verify an email address
+// @Description Mark the address a verification code was emailed to as verified
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param code body CodeRequest true "Code from the verification email"
+// @Success 200 {object} auth.Customer
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /auth/verify-email [post]
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	req := middleware.Bound[CodeRequest](c)
+
+	customer, err := h.accounts.VerifyEmail(req.Code)
+	switch {
+	case errors.Is(err, auth.ErrInvalidToken):
+		respond.Error(c, apierrors.New(apierrors.InvalidToken, "Invalid or expired verification code"))
+		return
+	case err != nil:
+		respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to verify email address").
+			AddDetail("error", err.Error()))
+		return
+	}
+
+	respond.JSON(c, http.StatusOK, customer)
+}
+
+// @Operation POST /auth/verify-email/resend
+// @Summary Resend the verification email
+// @Description Email the signed-in customer a new code that verifies their address. Nothing is sent when the address is already verified.
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 202
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /auth/verify-email/resend [post]
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	customer, ok := auth.FromContext(c.Request.Context())
+	if !ok {
+		respond.Error(c, apierrors.New(apierrors.Unauthorized, "Missing session token"))
+		return
+	}
+
+	if err := h.accounts.SendVerification(c.Request.Context(), customer); err != nil {
+		respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to send verification email").
+			AddDetail("error", err.Error()))
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// @Operation POST /auth/password/forgot
+// @Summary Ask for a password reset
+// @Description Email a code that sets a new password to the address of an account. The response is the same whether or not an account uses the address.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param account body EmailRequest true "Email address of the account"
+// @Success 202
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /auth/password/forgot [post]
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	req := middleware.Bound[EmailRequest](c)
+
+	if err := h.accounts.RequestPasswordReset(c.Request.Context(), req.Email); err != nil {
+		respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to send password reset email").
+			AddDetail("error", err.Error()))
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// @Operation POST /auth/password/reset
+// @Summary Reset a password
+// @Description Set a new password with the code from a password reset email. The account is unlocked, its address verified, and its sessions ended.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param reset body ResetPasswordRequest true "Code from the email and the new password"
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /auth/password/reset [post]
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	req := middleware.Bound[ResetPasswordRequest](c)
+
+	err := h.accounts.ResetPassword(req.Code, req.Password)
+	switch {
+	case errors.Is(err, auth.ErrInvalidToken):
+		respond.Error(c, apierrors.New(apierrors.InvalidToken, "Invalid or expired password reset code"))
+		return
+	case err != nil:
+		respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to reset password").
+			AddDetail("error", err.Error()))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Operation POST /auth/refresh
+// @Summary Renew a session
+// @Description Exchange a refresh token for a new session token and refresh token. Each refresh token can be used once; using one again ends its session.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body RefreshRequest true "Refresh token to exchange"
+// @Success 200 {object} auth.Session
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /auth/refresh [post]
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	req := middleware.Bound[RefreshRequest](c)
+
+	session, err := h.accounts.Refresh(req.RefreshToken)
+	switch {
+	case errors.Is(err, auth.ErrInvalidToken):
+		respond.Error(c, apierrors.New(apierrors.InvalidToken, "Invalid or expired refresh token"))
+		return
+	case err != nil:
+		respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to refresh session").
+			AddDetail("error", err.Error()))
+		return
+	}
+
+	respond.JSON(c, http.StatusOK, session)
+}
+
+// @Operation POST /auth/logout
+// @Summary Sign out
+// @Description End the session the token was issued in. Its session tokens and refresh token are no longer accepted.
+// @Tags auth
+// @Security BearerAuth
+// @Success 204
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	claims, ok := auth.SessionFromContext(c.Request.Context())
+	if !ok {
+		respond.Error(c, apierrors.New(apierrors.Unauthorized, "Missing session token"))
+		return
+	}
+
+	h.accounts.Logout(claims)
+	c.Status(http.StatusNoContent)
+}
+
+// @Operation GET /auth/me
+// @Summary Get the signed-in customer
+// @Description Get the profile of the customer the session token was issued to
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} auth.Customer
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /auth/me [get]
+func (h *AuthHandler) Me(c *gin.Context) {
+	customer, ok := auth.FromContext(c.Request.Context())
+	if !ok {
+		respond.Error(c, apierrors.New(apierrors.Unauthorized, "Missing session token"))
+		return
+	}
+
+	respond.JSON(c, http.StatusOK, customer)
+}
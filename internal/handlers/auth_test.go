@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/auth"
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/middleware"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthHandler_SignIn(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	idp := testutil.NewIdentityProvider(t)
+	unreachable := testutil.NewIdentityProvider(t)
+	unreachable.Close()
+	accounts, err := auth.Load(config.Auth{SessionTTL: time.Hour, RefreshTTL: time.Hour, Google: idp.Config(), Apple: unreachable.Config()}, nil)
+	require.NoError(t, err)
+
+	handler := NewAuthHandler(accounts)
+	engine := gin.New()
+	engine.POST("/auth/oidc/:provider", middleware.Bind[SignInRequest](), handler.SignIn)
+
+	tests := []struct {
+		name           string
+		provider       string
+		body           string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{name: "valid token", provider: "google", body: `{"idToken":"` + idp.Mint(t, idp.Claims("user-1", nil)) + `"}`, expectedStatus: http.StatusOK},
+		{name: "expired token", provider: "google", body: `{"idToken":"` + idp.Mint(t, idp.Claims("user-1", map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()})) + `"}`, expectedStatus: http.StatusUnauthorized, expectedCode: "INVALID_TOKEN"},
+		{name: "unknown provider", provider: "facebook", body: `{"idToken":"token"}`, expectedStatus: http.StatusNotFound, expectedCode: "NOT_FOUND"},
+		{name: "provider unreachable", provider: "apple", body: `{"idToken":"` + unreachable.Mint(t, unreachable.Claims("user-1", nil)) + `"}`, expectedStatus: http.StatusServiceUnavailable, expectedCode: "PROVIDER_UNAVAILABLE"},
+		{name: "missing token", provider: "google", body: `{}`, expectedStatus: http.StatusUnprocessableEntity, expectedCode: "VALIDATION_ERROR"},
+		{name: "malformed body", provider: "google", body: `{"idToken":`, expectedStatus: http.StatusBadRequest, expectedCode: "INVALID_REQUEST"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/auth/oidc/"+tt.provider, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code, "body: %s", rec.Body)
+			if tt.expectedStatus == http.StatusOK {
+				var session auth.Session
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&session))
+				assert.NotEmpty(t, session.AccessToken)
+				assert.Equal(t, "jane@example.com", session.Customer.Email)
+				return
+			}
+			var errResp models.ErrorResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&errResp))
+			assert.Equal(t, tt.expectedCode, errResp.Code)
+		})
+	}
+}
+
+func TestAuthHandler_Me(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := NewAuthHandler(nil)
+	customer := &auth.Customer{ID: "customer-1", Name: "Jane"}
+	engine := gin.New()
+	engine.GET("/auth/me", func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "" {
+			c.Request = c.Request.WithContext(auth.NewContext(c.Request.Context(), customer))
+		}
+	}, handler.Me)
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/me", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var got auth.Customer
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+	assert.Equal(t, "customer-1", got.ID)
+
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth/me", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestAuthHandler_RefreshAndLogout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	accounts, err := auth.Load(config.Auth{SessionTTL: time.Hour, RefreshTTL: time.Hour}, nil)
+	require.NoError(t, err)
+	customer, err := accounts.Customers.SignIn(&auth.Profile{Identity: auth.Identity{Provider: auth.ProviderGoogle, Subject: "g-1"}})
+	require.NoError(t, err)
+	session, err := accounts.Sessions.Issue(customer)
+	require.NoError(t, err)
+
+	handler := NewAuthHandler(accounts)
+	engine := gin.New()
+	engine.POST("/auth/refresh", middleware.Bind[RefreshRequest](), handler.Refresh)
+	engine.POST("/auth/logout", func(c *gin.Context) {
+		if _, claims, err := accounts.Authenticate(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")); err == nil {
+			c.Request = c.Request.WithContext(auth.WithSession(c.Request.Context(), claims))
+		}
+	}, handler.Logout)
+
+	refresh := func(token string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/auth/refresh", strings.NewReader(`{"refreshToken":"`+token+`"}`)))
+		return rec
+	}
+
+	rec := refresh(session.RefreshToken)
+	require.Equal(t, http.StatusOK, rec.Code, "body: %s", rec.Body)
+	var refreshed auth.Session
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&refreshed))
+	assert.Equal(t, customer.ID, refreshed.Customer.ID)
+
+	rec = refresh("unknown")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), "INVALID_TOKEN")
+
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/auth/refresh", strings.NewReader(`{}`)))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
+	req.Header.Set("Authorization", "Bearer "+refreshed.AccessToken)
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, http.StatusUnauthorized, refresh(refreshed.RefreshToken).Code)
+
+	// Logging out again with the ended session is refused
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestAuthHandler_Passwords(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mailbox := &testutil.Mailbox{}
+	accounts, err := auth.Load(config.Auth{
+		SessionTTL: time.Hour,
+		RefreshTTL: time.Hour,
+		Passwords:  config.Passwords{Hash: config.PasswordBcrypt, MaxFailedLogins: 1, UnlockTTL: time.Hour, VerifyTTL: time.Hour, ResetTTL: time.Hour},
+	}, mailbox)
+	require.NoError(t, err)
+	_, err = accounts.Register(context.Background(), "locked@example.com", "correct horse", "")
+	require.NoError(t, err)
+
+	handler := NewAuthHandler(accounts)
+	engine := gin.New()
+	engine.POST("/auth/register", middleware.Bind[RegisterRequest](), handler.Register)
+	engine.POST("/auth/login", middleware.Bind[LoginRequest](), handler.Login)
+	engine.POST("/auth/unlock", middleware.Bind[CodeRequest](), handler.Unlock)
+	engine.POST("/auth/unlock/email", middleware.Bind[EmailRequest](), handler.SendUnlockEmail)
+	engine.POST("/auth/verify-email", middleware.Bind[CodeRequest](), handler.VerifyEmail)
+	engine.POST("/auth/password/forgot", middleware.Bind[EmailRequest](), handler.ForgotPassword)
+	engine.POST("/auth/password/reset", middleware.Bind[ResetPasswordRequest](), handler.ResetPassword)
+
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{name: "register", path: "/auth/register", body: `{"email":"jane@example.com","password":"correct horse","name":"Jane"}`, expectedStatus: http.StatusCreated},
+		{name: "register taken address", path: "/auth/register", body: `{"email":"JANE@example.com","password":"correct horse"}`, expectedStatus: http.StatusConflict, expectedCode: "ACCOUNT_EXISTS"},
+		{name: "register short password", path: "/auth/register", body: `{"email":"john@example.com","password":"short"}`, expectedStatus: http.StatusUnprocessableEntity, expectedCode: "VALIDATION_ERROR"},
+		{name: "register invalid address", path: "/auth/register", body: `{"email":"john","password":"correct horse"}`, expectedStatus: http.StatusUnprocessableEntity, expectedCode: "VALIDATION_ERROR"},
+		{name: "log in", path: "/auth/login", body: `{"email":"jane@example.com","password":"correct horse"}`, expectedStatus: http.StatusOK},
+		{name: "log in unknown address", path: "/auth/login", body: `{"email":"john@example.com","password":"correct horse"}`, expectedStatus: http.StatusUnauthorized, expectedCode: "INVALID_CREDENTIALS"},
+		{name: "log in locking account", path: "/auth/login", body: `{"email":"locked@example.com","password":"wrong horse"}`, expectedStatus: http.StatusLocked, expectedCode: "ACCOUNT_LOCKED"},
+		{name: "log in locked account", path: "/auth/login", body: `{"email":"locked@example.com","password":"correct horse"}`, expectedStatus: http.StatusLocked, expectedCode: "ACCOUNT_LOCKED"},
+		{name: "unlock with wrong code", path: "/auth/unlock", body: `{"code":"wrong"}`, expectedStatus: http.StatusUnauthorized, expectedCode: "INVALID_TOKEN"},
+		{name: "unlock without code", path: "/auth/unlock", body: `{}`, expectedStatus: http.StatusUnprocessableEntity, expectedCode: "VALIDATION_ERROR"},
+		{name: "resend unlock email", path: "/auth/unlock/email", body: `{"email":"locked@example.com"}`, expectedStatus: http.StatusAccepted},
+		{name: "resend unlock email for unknown address", path: "/auth/unlock/email", body: `{"email":"john@example.com"}`, expectedStatus: http.StatusAccepted},
+		{name: "verify email with wrong code", path: "/auth/verify-email", body: `{"code":"wrong"}`, expectedStatus: http.StatusUnauthorized, expectedCode: "INVALID_TOKEN"},
+		{name: "verify email without code", path: "/auth/verify-email", body: `{}`, expectedStatus: http.StatusUnprocessableEntity, expectedCode: "VALIDATION_ERROR"},
+		{name: "forgot password", path: "/auth/password/forgot", body: `{"email":"jane@example.com"}`, expectedStatus: http.StatusAccepted},
+		{name: "forgot password for unknown address", path: "/auth/password/forgot", body: `{"email":"john@example.com"}`, expectedStatus: http.StatusAccepted},
+		{name: "reset password with wrong code", path: "/auth/password/reset", body: `{"code":"wrong","password":"battery staple"}`, expectedStatus: http.StatusUnauthorized, expectedCode: "INVALID_TOKEN"},
+		{name: "reset password too short", path: "/auth/password/reset", body: `{"code":"wrong","password":"short"}`, expectedStatus: http.StatusUnprocessableEntity, expectedCode: "VALIDATION_ERROR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+
+			assert.Equal(t, tt.expectedStatus, rec.Code, "body: %s", rec.Body)
+			if tt.expectedCode == "" {
+				return
+			}
+			var errResp models.ErrorResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&errResp))
+			assert.Equal(t, tt.expectedCode, errResp.Code)
+		})
+	}
+
+	// Both registrations, the lock, the resend and the reset each emailed a code
+	assert.Len(t, mailbox.Emails(), 5)
+}
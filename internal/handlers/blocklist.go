@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apikeys"
+	"github.com/ravibandhu/oolio-food-ordering/internal/auth"
+	"github.com/ravibandhu/oolio-food-ordering/internal/blocklist"
+	"github.com/ravibandhu/oolio-food-ordering/internal/middleware"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
+)
+
+// BlocklistHandler handles HTTP requests for managing blocked callers
+type BlocklistHandler struct {
+	list *blocklist.List
+}
+
+// NewBlocklistHandler creates a new BlocklistHandler instance
+func NewBlocklistHandler(list *blocklist.List) *BlocklistHandler {
+	return &BlocklistHandler{
+		list: list,
+	}
+}
+
+// @Operation GET /admin/blocklist
+// @Summary List blocklist entries
+// @Description Get every block and allow entry, oldest first
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Success 200 {array} blocklist.Entry
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/blocklist [get]
+func (h *BlocklistHandler) ListEntries(c *gin.Context) {
+	respond.JSON(c, http.StatusOK, h.list.Entries())
+}
+
+// @Operation POST /admin/blocklist
+// @Summary Add a blocklist entry
+// @Description Block, or exempt from blocks, an IP address, a CIDR range or an API key. Blocked callers are refused with 403 on every route; allow entries take precedence over block entries. API keys are only stored as fingerprints.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param entry body blocklist.EntryRequest true "Entry to add"
+// @Success 201 {object} blocklist.Entry
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/blocklist [post]
+func (h *BlocklistHandler) AddEntry(c *gin.Context) {
+	req := middleware.Bound[blocklist.EntryRequest](c)
+
+	entry, err := h.list.Add(*req, actor(c), c.ClientIP())
+	switch {
+	case errors.Is(err, blocklist.ErrInvalidEntry):
+		respond.Error(c, apierrors.New(apierrors.ValidationError, "Invalid blocklist entry").
+			AddDetail("error", err.Error()))
+		return
+	case errors.Is(err, blocklist.ErrExists):
+		respond.Error(c, apierrors.New(apierrors.EntryExists, "An identical entry already exists").
+			AddDetail("error", err.Error()))
+		return
+	case err != nil:
+		respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to add blocklist entry").
+			AddDetail("error", err.Error()))
+		return
+	}
+
+	respond.JSON(c, http.StatusCreated, entry)
+}
+
+// @Operation DELETE /admin/blocklist/{id}
+// @Summary Remove a blocklist entry
+// @Description Remove a block or allow entry. The removal is kept in the audit log.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "Entry ID"
+// @Success 204
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/blocklist/{id} [delete]
+func (h *BlocklistHandler) RemoveEntry(c *gin.Context) {
+	id := c.Param("id")
+	err := h.list.Remove(id, actor(c), c.ClientIP())
+	switch {
+	case errors.Is(err, blocklist.ErrNotFound):
+		respond.Error(c, apierrors.New(apierrors.NotFound, "Blocklist entry not found").AddDetail("id", id))
+		return
+	case err != nil:
+		respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to remove blocklist entry").
+			AddDetail("error", err.Error()))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Operation GET /admin/blocklist/audit
+// @Summary List blocklist changes
+// @Description Get every addition and removal of blocklist entries, oldest first, with the IP address and the admin that made it: the ID of their API key, or their customer ID when signed in
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Success 200 {array} blocklist.AuditEvent
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/blocklist/audit [get]
+func (h *BlocklistHandler) ListAudit(c *gin.Context) {
+	respond.JSON(c, http.StatusOK, h.list.Audit())
+}
+
+// actor identifies the admin making a change by their customer ID when they
+// signed in, or else by the ID of their API key, which tells keys apart in
+// the audit log without revealing them
+func actor(c *gin.Context) string {
+	if claims, ok := auth.SessionFromContext(c.Request.Context()); ok {
+		return claims.Subject
+	}
+	if key, ok := apikeys.FromContext(c.Request.Context()); ok {
+		return key.ID
+	}
+	return apikeys.ConfiguredID(middleware.APIKey(c))
+}
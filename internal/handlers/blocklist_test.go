@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/blocklist"
+	"github.com/ravibandhu/oolio-food-ordering/internal/middleware"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlocklistHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	list, err := blocklist.Load("")
+	require.NoError(t, err)
+
+	handler := NewBlocklistHandler(list)
+	engine := gin.New()
+	engine.GET("/admin/blocklist", handler.ListEntries)
+	engine.POST("/admin/blocklist", middleware.Bind[blocklist.EntryRequest](), handler.AddEntry)
+	engine.DELETE("/admin/blocklist/:id", handler.RemoveEntry)
+	engine.GET("/admin/blocklist/audit", handler.ListAudit)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-API-Key", "admin-key")
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{name: "block range", body: `{"type":"ip","value":"203.0.113.0/24"}`, expectedStatus: http.StatusCreated},
+		{name: "block same range", body: `{"type":"ip","value":"203.0.113.0/24"}`, expectedStatus: http.StatusConflict, expectedCode: "ENTRY_EXISTS"},
+		{name: "unknown type", body: `{"type":"customer","value":"cust-1"}`, expectedStatus: http.StatusUnprocessableEntity, expectedCode: "VALIDATION_ERROR"},
+		{name: "invalid range", body: `{"type":"ip","value":"203.0.113.0/33"}`, expectedStatus: http.StatusUnprocessableEntity, expectedCode: "VALIDATION_ERROR"},
+		{name: "malformed body", body: `{"type":`, expectedStatus: http.StatusBadRequest, expectedCode: "INVALID_REQUEST"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(http.MethodPost, "/admin/blocklist", tt.body)
+			assert.Equal(t, tt.expectedStatus, rec.Code, "body: %s", rec.Body)
+			if tt.expectedCode != "" {
+				var errResp models.ErrorResponse
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&errResp))
+				assert.Equal(t, tt.expectedCode, errResp.Code)
+			}
+		})
+	}
+
+	rec := do(http.MethodGet, "/admin/blocklist", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var entries []blocklist.Entry
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&entries))
+	require.Len(t, entries, 1)
+
+	rec = do(http.MethodDelete, "/admin/blocklist/"+entries[0].ID, "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = do(http.MethodDelete, "/admin/blocklist/"+entries[0].ID, "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = do(http.MethodGet, "/admin/blocklist/audit", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var audit []blocklist.AuditEvent
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&audit))
+	require.Len(t, audit, 2)
+	assert.Equal(t, blocklist.Fingerprint("admin-key")[:12], audit[0].Actor)
+	assert.Equal(t, blocklist.ActionRemoved, audit[1].Action)
+}
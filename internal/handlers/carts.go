@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/auth"
+	"github.com/ravibandhu/oolio-food-ordering/internal/carts"
+	"github.com/ravibandhu/oolio-food-ordering/internal/data"
+	"github.com/ravibandhu/oolio-food-ordering/internal/middleware"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/pos"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
+	"github.com/ravibandhu/oolio-food-ordering/internal/services"
+)
+
+// CartTokenHeader carries the host token of a group cart
+const CartTokenHeader = "X-Cart-Token"
+
+// CreatedCart is a group cart as opened, with the token its host checks out
+// with
+type CreatedCart struct {
+	carts.Cart
+
+	// The host's token, sent as X-Cart-Token to check out or remove others'
+	// items. It is only returned here.
+	// @example 4f1c2a9e8b7d6c5a4f1c2a9e8b7d6c5a4f1c2a9e8b7d6c5a
+	HostToken string `json:"host_token"`
+}
+
+// Receipt is the order placed from a group cart, itemized per participant
+type Receipt struct {
+	// The order placed
+	Order *models.Order `json:"order"`
+
+	// What each participant ordered, and their part of the total
+	Shares []carts.Share `json:"shares"`
+}
+
+// CartHandler handles HTTP requests for group carts
+type CartHandler struct {
+	store        *data.Store
+	carts        *carts.Store
+	orderService services.OrderService
+	holdTTL      time.Duration // How long stock is held for a cart after it last changed; 0 holds none
+}
+
+// NewCartHandler creates a new CartHandler instance, holding the stock of
+// each cart's items for holdTTL after it last changed
+func NewCartHandler(store *data.Store, cartStore *carts.Store, orderService services.OrderService, holdTTL time.Duration) *CartHandler {
+	return &CartHandler{
+		store:        store,
+		carts:        cartStore,
+		orderService: orderService,
+		holdTTL:      holdTTL,
+	}
+}
+
+// @Operation POST /carts
+// @Summary Open a group cart
+// @Description Open a cart that participants add their own items to through its ID, before the host checks out one order for everyone. Carts are discarded a day after they are opened. The host token is only returned here.
+// @Tags carts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param cart body carts.CartRequest true "Cart to open"
+// @Success 201 {object} CreatedCart
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /carts [post]
+func (h *CartHandler) CreateCart(c *gin.Context) {
+	req := middleware.Bound[carts.CartRequest](c)
+
+	host, ok := participant(c, req.Name)
+	if !ok {
+		return
+	}
+
+	cart, token, err := tenantCarts(c.Request.Context(), h.carts).Create(host)
+	if err != nil {
+		respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to open cart").
+			AddDetail("error", err.Error()))
+		return
+	}
+	respond.JSON(c, http.StatusCreated, CreatedCart{Cart: cart, HostToken: token})
+}
+
+// @Operation GET /carts/{id}
+// @Summary Get a group cart
+// @Description Get a group cart with every participant's items and, once checked out, what each ordered
+// @Tags carts
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Cart ID"
+// @Success 200 {object} carts.Cart
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /carts/{id} [get]
+func (h *CartHandler) GetCart(c *gin.Context) {
+	cart, err := tenantCarts(c.Request.Context(), h.carts).Get(c.Param("id"))
+	if err != nil {
+		cartError(c, err)
+		return
+	}
+	respond.JSON(c, http.StatusOK, cart)
+}
+
+// @Operation POST /carts/{id}/items
+// @Summary Add to a group cart
+// @Description Add items to an open group cart, as a named participant or as the signed-in customer. Items are priced when the host checks out. The stock of the cart's items is held for its checkout for ORDER_HOLD_TTL; items the POS has too few of, once other carts' holds are set aside, are refused with 409 OUT_OF_STOCK.
+// @Tags carts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Cart ID"
+// @Param items body carts.ItemRequest true "Items to add"
+// @Success 200 {object} carts.Cart
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /carts/{id}/items [post]
+func (h *CartHandler) AddItems(c *gin.Context) {
+	ctx := c.Request.Context()
+	req := middleware.Bound[carts.ItemRequest](c)
+
+	who, ok := participant(c, req.Name)
+	if !ok {
+		return
+	}
+	store := tenantStore(ctx, h.store)
+	for _, item := range req.Items {
+		if _, err := store.GetProduct(item.ProductID); err != nil {
+			respond.Error(c, apierrors.New(apierrors.InvalidProduct, "Product not found").
+				AddDetail("productId", item.ProductID))
+			return
+		}
+	}
+
+	cart, err := tenantCarts(ctx, h.carts).Add(c.Param("id"), who, req.Items, func(items []models.OrderItem) error {
+		return h.holdStock(ctx, c.Param("id"), items)
+	})
+	if err != nil {
+		cartError(c, err)
+		return
+	}
+	respond.JSON(c, http.StatusOK, cart)
+}
+
+// @Operation DELETE /carts/{id}/items/{item}
+// @Summary Remove from a group cart
+// @Description Remove an item from an open group cart. The host, sending X-Cart-Token, can remove any item; signed-in customers can remove their own.
+// @Tags carts
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Cart ID"
+// @Param item path string true "Item ID"
+// @Param X-Cart-Token header string false "Host token of the cart"
+// @Success 200 {object} carts.Cart
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /carts/{id}/items/{item} [delete]
+func (h *CartHandler) RemoveItem(c *gin.Context) {
+	var customerID string
+	if customer, ok := auth.FromContext(c.Request.Context()); ok {
+		customerID = customer.ID
+	}
+
+	cart, err := tenantCarts(c.Request.Context(), h.carts).
+		Remove(c.Param("id"), c.Param("item"), c.GetHeader(CartTokenHeader), customerID)
+	if err != nil {
+		cartError(c, err)
+		return
+	}
+	h.holdStock(c.Request.Context(), cart.ID, cart.OrderItems())
+	respond.JSON(c, http.StatusOK, cart)
+}
+
+// @Operation POST /carts/{id}/checkout
+// @Summary Check out a group cart
+// @Description Place one order for every item of a group cart, as POST /orders does, and close the cart. The order takes the stock held for the cart. The receipt itemizes what each participant ordered, with their part of the total in proportion to their subtotal. The cart reopens, holding its stock again, if the order fails.
+// @Tags carts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Cart ID"
+// @Param X-Cart-Token header string true "Host token of the cart"
+// @Param X-Challenge-Token header string false "Token of a solved bot challenge, required without an API key when orders are challenged"
+// @Param checkout body carts.CheckoutRequest true "Order details"
+// @Param Idempotency-Key header string false "Key to retry the request with; a retry of a request that succeeded is answered with the original response"
+// @Success 201 {object} Receipt
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Failure 504 {object} models.ErrorResponse
+// @Router /carts/{id}/checkout [post]
+func (h *CartHandler) Checkout(c *gin.Context) {
+	ctx := c.Request.Context()
+	req := middleware.Bound[carts.CheckoutRequest](c)
+	cartStore := tenantCarts(ctx, h.carts)
+
+	var placeErr error
+	cart, order, err := cartStore.Checkout(c.Param("id"), c.GetHeader(CartTokenHeader),
+		func(items []models.OrderItem) (*models.Order, error) {
+			order, err := h.orderService.PlaceOrder(pos.NewHoldContext(ctx, c.Param("id")), &models.OrderRequest{
+				CouponCode:      req.CouponCode,
+				Items:           items,
+				Notes:           req.Notes,
+				DeliveryAddress: req.DeliveryAddress,
+				TableToken:      req.TableToken,
+				Billing:         req.Billing,
+				Payment:         req.Payment,
+			})
+			placeErr = err
+			return order, err
+		})
+	switch {
+	case placeErr != nil:
+		if reopened, err := cartStore.Get(c.Param("id")); err == nil {
+			h.holdStock(ctx, reopened.ID, reopened.OrderItems())
+		}
+		orderError(c, placeErr)
+		return
+	case err != nil:
+		cartError(c, err)
+		return
+	}
+
+	respond.JSON(c, http.StatusCreated, Receipt{Order: withOrderLinks(ctx, order), Shares: cart.Shares})
+}
+
+// holdStock holds the stock of items for the checkout of a cart, failing
+// with the items the POS has too few of. Nothing is held when holds are off.
+func (h *CartHandler) holdStock(ctx context.Context, cartID string, items []models.OrderItem) error {
+	if h.holdTTL <= 0 {
+		return nil
+	}
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.ProductID
+	}
+	found, _, err := tenantStore(ctx, h.store).GetProducts(ids)
+	if err != nil {
+		return err
+	}
+	if short := tenantInventory(ctx).Hold(cartID, pos.Needs(items, found), h.holdTTL); len(short) > 0 {
+		return services.OutOfStock(items, found, short)
+	}
+	return nil
+}
+
+// participant returns who is adding to a cart: the signed-in customer, if
+// any, under name or their own. Anonymous participants must give a name.
+func participant(c *gin.Context, name string) (carts.Participant, bool) {
+	var p carts.Participant
+	if customer, ok := auth.FromContext(c.Request.Context()); ok {
+		p.CustomerID = customer.ID
+		p.Name = customer.Name
+	}
+	if name != "" {
+		p.Name = name
+	}
+	if p.Name == "" {
+		respond.Error(c, apierrors.New(apierrors.ValidationError, "Invalid request body").
+			AddDetail("error", "name is required without a session token").
+			AddDetail("fields", map[string]string{"name": "required"}))
+		return carts.Participant{}, false
+	}
+	return p, true
+}
+
+// cartError answers with the error a group cart operation failed with
+func cartError(c *gin.Context, err error) {
+	var errResp *models.ErrorResponse
+	switch {
+	case errors.As(err, &errResp):
+		respond.Error(c, errResp)
+	case errors.Is(err, carts.ErrNotFound):
+		respond.Error(c, apierrors.New(apierrors.NotFound, "Cart not found").
+			AddDetail("cartId", c.Param("id")))
+	case errors.Is(err, carts.ErrItemNotFound):
+		respond.Error(c, apierrors.New(apierrors.NotFound, "Item not found").
+			AddDetail("itemId", c.Param("item")))
+	case errors.Is(err, carts.ErrForbidden):
+		respond.Error(c, apierrors.New(apierrors.Forbidden, "Only the host can do this").
+			AddDetail("header", CartTokenHeader))
+	case errors.Is(err, carts.ErrClosed):
+		respond.Error(c, apierrors.New(apierrors.CartClosed, "The cart is checked out"))
+	case errors.Is(err, carts.ErrFull):
+		respond.Error(c, apierrors.New(apierrors.CartFull, "The cart is full").
+			AddDetail("maxItems", carts.MaxItems))
+	case errors.Is(err, carts.ErrEmpty):
+		respond.Error(c, apierrors.New(apierrors.CartEmpty, "The cart has no items"))
+	default:
+		respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to update cart").
+			AddDetail("error", err.Error()))
+	}
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/carts"
+	"github.com/ravibandhu/oolio-food-ordering/internal/middleware"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckout(t *testing.T) {
+	cartStore := carts.NewStore()
+	cart, token, err := cartStore.Create(carts.Participant{Name: "Jane"})
+	require.NoError(t, err)
+	_, err = cartStore.Add(cart.ID, carts.Participant{Name: "Jane"}, []models.OrderItem{{ProductID: "prod-1", Quantity: 2}}, nil)
+	require.NoError(t, err)
+	_, err = cartStore.Add(cart.ID, carts.Participant{Name: "Sam"}, []models.OrderItem{{ProductID: "prod-2", Quantity: 1}}, nil)
+	require.NoError(t, err)
+
+	checkout := func(handler *CartHandler, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/carts/"+cart.ID+"/checkout", strings.NewReader(`{"notes":"leave at door"}`))
+		req.Header.Set(CartTokenHeader, token)
+		rec := httptest.NewRecorder()
+		serve(rec, req, "/carts/:id/checkout", middleware.Bind[carts.CheckoutRequest](), handler.Checkout)
+		return rec
+	}
+
+	// Failed orders answer as POST /orders does, and reopen the cart
+	failing := new(MockOrderService)
+	failing.On("PlaceOrder", mock.AnythingOfType("*models.OrderRequest")).Return(nil,
+		models.NewErrorResponse("STORE_CLOSED", "The restaurant is not accepting orders right now"))
+	rec := checkout(NewCartHandler(nil, cartStore, failing, 0), token)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), "STORE_CLOSED")
+	reopened, err := cartStore.Get(cart.ID)
+	require.NoError(t, err)
+	assert.Equal(t, carts.StatusOpen, reopened.Status)
+
+	// Only the host checks out
+	rec = checkout(NewCartHandler(nil, cartStore, failing, 0), "wrong")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	placing := new(MockOrderService)
+	placing.On("PlaceOrder", mock.MatchedBy(func(req *models.OrderRequest) bool {
+		return len(req.Items) == 2 && req.Notes == "leave at door"
+	})).Return(&models.Order{
+		ID: "order-1",
+		Items: []models.OrderItem{
+			{ProductID: "prod-1", Quantity: 2, Price: 10},
+			{ProductID: "prod-2", Quantity: 1, Price: 20},
+		},
+		TotalAmount: 44,
+	}, nil)
+	rec = checkout(NewCartHandler(nil, cartStore, placing, 0), token)
+	require.Equal(t, http.StatusCreated, rec.Code, "body: %s", rec.Body)
+
+	var receipt Receipt
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&receipt))
+	assert.Equal(t, "order-1", receipt.Order.ID)
+	require.Len(t, receipt.Shares, 2)
+	assert.Equal(t, "Jane", receipt.Shares[0].Name)
+	assert.Equal(t, 20.0, receipt.Shares[0].Subtotal)
+	assert.Equal(t, 22.0, receipt.Shares[0].Total)
+	assert.Equal(t, "Sam", receipt.Shares[1].Name)
+	assert.Equal(t, 22.0, receipt.Shares[1].Total)
+	placing.AssertExpectations(t)
+
+	// Checked-out carts cannot be checked out again
+	rec = checkout(NewCartHandler(nil, cartStore, placing, 0), token)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), "CART_CLOSED")
+}
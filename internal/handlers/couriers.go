@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/couriers"
+	"github.com/ravibandhu/oolio-food-ordering/internal/middleware"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/orders"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
+)
+
+// CourierHandler handles the HTTP requests that follow delivery orders from
+// the restaurant to the customer
+type CourierHandler struct{}
+
+// NewCourierHandler creates a new CourierHandler instance
+func NewCourierHandler() *CourierHandler {
+	return &CourierHandler{}
+}
+
+// @Operation PUT /admin/orders/{id}/courier
+// @Summary Assign a courier to an order
+// @Description Assign a delivery order to a courier, moving it to the assigned status in its history. An order can be given another courier until it is picked up.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "Order ID"
+// @Param courier body couriers.Courier true "The courier"
+// @Success 200 {object} couriers.Tracking
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Router /admin/orders/{id}/courier [put]
+func (h *CourierHandler) AssignCourier(c *gin.Context) {
+	orderID := c.Param("id")
+	courier := middleware.Bound[couriers.Courier](c)
+	tracking, err := tenantCouriers(c.Request.Context()).Assign(orderID, *courier)
+	if err != nil {
+		respond.Error(c, deliveryError(orderID, err))
+		return
+	}
+	respond.JSON(c, http.StatusOK, tracking)
+}
+
+// @Operation POST /courier/orders/{id}/status
+// @Summary Move a delivery on
+// @Description Report that the courier picked an order up, is on the way to the customer or delivered it. Statuses only move forward and are added to the order's history. Picking an order up marks it ready in the kitchen, if it is not already.
+// @Tags courier
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "Order ID"
+// @Param status body couriers.StatusRequest true "The delivery status"
+// @Success 200 {object} couriers.Tracking
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Router /courier/orders/{id}/status [post]
+func (h *CourierHandler) UpdateStatus(c *gin.Context) {
+	orderID := c.Param("id")
+	req := middleware.Bound[couriers.StatusRequest](c)
+	tracking, err := tenantCouriers(c.Request.Context()).Advance(orderID, req.Status)
+	if err != nil {
+		respond.Error(c, deliveryError(orderID, err))
+		return
+	}
+	respond.JSON(c, http.StatusOK, tracking)
+}
+
+// @Operation PUT /courier/orders/{id}/location
+// @Summary Report a courier's location
+// @Description Report where the courier of an order is, from when it is assigned until it is delivered. The customer sees the last location reported.
+// @Tags courier
+// @Accept json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "Order ID"
+// @Param location body couriers.LocationRequest true "The courier's location"
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Router /courier/orders/{id}/location [put]
+func (h *CourierHandler) UpdateLocation(c *gin.Context) {
+	orderID := c.Param("id")
+	req := middleware.Bound[couriers.LocationRequest](c)
+	if err := tenantCouriers(c.Request.Context()).Locate(orderID, *req.Latitude, *req.Longitude); err != nil {
+		respond.Error(c, deliveryError(orderID, err))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// @Operation GET /orders/{id}/tracking
+// @Summary Track a delivery
+// @Description Get the delivery status of a delivery order, the courier delivering it and where the courier last reported being. The status is empty until a courier is assigned, and the courier and location are dropped once the order is delivered.
+// @Tags orders
+// @Produce json
+// @Param id path string true "Order ID"
+// @Success 200 {object} couriers.Tracking
+// @Failure 404 {object} models.ErrorResponse
+// @Router /orders/{id}/tracking [get]
+func (h *CourierHandler) GetTracking(c *gin.Context) {
+	orderID := c.Param("id")
+	tracking, err := tenantCouriers(c.Request.Context()).Track(orderID)
+	if err != nil {
+		// Orders that are not delivered have nothing to track
+		respond.Error(c, apierrors.New(apierrors.NotFound, "No tracking for order").AddDetail("id", orderID))
+		return
+	}
+	respond.JSON(c, http.StatusOK, tracking)
+}
+
+// deliveryError returns the error response for err, returned by the
+// courier tracker for an order
+func deliveryError(orderID string, err error) *models.ErrorResponse {
+	switch {
+	case errors.Is(err, orders.ErrNotFound):
+		return apierrors.New(apierrors.NotFound, "Order not found").AddDetail("id", orderID)
+	case errors.Is(err, couriers.ErrNotDelivery):
+		return apierrors.New(apierrors.NotDelivery, "Order is not for delivery").AddDetail("id", orderID)
+	case errors.Is(err, couriers.ErrInvalidStatus):
+		return apierrors.New(apierrors.InvalidDeliveryStatus, "Order cannot move to this delivery status").
+			AddDetail("error", err.Error())
+	default:
+		return apierrors.New(apierrors.InternalError, "Failed to record delivery").AddDetail("error", err.Error())
+	}
+}
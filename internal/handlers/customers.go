@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/auth"
+	"github.com/ravibandhu/oolio-food-ordering/internal/middleware"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
+)
+
+// RolesRequest represents the request body for granting staff roles
+type RolesRequest struct {
+	// Staff roles to grant, replacing the ones granted before: admin, kitchen or support
+	// @required
+	// @example ["kitchen"]
+	Roles []string `json:"roles" validate:"required"`
+}
+
+// CustomerHandler handles operator-facing HTTP requests about customers
+type CustomerHandler struct {
+	accounts *auth.Accounts
+}
+
+// NewCustomerHandler creates a new CustomerHandler instance
+func NewCustomerHandler(accounts *auth.Accounts) *CustomerHandler {
+	return &CustomerHandler{
+		accounts: accounts,
+	}
+}
+
+// @Operation PUT /admin/customers/{id}/roles
+// @Summary Grant staff roles
+// @Description Replace the staff roles granted to a customer. Their session tokens carry the roles, so every session they had ends and they sign in again to use the new ones.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "Customer ID"
+// @Param roles body RolesRequest true "Roles to grant"
+// @Success 200 {object} auth.Customer
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/customers/{id}/roles [put]
+func (h *CustomerHandler) SetRoles(c *gin.Context) {
+	req := middleware.Bound[RolesRequest](c)
+
+	id := c.Param("id")
+	customer, err := h.accounts.SetRoles(id, req.Roles)
+	switch {
+	case errors.Is(err, auth.ErrUnknownRole):
+		respond.Error(c, apierrors.New(apierrors.ValidationError, "Invalid roles").
+			AddDetail("error", err.Error()).
+			AddDetail("allowed", auth.StaffRoles))
+		return
+	case errors.Is(err, auth.ErrCustomerNotFound):
+		respond.Error(c, apierrors.New(apierrors.NotFound, "Customer not found").AddDetail("id", id))
+		return
+	case err != nil:
+		respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to grant roles").
+			AddDetail("error", err.Error()))
+		return
+	}
+
+	respond.JSON(c, http.StatusOK, customer)
+}
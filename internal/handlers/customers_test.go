@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/auth"
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/middleware"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomerHandler_SetRoles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	accounts, err := auth.Load(config.Auth{SessionTTL: time.Hour, RefreshTTL: time.Hour}, nil)
+	require.NoError(t, err)
+	customer, err := accounts.Customers.SignIn(&auth.Profile{Identity: auth.Identity{Provider: auth.ProviderGoogle, Subject: "g-1"}})
+	require.NoError(t, err)
+
+	handler := NewCustomerHandler(accounts)
+	engine := gin.New()
+	engine.PUT("/admin/customers/:id/roles", middleware.Bind[RolesRequest](), handler.SetRoles)
+
+	tests := []struct {
+		name           string
+		id             string
+		body           string
+		expectedStatus int
+		expectedCode   string
+		expectedRoles  []string
+	}{
+		{name: "grant roles", id: customer.ID, body: `{"roles":["support","kitchen"]}`, expectedStatus: http.StatusOK, expectedRoles: []string{"kitchen", "support"}},
+		{name: "revoke roles", id: customer.ID, body: `{"roles":[]}`, expectedStatus: http.StatusOK},
+		{name: "unknown role", id: customer.ID, body: `{"roles":["owner"]}`, expectedStatus: http.StatusUnprocessableEntity, expectedCode: "VALIDATION_ERROR"},
+		{name: "customer role", id: customer.ID, body: `{"roles":["customer"]}`, expectedStatus: http.StatusUnprocessableEntity, expectedCode: "VALIDATION_ERROR"},
+		{name: "missing roles", id: customer.ID, body: `{}`, expectedStatus: http.StatusUnprocessableEntity, expectedCode: "VALIDATION_ERROR"},
+		{name: "unknown customer", id: "customer-unknown", body: `{"roles":["admin"]}`, expectedStatus: http.StatusNotFound, expectedCode: "NOT_FOUND"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/admin/customers/"+tt.id+"/roles", strings.NewReader(tt.body)))
+
+			assert.Equal(t, tt.expectedStatus, rec.Code, "body: %s", rec.Body)
+			if tt.expectedCode != "" {
+				var errResp models.ErrorResponse
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&errResp))
+				assert.Equal(t, tt.expectedCode, errResp.Code)
+				return
+			}
+			var updated auth.Customer
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&updated))
+			assert.Equal(t, tt.expectedRoles, updated.Roles)
+		})
+	}
+}
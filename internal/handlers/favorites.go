@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/auth"
+	"github.com/ravibandhu/oolio-food-ordering/internal/data"
+	"github.com/ravibandhu/oolio-food-ordering/internal/i18n"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
+)
+
+// FavoriteHandler handles the HTTP requests of customers about the products
+// they saved
+type FavoriteHandler struct {
+	store    *data.Store
+	accounts *auth.Accounts
+}
+
+// NewFavoriteHandler creates a new FavoriteHandler instance
+func NewFavoriteHandler(store *data.Store, accounts *auth.Accounts) *FavoriteHandler {
+	return &FavoriteHandler{
+		store:    store,
+		accounts: accounts,
+	}
+}
+
+// @Operation GET /me/favorites
+// @Summary List favorite products
+// @Description Get the products the signed-in customer saved at this restaurant, in the order they were saved, as GET /products serves them, so they can be ordered again quickly. Saved products that were deleted are left out.
+// @Tags me
+// @Produce json
+// @Security BearerAuth
+// @Param lang query string false "Language to serve product text in, overriding Accept-Language"
+// @Param Accept-Language header string false "Languages to serve product text in"
+// @Success 200 {array} models.Product
+// @Header 200 {integer} X-Catalog-Revision "Catalog revision to send with orders as catalogRevision"
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /me/favorites [get]
+func (h *FavoriteHandler) ListFavorites(c *gin.Context) {
+	c.Writer.Header().Add("Vary", "Accept-Language")
+	ctx := c.Request.Context()
+	customer, ok := auth.FromContext(ctx)
+	if !ok {
+		respond.Error(c, apierrors.New(apierrors.Unauthorized, "Missing session token"))
+		return
+	}
+
+	ids, err := h.accounts.Customers.Favorites(customer.ID, tenantID(ctx))
+	if err != nil {
+		respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to get favorites").
+			AddDetail("error", err.Error()))
+		return
+	}
+
+	// The revision is read first, so it is never newer than the products
+	// served
+	store := tenantStore(ctx, h.store)
+	c.Header(CatalogRevisionHeader, strconv.FormatUint(store.Revision(), 10))
+	found, _, err := store.GetProducts(ids)
+	if err != nil {
+		respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to get favorites").
+			AddDetail("error", err.Error()))
+		return
+	}
+
+	ratings := tenantReviews(ctx, nil)
+	signer := tenantSigner(ctx)
+	preferred, fallback := i18n.Preferred(c.Request), tenantLocale(ctx)
+	products := make([]*models.Product, 0, len(found))
+	for _, id := range ids {
+		product, ok := found[id]
+		if !ok {
+			continue
+		}
+		localized, _ := i18n.Localize(withRating(product, ratings), preferred, fallback)
+		signed, err := withSignedImage(withProductLinks(ctx, localized), signer)
+		if err != nil {
+			respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to sign image URLs").
+				AddDetail("productId", product.ID).
+				AddDetail("error", err.Error()))
+			return
+		}
+		products = append(products, signed)
+	}
+
+	respond.JSON(c, http.StatusOK, products)
+}
+
+// @Operation PUT /me/favorites/{id}
+// @Summary Save a favorite product
+// @Description Save a product as a favorite of the signed-in customer at this restaurant, after the ones saved before. Saving a favorite again changes nothing. Up to 100 products can be saved at each restaurant.
+// @Tags me
+// @Security BearerAuth
+// @Param id path string true "Product ID"
+// @Success 204
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /me/favorites/{id} [put]
+func (h *FavoriteHandler) AddFavorite(c *gin.Context) {
+	ctx := c.Request.Context()
+	customer, ok := auth.FromContext(ctx)
+	if !ok {
+		respond.Error(c, apierrors.New(apierrors.Unauthorized, "Missing session token"))
+		return
+	}
+
+	// Only products that can be ordered are saved
+	productID := c.Param("id")
+	product, err := tenantStore(ctx, h.store).GetProduct(productID)
+	if err != nil || product.DeletedAt != nil {
+		respond.Error(c, apierrors.New(apierrors.NotFound, "Product not found").AddDetail("productId", productID))
+		return
+	}
+
+	err = h.accounts.Customers.AddFavorite(customer.ID, tenantID(ctx), productID)
+	switch {
+	case errors.Is(err, auth.ErrTooManyFavorites):
+		respond.Error(c, apierrors.New(apierrors.ValidationError, "Too many favorites").
+			AddDetail("max", auth.MaxFavorites))
+		return
+	case err != nil:
+		respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to save favorite").
+			AddDetail("error", err.Error()))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Operation DELETE /me/favorites/{id}
+// @Summary Remove a favorite product
+// @Description Forget a product the signed-in customer saved at this restaurant. Removing a product that is not saved changes nothing.
+// @Tags me
+// @Security BearerAuth
+// @Param id path string true "Product ID"
+// @Success 204
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /me/favorites/{id} [delete]
+func (h *FavoriteHandler) RemoveFavorite(c *gin.Context) {
+	ctx := c.Request.Context()
+	customer, ok := auth.FromContext(ctx)
+	if !ok {
+		respond.Error(c, apierrors.New(apierrors.Unauthorized, "Missing session token"))
+		return
+	}
+
+	if err := h.accounts.Customers.RemoveFavorite(customer.ID, tenantID(ctx), c.Param("id")); err != nil {
+		respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to remove favorite").
+			AddDetail("error", err.Error()))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/data"
+	"github.com/ravibandhu/oolio-food-ordering/internal/images"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
+)
+
+// maxImageUpload is the largest image upload accepted, in bytes
+const maxImageUpload = 10 << 20
+
+// ImageHandler handles product image HTTP requests
+type ImageHandler struct {
+	store   *data.Store
+	storage images.Storage
+}
+
+// NewImageHandler creates a new ImageHandler instance
+func NewImageHandler(store *data.Store, storage images.Storage) *ImageHandler {
+	return &ImageHandler{
+		store:   store,
+		storage: storage,
+	}
+}
+
+// @Operation POST /admin/products/{id}/image
+// @Summary Upload a product image
+// @Description Upload a JPEG, PNG or GIF photo of a product as the multipart field "image". Thumbnail, mobile, tablet and desktop renditions are generated, stored, and set as the product's image URLs.
+// @Tags admin
+// @Accept multipart/form-data
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "Product ID"
+// @Param image formData file true "Image to upload, at most 10 MiB"
+// @Success 200 {object} models.Product
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/products/{id}/image [post]
+func (h *ImageHandler) UploadImage(c *gin.Context) {
+	ctx := c.Request.Context()
+	store := tenantStore(ctx, h.store)
+	productID := c.Param("id")
+
+	product, err := store.GetProduct(productID)
+	if err != nil {
+		respond.Error(c, apierrors.New(apierrors.NotFound, "Product not found").AddDetail("productId", productID))
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImageUpload)
+	header, err := c.FormFile("image")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respond.Error(c, apierrors.New(apierrors.ImageTooLarge, "Image upload is too large").
+				AddDetail("limit", tooLarge.Limit))
+			return
+		}
+		respond.Error(c, apierrors.New(apierrors.InvalidRequest, "Expected an image file in the multipart field \"image\"").
+			AddDetail("error", err.Error()))
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		respond.Error(c, apierrors.New(apierrors.InvalidRequest, "Failed to read uploaded image").
+			AddDetail("error", err.Error()))
+		return
+	}
+	defer file.Close()
+
+	image, err := images.Process(ctx, h.storage, images.Key(tenantID(ctx), productID), file)
+	if err != nil {
+		if errors.Is(err, images.ErrInvalidImage) {
+			respond.Error(c, apierrors.New(apierrors.InvalidImage, "Upload is not a supported image").
+				AddDetail("error", err.Error()))
+			return
+		}
+		respond.Error(c, apierrors.New(apierrors.StorageFailed, "Failed to store image").
+			AddDetail("error", err.Error()))
+		return
+	}
+
+	updated := *product
+	updated.Image = image
+	updated.UpdatedAt = time.Now()
+	if err := store.UpdateProduct(&updated); err != nil {
+		if errors.Is(err, data.ErrProductNotFound) {
+			respond.Error(c, apierrors.New(apierrors.NotFound, "Product not found").AddDetail("productId", productID))
+			return
+		}
+		respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to update product").
+			AddDetail("error", err.Error()))
+		return
+	}
+
+	respond.JSON(c, http.StatusOK, updated)
+}
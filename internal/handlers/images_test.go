@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/data"
+	"github.com/ravibandhu/oolio-food-ordering/internal/images"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// multipartUpload builds a multipart body with content in the given field
+func multipartUpload(t *testing.T, field string, content []byte) (*bytes.Buffer, string) {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile(field, "photo.png")
+	require.NoError(t, err)
+	_, err = part.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return &body, writer.FormDataContentType()
+}
+
+func TestImageHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Setup test data
+	_, _, cfg, cleanup := setupTestData(t)
+	defer cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), cfg)
+	require.NoError(t, err)
+	defer store.Close()
+
+	dir := t.TempDir()
+	handler := NewImageHandler(store, images.NewLocalStorage(dir, "http://localhost:8080/public/images"))
+	engine := gin.New()
+	engine.POST("/admin/products/:id/image", handler.UploadImage)
+
+	var photo bytes.Buffer
+	require.NoError(t, png.Encode(&photo, image.NewRGBA(image.Rect(0, 0, 1600, 900))))
+
+	upload := func(productID, field string, content []byte) *httptest.ResponseRecorder {
+		body, contentType := multipartUpload(t, field, content)
+		req := httptest.NewRequest(http.MethodPost, "/admin/products/"+productID+"/image", body)
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("upload", func(t *testing.T) {
+		rec := upload("prod-1", "image", photo.Bytes())
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var got models.Product
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+		prefix := "http://localhost:8080/public/images/default/prod-1/"
+		for _, url := range []string{got.Image.Thumbnail, got.Image.Mobile, got.Image.Tablet, got.Image.Desktop} {
+			require.True(t, strings.HasPrefix(url, prefix), url)
+			_, err := os.Stat(filepath.Join(dir, "default", "prod-1", strings.TrimPrefix(url, prefix)))
+			assert.NoError(t, err)
+		}
+
+		stored, err := store.GetProduct("prod-1")
+		require.NoError(t, err)
+		assert.Equal(t, got.Image, stored.Image)
+	})
+
+	tests := []struct {
+		name           string
+		productID      string
+		field          string
+		content        []byte
+		expectedStatus int
+		expectedCode   string
+	}{
+		{name: "unknown product", productID: "missing", field: "image", content: photo.Bytes(), expectedStatus: http.StatusNotFound, expectedCode: "NOT_FOUND"},
+		{name: "missing file", productID: "prod-1", field: "photo", content: photo.Bytes(), expectedStatus: http.StatusBadRequest, expectedCode: "INVALID_REQUEST"},
+		{name: "not an image", productID: "prod-1", field: "image", content: []byte("plain text"), expectedStatus: http.StatusUnprocessableEntity, expectedCode: "INVALID_IMAGE"},
+		{name: "too large", productID: "prod-1", field: "image", content: make([]byte, maxImageUpload+1), expectedStatus: http.StatusRequestEntityTooLarge, expectedCode: "IMAGE_TOO_LARGE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := upload(tt.productID, tt.field, tt.content)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			var errResp models.ErrorResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&errResp))
+			assert.Equal(t, tt.expectedCode, errResp.Code)
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/kitchen"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
+	"github.com/ravibandhu/oolio-food-ordering/internal/tenant"
+)
+
+// KitchenHandler handles order preparation HTTP requests
+type KitchenHandler struct {
+	queue *kitchen.Queue
+}
+
+// NewKitchenHandler creates a new KitchenHandler instance
+func NewKitchenHandler(queue *kitchen.Queue) *KitchenHandler {
+	return &KitchenHandler{
+		queue: queue,
+	}
+}
+
+// @Operation GET /orders/{id}/eta
+// @Summary Get an order's estimated ready time
+// @Description Get when the kitchen expects an order to be ready and, for delivery orders, delivered. Estimates update as the kitchen queue changes.
+// @Tags orders
+// @Produce json
+// @Param id path string true "Order ID"
+// @Success 200 {object} kitchen.Estimate
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /orders/{id}/eta [get]
+func (h *KitchenHandler) GetETA(c *gin.Context) {
+	orderID := c.Param("id")
+	estimate, ok := h.queueFor(c.Request.Context()).Estimate(orderID)
+	if !ok {
+		respond.Error(c, apierrors.New(apierrors.NotFound, "No estimate for order").AddDetail("id", orderID))
+		return
+	}
+
+	respond.JSON(c, http.StatusOK, estimate)
+}
+
+// @Operation GET /orders/{id}/timeline
+// @Summary Get an order's status timeline
+// @Description Get every status an order has been in, oldest first, with when it entered the status and who moved it there
+// @Tags orders
+// @Produce json
+// @Param id path string true "Order ID"
+// @Success 200 {array} models.StatusChange
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /orders/{id}/timeline [get]
+func (h *KitchenHandler) GetTimeline(c *gin.Context) {
+	orderID := c.Param("id")
+
+	// Read the kitchen first, so the changes it made since it was last
+	// used are in the order's history
+	timeline, queued := h.queueFor(c.Request.Context()).Timeline(orderID)
+	if store := tenantOrders(c.Request.Context()); store != nil {
+		if history, ok := store.History(orderID); ok {
+			respond.JSON(c, http.StatusOK, history.Order.StatusHistory)
+			return
+		}
+	}
+	if !queued {
+		respond.Error(c, apierrors.New(apierrors.NotFound, "No timeline for order").AddDetail("id", orderID))
+		return
+	}
+
+	respond.JSON(c, http.StatusOK, timeline)
+}
+
+// @Operation GET /admin/kitchen/orders
+// @Summary List the kitchen's tickets
+// @Description List the orders queued or being prepared, in the order the kitchen takes them on, with their items and the customer's notes
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Success 200 {array} kitchen.Ticket
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/kitchen/orders [get]
+func (h *KitchenHandler) ListTickets(c *gin.Context) {
+	respond.JSON(c, http.StatusOK, h.queueFor(c.Request.Context()).Tickets())
+}
+
+// @Operation GET /admin/kitchen/tables
+// @Summary List the kitchen's tickets by table
+// @Description List the dine-in orders queued or being prepared, grouped by the table they are served to. Tables are listed in the order the kitchen takes on their first order; takeaway and delivery orders are left out.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Success 200 {array} kitchen.TableTickets
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/kitchen/tables [get]
+func (h *KitchenHandler) ListTableTickets(c *gin.Context) {
+	respond.JSON(c, http.StatusOK, h.queueFor(c.Request.Context()).TicketsByTable())
+}
+
+// @Operation POST /admin/kitchen/orders/{id}/ready
+// @Summary Mark an order ready
+// @Description Record that the kitchen finished an order, moving up the estimates of the orders behind it
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "Order ID"
+// @Success 200 {object} kitchen.Estimate
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/kitchen/orders/{id}/ready [post]
+func (h *KitchenHandler) MarkReady(c *gin.Context) {
+	orderID := c.Param("id")
+	estimate, ok := h.queueFor(c.Request.Context()).MarkReady(orderID)
+	if !ok {
+		respond.Error(c, apierrors.New(apierrors.NotFound, "Order is not in the kitchen queue").AddDetail("id", orderID))
+		return
+	}
+	respond.JSON(c, http.StatusOK, estimate)
+}
+
+// queueFor returns the kitchen queue of the tenant carried by ctx
+func (h *KitchenHandler) queueFor(ctx context.Context) *kitchen.Queue {
+	if t, ok := tenant.FromContext(ctx); ok {
+		return t.Kitchen
+	}
+	return h.queue
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/kitchen"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKitchenHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	queue := kitchen.NewQueue(config.Kitchen{Stations: 1, DefaultPrepTime: 10 * time.Minute})
+	queue.Enqueue(&models.Order{ID: "order-1"})
+	queue.Enqueue(&models.Order{ID: "order-2"})
+
+	handler := NewKitchenHandler(queue)
+	engine := gin.New()
+	engine.GET("/orders/:id/eta", handler.GetETA)
+	engine.POST("/admin/kitchen/orders/:id/ready", handler.MarkReady)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+		expectedState  string
+	}{
+		{name: "queued order", method: http.MethodGet, path: "/orders/order-2/eta", expectedStatus: http.StatusOK, expectedState: kitchen.StatusQueued},
+		{name: "unknown order", method: http.MethodGet, path: "/orders/missing/eta", expectedStatus: http.StatusNotFound},
+		{name: "mark ready", method: http.MethodPost, path: "/admin/kitchen/orders/order-1/ready", expectedStatus: http.StatusOK, expectedState: kitchen.StatusReady},
+		{name: "next order starts", method: http.MethodGet, path: "/orders/order-2/eta", expectedStatus: http.StatusOK, expectedState: kitchen.StatusPreparing},
+		{name: "mark unknown order ready", method: http.MethodPost, path: "/admin/kitchen/orders/missing/ready", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus != http.StatusOK {
+				var errResp models.ErrorResponse
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&errResp))
+				assert.Equal(t, "NOT_FOUND", errResp.Code)
+				return
+			}
+
+			var estimate kitchen.Estimate
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&estimate))
+			assert.Equal(t, tt.expectedState, estimate.Status)
+		})
+	}
+}
+
+func TestKitchenHandler_Timeline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	queue := kitchen.NewQueue(config.Kitchen{Stations: 1, DefaultPrepTime: 10 * time.Minute})
+	queue.Enqueue(models.NewOrder(nil, nil, 10, ""))
+	order := models.NewOrder(nil, nil, 10, "")
+	queue.Enqueue(order)
+
+	handler := NewKitchenHandler(queue)
+	engine := gin.New()
+	engine.GET("/orders/:id/timeline", handler.GetTimeline)
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders/"+order.ID+"/timeline", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var timeline []models.StatusChange
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&timeline))
+	require.Len(t, timeline, 1)
+	assert.Equal(t, models.OrderStatusPlaced, timeline[0].Status)
+	assert.Equal(t, models.ActorCustomer, timeline[0].Actor)
+
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders/missing/timeline", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestKitchenHandler_ListTickets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	queue := kitchen.NewQueue(config.Kitchen{Stations: 1, DefaultPrepTime: 10 * time.Minute})
+	handler := NewKitchenHandler(queue)
+	engine := gin.New()
+	engine.GET("/admin/kitchen/orders", handler.ListTickets)
+
+	// An empty queue is an empty list
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/kitchen/orders", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, "[]", rec.Body.String())
+
+	queue.Enqueue(&models.Order{
+		ID:    "order-1",
+		Items: []models.OrderItem{{ProductID: "prod-1", Quantity: 1, Notes: "no onions"}},
+		Notes: "leave at door",
+	})
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/kitchen/orders", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var tickets []kitchen.Ticket
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&tickets))
+	require.Len(t, tickets, 1)
+	assert.Equal(t, "order-1", tickets[0].OrderID)
+	assert.Equal(t, "leave at door", tickets[0].Notes)
+	assert.Equal(t, "no onions", tickets[0].Items[0].Notes)
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/middleware"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+// link returns a link to the resource at path
+func link(path string) *models.Link {
+	return &models.Link{Href: path}
+}
+
+// productPath returns the path of a product
+func productPath(productID string) string {
+	return "/products/" + url.PathEscape(productID)
+}
+
+// withProductLinks returns product with links to itself and its reviews
+// when the server serves links. The product is copied rather than
+// modified.
+func withProductLinks(ctx context.Context, product *models.Product) *models.Product {
+	if !middleware.WantLinks(ctx) {
+		return product
+	}
+	out := *product
+	self := productPath(product.ID)
+	out.Links = &models.Links{Self: link(self), Reviews: link(self + "/reviews")}
+	return &out
+}
+
+// withOrderLinks returns order with links to its products and, when it was
+// queued for the kitchen, its estimate and timeline, when the server serves
+// links. The order is copied rather than modified.
+func withOrderLinks(ctx context.Context, order *models.Order) *models.Order {
+	if !middleware.WantLinks(ctx) {
+		return order
+	}
+	out := *order
+	out.Links = &models.Links{}
+	if order.EstimatedReadyAt != nil {
+		path := "/orders/" + url.PathEscape(order.ID)
+		out.Links.ETA = link(path + "/eta")
+		out.Links.Timeline = link(path + "/timeline")
+	}
+	seen := make(map[string]bool, len(order.Items))
+	for _, item := range order.Items {
+		if !seen[item.ProductID] {
+			seen[item.ProductID] = true
+			out.Links.Products = append(out.Links.Products, *link(productPath(item.ProductID)))
+		}
+	}
+	return &out
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/maintenance"
+	"github.com/ravibandhu/oolio-food-ordering/internal/middleware"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
+)
+
+// MaintenanceResponse lists the route groups of a restaurant that can be
+// put into maintenance, and the ones that are
+type MaintenanceResponse struct {
+	// Route groups that can be put into maintenance
+	// @example ["catalog", "ordering"]
+	Groups []string `json:"groups"`
+
+	// Route groups in maintenance, by group
+	Windows []maintenance.Window `json:"windows"`
+}
+
+// MaintenanceHandler handles HTTP requests for putting route groups into
+// maintenance
+type MaintenanceHandler struct {
+	maintenance *maintenance.Switch
+	groups      func() []string
+}
+
+// NewMaintenanceHandler creates a new MaintenanceHandler instance. groups
+// returns the route groups that can be put into maintenance; maint, the
+// switch of the default tenant, is used when requests are not routed
+// through the tenant middleware.
+func NewMaintenanceHandler(maint *maintenance.Switch, groups func() []string) *MaintenanceHandler {
+	return &MaintenanceHandler{
+		maintenance: maint,
+		groups:      groups,
+	}
+}
+
+// @Operation GET /admin/maintenance
+// @Summary List maintenance
+// @Description Get the route groups of the restaurant that can be put into maintenance, and the ones that are
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Success 200 {object} MaintenanceResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/maintenance [get]
+func (h *MaintenanceHandler) ListMaintenance(c *gin.Context) {
+	respond.JSON(c, http.StatusOK, MaintenanceResponse{
+		Groups:  h.groups(),
+		Windows: tenantMaintenance(c.Request.Context(), h.maintenance).Windows(),
+	})
+}
+
+// @Operation PUT /admin/maintenance/{group}
+// @Summary Put a route group into maintenance
+// @Description Refuse requests to a route group of the restaurant, such as ordering, with 503 MAINTENANCE, a Retry-After and the given message, until the maintenance is ended or reaches its end. Other groups, such as the catalog, stay available. Maintenance is not kept across restarts.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param group path string true "Route group"
+// @Param window body maintenance.Request true "Maintenance to start"
+// @Success 200 {object} maintenance.Window
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Router /admin/maintenance/{group} [put]
+func (h *MaintenanceHandler) StartMaintenance(c *gin.Context) {
+	group, ok := h.group(c)
+	if !ok {
+		return
+	}
+	req := middleware.Bound[maintenance.Request](c)
+
+	w, err := tenantMaintenance(c.Request.Context(), h.maintenance).Start(group, *req)
+	if err != nil {
+		respond.Error(c, apierrors.New(apierrors.ValidationError, "Invalid maintenance window").
+			AddDetail("error", err.Error()))
+		return
+	}
+	respond.JSON(c, http.StatusOK, w)
+}
+
+// @Operation DELETE /admin/maintenance/{group}
+// @Summary End maintenance
+// @Description Serve a route group of the restaurant again
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param group path string true "Route group"
+// @Success 204
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/maintenance/{group} [delete]
+func (h *MaintenanceHandler) EndMaintenance(c *gin.Context) {
+	group, ok := h.group(c)
+	if !ok {
+		return
+	}
+
+	err := tenantMaintenance(c.Request.Context(), h.maintenance).End(group)
+	if errors.Is(err, maintenance.ErrNotFound) {
+		respond.Error(c, apierrors.New(apierrors.NotFound, "Route group is not in maintenance").
+			AddDetail("group", group))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// group returns the route group named in the path, answering with 404 when
+// it cannot be put into maintenance
+func (h *MaintenanceHandler) group(c *gin.Context) (string, bool) {
+	group := c.Param("group")
+	if groups := h.groups(); !slices.Contains(groups, group) {
+		respond.Error(c, apierrors.New(apierrors.NotFound, "Unknown route group").
+			AddDetail("group", group).
+			AddDetail("groups", groups))
+		return "", false
+	}
+	return group, true
+}
@@ -1,10 +1,14 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
+	"strconv"
 
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/middleware"
 	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
 	"github.com/ravibandhu/oolio-food-ordering/internal/services"
 )
 
@@ -20,66 +24,86 @@ func NewOrderHandler(orderService services.OrderService) *OrderHandler {
 	}
 }
 
-// @Operation POST /order
+// @Operation POST /orders
 // @Summary Place a new order
-// @Description Place a new order with optional coupon code
+// @Description Place a new order with optional coupon code. With dry_run=true, or an X-Dry-Run: true header, the order is checked and priced as it would be placed, including its coupon and stock, and returned with 200 without being placed: no stock is taken, no kitchen ticket queued, no payment captured, no invoice numbered and no order kept.
 // @Tags orders
 // @Accept json
 // @Produce json
 // @Param order body models.OrderRequest true "Order to place"
+// @Param X-Challenge-Token header string false "Token of a solved bot challenge, required without an API key when orders are challenged"
+// @Param dry_run query bool false "Check and price the order without placing it"
+// @Param Idempotency-Key header string false "Key to retry the request with; a retry of a request that succeeded is answered with the original response"
+// @Param X-Dry-Run header bool false "Check and price the order without placing it, when dry_run is not given"
+// @Success 200 {object} models.Order
 // @Success 201 {object} models.Order
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
 // @Failure 422 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
-// @Router /order [post]
-func (h *OrderHandler) PlaceOrder(w http.ResponseWriter, r *http.Request) {
-	// Set content type header for all responses
-	w.Header().Set("Content-Type", "application/json")
-
-	// Parse request body
-	var req models.OrderRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		errResp := models.NewErrorResponse("INVALID_REQUEST", "Failed to parse request body").
-			AddDetail("error", err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(errResp)
+// @Failure 502 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Failure 504 {object} models.ErrorResponse
+// @Router /orders [post]
+func (h *OrderHandler) PlaceOrder(c *gin.Context) {
+	req := middleware.Bound[models.OrderRequest](c)
+	dryRun, err := dryRunRequested(c)
+	if err != nil {
+		respond.Error(c, apierrors.New(apierrors.InvalidRequest, "Invalid dry run flag").
+			AddDetail("error", "dry_run and X-Dry-Run must be true or false"))
 		return
 	}
-
-	// Validate request
-	if err := models.Validate(&req); err != nil {
-		errResp := models.NewErrorResponse("VALIDATION_ERROR", "Invalid request data").
-			AddDetail("error", err.Error())
-		w.WriteHeader(http.StatusUnprocessableEntity)
-		json.NewEncoder(w).Encode(errResp)
-		return
+	ctx := c.Request.Context()
+	if dryRun {
+		ctx = services.NewDryRunContext(ctx)
 	}
 
 	// Process order
-	order, err := h.orderService.PlaceOrder(&req)
+	order, err := h.orderService.PlaceOrder(ctx, req)
 	if err != nil {
-		// Check if it's a known error type
-		if errResp, ok := err.(*models.ErrorResponse); ok {
-			w.WriteHeader(http.StatusUnprocessableEntity)
-			json.NewEncoder(w).Encode(errResp)
-			return
-		}
-
-		// Unknown error
-		errResp := models.NewErrorResponse("ORDER_FAILED", "Failed to place order").
-			AddDetail("error", err.Error())
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(errResp)
+		orderError(c, err)
 		return
 	}
 
-	// Return successful response
-	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(order); err != nil {
-		errResp := models.NewErrorResponse("INTERNAL_ERROR", "Failed to encode response").
-			AddDetail("error", err.Error())
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(errResp)
+	// Return successful response; nothing was created by a dry run
+	status := http.StatusCreated
+	if dryRun {
+		status = http.StatusOK
+	}
+	respond.JSON(c, status, withOrderLinks(ctx, order))
+}
+
+// dryRunRequested reports whether the request asks for a dry run, by its
+// dry_run query parameter or, without one, its X-Dry-Run header
+func dryRunRequested(c *gin.Context) (bool, error) {
+	raw, ok := c.GetQuery("dry_run")
+	if !ok {
+		raw = c.GetHeader("X-Dry-Run")
+	}
+	if raw == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(raw)
+}
+
+// orderError answers with the error placing an order failed with
+func orderError(c *gin.Context, err error) {
+	// Known errors carry a code from the catalog, which sets the status
+	if errResp, ok := err.(*models.ErrorResponse); ok {
+		// A busy kitchen tells the client when it will have room
+		if retryAfter, ok := errResp.Details["retry_after"].(int); ok {
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+		}
+		respond.Error(c, errResp)
 		return
 	}
+
+	// Unknown error
+	respond.Error(c, apierrors.New(apierrors.OrderFailed, "Failed to place order").
+		AddDetail("error", err.Error()))
 }
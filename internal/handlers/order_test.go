@@ -2,12 +2,14 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/ravibandhu/oolio-food-ordering/internal/middleware"
 	"github.com/ravibandhu/oolio-food-ordering/internal/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -18,7 +20,7 @@ type MockOrderService struct {
 	mock.Mock
 }
 
-func (m *MockOrderService) PlaceOrder(req *models.OrderRequest) (*models.Order, error) {
+func (m *MockOrderService) PlaceOrder(ctx context.Context, req *models.OrderRequest) (*models.Order, error) {
 	args := m.Called(req)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -161,18 +163,61 @@ func TestPlaceOrder(t *testing.T) {
 			},
 			setupMock: func(m *MockOrderService) {
 				m.On("PlaceOrder", mock.AnythingOfType("*models.OrderRequest")).Return(nil,
-					models.NewErrorResponse("PRODUCT_NOT_FOUND", "Product not found").
+					models.NewErrorResponse("INVALID_PRODUCT", "Product not found").
 						AddDetail("productId", "prod-1"))
 			},
-			expectedStatus: http.StatusUnprocessableEntity,
+			expectedStatus: http.StatusNotFound,
 			expectedBody: map[string]interface{}{ // Use map instead of struct for flexible comparison
-				"code":    "PRODUCT_NOT_FOUND",
+				"code":    "INVALID_PRODUCT",
 				"message": "Product not found",
 				"details": map[string]interface{}{
 					"productId": "prod-1",
 				},
 			},
 		},
+		{
+			name: "invalid coupon",
+			requestBody: models.OrderRequest{
+				Items: []models.OrderItem{
+					{
+						ProductID: "prod-1",
+						Quantity:  2,
+						Price:     9.99,
+					},
+				},
+				CouponCode: "EXPIRED",
+			},
+			setupMock: func(m *MockOrderService) {
+				m.On("PlaceOrder", mock.AnythingOfType("*models.OrderRequest")).Return(nil,
+					models.NewErrorResponse("INVALID_COUPON", "Invalid coupon code"))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: map[string]interface{}{
+				"code":    "INVALID_COUPON",
+				"message": "Invalid coupon code",
+			},
+		},
+		{
+			name: "store closed",
+			requestBody: models.OrderRequest{
+				Items: []models.OrderItem{
+					{
+						ProductID: "prod-1",
+						Quantity:  2,
+						Price:     9.99,
+					},
+				},
+			},
+			setupMock: func(m *MockOrderService) {
+				m.On("PlaceOrder", mock.AnythingOfType("*models.OrderRequest")).Return(nil,
+					models.NewErrorResponse("STORE_CLOSED", "The restaurant is not accepting orders right now"))
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody: map[string]interface{}{
+				"code":    "STORE_CLOSED",
+				"message": "The restaurant is not accepting orders right now",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -191,11 +236,11 @@ func TestPlaceOrder(t *testing.T) {
 			}
 
 			// Create test request and response recorder
-			req := httptest.NewRequest(http.MethodPost, "/order", &body)
+			req := httptest.NewRequest(http.MethodPost, "/orders", &body)
 			rec := httptest.NewRecorder()
 
 			// Call handler
-			handler.PlaceOrder(rec, req)
+			serve(rec, req, "/orders", middleware.Bind[models.OrderRequest](), handler.PlaceOrder)
 
 			// Check status code
 			assert.Equal(t, tt.expectedStatus, rec.Code)
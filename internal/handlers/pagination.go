@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/cursor"
+	"github.com/ravibandhu/oolio-food-ordering/internal/sorting"
+)
+
+// NextCursorHeader carries the cursor of the next page of listings, the only
+// place it is given for listings served as a bare array
+const NextCursorHeader = "X-Next-Cursor"
+
+// maxPageLimit bounds how many items a page can hold
+const maxPageLimit = 1000
+
+// Listings cursors page through, so a cursor of one is rejected by another.
+// Each sort of a listing pages through it differently, so the sort is part
+// of the listing, see sortedListing.
+const (
+	productListing = "products"
+	orderListing   = "orders"
+)
+
+// Sorts listings have unless the sort query parameter is given
+const (
+	defaultProductSort = "id"
+	defaultOrderSort   = "created_at"
+)
+
+// page is a page of a listing requested with the limit and cursor query
+// parameters
+type page[K any] struct {
+	limit  int  // Most items to return; zero for no limit
+	after  K    // Sort key of the last item of the previous page
+	cursor bool // Whether the page continues from a cursor
+}
+
+// parsePage reads the page of listing requested by query, with
+// defaultLimit items when no limit is given
+func parsePage[K any](query url.Values, listing string, defaultLimit int) (page[K], error) {
+	p := page[K]{limit: defaultLimit}
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return p, fmt.Errorf("limit: must be an integer from 1 to %d", maxPageLimit)
+		}
+		p.limit = limit
+	}
+	if raw := query.Get("cursor"); raw != "" {
+		after, err := cursor.Decode[K](listing, raw)
+		if err != nil {
+			return p, fmt.Errorf("cursor: %w", err)
+		}
+		p.after, p.cursor = after, true
+	}
+	return p, nil
+}
+
+// parseSort reads the order a listing sortable by fields is requested in by
+// the sort query parameter, or defaultSort when none is given
+func parseSort[T any](query url.Values, fields sorting.Fields[T], defaultSort string) (sorting.Order, error) {
+	raw := query.Get("sort")
+	if raw == "" {
+		raw = defaultSort
+	}
+	order, err := fields.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("sort: %w", err)
+	}
+	return order, nil
+}
+
+// sortedListing returns the name cursors of listing sorted in order are
+// issued for
+func sortedListing(listing string, order sorting.Order) string {
+	return listing + "?sort=" + order.String()
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/middleware"
+	"github.com/ravibandhu/oolio-food-ordering/internal/pos"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
+	"github.com/ravibandhu/oolio-food-ordering/internal/tenant"
+)
+
+// Page sizes of the order feed
+const (
+	defaultExportLimit = 100
+	maxExportLimit     = 1000
+)
+
+// POSHandler handles HTTP requests from a restaurant's point-of-sale system
+type POSHandler struct {
+	inventory *pos.Inventory
+	exports   *pos.Exports
+}
+
+// NewPOSHandler creates a new POSHandler instance
+func NewPOSHandler(inventory *pos.Inventory, exports *pos.Exports) *POSHandler {
+	return &POSHandler{
+		inventory: inventory,
+		exports:   exports,
+	}
+}
+
+// @Operation GET /pos/inventory
+// @Summary List stock levels
+// @Description Get the stock level of every SKU the POS has reported, sorted by SKU, less the units ordered online since
+// @Tags pos
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Success 200 {array} pos.Level
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /pos/inventory [get]
+func (h *POSHandler) ListInventory(c *gin.Context) {
+	inventory, _ := h.forTenant(c.Request.Context())
+	respond.JSON(c, http.StatusOK, inventory.List())
+}
+
+// @Operation PUT /pos/inventory
+// @Summary Sync stock levels
+// @Description Set the stock levels of SKUs, keyed by the SKUs of products and variants. Quantities are absolute and updates as old as or older than the level held are ignored, so a sync can be retried safely. Orders for items with too few units in stock fail with OUT_OF_STOCK; SKUs never reported are not limited.
+// @Tags pos
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param inventory body pos.InventoryRequest true "Stock levels"
+// @Success 200 {object} pos.InventoryResult
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /pos/inventory [put]
+func (h *POSHandler) SyncInventory(c *gin.Context) {
+	req := middleware.Bound[pos.InventoryRequest](c)
+
+	inventory, _ := h.forTenant(c.Request.Context())
+	respond.JSON(c, http.StatusOK, inventory.Sync(req.Levels))
+}
+
+// @Operation GET /pos/orders
+// @Summary Collect placed orders
+// @Description Get the orders placed online after a sequence, oldest first. Pass the returned next as after to collect the following page. Orders are kept in memory: the most recent 10000 are kept, and the feed restarts with a new ID when the server restarts.
+// @Tags pos
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param after query integer false "Sequence of the last order collected (default 0)"
+// @Param limit query integer false "Most orders to return, up to 1000 (default 100)"
+// @Success 200 {object} pos.ExportPage
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /pos/orders [get]
+func (h *POSHandler) ListOrders(c *gin.Context) {
+	after, limit := uint64(0), defaultExportLimit
+	var err error
+	if raw := c.Query("after"); raw != "" {
+		if after, err = strconv.ParseUint(raw, 10, 64); err != nil {
+			respond.Error(c, apierrors.New(apierrors.InvalidRequest, "Invalid cursor").
+				AddDetail("error", "after must be a non-negative integer"))
+			return
+		}
+	}
+	if raw := c.Query("limit"); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 1 || limit > maxExportLimit {
+			respond.Error(c, apierrors.New(apierrors.InvalidRequest, "Invalid limit").
+				AddDetail("error", "limit must be an integer from 1 to 1000"))
+			return
+		}
+	}
+
+	_, exports := h.forTenant(c.Request.Context())
+	respond.JSON(c, http.StatusOK, exports.After(after, limit))
+}
+
+// forTenant returns the stock levels and order feed of the tenant carried by
+// ctx
+func (h *POSHandler) forTenant(ctx context.Context) (*pos.Inventory, *pos.Exports) {
+	if t, ok := tenant.FromContext(ctx); ok {
+		return t.Inventory, t.Exports
+	}
+	return h.inventory, h.exports
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/middleware"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/pos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPOSHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	exports := pos.NewExports()
+	exports.Record(&models.Order{ID: "order-1", Status: models.OrderStatusPlaced})
+	exports.Record(&models.Order{ID: "order-2", Status: models.OrderStatusOnHold})
+
+	handler := NewPOSHandler(pos.NewInventory(), exports)
+	engine := gin.New()
+	engine.GET("/pos/inventory", handler.ListInventory)
+	engine.PUT("/pos/inventory", middleware.Bind[pos.InventoryRequest](), handler.SyncInventory)
+	engine.GET("/pos/orders", handler.ListOrders)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{name: "sync", method: http.MethodPut, path: "/pos/inventory", body: `{"levels":[{"sku":"WAF-BER","quantity":12,"updated_at":"2024-01-01T00:00:00Z"}]}`, expectedStatus: http.StatusOK},
+		{name: "sync again", method: http.MethodPut, path: "/pos/inventory", body: `{"levels":[{"sku":"WAF-BER","quantity":12,"updated_at":"2024-01-01T00:00:00Z"}]}`, expectedStatus: http.StatusOK},
+		{name: "sync without quantity", method: http.MethodPut, path: "/pos/inventory", body: `{"levels":[{"sku":"WAF-BER"}]}`, expectedStatus: http.StatusUnprocessableEntity, expectedCode: "VALIDATION_ERROR"},
+		{name: "sync repeated sku", method: http.MethodPut, path: "/pos/inventory", body: `{"levels":[{"sku":"WAF-BER","quantity":1},{"sku":"WAF-BER","quantity":2}]}`, expectedStatus: http.StatusUnprocessableEntity, expectedCode: "VALIDATION_ERROR"},
+		{name: "sync malformed", method: http.MethodPut, path: "/pos/inventory", body: `{"levels":`, expectedStatus: http.StatusBadRequest, expectedCode: "INVALID_REQUEST"},
+		{name: "list", method: http.MethodGet, path: "/pos/inventory", expectedStatus: http.StatusOK},
+		{name: "collect orders", method: http.MethodGet, path: "/pos/orders?after=1", expectedStatus: http.StatusOK},
+		{name: "collect orders with invalid cursor", method: http.MethodGet, path: "/pos/orders?after=-1", expectedStatus: http.StatusBadRequest, expectedCode: "INVALID_REQUEST"},
+		{name: "collect orders with invalid limit", method: http.MethodGet, path: "/pos/orders?limit=0", expectedStatus: http.StatusBadRequest, expectedCode: "INVALID_REQUEST"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedCode != "" {
+				var errResp models.ErrorResponse
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&errResp))
+				assert.Equal(t, tt.expectedCode, errResp.Code)
+			}
+		})
+	}
+
+	levels := handler.inventory.List()
+	require.Len(t, levels, 1)
+	assert.Equal(t, 12, levels[0].Quantity)
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/pos/orders?after=1", nil))
+	var page pos.ExportPage
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&page))
+	require.Len(t, page.Orders, 1)
+	assert.Equal(t, "order-2", page.Orders[0].OrderID)
+	assert.Equal(t, uint64(2), page.Next)
+}
@@ -1,14 +1,31 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/cursor"
 	"github.com/ravibandhu/oolio-food-ordering/internal/data"
+	"github.com/ravibandhu/oolio-food-ordering/internal/i18n"
+	"github.com/ravibandhu/oolio-food-ordering/internal/images"
+	"github.com/ravibandhu/oolio-food-ordering/internal/middleware"
 	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
+	"github.com/ravibandhu/oolio-food-ordering/internal/reviews"
 )
 
+// CatalogRevisionHeader carries the catalog revision products were served
+// from, which clients send back with orders
+const CatalogRevisionHeader = "X-Catalog-Revision"
+
 // swagger:parameters getProduct
 type productIDParam struct {
 	// ID of the product to retrieve
@@ -42,84 +59,164 @@ type productResponse struct {
 // ProductHandler handles product-related HTTP requests
 type ProductHandler struct {
 	store *data.Store
+	now   func() time.Time
 }
 
 // NewProductHandler creates a new ProductHandler instance
 func NewProductHandler(store *data.Store) *ProductHandler {
 	return &ProductHandler{
 		store: store,
+		now:   time.Now,
 	}
 }
 
 // @Operation GET /products
 // @Summary List all available products
-// @Description Get a list of all available products in the system
+// @Description Get a list of all available products on the menus being served, or on the menu given, sorted by ID unless sort is given, optionally filtered by dietary requirements, allergens and calories, and paged with limit and cursor. Names, descriptions and categories are translated into the language requested with lang or Accept-Language where available.
 // @Tags products
 // @Produce json
+// @Param lang query string false "Language to serve product text in, overriding Accept-Language"
+// @Param Accept-Language header string false "Languages to serve product text in"
+// @Param dietary query string false "Comma-separated dietary tags every product must meet, e.g. vegan,gluten-free"
+// @Param exclude_allergens query string false "Comma-separated allergens no product may contain, e.g. peanuts,milk"
+// @Param max_calories query int false "Maximum calories per serving; products without calorie information are excluded"
+// @Param include_deleted query bool false "Also list deleted products; requires the admin role"
+// @Param menu query string false "Menu to list, whether or not it is being served; the menus being served by default"
+// @Param sort query string false "Comma-separated fields to sort by, each prefixed with - for largest first: id, name, price, category, created_at or updated_at; ties are broken by ID" default(id)
+// @Param limit query int false "Most products to return, up to 1000; all by default"
+// @Param cursor query string false "Cursor from X-Next-Cursor, to continue after the previous page"
 // @Success 200 {array} models.Product
+// @Header 200 {integer} X-Catalog-Revision "Catalog revision to send with orders as catalogRevision"
+// @Header 200 {string} X-Next-Cursor "Cursor of the next page, when more products follow"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
 // @Router /products [get]
-func (h *ProductHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
-	// Get all products from the store
-	products := h.store.GetAllProducts()
-
-	// Set content type header
-	w.Header().Set("Content-Type", "application/json")
-
-	// Encode and send response
-	if err := json.NewEncoder(w).Encode(products); err != nil {
-		errResp := models.NewErrorResponse("INTERNAL_ERROR", "Failed to encode response").
-			AddDetail("error", err.Error())
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(errResp)
+func (h *ProductHandler) ListProducts(c *gin.Context) {
+	// Product text depends on the requested language
+	c.Writer.Header().Add("Vary", "Accept-Language")
+
+	query := c.Request.URL.Query()
+	filter, err := parseProductFilter(query)
+	if err != nil {
+		respond.Error(c, apierrors.New(apierrors.InvalidRequest, "Invalid product filter").
+			AddDetail("error", err.Error()))
+		return
+	}
+	order, err := parseSort(query, data.ProductSortFields, defaultProductSort)
+	if err != nil {
+		respond.Error(c, apierrors.New(apierrors.InvalidRequest, "Invalid sort").
+			AddDetail("error", err.Error()))
+		return
+	}
+	served := tenantMenus(c.Request.Context())
+	offered := served.Active(h.now())
+	if name := query.Get("menu"); name != "" {
+		if offered, err = served.Named(name); err != nil {
+			respond.Error(c, apierrors.New(apierrors.InvalidRequest, "Unknown menu").
+				AddDetail("menu", name).
+				AddDetail("menus", served.All().Names()))
+			return
+		}
+	}
+	listing := sortedListing(productListing, order)
+	pg, err := parsePage[*models.Product](query, listing, 0)
+	if err != nil {
+		respond.Error(c, apierrors.New(apierrors.InvalidRequest, "Invalid page").
+			AddDetail("error", err.Error()))
 		return
 	}
+
+	// Get all products from the store. The revision is read first, so it is
+	// never newer than the products served.
+	ctx := c.Request.Context()
+	store := tenantStore(ctx, h.store)
+	c.Header(CatalogRevisionHeader, strconv.FormatUint(store.Revision(), 10))
+	ratings := tenantReviews(ctx, nil)
+	signer := tenantSigner(ctx)
+	preferred, fallback := i18n.Preferred(c.Request), tenantLocale(ctx)
+	products := make([]*models.Product, 0)
+
+	// List products in order, starting after the last one of the previous
+	// page
+	all := store.SortedProducts(order)
+	start := 0
+	if pg.cursor {
+		compare := data.ProductSortFields.Compare(order, data.CompareProductIDs)
+		start = cursor.Start(all, pg.after, productSortKey, func(a, b *models.Product) bool { return compare(a, b) < 0 })
+	}
+	for _, product := range all[start:] {
+		if !filter.matches(product) || !offered.Offers(product) {
+			continue
+		}
+		if pg.limit > 0 && len(products) == pg.limit {
+			c.Header(NextCursorHeader, cursor.Encode(listing, productSortKey(products[len(products)-1])))
+			break
+		}
+		localized, _ := i18n.Localize(withRating(product, ratings), preferred, fallback)
+		signed, err := withSignedImage(withProductLinks(ctx, localized), signer)
+		if err != nil {
+			respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to sign image URLs").
+				AddDetail("productId", product.ID).
+				AddDetail("error", err.Error()))
+			return
+		}
+		products = append(products, signed)
+	}
+
+	respond.JSON(c, http.StatusOK, products)
 }
 
 // @Operation GET /products/{id}
 // @Summary Get a specific product
-// @Description Get detailed information about a specific product by its ID
+// @Description Get detailed information about a specific product by its ID, translated into the language requested with lang or Accept-Language where available. Deleted products are still returned, with deleted_at set, so orders that reference them can be rendered.
 // @Tags products
 // @Param id path string true "Product ID"
+// @Param lang query string false "Language to serve product text in, overriding Accept-Language"
+// @Param Accept-Language header string false "Languages to serve product text in"
 // @Produce json
 // @Success 200 {object} models.Product
+// @Header 200 {integer} X-Catalog-Revision "Catalog revision to send with orders as catalogRevision"
+// @Header 200 {string} ETag "Version of the product to send as If-Match when updating it"
 // @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
 // @Router /products/{id} [get]
-func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
-	// Extract product ID from URL path
-	path := r.URL.Path
-	parts := strings.Split(path, "/")
-	if len(parts) < 3 {
-		errResp := models.NewErrorResponse("INVALID_REQUEST", "Invalid product ID")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(errResp)
-		return
-	}
-	productID := parts[len(parts)-1]
+func (h *ProductHandler) GetProduct(c *gin.Context) {
+	// Product text depends on the requested language
+	c.Writer.Header().Add("Vary", "Accept-Language")
+	ctx := c.Request.Context()
+	productID := c.Param("id")
 
 	// Get product from store
-	product, err := h.store.GetProduct(productID)
+	store := tenantStore(ctx, h.store)
+	revision := store.Revision()
+	product, err := store.GetProduct(productID)
 	if err != nil {
-		errResp := models.NewErrorResponse("NOT_FOUND", "Product not found").
+		respond.Error(c, apierrors.New(apierrors.NotFound, "Product not found").
 			AddDetail("productId", productID).
-			AddDetail("error", err.Error())
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(errResp)
+			AddDetail("error", err.Error()))
 		return
 	}
 
-	// Set content type header
-	w.Header().Set("Content-Type", "application/json")
+	// Serve the product in the requested language
+	localized, locale := i18n.Localize(withRating(product, tenantReviews(ctx, nil)), i18n.Preferred(c.Request), tenantLocale(ctx))
+	c.Header("Content-Language", locale)
+	c.Header(CatalogRevisionHeader, strconv.FormatUint(revision, 10))
+	c.Header("ETag", data.ETag(product))
 
-	// Encode and send response
-	if err := json.NewEncoder(w).Encode(product); err != nil {
-		errResp := models.NewErrorResponse("INTERNAL_ERROR", "Failed to encode response").
-			AddDetail("error", err.Error())
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(errResp)
+	// Sign image URLs when a CDN serves them from a private bucket
+	signed, err := withSignedImage(withProductLinks(ctx, localized), tenantSigner(ctx))
+	if err != nil {
+		respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to sign image URLs").
+			AddDetail("productId", productID).
+			AddDetail("error", err.Error()))
 		return
 	}
+
+	respond.JSON(c, http.StatusOK, signed)
 }
 
 // @Operation POST /products
@@ -128,11 +225,287 @@ func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 // @Tags products
 // @Accept json
 // @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Param product body models.Product true "Product object to create"
 // @Success 201 {object} models.Product
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
 // @Router /products [post]
-func CreateProduct(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement
+func (h *ProductHandler) CreateProduct(c *gin.Context) {
+	product := middleware.Bound[models.Product](c)
+
+	// Stamp timestamps server-side; products are only deleted with DELETE
+	now := time.Now()
+	product.CreatedAt = now
+	product.UpdatedAt = now
+	product.DeletedAt = nil
+	product.Links = nil // Links are served, never stored
+
+	// Store product
+	if err := tenantStore(c.Request.Context(), h.store).AddProduct(product); err != nil {
+		if errors.Is(err, data.ErrProductExists) {
+			respond.Error(c, apierrors.New(apierrors.ProductExists, "Product already exists").
+				AddDetail("productId", product.ID))
+			return
+		}
+
+		respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to create product").
+			AddDetail("error", err.Error()))
+		return
+	}
+
+	// Return created product
+	respond.JSON(c, http.StatusCreated, withProductLinks(c.Request.Context(), product))
+}
+
+// ProductIDFromPath fills the ID of a product bound for PUT /products/{id}
+// from the path when the body leaves it out, so it can be validated. An ID
+// in the body cannot differ from the path.
+func ProductIDFromPath(c *gin.Context, product *models.Product) *models.ErrorResponse {
+	productID := c.Param("id")
+	if product.ID == "" {
+		product.ID = productID
+	}
+	if product.ID != productID {
+		return apierrors.New(apierrors.InvalidRequest, "Product ID does not match the path").
+			AddDetail("productId", productID)
+	}
+	return nil
+}
+
+// @Operation PUT /products/{id}
+// @Summary Update a product
+// @Description Replace a product with the provided information. Send the ETag from GET /products/{id} as If-Match so concurrent edits cannot silently overwrite each other.
+// @Tags products
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "Product ID"
+// @Param If-Match header string false "ETag the update was based on; the update fails if the product has changed since"
+// @Param product body models.Product true "Product to store"
+// @Success 200 {object} models.Product
+// @Header 200 {string} ETag "Version of the updated product"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 412 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /products/{id} [put]
+func (h *ProductHandler) UpdateProduct(c *gin.Context) {
+	ctx := c.Request.Context()
+	productID := c.Param("id")
+
+	product := middleware.Bound[models.Product](c)
+
+	store := tenantStore(ctx, h.store)
+	current, err := store.GetProduct(productID)
+	if err != nil {
+		respond.Error(c, apierrors.New(apierrors.NotFound, "Product not found").
+			AddDetail("productId", productID))
+		return
+	}
+
+	// Keep the creation time and stamp the update server-side; products are
+	// only deleted with DELETE
+	product.CreatedAt = current.CreatedAt
+	product.UpdatedAt = time.Now()
+	product.DeletedAt = nil
+	product.Links = nil // Links are served, never stored
+
+	// Store product, provided it has not changed since the client read it
+	if err := store.UpdateProductIfMatch(product, ifMatch(c.GetHeader("If-Match"), current)); err != nil {
+		var errResp *models.ErrorResponse
+		switch {
+		case errors.Is(err, data.ErrProductChanged):
+			errResp = apierrors.New(apierrors.PreconditionFailed, "Product has changed since it was read").
+				AddDetail("productId", productID)
+		case errors.Is(err, data.ErrProductNotFound):
+			errResp = apierrors.New(apierrors.NotFound, "Product not found").
+				AddDetail("productId", productID)
+		default:
+			errResp = apierrors.New(apierrors.InternalError, "Failed to update product").
+				AddDetail("error", err.Error())
+		}
+		respond.Error(c, errResp)
+		return
+	}
+
+	// Return updated product
+	c.Header("ETag", data.ETag(product))
+	respond.JSON(c, http.StatusOK, withProductLinks(ctx, product))
+}
+
+// @Operation DELETE /products/{id}
+// @Summary Delete a product
+// @Description Mark a product as deleted. Deleted products can no longer be ordered, updated or listed, except with include_deleted, but can still be fetched by ID so orders that reference them still render.
+// @Tags products
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "Product ID"
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /products/{id} [delete]
+func (h *ProductHandler) DeleteProduct(c *gin.Context) {
+	productID := c.Param("id")
+	if err := tenantStore(c.Request.Context(), h.store).DeleteProduct(productID); err != nil {
+		var errResp *models.ErrorResponse
+		if errors.Is(err, data.ErrProductNotFound) {
+			errResp = apierrors.New(apierrors.NotFound, "Product not found").
+				AddDetail("productId", productID)
+		} else {
+			errResp = apierrors.New(apierrors.InternalError, "Failed to delete product").
+				AddDetail("error", err.Error())
+		}
+		respond.Error(c, errResp)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ifMatch returns the ETag an update must be conditional on: empty without
+// an If-Match header, the current ETag when the header lists it or is "*",
+// and otherwise the first listed tag, which will not match
+func ifMatch(header string, current *models.Product) string {
+	if header == "" {
+		return ""
+	}
+	etag := data.ETag(current)
+	tags := strings.Split(header, ",")
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag == "*" || tag == etag {
+			return etag
+		}
+	}
+	return strings.TrimSpace(tags[0])
+}
+
+// withRating returns product with the aggregate of its approved reviews. The
+// stored product is copied rather than modified.
+func withRating(product *models.Product, ratings *reviews.Store) *models.Product {
+	rating := ratings.Rating(product.ID)
+	if rating == nil {
+		return product
+	}
+	out := *product
+	out.Rating = rating
+	return &out
+}
+
+// withSignedImage returns product with its image URLs signed by signer. The
+// product is copied rather than modified.
+func withSignedImage(product *models.Product, signer *images.Signer) (*models.Product, error) {
+	if signer == nil || product.Image == nil {
+		return product, nil
+	}
+	img, err := signer.SignImage(product.Image)
+	if err != nil {
+		return nil, err
+	}
+	out := *product
+	out.Image = img
+	return &out, nil
+}
+
+// productFilter narrows the product list by dietary information
+type productFilter struct {
+	dietary          []string
+	excludeAllergens []string
+	maxCalories      *int
+	includeDeleted   bool
+}
+
+// parseProductFilter reads a productFilter from list query parameters
+func parseProductFilter(query url.Values) (productFilter, error) {
+	var f productFilter
+	var err error
+	if f.dietary, err = parseTags(query.Get("dietary"), models.DietaryTags); err != nil {
+		return f, fmt.Errorf("dietary: %w", err)
+	}
+	if f.excludeAllergens, err = parseTags(query.Get("exclude_allergens"), models.Allergens); err != nil {
+		return f, fmt.Errorf("exclude_allergens: %w", err)
+	}
+	if raw := query.Get("max_calories"); raw != "" {
+		calories, err := strconv.Atoi(raw)
+		if err != nil || calories < 0 {
+			return f, fmt.Errorf("max_calories: must be a non-negative integer")
+		}
+		f.maxCalories = &calories
+	}
+	if raw := query.Get("include_deleted"); raw != "" {
+		if f.includeDeleted, err = strconv.ParseBool(raw); err != nil {
+			return f, fmt.Errorf("include_deleted: must be true or false")
+		}
+	}
+	return f, nil
+}
+
+// parseTags splits a comma-separated list, rejecting values not in allowed
+func parseTags(raw string, allowed []string) ([]string, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var tags []string
+	for _, tag := range strings.Split(raw, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !slices.Contains(allowed, tag) {
+			return nil, fmt.Errorf("unknown value %q", tag)
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// productSortKey returns the fields of a product listings can be sorted by,
+// which is all a cursor needs to find its place in any sort
+func productSortKey(product *models.Product) *models.Product {
+	return &models.Product{
+		ID:        product.ID,
+		Name:      product.Name,
+		Price:     product.Price,
+		Category:  product.Category,
+		CreatedAt: product.CreatedAt,
+		UpdatedAt: product.UpdatedAt,
+	}
+}
+
+// matches reports whether product meets every condition of the filter
+func (f productFilter) matches(product *models.Product) bool {
+	if product.DeletedAt != nil && !f.includeDeleted {
+		return false
+	}
+	for _, tag := range f.dietary {
+		if !slices.Contains(product.Dietary, tag) {
+			return false
+		}
+	}
+	for _, allergen := range f.excludeAllergens {
+		if slices.Contains(product.Allergens, allergen) {
+			return false
+		}
+	}
+	if f.maxCalories != nil && (product.Calories == nil || *product.Calories > *f.maxCalories) {
+		return false
+	}
+	return true
 }
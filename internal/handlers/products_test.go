@@ -7,13 +7,21 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/ravibandhu/oolio-food-ordering/internal/config"
 	"github.com/ravibandhu/oolio-food-ordering/internal/data"
+	"github.com/ravibandhu/oolio-food-ordering/internal/images"
+	"github.com/ravibandhu/oolio-food-ordering/internal/menus"
+	"github.com/ravibandhu/oolio-food-ordering/internal/middleware"
 	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/tenant"
+	"github.com/ravibandhu/oolio-food-ordering/internal/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func setupTestData(t *testing.T) (string, string, *config.Config, func()) {
@@ -43,6 +51,12 @@ func setupTestData(t *testing.T) (string, string, *config.Config, func()) {
 			"description": "Test Description 1",
 			"price": 9.99,
 			"category": "Test Category",
+			"translations": {
+				"fr": {"name": "Produit de test 1", "category": "Catégorie de test"}
+			},
+			"dietary": ["vegan", "gluten-free"],
+			"allergens": ["soy"],
+			"calories": 350,
 			"image": {
 				"thumbnail": "https://example.com/images/test1-thumb.jpg",
 				"mobile": "https://example.com/images/test1-mobile.jpg",
@@ -58,6 +72,9 @@ func setupTestData(t *testing.T) (string, string, *config.Config, func()) {
 			"description": "Test Description 2",
 			"price": 19.99,
 			"category": "Test Category",
+			"dietary": ["vegetarian"],
+			"allergens": ["milk", "eggs"],
+			"calories": 620,
 			"image": {
 				"thumbnail": "https://example.com/images/test2-thumb.jpg",
 				"mobile": "https://example.com/images/test2-mobile.jpg",
@@ -96,6 +113,15 @@ func setupTestData(t *testing.T) (string, string, *config.Config, func()) {
 	return productsFile, couponsDir, cfg, cleanup
 }
 
+// serve handles req with handlers registered at pattern, as the router
+// registers them
+func serve(rec *httptest.ResponseRecorder, req *http.Request, pattern string, handlers ...gin.HandlerFunc) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Handle(req.Method, pattern, handlers...)
+	engine.ServeHTTP(rec, req)
+}
+
 func TestListProducts(t *testing.T) {
 	// Setup test data
 	_, _, cfg, cleanup := setupTestData(t)
@@ -115,7 +141,7 @@ func TestListProducts(t *testing.T) {
 	rec := httptest.NewRecorder()
 
 	// Call handler
-	handler.ListProducts(rec, req)
+	serve(rec, req, "/products", handler.ListProducts)
 
 	// Check status code
 	assert.Equal(t, http.StatusOK, rec.Code)
@@ -138,6 +164,201 @@ func TestListProducts(t *testing.T) {
 	assert.Contains(t, productMap, "prod-2", "Product prod-2 should be present in the response")
 }
 
+func TestListProducts_Filters(t *testing.T) {
+	// Setup test data
+	_, _, cfg, cleanup := setupTestData(t)
+	defer cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), cfg)
+	assert.NoError(t, err)
+	defer store.Close()
+	handler := NewProductHandler(store)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedIDs    []string
+	}{
+		{name: "dietary tag", query: "dietary=vegan", expectedStatus: http.StatusOK, expectedIDs: []string{"prod-1"}},
+		{name: "every dietary tag must match", query: "dietary=vegan,vegetarian", expectedStatus: http.StatusOK, expectedIDs: []string{}},
+		{name: "dietary tags ignore case and spaces", query: "dietary=Vegan,%20Gluten-Free", expectedStatus: http.StatusOK, expectedIDs: []string{"prod-1"}},
+		{name: "exclude allergens", query: "exclude_allergens=milk", expectedStatus: http.StatusOK, expectedIDs: []string{"prod-1"}},
+		{name: "exclude several allergens", query: "exclude_allergens=soy,eggs", expectedStatus: http.StatusOK, expectedIDs: []string{}},
+		{name: "max calories", query: "max_calories=620", expectedStatus: http.StatusOK, expectedIDs: []string{"prod-1", "prod-2"}},
+		{name: "max calories excludes higher", query: "max_calories=400", expectedStatus: http.StatusOK, expectedIDs: []string{"prod-1"}},
+		{name: "combined filters", query: "dietary=vegetarian&exclude_allergens=soy&max_calories=700", expectedStatus: http.StatusOK, expectedIDs: []string{"prod-2"}},
+		{name: "unknown dietary tag", query: "dietary=paleo", expectedStatus: http.StatusBadRequest},
+		{name: "unknown allergen", query: "exclude_allergens=pollen", expectedStatus: http.StatusBadRequest},
+		{name: "invalid max calories", query: "max_calories=lots", expectedStatus: http.StatusBadRequest},
+		{name: "negative max calories", query: "max_calories=-1", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/products?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			serve(rec, req, "/products", handler.ListProducts)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus != http.StatusOK {
+				var errResp models.ErrorResponse
+				assert.NoError(t, json.NewDecoder(rec.Body).Decode(&errResp))
+				assert.Equal(t, "INVALID_REQUEST", errResp.Code)
+				return
+			}
+
+			var got []models.Product
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+			ids := make([]string, 0, len(got))
+			for _, p := range got {
+				ids = append(ids, p.ID)
+			}
+			assert.ElementsMatch(t, tt.expectedIDs, ids)
+		})
+	}
+}
+
+func TestListProducts_Menus(t *testing.T) {
+	_, _, cfg, cleanup := setupTestData(t)
+	defer cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), cfg)
+	require.NoError(t, err)
+	defer store.Close()
+	served, err := menus.New("", []config.Menu{
+		{Name: "breakfast", Products: []string{"prod-1"}, Weekly: map[string][]string{"monday": {"07:00-11:00"}}},
+		{Name: "dinner", Categories: []string{"test category"}, Weekly: map[string][]string{"monday": {"17:00-22:00"}}},
+	})
+	require.NoError(t, err)
+	handler := NewProductHandler(store)
+	handler.now = func() time.Time { return time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedIDs    []string
+	}{
+		{name: "menus being served", expectedStatus: http.StatusOK, expectedIDs: []string{"prod-1"}},
+		{name: "named menu", query: "menu=Dinner", expectedStatus: http.StatusOK, expectedIDs: []string{"prod-1", "prod-2"}},
+		{name: "named menu with filters", query: "menu=dinner&dietary=vegetarian", expectedStatus: http.StatusOK, expectedIDs: []string{"prod-2"}},
+		{name: "unknown menu", query: "menu=brunch", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/products?"+tt.query, nil)
+			req = req.WithContext(tenant.NewContext(req.Context(), &tenant.Tenant{Store: store, Menus: served}))
+			rec := httptest.NewRecorder()
+			serve(rec, req, "/products", handler.ListProducts)
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus != http.StatusOK {
+				assert.Contains(t, rec.Body.String(), "breakfast")
+				return
+			}
+			var got []models.Product
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+			ids := make([]string, 0, len(got))
+			for _, p := range got {
+				ids = append(ids, p.ID)
+			}
+			assert.ElementsMatch(t, tt.expectedIDs, ids)
+		})
+	}
+}
+
+func TestListProducts_Pages(t *testing.T) {
+	_, _, cfg, cleanup := setupTestData(t)
+	defer cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), cfg)
+	require.NoError(t, err)
+	defer store.Close()
+	handler := NewProductHandler(store)
+
+	list := func(query string) ([]string, string) {
+		rec := httptest.NewRecorder()
+		serve(rec, httptest.NewRequest(http.MethodGet, "/products?"+query, nil), "/products", handler.ListProducts)
+		require.Equal(t, http.StatusOK, rec.Code, "body: %s", rec.Body)
+		var got []models.Product
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+		ids := make([]string, 0, len(got))
+		for _, p := range got {
+			ids = append(ids, p.ID)
+		}
+		return ids, rec.Header().Get(NextCursorHeader)
+	}
+
+	ids, next := list("limit=1")
+	assert.Equal(t, []string{"prod-1"}, ids)
+	require.NotEmpty(t, next)
+
+	// Products added before the cursor do not shift the next page
+	added := *store.GetAllProducts()[0]
+	added.ID = "prod-0"
+	require.NoError(t, store.AddProduct(&added))
+	ids, next = list("limit=1&cursor=" + next)
+	assert.Equal(t, []string{"prod-2"}, ids)
+	assert.Empty(t, next, "no products follow the last page")
+
+	// Without a limit every remaining product is listed
+	ids, _ = list("")
+	assert.Equal(t, []string{"prod-0", "prod-1", "prod-2"}, ids)
+
+	for _, query := range []string{"limit=0", "limit=1001", "cursor=bogus"} {
+		rec := httptest.NewRecorder()
+		serve(rec, httptest.NewRequest(http.MethodGet, "/products?"+query, nil), "/products", handler.ListProducts)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
+
+func TestListProducts_Sorted(t *testing.T) {
+	_, _, cfg, cleanup := setupTestData(t)
+	defer cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), cfg)
+	require.NoError(t, err)
+	defer store.Close()
+	handler := NewProductHandler(store)
+
+	cheap := *store.GetAllProducts()[0]
+	cheap.ID, cheap.Price, cheap.CreatedAt = "prod-3", 9.99, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, store.AddProduct(&cheap))
+
+	list := func(query string) ([]string, string) {
+		rec := httptest.NewRecorder()
+		serve(rec, httptest.NewRequest(http.MethodGet, "/products?"+query, nil), "/products", handler.ListProducts)
+		require.Equal(t, http.StatusOK, rec.Code, "body: %s", rec.Body)
+		var got []models.Product
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+		ids := make([]string, 0, len(got))
+		for _, p := range got {
+			ids = append(ids, p.ID)
+		}
+		return ids, rec.Header().Get(NextCursorHeader)
+	}
+
+	ids, _ := list("sort=-price")
+	assert.Equal(t, []string{"prod-2", "prod-1", "prod-3"}, ids, "ties are broken by ID")
+
+	// Later keys order products the earlier ones tie on, across pages
+	ids, next := list("sort=price,-created_at&limit=1")
+	assert.Equal(t, []string{"prod-3"}, ids)
+	ids, next = list("sort=price,-created_at&limit=1&cursor=" + next)
+	assert.Equal(t, []string{"prod-1"}, ids)
+	ids, _ = list("sort=price,-created_at&cursor=" + next)
+	assert.Equal(t, []string{"prod-2"}, ids)
+
+	// Cursors only continue the sort they were issued for
+	for _, query := range []string{"sort=colour", "sort=price,-price", "sort=price,", "sort=name&cursor=" + next} {
+		rec := httptest.NewRecorder()
+		serve(rec, httptest.NewRequest(http.MethodGet, "/products?"+query, nil), "/products", handler.ListProducts)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
+
 func TestGetProduct(t *testing.T) {
 	// Setup test data
 	_, _, cfg, cleanup := setupTestData(t)
@@ -193,7 +414,7 @@ func TestGetProduct(t *testing.T) {
 			rec := httptest.NewRecorder()
 
 			// Call handler
-			handler.GetProduct(rec, req)
+			serve(rec, req, "/products/:id", handler.GetProduct)
 
 			// Check status code
 			assert.Equal(t, tt.expectedStatus, rec.Code)
@@ -203,3 +424,349 @@ func TestGetProduct(t *testing.T) {
 		})
 	}
 }
+
+func TestGetProduct_Localized(t *testing.T) {
+	// Setup test data
+	_, _, cfg, cleanup := setupTestData(t)
+	defer cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), cfg)
+	assert.NoError(t, err)
+	defer store.Close()
+	handler := NewProductHandler(store)
+
+	tests := []struct {
+		name           string
+		target         string
+		acceptLanguage string
+		catalogLocale  string
+		expectedName   string
+		expectedLocale string
+	}{
+		{name: "default locale", target: "/products/prod-1", expectedName: "Test Product 1", expectedLocale: "en"},
+		{name: "accept language", target: "/products/prod-1", acceptLanguage: "fr-FR, en;q=0.5", expectedName: "Produit de test 1", expectedLocale: "fr"},
+		{name: "lang overrides accept language", target: "/products/prod-1?lang=en", acceptLanguage: "fr", expectedName: "Test Product 1", expectedLocale: "en"},
+		{name: "untranslated falls back", target: "/products/prod-2?lang=fr", expectedName: "Test Product 2", expectedLocale: "en"},
+		{name: "tenant catalog locale", target: "/products/prod-2?lang=de", catalogLocale: "de", expectedName: "Test Product 2", expectedLocale: "de"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			if tt.catalogLocale != "" {
+				req = req.WithContext(tenant.NewContext(req.Context(), &tenant.Tenant{Store: store, Locale: tt.catalogLocale}))
+			}
+			rec := httptest.NewRecorder()
+			serve(rec, req, "/products/:id", handler.GetProduct)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.expectedLocale, rec.Header().Get("Content-Language"))
+			assert.Equal(t, "Accept-Language", rec.Header().Get("Vary"))
+			var got models.Product
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+			assert.Equal(t, tt.expectedName, got.Name)
+		})
+	}
+
+	t.Run("list", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/products?lang=fr", nil)
+		rec := httptest.NewRecorder()
+		serve(rec, req, "/products", handler.ListProducts)
+
+		var got []models.Product
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+		names := make(map[string]string)
+		for _, p := range got {
+			names[p.ID] = p.Name + "/" + p.Category
+		}
+		assert.Equal(t, map[string]string{
+			"prod-1": "Produit de test 1/Catégorie de test",
+			"prod-2": "Test Product 2/Test Category",
+		}, names)
+	})
+}
+
+func TestGetProduct_SignedImage(t *testing.T) {
+	// Setup test data
+	_, _, cfg, cleanup := setupTestData(t)
+	defer cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), cfg)
+	assert.NoError(t, err)
+	defer store.Close()
+	handler := NewProductHandler(store)
+
+	keyFile := filepath.Join(t.TempDir(), "cdn.key")
+	assert.NoError(t, os.WriteFile(keyFile, []byte("MDEyMzQ1Njc4OWFiY2RlZg=="), 0600))
+	signer, err := images.NewSigner(config.ImageSigning{
+		Type:      config.SigningCloudCDN,
+		URLPrefix: "https://example.com/images/test1-",
+		KeyID:     "catalog",
+		KeyFile:   keyFile,
+		TTL:       time.Hour,
+	})
+	assert.NoError(t, err)
+	withSigner := func(req *http.Request) *http.Request {
+		return req.WithContext(tenant.NewContext(req.Context(), &tenant.Tenant{Store: store, Images: signer}))
+	}
+
+	t.Run("get", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		serve(rec, withSigner(httptest.NewRequest(http.MethodGet, "/products/prod-1", nil)), "/products/:id", handler.GetProduct)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var got models.Product
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+		assert.True(t, strings.HasPrefix(got.Image.Thumbnail, "https://example.com/images/test1-thumb.jpg?Expires="), got.Image.Thumbnail)
+		assert.Contains(t, got.Image.Desktop, "&KeyName=catalog&Signature=")
+
+		// The stored product keeps its unsigned URLs
+		stored, err := store.GetProduct("prod-1")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com/images/test1-thumb.jpg", stored.Image.Thumbnail)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		serve(rec, withSigner(httptest.NewRequest(http.MethodGet, "/products", nil)), "/products", handler.ListProducts)
+
+		var got []models.Product
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+		assert.Len(t, got, 2)
+		for _, p := range got {
+			// Only URLs under the signing prefix are signed
+			assert.Equal(t, p.ID == "prod-1", strings.Contains(p.Image.Mobile, "Signature="), p.Image.Mobile)
+		}
+	})
+}
+
+func TestProducts_CatalogRevision(t *testing.T) {
+	_, _, cfg, cleanup := setupTestData(t)
+	defer cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), cfg)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer store.Close()
+	handler := NewProductHandler(store)
+
+	rec := httptest.NewRecorder()
+	serve(rec, httptest.NewRequest(http.MethodGet, "/products", nil), "/products", handler.ListProducts)
+	assert.Equal(t, "1", rec.Header().Get(CatalogRevisionHeader))
+
+	// Every change to the catalog is a new revision
+	assert.NoError(t, store.AddProduct(testutil.GetTestProduct()))
+	rec = httptest.NewRecorder()
+	serve(rec, httptest.NewRequest(http.MethodGet, "/products/prod-1", nil), "/products/:id", handler.GetProduct)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get(CatalogRevisionHeader))
+}
+
+func TestCreateProduct(t *testing.T) {
+	// Setup test data
+	_, _, cfg, cleanup := setupTestData(t)
+	defer cleanup()
+
+	// Create store
+	ctx := context.Background()
+	store, err := data.NewStore(ctx, cfg)
+	assert.NoError(t, err)
+
+	// Create handler
+	handler := NewProductHandler(store)
+
+	validProduct := `{
+		"id": "prod-3",
+		"name": "Test Product 3",
+		"price": 4.5,
+		"category": "Test Category",
+		"image": {
+			"thumbnail": "https://example.com/images/test3-thumb.jpg",
+			"mobile": "https://example.com/images/test3-mobile.jpg",
+			"tablet": "https://example.com/images/test3-tablet.jpg",
+			"desktop": "https://example.com/images/test3-desktop.jpg"
+		}
+	}`
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "valid product",
+			body:           validProduct,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "duplicate product",
+			body:           validProduct,
+			expectedStatus: http.StatusConflict,
+			expectedCode:   "PRODUCT_EXISTS",
+		},
+		{
+			name:           "invalid json",
+			body:           `{"id":`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "INVALID_REQUEST",
+		},
+		{
+			name:           "validation error",
+			body:           `{"id": "prod-4", "name": "No Price", "category": "Test Category"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   "VALIDATION_ERROR",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			serve(rec, req, "/products", middleware.Bind[models.Product](), handler.CreateProduct)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedCode != "" {
+				var got models.ErrorResponse
+				assert.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+				assert.Equal(t, tt.expectedCode, got.Code)
+			}
+		})
+	}
+
+	// The created product is served by the store
+	product, err := store.GetProduct("prod-3")
+	assert.NoError(t, err)
+	assert.False(t, product.CreatedAt.IsZero())
+}
+
+func TestUpdateProduct(t *testing.T) {
+	_, _, cfg, cleanup := setupTestData(t)
+	defer cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), cfg)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer store.Close()
+	handler := NewProductHandler(store)
+
+	update := func(id, body, ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/products/"+id, strings.NewReader(body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		serve(rec, req, "/products/:id", middleware.Bind(ProductIDFromPath), handler.UpdateProduct)
+		return rec
+	}
+	body := func(name string) string {
+		return `{"name": "` + name + `", "price": 11, "category": "Test Category", "image": {` +
+			`"thumbnail": "https://example.com/t.jpg", "mobile": "https://example.com/m.jpg",` +
+			`"tablet": "https://example.com/t.jpg", "desktop": "https://example.com/d.jpg"}}`
+	}
+
+	// Read the product and its ETag
+	rec := httptest.NewRecorder()
+	serve(rec, httptest.NewRequest(http.MethodGet, "/products/prod-1", nil), "/products/:id", handler.GetProduct)
+	etag := rec.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	// The first edit based on the ETag wins...
+	rec = update("prod-1", body("First Edit"), etag)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	updatedETag := rec.Header().Get("ETag")
+	assert.NotEqual(t, etag, updatedETag)
+
+	// ...and a concurrent edit based on the same ETag is rejected
+	rec = update("prod-1", body("Second Edit"), etag)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	var errResp models.ErrorResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&errResp))
+	assert.Equal(t, "PRECONDITION_FAILED", errResp.Code)
+
+	product, err := store.GetProduct("prod-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "First Edit", product.Name)
+	assert.Equal(t, 11.0, product.Price)
+
+	// A list including the current ETag, "*" or no If-Match all update
+	assert.Equal(t, http.StatusOK, update("prod-1", body("Third Edit"), etag+", "+updatedETag).Code)
+	assert.Equal(t, http.StatusOK, update("prod-1", body("Fourth Edit"), "*").Code)
+	assert.Equal(t, http.StatusOK, update("prod-1", body("Fifth Edit"), "").Code)
+
+	tests := []struct {
+		name           string
+		id             string
+		body           string
+		expectedStatus int
+	}{
+		{name: "unknown product", id: "missing", body: body("Missing"), expectedStatus: http.StatusNotFound},
+		{name: "mismatched id", id: "prod-1", body: `{"id": "prod-2"}`, expectedStatus: http.StatusBadRequest},
+		{name: "invalid product", id: "prod-1", body: `{"name": "No Price"}`, expectedStatus: http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedStatus, update(tt.id, tt.body, "").Code)
+		})
+	}
+}
+
+func TestDeleteProduct(t *testing.T) {
+	_, _, cfg, cleanup := setupTestData(t)
+	defer cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), cfg)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer store.Close()
+	handler := NewProductHandler(store)
+
+	rec := httptest.NewRecorder()
+	serve(rec, httptest.NewRequest(http.MethodDelete, "/products/prod-1", nil), "/products/:id", handler.DeleteProduct)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, rec.Body.String())
+
+	// Deleting again, or deleting an unknown product, is not found
+	rec = httptest.NewRecorder()
+	serve(rec, httptest.NewRequest(http.MethodDelete, "/products/prod-1", nil), "/products/:id", handler.DeleteProduct)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = httptest.NewRecorder()
+	serve(rec, httptest.NewRequest(http.MethodDelete, "/products/missing", nil), "/products/:id", handler.DeleteProduct)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// The deleted product still renders by ID...
+	rec = httptest.NewRecorder()
+	serve(rec, httptest.NewRequest(http.MethodGet, "/products/prod-1", nil), "/products/:id", handler.GetProduct)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var product models.Product
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&product))
+	assert.NotNil(t, product.DeletedAt)
+
+	// ...but is only listed when asked for
+	list := func(path string) []string {
+		rec := httptest.NewRecorder()
+		serve(rec, httptest.NewRequest(http.MethodGet, path, nil), "/products", handler.ListProducts)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var products []models.Product
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&products))
+		var ids []string
+		for _, product := range products {
+			ids = append(ids, product.ID)
+		}
+		return ids
+	}
+	assert.NotContains(t, list("/products"), "prod-1")
+	assert.Contains(t, list("/products?include_deleted=true"), "prod-1")
+	assert.NotContains(t, list("/products?include_deleted=false"), "prod-1")
+
+	rec = httptest.NewRecorder()
+	serve(rec, httptest.NewRequest(http.MethodGet, "/products?include_deleted=maybe", nil), "/products", handler.ListProducts)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
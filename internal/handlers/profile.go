@@ -1,14 +1,20 @@
 package handlers
 
 import (
-	"net/http"
 	"runtime"
 	"runtime/pprof"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
 )
 
+// profileContentType is the type of pprof's binary profiles. An error
+// after a profile has started is logged rather than sent, as the profile
+// is already on its way.
+const profileContentType = "application/octet-stream"
+
 // ProfileHandler handles profiling-related HTTP requests
 type ProfileHandler struct{}
 
@@ -28,10 +34,10 @@ func (h *ProfileHandler) StartCPUProfile(c *gin.Context) {
 	}
 
 	// Start CPU profiling
+	c.Header("Content-Type", profileContentType)
 	if err := pprof.StartCPUProfile(c.Writer); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to start CPU profile: " + err.Error(),
-		})
+		respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to start CPU profile").
+			AddDetail("error", err.Error()))
 		return
 	}
 
@@ -46,10 +52,10 @@ func (h *ProfileHandler) GetMemoryProfile(c *gin.Context) {
 	runtime.GC()
 
 	// Write memory profile
+	c.Header("Content-Type", profileContentType)
 	if err := pprof.WriteHeapProfile(c.Writer); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to write memory profile: " + err.Error(),
-		})
+		respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to write memory profile").
+			AddDetail("error", err.Error()))
 		return
 	}
 }
@@ -59,16 +65,14 @@ func (h *ProfileHandler) GetGoroutineProfile(c *gin.Context) {
 	// Get goroutine profile
 	p := pprof.Lookup("goroutine")
 	if p == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to get goroutine profile",
-		})
+		respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to get goroutine profile"))
 		return
 	}
 
+	c.Header("Content-Type", "text/plain; charset=utf-8")
 	if err := p.WriteTo(c.Writer, 1); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to write goroutine profile: " + err.Error(),
-		})
+		respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to write goroutine profile").
+			AddDetail("error", err.Error()))
 		return
 	}
 }
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/data"
+	"github.com/ravibandhu/oolio-food-ordering/internal/middleware"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
+	"github.com/ravibandhu/oolio-food-ordering/internal/reviews"
+)
+
+// ReviewHandler handles product review HTTP requests
+type ReviewHandler struct {
+	store   *data.Store
+	reviews *reviews.Store
+}
+
+// NewReviewHandler creates a new ReviewHandler instance
+func NewReviewHandler(store *data.Store, reviewStore *reviews.Store) *ReviewHandler {
+	return &ReviewHandler{
+		store:   store,
+		reviews: reviewStore,
+	}
+}
+
+// @Operation POST /products/{id}/reviews
+// @Summary Review a product
+// @Description Rate a product bought in an order. Reviews are published once approved by an admin.
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param id path string true "Product ID"
+// @Param review body models.ReviewRequest true "Review to submit"
+// @Success 201 {object} models.Review
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /products/{id}/reviews [post]
+func (h *ReviewHandler) SubmitReview(c *gin.Context) {
+	ctx := c.Request.Context()
+	productID := c.Param("id")
+
+	req := middleware.Bound[models.ReviewRequest](c)
+
+	if _, err := tenantStore(ctx, h.store).GetProduct(productID); err != nil {
+		respond.Error(c, apierrors.New(apierrors.NotFound, "Product not found").AddDetail("productId", productID))
+		return
+	}
+
+	review, err := tenantReviews(ctx, h.reviews).Submit(productID, req)
+	switch {
+	case errors.Is(err, reviews.ErrNotPurchased):
+		respond.Error(c, apierrors.New(apierrors.NotPurchased, "The product was not bought in this order").
+			AddDetail("orderId", req.OrderID))
+		return
+	case errors.Is(err, reviews.ErrAlreadyReviewed):
+		respond.Error(c, apierrors.New(apierrors.AlreadyReviewed, "The product was already reviewed for this order").
+			AddDetail("orderId", req.OrderID))
+		return
+	case err != nil:
+		respond.Error(c, apierrors.New(apierrors.InternalError, "Failed to submit review").
+			AddDetail("error", err.Error()))
+		return
+	}
+
+	respond.JSON(c, http.StatusCreated, review)
+}
+
+// @Operation GET /products/{id}/reviews
+// @Summary List a product's reviews
+// @Description Get the approved reviews of a product, oldest first
+// @Tags products
+// @Produce json
+// @Param id path string true "Product ID"
+// @Success 200 {array} models.Review
+// @Failure 503 {object} models.ErrorResponse
+// @Router /products/{id}/reviews [get]
+func (h *ReviewHandler) ListReviews(c *gin.Context) {
+	respond.JSON(c, http.StatusOK,
+		tenantReviews(c.Request.Context(), h.reviews).List(c.Param("id"), models.ReviewApproved))
+}
+
+// @Operation GET /admin/reviews
+// @Summary List reviews for moderation
+// @Description Get reviews of every product with the given status, oldest first
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param status query string false "Review status: pending (default), approved or rejected"
+// @Success 200 {array} models.Review
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/reviews [get]
+func (h *ReviewHandler) ListForModeration(c *gin.Context) {
+	status := c.DefaultQuery("status", models.ReviewPending)
+	switch status {
+	case models.ReviewPending, models.ReviewApproved, models.ReviewRejected:
+	default:
+		respond.Error(c, apierrors.New(apierrors.InvalidRequest, "Unknown review status").AddDetail("status", status))
+		return
+	}
+
+	respond.JSON(c, http.StatusOK, tenantReviews(c.Request.Context(), h.reviews).List("", status))
+}
+
+// @Operation POST /admin/reviews/{id}/approve
+// @Summary Approve a review
+// @Description Publish a review and count it towards the product's rating
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "Review ID"
+// @Success 200 {object} models.Review
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/reviews/{id}/approve [post]
+func (h *ReviewHandler) Approve(c *gin.Context) {
+	h.moderate(c, models.ReviewApproved)
+}
+
+// @Operation POST /admin/reviews/{id}/reject
+// @Summary Reject a review
+// @Description Hide a review and drop it from the product's rating
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "Review ID"
+// @Success 200 {object} models.Review
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/reviews/{id}/reject [post]
+func (h *ReviewHandler) Reject(c *gin.Context) {
+	h.moderate(c, models.ReviewRejected)
+}
+
+// moderate sets the status of the review named in the path
+func (h *ReviewHandler) moderate(c *gin.Context, status string) {
+	reviewID := c.Param("id")
+	review, err := tenantReviews(c.Request.Context(), h.reviews).Moderate(reviewID, status)
+	if err != nil {
+		respond.Error(c, apierrors.New(apierrors.NotFound, "Review not found").AddDetail("id", reviewID))
+		return
+	}
+
+	respond.JSON(c, http.StatusOK, review)
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/data"
+	"github.com/ravibandhu/oolio-food-ordering/internal/middleware"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/reviews"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReviewHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Setup test data
+	_, _, cfg, cleanup := setupTestData(t)
+	defer cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), cfg)
+	require.NoError(t, err)
+	defer store.Close()
+
+	reviewStore := reviews.NewStore()
+	reviewStore.RecordPurchase(&models.Order{
+		ID:    "order-1",
+		Items: []models.OrderItem{{ProductID: "prod-1", Quantity: 1}},
+	})
+
+	// Create handler and routes
+	handler := NewReviewHandler(store, reviewStore)
+	engine := gin.New()
+	engine.GET("/products/:id/reviews", handler.ListReviews)
+	engine.POST("/products/:id/reviews", middleware.Bind[models.ReviewRequest](), handler.SubmitReview)
+	engine.GET("/admin/reviews", handler.ListForModeration)
+	engine.POST("/admin/reviews/:id/approve", handler.Approve)
+	engine.POST("/admin/reviews/:id/reject", handler.Reject)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("submit errors", func(t *testing.T) {
+		tests := []struct {
+			name           string
+			path           string
+			body           string
+			expectedStatus int
+			expectedCode   string
+		}{
+			{name: "malformed body", path: "/products/prod-1/reviews", body: `{"rating":`, expectedStatus: http.StatusBadRequest, expectedCode: "INVALID_REQUEST"},
+			{name: "rating out of range", path: "/products/prod-1/reviews", body: `{"orderId":"order-1","rating":0}`, expectedStatus: http.StatusUnprocessableEntity, expectedCode: "VALIDATION_ERROR"},
+			{name: "unknown product", path: "/products/missing/reviews", body: `{"orderId":"order-1","rating":5}`, expectedStatus: http.StatusNotFound, expectedCode: "NOT_FOUND"},
+			{name: "product not in order", path: "/products/prod-2/reviews", body: `{"orderId":"order-1","rating":5}`, expectedStatus: http.StatusUnprocessableEntity, expectedCode: "NOT_PURCHASED"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				rec := do(http.MethodPost, tt.path, tt.body)
+				assert.Equal(t, tt.expectedStatus, rec.Code)
+				var errResp models.ErrorResponse
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&errResp))
+				assert.Equal(t, tt.expectedCode, errResp.Code)
+			})
+		}
+	})
+
+	t.Run("submit and moderate", func(t *testing.T) {
+		rec := do(http.MethodPost, "/products/prod-1/reviews", `{"orderId":"order-1","rating":4,"text":"Lovely"}`)
+		require.Equal(t, http.StatusCreated, rec.Code)
+		var review models.Review
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&review))
+		assert.Equal(t, models.ReviewPending, review.Status)
+
+		rec = do(http.MethodPost, "/products/prod-1/reviews", `{"orderId":"order-1","rating":5}`)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+
+		// Pending reviews are only visible to admins
+		var listed []models.Review
+		rec = do(http.MethodGet, "/products/prod-1/reviews", "")
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&listed))
+		assert.Empty(t, listed)
+
+		rec = do(http.MethodGet, "/admin/reviews", "")
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&listed))
+		require.Len(t, listed, 1)
+		assert.Equal(t, review.ID, listed[0].ID)
+
+		rec = do(http.MethodGet, "/admin/reviews?status=hidden", "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = do(http.MethodPost, "/admin/reviews/"+review.ID+"/approve", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		rec = do(http.MethodGet, "/products/prod-1/reviews", "")
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&listed))
+		require.Len(t, listed, 1)
+		assert.Equal(t, models.ReviewApproved, listed[0].Status)
+
+		rec = do(http.MethodPost, "/admin/reviews/"+review.ID+"/reject", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		rec = do(http.MethodGet, "/admin/reviews?status=rejected", "")
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&listed))
+		assert.Len(t, listed, 1)
+
+		rec = do(http.MethodPost, "/admin/reviews/missing/approve", "")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
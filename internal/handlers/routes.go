@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
+)
+
+// RouteInfo describes one route the server serves, and the requests it has
+// served since the server started
+type RouteInfo struct {
+	// @example POST
+	Method string `json:"method"`
+
+	// Path pattern, with parameters as :name and wildcards as *name
+	// @example /admin/products/:id/image
+	Path string `json:"path"`
+
+	// Who may call the route: public, customer, staff, or the role it
+	// requires
+	// @example admin
+	Scope string `json:"scope"`
+
+	// How long a request may run before it is answered with a TIMEOUT
+	// error; empty when the route has no timeout
+	// @example 5s
+	Timeout string `json:"timeout,omitempty"`
+
+	// Requests served
+	// @example 120
+	Requests int64 `json:"requests"`
+
+	// Requests answered with a 5xx status
+	// @example 1
+	ServerErrors int64 `json:"serverErrors"`
+
+	// Mean time taken to answer a request, in milliseconds
+	// @example 12.5
+	MeanLatencyMs float64 `json:"meanLatencyMs"`
+}
+
+// RoutesResponse lists the routes the server serves
+type RoutesResponse struct {
+	Routes []RouteInfo `json:"routes"`
+}
+
+// RouteHandler serves the server's routing table
+type RouteHandler struct {
+	routes func() []RouteInfo
+}
+
+// NewRouteHandler creates a new RouteHandler instance. routes returns the
+// routing table as it stands when called.
+func NewRouteHandler(routes func() []RouteInfo) *RouteHandler {
+	return &RouteHandler{
+		routes: routes,
+	}
+}
+
+// @Operation GET /admin/routes
+// @Summary List the routes
+// @Description List every route the server serves, in the order it was registered, with who may call it, its timeout and how many requests it has served, failed and how long they took since the server started.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Success 200 {object} RoutesResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/routes [get]
+func (h *RouteHandler) ListRoutes(c *gin.Context) {
+	respond.JSON(c, http.StatusOK, RoutesResponse{Routes: h.routes()})
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
+	"github.com/ravibandhu/oolio-food-ordering/internal/slo"
+)
+
+// SLOsResponse lists the objectives of the route groups
+type SLOsResponse struct {
+	SLOs []slo.Status `json:"slos"`
+}
+
+// SLOHandler serves the state of the route groups' objectives
+type SLOHandler struct {
+	slos func() []slo.Status
+}
+
+// NewSLOHandler creates a new SLOHandler instance. slos returns the state
+// of the objectives when called.
+func NewSLOHandler(slos func() []slo.Status) *SLOHandler {
+	return &SLOHandler{
+		slos: slos,
+	}
+}
+
+// @Operation GET /admin/slos
+// @Summary List the service level objectives
+// @Description List the availability and latency objectives of the route groups configured with one, with the error budget left over each objective's window and how fast it burned over the last 5 minutes, 30 minutes, hour and 6 hours. An objective burning fast enough to page for is flagged fast-burn or slow-burn, and one with no budget left exhausted. Requests are counted since the server started.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Success 200 {object} SLOsResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/slos [get]
+func (h *SLOHandler) ListSLOs(c *gin.Context) {
+	respond.JSON(c, http.StatusOK, SLOsResponse{SLOs: h.slos()})
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/middleware"
+	"github.com/ravibandhu/oolio-food-ordering/internal/orders"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
+	"github.com/ravibandhu/oolio-food-ordering/internal/split"
+)
+
+// SplitHandler handles HTTP requests for splitting the bills of orders
+type SplitHandler struct {
+	paymentLinkURL string
+}
+
+// NewSplitHandler creates a new SplitHandler instance. Payment links go to
+// paymentLinkURL; none are made when it is empty.
+func NewSplitHandler(paymentLinkURL string) *SplitHandler {
+	return &SplitHandler{
+		paymentLinkURL: paymentLinkURL,
+	}
+}
+
+// @Operation POST /orders/{id}/split
+// @Summary Split an order's bill
+// @Description Share the total of a placed order among people, evenly or by the items each had, with items no one claims shared evenly. Amounts include each person's part of any adjustments, discount, tax and fees, and add up to the total to the cent. Each person can be given a link to a payment page for their part.
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID"
+// @Param split body split.Request true "How to split the bill"
+// @Success 200 {object} split.Split
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /orders/{id}/split [post]
+func (h *SplitHandler) SplitOrder(c *gin.Context) {
+	orderID := c.Param("id")
+	req := middleware.Bound[split.Request](c)
+
+	var history orders.History
+	var ok bool
+	if store := tenantOrders(c.Request.Context()); store != nil {
+		history, ok = store.History(orderID)
+	}
+	if !ok {
+		respond.Error(c, apierrors.New(apierrors.NotFound, "Order not found").AddDetail("id", orderID))
+		return
+	}
+
+	s, err := split.New(history.Order, req, h.paymentLinkURL)
+	if err != nil {
+		respond.Error(c, apierrors.New(apierrors.ValidationError, "Invalid split").
+			AddDetail("error", err.Error()))
+		return
+	}
+	respond.JSON(c, http.StatusOK, s)
+}
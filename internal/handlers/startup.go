@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
+	"github.com/ravibandhu/oolio-food-ordering/internal/startup"
+)
+
+// StartupHandler serves the report of the self-checks run as the server
+// started
+type StartupHandler struct {
+	report *startup.Report
+}
+
+// NewStartupHandler creates a new StartupHandler instance
+func NewStartupHandler(report *startup.Report) *StartupHandler {
+	return &StartupHandler{
+		report: report,
+	}
+}
+
+// @Operation GET /admin/startup
+// @Summary Report the startup self-checks
+// @Description Get the report of the self-checks run as the server started: a summary of the configuration, the products and coupons each restaurant loaded, whether the port was bound and whether each service the server depends on accepted a connection. The same report is logged as one line at startup.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Success 200 {object} startup.Report
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/startup [get]
+func (h *StartupHandler) GetReport(c *gin.Context) {
+	respond.JSON(c, http.StatusOK, h.report)
+}
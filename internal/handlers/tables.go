@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
+	"github.com/ravibandhu/oolio-food-ordering/internal/tables"
+	"github.com/ravibandhu/oolio-food-ordering/internal/tenant"
+)
+
+// TableToken is the token printed as a QR code on a table
+type TableToken struct {
+	// The table the token is for
+	// @example 12
+	Table string `json:"table"`
+
+	// The token customers order at the table with, as tableToken
+	// @example 12.mZ0zT2dOv7bVq1b3Yk0Jm1r0aL3oT8oM0bXy4u6YvVc
+	Token string `json:"token"`
+}
+
+// TableHandler handles HTTP requests for the tokens of dine-in tables
+type TableHandler struct {
+	signer *tables.Signer
+}
+
+// NewTableHandler creates a new TableHandler instance
+func NewTableHandler(signer *tables.Signer) *TableHandler {
+	return &TableHandler{
+		signer: signer,
+	}
+}
+
+// @Operation GET /admin/tables/{table}/token
+// @Summary Get a table's QR token
+// @Description Get the token to print as a QR code on a table. Orders placed with it are served to the table. Tokens do not expire, and only work at the restaurant they were made for.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param table path string true "Table ID: up to 32 letters, digits and dashes"
+// @Success 200 {object} TableToken
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Router /admin/tables/{table}/token [get]
+func (h *TableHandler) GetToken(c *gin.Context) {
+	table := c.Param("table")
+	signer, tenantID := h.signerFor(c.Request.Context())
+	if signer == nil {
+		respond.Error(c, apierrors.New(apierrors.DineInUnavailable, "This restaurant does not take table orders"))
+		return
+	}
+
+	token, err := signer.Sign(tenantID, table)
+	if err != nil {
+		respond.Error(c, apierrors.New(apierrors.ValidationError, "Invalid table ID").
+			AddDetail("table", table).
+			AddDetail("error", err.Error()))
+		return
+	}
+	respond.JSON(c, http.StatusOK, TableToken{Table: table, Token: token})
+}
+
+// signerFor returns the table signer and ID of the tenant carried by ctx
+func (h *TableHandler) signerFor(ctx context.Context) (*tables.Signer, string) {
+	if t, ok := tenant.FromContext(ctx); ok {
+		return t.Tables, t.ID
+	}
+	return h.signer, config.DefaultTenantID
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/carts"
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/couriers"
+	"github.com/ravibandhu/oolio-food-ordering/internal/dashboard"
+	"github.com/ravibandhu/oolio-food-ordering/internal/data"
+	"github.com/ravibandhu/oolio-food-ordering/internal/images"
+	"github.com/ravibandhu/oolio-food-ordering/internal/invoices"
+	"github.com/ravibandhu/oolio-food-ordering/internal/maintenance"
+	"github.com/ravibandhu/oolio-food-ordering/internal/menus"
+	"github.com/ravibandhu/oolio-food-ordering/internal/orders"
+	"github.com/ravibandhu/oolio-food-ordering/internal/payments"
+	"github.com/ravibandhu/oolio-food-ordering/internal/pos"
+	"github.com/ravibandhu/oolio-food-ordering/internal/reviews"
+	"github.com/ravibandhu/oolio-food-ordering/internal/tenant"
+)
+
+// tenantStore returns the store of the tenant carried by ctx, or fallback
+// when the request was not routed through the tenant middleware
+func tenantStore(ctx context.Context, fallback *data.Store) *data.Store {
+	if t, ok := tenant.FromContext(ctx); ok {
+		return t.Store
+	}
+	return fallback
+}
+
+// tenantReviews returns the review store of the tenant carried by ctx, or
+// fallback when the request was not routed through the tenant middleware
+func tenantReviews(ctx context.Context, fallback *reviews.Store) *reviews.Store {
+	if t, ok := tenant.FromContext(ctx); ok {
+		return t.Reviews
+	}
+	return fallback
+}
+
+// tenantCarts returns the group carts of the tenant carried by ctx, or
+// fallback when the request was not routed through the tenant middleware
+func tenantCarts(ctx context.Context, fallback *carts.Store) *carts.Store {
+	if t, ok := tenant.FromContext(ctx); ok {
+		return t.Carts
+	}
+	return fallback
+}
+
+// tenantInventory returns the stock levels of the tenant carried by ctx, or
+// nil, which tracks no stock, when the request was not routed through the
+// tenant middleware
+func tenantInventory(ctx context.Context) *pos.Inventory {
+	if t, ok := tenant.FromContext(ctx); ok {
+		return t.Inventory
+	}
+	return nil
+}
+
+// tenantMaintenance returns the maintenance switch of the tenant carried by
+// ctx, or fallback when the request was not routed through the tenant
+// middleware
+func tenantMaintenance(ctx context.Context, fallback *maintenance.Switch) *maintenance.Switch {
+	if t, ok := tenant.FromContext(ctx); ok {
+		return t.Maintenance
+	}
+	return fallback
+}
+
+// tenantMenus returns the menus of the tenant carried by ctx, or nil, which
+// offers every product, when the request was not routed through the tenant
+// middleware
+func tenantMenus(ctx context.Context) *menus.Menus {
+	if t, ok := tenant.FromContext(ctx); ok {
+		return t.Menus
+	}
+	return nil
+}
+
+// tenantInvoices returns the invoice sequence of the tenant carried by ctx,
+// or nil when the request was not routed through the tenant middleware
+func tenantInvoices(ctx context.Context) *invoices.Sequence {
+	if t, ok := tenant.FromContext(ctx); ok {
+		return t.Invoices
+	}
+	return nil
+}
+
+// tenantPayments returns the takings by payment method of the tenant
+// carried by ctx, or nil when the request was not routed through the tenant
+// middleware
+func tenantPayments(ctx context.Context) *payments.Ledger {
+	if t, ok := tenant.FromContext(ctx); ok {
+		return t.Payments
+	}
+	return nil
+}
+
+// tenantOrders returns the order store of the tenant carried by ctx, or nil
+// when the request was not routed through the tenant middleware
+func tenantOrders(ctx context.Context) orders.Store {
+	if t, ok := tenant.FromContext(ctx); ok {
+		return t.Orders
+	}
+	return nil
+}
+
+// tenantCouriers returns the courier tracker of the tenant carried by ctx,
+// or nil, which knows no orders, when the request was not routed through
+// the tenant middleware
+func tenantCouriers(ctx context.Context) *couriers.Tracker {
+	if t, ok := tenant.FromContext(ctx); ok {
+		return t.Couriers
+	}
+	return nil
+}
+
+// tenantDashboard returns the order dashboard of the tenant carried by ctx,
+// or nil when the request was not routed through the tenant middleware
+func tenantDashboard(ctx context.Context) *dashboard.Projector {
+	if t, ok := tenant.FromContext(ctx); ok {
+		return t.Dashboard
+	}
+	return nil
+}
+
+// tenantLocale returns the language of the catalog of the tenant carried by
+// ctx, or the default locale
+func tenantLocale(ctx context.Context) string {
+	if t, ok := tenant.FromContext(ctx); ok && t.Locale != "" {
+		return t.Locale
+	}
+	return config.DefaultLocale
+}
+
+// tenantSigner returns the image URL signer of the tenant carried by ctx, or
+// nil when image URLs are served unsigned
+func tenantSigner(ctx context.Context) *images.Signer {
+	if t, ok := tenant.FromContext(ctx); ok {
+		return t.Images
+	}
+	return nil
+}
+
+// tenantID returns the ID of the tenant carried by ctx, or the default
+// tenant's ID
+func tenantID(ctx context.Context) string {
+	if t, ok := tenant.FromContext(ctx); ok {
+		return t.ID
+	}
+	return config.DefaultTenantID
+}
@@ -0,0 +1,167 @@
+// Package hours models restaurant opening hours.
+package hours
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Schedule holds weekly opening hours in a restaurant's time zone. A nil
+// Schedule is always open.
+type Schedule struct {
+	loc        *time.Location
+	windows    [7][]window // Indexed by time.Weekday
+	orderAhead time.Duration
+}
+
+// window is an opening period as offsets from midnight. End may exceed 24h
+// for periods that run past midnight.
+type window struct {
+	start, end time.Duration
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// New creates a Schedule from day names mapped to "HH:MM-HH:MM" ranges.
+// Ranges ending at or before their start run past midnight. Orders are
+// accepted up to orderAhead before each opening. An empty weekly map yields
+// a nil, always open Schedule.
+func New(timezone string, weekly map[string][]string, orderAhead time.Duration) (*Schedule, error) {
+	if len(weekly) == 0 {
+		return nil, nil
+	}
+	if orderAhead < 0 {
+		return nil, fmt.Errorf("invalid order ahead window: %v", orderAhead)
+	}
+
+	loc := time.UTC
+	if timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(timezone); err != nil {
+			return nil, fmt.Errorf("invalid timezone %q: %w", timezone, err)
+		}
+	}
+
+	s := &Schedule{loc: loc, orderAhead: orderAhead}
+	for day, ranges := range weekly {
+		weekday, ok := weekdays[strings.ToLower(day)]
+		if !ok {
+			return nil, fmt.Errorf("invalid day %q", day)
+		}
+		for _, r := range ranges {
+			w, err := parseWindow(r)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", day, err)
+			}
+			s.windows[weekday] = append(s.windows[weekday], w)
+		}
+		sort.Slice(s.windows[weekday], func(i, j int) bool {
+			return s.windows[weekday][i].start < s.windows[weekday][j].start
+		})
+	}
+
+	return s, nil
+}
+
+// parseWindow parses an "HH:MM-HH:MM" range
+func parseWindow(r string) (window, error) {
+	from, to, ok := strings.Cut(r, "-")
+	if !ok {
+		return window{}, fmt.Errorf("invalid range %q: expected HH:MM-HH:MM", r)
+	}
+	start, err := parseClock(strings.TrimSpace(from))
+	if err != nil {
+		return window{}, fmt.Errorf("invalid range %q: %w", r, err)
+	}
+	end, err := parseClock(strings.TrimSpace(to))
+	if err != nil {
+		return window{}, fmt.Errorf("invalid range %q: %w", r, err)
+	}
+	if end <= start {
+		end += 24 * time.Hour
+	}
+	return window{start: start, end: end}, nil
+}
+
+// parseClock parses HH:MM into an offset from midnight
+func parseClock(clock string) (time.Duration, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q", clock)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// IsOpen reports whether the restaurant is open at t
+func (s *Schedule) IsOpen(t time.Time) bool {
+	if s == nil {
+		return true
+	}
+	t = t.In(s.loc)
+
+	// Check today's windows and yesterday's windows running past midnight
+	for daysAgo := 0; daysAgo <= 1; daysAgo++ {
+		midnight := s.midnight(t, -daysAgo)
+		for _, w := range s.windows[midnight.Weekday()] {
+			if !t.Before(s.at(midnight, w.start)) && t.Before(s.at(midnight, w.end)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NextOpening returns the start of the next opening period after t. It
+// returns false when the schedule has no opening periods at all.
+func (s *Schedule) NextOpening(t time.Time) (time.Time, bool) {
+	if s == nil {
+		return t, true
+	}
+	t = t.In(s.loc)
+
+	for days := 0; days <= 7; days++ {
+		midnight := s.midnight(t, days)
+		for _, w := range s.windows[midnight.Weekday()] {
+			if opening := s.at(midnight, w.start); opening.After(t) {
+				return opening, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// AcceptsOrders reports whether orders can be placed at t: while open, or
+// within the order-ahead window before the next opening
+func (s *Schedule) AcceptsOrders(t time.Time) bool {
+	if s.IsOpen(t) {
+		return true
+	}
+	if s.orderAhead == 0 {
+		return false
+	}
+	next, ok := s.NextOpening(t)
+	return ok && next.Sub(t) <= s.orderAhead
+}
+
+// midnight returns the start of the day days after t's day, in the schedule's zone
+func (s *Schedule) midnight(t time.Time, days int) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d+days, 0, 0, 0, 0, s.loc)
+}
+
+// at returns the wall-clock time offset after midnight, so periods keep their
+// local times across daylight saving changes
+func (s *Schedule) at(midnight time.Time, offset time.Duration) time.Time {
+	y, m, d := midnight.Date()
+	return time.Date(y, m, d, 0, int(offset/time.Minute), 0, 0, s.loc)
+}
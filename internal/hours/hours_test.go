@@ -0,0 +1,110 @@
+package hours
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 2024-01-01 is a Monday
+func at(t *testing.T, value string) time.Time {
+	parsed, err := time.Parse("2006-01-02 15:04", value)
+	require.NoError(t, err)
+	return parsed
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		tz      string
+		weekly  map[string][]string
+		wantErr bool
+		wantNil bool
+	}{
+		{name: "empty is always open", wantNil: true},
+		{name: "valid", tz: "Australia/Sydney", weekly: map[string][]string{"Monday": {"09:00-17:00"}}},
+		{name: "invalid day", weekly: map[string][]string{"funday": {"09:00-17:00"}}, wantErr: true},
+		{name: "invalid range", weekly: map[string][]string{"monday": {"09:00"}}, wantErr: true},
+		{name: "invalid time", weekly: map[string][]string{"monday": {"09:00-25:00"}}, wantErr: true},
+		{name: "invalid timezone", tz: "Mars/Olympus", weekly: map[string][]string{"monday": {"09:00-17:00"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(tt.tz, tt.weekly, 0)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantNil, s == nil)
+		})
+	}
+}
+
+func TestSchedule_IsOpen(t *testing.T) {
+	s, err := New("", map[string][]string{
+		"monday": {"17:00-22:00", "09:00-14:00"},
+		"friday": {"18:00-02:00"},
+	}, 0)
+	require.NoError(t, err)
+
+	tests := []struct {
+		when string
+		want bool
+	}{
+		{when: "2024-01-01 08:59", want: false},
+		{when: "2024-01-01 09:00", want: true},
+		{when: "2024-01-01 14:00", want: false},
+		{when: "2024-01-01 21:59", want: true},
+		{when: "2024-01-02 12:00", want: false},
+		{when: "2024-01-05 23:30", want: true},
+		{when: "2024-01-06 01:59", want: true}, // Friday's period runs past midnight
+		{when: "2024-01-06 02:00", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.when, func(t *testing.T) {
+			assert.Equal(t, tt.want, s.IsOpen(at(t, tt.when)))
+		})
+	}
+
+	var always *Schedule
+	assert.True(t, always.IsOpen(time.Now()))
+}
+
+func TestSchedule_NextOpening(t *testing.T) {
+	s, err := New("", map[string][]string{
+		"monday": {"09:00-14:00", "17:00-22:00"},
+	}, 0)
+	require.NoError(t, err)
+
+	next, ok := s.NextOpening(at(t, "2024-01-01 15:00"))
+	require.True(t, ok)
+	assert.Equal(t, at(t, "2024-01-01 17:00"), next)
+
+	next, ok = s.NextOpening(at(t, "2024-01-01 22:30"))
+	require.True(t, ok)
+	assert.Equal(t, at(t, "2024-01-08 09:00"), next)
+}
+
+func TestSchedule_AcceptsOrders(t *testing.T) {
+	s, err := New("", map[string][]string{"monday": {"09:00-14:00"}}, 30*time.Minute)
+	require.NoError(t, err)
+
+	assert.True(t, s.AcceptsOrders(at(t, "2024-01-01 10:00")))
+	assert.True(t, s.AcceptsOrders(at(t, "2024-01-01 08:30")))
+	assert.False(t, s.AcceptsOrders(at(t, "2024-01-01 08:29")))
+	assert.False(t, s.AcceptsOrders(at(t, "2024-01-01 14:00")))
+}
+
+func TestSchedule_TimeZone(t *testing.T) {
+	s, err := New("Australia/Sydney", map[string][]string{"monday": {"09:00-17:00"}}, 0)
+	require.NoError(t, err)
+
+	// 09:30 Monday in Sydney (UTC+11 in January) is 22:30 Sunday UTC
+	assert.True(t, s.IsOpen(at(t, "2023-12-31 22:30")))
+	assert.False(t, s.IsOpen(at(t, "2024-01-01 09:30")))
+}
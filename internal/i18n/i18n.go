@@ -0,0 +1,116 @@
+// Package i18n picks the language products are served in from the languages
+// a client accepts.
+package i18n
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+// LangParam is the query parameter that overrides Accept-Language
+const LangParam = "lang"
+
+// Preferred returns the languages a request accepts, most preferred first.
+// The lang query parameter comes before the Accept-Language header.
+func Preferred(r *http.Request) []string {
+	var tags []string
+	if lang := normalize(r.URL.Query().Get(LangParam)); lang != "" {
+		tags = append(tags, lang)
+	}
+	return append(tags, parseAcceptLanguage(r.Header.Get("Accept-Language"))...)
+}
+
+// parseAcceptLanguage returns the tags of an Accept-Language header ordered
+// by quality. Tags with a quality of zero or an unreadable one are dropped.
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	var entries []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = normalize(tag)
+		if tag == "" {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+		entries = append(entries, weighted{tag: tag, q: q})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].q > entries[j].q })
+	tags := make([]string, len(entries))
+	for i, e := range entries {
+		tags[i] = e.tag
+	}
+	return tags
+}
+
+// normalize lowercases a language tag and uses hyphens between subtags
+func normalize(tag string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(tag)), "_", "-")
+}
+
+// Localize returns product in the first preferred language it is available
+// in, along with that language. A tag also matches by its primary language,
+// so "fr-ca" is served from "fr". Products are returned unchanged in
+// fallback, the language of their own text, when no preference matches.
+// The stored product is copied rather than modified.
+func Localize(product *models.Product, preferred []string, fallback string) (*models.Product, string) {
+	fallback = normalize(fallback)
+
+	translations := make(map[string]models.ProductTranslation, len(product.Translations))
+	for tag, translation := range product.Translations {
+		translations[normalize(tag)] = translation
+	}
+
+	for _, tag := range preferred {
+		if tag == "*" {
+			break
+		}
+		candidates := []string{tag}
+		if primary, _, found := strings.Cut(tag, "-"); found {
+			candidates = append(candidates, primary)
+		}
+
+		for _, candidate := range candidates {
+			if candidate == fallback {
+				return product, fallback
+			}
+			translation, ok := translations[candidate]
+			if !ok {
+				continue
+			}
+
+			out := *product
+			if translation.Name != "" {
+				out.Name = translation.Name
+			}
+			if translation.Description != "" {
+				out.Description = translation.Description
+			}
+			if translation.Category != "" {
+				out.Category = translation.Category
+			}
+			return &out, candidate
+		}
+	}
+
+	return product, fallback
+}
@@ -0,0 +1,77 @@
+package i18n
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreferred(t *testing.T) {
+	tests := []struct {
+		name           string
+		target         string
+		acceptLanguage string
+		want           []string
+	}{
+		{name: "nothing requested", target: "/products", want: nil},
+		{name: "ordered by quality", target: "/products", acceptLanguage: "de;q=0.7, fr-CH, en;q=0.8, fr;q=0.9", want: []string{"fr-ch", "fr", "en", "de"}},
+		{name: "equal quality keeps header order", target: "/products", acceptLanguage: "es, it", want: []string{"es", "it"}},
+		{name: "zero and unreadable quality dropped", target: "/products", acceptLanguage: "fr;q=0, de;q=high, it", want: []string{"it"}},
+		{name: "query parameter first", target: "/products?lang=PT_BR", acceptLanguage: "fr", want: []string{"pt-br", "fr"}},
+		{name: "wildcard kept", target: "/products", acceptLanguage: "fr, *;q=0.1", want: []string{"fr", "*"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.target, nil)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			assert.Equal(t, tt.want, Preferred(req))
+		})
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	product := &models.Product{
+		ID:          "waffle",
+		Name:        "Waffle with Berries",
+		Description: "Belgian waffle topped with fresh berries",
+		Category:    "Waffle",
+		Translations: map[string]models.ProductTranslation{
+			"fr":    {Name: "Gaufre aux fruits rouges", Description: "Gaufre belge garnie de fruits rouges frais", Category: "Gaufre"},
+			"pt-BR": {Name: "Waffle com frutas vermelhas"},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		preferred  []string
+		wantLocale string
+		wantName   string
+		wantCat    string
+	}{
+		{name: "no preference", wantLocale: "en", wantName: "Waffle with Berries", wantCat: "Waffle"},
+		{name: "exact translation", preferred: []string{"fr"}, wantLocale: "fr", wantName: "Gaufre aux fruits rouges", wantCat: "Gaufre"},
+		{name: "primary language", preferred: []string{"fr-ca"}, wantLocale: "fr", wantName: "Gaufre aux fruits rouges", wantCat: "Gaufre"},
+		{name: "keys match ignoring case", preferred: []string{"pt-br"}, wantLocale: "pt-br", wantName: "Waffle com frutas vermelhas", wantCat: "Waffle"},
+		{name: "untranslated language skipped", preferred: []string{"de", "fr"}, wantLocale: "fr", wantName: "Gaufre aux fruits rouges", wantCat: "Gaufre"},
+		{name: "fallback language preferred", preferred: []string{"en-au", "fr"}, wantLocale: "en", wantName: "Waffle with Berries", wantCat: "Waffle"},
+		{name: "wildcard", preferred: []string{"de", "*", "fr"}, wantLocale: "en", wantName: "Waffle with Berries", wantCat: "Waffle"},
+		{name: "no match", preferred: []string{"de"}, wantLocale: "en", wantName: "Waffle with Berries", wantCat: "Waffle"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, locale := Localize(product, tt.preferred, "en")
+			assert.Equal(t, tt.wantLocale, locale)
+			assert.Equal(t, tt.wantName, got.Name)
+			assert.Equal(t, tt.wantCat, got.Category)
+		})
+	}
+
+	// The stored product is left untouched
+	assert.Equal(t, "Waffle with Berries", product.Name)
+}
@@ -0,0 +1,124 @@
+// Package idempotency remembers the responses to requests sent with an
+// Idempotency-Key, so a client retrying a request that already succeeded,
+// e.g. after its connection dropped, is answered with the original response
+// rather than having the request carried out twice.
+package idempotency
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultTTL is how long a response is remembered
+const DefaultTTL = 24 * time.Hour
+
+var (
+	// ErrInProgress is returned when a request with the key is still being
+	// handled
+	ErrInProgress = errors.New("request with the same idempotency key is in progress")
+	// ErrMismatch is returned when the key was used for a different request
+	ErrMismatch = errors.New("idempotency key was used for a different request")
+)
+
+// Response is a remembered response
+type Response struct {
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+// Store remembers the responses to requests by key. Cache keeps them in the
+// memory of one instance; instances behind a load balancer share a Store
+// backed by a shared cache instead, so a retry reaching another instance is
+// still answered with the original response.
+type Store interface {
+	// Begin claims key for a request with the given fingerprint. It returns
+	// the remembered response when the request already succeeded, or nil
+	// when the caller is to handle the request and then Finish or Release
+	// the key. It fails when the key is in progress or was used for another
+	// request.
+	Begin(key, fingerprint string) (*Response, error)
+
+	// Finish remembers the response to the request that claimed key
+	Finish(key string, response Response)
+
+	// Release forgets key after its request failed, so it can be retried
+	Release(key string)
+}
+
+var _ Store = (*Cache)(nil)
+
+// entry is the state of a key
+type entry struct {
+	fingerprint string
+	response    *Response // nil while the request is in progress
+	expires     time.Time
+}
+
+// Cache holds the responses to requests by key. It is safe for concurrent
+// use.
+type Cache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+// New creates a Cache remembering responses for ttl
+func New(ttl time.Duration) *Cache {
+	return &Cache{ttl: ttl, now: time.Now, entries: make(map[string]*entry)}
+}
+
+// Begin claims key for a request with the given fingerprint. It returns the
+// remembered response when the request already succeeded, or nil when the
+// caller is to handle the request and then Finish or Release the key. It
+// fails when the key is in progress or was used for another request.
+func (c *Cache) Begin(key, fingerprint string) (*Response, error) {
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.prune(now)
+
+	if e, ok := c.entries[key]; ok {
+		if e.fingerprint != fingerprint {
+			return nil, ErrMismatch
+		}
+		if e.response == nil {
+			return nil, ErrInProgress
+		}
+		return e.response, nil
+	}
+	c.entries[key] = &entry{fingerprint: fingerprint}
+	return nil, nil
+}
+
+// Finish remembers the response to the request that claimed key
+func (c *Cache) Finish(key string, response Response) {
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok && e.response == nil {
+		e.response = &response
+		e.expires = now.Add(c.ttl)
+	}
+}
+
+// Release forgets key after its request failed, so it can be retried
+func (c *Cache) Release(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok && e.response == nil {
+		delete(c.entries, key)
+	}
+}
+
+// prune forgets the responses that expired by now. Callers must hold c.mu.
+func (c *Cache) prune(now time.Time) {
+	for key, e := range c.entries {
+		if e.response != nil && !now.Before(e.expires) {
+			delete(c.entries, key)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package idempotency
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache := New(time.Hour)
+	cache.now = func() time.Time { return now }
+
+	// The first request is handled, and a retry meanwhile is refused
+	response, err := cache.Begin("key-1", "order")
+	require.NoError(t, err)
+	assert.Nil(t, response)
+	_, err = cache.Begin("key-1", "order")
+	assert.ErrorIs(t, err, ErrInProgress)
+
+	// Once it succeeds, retries get its response
+	cache.Finish("key-1", Response{Status: http.StatusCreated, ContentType: "application/json", Body: []byte(`{"id":"1"}`)})
+	response, err = cache.Begin("key-1", "order")
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	assert.Equal(t, http.StatusCreated, response.Status)
+	assert.Equal(t, `{"id":"1"}`, string(response.Body))
+
+	// The key cannot be used for another request
+	_, err = cache.Begin("key-1", "other order")
+	assert.ErrorIs(t, err, ErrMismatch)
+
+	// Responses are forgotten after the TTL
+	now = now.Add(time.Hour)
+	response, err = cache.Begin("key-1", "other order")
+	require.NoError(t, err)
+	assert.Nil(t, response)
+}
+
+func TestCache_Release(t *testing.T) {
+	cache := New(time.Hour)
+
+	_, err := cache.Begin("key-1", "order")
+	require.NoError(t, err)
+	cache.Release("key-1")
+
+	// A failed request can be retried, even with another body
+	response, err := cache.Begin("key-1", "fixed order")
+	require.NoError(t, err)
+	assert.Nil(t, response)
+
+	// Finished keys are not released
+	cache.Finish("key-1", Response{Status: http.StatusCreated})
+	cache.Release("key-1")
+	response, err = cache.Begin("key-1", "fixed order")
+	require.NoError(t, err)
+	assert.NotNil(t, response)
+}
@@ -0,0 +1,172 @@
+// Package images turns uploaded product photos into the renditions the
+// catalog serves and stores them.
+package images
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // Register GIF decoding
+	"image/jpeg"
+	_ "image/png" // Register PNG decoding
+	"io"
+	"strings"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+// ErrInvalidImage is returned when an upload cannot be decoded as an image
+var ErrInvalidImage = errors.New("invalid image")
+
+// maxPixels bounds the decoded size of an upload, guarding against images
+// that are small to send but huge to decode
+const maxPixels = 40_000_000
+
+// jpegQuality is the quality renditions are encoded with
+const jpegQuality = 85
+
+// Rendition is a size product images are served in
+type Rendition struct {
+	Name  string
+	Width int // Maximum width; smaller images are not enlarged
+}
+
+// Renditions are generated for every upload, one per models.ProductImage field
+var Renditions = []Rendition{
+	{Name: "thumbnail", Width: 160},
+	{Name: "mobile", Width: 640},
+	{Name: "tablet", Width: 960},
+	{Name: "desktop", Width: 1280},
+}
+
+// Storage stores rendered images and returns the URL each is served from
+type Storage interface {
+	Put(ctx context.Context, key string, data []byte) (string, error)
+}
+
+// Process decodes an uploaded JPEG, PNG or GIF image, stores a JPEG of every
+// rendition under prefix and returns their URLs. Keys include a hash of the
+// upload, so replacing an image changes its URLs.
+func Process(ctx context.Context, storage Storage, prefix string, src io.Reader) (*models.ProductImage, error) {
+	raw, err := io.ReadAll(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return nil, fmt.Errorf("%w: %dx%d is too large", ErrInvalidImage, cfg.Width, cfg.Height)
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	img := flatten(decoded)
+
+	sum := sha256.Sum256(raw)
+	version := hex.EncodeToString(sum[:6])
+
+	urls := make(map[string]string, len(Renditions))
+	for _, rendition := range Renditions {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, resize(img, rendition.Width), &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", rendition.Name, err)
+		}
+
+		key := fmt.Sprintf("%s/%s-%s.jpg", prefix, version, rendition.Name)
+		url, err := storage.Put(ctx, key, buf.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("failed to store %s: %w", rendition.Name, err)
+		}
+		urls[rendition.Name] = url
+	}
+
+	return &models.ProductImage{
+		Thumbnail: urls["thumbnail"],
+		Mobile:    urls["mobile"],
+		Tablet:    urls["tablet"],
+		Desktop:   urls["desktop"],
+	}, nil
+}
+
+// Key joins path segments into a storage key, replacing characters that are
+// unsafe in file names and URLs
+func Key(segments ...string) string {
+	safe := make([]string, len(segments))
+	for i, segment := range segments {
+		segment = strings.Map(func(r rune) rune {
+			switch {
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+				return r
+			default:
+				return '_'
+			}
+		}, segment)
+		if segment == "" || strings.Trim(segment, ".") == "" {
+			segment = "_"
+		}
+		safe[i] = segment
+	}
+	return strings.Join(safe, "/")
+}
+
+// flatten draws img onto a white background, as JPEG has no transparency
+func flatten(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(out, out.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(out, out.Bounds(), img, bounds.Min, draw.Over)
+	return out
+}
+
+// resize scales img down to width, keeping its aspect ratio. Each output
+// pixel averages the block of input pixels it covers.
+func resize(img *image.RGBA, width int) *image.RGBA {
+	srcW, srcH := img.Bounds().Dx(), img.Bounds().Dy()
+	if srcW <= width {
+		return img
+	}
+	height := srcH * width / srcW
+	if height < 1 {
+		height = 1
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*srcH/height, (y+1)*srcH/height
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0, x1 := x*srcW/width, (x+1)*srcW/width
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				row := img.Pix[sy*img.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += int(p[0])
+					g += int(p[1])
+					b += int(p[2])
+					a += int(p[3])
+					n++
+				}
+			}
+
+			d := out.Pix[y*out.Stride+x*4:]
+			d[0], d[1], d[2], d[3] = uint8(r/n), uint8(g/n), uint8(b/n), uint8(a/n)
+		}
+	}
+	return out
+}
@@ -0,0 +1,113 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStorage keeps stored images in memory
+type memoryStorage struct {
+	files map[string][]byte
+	fail  bool
+}
+
+func (s *memoryStorage) Put(ctx context.Context, key string, data []byte) (string, error) {
+	if s.fail {
+		return "", errors.New("storage unavailable")
+	}
+	s.files[key] = data
+	return "https://cdn.example.com/" + key, nil
+}
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.NRGBA{R: 200, G: 40, B: 40, A: 255})
+		}
+	}
+	// A transparent corner is flattened onto white
+	img.Set(0, 0, color.NRGBA{})
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestProcess(t *testing.T) {
+	storage := &memoryStorage{files: make(map[string][]byte)}
+	upload := encodePNG(t, 800, 400)
+
+	img, err := Process(context.Background(), storage, "default/prod-1", bytes.NewReader(upload))
+	require.NoError(t, err)
+	require.Len(t, storage.files, len(Renditions))
+
+	wantWidths := map[string]int{"thumbnail": 160, "mobile": 640, "tablet": 800, "desktop": 800}
+	urls := map[string]string{"thumbnail": img.Thumbnail, "mobile": img.Mobile, "tablet": img.Tablet, "desktop": img.Desktop}
+	for name, url := range urls {
+		key := strings.TrimPrefix(url, "https://cdn.example.com/")
+		assert.True(t, strings.HasPrefix(key, "default/prod-1/"), "key %s", key)
+		assert.True(t, strings.HasSuffix(key, "-"+name+".jpg"), "key %s", key)
+
+		decoded, err := jpeg.Decode(bytes.NewReader(storage.files[key]))
+		require.NoError(t, err, name)
+		assert.Equal(t, wantWidths[name], decoded.Bounds().Dx(), name)
+		assert.Equal(t, wantWidths[name]/2, decoded.Bounds().Dy(), name)
+	}
+
+	// The same upload is stored under the same keys; a different one is not
+	again, err := Process(context.Background(), storage, "default/prod-1", bytes.NewReader(upload))
+	require.NoError(t, err)
+	assert.Equal(t, img, again)
+	other, err := Process(context.Background(), storage, "default/prod-1", bytes.NewReader(encodePNG(t, 801, 400)))
+	require.NoError(t, err)
+	assert.NotEqual(t, img.Desktop, other.Desktop)
+}
+
+func TestProcess_Errors(t *testing.T) {
+	_, err := Process(context.Background(), &memoryStorage{files: make(map[string][]byte)}, "p", strings.NewReader("not an image"))
+	assert.ErrorIs(t, err, ErrInvalidImage)
+
+	_, err = Process(context.Background(), &memoryStorage{fail: true}, "p", bytes.NewReader(encodePNG(t, 10, 10)))
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidImage)
+}
+
+func TestResize(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	// Left half black, right half white
+	for y := 0; y < 2; y++ {
+		for x := 2; x < 4; x++ {
+			src.Set(x, y, color.White)
+		}
+		for x := 0; x < 2; x++ {
+			src.Set(x, y, color.Black)
+		}
+	}
+
+	out := resize(src, 2)
+	assert.Equal(t, image.Rect(0, 0, 2, 1), out.Bounds())
+	assert.Equal(t, color.RGBA{A: 255}, out.RGBAAt(0, 0))
+	assert.Equal(t, color.RGBA{R: 255, G: 255, B: 255, A: 255}, out.RGBAAt(1, 0))
+
+	// Images are never enlarged
+	assert.Same(t, src, resize(src, 10))
+}
+
+func TestKey(t *testing.T) {
+	assert.Equal(t, "default/prod-1", Key("default", "prod-1"))
+	assert.Equal(t, "default/_", Key("default", ".."))
+	assert.Equal(t, "a_b/c_d", Key("a/b", "c d"))
+	assert.Equal(t, "_/x", Key("", "x"))
+}
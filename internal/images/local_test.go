@@ -0,0 +1,43 @@
+package images
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStorage_Put(t *testing.T) {
+	dir := t.TempDir()
+	storage := NewLocalStorage(dir, "http://localhost:8080/public/images/")
+
+	url, err := storage.Put(context.Background(), "default/prod 1/abc-thumbnail.jpg", []byte("jpeg"))
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:8080/public/images/default/prod%201/abc-thumbnail.jpg", url)
+
+	data, err := os.ReadFile(filepath.Join(dir, "default", "prod 1", "abc-thumbnail.jpg"))
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg", string(data))
+
+	// Overwriting replaces the file and leaves no temporary files behind
+	_, err = storage.Put(context.Background(), "default/prod 1/abc-thumbnail.jpg", []byte("jpeg2"))
+	require.NoError(t, err)
+	entries, err := os.ReadDir(filepath.Join(dir, "default", "prod 1"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestLocalStorage_Errors(t *testing.T) {
+	storage := NewLocalStorage(t.TempDir(), "http://localhost:8080/public/images")
+
+	_, err := storage.Put(context.Background(), "../escape.jpg", []byte("jpeg"))
+	assert.Error(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = storage.Put(ctx, "default/x.jpg", []byte("jpeg"))
+	assert.ErrorIs(t, err, context.Canceled)
+}
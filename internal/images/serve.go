@@ -0,0 +1,54 @@
+package images
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+)
+
+// FileServer returns an http.Handler serving the images stored in dir.
+// Responses carry an ETag and a public Cache-Control of maxAge, and
+// conditional and range requests are honoured. Directories are not listed
+// and hidden files, such as uploads in progress, are never served.
+func FileServer(dir string, maxAge time.Duration) http.Handler {
+	cacheControl := fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds()))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := path.Clean("/" + r.URL.Path)
+		for _, segment := range strings.Split(name, "/") {
+			if strings.HasPrefix(segment, ".") {
+				notFound(w)
+				return
+			}
+		}
+
+		f, err := http.Dir(dir).Open(name)
+		if err != nil {
+			notFound(w)
+			return
+		}
+		defer f.Close()
+
+		info, err := f.Stat()
+		if err != nil || info.IsDir() {
+			notFound(w)
+			return
+		}
+
+		w.Header().Set("Cache-Control", cacheControl)
+		w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()))
+		http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+	})
+}
+
+// notFound writes the API's standard not found error
+func notFound(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(apierrors.Status(apierrors.NotFound))
+	json.NewEncoder(w).Encode(apierrors.New(apierrors.NotFound, "Image not found"))
+}
@@ -0,0 +1,68 @@
+package images
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileServer(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "default"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "default", "waffle.jpg"), []byte("0123456789"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "default", ".upload-123"), []byte("partial"), 0644))
+
+	server := FileServer(dir, time.Hour)
+	get := func(target string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/default/waffle.jpg", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "0123456789", rec.Body.String())
+	assert.Equal(t, "image/jpeg", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "public, max-age=3600", rec.Header().Get("Cache-Control"))
+	assert.Equal(t, "bytes", rec.Header().Get("Accept-Ranges"))
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	t.Run("conditional", func(t *testing.T) {
+		rec := get("/default/waffle.jpg", http.Header{"If-None-Match": {etag}})
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+		assert.Empty(t, rec.Body.String())
+	})
+
+	t.Run("range", func(t *testing.T) {
+		rec := get("/default/waffle.jpg", http.Header{"Range": {"bytes=2-5"}})
+		assert.Equal(t, http.StatusPartialContent, rec.Code)
+		body, _ := io.ReadAll(rec.Body)
+		assert.Equal(t, "2345", string(body))
+		assert.Equal(t, "bytes 2-5/10", rec.Header().Get("Content-Range"))
+
+		// A stale If-Range returns the whole image
+		rec = get("/default/waffle.jpg", http.Header{"Range": {"bytes=2-5"}, "If-Range": {`"stale"`}})
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "0123456789", rec.Body.String())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		for _, target := range []string{"/default/missing.jpg", "/default", "/default/.upload-123", "/../default/waffle.jpg/.."} {
+			rec := get(target, nil)
+			assert.Equal(t, http.StatusNotFound, rec.Code, target)
+			assert.Contains(t, rec.Body.String(), "NOT_FOUND", target)
+		}
+	})
+}
@@ -0,0 +1,194 @@
+package images
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+// scheme signs a URL so the CDN serves it until expires
+type scheme interface {
+	sign(rawURL string, expires time.Time) (string, error)
+}
+
+// Signer signs image URLs at response time, so a CDN can serve the catalog
+// from a private bucket. A nil Signer leaves URLs unchanged.
+type Signer struct {
+	scheme scheme
+	prefix string
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// NewSigner creates the Signer described by cfg. It returns nil when signing
+// is not enabled.
+func NewSigner(cfg config.ImageSigning) (*Signer, error) {
+	var s scheme
+	switch cfg.Type {
+	case "":
+		return nil, nil
+	case config.SigningCloudFront:
+		key, err := loadRSAKey(cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		s = &cloudFront{keyPairID: cfg.KeyID, key: key}
+	case config.SigningCloudCDN:
+		key, err := loadHMACKey(cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		s = &cloudCDN{keyName: cfg.KeyID, key: key}
+	default:
+		return nil, fmt.Errorf("unknown signing type %q", cfg.Type)
+	}
+
+	return &Signer{
+		scheme: s,
+		prefix: cfg.URLPrefix,
+		ttl:    cfg.TTL,
+		now:    time.Now,
+	}, nil
+}
+
+// SignImage returns a copy of img with every URL under the signer's prefix
+// signed. Expiry is rounded down to half the TTL, so a URL stays the same
+// for a while and can be cached, yet is always valid for half the TTL.
+func (s *Signer) SignImage(img *models.ProductImage) (*models.ProductImage, error) {
+	if s == nil || img == nil {
+		return img, nil
+	}
+
+	step := s.ttl / 2
+	if step < time.Second {
+		step = time.Second
+	}
+	expires := s.now().Add(s.ttl).Truncate(step)
+
+	signed := *img
+	for _, url := range []*string{&signed.Thumbnail, &signed.Mobile, &signed.Tablet, &signed.Desktop} {
+		if !strings.HasPrefix(*url, s.prefix) {
+			continue
+		}
+		value, err := s.scheme.sign(*url, expires)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign image URL: %w", err)
+		}
+		*url = value
+	}
+	return &signed, nil
+}
+
+// cloudFront signs URLs with a CloudFront canned policy
+type cloudFront struct {
+	keyPairID string
+	key       *rsa.PrivateKey
+}
+
+// cloudFrontEncoding is base64 with the characters CloudFront rejects in
+// query strings replaced
+var cloudFrontEncoding = strings.NewReplacer("+", "-", "=", "_", "/", "~")
+
+func (c *cloudFront) sign(rawURL string, expires time.Time) (string, error) {
+	policy, err := json.Marshal(map[string]any{
+		"Statement": []any{map[string]any{
+			"Resource": rawURL,
+			"Condition": map[string]any{
+				"DateLessThan": map[string]int64{"AWS:EpochTime": expires.Unix()},
+			},
+		}},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	digest := sha1.Sum(policy)
+	sig, err := rsa.SignPKCS1v15(rand.Reader, c.key, crypto.SHA1, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return rawURL + querySeparator(rawURL) +
+		"Expires=" + strconv.FormatInt(expires.Unix(), 10) +
+		"&Signature=" + cloudFrontEncoding.Replace(base64.StdEncoding.EncodeToString(sig)) +
+		"&Key-Pair-Id=" + c.keyPairID, nil
+}
+
+// cloudCDN signs URLs with a Google Cloud CDN signing key
+type cloudCDN struct {
+	keyName string
+	key     []byte
+}
+
+func (c *cloudCDN) sign(rawURL string, expires time.Time) (string, error) {
+	unsigned := rawURL + querySeparator(rawURL) +
+		"Expires=" + strconv.FormatInt(expires.Unix(), 10) +
+		"&KeyName=" + c.keyName
+
+	mac := hmac.New(sha1.New, c.key)
+	mac.Write([]byte(unsigned))
+	return unsigned + "&Signature=" + base64.URLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// querySeparator returns the character that starts the next query parameter
+func querySeparator(rawURL string) string {
+	if strings.Contains(rawURL, "?") {
+		return "&"
+	}
+	return "?"
+}
+
+// loadRSAKey reads a PEM encoded PKCS#1 or PKCS#8 RSA private key
+func loadRSAKey(path string) (*rsa.PrivateKey, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("signing key %s is not PEM encoded", path)
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("signing key %s is not an RSA key", path)
+	}
+	return key, nil
+}
+
+// loadHMACKey reads a base64url encoded key, as Cloud CDN generates them
+func loadHMACKey(path string) ([]byte, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+	key, err := base64.URLEncoding.DecodeString(strings.TrimSpace(string(raw)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode signing key: %w", err)
+	}
+	if len(key) == 0 {
+		return nil, fmt.Errorf("signing key %s is empty", path)
+	}
+	return key, nil
+}
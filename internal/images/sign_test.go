@@ -0,0 +1,128 @@
+package images
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testImage = &models.ProductImage{
+	Thumbnail: "https://cdn.example.com/images/default/prod-1/abc-thumbnail.jpg",
+	Mobile:    "https://cdn.example.com/images/default/prod-1/abc-mobile.jpg",
+	Tablet:    "https://cdn.example.com/images/default/prod-1/abc-tablet.jpg",
+	Desktop:   "https://public.example.com/desktop.jpg",
+}
+
+// writeKey writes content to a file in a temporary directory
+func writeKey(t *testing.T, content []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(path, content, 0600))
+	return path
+}
+
+func TestNewSigner_Disabled(t *testing.T) {
+	signer, err := NewSigner(config.ImageSigning{})
+	require.NoError(t, err)
+	assert.Nil(t, signer)
+
+	// A nil signer leaves URLs unchanged
+	img, err := signer.SignImage(testImage)
+	require.NoError(t, err)
+	assert.Equal(t, testImage, img)
+}
+
+func TestNewSigner_InvalidKey(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.ImageSigning
+	}{
+		{name: "missing key file", cfg: config.ImageSigning{Type: config.SigningCloudCDN, KeyFile: filepath.Join(t.TempDir(), "missing")}},
+		{name: "cloud cdn key not base64", cfg: config.ImageSigning{Type: config.SigningCloudCDN, KeyFile: writeKey(t, []byte("not base64!"))}},
+		{name: "cloudfront key not PEM", cfg: config.ImageSigning{Type: config.SigningCloudFront, KeyFile: writeKey(t, []byte("not a key"))}},
+		{name: "unknown type", cfg: config.ImageSigning{Type: "s3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSigner(tt.cfg)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSigner_CloudFront(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	signer, err := NewSigner(config.ImageSigning{
+		Type:      config.SigningCloudFront,
+		URLPrefix: "https://cdn.example.com/images/",
+		KeyID:     "K2JCJMDEHXQW5F",
+		KeyFile:   writeKey(t, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		TTL:       time.Hour,
+	})
+	require.NoError(t, err)
+	signer.now = func() time.Time { return time.Unix(1_700_000_100, 0) }
+
+	img, err := signer.SignImage(testImage)
+	require.NoError(t, err)
+	assert.Equal(t, testImage.Desktop, img.Desktop, "URLs outside the prefix are not signed")
+	assert.NotEqual(t, testImage.Thumbnail, img.Thumbnail, "the original image is not modified")
+
+	signed, err := url.Parse(img.Thumbnail)
+	require.NoError(t, err)
+	query := signed.Query()
+	assert.Equal(t, "1700002800", query.Get("Expires"), "expiry is rounded down to half the TTL")
+	assert.Equal(t, "K2JCJMDEHXQW5F", query.Get("Key-Pair-Id"))
+
+	sig := strings.NewReplacer("-", "+", "_", "=", "~", "/").Replace(query.Get("Signature"))
+	raw, err := base64.StdEncoding.DecodeString(sig)
+	require.NoError(t, err)
+	policy := fmt.Sprintf(`{"Statement":[{"Condition":{"DateLessThan":{"AWS:EpochTime":1700002800}},"Resource":%q}]}`, testImage.Thumbnail)
+	digest := sha1.Sum([]byte(policy))
+	assert.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA1, digest[:], raw))
+}
+
+func TestSigner_CloudCDN(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	signer, err := NewSigner(config.ImageSigning{
+		Type:      config.SigningCloudCDN,
+		URLPrefix: "https://cdn.example.com/",
+		KeyID:     "catalog",
+		KeyFile:   writeKey(t, []byte(base64.URLEncoding.EncodeToString(key)+"\n")),
+		TTL:       10 * time.Minute,
+	})
+	require.NoError(t, err)
+	signer.now = func() time.Time { return time.Unix(1_700_000_100, 0) }
+
+	img, err := signer.SignImage(&models.ProductImage{Thumbnail: testImage.Thumbnail + "?v=2"})
+	require.NoError(t, err)
+
+	unsigned, sig, ok := strings.Cut(img.Thumbnail, "&Signature=")
+	require.True(t, ok, img.Thumbnail)
+	assert.Equal(t, testImage.Thumbnail+"?v=2&Expires=1700000700&KeyName=catalog", unsigned)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write([]byte(unsigned))
+	assert.Equal(t, base64.URLEncoding.EncodeToString(mac.Sum(nil)), sig)
+}
@@ -0,0 +1,230 @@
+// Package invoices issues each tenant's orders invoice numbers from a
+// sequence that survives restarts, as fiscal rules in several markets
+// require. Every number issued is appended to a journal before it is used,
+// so numbers are never issued twice, and numbers missing from the journal
+// are reported as gaps.
+package invoices
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// digits is how many digits invoice numbers are padded to
+const digits = 6
+
+// Entry records an invoice number issued to an order
+type Entry struct {
+	// Position of the number in the sequence
+	// @example 42
+	Number uint64 `json:"number"`
+
+	// The invoice number as printed
+	// @example INV-000042
+	Invoice string `json:"invoice"`
+
+	// The order the number was issued to
+	// @example order-0000-0000-0000-0000
+	OrderID string `json:"orderId"`
+
+	// When the number was issued
+	// @example 2024-01-01T00:00:00Z
+	IssuedAt time.Time `json:"issuedAt"`
+}
+
+// Gap is a run of numbers missing from the journal, both ends included
+type Gap struct {
+	// @example INV-000007
+	From string `json:"from"`
+	// @example INV-000009
+	To string `json:"to"`
+}
+
+// Status summarizes the numbers a sequence has issued
+type Status struct {
+	// Text invoice numbers start with
+	// @example INV-
+	Prefix string `json:"prefix"`
+
+	// How many numbers were issued
+	// @example 42
+	Issued int `json:"issued"`
+
+	// The last number issued, if any
+	// @example INV-000042
+	Last string `json:"last,omitempty"`
+
+	// The number the next order gets
+	// @example INV-000043
+	Next string `json:"next"`
+
+	// Numbers below the last one that are missing from the journal, such as
+	// when the journal was edited or restored from an older copy
+	Gaps []Gap `json:"gaps"`
+}
+
+// Sequence issues a tenant's invoice numbers, counting from 1. A nil
+// Sequence issues no numbers.
+type Sequence struct {
+	mu     sync.Mutex
+	path   string
+	prefix string
+	next   uint64
+	issued map[uint64]bool
+	now    func() time.Time
+}
+
+// Open reads the journal at path and continues the sequence after the
+// highest number in it. A missing journal starts the sequence at 1, and an
+// empty path keeps the sequence in memory only. A last line cut short by a
+// crash is dropped, as the number on it was never handed out.
+func Open(path, prefix string) (*Sequence, error) {
+	s := &Sequence{path: path, prefix: prefix, next: 1, issued: make(map[uint64]bool), now: time.Now}
+	if path == "" {
+		return s, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read invoice journal: %w", err)
+	}
+
+	valid := 0
+	reader := bufio.NewReader(bytes.NewReader(raw))
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// Lines are only complete once their newline is written
+			break
+		}
+		var entry Entry
+		if jerr := json.Unmarshal(line, &entry); jerr != nil || entry.Number == 0 {
+			return nil, fmt.Errorf("invalid invoice journal entry at byte %d", valid)
+		}
+		if s.issued[entry.Number] {
+			return nil, fmt.Errorf("invoice number %d is in the journal twice", entry.Number)
+		}
+		s.issued[entry.Number] = true
+		s.next = max(s.next, entry.Number+1)
+		valid += len(line)
+	}
+	if valid < len(raw) {
+		if err := os.Truncate(path, int64(valid)); err != nil {
+			return nil, fmt.Errorf("failed to drop incomplete invoice journal entry: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// Issue hands the next invoice number to an order. The number is written
+// to the journal and synced to disk first; if that fails no number is
+// issued and the next call tries the same number again.
+func (s *Sequence) Issue(orderID string) (string, error) {
+	if s == nil {
+		return "", nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := Entry{
+		Number:   s.next,
+		Invoice:  s.format(s.next),
+		OrderID:  orderID,
+		IssuedAt: s.now().UTC(),
+	}
+	if s.path != "" {
+		if err := appendEntry(s.path, entry); err != nil {
+			return "", fmt.Errorf("failed to record invoice number: %w", err)
+		}
+	}
+	s.issued[entry.Number] = true
+	s.next++
+	return entry.Invoice, nil
+}
+
+// Status reports the numbers issued so far and any gaps among them
+func (s *Sequence) Status() Status {
+	if s == nil {
+		return Status{Gaps: []Gap{}}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := Status{
+		Prefix: s.prefix,
+		Issued: len(s.issued),
+		Next:   s.format(s.next),
+		Gaps:   []Gap{},
+	}
+	if s.next > 1 {
+		status.Last = s.format(s.next - 1)
+	}
+
+	numbers := make([]uint64, 0, len(s.issued))
+	for number := range s.issued {
+		numbers = append(numbers, number)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	expected := uint64(1)
+	for _, number := range numbers {
+		if number > expected {
+			status.Gaps = append(status.Gaps, Gap{From: s.format(expected), To: s.format(number - 1)})
+		}
+		expected = number + 1
+	}
+	return status
+}
+
+// format prints a number of the sequence as an invoice number
+func (s *Sequence) format(number uint64) string {
+	return fmt.Sprintf("%s%0*d", s.prefix, digits, number)
+}
+
+// appendEntry appends entry to the journal at path as a line of JSON and
+// syncs it to disk, leaving the journal as it was on failure
+func appendEntry(path string, entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	// Cut off whatever part of the line was written, so the next entry
+	// starts on a line of its own
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Truncate(info.Size())
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Truncate(info.Size())
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
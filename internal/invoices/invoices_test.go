@@ -0,0 +1,75 @@
+package invoices
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSequence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invoices", "default.jsonl")
+	seq, err := Open(path, "INV-")
+	require.NoError(t, err)
+	assert.Equal(t, Status{Prefix: "INV-", Next: "INV-000001", Gaps: []Gap{}}, seq.Status())
+
+	first, err := seq.Issue("order-1")
+	require.NoError(t, err)
+	assert.Equal(t, "INV-000001", first)
+	second, err := seq.Issue("order-2")
+	require.NoError(t, err)
+	assert.Equal(t, "INV-000002", second)
+
+	// The sequence continues after a restart
+	reopened, err := Open(path, "INV-")
+	require.NoError(t, err)
+	assert.Equal(t, Status{Prefix: "INV-", Issued: 2, Last: "INV-000002", Next: "INV-000003", Gaps: []Gap{}}, reopened.Status())
+	third, err := reopened.Issue("order-3")
+	require.NoError(t, err)
+	assert.Equal(t, "INV-000003", third)
+}
+
+func TestOpen_Journal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "default.jsonl")
+
+	// A line cut short by a crash is dropped, and its number issued again
+	journal := `{"number":1,"invoice":"A-000001","orderId":"order-1","issuedAt":"2024-01-01T00:00:00Z"}
+{"number":4,"invoice":"A-000004","orderId":"order-4","issuedAt":"2024-01-01T00:00:00Z"}
+{"number":5,"invoice":"A-0000`
+	require.NoError(t, os.WriteFile(path, []byte(journal), 0644))
+	seq, err := Open(path, "A-")
+	require.NoError(t, err)
+	status := seq.Status()
+	assert.Equal(t, 2, status.Issued)
+	assert.Equal(t, "A-000005", status.Next)
+	assert.Equal(t, []Gap{{From: "A-000002", To: "A-000003"}}, status.Gaps)
+
+	number, err := seq.Issue("order-5")
+	require.NoError(t, err)
+	assert.Equal(t, "A-000005", number)
+	reopened, err := Open(path, "A-")
+	require.NoError(t, err)
+	assert.Equal(t, 3, reopened.Status().Issued)
+
+	// A number in the journal twice is refused
+	duplicate := `{"number":1,"orderId":"order-1"}
+{"number":1,"orderId":"order-2"}
+`
+	require.NoError(t, os.WriteFile(path, []byte(duplicate), 0644))
+	_, err = Open(path, "A-")
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte("not json\n"), 0644))
+	_, err = Open(path, "A-")
+	assert.Error(t, err)
+}
+
+func TestSequence_Nil(t *testing.T) {
+	var seq *Sequence
+	number, err := seq.Issue("order-1")
+	require.NoError(t, err)
+	assert.Empty(t, number)
+	assert.Empty(t, seq.Status().Gaps)
+}
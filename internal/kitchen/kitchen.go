@@ -0,0 +1,594 @@
+// Package kitchen tracks the orders a restaurant is preparing and estimates
+// when each will be ready.
+package kitchen
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+// Order statuses reported in estimates
+const (
+	StatusQueued    = "queued"
+	StatusPreparing = "preparing"
+	StatusReady     = "ready"
+)
+
+// readyRetention is how long estimates stay available after an order is ready
+const readyRetention = time.Hour
+
+// fallbackPrepTime is used when the configuration sets no default prep time
+const fallbackPrepTime = 15 * time.Minute
+
+// BusyError reports an order refused because the kitchen already has its
+// maximum of active orders
+type BusyError struct {
+	Active  int       // Orders queued or being prepared
+	RetryAt time.Time // When the kitchen expects to have room for another order
+}
+
+func (e *BusyError) Error() string {
+	return fmt.Sprintf("kitchen is busy with %d orders until %s", e.Active, e.RetryAt.Format(time.RFC3339))
+}
+
+// Estimate is the expected ready and delivery time of an order
+type Estimate struct {
+	// The order the estimate is for
+	// @example order-0000-0000-0000-0000
+	OrderID string `json:"order_id"`
+
+	// Preparation status: queued, preparing or ready
+	// @example queued
+	Status string `json:"status"`
+
+	// Number of orders that will start preparation before this one
+	// @example 2
+	Position int `json:"position"`
+
+	// When the order is expected to be ready
+	// @example 2024-01-01T12:30:00Z
+	ReadyAt time.Time `json:"ready_at"`
+
+	// When a delivery order is expected to arrive
+	// @example 2024-01-01T13:00:00Z
+	DeliveryAt *time.Time `json:"delivery_at,omitempty"`
+}
+
+// Ticket is an order as the kitchen prepares it, with the customer's notes
+type Ticket struct {
+	Estimate
+
+	// The table a dine-in order is served to
+	// @example 12
+	Table string `json:"table,omitempty"`
+
+	// What to prepare
+	Items []TicketItem `json:"items"`
+
+	// Instructions about the whole order
+	// @example leave at door
+	Notes string `json:"notes,omitempty"`
+}
+
+// TableTickets is the tickets of the dine-in orders served to one table
+type TableTickets struct {
+	// The table the orders are served to
+	// @example 12
+	Table string `json:"table"`
+
+	// The table's orders, in the order the kitchen takes them on
+	Tickets []Ticket `json:"tickets"`
+}
+
+// TicketItem is a line of a ticket
+type TicketItem struct {
+	// The ID of the product to prepare
+	// @example 1
+	ProductID string `json:"product_id"`
+
+	// The name of the product
+	// @example Waffle with Berries
+	Name string `json:"name"`
+
+	// The variant of the product, if any
+	// @example large
+	VariantID string `json:"variant_id,omitempty"`
+
+	// How many to prepare
+	// @example 2
+	Quantity int `json:"quantity"`
+
+	// Instructions about this item
+	// @example no onions
+	Notes string `json:"notes,omitempty"`
+}
+
+// entry is an order in the queue
+type entry struct {
+	orderID  string
+	prep     time.Duration
+	delivery bool
+	table    string
+	start    time.Time
+	ready    time.Time
+	started  bool // Preparation began, so start and ready are fixed
+	done     bool // Marked ready before its estimate
+	history  []models.StatusChange
+	reported int // Changes of history the recorder took
+	items    []TicketItem
+	notes    string
+}
+
+// Queue schedules orders first-in first-out across the kitchen's stations.
+// Estimates are recomputed whenever the queue changes.
+type Queue struct {
+	mu           sync.Mutex
+	stations     int
+	prepTimes    map[string]time.Duration
+	defaultPrep  time.Duration
+	deliveryTime time.Duration
+	maxActive    int
+	whenBusy     string
+	busyDelay    time.Duration
+	now          func() time.Time
+	entries      []*entry
+	byID         map[string]*entry
+	recorder     func(orderID string, change models.StatusChange) error
+	recording    sync.Mutex // Held while changes are passed to recorder
+	unrecorded   bool       // Some changes are yet to be passed to recorder
+	dropped      []*entry   // Expired entries with changes yet to be passed
+}
+
+// NewQueue creates a new Queue instance
+func NewQueue(cfg config.Kitchen) *Queue {
+	stations := cfg.Stations
+	if stations < 1 {
+		stations = 1
+	}
+	defaultPrep := cfg.DefaultPrepTime
+	if defaultPrep <= 0 {
+		defaultPrep = fallbackPrepTime
+	}
+	prepTimes := make(map[string]time.Duration, len(cfg.PrepTimes))
+	for category, prep := range cfg.PrepTimes {
+		prepTimes[strings.ToLower(category)] = prep
+	}
+	return &Queue{
+		stations:     stations,
+		prepTimes:    prepTimes,
+		defaultPrep:  defaultPrep,
+		deliveryTime: cfg.DeliveryTime,
+		maxActive:    cfg.MaxActive,
+		whenBusy:     cfg.WhenBusy,
+		busyDelay:    cfg.BusyDelay,
+		now:          time.Now,
+		byID:         make(map[string]*entry),
+	}
+}
+
+// RecordTo passes every status change the queue records from now on to
+// recorder, to add it to the order's history. A change recorder fails to
+// take is passed again whenever the queue is next used, so the changes of
+// an order recorded before it was placed are kept once it is. recorder is
+// called after the queue is unlocked, one change at a time, so a slow
+// recorder holds up only the call that passes it changes.
+func (q *Queue) RecordTo(recorder func(orderID string, change models.StatusChange) error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.recorder = recorder
+}
+
+// Flush passes the status changes recorder failed to take to it again, such
+// as once an order that started preparing when it was queued is placed
+func (q *Queue) Flush() {
+	q.mu.Lock()
+	q.reschedule(q.now())
+	q.mu.Unlock()
+
+	// Wait for any changes being passed, so the caller sees them taken
+	q.recording.Lock()
+	q.deliverHeld()
+}
+
+// Enqueue adds an order to the back of the queue and returns its estimate.
+// An order takes as long as its slowest item category. When the kitchen
+// already has its maximum of active orders, the order is refused with a
+// *BusyError or, with the extend policy, quoted the busy delay later.
+func (q *Queue) Enqueue(order *models.Order) (Estimate, error) {
+	var prep time.Duration
+	for _, product := range order.Products {
+		if p := q.prepTime(product.Category); p > prep {
+			prep = p
+		}
+	}
+	if prep == 0 {
+		prep = q.defaultPrep
+	}
+
+	defer q.deliver()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	q.reschedule(now)
+	if active := q.active(now); q.maxActive > 0 && len(active) >= q.maxActive {
+		if q.whenBusy != config.KitchenBusyExtend {
+			return Estimate{}, &BusyError{Active: len(active), RetryAt: q.roomAt(active)}
+		}
+		prep += q.busyDelay
+	}
+
+	e := &entry{
+		orderID:  order.ID,
+		prep:     prep,
+		delivery: order.DeliveryAddress != nil,
+		table:    order.Table,
+		history:  slices.Clone(order.StatusHistory),
+		reported: len(order.StatusHistory),
+		items:    ticketItems(order),
+		notes:    order.Notes,
+	}
+	q.entries = append(q.entries, e)
+	q.byID[e.orderID] = e
+
+	q.reschedule(now)
+	return q.estimate(e, now), nil
+}
+
+// Remove takes an order that was not placed after all out of the queue,
+// moving up the orders behind it
+func (q *Queue) Remove(orderID string) {
+	defer q.deliver()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.byID[orderID]; !ok {
+		return
+	}
+	delete(q.byID, orderID)
+	q.entries = slices.DeleteFunc(q.entries, func(e *entry) bool { return e.orderID == orderID })
+	q.reschedule(q.now())
+}
+
+// Estimate returns the current estimate of an order
+func (q *Queue) Estimate(orderID string) (Estimate, bool) {
+	defer q.deliver()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	q.reschedule(now)
+	e, ok := q.byID[orderID]
+	if !ok {
+		return Estimate{}, false
+	}
+	return q.estimate(e, now), true
+}
+
+// Tickets returns the orders queued or being prepared, in the order the
+// kitchen takes them on
+func (q *Queue) Tickets() []Ticket {
+	defer q.deliver()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	q.reschedule(now)
+	pending := q.active(now)
+	slices.SortStableFunc(pending, func(a, b *entry) int { return a.start.Compare(b.start) })
+
+	tickets := make([]Ticket, len(pending))
+	for i, e := range pending {
+		tickets[i] = Ticket{
+			Estimate: q.estimate(e, now),
+			Table:    e.table,
+			Items:    slices.Clone(e.items),
+			Notes:    e.notes,
+		}
+	}
+	return tickets
+}
+
+// TicketsByTable returns the tickets of the dine-in orders queued or being
+// prepared, grouped by table. Tables are listed in the order the kitchen
+// takes on their first order.
+func (q *Queue) TicketsByTable() []TableTickets {
+	var groups []TableTickets
+	index := make(map[string]int)
+	for _, ticket := range q.Tickets() {
+		if ticket.Table == "" {
+			continue
+		}
+		i, ok := index[ticket.Table]
+		if !ok {
+			i = len(groups)
+			index[ticket.Table] = i
+			groups = append(groups, TableTickets{Table: ticket.Table})
+		}
+		groups[i].Tickets = append(groups[i].Tickets, ticket)
+	}
+	return groups
+}
+
+// Timeline returns the status changes of an order, oldest first: how it was
+// placed, when preparation started and when it was ready. Orders are tracked
+// until an hour after they are ready.
+func (q *Queue) Timeline(orderID string) ([]models.StatusChange, bool) {
+	defer q.deliver()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.reschedule(q.now())
+	e, ok := q.byID[orderID]
+	if !ok {
+		return nil, false
+	}
+	return slices.Clone(e.history), true
+}
+
+// MarkReady records that an order finished early, freeing its station for
+// the orders behind it
+func (q *Queue) MarkReady(orderID string) (Estimate, bool) {
+	defer q.deliver()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	q.reschedule(now)
+	e, ok := q.byID[orderID]
+	if !ok {
+		return Estimate{}, false
+	}
+	if !e.done && e.ready.After(now) {
+		e.done = true
+		e.started = true
+		if e.start.After(now) {
+			e.start = now
+		}
+		e.ready = now
+		q.reschedule(now)
+	}
+	return q.estimate(e, now), true
+}
+
+// Depth returns the number of orders queued or being prepared
+func (q *Queue) Depth() int {
+	defer q.deliver()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	q.reschedule(now)
+	return len(q.active(now))
+}
+
+// active returns the orders queued or being prepared, in queue order
+func (q *Queue) active(now time.Time) []*entry {
+	active := make([]*entry, 0, len(q.entries))
+	for _, e := range q.entries {
+		if e.ready.After(now) {
+			active = append(active, e)
+		}
+	}
+	return active
+}
+
+// roomAt returns when enough of the active orders will be ready for the
+// kitchen to be under its maximum again
+func (q *Queue) roomAt(active []*entry) time.Time {
+	ready := make([]time.Time, len(active))
+	for i, e := range active {
+		ready[i] = e.ready
+	}
+	slices.SortFunc(ready, time.Time.Compare)
+	return ready[len(active)-q.maxActive]
+}
+
+// prepTime returns the preparation time of a product category, ignoring case
+func (q *Queue) prepTime(category string) time.Duration {
+	if p, ok := q.prepTimes[strings.ToLower(category)]; ok {
+		return p
+	}
+	return q.defaultPrep
+}
+
+// reschedule drops expired entries, fixes the times of orders that have
+// started, and assigns the remaining orders to the earliest free station,
+// recording the status changes the orders have gone through by now
+func (q *Queue) reschedule(now time.Time) {
+	kept := q.entries[:0]
+	for _, e := range q.entries {
+		if !e.ready.IsZero() && now.Sub(e.ready) > readyRetention {
+			// The order may have started and been ready since the queue
+			// was last used
+			e.started = true
+			e.record(now)
+			if q.report(e) {
+				q.dropped = append(q.dropped, e)
+			}
+			delete(q.byID, e.orderID)
+			continue
+		}
+		kept = append(kept, e)
+	}
+	q.entries = kept
+
+	// Stations are busy until their in-progress order is ready
+	free := make([]time.Time, q.stations)
+	for i := range free {
+		free[i] = now
+	}
+	var pending []*entry
+	for _, e := range q.entries {
+		// Orders whose scheduled start has passed are in preparation
+		if e.started || (!e.start.IsZero() && !e.start.After(now)) {
+			e.started = true
+			if e.ready.After(now) {
+				occupy(free, e.ready)
+			}
+			continue
+		}
+		pending = append(pending, e)
+	}
+
+	for _, e := range pending {
+		i := earliest(free)
+		e.start = free[i]
+		e.ready = e.start.Add(e.prep)
+		e.started = !e.start.After(now)
+		free[i] = e.ready
+	}
+
+	for _, e := range q.entries {
+		e.record(now)
+		q.report(e)
+	}
+}
+
+// report reports whether e has changes the recorder has not taken, marking
+// them to be passed once the queue is unlocked
+func (q *Queue) report(e *entry) bool {
+	if q.recorder == nil || e.reported == len(e.history) {
+		return false
+	}
+	q.unrecorded = true
+	return true
+}
+
+// deliver passes the changes marked by report to the recorder. The queue
+// must not be locked. When another call is already passing changes, it
+// passes these too rather than this call waiting for it.
+func (q *Queue) deliver() {
+	if q.recording.TryLock() {
+		q.deliverHeld()
+	}
+}
+
+// deliverHeld passes changes to the recorder until none are marked, each
+// order's oldest first and stopping at the first it fails to take, then
+// releases q.recording. Callers must hold q.recording.
+func (q *Queue) deliverHeld() {
+	for {
+		q.mu.Lock()
+		if !q.unrecorded {
+			// Released with the queue locked, so changes marked after
+			// this are passed by whoever holds q.recording next
+			q.recording.Unlock()
+			q.mu.Unlock()
+			return
+		}
+		q.unrecorded = false
+		recorder := q.recorder
+		type pending struct {
+			e       *entry
+			changes []models.StatusChange
+		}
+		var batch []pending
+		for _, e := range slices.Concat(q.dropped, q.entries) {
+			if e.reported < len(e.history) {
+				batch = append(batch, pending{e, slices.Clone(e.history[e.reported:])})
+			}
+		}
+		q.dropped = nil
+		q.mu.Unlock()
+
+		for _, p := range batch {
+			for _, change := range p.changes {
+				if err := recorder(p.e.orderID, change); err != nil {
+					break
+				}
+				q.mu.Lock()
+				p.e.reported++
+				q.mu.Unlock()
+			}
+		}
+	}
+}
+
+// record adds the status changes e has gone through by now to its history,
+// at the times they happened
+func (e *entry) record(now time.Time) {
+	reached := func(status string) bool {
+		return slices.ContainsFunc(e.history, func(c models.StatusChange) bool { return c.Status == status })
+	}
+	if e.started && !reached(StatusPreparing) {
+		e.history = append(e.history, models.StatusChange{Status: StatusPreparing, At: e.start, Actor: models.ActorKitchen})
+	}
+	if !e.ready.After(now) && !reached(StatusReady) {
+		actor := models.ActorKitchen
+		if e.done {
+			actor = models.ActorStaff
+		}
+		e.history = append(e.history, models.StatusChange{Status: StatusReady, At: e.ready, Actor: actor})
+	}
+}
+
+// estimate reports the scheduled times of e
+func (q *Queue) estimate(e *entry, now time.Time) Estimate {
+	est := Estimate{
+		OrderID: e.orderID,
+		ReadyAt: e.ready,
+	}
+
+	switch {
+	case !e.ready.After(now):
+		est.Status = StatusReady
+	case e.started:
+		est.Status = StatusPreparing
+	default:
+		est.Status = StatusQueued
+		for _, other := range q.entries {
+			if other != e && !other.started && other.start.Before(e.start) {
+				est.Position++
+			}
+		}
+	}
+
+	if e.delivery {
+		deliveryAt := e.ready.Add(q.deliveryTime)
+		est.DeliveryAt = &deliveryAt
+	}
+	return est
+}
+
+// ticketItems lists the lines of order for the kitchen, naming each from the
+// matching product of the order
+func ticketItems(order *models.Order) []TicketItem {
+	items := make([]TicketItem, len(order.Items))
+	for i, item := range order.Items {
+		items[i] = TicketItem{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+			Notes:     item.Notes,
+		}
+		if i < len(order.Products) && order.Products[i].ID == item.ProductID {
+			items[i].Name = order.Products[i].Name
+		}
+	}
+	return items
+}
+
+// occupy marks the earliest free station busy until t
+func occupy(free []time.Time, t time.Time) {
+	if i := earliest(free); t.After(free[i]) {
+		free[i] = t
+	}
+}
+
+// earliest returns the index of the station that frees up first
+func earliest(free []time.Time) int {
+	best := 0
+	for i := range free {
+		if free[i].Before(free[best]) {
+			best = i
+		}
+	}
+	return best
+}
@@ -0,0 +1,341 @@
+package kitchen
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a manually advanced clock
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestQueue(stations int) (*Queue, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	q := NewQueue(config.Kitchen{
+		Stations:        stations,
+		PrepTimes:       map[string]time.Duration{"pizza": 20 * time.Minute, "drinks": 2 * time.Minute},
+		DefaultPrepTime: 10 * time.Minute,
+		DeliveryTime:    25 * time.Minute,
+	})
+	q.now = clock.Now
+	return q, clock
+}
+
+func newOrder(id string, categories ...string) *models.Order {
+	order := &models.Order{ID: id}
+	for _, category := range categories {
+		order.Products = append(order.Products, models.Product{Category: category})
+	}
+	return order
+}
+
+func mustEnqueue(t *testing.T, q *Queue, order *models.Order) Estimate {
+	t.Helper()
+	est, err := q.Enqueue(order)
+	require.NoError(t, err)
+	return est
+}
+
+func TestQueue_Enqueue(t *testing.T) {
+	q, clock := newTestQueue(1)
+	start := clock.now
+
+	// The slowest category sets the preparation time, matched ignoring case
+	first := mustEnqueue(t, q, newOrder("order-1", "Pizza", "Drinks"))
+	assert.Equal(t, StatusPreparing, first.Status)
+	assert.Equal(t, start.Add(20*time.Minute), first.ReadyAt)
+	assert.Nil(t, first.DeliveryAt)
+
+	// Later orders wait for the station
+	second := mustEnqueue(t, q, newOrder("order-2", "Salads"))
+	assert.Equal(t, StatusQueued, second.Status)
+	assert.Equal(t, 0, second.Position)
+	assert.Equal(t, start.Add(30*time.Minute), second.ReadyAt)
+
+	delivery := newOrder("order-3", "Drinks")
+	delivery.DeliveryAddress = &models.Address{Line1: "1 George St", Postcode: "2000"}
+	third := mustEnqueue(t, q, delivery)
+	assert.Equal(t, 1, third.Position)
+	assert.Equal(t, start.Add(32*time.Minute), third.ReadyAt)
+	require.NotNil(t, third.DeliveryAt)
+	assert.Equal(t, start.Add(57*time.Minute), *third.DeliveryAt)
+
+	assert.Equal(t, 3, q.Depth())
+}
+
+func TestQueue_Busy(t *testing.T) {
+	q, clock := newTestQueue(2)
+	start := clock.now
+	q.maxActive = 3
+
+	mustEnqueue(t, q, newOrder("order-1", "Pizza"))
+	mustEnqueue(t, q, newOrder("order-2", "Salads"))
+	mustEnqueue(t, q, newOrder("order-3", "Salads"))
+
+	// A full kitchen refuses orders until the first active order is ready
+	_, err := q.Enqueue(newOrder("order-4", "Salads"))
+	var busy *BusyError
+	require.ErrorAs(t, err, &busy)
+	assert.Equal(t, 3, busy.Active)
+	assert.Equal(t, start.Add(10*time.Minute), busy.RetryAt)
+	_, ok := q.Estimate("order-4")
+	assert.False(t, ok)
+
+	clock.Advance(10 * time.Minute)
+	mustEnqueue(t, q, newOrder("order-4", "Salads"))
+
+	// With the extend policy, orders over the maximum are taken but quoted later
+	q.whenBusy = config.KitchenBusyExtend
+	q.busyDelay = 15 * time.Minute
+	extended := mustEnqueue(t, q, newOrder("order-5", "Salads"))
+	assert.Equal(t, start.Add(45*time.Minute), extended.ReadyAt)
+	assert.Equal(t, 4, q.Depth())
+}
+
+func TestQueue_Stations(t *testing.T) {
+	q, clock := newTestQueue(2)
+	start := clock.now
+
+	q.Enqueue(newOrder("order-1", "Pizza"))
+	second := mustEnqueue(t, q, newOrder("order-2", "Pizza"))
+	third := mustEnqueue(t, q, newOrder("order-3", "Pizza"))
+
+	assert.Equal(t, start.Add(20*time.Minute), second.ReadyAt, "second station starts immediately")
+	assert.Equal(t, start.Add(40*time.Minute), third.ReadyAt)
+}
+
+func TestQueue_UpdatesAsQueueChanges(t *testing.T) {
+	q, clock := newTestQueue(1)
+	start := clock.now
+
+	q.Enqueue(newOrder("order-1", "Pizza"))
+	q.Enqueue(newOrder("order-2", "Pizza"))
+
+	// Finishing early moves up the orders behind
+	clock.Advance(5 * time.Minute)
+	ready, ok := q.MarkReady("order-1")
+	require.True(t, ok)
+	assert.Equal(t, StatusReady, ready.Status)
+
+	second, ok := q.Estimate("order-2")
+	require.True(t, ok)
+	assert.Equal(t, StatusPreparing, second.Status)
+	assert.Equal(t, start.Add(25*time.Minute), second.ReadyAt)
+
+	// Ready orders are reported until the retention period passes
+	clock.Advance(20 * time.Minute)
+	second, ok = q.Estimate("order-2")
+	require.True(t, ok)
+	assert.Equal(t, StatusReady, second.Status)
+	assert.Equal(t, 0, q.Depth())
+
+	clock.Advance(readyRetention + time.Minute)
+	_, ok = q.Estimate("order-2")
+	assert.False(t, ok)
+}
+
+func TestQueue_StartedOrdersKeepTheirTimes(t *testing.T) {
+	q, clock := newTestQueue(1)
+	start := clock.now
+
+	q.Enqueue(newOrder("order-1", "Salads"))
+	q.Enqueue(newOrder("order-2", "Salads"))
+
+	// order-2 started at 12:10 as scheduled; its estimate must not drift
+	clock.Advance(15 * time.Minute)
+	second, ok := q.Estimate("order-2")
+	require.True(t, ok)
+	assert.Equal(t, StatusPreparing, second.Status)
+	assert.Equal(t, start.Add(20*time.Minute), second.ReadyAt)
+}
+
+func TestQueue_Tickets(t *testing.T) {
+	q, clock := newTestQueue(1)
+
+	first := newOrder("order-1", "Salads")
+	first.Notes = "leave at door"
+	first.Products[0].ID, first.Products[0].Name = "salad", "Caesar Salad"
+	first.Items = []models.OrderItem{{ProductID: "salad", Quantity: 2, Notes: "no croutons"}}
+	q.Enqueue(first)
+	q.Enqueue(newOrder("order-2", "Salads"))
+
+	// Tickets are listed in the order the kitchen takes them on
+	tickets := q.Tickets()
+	require.Len(t, tickets, 2)
+	assert.Equal(t, "order-1", tickets[0].OrderID)
+	assert.Equal(t, StatusPreparing, tickets[0].Status)
+	assert.Equal(t, "leave at door", tickets[0].Notes)
+	assert.Equal(t, []TicketItem{{ProductID: "salad", Name: "Caesar Salad", Quantity: 2, Notes: "no croutons"}}, tickets[0].Items)
+	assert.Equal(t, "order-2", tickets[1].OrderID)
+	assert.Equal(t, StatusQueued, tickets[1].Status)
+
+	// Ready orders leave the list
+	clock.Advance(10 * time.Minute)
+	tickets = q.Tickets()
+	require.Len(t, tickets, 1)
+	assert.Equal(t, "order-2", tickets[0].OrderID)
+}
+
+func TestQueue_TicketsByTable(t *testing.T) {
+	q, _ := newTestQueue(1)
+
+	for _, o := range []struct{ id, table string }{{"order-1", "12"}, {"order-2", ""}, {"order-3", "4"}, {"order-4", "12"}} {
+		order := newOrder(o.id, "Salads")
+		order.Table = o.table
+		mustEnqueue(t, q, order)
+	}
+
+	// Takeaway orders are left out, and tables listed as the kitchen takes
+	// on their first order
+	groups := q.TicketsByTable()
+	require.Len(t, groups, 2)
+	assert.Equal(t, "12", groups[0].Table)
+	require.Len(t, groups[0].Tickets, 2)
+	assert.Equal(t, "order-1", groups[0].Tickets[0].OrderID)
+	assert.Equal(t, "order-4", groups[0].Tickets[1].OrderID)
+	assert.Equal(t, "12", groups[0].Tickets[1].Table)
+	assert.Equal(t, "4", groups[1].Table)
+	require.Len(t, groups[1].Tickets, 1)
+	assert.Equal(t, "order-3", groups[1].Tickets[0].OrderID)
+}
+
+func TestQueue_Timeline(t *testing.T) {
+	q, clock := newTestQueue(1)
+	start := clock.now
+
+	placed := models.StatusChange{Status: models.OrderStatusPlaced, At: start, Actor: models.ActorCustomer}
+	first := newOrder("order-1", "Salads")
+	first.StatusHistory = []models.StatusChange{placed}
+	second := newOrder("order-2", "Salads")
+	second.StatusHistory = []models.StatusChange{placed}
+	q.Enqueue(first)
+	q.Enqueue(second)
+
+	// The first order starts at once, the second waits for the station
+	timeline, ok := q.Timeline("order-2")
+	require.True(t, ok)
+	assert.Equal(t, []models.StatusChange{placed}, timeline)
+
+	// Changes are recorded at the times they happened, not when observed
+	clock.Advance(15 * time.Minute)
+	timeline, ok = q.Timeline("order-1")
+	require.True(t, ok)
+	assert.Equal(t, []models.StatusChange{
+		placed,
+		{Status: StatusPreparing, At: start, Actor: models.ActorKitchen},
+		{Status: StatusReady, At: start.Add(10 * time.Minute), Actor: models.ActorKitchen},
+	}, timeline)
+
+	// Orders marked ready were finished by staff
+	q.MarkReady("order-2")
+	timeline, ok = q.Timeline("order-2")
+	require.True(t, ok)
+	assert.Equal(t, []models.StatusChange{
+		placed,
+		{Status: StatusPreparing, At: start.Add(10 * time.Minute), Actor: models.ActorKitchen},
+		{Status: StatusReady, At: clock.now, Actor: models.ActorStaff},
+	}, timeline)
+
+	// Timelines are not shared with callers
+	timeline[0].Status = "changed"
+	timeline, _ = q.Timeline("order-2")
+	assert.Equal(t, placed, timeline[0])
+
+	clock.Advance(readyRetention + time.Minute)
+	_, ok = q.Timeline("order-2")
+	assert.False(t, ok)
+}
+
+func TestQueue_RecordTo(t *testing.T) {
+	q, clock := newTestQueue(1)
+	start := clock.now
+
+	var recorded []models.StatusChange
+	placed := map[string]bool{}
+	q.RecordTo(func(orderID string, change models.StatusChange) error {
+		if !placed[orderID] {
+			return errors.New("not placed")
+		}
+		recorded = append(recorded, change)
+		return nil
+	})
+
+	// Changes to an order that is not placed yet are kept until it is
+	order := newOrder("order-1", "Salads")
+	order.StatusHistory = []models.StatusChange{{Status: models.OrderStatusPlaced, At: start, Actor: models.ActorCustomer}}
+	q.Enqueue(order)
+	assert.Empty(t, recorded)
+	placed["order-1"] = true
+	q.Flush()
+	assert.Equal(t, []models.StatusChange{
+		{Status: StatusPreparing, At: start, Actor: models.ActorKitchen},
+	}, recorded)
+
+	// Orders ready by the time the queue is next used are recorded, even
+	// once they are no longer tracked
+	clock.Advance(readyRetention + time.Hour)
+	q.Depth()
+	assert.Equal(t, []models.StatusChange{
+		{Status: StatusPreparing, At: start, Actor: models.ActorKitchen},
+		{Status: StatusReady, At: start.Add(10 * time.Minute), Actor: models.ActorKitchen},
+	}, recorded)
+	_, ok := q.Timeline("order-1")
+	assert.False(t, ok)
+}
+
+func TestQueue_RecordToUnlocked(t *testing.T) {
+	q, clock := newTestQueue(1)
+	q.Enqueue(newOrder("order-1", "Salads"))
+
+	// A recorder slow to take a change holds up only the call passing it
+	entered := make(chan struct{}, 2)
+	release := make(chan struct{})
+	var recorded []models.StatusChange
+	q.RecordTo(func(orderID string, change models.StatusChange) error {
+		entered <- struct{}{}
+		<-release
+		_, ok := q.Timeline(orderID)
+		assert.True(t, ok, "recorder can use the queue")
+		recorded = append(recorded, change)
+		return nil
+	})
+
+	clock.Advance(10 * time.Minute)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		q.Depth()
+	}()
+	<-entered
+	assert.Equal(t, 0, q.Depth())
+	_, ok := q.Estimate("order-1")
+	assert.True(t, ok)
+
+	close(release)
+	<-done
+	require.Len(t, recorded, 2)
+	assert.Equal(t, StatusPreparing, recorded[0].Status)
+	assert.Equal(t, StatusReady, recorded[1].Status)
+}
+
+func TestQueue_UnknownOrder(t *testing.T) {
+	q, _ := newTestQueue(1)
+
+	_, ok := q.Estimate("missing")
+	assert.False(t, ok)
+	_, ok = q.MarkReady("missing")
+	assert.False(t, ok)
+	_, ok = q.Timeline("missing")
+	assert.False(t, ok)
+}
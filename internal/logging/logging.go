@@ -0,0 +1,180 @@
+// Package logging writes the server's logs to a file rotated by size and
+// age, and samples high-volume messages so a busy or failing subsystem
+// cannot fill the disk with the same line.
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+)
+
+// backupLayout is the layout of the time a log file was rotated, appended
+// to its name. Its values sort in the order files were rotated.
+const backupLayout = "20060102T150405.000"
+
+// File is a log file rotated when it reaches a size or when a new period
+// of its maximum age starts, at midnight UTC for a day. Rotated files are
+// renamed with the time they were rotated, and the oldest are removed
+// beyond the number of backups kept. It is safe for concurrent use.
+type File struct {
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+	now        func() time.Time
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	period time.Time // Start of the period of maxAge the file was last written in
+}
+
+// Open opens the log file cfg configures for appending, creating it and
+// its directory as needed. It returns nil when no file is configured.
+func Open(cfg config.LoggingConfig) (*File, error) {
+	if cfg.File == "" {
+		return nil, nil
+	}
+	f := &File{
+		path:       cfg.File,
+		maxSize:    cfg.MaxSize,
+		maxAge:     cfg.MaxAge,
+		maxBackups: cfg.MaxBackups,
+		now:        time.Now,
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return nil, fmt.Errorf("create log directory: %w", err)
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Write appends p to the file, rotating it first when p would take it past
+// its maximum size or a new period has started. A line is never split
+// across files.
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.size > 0 && f.due(int64(len(p))) {
+		if err := f.rotate(); err != nil {
+			// Keep logging to the file as it is rather than losing lines
+			fmt.Fprintf(os.Stderr, "Failed to rotate log file %s: %v\n", f.path, err)
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	f.period = f.periodOf(f.now())
+	return n, err
+}
+
+// Close closes the file
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Close()
+}
+
+// due reports whether the file is to be rotated before n more bytes are
+// written to it
+func (f *File) due(n int64) bool {
+	if f.maxSize > 0 && f.size+n > f.maxSize {
+		return true
+	}
+	return f.maxAge > 0 && !f.periodOf(f.now()).Equal(f.period)
+}
+
+// periodOf returns the start of the period of maxAge t falls in
+func (f *File) periodOf(t time.Time) time.Time {
+	if f.maxAge <= 0 {
+		return time.Time{}
+	}
+	return t.UTC().Truncate(f.maxAge)
+}
+
+// open opens the file at path for appending. A file left by an earlier run
+// belongs to the period it was last written in.
+func (f *File) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("open log file: %w", err)
+	}
+	f.file = file
+	f.size = info.Size()
+	f.period = f.periodOf(info.ModTime())
+	return nil
+}
+
+// rotate renames the file with the time it was rotated, opens a new one in
+// its place and removes the backups beyond the number kept
+func (f *File) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	renameErr := os.Rename(f.path, f.backupName(f.now()))
+	if err := f.open(); err != nil {
+		return err
+	}
+	if renameErr != nil {
+		return renameErr
+	}
+	return f.prune()
+}
+
+// backupName returns the name the file is renamed to when rotated at t,
+// keeping its extension: server.log becomes server-20240102T150405.000.log
+func (f *File) backupName(t time.Time) string {
+	ext := filepath.Ext(f.path)
+	return strings.TrimSuffix(f.path, ext) + "-" + t.UTC().Format(backupLayout) + ext
+}
+
+// prune removes the oldest backups beyond maxBackups
+func (f *File) prune() error {
+	if f.maxBackups <= 0 {
+		return nil
+	}
+	backups, err := f.backups()
+	if err != nil {
+		return err
+	}
+	for len(backups) > f.maxBackups {
+		if err := os.Remove(backups[0]); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// backups returns the paths of the file's backups, oldest first
+func (f *File) backups() ([]string, error) {
+	ext := filepath.Ext(f.path)
+	prefix := strings.TrimSuffix(f.path, ext) + "-"
+	matches, err := filepath.Glob(prefix + "*" + ext)
+	if err != nil {
+		return nil, err
+	}
+	backups := matches[:0]
+	for _, match := range matches {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(match, prefix), ext)
+		if _, err := time.Parse(backupLayout, stamp); err == nil {
+			backups = append(backups, match)
+		}
+	}
+	sort.Strings(backups)
+	return backups, nil
+}
@@ -0,0 +1,85 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpen_NoFile(t *testing.T) {
+	f, err := Open(config.LoggingConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, f)
+}
+
+func TestFile_RotatesBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "server.log")
+	f, err := Open(config.LoggingConfig{File: path, MaxSize: 10, MaxBackups: 2})
+	require.NoError(t, err)
+	defer f.Close()
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	f.now = func() time.Time { now = now.Add(time.Second); return now }
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	// Each line took the file past 10 bytes, and only two backups are kept
+	backups, err := f.backups()
+	require.NoError(t, err)
+	require.Len(t, backups, 2)
+	assert.Regexp(t, `server-20240102T1504\d\d\.000\.log$`, backups[0])
+	assertContent(t, "second\n", backups[0])
+	assertContent(t, "third\n", backups[1])
+	assertContent(t, "fourth\n", path)
+}
+
+func TestFile_RotatesByAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.log")
+	f, err := Open(config.LoggingConfig{File: path, MaxAge: 24 * time.Hour})
+	require.NoError(t, err)
+	defer f.Close()
+	now := time.Date(2024, 1, 2, 23, 0, 0, 0, time.UTC)
+	f.now = func() time.Time { return now }
+
+	_, err = f.Write([]byte("monday\n"))
+	require.NoError(t, err)
+	now = now.Add(30 * time.Minute)
+	_, err = f.Write([]byte("still monday\n"))
+	require.NoError(t, err)
+	now = now.Add(time.Hour)
+	_, err = f.Write([]byte("tuesday\n"))
+	require.NoError(t, err)
+
+	backups, err := f.backups()
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assertContent(t, "monday\nstill monday\n", backups[0])
+	assertContent(t, "tuesday\n", path)
+}
+
+func TestFile_AppendsToExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.log")
+	require.NoError(t, os.WriteFile(path, []byte("before restart\n"), 0o644))
+
+	f, err := Open(config.LoggingConfig{File: path, MaxSize: 1 << 20, MaxAge: 24 * time.Hour})
+	require.NoError(t, err)
+	_, err = f.Write([]byte("after restart\n"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	assertContent(t, "before restart\nafter restart\n", path)
+}
+
+func assertContent(t *testing.T, want, path string) {
+	t.Helper()
+	got, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, want, string(got))
+}
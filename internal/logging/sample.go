@@ -0,0 +1,99 @@
+package logging
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// sampleInterval is the period each message's sampling limit applies to
+const sampleInterval = time.Minute
+
+// Sampler limits how many lines each message logs per interval. Lines past
+// the limit are counted rather than logged, and the count is reported with
+// the message's first line of the next interval. It is safe for concurrent
+// use.
+type Sampler struct {
+	limit    int
+	interval time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	windows map[string]*window
+}
+
+// window counts the lines of a message in the current interval
+type window struct {
+	start      time.Time
+	logged     int
+	suppressed int
+}
+
+// NewSampler returns a sampler letting each message log limit lines per
+// interval. A limit of zero lets every line through.
+func NewSampler(limit int, interval time.Duration) *Sampler {
+	return &Sampler{
+		limit:    limit,
+		interval: interval,
+		now:      time.Now,
+		windows:  make(map[string]*window),
+	}
+}
+
+// Allow reports whether a line of the message key may be logged and, when
+// it may, how many lines of it were suppressed since the last one logged
+func (s *Sampler) Allow(key string) (bool, int) {
+	if s == nil || s.limit <= 0 {
+		return true, 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	w, ok := s.windows[key]
+	if !ok || now.Sub(w.start) >= s.interval {
+		suppressed := 0
+		if ok {
+			suppressed = w.suppressed
+		}
+		s.windows[key] = &window{start: now, logged: 1}
+		return true, suppressed
+	}
+	if w.logged < s.limit {
+		w.logged++
+		return true, 0
+	}
+	w.suppressed++
+	return false, 0
+}
+
+// sampler samples the lines logged with Sampled
+var sampler atomic.Pointer[Sampler]
+
+func init() {
+	SetSampleLimit(10)
+}
+
+// SetSampleLimit sets how many lines a minute each message logged with
+// Sampled may log. A limit of zero logs every line.
+func SetSampleLimit(limit int) {
+	sampler.Store(NewSampler(limit, sampleInterval))
+}
+
+// Sampled logs like log.Printf, unless the message key has logged its
+// limit of lines this minute. Lines left out are counted, and the count is
+// added to the next line logged. It is meant for lines a busy or failing
+// subsystem logs for every event.
+func Sampled(key, format string, args ...any) {
+	ok, suppressed := sampler.Load().Allow(key)
+	if !ok {
+		return
+	}
+	line := fmt.Sprintf(format, args...)
+	if suppressed > 0 {
+		line += fmt.Sprintf(" (%d similar lines suppressed)", suppressed)
+	}
+	log.Print(line)
+}
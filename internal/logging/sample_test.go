@@ -0,0 +1,68 @@
+package logging
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampler(t *testing.T) {
+	s := NewSampler(2, time.Minute)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	allowed := func(key string) bool {
+		ok, _ := s.Allow(key)
+		return ok
+	}
+	assert.True(t, allowed("flush"))
+	assert.True(t, allowed("flush"))
+	assert.False(t, allowed("flush"))
+	assert.False(t, allowed("flush"))
+	assert.True(t, allowed("other"), "each message has its own limit")
+
+	// The next minute reports the lines left out
+	now = now.Add(time.Minute)
+	ok, suppressed := s.Allow("flush")
+	assert.True(t, ok)
+	assert.Equal(t, 2, suppressed)
+	ok, suppressed = s.Allow("flush")
+	assert.True(t, ok)
+	assert.Zero(t, suppressed)
+}
+
+func TestSampler_NoLimit(t *testing.T) {
+	s := NewSampler(0, time.Minute)
+	for range 100 {
+		ok, _ := s.Allow("flush")
+		assert.True(t, ok)
+	}
+}
+
+func TestSampled(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	flags := log.Flags()
+	log.SetFlags(0)
+	t.Cleanup(func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(flags)
+		SetSampleLimit(10)
+	})
+	SetSampleLimit(1)
+	s := sampler.Load()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	Sampled("flush", "flushed batch %d", 1)
+	Sampled("flush", "flushed batch %d", 2)
+	Sampled("flush", "flushed batch %d", 3)
+	now = now.Add(time.Minute)
+	Sampled("flush", "flushed batch %d", 4)
+
+	assert.Equal(t, "flushed batch 1\nflushed batch 4 (2 similar lines suppressed)\n", buf.String())
+}
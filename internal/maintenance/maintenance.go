@@ -0,0 +1,146 @@
+// Package maintenance keeps which route groups of a restaurant are down for
+// maintenance, so that ordering, say, can be closed while the catalog stays
+// available.
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRetryAfter is how long clients are asked to wait before retrying
+// a group whose maintenance has no planned end
+const DefaultRetryAfter = 5 * time.Minute
+
+var (
+	// ErrNotFound is returned when a group is not in maintenance
+	ErrNotFound = errors.New("group is not in maintenance")
+	// ErrInvalidWindow is returned for maintenance planned to end before it starts
+	ErrInvalidWindow = errors.New("invalid maintenance window")
+)
+
+// Window is the maintenance of a route group
+type Window struct {
+	// The route group in maintenance
+	// @example ordering
+	Group string `json:"group"`
+
+	// What callers of the group are told
+	// @example Ordering is down for maintenance. Please try again in a few minutes.
+	Message string `json:"message"`
+
+	// When the maintenance started
+	// @example 2024-01-01T09:00:00Z
+	Since time.Time `json:"since"`
+
+	// When the maintenance ends by itself; absent when it lasts until it is
+	// ended
+	// @example 2024-01-01T09:30:00Z
+	Until *time.Time `json:"until,omitempty"`
+}
+
+// Request represents the request body for putting a group into maintenance
+type Request struct {
+	// What callers of the group are told; a generic message by default
+	// @example Ordering is down for maintenance. Please try again in a few minutes.
+	Message string `json:"message,omitempty" validate:"omitempty,max=200"`
+
+	// When the maintenance ends by itself; it lasts until it is ended by
+	// default
+	// @example 2024-01-01T09:30:00Z
+	Until *time.Time `json:"until,omitempty"`
+}
+
+// RetryAfter returns how long callers should wait before retrying, as of
+// now: until the window ends, or DefaultRetryAfter when it has no end
+func (w Window) RetryAfter(now time.Time) time.Duration {
+	if w.Until == nil {
+		return DefaultRetryAfter
+	}
+	return max(w.Until.Sub(now), time.Second)
+}
+
+// Switch holds the route groups of a restaurant that are in maintenance. A
+// nil Switch has no group in maintenance.
+type Switch struct {
+	mu      sync.RWMutex
+	windows map[string]Window
+	now     func() time.Time
+}
+
+// New creates a new Switch with no group in maintenance
+func New() *Switch {
+	return &Switch{
+		windows: make(map[string]Window),
+		now:     time.Now,
+	}
+}
+
+// Start puts group into maintenance, replacing any maintenance it is in
+func (s *Switch) Start(group string, req Request) (Window, error) {
+	now := s.now()
+	if req.Until != nil && !req.Until.After(now) {
+		return Window{}, fmt.Errorf("%w: until %s has passed", ErrInvalidWindow, req.Until.Format(time.RFC3339))
+	}
+
+	w := Window{Group: group, Message: req.Message, Since: now, Until: req.Until}
+	if w.Message == "" {
+		w.Message = fmt.Sprintf("%s is down for maintenance. Please try again shortly.", strings.ToUpper(group[:1])+group[1:])
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.windows[group] = w
+	return w, nil
+}
+
+// End takes group out of maintenance
+func (s *Switch) End(group string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.active(group); !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, group)
+	}
+	delete(s.windows, group)
+	return nil
+}
+
+// Active returns the maintenance group is in, if it is
+func (s *Switch) Active(group string) (Window, bool) {
+	if s == nil {
+		return Window{}, false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.active(group)
+}
+
+// Windows returns the maintenance of every group in maintenance, by group
+func (s *Switch) Windows() []Window {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	windows := make([]Window, 0, len(s.windows))
+	for group := range s.windows {
+		if w, ok := s.active(group); ok {
+			windows = append(windows, w)
+		}
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i].Group < windows[j].Group })
+	return windows
+}
+
+// active returns the maintenance group is in, if it has not ended by
+// itself. Callers must hold s.mu.
+func (s *Switch) active(group string) (Window, bool) {
+	w, ok := s.windows[group]
+	if !ok || (w.Until != nil && !w.Until.After(s.now())) {
+		return Window{}, false
+	}
+	return w, true
+}
@@ -0,0 +1,57 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSwitch(t *testing.T) {
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	s := New()
+	s.now = func() time.Time { return now }
+
+	_, active := s.Active("ordering")
+	assert.False(t, active)
+
+	w, err := s.Start("ordering", Request{})
+	require.NoError(t, err)
+	assert.Equal(t, "Ordering is down for maintenance. Please try again shortly.", w.Message)
+	assert.Equal(t, now, w.Since)
+	assert.Equal(t, DefaultRetryAfter, w.RetryAfter(now))
+
+	got, active := s.Active("ordering")
+	assert.True(t, active)
+	assert.Equal(t, w, got)
+	_, active = s.Active("catalog")
+	assert.False(t, active)
+
+	require.NoError(t, s.End("ordering"))
+	_, active = s.Active("ordering")
+	assert.False(t, active)
+	assert.ErrorIs(t, s.End("ordering"), ErrNotFound)
+
+	// Windows with an end close by themselves
+	until := now.Add(30 * time.Minute)
+	w, err = s.Start("accounts", Request{Message: "Back soon", Until: &until})
+	require.NoError(t, err)
+	assert.Equal(t, "Back soon", w.Message)
+	assert.Equal(t, 30*time.Minute, w.RetryAfter(now))
+	assert.Equal(t, []Window{w}, s.Windows())
+
+	now = until
+	_, active = s.Active("accounts")
+	assert.False(t, active)
+	assert.Empty(t, s.Windows())
+	assert.ErrorIs(t, s.End("accounts"), ErrNotFound)
+
+	past := now.Add(-time.Minute)
+	_, err = s.Start("ordering", Request{Until: &past})
+	assert.ErrorIs(t, err, ErrInvalidWindow)
+
+	var none *Switch
+	_, active = none.Active("ordering")
+	assert.False(t, active)
+}
@@ -0,0 +1,183 @@
+package menuimport
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Deliverect product types
+const (
+	deliverectProduct       = 1
+	deliverectModifier      = 2
+	deliverectModifierGroup = 3
+	deliverectBundle        = 4
+)
+
+// deliverectMenu is a Deliverect menu push. Products, modifiers and modifier
+// groups share one collection, sent either as a list or keyed by ID.
+type deliverectMenu struct {
+	Categories []deliverectCategory `json:"categories"`
+	Products   json.RawMessage      `json:"products"`
+}
+
+type deliverectCategory struct {
+	ID               string            `json:"_id"`
+	Name             string            `json:"name"`
+	NameTranslations map[string]string `json:"nameTranslations"`
+	Products         []string          `json:"products"`
+}
+
+type deliverectItem struct {
+	ID                      string            `json:"_id"`
+	PLU                     string            `json:"plu"`
+	Name                    string            `json:"name"`
+	NameTranslations        map[string]string `json:"nameTranslations"`
+	Description             string            `json:"description"`
+	DescriptionTranslations map[string]string `json:"descriptionTranslations"`
+	Price                   int               `json:"price"` // In cents
+	ImageURL                string            `json:"imageUrl"`
+	ProductType             int               `json:"productType"`
+	SubProducts             []string          `json:"subProducts"`
+	Min                     int               `json:"min"`
+	Max                     int               `json:"max"`
+}
+
+// parseDeliverect maps the products listed in categories, identified by
+// their PLU; products without a type are taken as plain products. A required
+// single choice modifier group becomes the product's variants, priced at the
+// product's price plus the modifier's; other modifier groups and bundles are
+// left out. Product tags are not mapped, as their codes vary by account.
+func parseDeliverect(menu []byte) ([]item, []string, error) {
+	var m deliverectMenu
+	if err := json.Unmarshal(menu, &m); err != nil {
+		return nil, nil, err
+	}
+	products, err := deliverectItems(m.Products)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var items []item
+	var warnings []string
+	for _, category := range m.Categories {
+		for _, id := range category.Products {
+			dp, ok := products[id]
+			switch {
+			case !ok:
+				warnings = append(warnings, fmt.Sprintf("category %s: product %s is not in the menu", category.Name, id))
+				continue
+			case dp.ProductType == deliverectBundle:
+				warnings = append(warnings, fmt.Sprintf("item %s: bundles are not imported", deliverectID(dp)))
+				continue
+			case dp.ProductType != deliverectProduct && dp.ProductType != 0:
+				continue
+			}
+
+			it := item{
+				id:           deliverectID(dp),
+				sku:          dp.PLU,
+				name:         dp.Name,
+				description:  dp.Description,
+				category:     category.Name,
+				cents:        dp.Price,
+				imageURL:     dp.ImageURL,
+				translations: translationsOf(normalizeTags(dp.NameTranslations), normalizeTags(dp.DescriptionTranslations), normalizeTags(category.NameTranslations)),
+			}
+
+			// Only the first single required choice can become variants
+			chosen := false
+			for _, groupID := range dp.SubProducts {
+				group, ok := products[groupID]
+				if !ok || group.ProductType != deliverectModifierGroup {
+					warnings = append(warnings, fmt.Sprintf("item %s: modifier group %s is not in the menu", it.id, groupID))
+					continue
+				}
+				if chosen || group.Min != 1 || group.Max != 1 {
+					warnings = append(warnings, fmt.Sprintf("item %s: modifier group %s was left out; only one single required choice is imported, as variants", it.id, deliverectID(group)))
+					continue
+				}
+				for _, modifierID := range group.SubProducts {
+					modifier, ok := products[modifierID]
+					if !ok || modifier.ProductType != deliverectModifier {
+						warnings = append(warnings, fmt.Sprintf("item %s: option %s is not in the menu", it.id, modifierID))
+						continue
+					}
+					it.variants = append(it.variants, variant{
+						id:    deliverectID(modifier),
+						name:  modifier.Name,
+						cents: dp.Price + modifier.Price,
+						sku:   deliverectID(modifier),
+					})
+				}
+				chosen = true
+			}
+			items = append(items, it)
+		}
+	}
+	return items, warnings, nil
+}
+
+// deliverectItems indexes the products of a menu by ID, accepting a list or
+// an object keyed by ID. Items in a list are also found by PLU.
+func deliverectItems(raw json.RawMessage) (map[string]*deliverectItem, error) {
+	products := make(map[string]*deliverectItem)
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return nil, errors.New("menu has no products")
+	}
+	if raw[0] == '[' {
+		var list []*deliverectItem
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return nil, err
+		}
+		for _, dp := range list {
+			if dp == nil {
+				continue
+			}
+			if dp.ID != "" {
+				products[dp.ID] = dp
+			}
+			if _, exists := products[dp.PLU]; dp.PLU != "" && !exists {
+				products[dp.PLU] = dp
+			}
+		}
+		return products, nil
+	}
+
+	var keyed map[string]*deliverectItem
+	if err := json.Unmarshal(raw, &keyed); err != nil {
+		return nil, err
+	}
+	for id, dp := range keyed {
+		if dp == nil {
+			continue
+		}
+		if dp.ID == "" {
+			dp.ID = id
+		}
+		products[id] = dp
+	}
+	return products, nil
+}
+
+// deliverectID returns the ID a Deliverect product is imported with: its
+// PLU, which stays the same across menu pushes, or else its ID
+func deliverectID(dp *deliverectItem) string {
+	if dp.PLU != "" {
+		return dp.PLU
+	}
+	return dp.ID
+}
+
+// normalizeTags re-keys text by normalized language tag
+func normalizeTags(texts map[string]string) map[string]string {
+	normalized := make(map[string]string, len(texts))
+	for tag, text := range texts {
+		if text != "" {
+			normalized[normalizeTag(tag)] = text
+		}
+	}
+	return normalized
+}
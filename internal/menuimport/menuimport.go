@@ -0,0 +1,236 @@
+// Package menuimport maps menus exported from delivery platforms into
+// products, so restaurants already selling through them can be onboarded
+// without entering their catalog again.
+package menuimport
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+// Formats menus can be imported from
+const (
+	FormatDeliverect = "deliverect" // Deliverect menu push: products, modifiers and modifier groups in one list
+	FormatUberEats   = "ubereats"   // Uber Eats menu: categories, items and modifier groups
+)
+
+// Formats lists every supported format
+var Formats = []string{FormatDeliverect, FormatUberEats}
+
+var (
+	// ErrUnknownFormat is returned for a format not in Formats
+	ErrUnknownFormat = errors.New("unknown menu format")
+	// ErrInvalidMenu is returned when a menu does not decode or holds no products
+	ErrInvalidMenu = errors.New("invalid menu")
+)
+
+// Result is a menu mapped into products
+type Result struct {
+	// The products the menu maps to, sorted by ID
+	Products []*models.Product
+
+	// What was left out and why, such as items without an image
+	Warnings []string
+}
+
+// Parse maps a menu in format into products. Text is taken in locale where
+// the menu has several languages; the others become translations. Items that
+// cannot be mapped to a valid product are left out with a warning, and the
+// menu is invalid when none can be mapped.
+func Parse(format string, menu []byte, locale string) (*Result, error) {
+	var items []item
+	var warnings []string
+	var err error
+	switch format {
+	case FormatDeliverect:
+		items, warnings, err = parseDeliverect(menu)
+	case FormatUberEats:
+		items, warnings, err = parseUberEats(menu, locale)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMenu, err)
+	}
+
+	result := &Result{Warnings: warnings}
+	now := time.Now().UTC()
+	seen := make(map[string]bool)
+	for _, it := range items {
+		if seen[it.id] {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("item %s: listed more than once; the first is imported", it.id))
+			continue
+		}
+		seen[it.id] = true
+
+		product, err := it.product(now)
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("item %s: %v", it.id, err))
+			continue
+		}
+		result.Products = append(result.Products, product)
+	}
+	if len(result.Products) == 0 {
+		return nil, fmt.Errorf("%w: no importable items", ErrInvalidMenu)
+	}
+	sort.Slice(result.Products, func(a, b int) bool { return result.Products[a].ID < result.Products[b].ID })
+	return result, nil
+}
+
+// item is a menu item in a format-independent shape, before validation
+type item struct {
+	id           string
+	sku          string
+	name         string
+	description  string
+	category     string
+	cents        int
+	imageURL     string
+	dietary      []string
+	variants     []variant
+	translations map[string]models.ProductTranslation
+}
+
+// variant is an option of a required single choice, priced in full
+type variant struct {
+	id    string
+	name  string
+	cents int
+	sku   string
+}
+
+// product maps the item into a valid product
+func (it item) product(now time.Time) (*models.Product, error) {
+	if it.imageURL == "" {
+		return nil, errors.New("has no image")
+	}
+	if it.category == "" {
+		return nil, errors.New("is in no category")
+	}
+
+	product := &models.Product{
+		ID:          it.id,
+		Name:        it.name,
+		Description: it.description,
+		Price:       price(it.cents),
+		Category:    it.category,
+		Image: &models.ProductImage{
+			Thumbnail: it.imageURL,
+			Mobile:    it.imageURL,
+			Tablet:    it.imageURL,
+			Desktop:   it.imageURL,
+		},
+		Dietary:      it.dietary,
+		Translations: it.translations,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if len(it.variants) == 0 {
+		product.SKU = it.sku
+	}
+	for _, v := range it.variants {
+		product.Variants = append(product.Variants, models.ProductVariant{
+			ID:    v.id,
+			Name:  v.name,
+			Price: price(v.cents),
+			SKU:   v.sku,
+		})
+	}
+	if err := models.Validate(product); err != nil {
+		return nil, fmt.Errorf("is not a valid product: %w", err)
+	}
+	return product, nil
+}
+
+// price converts an amount in cents, as platforms send prices, to the
+// currency unit products are priced in
+func price(cents int) float64 {
+	return float64(cents) / 100
+}
+
+// dietaryTags maps platform dietary labels such as "GLUTEN_FREE" to the
+// dietary tags products declare, reporting labels with no matching tag
+func dietaryTags(labels []string) (tags, unknown []string) {
+	for _, label := range labels {
+		tag := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(label)), "_", "-")
+		if slices.Contains(models.DietaryTags, tag) {
+			tags = append(tags, tag)
+		} else {
+			unknown = append(unknown, label)
+		}
+	}
+	return tags, unknown
+}
+
+// normalizeTag lowercases a language tag and uses hyphens between subtags,
+// so "en_US" becomes "en-us"
+func normalizeTag(tag string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(tag)), "_", "-")
+}
+
+// pickLocale splits text keyed by language tag into the text in locale and
+// the text in every other language. A tag matches locale by its primary
+// language too, so "en_us" is picked for "en". Without a match the first
+// tag in sorted order is picked.
+func pickLocale(texts map[string]string, locale string) (string, map[string]string) {
+	if len(texts) == 0 {
+		return "", nil
+	}
+	locale = normalizeTag(locale)
+	tags := make([]string, 0, len(texts))
+	for tag := range texts {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	picked, matched := tags[0], false
+	for _, tag := range tags {
+		normalized := normalizeTag(tag)
+		if normalized == locale {
+			picked = tag
+			break
+		}
+		if primary, _, _ := strings.Cut(normalized, "-"); primary == locale && !matched {
+			picked, matched = tag, true
+		}
+	}
+
+	others := make(map[string]string)
+	for _, tag := range tags {
+		if tag != picked && texts[tag] != "" {
+			others[normalizeTag(tag)] = texts[tag]
+		}
+	}
+	return texts[picked], others
+}
+
+// translationsOf gathers the translated name, description and category of
+// an item by language tag
+func translationsOf(names, descriptions, categories map[string]string) map[string]models.ProductTranslation {
+	translations := make(map[string]models.ProductTranslation)
+	for tag, name := range names {
+		t := translations[tag]
+		t.Name = name
+		translations[tag] = t
+	}
+	for tag, description := range descriptions {
+		t := translations[tag]
+		t.Description = description
+		translations[tag] = t
+	}
+	for tag, category := range categories {
+		t := translations[tag]
+		t.Category = category
+		translations[tag] = t
+	}
+	if len(translations) == 0 {
+		return nil
+	}
+	return translations
+}
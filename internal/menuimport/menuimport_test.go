@@ -0,0 +1,147 @@
+package menuimport
+
+import (
+	"testing"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const uberEatsExample = `{
+	"menus": [{"id": "all-day", "title": {"translations": {"en_us": "All day"}}, "category_ids": ["breakfast"]}],
+	"categories": [
+		{"id": "breakfast", "title": {"translations": {"en_us": "Breakfast", "fr_fr": "Petit-déjeuner"}}, "entities": [
+			{"id": "waffle", "type": "ITEM"},
+			{"id": "coffee", "type": "ITEM"},
+			{"id": "toast", "type": "ITEM"},
+			{"id": "missing", "type": "ITEM"}
+		]}
+	],
+	"items": [
+		{"id": "waffle", "external_data": "WAF-BER", "title": {"translations": {"en_us": "Waffle", "fr_fr": "Gaufre"}},
+		 "description": {"translations": {"en_us": "With berries"}}, "image_url": "https://example.com/waffle.jpg",
+		 "price_info": {"price": 650}, "dietary_info": {"labels": ["VEGETARIAN", "LOW_CARB"]},
+		 "modifier_group_ids": {"ids": ["extras"]}},
+		{"id": "coffee", "title": {"translations": {"en_us": "Coffee"}}, "image_url": "https://example.com/coffee.jpg",
+		 "price_info": {"price": 350}, "modifier_group_ids": {"ids": ["size"]}},
+		{"id": "toast", "title": {"translations": {"en_us": "Toast"}}, "price_info": {"price": 400}},
+		{"id": "small", "external_data": "COF-S", "title": {"translations": {"en_us": "Small"}}, "price_info": {"price": 0}},
+		{"id": "large", "external_data": "COF-L", "title": {"translations": {"en_us": "Large"}}, "price_info": {"price": 150}},
+		{"id": "cream", "title": {"translations": {"en_us": "Cream"}}, "price_info": {"price": 100}}
+	],
+	"modifier_groups": [
+		{"id": "size", "quantity_info": {"quantity": {"min_permitted": 1, "max_permitted": 1}},
+		 "modifier_options": [{"id": "small", "type": "ITEM"}, {"id": "large", "type": "ITEM"}]},
+		{"id": "extras", "quantity_info": {"quantity": {"min_permitted": 0, "max_permitted": 3}},
+		 "modifier_options": [{"id": "cream", "type": "ITEM"}]}
+	]
+}`
+
+const deliverectExample = `{
+	"menu": "Lunch",
+	"categories": [
+		{"_id": "c1", "name": "Mains", "nameTranslations": {"fr": "Plats"}, "products": ["p1", "p2", "p3"]}
+	],
+	"products": {
+		"p1": {"plu": "BURGER", "name": "Burger", "nameTranslations": {"fr": "Hamburger"}, "price": 1200,
+		       "imageUrl": "https://example.com/burger.jpg", "productType": 1, "subProducts": ["g1"]},
+		"g1": {"plu": "SIZE", "name": "Size", "productType": 3, "min": 1, "max": 1, "subProducts": ["m1", "m2"]},
+		"m1": {"plu": "BURGER-S", "name": "Single", "price": 0, "productType": 2},
+		"m2": {"plu": "BURGER-D", "name": "Double", "price": 400, "productType": 2},
+		"p2": {"plu": "MEAL", "name": "Meal deal", "price": 1500, "productType": 4},
+		"p3": {"plu": "FRIES", "name": "Fries", "price": 450, "imageUrl": "https://example.com/fries.jpg", "productType": 1}
+	}
+}`
+
+func TestParse_UberEats(t *testing.T) {
+	result, err := Parse(FormatUberEats, []byte(uberEatsExample), "en")
+	require.NoError(t, err)
+	require.Len(t, result.Products, 2)
+
+	coffee := result.Products[0]
+	assert.Equal(t, "coffee", coffee.ID)
+	assert.Equal(t, "Breakfast", coffee.Category)
+	assert.Equal(t, 3.5, coffee.Price)
+	assert.Empty(t, coffee.SKU)
+	assert.Equal(t, []models.ProductVariant{
+		{ID: "small", Name: "Small", Price: 3.5, SKU: "COF-S"},
+		{ID: "large", Name: "Large", Price: 5, SKU: "COF-L"},
+	}, coffee.Variants)
+
+	waffle := result.Products[1]
+	assert.Equal(t, "Waffle", waffle.Name)
+	assert.Equal(t, "With berries", waffle.Description)
+	assert.Equal(t, 6.5, waffle.Price)
+	assert.Equal(t, "WAF-BER", waffle.SKU)
+	assert.Equal(t, "https://example.com/waffle.jpg", waffle.Image.Desktop)
+	assert.Equal(t, []string{"vegetarian"}, waffle.Dietary)
+	assert.Equal(t, map[string]models.ProductTranslation{"fr-fr": {Name: "Gaufre", Category: "Petit-déjeuner"}}, waffle.Translations)
+
+	assert.ElementsMatch(t, []string{
+		"category breakfast: item missing is not in the menu",
+		"item waffle: dietary label LOW_CARB has no matching tag",
+		"item waffle: modifier group extras was left out; only one single required choice is imported, as variants",
+		"item toast: has no image",
+	}, result.Warnings)
+}
+
+func TestParse_Deliverect(t *testing.T) {
+	result, err := Parse(FormatDeliverect, []byte(deliverectExample), "en")
+	require.NoError(t, err)
+	require.Len(t, result.Products, 2)
+
+	burger := result.Products[0]
+	assert.Equal(t, "BURGER", burger.ID)
+	assert.Equal(t, "Mains", burger.Category)
+	assert.Equal(t, []models.ProductVariant{
+		{ID: "BURGER-S", Name: "Single", Price: 12, SKU: "BURGER-S"},
+		{ID: "BURGER-D", Name: "Double", Price: 16, SKU: "BURGER-D"},
+	}, burger.Variants)
+	assert.Equal(t, map[string]models.ProductTranslation{"fr": {Name: "Hamburger", Category: "Plats"}}, burger.Translations)
+
+	fries := result.Products[1]
+	assert.Equal(t, "FRIES", fries.ID)
+	assert.Equal(t, "FRIES", fries.SKU)
+	assert.Equal(t, 4.5, fries.Price)
+	assert.Equal(t, []string{"item MEAL: bundles are not imported"}, result.Warnings)
+
+	// Products may also be sent as a list
+	list := `{"categories": [{"name": "Sides", "products": ["FRIES"]}],
+		"products": [{"_id": "p3", "plu": "FRIES", "name": "Fries", "price": 450, "imageUrl": "https://example.com/fries.jpg", "productType": 1}]}`
+	result, err = Parse(FormatDeliverect, []byte(list), "en")
+	require.NoError(t, err)
+	require.Len(t, result.Products, 1)
+	assert.Equal(t, "Sides", result.Products[0].Category)
+}
+
+func TestParse_Invalid(t *testing.T) {
+	_, err := Parse("toast", []byte(`{}`), "en")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+
+	for _, format := range Formats {
+		_, err = Parse(format, []byte(`{"categories": [`), "en")
+		assert.ErrorIs(t, err, ErrInvalidMenu, format)
+		_, err = Parse(format, []byte(`{"categories": [], "products": {}, "items": []}`), "en")
+		assert.ErrorIs(t, err, ErrInvalidMenu, format)
+	}
+}
+
+func TestPickLocale(t *testing.T) {
+	texts := map[string]string{"de_DE": "Kaffee", "en_GB": "Coffee", "fr": "Café"}
+
+	text, others := pickLocale(texts, "en")
+	assert.Equal(t, "Coffee", text)
+	assert.Equal(t, map[string]string{"de-de": "Kaffee", "fr": "Café"}, others)
+
+	text, _ = pickLocale(texts, "fr")
+	assert.Equal(t, "Café", text)
+
+	// Without a match the first tag is picked
+	text, _ = pickLocale(texts, "ja")
+	assert.Equal(t, "Kaffee", text)
+
+	text, others = pickLocale(nil, "en")
+	assert.Empty(t, text)
+	assert.Empty(t, others)
+}
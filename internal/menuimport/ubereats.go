@@ -0,0 +1,155 @@
+package menuimport
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// uberMenu is an Uber Eats menu. Fields the mapping does not use, such as
+// menus, hours and tax rates, are ignored.
+type uberMenu struct {
+	Categories     []uberCategory      `json:"categories"`
+	Items          []uberItem          `json:"items"`
+	ModifierGroups []uberModifierGroup `json:"modifier_groups"`
+}
+
+// uberText is text keyed by language tag, e.g. "en_us"
+type uberText struct {
+	Translations map[string]string `json:"translations"`
+}
+
+type uberEntity struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+type uberCategory struct {
+	ID       string       `json:"id"`
+	Title    uberText     `json:"title"`
+	Entities []uberEntity `json:"entities"`
+}
+
+type uberItem struct {
+	ID           string   `json:"id"`
+	ExternalData string   `json:"external_data"`
+	Title        uberText `json:"title"`
+	Description  uberText `json:"description"`
+	ImageURL     string   `json:"image_url"`
+	PriceInfo    struct {
+		Price int `json:"price"` // In cents
+	} `json:"price_info"`
+	ModifierGroupIDs struct {
+		IDs []string `json:"ids"`
+	} `json:"modifier_group_ids"`
+	DietaryInfo struct {
+		Labels []string `json:"labels"`
+	} `json:"dietary_info"`
+}
+
+type uberModifierGroup struct {
+	ID           string `json:"id"`
+	QuantityInfo struct {
+		Quantity struct {
+			MinPermitted int `json:"min_permitted"`
+			MaxPermitted int `json:"max_permitted"`
+		} `json:"quantity"`
+	} `json:"quantity_info"`
+	ModifierOptions []uberEntity `json:"modifier_options"`
+}
+
+// parseUberEats maps the items listed in categories. A required single
+// choice, such as a size, becomes the item's variants, priced at the item's
+// price plus the option's; other modifier groups are left out.
+func parseUberEats(menu []byte, locale string) ([]item, []string, error) {
+	var m uberMenu
+	if err := json.Unmarshal(menu, &m); err != nil {
+		return nil, nil, err
+	}
+
+	uberItems := make(map[string]*uberItem, len(m.Items))
+	for i := range m.Items {
+		uberItems[m.Items[i].ID] = &m.Items[i]
+	}
+	groups := make(map[string]*uberModifierGroup, len(m.ModifierGroups))
+	for i := range m.ModifierGroups {
+		groups[m.ModifierGroups[i].ID] = &m.ModifierGroups[i]
+	}
+
+	var items []item
+	var warnings []string
+	for _, category := range m.Categories {
+		categoryName, categoryTranslations := pickLocale(category.Title.Translations, locale)
+		for _, entity := range category.Entities {
+			if entity.Type != "" && !strings.EqualFold(entity.Type, "ITEM") {
+				continue
+			}
+			ui, ok := uberItems[entity.ID]
+			if !ok {
+				warnings = append(warnings, fmt.Sprintf("category %s: item %s is not in the menu", category.ID, entity.ID))
+				continue
+			}
+
+			name, names := pickLocale(ui.Title.Translations, locale)
+			description, descriptions := pickLocale(ui.Description.Translations, locale)
+			dietary, unknown := dietaryTags(ui.DietaryInfo.Labels)
+			for _, label := range unknown {
+				warnings = append(warnings, fmt.Sprintf("item %s: dietary label %s has no matching tag", ui.ID, label))
+			}
+			it := item{
+				id:           ui.ID,
+				sku:          ui.ExternalData,
+				name:         name,
+				description:  description,
+				category:     categoryName,
+				cents:        ui.PriceInfo.Price,
+				imageURL:     ui.ImageURL,
+				dietary:      dietary,
+				translations: translationsOf(names, descriptions, categoryTranslations),
+			}
+
+			// Only the first single required choice can become variants
+			chosen := false
+			for _, groupID := range ui.ModifierGroupIDs.IDs {
+				group, ok := groups[groupID]
+				if !ok {
+					warnings = append(warnings, fmt.Sprintf("item %s: modifier group %s is not in the menu", ui.ID, groupID))
+					continue
+				}
+				quantity := group.QuantityInfo.Quantity
+				if chosen || quantity.MinPermitted != 1 || quantity.MaxPermitted != 1 {
+					warnings = append(warnings, fmt.Sprintf("item %s: modifier group %s was left out; only one single required choice is imported, as variants", ui.ID, groupID))
+					continue
+				}
+				it.variants, warnings = uberVariants(ui, group, uberItems, locale, warnings)
+				chosen = true
+			}
+			items = append(items, it)
+		}
+	}
+	return items, warnings, nil
+}
+
+// uberVariants maps the options of a single required choice into variants
+func uberVariants(ui *uberItem, group *uberModifierGroup, uberItems map[string]*uberItem, locale string, warnings []string) ([]variant, []string) {
+	var variants []variant
+	for _, option := range group.ModifierOptions {
+		oi, ok := uberItems[option.ID]
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("item %s: option %s is not in the menu", ui.ID, option.ID))
+			continue
+		}
+		name, _ := pickLocale(oi.Title.Translations, locale)
+		sku := oi.ExternalData
+		if sku == "" {
+			sku = oi.ID
+		}
+		variants = append(variants, variant{
+			id:    oi.ID,
+			name:  name,
+			cents: ui.PriceInfo.Price + oi.PriceInfo.Price,
+			sku:   sku,
+		})
+	}
+	return variants, warnings
+}
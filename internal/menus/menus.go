@@ -0,0 +1,146 @@
+// Package menus schedules the named menus a restaurant serves, such as
+// breakfast, lunch and dinner, and decides which products are offered when.
+package menus
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/hours"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+// ErrUnknownMenu is returned when a menu name matches no menu
+var ErrUnknownMenu = errors.New("unknown menu")
+
+// Menu is a named set of products served during its weekly windows
+type Menu struct {
+	Name string
+
+	categories map[string]bool // Lowercased
+	products   map[string]bool
+	schedule   *hours.Schedule
+}
+
+// Includes reports whether product is on the menu, by its category or ID
+func (m *Menu) Includes(product *models.Product) bool {
+	return m.categories[strings.ToLower(product.Category)] || m.products[product.ID]
+}
+
+// Selection is the menus products are offered from. A nil Selection offers
+// every product; an empty one offers none.
+type Selection []*Menu
+
+// Offers reports whether product is on a menu of the selection
+func (s Selection) Offers(product *models.Product) bool {
+	if s == nil {
+		return true
+	}
+	for _, m := range s {
+		if m.Includes(product) {
+			return true
+		}
+	}
+	return false
+}
+
+// Names returns the names of the menus of the selection
+func (s Selection) Names() []string {
+	names := make([]string, len(s))
+	for i, m := range s {
+		names[i] = m.Name
+	}
+	return names
+}
+
+// Menus is the set of menus of a restaurant. A nil Menus offers every
+// product at all times.
+type Menus struct {
+	menus []*Menu
+}
+
+// New creates Menus from their configuration, with windows in the given
+// time zone. No menus yields nil.
+func New(timezone string, cfg []config.Menu) (*Menus, error) {
+	if len(cfg) == 0 {
+		return nil, nil
+	}
+
+	ms := &Menus{}
+	names := make(map[string]bool)
+	for _, mc := range cfg {
+		if mc.Name == "" {
+			return nil, fmt.Errorf("menu name is required")
+		}
+		name := strings.ToLower(mc.Name)
+		if names[name] {
+			return nil, fmt.Errorf("duplicate menu: %s", mc.Name)
+		}
+		names[name] = true
+
+		if len(mc.Categories) == 0 && len(mc.Products) == 0 {
+			return nil, fmt.Errorf("menu %s: categories or products is required", mc.Name)
+		}
+		if len(mc.Weekly) == 0 {
+			return nil, fmt.Errorf("menu %s: weekly is required", mc.Name)
+		}
+		schedule, err := hours.New(timezone, mc.Weekly, 0)
+		if err != nil {
+			return nil, fmt.Errorf("menu %s: %w", mc.Name, err)
+		}
+
+		menu := &Menu{
+			Name:       name,
+			categories: make(map[string]bool, len(mc.Categories)),
+			products:   make(map[string]bool, len(mc.Products)),
+			schedule:   schedule,
+		}
+		for _, category := range mc.Categories {
+			menu.categories[strings.ToLower(category)] = true
+		}
+		for _, id := range mc.Products {
+			menu.products[id] = true
+		}
+		ms.menus = append(ms.menus, menu)
+	}
+
+	return ms, nil
+}
+
+// Active returns the menus served at t, in configuration order
+func (ms *Menus) Active(t time.Time) Selection {
+	if ms == nil {
+		return nil
+	}
+	active := make(Selection, 0, len(ms.menus))
+	for _, m := range ms.menus {
+		if m.schedule.IsOpen(t) {
+			active = append(active, m)
+		}
+	}
+	return active
+}
+
+// Named returns the menu called name, ignoring case, whether or not it is
+// being served
+func (ms *Menus) Named(name string) (Selection, error) {
+	if ms != nil {
+		for _, m := range ms.menus {
+			if strings.EqualFold(m.Name, name) {
+				return Selection{m}, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownMenu, name)
+}
+
+// All returns every menu, in configuration order
+func (ms *Menus) All() Selection {
+	if ms == nil {
+		return Selection{}
+	}
+	return Selection(ms.menus)
+}
@@ -0,0 +1,83 @@
+package menus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	weekly := map[string][]string{"monday": {"07:00-11:00"}}
+	tests := []struct {
+		name    string
+		menus   []config.Menu
+		wantErr bool
+		wantNil bool
+	}{
+		{name: "no menus", wantNil: true},
+		{name: "categories", menus: []config.Menu{{Name: "breakfast", Categories: []string{"Waffle"}, Weekly: weekly}}},
+		{name: "missing name", menus: []config.Menu{{Categories: []string{"Waffle"}, Weekly: weekly}}, wantErr: true},
+		{name: "duplicate name", menus: []config.Menu{{Name: "a", Products: []string{"1"}, Weekly: weekly}, {Name: "A", Products: []string{"2"}, Weekly: weekly}}, wantErr: true},
+		{name: "no products", menus: []config.Menu{{Name: "empty", Weekly: weekly}}, wantErr: true},
+		{name: "no windows", menus: []config.Menu{{Name: "breakfast", Products: []string{"1"}}}, wantErr: true},
+		{name: "invalid window", menus: []config.Menu{{Name: "breakfast", Products: []string{"1"}, Weekly: map[string][]string{"someday": {"07:00-11:00"}}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			menus, err := New("", tt.menus)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantNil, menus == nil)
+		})
+	}
+}
+
+func TestMenus(t *testing.T) {
+	ms, err := New("", []config.Menu{
+		{Name: "Breakfast", Categories: []string{"Waffle"}, Products: []string{"coffee"}, Weekly: map[string][]string{"monday": {"07:00-11:00"}}},
+		{Name: "lunch", Categories: []string{"sandwich"}, Products: []string{"coffee"}, Weekly: map[string][]string{"monday": {"10:30-15:00"}}},
+	})
+	require.NoError(t, err)
+
+	waffle := &models.Product{ID: "1", Category: "waffle"}
+	sandwich := &models.Product{ID: "2", Category: "Sandwich"}
+	coffee := &models.Product{ID: "coffee", Category: "Drinks"}
+
+	// Overlapping menus offer the products of both
+	active := ms.Active(time.Date(2024, 1, 1, 10, 45, 0, 0, time.UTC))
+	assert.Equal(t, []string{"breakfast", "lunch"}, active.Names())
+	assert.True(t, active.Offers(waffle))
+	assert.True(t, active.Offers(sandwich))
+
+	active = ms.Active(time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC))
+	assert.True(t, active.Offers(waffle))
+	assert.True(t, active.Offers(coffee))
+	assert.False(t, active.Offers(sandwich))
+
+	// Between menus nothing is offered
+	active = ms.Active(time.Date(2024, 1, 1, 16, 0, 0, 0, time.UTC))
+	assert.Empty(t, active.Names())
+	assert.False(t, active.Offers(coffee))
+
+	lunch, err := ms.Named("LUNCH")
+	require.NoError(t, err)
+	assert.True(t, lunch.Offers(sandwich))
+	assert.False(t, lunch.Offers(waffle))
+	_, err = ms.Named("dinner")
+	assert.ErrorIs(t, err, ErrUnknownMenu)
+	assert.Equal(t, []string{"breakfast", "lunch"}, ms.All().Names())
+
+	// Restaurants without menus offer every product at all times
+	var none *Menus
+	assert.True(t, none.Active(time.Now()).Offers(sandwich))
+	_, err = none.Named("lunch")
+	assert.ErrorIs(t, err, ErrUnknownMenu)
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	serve := func(t *testing.T, format string) string {
+		var buf bytes.Buffer
+		defaultWriter := gin.DefaultWriter
+		gin.DefaultWriter = &buf
+		t.Cleanup(func() { gin.DefaultWriter = defaultWriter })
+
+		engine := gin.New()
+		engine.Use(AccessLog(format))
+		engine.GET("/products", func(c *gin.Context) { c.String(http.StatusOK, "hello") })
+
+		req := httptest.NewRequest(http.MethodGet, "/products?page=2", nil)
+		req.RemoteAddr = "203.0.113.7:51234"
+		req.Header.Set("User-Agent", `curl/8.0 "quoted"`)
+		req.Header.Set(RequestIDHeader, "req-1")
+		engine.ServeHTTP(httptest.NewRecorder(), req)
+		return buf.String()
+	}
+
+	t.Run("combined", func(t *testing.T) {
+		line := serve(t, AccessLogCombined)
+		assert.Regexp(t, `^203\.0\.113\.7 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /products\?page=2 HTTP/1\.1" 200 5 "-" "curl/8\.0 \\"quoted\\""\n$`, line)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var entry accessLogEntry
+		require.NoError(t, json.Unmarshal([]byte(serve(t, AccessLogJSON)), &entry))
+		assert.Equal(t, http.MethodGet, entry.Method)
+		assert.Equal(t, "/products?page=2", entry.Path)
+		assert.Equal(t, http.StatusOK, entry.Status)
+		assert.Equal(t, "203.0.113.7", entry.ClientIP)
+		assert.Equal(t, 5, entry.Bytes)
+		assert.Equal(t, "req-1", entry.RequestID)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		line := serve(t, AccessLogText)
+		assert.Contains(t, line, "[GIN]")
+		assert.Contains(t, line, `"/products?page=2"`)
+	})
+}
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
+)
+
+// APIKeyHeader is the header clients use to authenticate
+const APIKeyHeader = "X-API-Key"
+
+// RequestedWithHeader must accompany writes authenticated with HTTP Basic
+// credentials. Browsers attach cached Basic credentials to cross-site
+// requests, but cannot add a custom header to them without a CORS
+// preflight, which never grants credentials, so the header shows the write
+// came from a page of the API's own origin, such as the admin dashboard.
+const RequestedWithHeader = "X-Requested-With"
+
+// APIKeyAuth returns a middleware that only lets requests through when they
+// carry one of the configured API keys. With no keys configured every request
+// is rejected, so admin routes are closed by default.
+func APIKeyAuth(keys []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !validAPIKey(c.GetHeader(APIKeyHeader), keys) {
+			respond.Abort(c, apierrors.New(apierrors.Unauthorized, "Missing or invalid API key"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// APIKeyAuthIf behaves like APIKeyAuth for requests cond matches and lets
+// every other request through. It guards admin-only options of public routes.
+func APIKeyAuthIf(keys []string, cond func(c *gin.Context) bool) gin.HandlerFunc {
+	requireKey := APIKeyAuth(keys)
+	return func(c *gin.Context) {
+		if cond(c) {
+			requireKey(c)
+			return
+		}
+		c.Next()
+	}
+}
+
+// BrowserAPIKeyAuth behaves like APIKeyAuth but also accepts the API key as
+// the password of HTTP Basic credentials, and challenges unauthenticated
+// requests so browsers prompt for it. It is meant for pages opened directly
+// in a browser, which cannot attach custom headers to navigations. Writes
+// sending Basic credentials without RequestedWithHeader are refused.
+func BrowserAPIKeyAuth(realm string, keys []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if refuseBasicWrite(c) {
+			return
+		}
+		if !validAPIKey(APIKey(c), keys) {
+			c.Header("WWW-Authenticate", `Basic realm="`+realm+`", charset="UTF-8"`)
+			respond.Abort(c, apierrors.New(apierrors.Unauthorized, "Missing or invalid API key"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// APIKey returns the API key a request presents, in the X-API-Key header or
+// else as the password of HTTP Basic credentials. Basic credentials only
+// count for reads, and for writes sending RequestedWithHeader.
+func APIKey(c *gin.Context) string {
+	if key := c.GetHeader(APIKeyHeader); key != "" {
+		return key
+	}
+	if !basicAllowed(c) {
+		return ""
+	}
+	_, key, _ := c.Request.BasicAuth()
+	return key
+}
+
+// basicAllowed reports whether a request may authenticate with HTTP Basic
+// credentials: reads may, and writes sending RequestedWithHeader, which
+// cross-site requests cannot
+func basicAllowed(c *gin.Context) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return c.GetHeader(RequestedWithHeader) != ""
+}
+
+// refuseBasicWrite aborts a write authenticated only with HTTP Basic
+// credentials that does not send RequestedWithHeader with 403, as it may
+// have been forged by another site, and reports whether it did
+func refuseBasicWrite(c *gin.Context) bool {
+	if c.GetHeader(APIKeyHeader) != "" || basicAllowed(c) {
+		return false
+	}
+	if _, _, ok := c.Request.BasicAuth(); !ok {
+		return false
+	}
+	respond.Abort(c, apierrors.New(apierrors.Forbidden, "Writes authenticated with Basic credentials must send "+RequestedWithHeader).
+		AddDetail("header", RequestedWithHeader))
+	return true
+}
+
+// validAPIKey compares the presented key against each configured key in constant time
+func validAPIKey(presented string, keys []string) bool {
+	if presented == "" {
+		return false
+	}
+	valid := false
+	for _, key := range keys {
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(key)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
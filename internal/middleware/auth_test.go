@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeyAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		keys           []string
+		header         string
+		expectedStatus int
+	}{
+		{
+			name:           "valid key",
+			keys:           []string{"key-1", "key-2"},
+			header:         "key-2",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid key",
+			keys:           []string{"key-1"},
+			header:         "wrong",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "missing key",
+			keys:           []string{"key-1"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "no keys configured",
+			header:         "key-1",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := gin.New()
+			engine.GET("/admin", APIKeyAuth(tt.keys), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			if tt.header != "" {
+				req.Header.Set(APIKeyHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
+
+func TestAPIKeyAuthIf(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	guarded := func(c *gin.Context) bool { return c.Query("all") == "true" }
+	engine.GET("/products", APIKeyAuthIf([]string{"key-1"}, guarded), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name           string
+		path           string
+		header         string
+		expectedStatus int
+	}{
+		{name: "unguarded without key", path: "/products", expectedStatus: http.StatusOK},
+		{name: "guarded with key", path: "/products?all=true", header: "key-1", expectedStatus: http.StatusOK},
+		{name: "guarded without key", path: "/products?all=true", expectedStatus: http.StatusUnauthorized},
+		{name: "guarded with invalid key", path: "/products?all=true", header: "wrong", expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(APIKeyHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
+
+func TestBrowserAPIKeyAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	engine.Any("/admin", BrowserAPIKeyAuth("admin", []string{"key-1"}), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	t.Run("basic auth password", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		req.SetBasicAuth("operator", "key-1")
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("header", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		req.Header.Set(APIKeyHeader, "key-1")
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("basic auth write", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/admin", nil)
+		req.SetBasicAuth("operator", "key-1")
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		req.Header.Set(RequestedWithHeader, "XMLHttpRequest")
+		rec = httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("challenge", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Contains(t, rec.Header().Get("WWW-Authenticate"), `Basic realm="admin"`)
+	})
+}
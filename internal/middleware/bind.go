@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
+)
+
+// boundKey is the context key Bind stores the request body under
+const boundKey = "middleware.bound"
+
+// Bind returns a middleware that decodes the request body into a T, as
+// DecodeJSON does, and validates it with models.Validate when T is a
+// struct. Each prepare func is called on the decoded value before it is
+// validated, such as to fill a field from the path, and may refuse the
+// request with an error. A body that cannot be decoded is refused as
+// DecodeError maps it; one that fails validation with 422
+// VALIDATION_ERROR, listing the rule each field failed by its JSON path.
+// Handlers read the value with Bound.
+func Bind[T any](prepare ...func(c *gin.Context, v *T) *models.ErrorResponse) gin.HandlerFunc {
+	validated := reflect.TypeFor[T]().Kind() == reflect.Struct
+	return func(c *gin.Context) {
+		v := new(T)
+		if err := DecodeJSON(c.Request, v); err != nil {
+			respond.Abort(c, DecodeError(err))
+			return
+		}
+		for _, p := range prepare {
+			if errResp := p(c, v); errResp != nil {
+				respond.Abort(c, errResp)
+				return
+			}
+		}
+		if validated {
+			if err := models.Validate(v); err != nil {
+				respond.Abort(c, apierrors.New(apierrors.ValidationError, "Invalid request body").
+					AddDetail("error", err.Error()).
+					AddDetail("fields", models.ValidationFields(err)))
+				return
+			}
+		}
+		c.Set(boundKey, v)
+		c.Next()
+	}
+}
+
+// Bound returns the request body Bind decoded. It panics when the route
+// does not bind a T, which is a mistake in the route table.
+func Bound[T any](c *gin.Context) *T {
+	bound, _ := c.Get(boundKey)
+	v, ok := bound.(*T)
+	if !ok {
+		panic(fmt.Sprintf("middleware: route %s %s does not bind a %s", c.Request.Method, c.FullPath(), reflect.TypeFor[T]()))
+	}
+	return v
+}
+
+// DecodeJSON decodes a single JSON value from the request body into v.
+// Data after the value is rejected, as are unknown fields when the request
+// was routed through a strict BodyLimit.
+func DecodeJSON(r *http.Request, v any) error {
+	decoder := json.NewDecoder(r.Body)
+	if StrictJSON(r.Context()) {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return err
+		}
+		return fmt.Errorf("unexpected data after the JSON body")
+	}
+	return nil
+}
+
+// DecodeError maps an error from DecodeJSON to an error response:
+// PAYLOAD_TOO_LARGE when the body exceeds the size limit, INVALID_REQUEST
+// otherwise
+func DecodeError(err error) *models.ErrorResponse {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return apierrors.New(apierrors.PayloadTooLarge, "Request body is too large").
+			AddDetail("maxBytes", tooLarge.Limit)
+	}
+	return apierrors.New(apierrors.InvalidRequest, "Failed to parse request body").
+		AddDetail("error", err.Error())
+}
@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		strict         bool
+		body           string
+		expectedStatus int // 0 when the body decodes
+		expectedCode   string
+	}{
+		{name: "valid", strict: true, body: `{"orderId":"order-1","rating":5}`},
+		{name: "trailing whitespace", strict: true, body: "{\"orderId\":\"order-1\",\"rating\":5}\n"},
+		{name: "unknown field when strict", strict: true, body: `{"orderId":"order-1","rating":5,"stars":5}`, expectedStatus: http.StatusBadRequest, expectedCode: "INVALID_REQUEST"},
+		{name: "unknown field when lax", strict: false, body: `{"orderId":"order-1","rating":5,"stars":5}`},
+		{name: "trailing data", strict: false, body: `{"orderId":"order-1","rating":5}{}`, expectedStatus: http.StatusBadRequest, expectedCode: "INVALID_REQUEST"},
+		{name: "malformed", strict: true, body: `{"orderId":`, expectedStatus: http.StatusBadRequest, expectedCode: "INVALID_REQUEST"},
+		{name: "too large", strict: true, body: `{"orderId":"order-1","rating":5,"text":"` + strings.Repeat("x", 128) + `"}`, expectedStatus: http.StatusRequestEntityTooLarge, expectedCode: "PAYLOAD_TOO_LARGE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var decodeErr error
+			var got models.ReviewRequest
+			engine := gin.New()
+			engine.POST("/", BodyLimit(64, tt.strict), func(c *gin.Context) {
+				decodeErr = DecodeJSON(c.Request, &got)
+			})
+			engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body)))
+
+			if tt.expectedStatus == 0 {
+				require.NoError(t, decodeErr)
+				assert.Equal(t, "order-1", got.OrderID)
+				return
+			}
+			require.Error(t, decodeErr)
+			errResp := DecodeError(decodeErr)
+			assert.Equal(t, tt.expectedStatus, apierrors.Status(errResp.Code))
+			assert.Equal(t, tt.expectedCode, errResp.Code)
+		})
+	}
+
+	t.Run("lax without middleware", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"orderId":"order-1","stars":5}`)).WithContext(context.Background())
+		var got models.ReviewRequest
+		assert.NoError(t, DecodeJSON(req, &got))
+	})
+}
+
+func TestBind(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var got *models.OrderRequest
+	engine := gin.New()
+	engine.POST("/orders/:coupon", BodyLimit(1024, true), Bind(func(c *gin.Context, req *models.OrderRequest) *models.ErrorResponse {
+		if c.Param("coupon") == "EXPIRED" {
+			return apierrors.New(apierrors.InvalidCoupon, "Coupon has expired")
+		}
+		req.CouponCode = c.Param("coupon")
+		return nil
+	}), func(c *gin.Context) {
+		got = Bound[models.OrderRequest](c)
+	})
+	engine.POST("/raw", Bind[json.RawMessage](), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	engine.POST("/unbound", func(c *gin.Context) {
+		assert.Panics(t, func() { Bound[models.OrderRequest](c) })
+	})
+
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		expectedStatus int
+		expectedCode   string
+		expectedFields map[string]any
+	}{
+		{name: "valid", path: "/orders/HAPPYHRS", body: `{"items":[{"productId":"prod-1","quantity":2}]}`, expectedStatus: http.StatusOK},
+		{name: "malformed", path: "/orders/HAPPYHRS", body: `{"items":`, expectedStatus: http.StatusBadRequest, expectedCode: "INVALID_REQUEST"},
+		{name: "refused while prepared", path: "/orders/EXPIRED", body: `{"items":[]}`, expectedStatus: http.StatusBadRequest, expectedCode: "INVALID_COUPON"},
+		{name: "unknown field", path: "/orders/HAPPYHRS", body: `{"items":[],"extra":1}`, expectedStatus: http.StatusBadRequest, expectedCode: "INVALID_REQUEST"},
+		{
+			name:           "invalid",
+			path:           "/orders/HAPPYHRS",
+			body:           `{"items":[{"productId":"prod-1","quantity":0}]}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   "VALIDATION_ERROR",
+			expectedFields: map[string]any{"items[0].quantity": "required"},
+		},
+		{name: "not a struct", path: "/raw", body: `[1, 2]`, expectedStatus: http.StatusNoContent},
+		{name: "unbound", path: "/unbound", body: `{}`, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedCode == "" {
+				return
+			}
+
+			assert.Nil(t, got)
+			var errResp models.ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &errResp))
+			assert.Equal(t, tt.expectedCode, errResp.Code)
+			if tt.expectedFields != nil {
+				assert.Equal(t, tt.expectedFields, errResp.Details["fields"])
+			}
+		})
+	}
+
+	t.Run("prepared before use", func(t *testing.T) {
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/orders/HAPPYHRS", strings.NewReader(`{"items":[{"productId":"prod-1","quantity":2}]}`)))
+		require.NotNil(t, got)
+		assert.Equal(t, "HAPPYHRS", got.CouponCode)
+	})
+}
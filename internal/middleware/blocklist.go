@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/netip"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/blocklist"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
+)
+
+// Blocklist returns a middleware that refuses callers the list blocks by IP
+// address or by API key, whether sent in the X-API-Key header or as the
+// password of HTTP Basic credentials, before any other handling
+func Blocklist(list *blocklist.List) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip, _ := netip.ParseAddr(c.ClientIP())
+		if entry, blocked := list.Blocked(ip, APIKey(c)); blocked {
+			respond.Abort(c, apierrors.New(apierrors.Forbidden, "Access denied").
+				AddDetail("entryId", entry.ID))
+			return
+		}
+		c.Next()
+	}
+}
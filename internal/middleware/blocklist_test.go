@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/blocklist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlocklist(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	list, err := blocklist.Load("")
+	require.NoError(t, err)
+	_, err = list.Add(blocklist.EntryRequest{Type: blocklist.TypeIP, Value: "203.0.113.0/24"}, "admin", "")
+	require.NoError(t, err)
+	_, err = list.Add(blocklist.EntryRequest{Type: blocklist.TypeAPIKey, Value: "stolen-key"}, "admin", "")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		remoteAddr     string
+		header         string
+		basic          string
+		expectedStatus int
+	}{
+		{
+			name:           "unlisted caller",
+			remoteAddr:     "192.0.2.10:1234",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "blocked range",
+			remoteAddr:     "203.0.113.7:1234",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "blocked key in header",
+			remoteAddr:     "192.0.2.10:1234",
+			header:         "stolen-key",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "blocked key as basic password",
+			remoteAddr:     "192.0.2.10:1234",
+			basic:          "stolen-key",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "other key",
+			remoteAddr:     "192.0.2.10:1234",
+			header:         "good-key",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := gin.New()
+			engine.Use(Blocklist(list))
+			engine.GET("/products", func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/products", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.header != "" {
+				req.Header.Set(APIKeyHeader, tt.header)
+			}
+			if tt.basic != "" {
+				req.SetBasicAuth("admin", tt.basic)
+			}
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus == http.StatusForbidden {
+				assert.Contains(t, rec.Body.String(), "FORBIDDEN")
+			}
+		})
+	}
+}
+
+func TestBlocklist_NilList(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	engine.Use(Blocklist(nil))
+	engine.GET("/products", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/products", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
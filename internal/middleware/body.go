@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// strictJSONKey is the context key recording the JSON decoding policy
+type strictJSONKey struct{}
+
+// BodyLimit returns a middleware that caps request bodies at maxBytes, with
+// no cap when maxBytes is not positive. Reads past the cap fail with an
+// *http.MaxBytesError. strictJSON is recorded for handlers decoding JSON
+// bodies; see StrictJSON.
+func BodyLimit(maxBytes int64, strictJSON bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if maxBytes > 0 && c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		}
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), strictJSONKey{}, strictJSON))
+		c.Next()
+	}
+}
+
+// StrictJSON reports whether JSON request bodies must not contain fields
+// the API does not know. It is false unless set by BodyLimit.
+func StrictJSON(ctx context.Context) bool {
+	strict, _ := ctx.Value(strictJSONKey{}).(bool)
+	return strict
+}
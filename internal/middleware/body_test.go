@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBodyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		maxBytes   int64
+		strictJSON bool
+		body       string
+		tooLarge   bool
+	}{
+		{name: "within limit", maxBytes: 8, strictJSON: true, body: "12345678"},
+		{name: "over limit", maxBytes: 8, body: "123456789", tooLarge: true},
+		{name: "no limit", maxBytes: 0, body: strings.Repeat("x", 1024)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var readErr error
+			var strict bool
+			engine := gin.New()
+			engine.POST("/", BodyLimit(tt.maxBytes, tt.strictJSON), func(c *gin.Context) {
+				_, readErr = io.ReadAll(c.Request.Body)
+				strict = StrictJSON(c.Request.Context())
+				c.Status(http.StatusNoContent)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			engine.ServeHTTP(httptest.NewRecorder(), req)
+
+			var maxBytesErr *http.MaxBytesError
+			assert.Equal(t, tt.tooLarge, errors.As(readErr, &maxBytesErr), readErr)
+			assert.Equal(t, tt.strictJSON, strict)
+		})
+	}
+}
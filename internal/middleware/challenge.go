@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apikeys"
+	"github.com/ravibandhu/oolio-food-ordering/internal/challenge"
+	"github.com/ravibandhu/oolio-food-ordering/internal/logging"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
+	"github.com/ravibandhu/oolio-food-ordering/internal/tenant"
+)
+
+// ChallengeHeader is the header clients send the token of a solved challenge in
+const ChallengeHeader = "X-Challenge-Token"
+
+// Challenge returns a middleware that, when the request's tenant challenges
+// orders, only lets requests through that carry an active API key or a
+// challenge token the tenant's verifier accepts. Tokens that
+// cannot be verified are refused, so orders are not accepted unchallenged
+// while the provider is unreachable.
+func Challenge(keys *apikeys.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		t, ok := tenant.FromContext(c.Request.Context())
+		if !ok || t.Challenge == nil {
+			c.Next()
+			return
+		}
+		if _, ok := keys.Authenticate(APIKey(c)); ok {
+			c.Next()
+			return
+		}
+
+		err := t.Challenge.Verify(c.Request.Context(), c.GetHeader(ChallengeHeader), c.ClientIP())
+		switch {
+		case errors.Is(err, challenge.ErrMissingToken):
+			respond.Abort(c, apierrors.New(apierrors.ChallengeFailed, "A challenge token is required").
+				AddDetail("header", ChallengeHeader))
+			return
+		case errors.Is(err, challenge.ErrRejected):
+			respond.Abort(c, apierrors.New(apierrors.ChallengeFailed, "The challenge token is invalid or expired").
+				AddDetail("header", ChallengeHeader))
+			return
+		case err != nil:
+			logging.Sampled("challenge-failed", "challenge verification failed: %v", err)
+			respond.Abort(c, apierrors.New(apierrors.ChallengeUnavailable, "The challenge token could not be verified"))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/challenge"
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/tenant"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeVerifier accepts the token "solved" and fails to reach its provider
+// for the token "unreachable"
+type fakeVerifier struct{}
+
+func (fakeVerifier) Verify(ctx context.Context, token, remoteIP string) error {
+	switch token {
+	case "":
+		return challenge.ErrMissingToken
+	case "solved":
+		return nil
+	case "unreachable":
+		return errors.New("connection refused")
+	}
+	return challenge.ErrRejected
+}
+
+func TestChallenge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	keys := loadKeys(t, config.Auth{KitchenKeys: []string{"key-1"}})
+
+	tests := []struct {
+		name           string
+		verifier       challenge.Verifier
+		token          string
+		apiKey         string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{name: "not challenged", expectedStatus: http.StatusOK},
+		{name: "solved", verifier: fakeVerifier{}, token: "solved", expectedStatus: http.StatusOK},
+		{name: "missing token", verifier: fakeVerifier{}, expectedStatus: http.StatusForbidden, expectedCode: "CHALLENGE_FAILED"},
+		{name: "rejected token", verifier: fakeVerifier{}, token: "forged", expectedStatus: http.StatusForbidden, expectedCode: "CHALLENGE_FAILED"},
+		{name: "provider unreachable", verifier: fakeVerifier{}, token: "unreachable", expectedStatus: http.StatusServiceUnavailable, expectedCode: "CHALLENGE_UNAVAILABLE"},
+		{name: "authenticated", verifier: fakeVerifier{}, apiKey: "key-1", expectedStatus: http.StatusOK},
+		{name: "invalid key", verifier: fakeVerifier{}, apiKey: "wrong", expectedStatus: http.StatusForbidden, expectedCode: "CHALLENGE_FAILED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := gin.New()
+			engine.Use(func(c *gin.Context) {
+				ctx := tenant.NewContext(c.Request.Context(), &tenant.Tenant{ID: "default", Challenge: tt.verifier})
+				c.Request = c.Request.WithContext(ctx)
+			})
+			engine.POST("/orders", Challenge(keys), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+			if tt.token != "" {
+				req.Header.Set(ChallengeHeader, tt.token)
+			}
+			if tt.apiKey != "" {
+				req.Header.Set(APIKeyHeader, tt.apiKey)
+			}
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedCode != "" {
+				assert.Contains(t, rec.Body.String(), tt.expectedCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,15 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/velocity"
+)
+
+// Client returns a middleware that identifies the client of each request by
+// its IP address and stores it in the request context for velocity checks
+func Client() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(velocity.NewContext(c.Request.Context(), c.ClientIP()))
+		c.Next()
+	}
+}
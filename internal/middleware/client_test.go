@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/velocity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var client string
+	engine := gin.New()
+	engine.GET("/orders", Client(), func(c *gin.Context) {
+		client = velocity.FromContext(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.RemoteAddr = "192.0.2.10:54321"
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "192.0.2.10", client)
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// corsRequestHeaders are the request headers browsers may send across
+// origins: the ones the API reads
+var corsRequestHeaders = strings.Join([]string{
+	"Accept-Language", "Authorization", "Content-Type", "If-Match",
+	APIKeyHeader, ChallengeHeader, EnvelopeHeader, RequestIDHeader, TenantHeader,
+}, ", ")
+
+// corsResponseHeaders are the response headers scripts on other origins
+// may read, besides the ones browsers always expose
+var corsResponseHeaders = strings.Join([]string{
+	"Deprecation", "ETag", "Retry-After", "Sunset", "X-Catalog-Revision",
+	nextCursorHeader, RequestIDHeader,
+}, ", ")
+
+// CORS returns a middleware that lets scripts on origins read the API's
+// responses, or on any origin when origins holds "*". Requests from other
+// origins are served as usual, but browsers do not let their scripts read
+// the response. With no origins, no CORS headers are sent.
+func CORS(origins []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if origin := c.GetHeader("Origin"); allowsOrigin(origins, origin) {
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Header("Access-Control-Expose-Headers", corsResponseHeaders)
+		}
+		if len(origins) > 0 {
+			c.Writer.Header().Add("Vary", "Origin")
+		}
+		c.Next()
+	}
+}
+
+// Options returns the handler answering OPTIONS requests to a path served
+// with methods. It lists the methods in Allow and, for CORS preflight
+// requests from origins that may call the API, allows the method asked
+// for if the path serves it, and the headers the API reads. Browsers cache
+// the answer for maxAge. Preflights the API does not allow get no
+// Access-Control-Allow-Methods, so browsers do not send the request.
+func Options(methods []string, origins []string, maxAge time.Duration) gin.HandlerFunc {
+	methods = append(slices.Clone(methods), http.MethodOptions)
+	allow := strings.Join(methods, ", ")
+	return func(c *gin.Context) {
+		c.Header("Allow", allow)
+		requested := c.GetHeader("Access-Control-Request-Method")
+		if requested != "" && allowsOrigin(origins, c.GetHeader("Origin")) && slices.Contains(methods, requested) {
+			c.Header("Access-Control-Allow-Methods", allow)
+			c.Header("Access-Control-Allow-Headers", corsRequestHeaders)
+			c.Header("Access-Control-Max-Age", strconv.Itoa(int(maxAge.Seconds())))
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+// allowsOrigin reports whether scripts on origin may call the API
+func allowsOrigin(origins []string, origin string) bool {
+	return origin != "" && (slices.Contains(origins, "*") || slices.Contains(origins, origin))
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		origins       []string
+		origin        string
+		expectedAllow string
+		expectedVary  string
+	}{
+		{name: "no origins configured", origin: "https://shop.example.com"},
+		{name: "allowed origin", origins: []string{"https://shop.example.com"}, origin: "https://shop.example.com", expectedAllow: "https://shop.example.com", expectedVary: "Origin"},
+		{name: "other origin", origins: []string{"https://shop.example.com"}, origin: "https://evil.example.com", expectedVary: "Origin"},
+		{name: "any origin", origins: []string{"*"}, origin: "https://evil.example.com", expectedAllow: "https://evil.example.com", expectedVary: "Origin"},
+		{name: "same origin", origins: []string{"*"}, expectedVary: "Origin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := gin.New()
+			engine.Use(CORS(tt.origins))
+			engine.GET("/", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusNoContent, rec.Code)
+			assert.Equal(t, tt.expectedAllow, rec.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, tt.expectedVary, rec.Header().Get("Vary"))
+			if tt.expectedAllow != "" {
+				assert.Contains(t, rec.Header().Get("Access-Control-Expose-Headers"), RequestIDHeader)
+			}
+		})
+	}
+}
+
+func TestOptions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	engine.OPTIONS("/", Options([]string{http.MethodGet, http.MethodPost}, []string{"https://shop.example.com"}, 10*time.Minute))
+
+	tests := []struct {
+		name            string
+		origin          string
+		method          string
+		expectedMethods string
+	}{
+		{name: "not a preflight", origin: "https://shop.example.com"},
+		{name: "preflight", origin: "https://shop.example.com", method: http.MethodPost, expectedMethods: "GET, POST, OPTIONS"},
+		{name: "unserved method", origin: "https://shop.example.com", method: http.MethodDelete},
+		{name: "other origin", origin: "https://evil.example.com", method: http.MethodPost},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, "/", nil)
+			req.Header.Set("Origin", tt.origin)
+			if tt.method != "" {
+				req.Header.Set("Access-Control-Request-Method", tt.method)
+			}
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusNoContent, rec.Code)
+			assert.Equal(t, "GET, POST, OPTIONS", rec.Header().Get("Allow"))
+			assert.Equal(t, tt.expectedMethods, rec.Header().Get("Access-Control-Allow-Methods"))
+			if tt.expectedMethods != "" {
+				assert.Contains(t, rec.Header().Get("Access-Control-Allow-Headers"), APIKeyHeader)
+				assert.Equal(t, "600", rec.Header().Get("Access-Control-Max-Age"))
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/auth"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
+)
+
+// CustomerAuth returns a middleware that only lets requests through when
+// they carry a valid customer session token as "Authorization: Bearer
+// <token>", and stores the signed-in customer and the token's session claims
+// in the request context
+func CustomerAuth(accounts *auth.Accounts) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := bearerToken(c)
+		if !ok {
+			c.Header("WWW-Authenticate", "Bearer")
+			respond.Abort(c, apierrors.New(apierrors.Unauthorized, "Missing session token"))
+			return
+		}
+		signIn(c, accounts, token)
+	}
+}
+
+// OptionalCustomerAuth returns a middleware that lets every request through,
+// signing in the customer of requests carrying a session token as
+// CustomerAuth does. Requests with an invalid token are refused rather than
+// served anonymously.
+func OptionalCustomerAuth(accounts *auth.Accounts) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		token, ok := bearerToken(c)
+		if !ok {
+			c.Header("WWW-Authenticate", "Bearer")
+			respond.Abort(c, apierrors.New(apierrors.Unauthorized, "Missing session token"))
+			return
+		}
+		signIn(c, accounts, token)
+	}
+}
+
+// signIn stores the customer of a session token and its claims in the
+// request context, refusing invalid tokens
+func signIn(c *gin.Context, accounts *auth.Accounts, token string) {
+	customer, claims, err := accounts.Authenticate(token)
+	if err != nil {
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		respond.Abort(c, apierrors.New(apierrors.InvalidToken, "Invalid or expired session token"))
+		return
+	}
+
+	ctx := auth.NewContext(c.Request.Context(), customer)
+	c.Request = c.Request.WithContext(auth.WithSession(ctx, claims))
+	c.Next()
+}
+
+// bearerToken returns the token of a request's Bearer authorization
+func bearerToken(c *gin.Context) (string, bool) {
+	scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/auth"
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomerAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	accounts, err := auth.Load(config.Auth{SessionTTL: time.Hour, RefreshTTL: time.Hour}, nil)
+	require.NoError(t, err)
+	customer, err := accounts.Customers.SignIn(&auth.Profile{Identity: auth.Identity{Provider: auth.ProviderGoogle, Subject: "g-1"}})
+	require.NoError(t, err)
+	session, err := accounts.Sessions.Issue(customer)
+	require.NoError(t, err)
+	ended, err := accounts.Sessions.Issue(customer)
+	require.NoError(t, err)
+	_, claims, err := accounts.Authenticate(ended.AccessToken)
+	require.NoError(t, err)
+	accounts.Logout(claims)
+
+	tests := []struct {
+		name           string
+		authorization  string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{name: "valid session", authorization: "Bearer " + session.AccessToken, expectedStatus: http.StatusOK},
+		{name: "lowercase scheme", authorization: "bearer " + session.AccessToken, expectedStatus: http.StatusOK},
+		{name: "missing", expectedStatus: http.StatusUnauthorized, expectedCode: "UNAUTHORIZED"},
+		{name: "basic credentials", authorization: "Basic dXNlcjpwYXNz", expectedStatus: http.StatusUnauthorized, expectedCode: "UNAUTHORIZED"},
+		{name: "invalid token", authorization: "Bearer not-a-token", expectedStatus: http.StatusUnauthorized, expectedCode: "INVALID_TOKEN"},
+		{name: "ended session", authorization: "Bearer " + ended.AccessToken, expectedStatus: http.StatusUnauthorized, expectedCode: "INVALID_TOKEN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var signedIn, sessionID string
+			engine := gin.New()
+			engine.GET("/auth/me", CustomerAuth(accounts), func(c *gin.Context) {
+				if customer, ok := auth.FromContext(c.Request.Context()); ok {
+					signedIn = customer.ID
+				}
+				if claims, ok := auth.SessionFromContext(c.Request.Context()); ok {
+					sessionID = claims.SessionID
+				}
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/auth/me", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, customer.ID, signedIn)
+				assert.NotEmpty(t, sessionID)
+				return
+			}
+			assert.Contains(t, rec.Body.String(), tt.expectedCode)
+			assert.Contains(t, rec.Header().Get("WWW-Authenticate"), "Bearer")
+		})
+	}
+}
+
+func TestOptionalCustomerAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	accounts, err := auth.Load(config.Auth{SessionTTL: time.Hour, RefreshTTL: time.Hour}, nil)
+	require.NoError(t, err)
+	customer, err := accounts.Customers.SignIn(&auth.Profile{Identity: auth.Identity{Provider: auth.ProviderGoogle, Subject: "g-1"}})
+	require.NoError(t, err)
+	session, err := accounts.Sessions.Issue(customer)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		authorization  string
+		expectedStatus int
+		expectedID     string
+	}{
+		{name: "anonymous", expectedStatus: http.StatusOK},
+		{name: "valid session", authorization: "Bearer " + session.AccessToken, expectedStatus: http.StatusOK, expectedID: customer.ID},
+		{name: "basic credentials", authorization: "Basic dXNlcjpwYXNz", expectedStatus: http.StatusUnauthorized},
+		{name: "invalid token", authorization: "Bearer not-a-token", expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var signedIn string
+			engine := gin.New()
+			engine.GET("/carts/1", OptionalCustomerAuth(accounts), func(c *gin.Context) {
+				if customer, ok := auth.FromContext(c.Request.Context()); ok {
+					signedIn = customer.ID
+				}
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/carts/1", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Equal(t, tt.expectedID, signedIn)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/deprecation"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
+)
+
+// warningsKey is the gin context key warnings for the client are kept under
+const warningsKey = "warnings"
+
+// AddWarning records a warning for the client, given in the meta of
+// enveloped responses
+func AddWarning(c *gin.Context, warning string) {
+	warnings, _ := c.Get(warningsKey)
+	list, _ := warnings.([]string)
+	c.Set(warningsKey, append(list, warning))
+}
+
+// Warnings returns the warnings recorded for the client
+func Warnings(c *gin.Context) []string {
+	warnings, _ := c.Get(warningsKey)
+	list, _ := warnings.([]string)
+	return list
+}
+
+// Deprecation returns a middleware that announces the notices of policy on
+// the routes they cover. Deprecated routes answer with a Deprecation header
+// and, when set, Sunset and Link headers; every notice, including those of
+// fields, is recorded as a warning. Routes past their sunset are refused
+// with 410 GONE.
+func Deprecation(policy *deprecation.Policy, now func() time.Time) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		notices := policy.For(c.Request.Method, c.FullPath())
+		for _, n := range notices {
+			AddWarning(c, n.Warning())
+			if n.Field != "" {
+				continue
+			}
+			c.Header("Deprecation", "@"+strconv.FormatInt(n.Since.Unix(), 10))
+			if !n.Sunset.IsZero() {
+				c.Header("Sunset", n.Sunset.UTC().Format(http.TimeFormat))
+			}
+			if n.Link != "" {
+				c.Writer.Header().Add("Link", "<"+n.Link+`>; rel="deprecation"`)
+			}
+			if n.Retired(now()) {
+				respond.Abort(c, apierrors.New(apierrors.Gone, "Route was retired").
+					AddDetail("sunset", n.Sunset.UTC().Format(time.RFC3339)).
+					AddDetail("warning", n.Warning()))
+				return
+			}
+		}
+		c.Next()
+	}
+}
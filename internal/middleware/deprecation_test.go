@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/deprecation"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeprecation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)
+	policy, err := deprecation.New([]deprecation.Notice{
+		{Route: "/v1/items/{id}", Since: since, Sunset: sunset, Link: "https://example.com/v2"},
+		{Method: http.MethodGet, Route: "/v2/items/:id", Field: "colour", Since: since},
+	})
+	require.NoError(t, err)
+
+	serve := func(now time.Time, path string) *httptest.ResponseRecorder {
+		engine := gin.New()
+		engine.Use(Envelope(true), Deprecation(policy, func() time.Time { return now }))
+		engine.GET("/v1/items/:id", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"id": c.Param("id")}) })
+		engine.GET("/v2/items/:id", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"id": c.Param("id")}) })
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+	envelope := func(rec *httptest.ResponseRecorder) models.Envelope {
+		var env models.Envelope
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &env))
+		return env
+	}
+
+	rec := serve(since.AddDate(0, 1, 0), "/v1/items/7")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "@1767225600", rec.Header().Get("Deprecation"))
+	assert.Equal(t, "Wed, 01 Jul 2026 00:00:00 GMT", rec.Header().Get("Sunset"))
+	assert.Equal(t, `<https://example.com/v2>; rel="deprecation"`, rec.Header().Get("Link"))
+	assert.Len(t, envelope(rec).Meta.Warnings, 1)
+
+	// Deprecated fields are warned about without deprecating the route
+	rec = serve(since.AddDate(0, 1, 0), "/v2/items/7")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Deprecation"))
+	assert.Equal(t, []string{"Field colour of GET /v2/items/:id is deprecated since 2026-01-01."}, envelope(rec).Meta.Warnings)
+
+	// Routes past their sunset are refused
+	rec = serve(sunset, "/v1/items/7")
+	assert.Equal(t, http.StatusGone, rec.Code)
+	env := envelope(rec)
+	require.Len(t, env.Errors, 1)
+	assert.Equal(t, apierrors.Gone, env.Errors[0].Code)
+	assert.Len(t, env.Meta.Warnings, 1)
+}
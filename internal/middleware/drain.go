@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
+)
+
+// Drain returns a middleware that, while draining reports true, refuses
+// requests that change anything, such as placing an order, with 503
+// SHUTTING_DOWN, so no write is cut off halfway as the server stops. Reads
+// are still served. Every response while draining asks the client to close
+// its connection, so its next request goes to another instance.
+func Drain(draining func() bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !draining() {
+			c.Next()
+			return
+		}
+
+		c.Header("Connection", "close")
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+		default:
+			c.Header("Retry-After", "1")
+			respond.Abort(c, apierrors.New(apierrors.ShuttingDown, "Server is shutting down; retry the request"))
+		}
+	}
+}
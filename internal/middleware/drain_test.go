@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrain(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	draining := false
+	router := gin.New()
+	router.Use(Drain(func() bool { return draining }))
+	router.GET("/products", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.POST("/orders", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		name           string
+		draining       bool
+		method         string
+		path           string
+		expectedStatus int
+	}{
+		{name: "write while serving", method: http.MethodPost, path: "/orders", expectedStatus: http.StatusOK},
+		{name: "read while draining", draining: true, method: http.MethodGet, path: "/products", expectedStatus: http.StatusOK},
+		{name: "write while draining", draining: true, method: http.MethodPost, path: "/orders", expectedStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			draining = tt.draining
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.draining {
+				assert.Equal(t, "close", w.Header().Get("Connection"))
+			} else {
+				assert.Empty(t, w.Header().Get("Connection"))
+			}
+			if tt.expectedStatus == http.StatusServiceUnavailable {
+				assert.Contains(t, w.Body.String(), "SHUTTING_DOWN")
+				assert.Equal(t, "1", w.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+const (
+	// EnvelopeHeader is the header clients use to ask for enveloped
+	// responses, or for bare ones when the server envelopes by default
+	EnvelopeHeader = "X-Response-Envelope"
+
+	// RequestIDHeader carries the ID of a request, sent by the client or
+	// generated for enveloped responses
+	RequestIDHeader = "X-Request-ID"
+
+	// nextCursorHeader carries the cursor of the next page of a listing;
+	// see handlers.NextCursorHeader
+	nextCursorHeader = "X-Next-Cursor"
+)
+
+// requestIDPattern matches request IDs clients may send; others are
+// replaced with a generated ID
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// bufferedWriter holds a response back until it is enveloped
+type bufferedWriter struct {
+	gin.ResponseWriter
+	c      *gin.Context
+	status int
+	body   bytes.Buffer
+	sent   bool
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if !w.sent {
+		w.status = code
+	}
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	if w.sent {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	if w.sent {
+		return w.ResponseWriter.WriteString(s)
+	}
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	if w.sent {
+		return w.ResponseWriter.Size()
+	}
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.sent || w.body.Len() > 0
+}
+
+// Flush sends what the handler wrote so far as the whole response, so a
+// middleware such as Timeout can answer before the handler returns. Later
+// writes go straight to the client.
+func (w *bufferedWriter) Flush() {
+	w.send()
+	w.ResponseWriter.Flush()
+}
+
+// send envelopes the response held back and writes it to the client, once
+func (w *bufferedWriter) send() {
+	if w.sent {
+		return
+	}
+	w.sent = true
+	original := w.ResponseWriter
+
+	body := w.body.Bytes()
+	if len(body) == 0 || !strings.HasPrefix(original.Header().Get("Content-Type"), "application/json") {
+		original.WriteHeader(w.status)
+		original.Write(body)
+		return
+	}
+
+	requestID := w.c.GetHeader(RequestIDHeader)
+	if !requestIDPattern.MatchString(requestID) {
+		requestID = uuid.New().String()
+	}
+	envelope := models.Envelope{Meta: models.EnvelopeMeta{RequestID: requestID, Warnings: Warnings(w.c)}}
+	var errResp models.ErrorResponse
+	if w.status >= http.StatusBadRequest && json.Unmarshal(body, &errResp) == nil && errResp.Code != "" {
+		envelope.Data = json.RawMessage("null")
+		envelope.Errors = []models.ErrorResponse{errResp}
+	} else {
+		envelope.Data = bytes.TrimSpace(body)
+		envelope.Meta.Pagination = pagination(w.c, original.Header())
+	}
+
+	wrapped, err := json.Marshal(envelope)
+	if err != nil {
+		// The handler wrote something other than JSON; serve it as it is
+		original.WriteHeader(w.status)
+		original.Write(body)
+		return
+	}
+	original.Header().Del("Content-Length")
+	original.Header().Set(RequestIDHeader, requestID)
+	original.WriteHeader(w.status)
+	original.Write(append(wrapped, '\n'))
+}
+
+// Envelope returns a middleware that wraps JSON responses in a
+// models.Envelope for clients that ask for it with the X-Response-Envelope
+// header, or for every client that does not opt out when byDefault is set.
+// Responses that are not JSON, such as images and backups, are served as
+// they are.
+func Envelope(byDefault bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", EnvelopeHeader)
+		enveloped := byDefault
+		if raw := c.GetHeader(EnvelopeHeader); raw != "" {
+			if want, err := strconv.ParseBool(raw); err == nil {
+				enveloped = want
+			}
+		}
+		if !enveloped {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		buffered := &bufferedWriter{ResponseWriter: original, c: c, status: http.StatusOK}
+		c.Writer = buffered
+		// A panic leaves the response to the recovery middleware, which
+		// writes straight to the client
+		defer func() { c.Writer = original }()
+		c.Next()
+		buffered.send()
+	}
+}
+
+// pagination describes the page a listing served, given the headers of its
+// response, or nil for responses that are not paged
+func pagination(c *gin.Context, header http.Header) *models.Pagination {
+	p := &models.Pagination{NextCursor: header.Get(nextCursorHeader)}
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil {
+		p.Limit = limit
+	}
+	if p.Limit == 0 && p.NextCursor == "" && c.Query("cursor") == "" {
+		return nil
+	}
+	return p
+}
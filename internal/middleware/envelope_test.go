@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvelope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newEngine := func(byDefault bool) *gin.Engine {
+		engine := gin.New()
+		engine.Use(Envelope(byDefault))
+		engine.GET("/items", func(c *gin.Context) {
+			c.Header(nextCursorHeader, "next-page")
+			c.JSON(http.StatusOK, []string{"a", "b"})
+		})
+		engine.GET("/missing", func(c *gin.Context) {
+			c.JSON(apierrors.Status(apierrors.NotFound), apierrors.New(apierrors.NotFound, "Not found"))
+		})
+		engine.GET("/image", func(c *gin.Context) {
+			c.Data(http.StatusOK, "image/png", []byte("png"))
+		})
+		return engine
+	}
+	serve := func(engine *gin.Engine, path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("bare unless asked for", func(t *testing.T) {
+		rec := serve(newEngine(false), "/items", nil)
+		assert.JSONEq(t, `["a","b"]`, rec.Body.String())
+		assert.Contains(t, rec.Header().Values("Vary"), EnvelopeHeader)
+	})
+
+	t.Run("enveloped on request", func(t *testing.T) {
+		rec := serve(newEngine(false), "/items?limit=2", map[string]string{EnvelopeHeader: "true", RequestIDHeader: "req-42"})
+		require.Equal(t, http.StatusOK, rec.Code)
+		var env models.Envelope
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &env))
+		assert.JSONEq(t, `["a","b"]`, string(env.Data))
+		assert.Equal(t, "req-42", env.Meta.RequestID)
+		assert.Equal(t, &models.Pagination{Limit: 2, NextCursor: "next-page"}, env.Meta.Pagination)
+		assert.Empty(t, env.Errors)
+		assert.Equal(t, "req-42", rec.Header().Get(RequestIDHeader))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		rec := serve(newEngine(true), "/missing", map[string]string{RequestIDHeader: "not a valid id"})
+		require.Equal(t, http.StatusNotFound, rec.Code)
+		var env models.Envelope
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &env))
+		assert.Equal(t, "null", string(env.Data))
+		require.Len(t, env.Errors, 1)
+		assert.Equal(t, apierrors.NotFound, env.Errors[0].Code)
+		assert.NotEqual(t, "not a valid id", env.Meta.RequestID, "invalid IDs are replaced")
+		assert.NotEmpty(t, env.Meta.RequestID)
+		assert.Nil(t, env.Meta.Pagination)
+	})
+
+	t.Run("opt out of the default", func(t *testing.T) {
+		rec := serve(newEngine(true), "/items", map[string]string{EnvelopeHeader: "false"})
+		assert.JSONEq(t, `["a","b"]`, rec.Body.String())
+	})
+
+	t.Run("other content as is", func(t *testing.T) {
+		rec := serve(newEngine(true), "/image", nil)
+		assert.Equal(t, "png", rec.Body.String())
+		assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+	})
+}
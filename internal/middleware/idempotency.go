@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/idempotency"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
+	"github.com/ravibandhu/oolio-food-ordering/internal/tenant"
+)
+
+const (
+	// IdempotencyKeyHeader carries the key clients retry a request with
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// IdempotentReplayedHeader is set on responses replayed for a retry
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	// maxIdempotencyKey is the longest key accepted
+	maxIdempotencyKey = 255
+)
+
+// recordingWriter passes a response through while keeping a copy of it
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency returns a middleware that answers a retried request, sent
+// with the Idempotency-Key of a request that already succeeded, with the
+// original response rather than handling it again. Keys belong to the
+// tenant and the caller, by API key, Authorization header or IP address.
+// A retry while the first request is in progress is refused with 409, and
+// a key reused for a different request with 422. Failed requests are not
+// remembered, so they can be retried. Requests without a key are handled
+// as usual. Instances sharing store answer each other's retries.
+func Idempotency(store idempotency.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKey {
+			respond.Abort(c, apierrors.New(apierrors.InvalidRequest, "Idempotency-Key is too long").
+				AddDetail("maxLength", maxIdempotencyKey))
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			respond.Abort(c, DecodeError(err))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		key = idempotencyScope(c, key)
+		response, err := store.Begin(key, requestFingerprint(c.Request, body))
+		switch {
+		case errors.Is(err, idempotency.ErrInProgress):
+			respond.Abort(c, apierrors.New(apierrors.RequestInProgress, "A request with this Idempotency-Key is still being handled"))
+			return
+		case errors.Is(err, idempotency.ErrMismatch):
+			respond.Abort(c, apierrors.New(apierrors.IdempotencyKeyReused, "Idempotency-Key was already used for a different request"))
+			return
+		case response != nil:
+			c.Abort()
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(response.Status, response.ContentType, response.Body)
+			return
+		}
+
+		// Release the key unless the response is remembered, including when
+		// the handler panics, so the request can be retried
+		stored := false
+		defer func() {
+			if !stored {
+				store.Release(key)
+			}
+		}()
+
+		recorder := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+		c.Writer = recorder.ResponseWriter
+
+		status := recorder.Status()
+		if status < http.StatusOK || status >= http.StatusMultipleChoices {
+			return
+		}
+		store.Finish(key, idempotency.Response{
+			Status:      status,
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		})
+		stored = true
+	}
+}
+
+// idempotencyScope returns key qualified by the tenant and caller of c,
+// hashed so no credential is kept
+func idempotencyScope(c *gin.Context, key string) string {
+	caller := APIKey(c)
+	if caller == "" {
+		caller = c.GetHeader("Authorization")
+	}
+	if caller == "" {
+		caller = c.ClientIP()
+	}
+	tenantID := ""
+	if t, ok := tenant.FromContext(c.Request.Context()); ok {
+		tenantID = t.ID
+	}
+	sum := sha256.Sum256([]byte(tenantID + "\x00" + caller + "\x00" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+// requestFingerprint identifies a request by its URL, dry-run header and
+// body, so a key cannot be reused for another request
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\x00"+r.Header.Get("X-Dry-Run")+"\x00")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/idempotency"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var handled atomic.Int32
+	started, release := make(chan struct{}), make(chan struct{})
+	engine := gin.New()
+	engine.Use(Idempotency(idempotency.New(time.Hour)))
+	engine.POST("/orders", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		if string(body) == "slow" {
+			started <- struct{}{}
+			<-release
+		}
+		c.String(http.StatusCreated, "order %d", handled.Add(1))
+	})
+
+	send := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// The handler sees the body, and retries see its response
+	rec := send("key-1", "fast")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "order 1", rec.Body.String())
+	rec = send("key-1", "fast")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "order 1", rec.Body.String())
+	assert.Equal(t, "true", rec.Header().Get(IdempotentReplayedHeader))
+
+	// Requests without a key are always handled
+	assert.Equal(t, "order 2", send("", "fast").Body.String())
+	assert.Equal(t, "order 3", send("", "fast").Body.String())
+
+	// A retry while the request is handled is refused
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- send("key-2", "slow") }()
+	<-started
+	assert.Equal(t, http.StatusConflict, send("key-2", "slow").Code)
+	close(release)
+	assert.Equal(t, http.StatusCreated, (<-done).Code)
+
+	rec = send(strings.Repeat("k", maxIdempotencyKey+1), "fast")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestIdempotency_SharedStore(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Two instances behind a load balancer share one store
+	store := idempotency.New(time.Hour)
+	var handled atomic.Int32
+	instance := func() *gin.Engine {
+		engine := gin.New()
+		engine.Use(Idempotency(store))
+		engine.POST("/orders", func(c *gin.Context) {
+			c.String(http.StatusCreated, "order %d", handled.Add(1))
+		})
+		return engine
+	}
+	first, second := instance(), instance()
+
+	send := func(engine *gin.Engine) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader("order"))
+		req.Header.Set(IdempotencyKeyHeader, "key-1")
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// A retry reaching the other instance is answered with the original
+	assert.Equal(t, "order 1", send(first).Body.String())
+	rec := send(second)
+	assert.Equal(t, "order 1", rec.Body.String())
+	assert.Equal(t, "true", rec.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, int32(1), handled.Load())
+}
+
+func TestIdempotency_Panic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var handled atomic.Int32
+	engine := gin.New()
+	engine.Use(gin.Recovery(), Idempotency(idempotency.New(time.Hour)))
+	engine.POST("/orders", func(c *gin.Context) {
+		if handled.Add(1) == 1 {
+			panic("order failed")
+		}
+		c.String(http.StatusCreated, "order placed")
+	})
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader("order"))
+		req.Header.Set(IdempotencyKeyHeader, "key-1")
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// The key of a request whose handler panicked is released for a retry
+	assert.Equal(t, http.StatusInternalServerError, send().Code)
+	rec := send()
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "order placed", rec.Body.String())
+	assert.Equal(t, int32(2), handled.Load())
+}
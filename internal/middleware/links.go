@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+// linksKey is the context key recording whether responses carry links
+type linksKey struct{}
+
+// Links returns a middleware recording whether responses should carry
+// links to related resources, for handlers building them; see WantLinks.
+func Links(enabled bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), linksKey{}, enabled))
+		c.Next()
+	}
+}
+
+// WantLinks reports whether responses should carry links to related
+// resources. It is false unless set by Links.
+func WantLinks(ctx context.Context) bool {
+	enabled, _ := ctx.Value(linksKey{}).(bool)
+	return enabled
+}
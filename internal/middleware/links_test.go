@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	for _, enabled := range []bool{true, false} {
+		var want bool
+		engine := gin.New()
+		engine.GET("/", Links(enabled), func(c *gin.Context) {
+			want = WantLinks(c.Request.Context())
+			c.Status(http.StatusNoContent)
+		})
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, enabled, want)
+	}
+
+	assert.False(t, WantLinks(context.Background()), "no links unless enabled")
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
+	"github.com/ravibandhu/oolio-food-ordering/internal/tenant"
+)
+
+// Maintenance returns a middleware that refuses requests to the route
+// group called group with 503 MAINTENANCE while the request's tenant has
+// the group down for maintenance, telling the caller the tenant's message
+// and, in Retry-After, when to try again. Requests not routed through the
+// Tenant middleware pass through.
+func Maintenance(group string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		t, ok := tenant.FromContext(c.Request.Context())
+		if !ok {
+			c.Next()
+			return
+		}
+		w, active := t.Maintenance.Active(group)
+		if !active {
+			c.Next()
+			return
+		}
+
+		retryAfter := w.RetryAfter(time.Now())
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		errResp := apierrors.New(apierrors.Maintenance, w.Message).AddDetail("group", group)
+		if w.Until != nil {
+			errResp.AddDetail("until", w.Until)
+		}
+		respond.Abort(c, errResp)
+	}
+}
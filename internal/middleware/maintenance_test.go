@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/maintenance"
+	"github.com/ravibandhu/oolio-food-ordering/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenance(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	restaurant := &tenant.Tenant{ID: "harbour", Maintenance: maintenance.New()}
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) {
+		if c.Query("tenant") != "none" {
+			c.Request = c.Request.WithContext(tenant.NewContext(c.Request.Context(), restaurant))
+		}
+	})
+	ok := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	engine.POST("/orders", Maintenance("ordering"), ok)
+	engine.GET("/products", Maintenance("catalog"), ok)
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusNoContent, serve(http.MethodPost, "/orders").Code)
+
+	_, err := restaurant.Maintenance.Start("ordering", maintenance.Request{Message: "Back at noon"})
+	require.NoError(t, err)
+
+	rec := serve(http.MethodPost, "/orders")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, strconv.Itoa(int(maintenance.DefaultRetryAfter.Seconds())), rec.Header().Get("Retry-After"))
+	assert.Contains(t, rec.Body.String(), "MAINTENANCE")
+	assert.Contains(t, rec.Body.String(), "Back at noon")
+
+	// Other groups stay available
+	assert.Equal(t, http.StatusNoContent, serve(http.MethodGet, "/products").Code)
+
+	// Requests without a tenant are not refused
+	assert.Equal(t, http.StatusNoContent, serve(http.MethodPost, "/orders?tenant=none").Code)
+
+	// Windows with an end ask callers to retry once they end
+	until := time.Now().Add(90 * time.Second)
+	_, err = restaurant.Maintenance.Start("catalog", maintenance.Request{Until: &until})
+	require.NoError(t, err)
+	rec = serve(http.MethodGet, "/products")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	retryAfter, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	assert.InDelta(t, 90, retryAfter, 2)
+	assert.Contains(t, rec.Body.String(), "until")
+}
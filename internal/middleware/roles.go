@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apikeys"
+	"github.com/ravibandhu/oolio-food-ordering/internal/auth"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
+)
+
+// RequireRole returns a middleware that only lets requests through from
+// callers holding one of roles. Callers present an active API key, in the
+// X-API-Key header or as the password of HTTP Basic credentials, whose
+// scopes are its roles, or a staff member's session token as
+// "Authorization: Bearer <token>". Admins hold every role. Unauthenticated
+// requests get 401 and callers without the role 403, as do writes sending
+// Basic credentials without RequestedWithHeader. The roles of the caller
+// are stored in the request context, so later RequireRole checks on the
+// same request do not authenticate it again.
+func RequireRole(keys *apikeys.Store, accounts *auth.Accounts, roles ...string) gin.HandlerFunc {
+	return requireRole("", keys, accounts, roles)
+}
+
+// RequireRoleIf behaves like RequireRole for requests cond matches and lets
+// every other request through, like APIKeyAuthIf
+func RequireRoleIf(cond func(c *gin.Context) bool, keys *apikeys.Store, accounts *auth.Accounts, roles ...string) gin.HandlerFunc {
+	require := RequireRole(keys, accounts, roles...)
+	return func(c *gin.Context) {
+		if cond(c) {
+			require(c)
+			return
+		}
+		c.Next()
+	}
+}
+
+// BrowserRequireRole behaves like RequireRole but challenges unauthenticated
+// requests so browsers prompt for an API key, like BrowserAPIKeyAuth
+func BrowserRequireRole(realm string, keys *apikeys.Store, accounts *auth.Accounts, roles ...string) gin.HandlerFunc {
+	return requireRole(realm, keys, accounts, roles)
+}
+
+// requireRole implements RequireRole, challenging browsers for Basic
+// credentials unless realm is empty
+func requireRole(realm string, keys *apikeys.Store, accounts *auth.Accounts, roles []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		held, ok := auth.RolesFromContext(c.Request.Context())
+		if !ok {
+			if held, ok = authenticateCaller(c, realm, keys, accounts); !ok {
+				return
+			}
+			c.Request = c.Request.WithContext(auth.WithRoles(c.Request.Context(), held))
+		}
+
+		if !auth.HasRole(held, roles...) {
+			respond.Abort(c, apierrors.New(apierrors.Forbidden, "Caller lacks the role this route requires").
+				AddDetail("roles", roles))
+			return
+		}
+		c.Next()
+	}
+}
+
+// authenticateCaller returns the roles of the API key or session token a
+// request presents, or aborts it with 401. The key or the session claims
+// are stored in the request context.
+func authenticateCaller(c *gin.Context, realm string, keys *apikeys.Store, accounts *auth.Accounts) ([]string, bool) {
+	if token, ok := bearerToken(c); ok {
+		_, claims, err := accounts.Authenticate(token)
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			respond.Abort(c, apierrors.New(apierrors.InvalidToken, "Invalid or expired session token"))
+			return nil, false
+		}
+		ctx := auth.WithSession(c.Request.Context(), claims)
+		c.Request = c.Request.WithContext(ctx)
+		return claims.Roles, true
+	}
+
+	if refuseBasicWrite(c) {
+		return nil, false
+	}
+	key, ok := keys.Authenticate(APIKey(c))
+	if !ok {
+		if realm != "" {
+			c.Header("WWW-Authenticate", `Basic realm="`+realm+`", charset="UTF-8"`)
+		}
+		respond.Abort(c, apierrors.New(apierrors.Unauthorized, "Missing, invalid, expired or disabled API key"))
+		return nil, false
+	}
+	c.Request = c.Request.WithContext(apikeys.WithKey(c.Request.Context(), key))
+	return key.Scopes, true
+}
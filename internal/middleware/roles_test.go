@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apikeys"
+	"github.com/ravibandhu/oolio-food-ordering/internal/auth"
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	accounts, err := auth.Load(config.Auth{SessionTTL: time.Hour, RefreshTTL: time.Hour}, nil)
+	require.NoError(t, err)
+	customer, err := accounts.Customers.SignIn(&auth.Profile{Identity: auth.Identity{Provider: auth.ProviderGoogle, Subject: "g-1"}})
+	require.NoError(t, err)
+	customerSession, err := accounts.Sessions.Issue(customer)
+	require.NoError(t, err)
+	cook, err := accounts.Customers.SignIn(&auth.Profile{Identity: auth.Identity{Provider: auth.ProviderGoogle, Subject: "g-2"}})
+	require.NoError(t, err)
+	cook, err = accounts.SetRoles(cook.ID, []string{auth.RoleKitchen})
+	require.NoError(t, err)
+	cookSession, err := accounts.Sessions.Issue(cook)
+	require.NoError(t, err)
+
+	keys := loadKeys(t, config.Auth{
+		APIKeys:     []string{"admin-key"},
+		KitchenKeys: []string{"kitchen-key", "retired-key"},
+		SupportKeys: []string{"support-key"},
+	})
+	_, err = keys.Disable(apikeys.ConfiguredID("retired-key"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		apiKey         string
+		authorization  string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{name: "kitchen key", apiKey: "kitchen-key", expectedStatus: http.StatusOK},
+		{name: "admin key", apiKey: "admin-key", expectedStatus: http.StatusOK},
+		{name: "support key", apiKey: "support-key", expectedStatus: http.StatusForbidden, expectedCode: "FORBIDDEN"},
+		{name: "unknown key", apiKey: "wrong", expectedStatus: http.StatusUnauthorized, expectedCode: "UNAUTHORIZED"},
+		{name: "disabled key", apiKey: "retired-key", expectedStatus: http.StatusUnauthorized, expectedCode: "UNAUTHORIZED"},
+		{name: "missing", expectedStatus: http.StatusUnauthorized, expectedCode: "UNAUTHORIZED"},
+		{name: "kitchen staff session", authorization: "Bearer " + cookSession.AccessToken, expectedStatus: http.StatusOK},
+		{name: "customer session", authorization: "Bearer " + customerSession.AccessToken, expectedStatus: http.StatusForbidden, expectedCode: "FORBIDDEN"},
+		{name: "invalid token", authorization: "Bearer not-a-token", expectedStatus: http.StatusUnauthorized, expectedCode: "INVALID_TOKEN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var held []string
+			engine := gin.New()
+			engine.GET("/admin/kitchen/orders", RequireRole(keys, accounts, auth.RoleKitchen), func(c *gin.Context) {
+				held, _ = auth.RolesFromContext(c.Request.Context())
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/kitchen/orders", nil)
+			if tt.apiKey != "" {
+				req.Header.Set(APIKeyHeader, tt.apiKey)
+			}
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code, "body: %s", rec.Body)
+			if tt.expectedCode == "" {
+				assert.NotEmpty(t, held)
+				return
+			}
+			var errResp models.ErrorResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&errResp))
+			assert.Equal(t, tt.expectedCode, errResp.Code)
+		})
+	}
+}
+
+func TestRequireRole_Chained(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	accounts, err := auth.Load(config.Auth{SessionTTL: time.Hour, RefreshTTL: time.Hour}, nil)
+	require.NoError(t, err)
+	keys := loadKeys(t, config.Auth{KitchenKeys: []string{"kitchen-key"}, SupportKeys: []string{"support-key"}})
+
+	engine := gin.New()
+	staff := engine.Group("/admin", BrowserRequireRole("oolio-admin", keys, accounts, auth.RoleKitchen, auth.RoleSupport))
+	staff.GET("/reviews", RequireRole(keys, accounts, auth.RoleSupport), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	staff.POST("/reviews", RequireRole(keys, accounts, auth.RoleSupport), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	// Browsers are challenged for credentials and may send the key as a password
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/reviews", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, `Basic realm="oolio-admin", charset="UTF-8"`, rec.Header().Get("WWW-Authenticate"))
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/reviews", nil)
+	req.SetBasicAuth("", "support-key")
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Staff reach the group, but only the route's role gets through it
+	req = httptest.NewRequest(http.MethodGet, "/admin/reviews", nil)
+	req.Header.Set(APIKeyHeader, "kitchen-key")
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// Writes with Basic credentials must show they came from the API's own
+	// pages, as browsers send cached credentials with forged requests too
+	req = httptest.NewRequest(http.MethodPost, "/admin/reviews", nil)
+	req.SetBasicAuth("", "support-key")
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), RequestedWithHeader)
+	assert.Empty(t, rec.Header().Get("WWW-Authenticate"))
+
+	req = httptest.NewRequest(http.MethodPost, "/admin/reviews", nil)
+	req.SetBasicAuth("", "support-key")
+	req.Header.Set(RequestedWithHeader, "XMLHttpRequest")
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/admin/reviews", nil)
+	req.Header.Set(APIKeyHeader, "support-key")
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestRequireRoleIf(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	accounts, err := auth.Load(config.Auth{SessionTTL: time.Hour, RefreshTTL: time.Hour}, nil)
+	require.NoError(t, err)
+	keys := loadKeys(t, config.Auth{APIKeys: []string{"admin-key"}, KitchenKeys: []string{"kitchen-key"}})
+	deleted := func(c *gin.Context) bool { return c.Query("include_deleted") == "true" }
+
+	engine := gin.New()
+	engine.GET("/products", RequireRoleIf(deleted, keys, accounts, auth.RoleAdmin), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name           string
+		path           string
+		apiKey         string
+		expectedStatus int
+	}{
+		{name: "public listing", path: "/products", expectedStatus: http.StatusOK},
+		{name: "deleted without key", path: "/products?include_deleted=true", expectedStatus: http.StatusUnauthorized},
+		{name: "deleted with kitchen key", path: "/products?include_deleted=true", apiKey: "kitchen-key", expectedStatus: http.StatusForbidden},
+		{name: "deleted with admin key", path: "/products?include_deleted=true", apiKey: "admin-key", expectedStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.apiKey != "" {
+				req.Header.Set(APIKeyHeader, tt.apiKey)
+			}
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, req)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
+
+// loadKeys returns an in-memory key store holding the keys configured in cfg
+func loadKeys(t *testing.T, cfg config.Auth) *apikeys.Store {
+	t.Helper()
+	keys, err := apikeys.Load(cfg)
+	require.NoError(t, err)
+	return keys
+}
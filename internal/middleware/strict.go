@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
+)
+
+// RequireJSON returns a middleware that rejects POST, PUT and PATCH requests
+// whose body is not declared as application/json with 415 Unsupported Media
+// Type. Requests without a body pass through.
+func RequireJSON() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+		default:
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength == 0 {
+			c.Next()
+			return
+		}
+
+		contentType := c.GetHeader("Content-Type")
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || mediaType != "application/json" {
+			respond.Abort(c, apierrors.New(apierrors.UnsupportedMediaType, "Request body must be application/json").
+				AddDetail("contentType", contentType))
+			return
+		}
+		c.Next()
+	}
+}
+
+// MethodNotAllowed answers requests for a known path with an unsupported
+// method. It is meant for gin.Engine.NoMethod, which sets the Allow header
+// to the methods the path supports before calling it.
+func MethodNotAllowed(c *gin.Context) {
+	allow := c.Writer.Header().Get("Allow")
+	respond.Abort(c, apierrors.New(apierrors.MethodNotAllowed, "Method not allowed").
+		AddDetail("method", c.Request.Method).
+		AddDetail("allow", allow))
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	engine.Use(RequireJSON())
+	engine.GET("/", ok)
+	engine.POST("/", ok)
+	engine.PUT("/", ok)
+
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		contentType    string
+		expectedStatus int
+	}{
+		{name: "json", method: http.MethodPost, body: "{}", contentType: "application/json", expectedStatus: http.StatusNoContent},
+		{name: "json with charset", method: http.MethodPut, body: "{}", contentType: "application/json; charset=utf-8", expectedStatus: http.StatusNoContent},
+		{name: "no body", method: http.MethodPost, expectedStatus: http.StatusNoContent},
+		{name: "get ignores content type", method: http.MethodGet, body: "{}", contentType: "text/plain", expectedStatus: http.StatusNoContent},
+		{name: "missing content type", method: http.MethodPost, body: "{}", expectedStatus: http.StatusUnsupportedMediaType},
+		{name: "form", method: http.MethodPost, body: "a=1", contentType: "application/x-www-form-urlencoded", expectedStatus: http.StatusUnsupportedMediaType},
+		{name: "malformed content type", method: http.MethodPut, body: "{}", contentType: "application/json;;", expectedStatus: http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus == http.StatusUnsupportedMediaType {
+				assert.Contains(t, rec.Body.String(), "UNSUPPORTED_MEDIA_TYPE")
+			}
+		})
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	engine.HandleMethodNotAllowed = true
+	engine.NoMethod(MethodNotAllowed)
+	engine.GET("/orders/:id", func(c *gin.Context) {})
+	engine.POST("/orders/:id", func(c *gin.Context) {})
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/orders/1", nil))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, "GET, POST", rec.Header().Get("Allow"))
+	assert.Contains(t, rec.Body.String(), "METHOD_NOT_ALLOWED")
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/respond"
+	"github.com/ravibandhu/oolio-food-ordering/internal/tenant"
+)
+
+// TenantHeader is the header clients use to select a tenant explicitly
+const TenantHeader = "X-Tenant-ID"
+
+// Tenant returns a middleware that resolves the tenant of each request and
+// stores it in the request context. The X-Tenant-ID header takes precedence
+// over the Host header; requests matching neither go to the default tenant.
+func Tenant(registry *tenant.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		t := registry.ForHost(c.Request.Host)
+		if id := c.GetHeader(TenantHeader); id != "" {
+			var ok bool
+			if t, ok = registry.Get(id); !ok {
+				respond.Abort(c, apierrors.New(apierrors.TenantNotFound, "Unknown tenant").AddDetail("tenant", id))
+				return
+			}
+		}
+
+		c.Header(TenantHeader, t.ID)
+		c.Request = c.Request.WithContext(tenant.NewContext(c.Request.Context(), t))
+		c.Next()
+	}
+}
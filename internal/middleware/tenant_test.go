@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/data"
+	"github.com/ravibandhu/oolio-food-ordering/internal/tenant"
+	"github.com/ravibandhu/oolio-food-ordering/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	defaultData := testutil.SetupTestData(t)
+	defer defaultData.Cleanup()
+	harbourData := testutil.SetupTestData(t)
+	defer harbourData.Cleanup()
+
+	cfg := defaultData.Config
+	cfg.Tenants = []config.Tenant{{
+		ID:    "harbour",
+		Hosts: []string{"harbour.example.com"},
+		Files: harbourData.Config.Files,
+	}}
+	store, err := data.NewIsolatedStore(context.Background(), cfg)
+	require.NoError(t, err)
+	registry, err := tenant.NewRegistry(context.Background(), cfg, store)
+	require.NoError(t, err)
+	defer registry.Close()
+
+	tests := []struct {
+		name           string
+		host           string
+		header         string
+		expectedStatus int
+		expectedTenant string
+	}{
+		{
+			name:           "default tenant",
+			host:           "localhost:8080",
+			expectedStatus: http.StatusOK,
+			expectedTenant: config.DefaultTenantID,
+		},
+		{
+			name:           "tenant from host",
+			host:           "harbour.example.com",
+			expectedStatus: http.StatusOK,
+			expectedTenant: "harbour",
+		},
+		{
+			name:           "header overrides host",
+			host:           "harbour.example.com",
+			header:         config.DefaultTenantID,
+			expectedStatus: http.StatusOK,
+			expectedTenant: config.DefaultTenantID,
+		},
+		{
+			name:           "unknown tenant header",
+			header:         "missing",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(Tenant(registry))
+			r.GET("/", func(c *gin.Context) {
+				resolved, ok := tenant.FromContext(c.Request.Context())
+				require.True(t, ok)
+				c.String(http.StatusOK, resolved.ID)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Host = tt.host
+			if tt.header != "" {
+				req.Header.Set(TenantHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, tt.expectedTenant, rec.Body.String())
+				assert.Equal(t, tt.expectedTenant, rec.Header().Get(TenantHeader))
+			} else {
+				assert.Contains(t, rec.Body.String(), "TENANT_NOT_FOUND")
+			}
+		})
+	}
+}
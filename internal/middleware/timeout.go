@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+)
+
+// timeoutWriter holds a handler's response until it is known whether the
+// handler finished in time. Writes after the timeout are dropped.
+type timeoutWriter struct {
+	gin.ResponseWriter
+	mu       sync.Mutex
+	header   http.Header
+	status   int
+	body     bytes.Buffer
+	timedOut bool
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(data)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *timeoutWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.body.Len()
+}
+
+func (w *timeoutWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status != 0
+}
+
+// Flush does nothing; the response is sent once the handler finishes
+func (w *timeoutWriter) Flush() {}
+
+// Timeout returns a middleware that gives the rest of the chain d to
+// answer, with no limit when d is not positive. The request's context is
+// cancelled at the deadline, so stores and services can stop early, and the
+// client is answered with a TIMEOUT error straight away. Whatever the
+// handler writes afterwards is dropped. The middleware still waits for the
+// handler to return, as gin reuses the context once the request is done.
+func Timeout(d time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if d <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		original := c.Writer
+		buffered := &timeoutWriter{ResponseWriter: original, header: original.Header().Clone()}
+		c.Writer = buffered
+		defer func() { c.Writer = original }()
+
+		// A panic is carried back and raised again here, where the recovery
+		// middleware can catch it
+		done := make(chan any, 1)
+		go func() {
+			defer func() { done <- recover() }()
+			c.Next()
+		}()
+
+		select {
+		case p := <-done:
+			if p != nil {
+				panic(p)
+			}
+			buffered.mu.Lock()
+			defer buffered.mu.Unlock()
+			header := original.Header()
+			clear(header)
+			for k, v := range buffered.header {
+				header[k] = v
+			}
+			if buffered.status != 0 {
+				original.WriteHeader(buffered.status)
+			}
+			original.Write(buffered.body.Bytes())
+
+		case <-ctx.Done():
+			buffered.mu.Lock()
+			buffered.timedOut = true
+			buffered.mu.Unlock()
+
+			// A client that went away needs no answer
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				body, _ := json.Marshal(apierrors.New(apierrors.Timeout, "Request timed out").
+					AddDetail("timeout", d.String()))
+				original.Header().Set("Content-Type", "application/json; charset=utf-8")
+				original.WriteHeader(apierrors.Status(apierrors.Timeout))
+				original.Write(body)
+				original.Flush()
+			}
+			if p := <-done; p != nil {
+				log.Printf("Handler panicked after timing out: %v", p)
+			}
+			c.Abort()
+		}
+	}
+}
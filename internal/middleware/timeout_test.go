@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cancelled := make(chan error, 1)
+	serve := func(d time.Duration, enveloped bool, path string) *httptest.ResponseRecorder {
+		engine := gin.New()
+		engine.Use(gin.Recovery())
+		if enveloped {
+			engine.Use(Envelope(true))
+		}
+		engine.Use(Timeout(d))
+		engine.GET("/fast", func(c *gin.Context) {
+			c.Header("X-Fast", "yes")
+			c.JSON(http.StatusCreated, gin.H{"ok": true})
+		})
+		engine.GET("/slow", func(c *gin.Context) {
+			select {
+			case <-c.Request.Context().Done():
+				cancelled <- c.Request.Context().Err()
+			case <-time.After(time.Second):
+				cancelled <- nil
+			}
+			c.JSON(http.StatusOK, gin.H{"ok": true})
+		})
+		engine.GET("/panic", func(c *gin.Context) { panic("boom") })
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	t.Run("fast handlers are served as they answer", func(t *testing.T) {
+		rec := serve(time.Second, false, "/fast")
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "yes", rec.Header().Get("X-Fast"))
+		assert.JSONEq(t, `{"ok":true}`, rec.Body.String())
+	})
+
+	t.Run("slow handlers time out", func(t *testing.T) {
+		rec := serve(20*time.Millisecond, false, "/slow")
+		require.Equal(t, http.StatusGatewayTimeout, rec.Code)
+		var resp models.ErrorResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, apierrors.Timeout, resp.Code)
+		assert.Equal(t, "20ms", resp.Details["timeout"])
+		assert.ErrorIs(t, <-cancelled, context.DeadlineExceeded)
+	})
+
+	t.Run("timeouts are enveloped", func(t *testing.T) {
+		rec := serve(20*time.Millisecond, true, "/slow")
+		require.Equal(t, http.StatusGatewayTimeout, rec.Code)
+		<-cancelled
+		var env models.Envelope
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &env))
+		require.Len(t, env.Errors, 1)
+		assert.Equal(t, apierrors.Timeout, env.Errors[0].Code)
+	})
+
+	t.Run("no limit when disabled", func(t *testing.T) {
+		rec := serve(0, false, "/fast")
+		assert.Equal(t, http.StatusCreated, rec.Code)
+	})
+
+	t.Run("panics reach the recovery middleware", func(t *testing.T) {
+		rec := serve(time.Second, false, "/panic")
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
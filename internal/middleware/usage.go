@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apikeys"
+	"github.com/ravibandhu/oolio-food-ordering/internal/usage"
+)
+
+// Usage returns a middleware that records every request made with one of
+// the keys in keys in tracker once it was handled, along with the status it
+// got. Requests made with expired or disabled keys are recorded too, as the
+// errors they are. Requests placesOrder matches count as orders when they
+// succeed.
+func Usage(tracker *usage.Tracker, keys *apikeys.Store, placesOrder func(c *gin.Context) bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		key, ok := keys.Lookup(APIKey(c))
+		if !ok {
+			return
+		}
+		status := c.Writer.Status()
+		succeeded := status >= http.StatusOK && status < http.StatusMultipleChoices
+		tracker.Record(key.ID, status, succeeded && placesOrder(c))
+	}
+}
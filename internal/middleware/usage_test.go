@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apikeys"
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/usage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tracker, err := usage.Load("")
+	require.NoError(t, err)
+	keys := loadKeys(t, config.Auth{APIKeys: []string{"admin-key"}, KitchenKeys: []string{"kitchen-key"}})
+	partner, err := keys.Create(apikeys.KeyRequest{Name: "Partner", Scopes: []string{"kitchen"}})
+	require.NoError(t, err)
+	placesOrder := func(c *gin.Context) bool { return c.FullPath() == "/orders" }
+
+	engine := gin.New()
+	engine.Use(Usage(tracker, keys, placesOrder))
+	engine.POST("/orders", func(c *gin.Context) {
+		if c.Query("fail") != "" {
+			c.Status(http.StatusUnprocessableEntity)
+			return
+		}
+		c.Status(http.StatusCreated)
+	})
+	engine.GET("/products", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	send := func(method, path, key string) {
+		req := httptest.NewRequest(method, path, nil)
+		if key != "" {
+			req.Header.Set(APIKeyHeader, key)
+		}
+		engine.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	send(http.MethodPost, "/orders", "admin-key")
+	send(http.MethodPost, "/orders?fail=1", "admin-key")
+	send(http.MethodGet, "/products", "admin-key")
+	send(http.MethodGet, "/missing", "admin-key")
+	send(http.MethodGet, "/products", "kitchen-key")
+	send(http.MethodGet, "/products", "unknown-key")
+	send(http.MethodGet, "/products", "")
+
+	// Rotating a key keeps counting its requests under the same ID
+	send(http.MethodPost, "/orders", partner.Secret)
+	rotated, err := keys.Rotate(partner.ID)
+	require.NoError(t, err)
+	send(http.MethodPost, "/orders", rotated.Secret)
+
+	report, err := tracker.Report(apikeys.ConfiguredID("admin-key"), time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, usage.Counts{Requests: 4, ClientErrors: 2, Orders: 1}, report.Total)
+
+	report, err = tracker.Report(apikeys.ConfiguredID("kitchen-key"), time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), report.Total.Requests)
+
+	report, err = tracker.Report(partner.ID, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, usage.Counts{Requests: 2, Orders: 2}, report.Total)
+
+	// Unknown keys are not counted
+	_, err = tracker.Report(apikeys.ConfiguredID("unknown-key"), time.Time{}, time.Time{})
+	assert.ErrorIs(t, err, usage.ErrNotFound)
+}
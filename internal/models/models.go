@@ -1,7 +1,12 @@
 package models
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -23,6 +28,10 @@ type Product struct {
 	// @example Waffle with Berries
 	Name string `json:"name" validate:"required"`
 
+	// A short description of the product
+	// @example Belgian waffle topped with fresh berries
+	Description string `json:"description,omitempty"`
+
 	// The price of the product in the default currency
 	// @required
 	// @minimum 0.01
@@ -38,6 +47,38 @@ type Product struct {
 	// @required
 	Image *ProductImage `json:"image" validate:"required"`
 
+	// Stock keeping unit of a product without variants, as the restaurant's
+	// POS knows it
+	// @example WAF-BER
+	SKU string `json:"sku,omitempty" validate:"omitempty,max=64"`
+
+	// Sizes or other options the product is sold in, each with its own
+	// price. Products with variants must be ordered by variant.
+	Variants []ProductVariant `json:"variants,omitempty" validate:"omitempty,unique=ID,unique=SKU,dive"`
+
+	// Allergens the product contains
+	// @example ["milk","eggs"]
+	Allergens []string `json:"allergens,omitempty" validate:"omitempty,dive,allergen"`
+
+	// Dietary requirements the product meets
+	// @example ["vegetarian"]
+	Dietary []string `json:"dietary,omitempty" validate:"omitempty,dive,dietary"`
+
+	// Energy per serving in kilocalories
+	// @minimum 0
+	// @example 450
+	Calories *int `json:"calories,omitempty" validate:"omitempty,gte=0"`
+
+	// Nutrition per serving
+	Nutrition *Nutrition `json:"nutrition,omitempty"`
+
+	// Translations of the name, description and category keyed by
+	// language tag, e.g. "fr" or "pt-br"
+	Translations map[string]ProductTranslation `json:"translations,omitempty" validate:"omitempty,dive"`
+
+	// Aggregate of the product's approved reviews, if it has any
+	Rating *Rating `json:"rating,omitempty" validate:"-"`
+
 	// The timestamp when the product was created
 	// @example 2024-01-01T00:00:00Z
 	CreatedAt time.Time `json:"created_at,omitempty"`
@@ -45,6 +86,193 @@ type Product struct {
 	// The timestamp when the product was last updated
 	// @example 2024-01-01T00:00:00Z
 	UpdatedAt time.Time `json:"updated_at,omitempty"`
+
+	// The timestamp when the product was deleted. Deleted products are kept
+	// so orders that reference them still render, but cannot be ordered.
+	// @example 2024-01-01T00:00:00Z
+	DeletedAt *time.Time `json:"deleted_at,omitempty" validate:"-"`
+
+	// Links to related resources, when the server is configured to serve
+	// them. Links are never stored.
+	Links *Links `json:"_links,omitempty" validate:"-"`
+}
+
+// ProductTranslation holds a product's text in one language. Empty fields
+// fall back to the product's own text.
+type ProductTranslation struct {
+	// The translated name
+	// @example Gaufre aux fruits rouges
+	Name string `json:"name,omitempty"`
+
+	// The translated description
+	// @example Gaufre belge garnie de fruits rouges frais
+	Description string `json:"description,omitempty"`
+
+	// The translated category
+	// @example Gaufre
+	Category string `json:"category,omitempty"`
+}
+
+// ProductVariant is an option a product is sold in, such as a size
+type ProductVariant struct {
+	// The identifier of the variant, unique within the product
+	// @required
+	// @example large
+	ID string `json:"id" validate:"required"`
+
+	// The display name of the variant
+	// @required
+	// @example Large
+	Name string `json:"name" validate:"required"`
+
+	// The price of the variant, used instead of the product price
+	// @required
+	// @minimum 0.01
+	// @example 8.50
+	Price float64 `json:"price" validate:"required,gt=0"`
+
+	// Stock keeping unit, unique within the product
+	// @required
+	// @example WAF-BER-L
+	SKU string `json:"sku" validate:"required"`
+}
+
+// Allergens lists the allergens a product can declare
+var Allergens = []string{
+	"celery", "crustaceans", "eggs", "fish", "gluten", "lupin", "milk",
+	"molluscs", "mustard", "peanuts", "sesame", "soy", "sulphites", "tree-nuts",
+}
+
+// DietaryTags lists the dietary requirements a product can declare
+var DietaryTags = []string{
+	"dairy-free", "gluten-free", "halal", "kosher", "nut-free", "vegan", "vegetarian",
+}
+
+// Nutrition is the nutritional content of one serving
+type Nutrition struct {
+	// Protein in grams
+	// @example 12.5
+	Protein float64 `json:"protein_g" validate:"gte=0"`
+
+	// Carbohydrates in grams
+	// @example 48
+	Carbohydrates float64 `json:"carbohydrates_g" validate:"gte=0"`
+
+	// Sugars in grams
+	// @example 21
+	Sugar float64 `json:"sugar_g" validate:"gte=0"`
+
+	// Fat in grams
+	// @example 17.2
+	Fat float64 `json:"fat_g" validate:"gte=0"`
+
+	// Saturated fat in grams
+	// @example 9.1
+	SaturatedFat float64 `json:"saturated_fat_g" validate:"gte=0"`
+
+	// Fibre in grams
+	// @example 2.4
+	Fibre float64 `json:"fibre_g" validate:"gte=0"`
+
+	// Sodium in milligrams
+	// @example 310
+	Sodium float64 `json:"sodium_mg" validate:"gte=0"`
+}
+
+// Rating summarizes the approved reviews of a product
+type Rating struct {
+	// Mean star rating
+	// @example 4.5
+	Average float64 `json:"average"`
+
+	// Number of approved reviews
+	// @example 12
+	Count int `json:"count"`
+}
+
+// Link is a link to a related resource
+type Link struct {
+	// Path of the resource, relative to the API
+	// @example /products/prod-1
+	Href string `json:"href"`
+}
+
+// Links are the links of a response by relation, so clients can follow
+// them rather than build URLs. Relations that do not apply are left out.
+type Links struct {
+	// The resource itself
+	Self *Link `json:"self,omitempty"`
+
+	// Approved reviews of a product
+	Reviews *Link `json:"reviews,omitempty"`
+
+	// When the kitchen expects an order to be ready
+	ETA *Link `json:"eta,omitempty"`
+
+	// Every status an order has been in
+	Timeline *Link `json:"timeline,omitempty"`
+
+	// The products of an order, in the order of its items
+	Products []Link `json:"product,omitempty"`
+}
+
+// Review statuses. Reviews are pending until moderated.
+const (
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+)
+
+// Review is a customer's rating of a product they ordered
+type Review struct {
+	// The unique identifier of the review
+	// @example review-0000-0000-0000-0000
+	ID string `json:"id"`
+
+	// The reviewed product
+	// @example 1
+	ProductID string `json:"product_id"`
+
+	// The order the product was bought in
+	// @example order-0000-0000-0000-0000
+	OrderID string `json:"order_id"`
+
+	// Star rating from 1 to 5
+	// @example 5
+	Rating int `json:"rating"`
+
+	// Review text
+	// @example Crispy and not too sweet
+	Text string `json:"text,omitempty"`
+
+	// Moderation status: pending, approved or rejected
+	// @example pending
+	Status string `json:"status"`
+
+	// The timestamp when the review was submitted
+	// @example 2024-01-01T00:00:00Z
+	CreatedAt time.Time `json:"created_at"`
+
+	// The timestamp when the review was last moderated
+	// @example 2024-01-01T00:00:00Z
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ReviewRequest represents the request body for reviewing a product
+type ReviewRequest struct {
+	// The order the product was bought in
+	// @required
+	// @example order-0000-0000-0000-0000
+	OrderID string `json:"orderId" validate:"required"`
+
+	// Star rating from 1 to 5
+	// @required
+	// @example 5
+	Rating int `json:"rating" validate:"required,min=1,max=5"`
+
+	// Review text
+	// @example Crispy and not too sweet
+	Text string `json:"text" validate:"max=2000"`
 }
 
 // ProductImage represents different sizes of a product image
@@ -73,17 +301,41 @@ type OrderItem struct {
 	// @example 1
 	ProductID string `json:"productId" validate:"required"`
 
+	// The variant of the product being ordered. Required for products with
+	// variants.
+	// @example large
+	VariantID string `json:"variantId,omitempty"`
+
 	// The quantity of the product ordered
 	// @required
 	// @minimum 1
 	// @example 2
 	Quantity int `json:"quantity" validate:"required,gt=0"`
 
-	// The price of the product at the time of ordering
-	// @required
-	// @minimum 0.01
+	// The unit price charged. Optional in requests; when given, it must
+	// match the current price, or the order fails with PRICE_CHANGED.
+	// @minimum 0
 	// @example 9.99
 	Price float64 `json:"price"`
+
+	// Instructions for the kitchen about this item, up to 140 characters
+	// @example no onions
+	Notes string `json:"notes,omitempty" validate:"omitempty,max=140"`
+}
+
+// RoundingRule is how the amounts of an order were rounded
+type RoundingRule struct {
+	// ISO 4217 code of the currency the order was charged in
+	// @example CHF
+	Currency string `json:"currency"`
+
+	// How ties were broken: half-up, or half-even as in banker's rounding
+	// @example half-up
+	Mode string `json:"mode"`
+
+	// Smallest amount charged
+	// @example 0.05
+	Increment float64 `json:"increment"`
 }
 
 // Order represents a complete order with its items and details
@@ -100,7 +352,30 @@ type Order struct {
 	// @required
 	Products []Product `json:"products" validate:"required"`
 
-	// The total amount of the order after any discounts
+	// The restaurant the order was placed with
+	// @example default
+	TenantID string `json:"tenant_id,omitempty"`
+
+	// The address the order is delivered to, if any
+	DeliveryAddress *Address `json:"delivery_address,omitempty"`
+
+	// The table a dine-in order is served to
+	// @example 12
+	Table string `json:"table,omitempty"`
+
+	// The delivery zone serving the address
+	// @example inner-city
+	DeliveryZone string `json:"delivery_zone,omitempty"`
+
+	// The delivery fee included in the total
+	// @example 4.5
+	DeliveryFee float64 `json:"delivery_fee,omitempty"`
+
+	// Time-based price modifiers in effect when the order was placed, such
+	// as a happy hour discount, included in the total
+	Adjustments []PriceAdjustment `json:"adjustments,omitempty"`
+
+	// The total amount of the order after any discounts, tax and fees
 	// @required
 	// @minimum 0
 	// @example 19.99
@@ -110,6 +385,59 @@ type Order struct {
 	// @example SAVE10
 	CouponCode string `json:"coupon_code,omitempty"`
 
+	// The amount the coupon took off the subtotal, before tax
+	// @example 2
+	Discount float64 `json:"discount,omitempty"`
+
+	// Instructions about the whole order
+	// @example leave at door
+	Notes string `json:"notes,omitempty"`
+
+	// The invoice number issued to the order, from the restaurant's sequence
+	// @example INV-000042
+	InvoiceNumber string `json:"invoice_number,omitempty"`
+
+	// The business the order is invoiced to, if any
+	Billing *BusinessBilling `json:"billing,omitempty"`
+
+	// The tax included in the total; zero for orders exempt from tax
+	// @example 1.8
+	TaxAmount float64 `json:"tax_amount"`
+
+	// The flat service fee included in the total
+	// @example 1.5
+	ServiceFee float64 `json:"service_fee,omitempty"`
+
+	// How the subtotal, discount, tax and total were rounded; absent when
+	// the restaurant charges in no particular currency
+	Rounding *RoundingRule `json:"rounding,omitempty"`
+
+	// How the order is paid for, if recorded
+	Payment *Payment `json:"payment,omitempty"`
+
+	// Change to bring for cash orders paid with more than the total
+	// @example 30.01
+	ChangeDue float64 `json:"change_due,omitempty"`
+
+	// The payment processor's reference for the captured payment, if any
+	// @example ch_3PqR7s
+	PaymentReference string `json:"payment_reference,omitempty"`
+
+	// When the kitchen expects the order to be ready
+	// @example 2024-01-01T12:30:00Z
+	EstimatedReadyAt *time.Time `json:"estimated_ready_at,omitempty"`
+
+	// When a delivery order is expected to arrive
+	// @example 2024-01-01T13:00:00Z
+	EstimatedDeliveryAt *time.Time `json:"estimated_delivery_at,omitempty"`
+
+	// The current status of the order
+	// @example placed
+	Status string `json:"status,omitempty"`
+
+	// Every status the order has been in, oldest first
+	StatusHistory []StatusChange `json:"status_history,omitempty"`
+
 	// The timestamp when the order was created
 	// @example 2024-01-01T00:00:00Z
 	CreatedAt time.Time `json:"created_at,omitempty"`
@@ -117,6 +445,72 @@ type Order struct {
 	// The timestamp when the order was last updated
 	// @example 2024-01-01T00:00:00Z
 	UpdatedAt time.Time `json:"updated_at,omitempty"`
+
+	// Links to related resources, when the server is configured to serve
+	// them. Links are never stored.
+	Links *Links `json:"_links,omitempty"`
+}
+
+// Order statuses set outside the kitchen
+const (
+	OrderStatusPlaced = "placed"  // Newly placed
+	OrderStatusOnHold = "on_hold" // Held for review instead of being prepared
+)
+
+// Actors that change the status of orders
+const (
+	ActorCustomer = "customer"
+	ActorKitchen  = "kitchen"
+	ActorStaff    = "staff"
+	ActorCourier  = "courier"
+	ActorSystem   = "system"
+)
+
+// StatusChange records an order entering a status
+type StatusChange struct {
+	// The status the order entered
+	// @example placed
+	Status string `json:"status"`
+
+	// When the order entered the status
+	// @example 2024-01-01T00:00:00Z
+	At time.Time `json:"at"`
+
+	// Who moved the order to the status: customer, kitchen, staff, courier or
+	// system
+	// @example customer
+	Actor string `json:"actor"`
+}
+
+// PriceAdjustment is a time-based price modifier applied to an order
+type PriceAdjustment struct {
+	// The name of the promotion
+	// @example happy-hour
+	Name string `json:"name"`
+
+	// The percentage the promotion changes prices by: negative for a
+	// discount, positive for a surcharge
+	// @example -20
+	Percent float64 `json:"percent"`
+
+	// The amount added to the order before any coupon discount and tax;
+	// negative for a discount
+	// @example -3.6
+	Amount float64 `json:"amount"`
+}
+
+// SetStatus moves the order to status at the given time on behalf of actor,
+// recording the change in its history. Every change to an order must go
+// through SetStatus or Touch, so UpdatedAt stays current.
+func (o *Order) SetStatus(status, actor string, at time.Time) {
+	o.Status = status
+	o.StatusHistory = append(o.StatusHistory, StatusChange{Status: status, At: at, Actor: actor})
+	o.Touch(at)
+}
+
+// Touch records that the order changed at the given time
+func (o *Order) Touch(at time.Time) {
+	o.UpdatedAt = at
 }
 
 // Coupon represents a discount coupon that can be applied to orders
@@ -183,6 +577,128 @@ type OrderRequest struct {
 	// List of items to order
 	// @required
 	Items []OrderItem `json:"items" validate:"required,min=1,dive"`
+
+	// Instructions about the whole order, up to 500 characters
+	// @example leave at door
+	Notes string `json:"notes,omitempty" validate:"omitempty,max=500"`
+
+	// Address to deliver the order to. Orders without one are for pickup,
+	// or served at the table when they have a table token.
+	DeliveryAddress *Address `json:"deliveryAddress,omitempty" validate:"omitempty,excluded_with=TableToken"`
+
+	// Token from the QR code on the table to serve a dine-in order to.
+	// Dine-in orders are not delivered.
+	// @example 12.mZ0zT2dOv7bVq1b3Yk0Jm1r0aL3oT8oM0bXy4u6YvVc
+	TableToken string `json:"tableToken,omitempty" validate:"omitempty,max=128"`
+
+	// The X-Catalog-Revision the cart was built from. When given, items
+	// repriced since then fail the order with PRICE_CHANGED.
+	// @example 42
+	CatalogRevision uint64 `json:"catalogRevision,omitempty"`
+
+	// The business to invoice the order to. Orders without one are
+	// invoiced to the customer.
+	Billing *BusinessBilling `json:"billing,omitempty" validate:"omitempty"`
+
+	// How the customer pays for the order
+	Payment *Payment `json:"payment,omitempty" validate:"omitempty"`
+}
+
+// Payment methods
+const (
+	PaymentCash     = "cash"      // Cash, paid on delivery or at pickup
+	PaymentCard     = "card"      // Credit or debit card
+	PaymentGiftCard = "gift_card" // Gift card
+	PaymentPoints   = "points"    // Loyalty points
+)
+
+// PaymentMethods lists the ways an order can be paid for
+var PaymentMethods = []string{PaymentCash, PaymentCard, PaymentGiftCard, PaymentPoints}
+
+// Payment records how an order is paid for. Each method takes its own
+// details, and rejects the details of the others.
+type Payment struct {
+	// How the order is paid for: cash, card, gift_card or points
+	// @required
+	// @example card
+	Method string `json:"method" validate:"required,payment_method"`
+
+	// Cash the customer will hand over, so change can be brought; cash only
+	// @minimum 0
+	// @example 50
+	CashTendered float64 `json:"cashTendered,omitempty" validate:"omitempty,gte=0"`
+
+	// Last four digits of the card; required for card
+	// @example 4242
+	CardLast4 string `json:"cardLast4,omitempty" validate:"omitempty,len=4,numeric"`
+
+	// Code of the gift card; required for gift_card. Orders return only
+	// its last four characters.
+	// @example GC7Q2X9K4242
+	GiftCardCode string `json:"giftCardCode,omitempty" validate:"omitempty,min=8,max=32,alphanum"`
+
+	// Loyalty points redeemed; required for points
+	// @minimum 1
+	// @example 1999
+	Points int `json:"points,omitempty" validate:"omitempty,gt=0"`
+}
+
+// BusinessBilling identifies a business an order is invoiced to, and
+// whether the order is exempt from tax
+type BusinessBilling struct {
+	// Legal name of the business
+	// @required
+	// @example Acme Catering Pty Ltd
+	CompanyName string `json:"companyName" validate:"required,max=200"`
+
+	// Country that issued the tax ID, as an ISO 3166-1 alpha-2 code
+	// @required
+	// @example AU
+	Country string `json:"country" validate:"required,len=2"`
+
+	// Tax ID of the business, such as an ABN or EU VAT number, in the
+	// country's format. Orders return it normalized.
+	// @required
+	// @example 51 824 753 556
+	TaxID string `json:"taxId" validate:"required,max=32"`
+
+	// Whether the order is exempt from tax, invoicing it without tax
+	// @example true
+	TaxExempt bool `json:"taxExempt,omitempty"`
+
+	// Why the order is exempt from tax, recorded on the invoice; required
+	// when exempt, up to 500 characters
+	// @example Registered charity, exempt under GST Act s38-250
+	ExemptionReason string `json:"exemptionReason,omitempty" validate:"required_if=TaxExempt true,max=500"`
+}
+
+// Address represents a delivery address
+type Address struct {
+	// Street address
+	// @required
+	// @example 1 George St
+	Line1 string `json:"line1" validate:"required"`
+
+	// Apartment, suite or unit
+	// @example Unit 4
+	Line2 string `json:"line2,omitempty"`
+
+	// Suburb or city
+	// @example Sydney
+	City string `json:"city,omitempty"`
+
+	// Postal code
+	// @required
+	// @example 2000
+	Postcode string `json:"postcode" validate:"required"`
+
+	// Latitude of the address, used to match geographic delivery zones
+	// @example -33.8688
+	Latitude *float64 `json:"latitude,omitempty" validate:"omitempty,gte=-90,lte=90"`
+
+	// Longitude of the address, used to match geographic delivery zones
+	// @example 151.2093
+	Longitude *float64 `json:"longitude,omitempty" validate:"omitempty,gte=-180,lte=180"`
 }
 
 // ErrorResponse represents an error response from the API
@@ -199,12 +715,101 @@ type ErrorResponse struct {
 	Details map[string]interface{} `json:"details,omitempty"`
 }
 
+// Envelope wraps a response for clients that ask for enveloped responses
+type Envelope struct {
+	// The response as served without an envelope; null for errors
+	Data json.RawMessage `json:"data" swaggertype:"object"`
+
+	// About the request and response
+	Meta EnvelopeMeta `json:"meta"`
+
+	// What went wrong, for error responses
+	Errors []ErrorResponse `json:"errors,omitempty"`
+}
+
+// EnvelopeMeta describes an enveloped response
+type EnvelopeMeta struct {
+	// ID of the request, as sent in X-Request-ID or generated
+	// @example 5f0c6a1e9b7d4e2fa1c3b8d7e6f5a4b3
+	RequestID string `json:"request_id"`
+
+	// How the listing is paged, for paged listings
+	Pagination *Pagination `json:"pagination,omitempty"`
+
+	// Notices for the client, such as routes and fields being deprecated
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Pagination describes a page of a listing
+type Pagination struct {
+	// Most items the page could hold
+	// @example 50
+	Limit int `json:"limit,omitempty"`
+
+	// Cursor of the next page; empty when no items follow
+	// @example eyJsIjoib3JkZXJzIn0
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// validate is shared by every validation, as it caches what it learns of
+// each struct type. Fields are named as they are in JSON.
+var validate = newValidator()
+
+// newValidator returns a validator with the API's custom validations
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterValidation("allergen", oneOf(Allergens))
+	v.RegisterValidation("dietary", oneOf(DietaryTags))
+	v.RegisterValidation("payment_method", oneOf(PaymentMethods))
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	return v
+}
+
 // Validate uses the validator package to validate a struct
 func Validate(i interface{}) error {
-	validate := validator.New()
 	return validate.Struct(i)
 }
 
+// ValidationFields returns the rule each field failed in an error from
+// Validate, by the field's path in JSON, such as items[0].quantity. It
+// returns nil for any other error.
+func ValidationFields(err error) map[string]string {
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		return nil
+	}
+	fields := make(map[string]string, len(errs))
+	for _, fe := range errs {
+		// The namespace starts with the name of the validated type
+		_, path, _ := strings.Cut(fe.Namespace(), ".")
+		fields[path] = fe.Tag()
+	}
+	return fields
+}
+
+// oneOf returns a validation accepting only the given values
+func oneOf(values []string) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		return slices.Contains(values, fl.Field().String())
+	}
+}
+
+// Variant returns the variant of the product with the given ID
+func (p *Product) Variant(id string) (ProductVariant, bool) {
+	for _, v := range p.Variants {
+		if v.ID == id {
+			return v, true
+		}
+	}
+	return ProductVariant{}, false
+}
+
 // NewProduct creates a new Product instance
 func NewProduct(id, name string, price float64, category string, image *ProductImage) *Product {
 	now := time.Now()
@@ -219,9 +824,9 @@ func NewProduct(id, name string, price float64, category string, image *ProductI
 	}
 }
 
-// NewOrder creates a new Order instance
+// NewOrder creates a new Order instance in the placed status
 func NewOrder(items []OrderItem, products []Product, totalAmount float64, couponCode string) *Order {
-	return &Order{
+	order := &Order{
 		ID:          fmt.Sprintf("order-%s", uuid.New().String()),
 		Items:       items,
 		Products:    products,
@@ -229,6 +834,8 @@ func NewOrder(items []OrderItem, products []Product, totalAmount float64, coupon
 		CouponCode:  couponCode,
 		CreatedAt:   time.Now(),
 	}
+	order.SetStatus(OrderStatusPlaced, ActorCustomer, order.CreatedAt)
+	return order
 }
 
 // NewCoupon creates a new Coupon with the current timestamp
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -74,12 +75,36 @@ func TestNewOrder(t *testing.T) {
 	assert.Equal(t, totalAmount, order.TotalAmount)
 	assert.Equal(t, couponCode, order.CouponCode)
 	assert.False(t, order.CreatedAt.IsZero())
+	assert.Equal(t, order.CreatedAt, order.UpdatedAt)
+	assert.Equal(t, OrderStatusPlaced, order.Status)
+	assert.Equal(t, []StatusChange{{Status: OrderStatusPlaced, At: order.CreatedAt, Actor: ActorCustomer}}, order.StatusHistory)
 
 	// Test validation
 	err := Validate(order)
 	assert.NoError(t, err)
 }
 
+func TestOrder_SetStatus(t *testing.T) {
+	order := NewOrder(nil, nil, 10, "")
+	placed := order.CreatedAt
+
+	later := placed.Add(time.Minute)
+	order.SetStatus("cancelled", ActorStaff, later)
+	assert.Equal(t, "cancelled", order.Status)
+	assert.Equal(t, later, order.UpdatedAt)
+	assert.Equal(t, placed, order.CreatedAt)
+	assert.Equal(t, []StatusChange{
+		{Status: OrderStatusPlaced, At: placed, Actor: ActorCustomer},
+		{Status: "cancelled", At: later, Actor: ActorStaff},
+	}, order.StatusHistory)
+
+	// Other changes bump UpdatedAt without a new status
+	latest := later.Add(time.Minute)
+	order.Touch(latest)
+	assert.Equal(t, latest, order.UpdatedAt)
+	assert.Len(t, order.StatusHistory, 2)
+}
+
 func TestNewCoupon(t *testing.T) {
 	code := "SAVE10"
 	discount := 10.0
@@ -249,6 +274,197 @@ func TestValidation(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "valid product with dietary information",
+			input: &Product{
+				ID:       "prod-1",
+				Name:     "Test Product",
+				Price:    9.99,
+				Category: "Test Category",
+				Image: &ProductImage{
+					Thumbnail: "https://example.com/images/test-thumb.jpg",
+					Mobile:    "https://example.com/images/test-mobile.jpg",
+					Tablet:    "https://example.com/images/test-tablet.jpg",
+					Desktop:   "https://example.com/images/test-desktop.jpg",
+				},
+				Allergens: []string{"milk", "tree-nuts"},
+				Dietary:   []string{"vegetarian", "gluten-free"},
+				Calories:  func() *int { v := 450; return &v }(),
+				Nutrition: &Nutrition{Protein: 12.5, Carbohydrates: 48, Fat: 17.2, Sodium: 310},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid product - unknown allergen",
+			input: &Product{
+				ID:       "prod-1",
+				Name:     "Test Product",
+				Price:    9.99,
+				Category: "Test Category",
+				Image: &ProductImage{
+					Thumbnail: "https://example.com/images/test-thumb.jpg",
+					Mobile:    "https://example.com/images/test-mobile.jpg",
+					Tablet:    "https://example.com/images/test-tablet.jpg",
+					Desktop:   "https://example.com/images/test-desktop.jpg",
+				},
+				Allergens: []string{"milk", "pollen"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid product - unknown dietary tag",
+			input: &Product{
+				ID:       "prod-1",
+				Name:     "Test Product",
+				Price:    9.99,
+				Category: "Test Category",
+				Image: &ProductImage{
+					Thumbnail: "https://example.com/images/test-thumb.jpg",
+					Mobile:    "https://example.com/images/test-mobile.jpg",
+					Tablet:    "https://example.com/images/test-tablet.jpg",
+					Desktop:   "https://example.com/images/test-desktop.jpg",
+				},
+				Dietary: []string{"paleo"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid product - negative calories",
+			input: &Product{
+				ID:       "prod-1",
+				Name:     "Test Product",
+				Price:    9.99,
+				Category: "Test Category",
+				Image: &ProductImage{
+					Thumbnail: "https://example.com/images/test-thumb.jpg",
+					Mobile:    "https://example.com/images/test-mobile.jpg",
+					Tablet:    "https://example.com/images/test-tablet.jpg",
+					Desktop:   "https://example.com/images/test-desktop.jpg",
+				},
+				Calories: func() *int { v := -1; return &v }(),
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid product - negative nutrition",
+			input: &Product{
+				ID:       "prod-1",
+				Name:     "Test Product",
+				Price:    9.99,
+				Category: "Test Category",
+				Image: &ProductImage{
+					Thumbnail: "https://example.com/images/test-thumb.jpg",
+					Mobile:    "https://example.com/images/test-mobile.jpg",
+					Tablet:    "https://example.com/images/test-tablet.jpg",
+					Desktop:   "https://example.com/images/test-desktop.jpg",
+				},
+				Nutrition: &Nutrition{Fat: -2},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid product with variants",
+			input: &Product{
+				ID:       "prod-1",
+				Name:     "Test Product",
+				Price:    9.99,
+				Category: "Test Category",
+				Image: &ProductImage{
+					Thumbnail: "https://example.com/images/test-thumb.jpg",
+					Mobile:    "https://example.com/images/test-mobile.jpg",
+					Tablet:    "https://example.com/images/test-tablet.jpg",
+					Desktop:   "https://example.com/images/test-desktop.jpg",
+				},
+				Variants: []ProductVariant{
+					{ID: "small", Name: "Small", Price: 3.5, SKU: "COF-S"},
+					{ID: "large", Name: "Large", Price: 5, SKU: "COF-L"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid product - duplicate variant ID",
+			input: &Product{
+				ID:       "prod-1",
+				Name:     "Test Product",
+				Price:    9.99,
+				Category: "Test Category",
+				Image: &ProductImage{
+					Thumbnail: "https://example.com/images/test-thumb.jpg",
+					Mobile:    "https://example.com/images/test-mobile.jpg",
+					Tablet:    "https://example.com/images/test-tablet.jpg",
+					Desktop:   "https://example.com/images/test-desktop.jpg",
+				},
+				Variants: []ProductVariant{
+					{ID: "small", Name: "Small", Price: 3.5, SKU: "COF-S"},
+					{ID: "small", Name: "Large", Price: 5, SKU: "COF-L"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid product - duplicate variant SKU",
+			input: &Product{
+				ID:       "prod-1",
+				Name:     "Test Product",
+				Price:    9.99,
+				Category: "Test Category",
+				Image: &ProductImage{
+					Thumbnail: "https://example.com/images/test-thumb.jpg",
+					Mobile:    "https://example.com/images/test-mobile.jpg",
+					Tablet:    "https://example.com/images/test-tablet.jpg",
+					Desktop:   "https://example.com/images/test-desktop.jpg",
+				},
+				Variants: []ProductVariant{
+					{ID: "small", Name: "Small", Price: 3.5, SKU: "COF"},
+					{ID: "large", Name: "Large", Price: 5, SKU: "COF"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid product - variant without price",
+			input: &Product{
+				ID:       "prod-1",
+				Name:     "Test Product",
+				Price:    9.99,
+				Category: "Test Category",
+				Image: &ProductImage{
+					Thumbnail: "https://example.com/images/test-thumb.jpg",
+					Mobile:    "https://example.com/images/test-mobile.jpg",
+					Tablet:    "https://example.com/images/test-tablet.jpg",
+					Desktop:   "https://example.com/images/test-desktop.jpg",
+				},
+				Variants: []ProductVariant{{ID: "small", Name: "Small", SKU: "COF-S"}},
+			},
+			wantErr: true,
+		},
+
+		// Order request validation tests
+		{
+			name: "valid order request with delivery address",
+			input: &OrderRequest{
+				Items:           []OrderItem{{ProductID: "prod-1", Quantity: 1}},
+				DeliveryAddress: &Address{Line1: "1 George St", Postcode: "2000"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid order request - address missing postcode",
+			input: &OrderRequest{
+				Items:           []OrderItem{{ProductID: "prod-1", Quantity: 1}},
+				DeliveryAddress: &Address{Line1: "1 George St"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid order request - address latitude out of range",
+			input: &OrderRequest{
+				Items:           []OrderItem{{ProductID: "prod-1", Quantity: 1}},
+				DeliveryAddress: &Address{Line1: "1 George St", Postcode: "2000", Latitude: func() *float64 { v := 91.0; return &v }()},
+			},
+			wantErr: true,
+		},
 
 		// Order validation tests
 		{
@@ -461,3 +677,35 @@ func TestValidation(t *testing.T) {
 		})
 	}
 }
+
+func FuzzOrderRequest(f *testing.F) {
+	f.Add(`{"couponCode":"HAPPYHRS","items":[{"productId":"prod-1","quantity":2}]}`)
+	f.Add(`{"items":[]}`)
+	f.Add(`{"items":[{"productId":"","quantity":-1}]}`)
+	f.Add(`{"items":[{"productId":"prod-1","quantity":9223372036854775807,"price":1e308}]}`)
+	f.Add(`{"items":null}`)
+	f.Add(`[]`)
+
+	f.Fuzz(func(t *testing.T, body string) {
+		var req OrderRequest
+		if err := json.Unmarshal([]byte(body), &req); err != nil {
+			return
+		}
+		if err := Validate(&req); err != nil {
+			return
+		}
+
+		// Anything that passes validation must be safe to price
+		if len(req.Items) == 0 {
+			t.Fatalf("validated request has no items: %s", body)
+		}
+		for _, item := range req.Items {
+			if item.ProductID == "" || item.Quantity <= 0 {
+				t.Fatalf("validated request has invalid item %+v: %s", item, body)
+			}
+		}
+		if _, err := json.Marshal(&req); err != nil {
+			t.Fatalf("validated request does not re-encode: %v", err)
+		}
+	})
+}
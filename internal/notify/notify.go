@@ -0,0 +1,116 @@
+// Package notify renders the emails customers are sent, such as the codes
+// that verify their address or reset their password, and sends them.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+)
+
+// Email is a plain-text email to a single recipient
+type Email struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Email templates
+const (
+	TemplateUnlock        = "unlock.txt"
+	TemplateVerifyEmail   = "verify_email.txt"
+	TemplateResetPassword = "reset_password.txt"
+)
+
+//go:embed templates/*.txt
+var templateFS embed.FS
+
+// templates are the emails customers are sent. Each starts with a
+// "Subject:" line and a blank line, followed by the body.
+var templates = template.Must(template.ParseFS(templateFS, "templates/*.txt"))
+
+// Render builds an email to the given address from the named template
+func Render(to, name string, data interface{}) (Email, error) {
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, name, data); err != nil {
+		return Email{}, fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	header, body, ok := strings.Cut(buf.String(), "\n\n")
+	subject, hasSubject := strings.CutPrefix(header, "Subject: ")
+	if !ok || !hasSubject {
+		return Email{}, fmt.Errorf("template %s does not start with a subject", name)
+	}
+	return Email{To: to, Subject: subject, Body: body}, nil
+}
+
+// Sender delivers emails
+type Sender interface {
+	Send(ctx context.Context, email Email) error
+}
+
+// NewSender creates the Sender described by cfg. Without an SMTP server,
+// emails are written to the log so they can be read during development.
+func NewSender(cfg config.Email) Sender {
+	if cfg.SMTPAddr == "" {
+		return logSender{}
+	}
+	return &smtpSender{cfg: cfg}
+}
+
+// logSender writes emails to the log instead of delivering them
+type logSender struct{}
+
+// Send implements Sender
+func (logSender) Send(_ context.Context, email Email) error {
+	log.Printf("email to %s: %s\n%s", email.To, email.Subject, email.Body)
+	return nil
+}
+
+// smtpSender delivers emails through an SMTP server
+type smtpSender struct {
+	cfg config.Email
+}
+
+// Send implements Sender
+func (s *smtpSender) Send(ctx context.Context, email Email) error {
+	if strings.ContainsAny(email.To+email.Subject, "\r\n") {
+		return fmt.Errorf("invalid email header")
+	}
+
+	var auth smtp.Auth
+	if s.cfg.SMTPUsername != "" {
+		host, _, err := net.SplitHostPort(s.cfg.SMTPAddr)
+		if err != nil {
+			return fmt.Errorf("invalid SMTP address: %w", err)
+		}
+		auth = smtp.PlainAuth("", s.cfg.SMTPUsername, s.cfg.SMTPPassword, host)
+	}
+
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s",
+		s.cfg.From, email.To, email.Subject, time.Now().Format(time.RFC1123Z),
+		strings.ReplaceAll(email.Body, "\n", "\r\n"))
+
+	// net/smtp takes no context, so give up waiting rather than the dial
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(s.cfg.SMTPAddr, auth, s.cfg.From, []string{email.To}, []byte(msg))
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("failed to send email: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
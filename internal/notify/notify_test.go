@@ -0,0 +1,117 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTP accepts a single SMTP session and returns the message data it
+// received
+func fakeSMTP(t *testing.T) (string, <-chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	data := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+
+		reply("220 localhost")
+		var body strings.Builder
+		inData := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if inData {
+				if line == ".\r\n" {
+					inData = false
+					data <- body.String()
+					reply("250 OK")
+					continue
+				}
+				body.WriteString(line)
+				continue
+			}
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case cmd == "DATA":
+				inData = true
+				reply("354 Go ahead")
+			case cmd == "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+	return ln.Addr().String(), data
+}
+
+func TestSMTPSender(t *testing.T) {
+	addr, data := fakeSMTP(t)
+	sender := NewSender(config.Email{SMTPAddr: addr, From: "no-reply@example.com"})
+
+	err := sender.Send(context.Background(), Email{To: "jane@example.com", Subject: "Hello", Body: "Line 1\nLine 2"})
+	require.NoError(t, err)
+
+	msg := <-data
+	assert.Contains(t, msg, "From: no-reply@example.com\r\n")
+	assert.Contains(t, msg, "To: jane@example.com\r\n")
+	assert.Contains(t, msg, "Subject: Hello\r\n")
+	assert.Contains(t, msg, "Line 1\r\nLine 2")
+}
+
+func TestSMTPSender_RejectsHeaderInjection(t *testing.T) {
+	sender := NewSender(config.Email{SMTPAddr: "127.0.0.1:1", From: "no-reply@example.com"})
+	err := sender.Send(context.Background(), Email{To: "jane@example.com\r\nBcc: all@example.com", Subject: "Hello"})
+	assert.Error(t, err)
+}
+
+func TestNewSender_LogsWithoutSMTP(t *testing.T) {
+	sender := NewSender(config.Email{From: "no-reply@example.com"})
+	assert.IsType(t, logSender{}, sender)
+	assert.NoError(t, sender.Send(context.Background(), Email{To: "jane@example.com", Subject: "Hello"}))
+}
+
+func TestRender(t *testing.T) {
+	data := struct{ Name, Code, Link, Expires string }{Name: "Jane", Code: "abc123", Expires: "Mon, 01 Jan 2024 12:00:00 UTC"}
+	for _, name := range []string{TemplateUnlock, TemplateVerifyEmail, TemplateResetPassword} {
+		t.Run(name, func(t *testing.T) {
+			email, err := Render("jane@example.com", name, data)
+			require.NoError(t, err)
+			assert.Equal(t, "jane@example.com", email.To)
+			assert.NotEmpty(t, email.Subject)
+			assert.NotContains(t, email.Subject, "\n")
+			assert.True(t, strings.HasPrefix(email.Body, "Hi Jane,\n"), email.Body)
+			assert.Contains(t, email.Body, "\nabc123\n")
+			assert.Contains(t, email.Body, data.Expires)
+		})
+	}
+
+	withLink := data
+	withLink.Link = "https://app.example.com/verify-email?code=abc123"
+	email, err := Render("jane@example.com", TemplateVerifyEmail, withLink)
+	require.NoError(t, err)
+	assert.Contains(t, email.Body, "\n"+withLink.Link+"\n")
+	assert.NotContains(t, email.Body, "\nabc123\n")
+
+	_, err = Render("jane@example.com", "missing.txt", data)
+	assert.Error(t, err)
+}
@@ -0,0 +1,466 @@
+// Package orders keeps the orders each restaurant takes. The event store
+// appends every change to an order to a log and derives the order from its
+// events, so the full history of any order can be reconstructed, and the
+// views built from orders, such as takings by payment method, can be
+// rebuilt from the log after a restart.
+package orders
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/sorting"
+)
+
+// Event types
+const (
+	EventPlaced        = "placed"         // The order was placed; Order holds it as placed
+	EventStatusChanged = "status_changed" // The order moved to another status; Change holds the move
+)
+
+var (
+	// ErrNotFound is returned for changes to orders that were never placed
+	ErrNotFound = errors.New("order not found")
+	// ErrExists is returned when an order is placed twice
+	ErrExists = errors.New("order already placed")
+)
+
+// Event is a change to an order
+type Event struct {
+	// Position of the event in the log
+	// @example 42
+	Seq uint64 `json:"seq"`
+
+	// What happened: placed or status_changed
+	// @example placed
+	Type string `json:"type"`
+
+	// @example order-0000-0000-0000-0000
+	OrderID string `json:"orderId"`
+
+	// When the event was recorded
+	// @example 2024-01-01T00:00:00Z
+	At time.Time `json:"at"`
+
+	// The order as placed, for placed events
+	Order *models.Order `json:"order,omitempty"`
+
+	// The status the order moved to, for status_changed events
+	Change *models.StatusChange `json:"change,omitempty"`
+}
+
+// History is an order as its events leave it, with the events
+type History struct {
+	// The order with every event applied
+	Order *models.Order `json:"order"`
+
+	// Every event of the order, oldest first
+	Events []Event `json:"events"`
+}
+
+// Store keeps the orders of a restaurant
+type Store interface {
+	// Place keeps a newly placed order
+	Place(order *models.Order) error
+
+	// ChangeStatus records an order moving to another status
+	ChangeStatus(orderID string, change models.StatusChange) error
+
+	// History returns an order and the events that led to it
+	History(orderID string) (History, bool)
+
+	// List returns up to limit orders sorted in order, each as its events
+	// leave it, starting after the order sorting like after, or from the
+	// first when after is nil. It also reports whether more orders follow.
+	List(order sorting.Order, after *models.Order, limit int) ([]*models.Order, bool)
+
+	// Archive removes the orders created before cutoff, with their events,
+	// appending the events to the log at archivePath first, or discarding
+	// them when it is empty. It returns how many orders it removed.
+	Archive(cutoff time.Time, archivePath string) (int, error)
+}
+
+// SortFields are the fields orders can be listed by
+var SortFields = sorting.Fields[*models.Order]{
+	"created_at":   func(a, b *models.Order) int { return a.CreatedAt.Compare(b.CreatedAt) },
+	"total_amount": func(a, b *models.Order) int { return cmp.Compare(a.TotalAmount, b.TotalAmount) },
+	"status":       func(a, b *models.Order) int { return cmp.Compare(a.Status, b.Status) },
+}
+
+// CompareIDs orders orders by ID, which breaks ties in every sort
+func CompareIDs(a, b *models.Order) int {
+	return cmp.Compare(a.ID, b.ID)
+}
+
+// EventStore is a Store that keeps orders as a log of events
+type EventStore struct {
+	mu     sync.Mutex
+	path   string
+	next   uint64
+	events []Event
+	byID   map[string][]int // Positions of each order's events
+	sorted sorting.Index[*models.Order]
+	now    func() time.Time
+}
+
+// Open reads the event log at path. A missing log starts empty, and an
+// empty path keeps the events in memory only. A last line cut short by a
+// crash is dropped, as the change on it was never confirmed.
+func Open(path string) (*EventStore, error) {
+	s := &EventStore{path: path, next: 1, byID: make(map[string][]int), now: time.Now}
+	if path == "" {
+		return s, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read order events: %w", err)
+	}
+
+	valid := 0
+	reader := bufio.NewReader(bytes.NewReader(raw))
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// Lines are only complete once their newline is written
+			break
+		}
+		var event Event
+		if jerr := json.Unmarshal(line, &event); jerr != nil {
+			return nil, fmt.Errorf("invalid order event at byte %d", valid)
+		}
+		if err := s.check(event); err != nil {
+			return nil, fmt.Errorf("invalid order event %d: %w", event.Seq, err)
+		}
+		s.apply(event)
+		valid += len(line)
+	}
+	if valid < len(raw) {
+		if err := os.Truncate(path, int64(valid)); err != nil {
+			return nil, fmt.Errorf("failed to drop incomplete order event: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// Place appends a placed event for order. The event is synced to disk
+// before Place returns; on failure nothing is recorded.
+func (s *EventStore) Place(order *models.Order) error {
+	placed := *order
+	placed.StatusHistory = slices.Clone(order.StatusHistory)
+	return s.append(Event{Type: EventPlaced, OrderID: order.ID, Order: &placed})
+}
+
+// ChangeStatus appends a status_changed event for an order
+func (s *EventStore) ChangeStatus(orderID string, change models.StatusChange) error {
+	return s.append(Event{Type: EventStatusChanged, OrderID: orderID, Change: &change})
+}
+
+// History replays the events of an order, oldest first
+func (s *EventStore) History(orderID string) (History, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.byID[orderID]; !ok {
+		return History{}, false
+	}
+	history := History{Events: s.orderEvents(orderID)}
+	history.Order = Project(history.Events)
+	return history, true
+}
+
+// orderEvents returns the events of an order, oldest first
+func (s *EventStore) orderEvents(orderID string) []Event {
+	positions := s.byID[orderID]
+	events := make([]Event, len(positions))
+	for i, pos := range positions {
+		events[i] = s.events[pos]
+	}
+	return events
+}
+
+// List returns the orders sorted in order after the order sorting like
+// after. The orders are kept sorted in each order they are listed in until
+// the next event, so the first order is found by binary search rather than
+// by sorting and counting past earlier orders. The orders are shared and
+// must not be changed.
+func (s *EventStore) List(order sorting.Order, after *models.Order, limit int) ([]*models.Order, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	compare := SortFields.Compare(order, CompareIDs)
+	sorted := s.sorted.Sorted(s.next, order, compare, s.projectAll)
+	start := 0
+	if after != nil {
+		start = sort.Search(len(sorted), func(i int) bool { return compare(after, sorted[i]) < 0 })
+	}
+	list := []*models.Order{}
+	for _, placed := range sorted[start:] {
+		if len(list) == limit {
+			return list, true
+		}
+		list = append(list, placed)
+	}
+	return list, false
+}
+
+// projectAll derives every order from its events
+func (s *EventStore) projectAll() []*models.Order {
+	list := make([]*models.Order, 0, len(s.byID))
+	for orderID := range s.byID {
+		list = append(list, Project(s.orderEvents(orderID)))
+	}
+	return list
+}
+
+// Replay passes every event in the log to fn, in the order they happened,
+// to rebuild views of the orders
+func (s *EventStore) Replay(fn func(Event)) {
+	s.mu.Lock()
+	events := slices.Clone(s.events)
+	s.mu.Unlock()
+
+	for _, event := range events {
+		fn(event)
+	}
+}
+
+// EventsAfter returns the events after the one numbered seq, oldest first,
+// so views can catch up with the log without replaying it
+func (s *EventStore) EventsAfter(seq uint64) []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := sort.Search(len(s.events), func(i int) bool { return s.events[i].Seq > seq })
+	return slices.Clone(s.events[i:])
+}
+
+// Archive removes the orders created before cutoff and every event of each,
+// appending the events to the log at archivePath first, in the format of
+// the event log, or discarding them when archivePath is empty. The event
+// log is rewritten without them, so they are also left out of the views
+// rebuilt after a restart. On failure the store is left as it was, though
+// orders archived just before a crash can be archived again by the next
+// call.
+func (s *EventStore) Archive(cutoff time.Time, archivePath string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	archived := make(map[string]bool)
+	for _, event := range s.events {
+		if event.Type == EventPlaced && event.Order.CreatedAt.Before(cutoff) {
+			archived[event.OrderID] = true
+		}
+	}
+	if len(archived) == 0 {
+		return 0, nil
+	}
+
+	var old, kept []Event
+	for _, event := range s.events {
+		if archived[event.OrderID] {
+			old = append(old, event)
+		} else {
+			kept = append(kept, event)
+		}
+	}
+	if archivePath != "" {
+		if err := appendEvents(archivePath, old...); err != nil {
+			return 0, fmt.Errorf("failed to archive orders: %w", err)
+		}
+	}
+	if s.path != "" {
+		if err := writeEvents(s.path, kept); err != nil {
+			return 0, fmt.Errorf("failed to rewrite order events: %w", err)
+		}
+	}
+
+	s.events = nil
+	s.byID = make(map[string][]int)
+	for _, event := range kept {
+		s.apply(event)
+	}
+	s.sorted = sorting.Index[*models.Order]{}
+	return len(archived), nil
+}
+
+// Project derives an order from its events, oldest first. It returns nil
+// when the events do not start with the order being placed.
+func Project(events []Event) *models.Order {
+	if len(events) == 0 || events[0].Type != EventPlaced {
+		return nil
+	}
+	order := *events[0].Order
+	order.StatusHistory = slices.Clone(order.StatusHistory)
+	for _, event := range events[1:] {
+		if event.Type == EventStatusChanged {
+			order.SetStatus(event.Change.Status, event.Change.Actor, event.Change.At)
+		}
+	}
+	return &order
+}
+
+// append numbers event, writes it to the log and applies it
+func (s *EventStore) append(event Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event.Seq = s.next
+	event.At = s.now().UTC()
+	if err := s.check(event); err != nil {
+		return err
+	}
+	if s.path != "" {
+		if err := appendEvents(s.path, event); err != nil {
+			return fmt.Errorf("failed to record order event: %w", err)
+		}
+	}
+	s.apply(event)
+	return nil
+}
+
+// check reports whether event can follow the events recorded so far
+func (s *EventStore) check(event Event) error {
+	_, exists := s.byID[event.OrderID]
+	switch event.Type {
+	case EventPlaced:
+		if event.Order == nil {
+			return errors.New("placed event without an order")
+		}
+		if exists {
+			return fmt.Errorf("%w: %s", ErrExists, event.OrderID)
+		}
+	case EventStatusChanged:
+		if event.Change == nil {
+			return errors.New("status_changed event without a change")
+		}
+		if !exists {
+			return fmt.Errorf("%w: %s", ErrNotFound, event.OrderID)
+		}
+	default:
+		return fmt.Errorf("unknown event type %q", event.Type)
+	}
+	return nil
+}
+
+// apply adds a checked event to the store
+func (s *EventStore) apply(event Event) {
+	s.byID[event.OrderID] = append(s.byID[event.OrderID], len(s.events))
+	s.events = append(s.events, event)
+	s.next = max(s.next, event.Seq+1)
+}
+
+// appendEvents appends events to the log at path, each as a line of JSON,
+// and syncs them to disk, leaving the log as it was on failure. A new log
+// is synced into its directory first, so a crash cannot lose the file along
+// with the first order in it.
+func appendEvents(path string, events ...Event) error {
+	lines, err := marshalEvents(events)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if info.Size() == 0 {
+		if err := syncDir(filepath.Dir(path)); err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	// Cut off whatever part of the lines was written, so the next event
+	// starts on a line of its own
+	if _, err := f.Write(lines); err != nil {
+		f.Truncate(info.Size())
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Truncate(info.Size())
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeEvents replaces the log at path with events, through a temporary
+// file synced to disk and renamed into place, so a crash leaves either the
+// old log or the new one
+func writeEvents(path string, events []Event) error {
+	lines, err := marshalEvents(events)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(lines); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// marshalEvents returns events as lines of JSON
+func marshalEvents(events []Event) ([]byte, error) {
+	var lines []byte
+	for _, event := range events {
+		line, err := json.Marshal(event)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(append(lines, line...), '\n')
+	}
+	return lines, nil
+}
+
+// syncDir syncs the entries of dir to disk
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,21 @@
+package orders_test
+
+import (
+	"testing"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/data/storetest"
+	"github.com/ravibandhu/oolio-food-ordering/internal/orders"
+)
+
+func TestEventStore_Conformance(t *testing.T) {
+	t.Run("File", func(t *testing.T) {
+		storetest.TestOrders(t, func(t *testing.T, path string) (orders.Store, error) {
+			return orders.Open(path)
+		}, true)
+	})
+	t.Run("Memory", func(t *testing.T) {
+		storetest.TestOrders(t, func(t *testing.T, path string) (orders.Store, error) {
+			return orders.Open("")
+		}, false)
+	})
+}
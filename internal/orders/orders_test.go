@@ -0,0 +1,139 @@
+package orders
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/sorting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// placedOrder returns an order as it is placed
+func placedOrder(id string) *models.Order {
+	order := &models.Order{ID: id, TotalAmount: 12.5, CreatedAt: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	order.SetStatus(models.OrderStatusPlaced, models.ActorCustomer, order.CreatedAt)
+	return order
+}
+
+func TestEventStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders", "harbour.jsonl")
+	store, err := Open(path)
+	require.NoError(t, err)
+
+	require.NoError(t, store.Place(placedOrder("order-1")))
+	require.NoError(t, store.Place(placedOrder("order-2")))
+	ready := models.StatusChange{Status: "ready", At: time.Date(2024, 1, 1, 12, 20, 0, 0, time.UTC), Actor: models.ActorStaff}
+	require.NoError(t, store.ChangeStatus("order-1", ready))
+
+	assert.ErrorIs(t, store.Place(placedOrder("order-1")), ErrExists)
+	assert.ErrorIs(t, store.ChangeStatus("order-9", ready), ErrNotFound)
+
+	history, ok := store.History("order-1")
+	require.True(t, ok)
+	require.Len(t, history.Events, 2)
+	assert.Equal(t, EventPlaced, history.Events[0].Type)
+	assert.Equal(t, uint64(3), history.Events[1].Seq)
+	assert.Equal(t, "ready", history.Order.Status)
+	assert.Len(t, history.Order.StatusHistory, 2)
+	assert.Equal(t, ready.At, history.Order.UpdatedAt)
+
+	_, ok = store.History("order-9")
+	assert.False(t, ok)
+
+	// The log rebuilds the same history after a restart
+	reopened, err := Open(path)
+	require.NoError(t, err)
+	again, ok := reopened.History("order-1")
+	require.True(t, ok)
+	assert.Equal(t, history.Order.Status, again.Order.Status)
+	assert.Equal(t, history.Order.StatusHistory, again.Order.StatusHistory)
+
+	var replayed []string
+	reopened.Replay(func(event Event) { replayed = append(replayed, event.OrderID+" "+event.Type) })
+	assert.Equal(t, []string{"order-1 placed", "order-2 placed", "order-1 status_changed"}, replayed)
+
+	// Numbering continues after the last event
+	require.NoError(t, reopened.Place(placedOrder("order-3")))
+	history, _ = reopened.History("order-3")
+	assert.Equal(t, uint64(4), history.Events[0].Seq)
+}
+
+func TestOpen_IncompleteEvent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "harbour.jsonl")
+	store, err := Open(path)
+	require.NoError(t, err)
+	require.NoError(t, store.Place(placedOrder("order-1")))
+
+	// A crash mid-write leaves part of a line behind
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"seq":2,"type":"placed","orderId":"order-2","or`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	store, err = Open(path)
+	require.NoError(t, err)
+	_, ok := store.History("order-2")
+	assert.False(t, ok)
+	require.NoError(t, store.Place(placedOrder("order-2")))
+
+	store, err = Open(path)
+	require.NoError(t, err)
+	_, ok = store.History("order-2")
+	assert.True(t, ok)
+}
+
+func TestOpen_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "harbour.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte(`{"seq":1,"type":"status_changed","orderId":"order-1","change":{"status":"ready"}}`+"\n"), 0644))
+
+	_, err := Open(path)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestEventStore_List(t *testing.T) {
+	store, err := Open("")
+	require.NoError(t, err)
+	for _, id := range []string{"order-1", "order-2", "order-3"} {
+		require.NoError(t, store.Place(placedOrder(id)))
+	}
+	require.NoError(t, store.ChangeStatus("order-1", models.StatusChange{Status: "ready", Actor: models.ActorStaff}))
+
+	oldest, err := SortFields.Parse("created_at")
+	require.NoError(t, err)
+	page, more := store.List(oldest, nil, 2)
+	require.Len(t, page, 2)
+	assert.Equal(t, "order-1", page[0].ID)
+	assert.Equal(t, "ready", page[0].Status)
+	assert.True(t, more)
+
+	// Orders placed between pages follow on
+	require.NoError(t, store.Place(placedOrder("order-4")))
+	page, more = store.List(oldest, page[1], 2)
+	require.Len(t, page, 2)
+	assert.Equal(t, "order-3", page[0].ID)
+	assert.Equal(t, "order-4", page[1].ID)
+	assert.False(t, more)
+
+	// Sorts by several fields fall back to the next field on ties
+	large := placedOrder("order-5")
+	large.TotalAmount = 40
+	require.NoError(t, store.Place(large))
+	byTotal, err := SortFields.Parse("-total_amount,status")
+	require.NoError(t, err)
+	page, more = store.List(byTotal, nil, 3)
+	require.Len(t, page, 3)
+	assert.Equal(t, []string{"order-5", "order-2", "order-3"}, []string{page[0].ID, page[1].ID, page[2].ID})
+	assert.True(t, more)
+	page, more = store.List(byTotal, page[2], 3)
+	require.Len(t, page, 2)
+	assert.Equal(t, []string{"order-4", "order-1"}, []string{page[0].ID, page[1].ID})
+	assert.False(t, more)
+
+	_, err = SortFields.Parse("customer")
+	assert.ErrorIs(t, err, sorting.ErrInvalid)
+}
@@ -0,0 +1,145 @@
+// Package payments totals the orders a restaurant takes by payment method
+// and day, so cash can be reconciled against card and gift card takings.
+// Totals are kept in memory and start over when the server restarts.
+package payments
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+// Unspecified is the method orders placed without a payment are totalled
+// under
+const Unspecified = "unspecified"
+
+// Total is the orders taken with a payment method over some period
+type Total struct {
+	// Orders taken
+	// @example 12
+	Orders int `json:"orders"`
+
+	// Sum of their totals
+	// @example 240.5
+	Amount float64 `json:"amount"`
+}
+
+// add adds other to t
+func (t *Total) add(other Total) {
+	t.Orders += other.Orders
+	t.Amount += other.Amount
+}
+
+// MethodTotal is the orders taken with one payment method
+type MethodTotal struct {
+	// The payment method, or unspecified for orders placed without one
+	// @example card
+	Method string `json:"method"`
+
+	Total
+}
+
+// Report is the orders taken by payment method over a range of days
+type Report struct {
+	// First and last day covered, in UTC; empty when the range is open
+	// @example 2024-01-01
+	From string `json:"from,omitempty"`
+	// @example 2024-01-31
+	To string `json:"to,omitempty"`
+
+	// Orders taken with each method that was used, sorted by method
+	Methods []MethodTotal `json:"methods"`
+
+	// Orders taken with any method
+	Total Total `json:"total"`
+}
+
+// Ledger totals orders by payment method and day. A nil Ledger records
+// nothing.
+type Ledger struct {
+	mu   sync.Mutex
+	days map[string]map[string]*Total // By date, then method
+}
+
+// NewLedger creates a new, empty ledger
+func NewLedger() *Ledger {
+	return &Ledger{days: make(map[string]map[string]*Total)}
+}
+
+// Record adds an order to the totals of its payment method on the day it
+// was placed, in UTC
+func (l *Ledger) Record(order *models.Order) {
+	if l == nil {
+		return
+	}
+
+	method := Unspecified
+	if order.Payment != nil {
+		method = order.Payment.Method
+	}
+	date := order.CreatedAt.UTC().Format(time.DateOnly)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	byMethod, ok := l.days[date]
+	if !ok {
+		byMethod = make(map[string]*Total)
+		l.days[date] = byMethod
+	}
+	total, ok := byMethod[method]
+	if !ok {
+		total = &Total{}
+		byMethod[method] = total
+	}
+	total.Orders++
+	total.Amount += order.TotalAmount
+}
+
+// Report returns the totals by method from one day to another, both
+// included. A zero from or to leaves that end of the range open.
+func (l *Ledger) Report(from, to time.Time) Report {
+	report := Report{Methods: []MethodTotal{}}
+	if !from.IsZero() {
+		report.From = from.UTC().Format(time.DateOnly)
+	}
+	if !to.IsZero() {
+		report.To = to.UTC().Format(time.DateOnly)
+	}
+	if l == nil {
+		return report
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	totals := make(map[string]*Total)
+	for date, byMethod := range l.days {
+		// Dates sort lexically in the same order as chronologically
+		if (report.From != "" && date < report.From) || (report.To != "" && date > report.To) {
+			continue
+		}
+		for method, total := range byMethod {
+			sum, ok := totals[method]
+			if !ok {
+				sum = &Total{}
+				totals[method] = sum
+			}
+			sum.add(*total)
+		}
+	}
+	for method, total := range totals {
+		total.Amount = roundCents(total.Amount)
+		report.Methods = append(report.Methods, MethodTotal{Method: method, Total: *total})
+		report.Total.add(*total)
+	}
+	sort.Slice(report.Methods, func(i, j int) bool { return report.Methods[i].Method < report.Methods[j].Method })
+	report.Total.Amount = roundCents(report.Total.Amount)
+	return report
+}
+
+// roundCents rounds an amount to whole cents
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
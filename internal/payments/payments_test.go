@@ -0,0 +1,49 @@
+package payments
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLedger(t *testing.T) {
+	ledger := NewLedger()
+	day := time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC)
+	order := func(method string, total float64, at time.Time) *models.Order {
+		o := &models.Order{TotalAmount: total, CreatedAt: at}
+		if method != "" {
+			o.Payment = &models.Payment{Method: method}
+		}
+		return o
+	}
+
+	ledger.Record(order(models.PaymentCard, 10.1, day))
+	ledger.Record(order(models.PaymentCard, 20.2, day))
+	ledger.Record(order(models.PaymentCash, 5, day))
+	ledger.Record(order("", 7.5, day.Add(2*time.Hour)))
+
+	report := ledger.Report(time.Time{}, time.Time{})
+	assert.Equal(t, []MethodTotal{
+		{Method: models.PaymentCard, Total: Total{Orders: 2, Amount: 30.3}},
+		{Method: models.PaymentCash, Total: Total{Orders: 1, Amount: 5}},
+		{Method: Unspecified, Total: Total{Orders: 1, Amount: 7.5}},
+	}, report.Methods)
+	assert.Equal(t, Total{Orders: 4, Amount: 42.8}, report.Total)
+
+	// Days are counted in UTC
+	report = ledger.Report(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), time.Time{})
+	assert.Equal(t, "2024-01-02", report.From)
+	assert.Equal(t, []MethodTotal{{Method: Unspecified, Total: Total{Orders: 1, Amount: 7.5}}}, report.Methods)
+
+	report = ledger.Report(time.Time{}, time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC))
+	assert.Empty(t, report.Methods)
+	assert.Zero(t, report.Total)
+}
+
+func TestLedger_Nil(t *testing.T) {
+	var ledger *Ledger
+	ledger.Record(&models.Order{TotalAmount: 1})
+	assert.Empty(t, ledger.Report(time.Time{}, time.Time{}).Methods)
+}
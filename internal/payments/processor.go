@@ -0,0 +1,30 @@
+package payments
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+// ErrDeclined is returned when the customer's payment is refused, such as
+// for insufficient funds or an expired gift card
+var ErrDeclined = errors.New("payment declined")
+
+// Processor captures the payments of orders with a payment provider. Cash is
+// collected on delivery and never captured.
+type Processor interface {
+	// Capture takes the payment of an order and returns the provider's
+	// reference for it. It returns ErrDeclined when the payment is refused,
+	// and any other error when the provider could not be reached.
+	Capture(ctx context.Context, order *models.Order) (string, error)
+
+	// Void releases a captured payment, for orders that could not be placed
+	// after all
+	Void(ctx context.Context, reference string) error
+}
+
+// Captured reports whether an order's payment is captured by a processor
+func Captured(payment *models.Payment) bool {
+	return payment != nil && payment.Method != models.PaymentCash
+}
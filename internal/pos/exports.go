@@ -0,0 +1,225 @@
+package pos
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+// maxExports bounds the orders kept for the POS to collect; older orders are
+// dropped whether or not they were collected
+const maxExports = 10000
+
+// OrderExport is an order as the POS collects it
+type OrderExport struct {
+	// Position of the order in the feed
+	// @example 42
+	Sequence uint64 `json:"sequence"`
+
+	// The order
+	// @example order-0000-0000-0000-0000
+	OrderID string `json:"order_id"`
+
+	// The status the order was placed in: placed or on_hold
+	// @example placed
+	Status string `json:"status"`
+
+	// The invoice number issued to the order
+	// @example INV-000042
+	InvoiceNumber string `json:"invoice_number,omitempty"`
+
+	// What was ordered
+	Items []ExportItem `json:"items"`
+
+	// Instructions about the whole order
+	// @example leave at door
+	Notes string `json:"notes,omitempty"`
+
+	// The coupon code used for the order, if any
+	// @example SAVE10
+	CouponCode string `json:"coupon_code,omitempty"`
+
+	// The business the order is invoiced to, if any
+	Billing *models.BusinessBilling `json:"billing,omitempty"`
+
+	// The tax included in the total; zero for orders exempt from tax
+	// @example 1.8
+	TaxAmount float64 `json:"tax_amount"`
+
+	// How the order is paid for, if recorded
+	Payment *models.Payment `json:"payment,omitempty"`
+
+	// Change to bring for cash orders paid with more than the total
+	// @example 30.01
+	ChangeDue float64 `json:"change_due,omitempty"`
+
+	// The address the order is delivered to, if any
+	DeliveryAddress *models.Address `json:"delivery_address,omitempty"`
+
+	// The delivery fee included in the total
+	// @example 4.5
+	DeliveryFee float64 `json:"delivery_fee,omitempty"`
+
+	// Time-based price modifiers included in the total, such as a happy
+	// hour discount
+	Adjustments []models.PriceAdjustment `json:"adjustments,omitempty"`
+
+	// The total amount of the order
+	// @example 19.99
+	TotalAmount float64 `json:"total_amount"`
+
+	// When the order was placed
+	// @example 2024-01-01T00:00:00Z
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ExportItem is a line of an exported order
+type ExportItem struct {
+	// The ordered product
+	// @example 1
+	ProductID string `json:"product_id"`
+
+	// The ordered variant, if any
+	// @example large
+	VariantID string `json:"variant_id,omitempty"`
+
+	// The SKU of the item, if it has one
+	// @example WAF-BER-L
+	SKU string `json:"sku,omitempty"`
+
+	// The name of the product
+	// @example Waffle with Berries
+	Name string `json:"name"`
+
+	// How many were ordered
+	// @example 2
+	Quantity int `json:"quantity"`
+
+	// The unit price charged
+	// @example 6.5
+	Price float64 `json:"price"`
+
+	// Instructions about this item
+	// @example no onions
+	Notes string `json:"notes,omitempty"`
+}
+
+// ExportPage is a page of the order feed
+type ExportPage struct {
+	// Identifies the feed. Sequences restart when it changes, after the
+	// server restarts, so the POS collects again from 0.
+	// @example 5f0c6a4e-3c1b-4b7e-9a7e-1d2c3b4a5f6e
+	Feed string `json:"feed"`
+
+	// Orders after the requested sequence, oldest first
+	Orders []OrderExport `json:"orders"`
+
+	// The sequence to collect the next page after
+	// @example 42
+	Next uint64 `json:"next"`
+
+	// Whether orders after the requested sequence were dropped before they
+	// were collected
+	// @example false
+	Missed bool `json:"missed"`
+}
+
+// Exports is the feed of orders placed online, for the POS to collect.
+// Orders are numbered in the order they were placed and kept in memory. A
+// nil Exports records nothing.
+type Exports struct {
+	mu     sync.Mutex
+	feed   string
+	last   uint64
+	orders []OrderExport
+}
+
+// NewExports creates a new, empty feed
+func NewExports() *Exports {
+	return &Exports{feed: uuid.New().String()}
+}
+
+// Record appends order to the feed
+func (e *Exports) Record(order *models.Order) {
+	if e == nil {
+		return
+	}
+
+	products := make(map[string]*models.Product, len(order.Products))
+	for i := range order.Products {
+		products[order.Products[i].ID] = &order.Products[i]
+	}
+	items := make([]ExportItem, len(order.Items))
+	for i, item := range order.Items {
+		items[i] = ExportItem{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+			Price:     item.Price,
+			Notes:     item.Notes,
+		}
+		if product, ok := products[item.ProductID]; ok {
+			items[i].SKU = SKU(product, item.VariantID)
+			items[i].Name = product.Name
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.last++
+	e.orders = append(e.orders, OrderExport{
+		Sequence:        e.last,
+		OrderID:         order.ID,
+		Status:          order.Status,
+		InvoiceNumber:   order.InvoiceNumber,
+		Items:           items,
+		Notes:           order.Notes,
+		CouponCode:      order.CouponCode,
+		Billing:         order.Billing,
+		TaxAmount:       order.TaxAmount,
+		Payment:         order.Payment,
+		ChangeDue:       order.ChangeDue,
+		DeliveryAddress: order.DeliveryAddress,
+		DeliveryFee:     order.DeliveryFee,
+		Adjustments:     order.Adjustments,
+		TotalAmount:     order.TotalAmount,
+		CreatedAt:       order.CreatedAt,
+	})
+	if len(e.orders) > maxExports {
+		e.orders = append([]OrderExport(nil), e.orders[len(e.orders)-maxExports:]...)
+	}
+}
+
+// After returns up to limit orders placed after sequence after. A sequence
+// beyond the last one, from an earlier feed, is treated as 0.
+func (e *Exports) After(after uint64, limit int) ExportPage {
+	if e == nil {
+		return ExportPage{Orders: []OrderExport{}}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if after > e.last {
+		after = 0
+	}
+	page := ExportPage{Feed: e.feed, Orders: []OrderExport{}, Next: after}
+	if len(e.orders) == 0 {
+		return page
+	}
+
+	// Sequences are consecutive, so the first order after is found by offset
+	first := e.orders[0].Sequence
+	page.Missed = after+1 < first
+	start := 0
+	if after >= first {
+		start = int(after - first + 1)
+	}
+	end := min(start+limit, len(e.orders))
+	page.Orders = append(page.Orders, e.orders[start:end]...)
+	if len(page.Orders) > 0 {
+		page.Next = page.Orders[len(page.Orders)-1].Sequence
+	}
+	return page
+}
@@ -0,0 +1,65 @@
+package pos
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExports_After(t *testing.T) {
+	exports := NewExports()
+	page := exports.After(0, 10)
+	assert.NotEmpty(t, page.Feed)
+	assert.Empty(t, page.Orders)
+	assert.Zero(t, page.Next)
+
+	waffle := models.Product{ID: "waffle", Name: "Waffle", SKU: "WAF-BER"}
+	for i := 0; i < 3; i++ {
+		order := models.NewOrder([]models.OrderItem{{ProductID: "waffle", Quantity: i + 1, Price: 6.5}}, []models.Product{waffle}, 6.5*float64(i+1), "")
+		exports.Record(order)
+	}
+
+	page = exports.After(0, 2)
+	require.Len(t, page.Orders, 2)
+	assert.Equal(t, uint64(1), page.Orders[0].Sequence)
+	assert.Equal(t, models.OrderStatusPlaced, page.Orders[0].Status)
+	assert.Equal(t, ExportItem{ProductID: "waffle", SKU: "WAF-BER", Name: "Waffle", Quantity: 1, Price: 6.5}, page.Orders[0].Items[0])
+	assert.Equal(t, uint64(2), page.Next)
+
+	page = exports.After(page.Next, 2)
+	require.Len(t, page.Orders, 1)
+	assert.Equal(t, uint64(3), page.Orders[0].Sequence)
+	assert.Equal(t, uint64(3), page.Next)
+
+	// Collecting again past the end returns nothing and keeps the cursor
+	page = exports.After(3, 2)
+	assert.Empty(t, page.Orders)
+	assert.Equal(t, uint64(3), page.Next)
+
+	// A cursor from an earlier feed starts over
+	page = exports.After(99, 10)
+	assert.Len(t, page.Orders, 3)
+	assert.NotEqual(t, NewExports().feed, page.Feed)
+}
+
+func TestExports_Retention(t *testing.T) {
+	exports := NewExports()
+	for i := 0; i < maxExports+5; i++ {
+		exports.Record(&models.Order{ID: fmt.Sprintf("order-%d", i), Status: models.OrderStatusPlaced})
+	}
+
+	page := exports.After(0, 1)
+	assert.True(t, page.Missed)
+	assert.Equal(t, uint64(6), page.Orders[0].Sequence)
+
+	page = exports.After(5, 1)
+	assert.False(t, page.Missed)
+	assert.Equal(t, "order-5", page.Orders[0].OrderID)
+
+	var untracked *Exports
+	untracked.Record(&models.Order{ID: "order-1"})
+	assert.Empty(t, untracked.After(0, 10).Orders)
+}
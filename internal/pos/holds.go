@@ -0,0 +1,114 @@
+package pos
+
+import (
+	"context"
+	"time"
+)
+
+// hold is stock set aside for a checkout that has not been placed yet, so
+// two customers are not both promised the last unit
+type hold struct {
+	needs   map[string]int // Units of each tracked SKU
+	expires time.Time
+}
+
+// Hold sets aside the units of each tracked SKU in needs for checkout until
+// ttl from now, replacing whatever it held. When any tracked SKU has too few
+// units free of other checkouts' holds, the checkout keeps what it held and
+// those SKUs are returned, sorted. Holds of nothing release the checkout's.
+func (i *Inventory) Hold(checkout string, needs map[string]int, ttl time.Duration) []string {
+	if i == nil {
+		return nil
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.prune()
+	if short := i.short(checkout, needs); len(short) > 0 {
+		return short
+	}
+	tracked := make(map[string]int, len(needs))
+	for sku, quantity := range needs {
+		if _, ok := i.levels[sku]; ok && quantity > 0 {
+			tracked[sku] = quantity
+		}
+	}
+	if len(tracked) == 0 {
+		delete(i.holds, checkout)
+		return nil
+	}
+	i.holds[checkout] = &hold{needs: tracked, expires: i.now().Add(ttl)}
+	return nil
+}
+
+// Release gives back the units held for checkout
+func (i *Inventory) Release(checkout string) {
+	if i == nil {
+		return
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.holds, checkout)
+}
+
+// TakeHeld removes the units of each SKU in needs from stock, as Take does,
+// counting the units held for checkout as free. Once taken, the checkout's
+// hold is released.
+func (i *Inventory) TakeHeld(checkout string, needs map[string]int) []string {
+	if i == nil {
+		return nil
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.prune()
+	if short := i.short(checkout, needs); len(short) > 0 {
+		return short
+	}
+	for sku, quantity := range needs {
+		if level, ok := i.levels[sku]; ok {
+			level.Quantity -= quantity
+		}
+	}
+	if checkout != "" {
+		delete(i.holds, checkout)
+	}
+	return nil
+}
+
+// heldBy returns the units of sku held for checkouts other than checkout.
+// Callers must hold i.mu.
+func (i *Inventory) heldExcept(sku, checkout string) int {
+	held := 0
+	for id, h := range i.holds {
+		if id != checkout {
+			held += h.needs[sku]
+		}
+	}
+	return held
+}
+
+// prune drops expired holds. Callers must hold i.mu.
+func (i *Inventory) prune() {
+	now := i.now()
+	for id, h := range i.holds {
+		if !now.Before(h.expires) {
+			delete(i.holds, id)
+		}
+	}
+}
+
+type holdKey struct{}
+
+// NewHoldContext returns a copy of ctx placing the order of checkout, whose
+// held stock the order takes
+func NewHoldContext(ctx context.Context, checkout string) context.Context {
+	return context.WithValue(ctx, holdKey{}, checkout)
+}
+
+// HoldFromContext returns the checkout whose order ctx places, if any
+func HoldFromContext(ctx context.Context) string {
+	checkout, _ := ctx.Value(holdKey{}).(string)
+	return checkout
+}
@@ -0,0 +1,52 @@
+package pos
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInventory_Hold(t *testing.T) {
+	inventory := NewInventory()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	inventory.now = func() time.Time { return now }
+	three := 3
+	inventory.Sync([]LevelUpdate{{SKU: "COF-L", Quantity: &three}})
+
+	// Held units are not free to other checkouts or orders
+	assert.Empty(t, inventory.Hold("cart-1", map[string]int{"COF-L": 2, "TEA": 5}, 10*time.Minute))
+	assert.Equal(t, []string{"COF-L"}, inventory.Hold("cart-2", map[string]int{"COF-L": 2}, 10*time.Minute))
+	assert.Equal(t, []string{"COF-L"}, inventory.Short(map[string]int{"COF-L": 2}))
+	assert.Equal(t, []string{"COF-L"}, inventory.Take(map[string]int{"COF-L": 2}))
+	assert.Empty(t, inventory.Take(map[string]int{"COF-L": 1}))
+
+	// A checkout that cannot grow its hold keeps what it held
+	assert.Equal(t, []string{"COF-L"}, inventory.Hold("cart-1", map[string]int{"COF-L": 3}, 10*time.Minute))
+	assert.Equal(t, []string{"COF-L"}, inventory.Take(map[string]int{"COF-L": 1}))
+
+	// Its order takes what it held, releasing the hold
+	assert.Empty(t, inventory.TakeHeld("cart-1", map[string]int{"COF-L": 2}))
+	assert.Zero(t, inventory.List()[0].Quantity)
+	assert.Empty(t, inventory.holds)
+
+	// Holds run out after their TTL, or when released
+	inventory.Restock(map[string]int{"COF-L": 2})
+	assert.Empty(t, inventory.Hold("cart-2", map[string]int{"COF-L": 2}, 10*time.Minute))
+	now = now.Add(10 * time.Minute)
+	assert.Empty(t, inventory.Short(map[string]int{"COF-L": 2}))
+	assert.Empty(t, inventory.Hold("cart-3", map[string]int{"COF-L": 2}, 10*time.Minute))
+	inventory.Release("cart-3")
+	assert.Empty(t, inventory.Short(map[string]int{"COF-L": 2}))
+
+	var untracked *Inventory
+	assert.Empty(t, untracked.Hold("cart-1", map[string]int{"COF-L": 1}, time.Minute))
+	assert.Empty(t, untracked.TakeHeld("cart-1", map[string]int{"COF-L": 1}))
+	untracked.Release("cart-1")
+}
+
+func TestHoldContext(t *testing.T) {
+	assert.Empty(t, HoldFromContext(context.Background()))
+	assert.Equal(t, "cart-1", HoldFromContext(NewHoldContext(context.Background(), "cart-1")))
+}
@@ -0,0 +1,210 @@
+// Package pos connects a restaurant's point-of-sale system: the POS reports
+// the stock of the SKUs it tracks and collects the orders placed online.
+package pos
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+// Level is the stock of a SKU
+type Level struct {
+	// The SKU, as the POS knows it
+	// @example WAF-BER-L
+	SKU string `json:"sku"`
+
+	// Units in stock, less the units ordered online since the POS counted them
+	// @example 12
+	Quantity int `json:"quantity"`
+
+	// When the POS counted the stock
+	// @example 2024-01-01T00:00:00Z
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// LevelUpdate reports the stock of a SKU
+type LevelUpdate struct {
+	// The SKU, as the POS knows it
+	// @required
+	// @example WAF-BER-L
+	SKU string `json:"sku" validate:"required,max=64"`
+
+	// Units in stock
+	// @required
+	// @minimum 0
+	// @example 12
+	Quantity *int `json:"quantity" validate:"required,gte=0"`
+
+	// When the POS counted the stock. Updates older than the level held are
+	// ignored; without it the update is counted as of now.
+	// @example 2024-01-01T00:00:00Z
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+// InventoryRequest is the body of an inventory sync
+type InventoryRequest struct {
+	// The levels to set, at most one per SKU
+	// @required
+	Levels []LevelUpdate `json:"levels" validate:"required,min=1,max=1000,unique=SKU,dive"`
+}
+
+// InventoryResult reports how an inventory sync was applied
+type InventoryResult struct {
+	// Number of levels that changed
+	// @example 1
+	Applied int `json:"applied"`
+
+	// SKUs whose update was older than the level held, and was ignored
+	// @example ["WAF-BER-L"]
+	Stale []string `json:"stale,omitempty"`
+
+	// The levels of every SKU in the request after the sync
+	Levels []Level `json:"levels"`
+}
+
+// Inventory holds the stock levels a POS reports, and the stock held for
+// checkouts not placed yet. SKUs it has not reported are not tracked and
+// never run out. A nil Inventory tracks no SKUs.
+type Inventory struct {
+	mu     sync.Mutex
+	levels map[string]*Level
+	holds  map[string]*hold // By checkout, see Hold
+	now    func() time.Time
+}
+
+// NewInventory creates a new, empty Inventory
+func NewInventory() *Inventory {
+	return &Inventory{
+		levels: make(map[string]*Level),
+		holds:  make(map[string]*hold),
+		now:    time.Now,
+	}
+}
+
+// Sync sets the levels in updates. Quantities are absolute and updates
+// older than or as old as the level held are ignored, so replaying a sync
+// changes nothing, even after orders have taken stock since.
+func (i *Inventory) Sync(updates []LevelUpdate) InventoryResult {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	result := InventoryResult{Levels: make([]Level, 0, len(updates))}
+	for _, update := range updates {
+		at := i.now().UTC()
+		if update.UpdatedAt != nil {
+			at = update.UpdatedAt.UTC()
+		}
+		level, ok := i.levels[update.SKU]
+		switch {
+		case !ok:
+			level = &Level{SKU: update.SKU, Quantity: *update.Quantity, UpdatedAt: at}
+			i.levels[update.SKU] = level
+			result.Applied++
+		case !at.After(level.UpdatedAt):
+			result.Stale = append(result.Stale, update.SKU)
+		default:
+			level.Quantity, level.UpdatedAt = *update.Quantity, at
+			result.Applied++
+		}
+		result.Levels = append(result.Levels, *level)
+	}
+	return result
+}
+
+// List returns every level, sorted by SKU
+func (i *Inventory) List() []Level {
+	if i == nil {
+		return []Level{}
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	levels := make([]Level, 0, len(i.levels))
+	for _, level := range i.levels {
+		levels = append(levels, *level)
+	}
+	sort.Slice(levels, func(a, b int) bool { return levels[a].SKU < levels[b].SKU })
+	return levels
+}
+
+// Take removes the units of each SKU in needs from stock. Units held for
+// checkouts are not free to take. When any tracked SKU has too few free
+// units, nothing is taken and those SKUs are returned, sorted.
+func (i *Inventory) Take(needs map[string]int) []string {
+	return i.TakeHeld("", needs)
+}
+
+// Short returns the tracked SKUs of needs that have too few free units,
+// sorted, without taking any
+func (i *Inventory) Short(needs map[string]int) []string {
+	if i == nil {
+		return nil
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.prune()
+	return i.short("", needs)
+}
+
+// short returns the tracked SKUs of needs that have too few units free of
+// the holds of checkouts other than checkout, sorted. Callers must hold
+// i.mu.
+func (i *Inventory) short(checkout string, needs map[string]int) []string {
+	var short []string
+	for sku, quantity := range needs {
+		if level, ok := i.levels[sku]; ok && level.Quantity-i.heldExcept(sku, checkout) < quantity {
+			short = append(short, sku)
+		}
+	}
+	sort.Strings(short)
+	return short
+}
+
+// Restock returns units taken for an order that was not placed
+func (i *Inventory) Restock(needs map[string]int) {
+	if i == nil {
+		return
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for sku, quantity := range needs {
+		if level, ok := i.levels[sku]; ok {
+			level.Quantity += quantity
+		}
+	}
+}
+
+// SKU returns the SKU stock of an item is counted under: the variant's SKU
+// for products with variants, otherwise the product's own, if it has one
+func SKU(product *models.Product, variantID string) string {
+	if variantID == "" {
+		return product.SKU
+	}
+	for _, variant := range product.Variants {
+		if variant.ID == variantID {
+			return variant.SKU
+		}
+	}
+	return ""
+}
+
+// Needs returns the units of each SKU items take from stock. Items without
+// a SKU are left out.
+func Needs(items []models.OrderItem, products map[string]*models.Product) map[string]int {
+	needs := make(map[string]int)
+	for _, item := range items {
+		product, ok := products[item.ProductID]
+		if !ok {
+			continue
+		}
+		if sku := SKU(product, item.VariantID); sku != "" {
+			needs[sku] += item.Quantity
+		}
+	}
+	return needs
+}
@@ -0,0 +1,88 @@
+package pos
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInventory_Sync(t *testing.T) {
+	inventory := NewInventory()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	inventory.now = func() time.Time { return now }
+
+	earlier, later := now.Add(-time.Hour), now.Add(time.Hour)
+	five, three, eight := 5, 3, 8
+	result := inventory.Sync([]LevelUpdate{
+		{SKU: "WAF-BER", Quantity: &five, UpdatedAt: &earlier},
+		{SKU: "COF-L", Quantity: &three},
+	})
+	assert.Equal(t, 2, result.Applied)
+	assert.Empty(t, result.Stale)
+	assert.Equal(t, []Level{
+		{SKU: "WAF-BER", Quantity: 5, UpdatedAt: earlier},
+		{SKU: "COF-L", Quantity: 3, UpdatedAt: now},
+	}, result.Levels)
+
+	// Replaying a sync changes nothing, even after orders took stock
+	require.Empty(t, inventory.Take(map[string]int{"WAF-BER": 2}))
+	result = inventory.Sync([]LevelUpdate{{SKU: "WAF-BER", Quantity: &five, UpdatedAt: &earlier}})
+	assert.Zero(t, result.Applied)
+	assert.Equal(t, []string{"WAF-BER"}, result.Stale)
+	assert.Equal(t, 3, result.Levels[0].Quantity)
+
+	// Newer counts replace the level
+	result = inventory.Sync([]LevelUpdate{{SKU: "WAF-BER", Quantity: &eight, UpdatedAt: &later}})
+	assert.Equal(t, 1, result.Applied)
+	assert.Equal(t, []Level{
+		{SKU: "COF-L", Quantity: 3, UpdatedAt: now},
+		{SKU: "WAF-BER", Quantity: 8, UpdatedAt: later},
+	}, inventory.List())
+}
+
+func TestInventory_Take(t *testing.T) {
+	inventory := NewInventory()
+	two, none := 2, 0
+	inventory.Sync([]LevelUpdate{{SKU: "COF-L", Quantity: &two}, {SKU: "COF-S", Quantity: &none}})
+
+	// Untracked SKUs never run out
+	assert.Empty(t, inventory.Take(map[string]int{"COF-L": 2, "TEA": 100}))
+	assert.Zero(t, inventory.List()[0].Quantity)
+
+	// Nothing is taken when any SKU is short
+	inventory.Restock(map[string]int{"COF-L": 1})
+	assert.Equal(t, []string{"COF-L", "COF-S"}, inventory.Take(map[string]int{"COF-L": 2, "COF-S": 1}))
+	assert.Equal(t, 1, inventory.List()[0].Quantity)
+
+	// Short checks without taking
+	assert.Equal(t, []string{"COF-S"}, inventory.Short(map[string]int{"COF-L": 1, "COF-S": 1}))
+	assert.Equal(t, 1, inventory.List()[0].Quantity)
+
+	var untracked *Inventory
+	assert.Empty(t, untracked.Take(map[string]int{"COF-L": 1}))
+	assert.Empty(t, untracked.Short(map[string]int{"COF-L": 1}))
+	assert.Empty(t, untracked.List())
+}
+
+func TestNeeds(t *testing.T) {
+	coffee := &models.Product{ID: "coffee", Variants: []models.ProductVariant{
+		{ID: "small", SKU: "COF-S"},
+		{ID: "large", SKU: "COF-L"},
+	}}
+	waffle := &models.Product{ID: "waffle", SKU: "WAF-BER"}
+	toast := &models.Product{ID: "toast"}
+	products := map[string]*models.Product{"coffee": coffee, "waffle": waffle, "toast": toast}
+
+	needs := Needs([]models.OrderItem{
+		{ProductID: "coffee", VariantID: "large", Quantity: 2},
+		{ProductID: "coffee", VariantID: "large", Quantity: 1, Notes: "oat milk"},
+		{ProductID: "waffle", Quantity: 1},
+		{ProductID: "toast", Quantity: 4},
+		{ProductID: "missing", Quantity: 1},
+	}, products)
+	assert.Equal(t, map[string]int{"COF-L": 3, "WAF-BER": 1}, needs)
+	assert.Empty(t, SKU(coffee, "medium"))
+}
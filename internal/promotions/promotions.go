@@ -0,0 +1,111 @@
+// Package promotions applies time-based price modifiers, such as happy hour
+// discounts and late-night surcharges, to the orders a restaurant takes.
+package promotions
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/hours"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+// Promotion is a price modifier in effect during its weekly windows
+type Promotion struct {
+	Name    string
+	Percent float64
+
+	categories map[string]bool // Lowercased; every category when empty
+	schedule   *hours.Schedule
+}
+
+// Promotions is the set of promotions of a restaurant. A nil Promotions
+// modifies no prices.
+type Promotions struct {
+	promotions []*Promotion
+}
+
+// New creates Promotions from their configuration, with windows in the
+// given time zone. No promotions yields nil.
+func New(timezone string, cfg []config.Promotion) (*Promotions, error) {
+	if len(cfg) == 0 {
+		return nil, nil
+	}
+
+	ps := &Promotions{}
+	names := make(map[string]bool)
+	for _, pc := range cfg {
+		if pc.Name == "" {
+			return nil, fmt.Errorf("promotion name is required")
+		}
+		if names[pc.Name] {
+			return nil, fmt.Errorf("duplicate promotion: %s", pc.Name)
+		}
+		names[pc.Name] = true
+
+		if pc.Percent == 0 || pc.Percent < -100 || pc.Percent > 100 {
+			return nil, fmt.Errorf("promotion %s: percent must be between -100 and 100, and not 0", pc.Name)
+		}
+		if len(pc.Weekly) == 0 {
+			return nil, fmt.Errorf("promotion %s: weekly is required", pc.Name)
+		}
+		schedule, err := hours.New(timezone, pc.Weekly, 0)
+		if err != nil {
+			return nil, fmt.Errorf("promotion %s: %w", pc.Name, err)
+		}
+
+		promotion := &Promotion{
+			Name:       pc.Name,
+			Percent:    pc.Percent,
+			categories: make(map[string]bool, len(pc.Categories)),
+			schedule:   schedule,
+		}
+		for _, category := range pc.Categories {
+			promotion.categories[strings.ToLower(category)] = true
+		}
+		ps.promotions = append(ps.promotions, promotion)
+	}
+
+	return ps, nil
+}
+
+// Adjustments returns the promotions in effect at t that apply to items,
+// in configuration order, each with the amount it adds to the price of the
+// items it covers. products holds the product of each item, priced at
+// item.Price. Promotions do not compound: each is a percentage of the
+// undiscounted prices.
+func (ps *Promotions) Adjustments(t time.Time, items []models.OrderItem, products []models.Product) []models.PriceAdjustment {
+	if ps == nil {
+		return nil
+	}
+
+	var adjustments []models.PriceAdjustment
+	for _, p := range ps.promotions {
+		if !p.schedule.IsOpen(t) {
+			continue
+		}
+		var base float64
+		for i, item := range items {
+			if p.covers(products[i]) {
+				base += item.Price * float64(item.Quantity)
+			}
+		}
+		if base == 0 {
+			continue
+		}
+		adjustments = append(adjustments, models.PriceAdjustment{
+			Name:    p.Name,
+			Percent: p.Percent,
+			Amount:  math.Round(base*p.Percent) / 100,
+		})
+	}
+	return adjustments
+}
+
+// covers reports whether the promotion applies to product
+func (p *Promotion) covers(product models.Product) bool {
+	return len(p.categories) == 0 || p.categories[strings.ToLower(product.Category)]
+}
@@ -0,0 +1,90 @@
+package promotions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	weekly := map[string][]string{"friday": {"17:00-19:00"}}
+	tests := []struct {
+		name       string
+		promotions []config.Promotion
+		wantErr    bool
+		wantNil    bool
+	}{
+		{name: "no promotions", wantNil: true},
+		{name: "discount", promotions: []config.Promotion{{Name: "happy-hour", Percent: -20, Weekly: weekly}}},
+		{name: "missing name", promotions: []config.Promotion{{Percent: -20, Weekly: weekly}}, wantErr: true},
+		{name: "duplicate name", promotions: []config.Promotion{{Name: "a", Percent: 5, Weekly: weekly}, {Name: "a", Percent: 5, Weekly: weekly}}, wantErr: true},
+		{name: "zero percent", promotions: []config.Promotion{{Name: "none", Weekly: weekly}}, wantErr: true},
+		{name: "over a full discount", promotions: []config.Promotion{{Name: "free", Percent: -150, Weekly: weekly}}, wantErr: true},
+		{name: "no windows", promotions: []config.Promotion{{Name: "always", Percent: 5}}, wantErr: true},
+		{name: "invalid window", promotions: []config.Promotion{{Name: "late", Percent: 5, Weekly: map[string][]string{"friday": {"late"}}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			promotions, err := New("Australia/Sydney", tt.promotions)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantNil, promotions == nil)
+		})
+	}
+}
+
+func TestPromotions_Adjustments(t *testing.T) {
+	promotions, err := New("Australia/Sydney", []config.Promotion{
+		{Name: "happy-hour", Percent: -20, Categories: []string{"Drinks"}, Weekly: map[string][]string{"friday": {"17:00-19:00"}}},
+		{Name: "late-night", Percent: 10, Weekly: map[string][]string{"friday": {"22:00-02:00"}}},
+	})
+	require.NoError(t, err)
+
+	sydney, err := time.LoadLocation("Australia/Sydney")
+	require.NoError(t, err)
+	items := []models.OrderItem{
+		{ProductID: "beer", Quantity: 2, Price: 9},
+		{ProductID: "chips", Quantity: 1, Price: 6.5},
+	}
+	products := []models.Product{{ID: "beer", Category: "drinks"}, {ID: "chips", Category: "Sides"}}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want []models.PriceAdjustment
+	}{
+		{name: "outside every window", at: time.Date(2024, 1, 5, 12, 0, 0, 0, sydney)},
+		{
+			name: "discount on matching categories",
+			at:   time.Date(2024, 1, 5, 17, 30, 0, 0, sydney),
+			want: []models.PriceAdjustment{{Name: "happy-hour", Percent: -20, Amount: -3.6}},
+		},
+		{
+			name: "surcharge past midnight",
+			at:   time.Date(2024, 1, 6, 1, 0, 0, 0, sydney),
+			want: []models.PriceAdjustment{{Name: "late-night", Percent: 10, Amount: 2.45}},
+		},
+		{name: "windows in the restaurant's time zone", at: time.Date(2024, 1, 5, 17, 30, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, promotions.Adjustments(tt.at, items, products))
+		})
+	}
+
+	// Promotions covering none of the items are left out
+	happyHour := time.Date(2024, 1, 5, 17, 30, 0, 0, sydney)
+	assert.Empty(t, promotions.Adjustments(happyHour, items[1:], products[1:]))
+
+	var none *Promotions
+	assert.Nil(t, none.Adjustments(happyHour, items, products))
+}
@@ -0,0 +1,34 @@
+// Package respond writes the API's JSON responses, so every handler and
+// middleware sends the same Content-Type, writes its status once and gives
+// each error the status of its code.
+package respond
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/logging"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+// JSON writes v as the response body with status. A response whose body
+// has already been started is left as it is, as its status and headers can
+// no longer change, and the dropped response is logged.
+func JSON(c *gin.Context, status int, v any) {
+	if c.Writer.Written() {
+		logging.Sampled("response-dropped", "Response to %s %s already started; dropped a %d response", c.Request.Method, c.Request.URL.Path, status)
+		return
+	}
+	c.JSON(status, v)
+}
+
+// Error writes err with the status of its code
+func Error(c *gin.Context, err *models.ErrorResponse) {
+	JSON(c, apierrors.Status(err.Code), err)
+}
+
+// Abort writes err like Error and stops the handlers after the caller, for
+// middleware refusing a request
+func Abort(c *gin.Context, err *models.ErrorResponse) {
+	c.Abort()
+	Error(c, err)
+}
@@ -0,0 +1,73 @@
+package respond
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRespond(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		handler        gin.HandlerFunc
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "json",
+			handler:        func(c *gin.Context) { JSON(c, http.StatusCreated, map[string]string{"id": "1"}) },
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"id":"1"}`,
+		},
+		{
+			name:           "error takes the status of its code",
+			handler:        func(c *gin.Context) { Error(c, apierrors.New(apierrors.NotFound, "Product not found")) },
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"code":"NOT_FOUND","message":"Product not found"}`,
+		},
+		{
+			name: "started response is kept",
+			handler: func(c *gin.Context) {
+				c.Header("Content-Type", "application/json; charset=utf-8")
+				c.String(http.StatusOK, `{"partial":true}`)
+				Error(c, apierrors.New(apierrors.InternalError, "Failed to write"))
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"partial":true}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := gin.New()
+			engine.GET("/", tt.handler)
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+			assert.JSONEq(t, tt.expectedBody, rec.Body.String())
+		})
+	}
+}
+
+func TestAbort(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	reached := false
+	engine.GET("/", func(c *gin.Context) {
+		Abort(c, apierrors.New(apierrors.Forbidden, "Access denied"))
+	}, func(c *gin.Context) { reached = true })
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.False(t, reached)
+}
@@ -0,0 +1,177 @@
+// Package reviews collects customer reviews of products and the ratings
+// they add up to.
+package reviews
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+var (
+	// ErrNotPurchased is returned when the cited order did not include the product
+	ErrNotPurchased = errors.New("product was not purchased in this order")
+	// ErrAlreadyReviewed is returned when the product was already reviewed for the order
+	ErrAlreadyReviewed = errors.New("product already reviewed for this order")
+	// ErrNotFound is returned when a review does not exist
+	ErrNotFound = errors.New("review not found")
+	// ErrInvalidStatus is returned when moderating to a status other than approved or rejected
+	ErrInvalidStatus = errors.New("invalid review status")
+)
+
+// purchaseKey identifies a product bought in an order
+type purchaseKey struct {
+	orderID   string
+	productID string
+}
+
+// Store holds the reviews of a restaurant and the purchases they can be
+// written for. A nil Store has no purchases and no reviews.
+type Store struct {
+	mu        sync.RWMutex
+	purchases map[purchaseKey]bool // true once reviewed
+	reviews   map[string]*models.Review
+	now       func() time.Time
+}
+
+// NewStore creates a new, empty Store
+func NewStore() *Store {
+	return &Store{
+		purchases: make(map[purchaseKey]bool),
+		reviews:   make(map[string]*models.Review),
+		now:       time.Now,
+	}
+}
+
+// RecordPurchase makes every product in order reviewable against the order
+func (s *Store) RecordPurchase(order *models.Order) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, item := range order.Items {
+		key := purchaseKey{orderID: order.ID, productID: item.ProductID}
+		if _, ok := s.purchases[key]; !ok {
+			s.purchases[key] = false
+		}
+	}
+}
+
+// Submit records a pending review of productID. The product must have been
+// bought in the cited order, and each order reviews a product only once.
+func (s *Store) Submit(productID string, req *models.ReviewRequest) (*models.Review, error) {
+	if s == nil {
+		return nil, ErrNotPurchased
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := purchaseKey{orderID: req.OrderID, productID: productID}
+	reviewed, ok := s.purchases[key]
+	if !ok {
+		return nil, ErrNotPurchased
+	}
+	if reviewed {
+		return nil, ErrAlreadyReviewed
+	}
+
+	now := s.now()
+	review := &models.Review{
+		ID:        fmt.Sprintf("review-%s", uuid.New().String()),
+		ProductID: productID,
+		OrderID:   req.OrderID,
+		Rating:    req.Rating,
+		Text:      req.Text,
+		Status:    models.ReviewPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.purchases[key] = true
+	s.reviews[review.ID] = review
+
+	out := *review
+	return &out, nil
+}
+
+// Moderate sets the status of a review to approved or rejected
+func (s *Store) Moderate(id, status string) (*models.Review, error) {
+	if status != models.ReviewApproved && status != models.ReviewRejected {
+		return nil, ErrInvalidStatus
+	}
+	if s == nil {
+		return nil, ErrNotFound
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	review, ok := s.reviews[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	review.Status = status
+	review.UpdatedAt = s.now()
+
+	out := *review
+	return &out, nil
+}
+
+// List returns the reviews with the given status, oldest first. An empty
+// productID lists reviews of every product.
+func (s *Store) List(productID, status string) []models.Review {
+	if s == nil {
+		return []models.Review{}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]models.Review, 0)
+	for _, review := range s.reviews {
+		if review.Status != status || (productID != "" && review.ProductID != productID) {
+			continue
+		}
+		out = append(out, *review)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// Rating returns the aggregate of a product's approved reviews, or nil when
+// it has none
+func (s *Store) Rating(productID string) *models.Rating {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var sum, count int
+	for _, review := range s.reviews {
+		if review.ProductID == productID && review.Status == models.ReviewApproved {
+			sum += review.Rating
+			count++
+		}
+	}
+	if count == 0 {
+		return nil
+	}
+	return &models.Rating{
+		Average: float64(sum) / float64(count),
+		Count:   count,
+	}
+}
@@ -0,0 +1,98 @@
+package reviews
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newOrder(id string, productIDs ...string) *models.Order {
+	order := &models.Order{ID: id}
+	for _, productID := range productIDs {
+		order.Items = append(order.Items, models.OrderItem{ProductID: productID, Quantity: 1})
+	}
+	return order
+}
+
+func TestStore_Submit(t *testing.T) {
+	s := NewStore()
+	s.RecordPurchase(newOrder("order-1", "prod-1", "prod-2"))
+
+	review, err := s.Submit("prod-1", &models.ReviewRequest{OrderID: "order-1", Rating: 4, Text: "Good"})
+	require.NoError(t, err)
+	assert.Equal(t, "prod-1", review.ProductID)
+	assert.Equal(t, "order-1", review.OrderID)
+	assert.Equal(t, models.ReviewPending, review.Status)
+
+	// One review per product per order
+	_, err = s.Submit("prod-1", &models.ReviewRequest{OrderID: "order-1", Rating: 5})
+	assert.ErrorIs(t, err, ErrAlreadyReviewed)
+
+	// Only products bought in the order can be reviewed
+	_, err = s.Submit("prod-3", &models.ReviewRequest{OrderID: "order-1", Rating: 5})
+	assert.ErrorIs(t, err, ErrNotPurchased)
+	_, err = s.Submit("prod-1", &models.ReviewRequest{OrderID: "order-2", Rating: 5})
+	assert.ErrorIs(t, err, ErrNotPurchased)
+
+	// Recording the same order again does not reopen reviewed products
+	s.RecordPurchase(newOrder("order-1", "prod-1"))
+	_, err = s.Submit("prod-1", &models.ReviewRequest{OrderID: "order-1", Rating: 5})
+	assert.ErrorIs(t, err, ErrAlreadyReviewed)
+}
+
+func TestStore_Moderation(t *testing.T) {
+	s := NewStore()
+	clock := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time {
+		clock = clock.Add(time.Minute)
+		return clock
+	}
+	s.RecordPurchase(newOrder("order-1", "prod-1"))
+	s.RecordPurchase(newOrder("order-2", "prod-1"))
+	s.RecordPurchase(newOrder("order-3", "prod-1"))
+
+	var ids []string
+	for i, rating := range []int{5, 2, 1} {
+		review, err := s.Submit("prod-1", &models.ReviewRequest{OrderID: fmt.Sprintf("order-%d", i+1), Rating: rating})
+		require.NoError(t, err)
+		ids = append(ids, review.ID)
+	}
+
+	// Pending reviews do not count towards the rating
+	assert.Nil(t, s.Rating("prod-1"))
+	assert.Len(t, s.List("", models.ReviewPending), 3)
+	assert.Empty(t, s.List("prod-1", models.ReviewApproved))
+
+	_, err := s.Moderate(ids[0], models.ReviewApproved)
+	require.NoError(t, err)
+	_, err = s.Moderate(ids[1], models.ReviewApproved)
+	require.NoError(t, err)
+	rejected, err := s.Moderate(ids[2], models.ReviewRejected)
+	require.NoError(t, err)
+	assert.Equal(t, models.ReviewRejected, rejected.Status)
+	assert.True(t, rejected.UpdatedAt.After(rejected.CreatedAt))
+
+	assert.Equal(t, &models.Rating{Average: 3.5, Count: 2}, s.Rating("prod-1"))
+	approved := s.List("prod-1", models.ReviewApproved)
+	require.Len(t, approved, 2)
+	assert.Equal(t, ids[0], approved[0].ID, "oldest first")
+	assert.Empty(t, s.List("prod-2", models.ReviewApproved))
+
+	_, err = s.Moderate("review-missing", models.ReviewApproved)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = s.Moderate(ids[0], models.ReviewPending)
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+}
+
+func TestStore_Nil(t *testing.T) {
+	var s *Store
+	s.RecordPurchase(newOrder("order-1", "prod-1"))
+	_, err := s.Submit("prod-1", &models.ReviewRequest{OrderID: "order-1", Rating: 5})
+	assert.ErrorIs(t, err, ErrNotPurchased)
+	assert.Nil(t, s.Rating("prod-1"))
+	assert.Empty(t, s.List("", models.ReviewPending))
+}
@@ -0,0 +1,491 @@
+package router_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/apikeys"
+	"github.com/ravibandhu/oolio-food-ordering/internal/auth"
+	"github.com/ravibandhu/oolio-food-ordering/internal/blocklist"
+	"github.com/ravibandhu/oolio-food-ordering/internal/carts"
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/dashboard"
+	"github.com/ravibandhu/oolio-food-ordering/internal/data"
+	"github.com/ravibandhu/oolio-food-ordering/internal/handlers"
+	"github.com/ravibandhu/oolio-food-ordering/internal/invoices"
+	"github.com/ravibandhu/oolio-food-ordering/internal/kitchen"
+	"github.com/ravibandhu/oolio-food-ordering/internal/maintenance"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/orders"
+	"github.com/ravibandhu/oolio-food-ordering/internal/payments"
+	"github.com/ravibandhu/oolio-food-ordering/internal/pos"
+	"github.com/ravibandhu/oolio-food-ordering/internal/split"
+	"github.com/ravibandhu/oolio-food-ordering/internal/startup"
+	"github.com/ravibandhu/oolio-food-ordering/internal/testutil/testserver"
+	"github.com/ravibandhu/oolio-food-ordering/internal/usage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// operationSpec is one documented operation, parsed from the swag annotations
+// the OpenAPI document is generated from
+type operationSpec struct {
+	method    string
+	path      string // OpenAPI template, e.g. /products/{id}
+	pattern   *regexp.Regexp
+	responses map[int]responseSpec
+}
+
+// responseSpec is a documented response: its schema kind and type name
+type responseSpec struct {
+	array    bool
+	typeName string
+}
+
+var (
+	routeAnnotation    = regexp.MustCompile(`^@Router\s+(\S+)\s+\[(\w+)\]`)
+	responseAnnotation = regexp.MustCompile(`^@(?:Success|Failure)\s+(\d{3})(?:\s+\{(\w+)\}\s+(\S+))?`)
+	pathParam          = regexp.MustCompile(`\{[^/]+\}`)
+)
+
+// schemaTypes maps documented type names to constructors used to decode
+// response bodies strictly
+var schemaTypes = map[string]func() interface{}{
+	"models.Product":        func() interface{} { return &models.Product{} },
+	"models.Order":          func() interface{} { return &models.Order{} },
+	"models.ErrorResponse":  func() interface{} { return &models.ErrorResponse{} },
+	"CouponCheckResponse":   func() interface{} { return &handlers.CouponCheckResponse{} },
+	"ProductErrorsResponse": func() interface{} { return &handlers.ProductErrorsResponse{} },
+	"OrderListResponse":     func() interface{} { return &handlers.OrderListResponse{} },
+	"models.Review":         func() interface{} { return &models.Review{} },
+	"kitchen.Estimate":      func() interface{} { return &kitchen.Estimate{} },
+	"models.StatusChange":   func() interface{} { return &models.StatusChange{} },
+	"kitchen.Ticket":        func() interface{} { return &kitchen.Ticket{} },
+	"map[string]string":     func() interface{} { return &map[string]string{} },
+	"data.Manifest":         func() interface{} { return &data.Manifest{} },
+	"blocklist.Entry":       func() interface{} { return &blocklist.Entry{} },
+	"blocklist.AuditEvent":  func() interface{} { return &blocklist.AuditEvent{} },
+	"auth.Session":          func() interface{} { return &auth.Session{} },
+	"auth.Customer":         func() interface{} { return &auth.Customer{} },
+	"usage.Report":          func() interface{} { return &usage.Report{} },
+	"apikeys.Key":           func() interface{} { return &apikeys.Key{} },
+	"apikeys.Secret":        func() interface{} { return &apikeys.Secret{} },
+	"pos.Level":             func() interface{} { return &pos.Level{} },
+	"pos.InventoryResult":   func() interface{} { return &pos.InventoryResult{} },
+	"pos.ExportPage":        func() interface{} { return &pos.ExportPage{} },
+	"invoices.Status":       func() interface{} { return &invoices.Status{} },
+	"payments.Report":       func() interface{} { return &payments.Report{} },
+	"orders.History":        func() interface{} { return &orders.History{} },
+	"dashboard.Summary":     func() interface{} { return &dashboard.Summary{} },
+	"startup.Report":        func() interface{} { return &startup.Report{} },
+	"data.ProductsCheck":    func() interface{} { return &data.ProductsCheck{} },
+	"RoutesResponse":        func() interface{} { return &handlers.RoutesResponse{} },
+	"MaintenanceResponse":   func() interface{} { return &handlers.MaintenanceResponse{} },
+	"maintenance.Window":    func() interface{} { return &maintenance.Window{} },
+	"CreatedCart":           func() interface{} { return &handlers.CreatedCart{} },
+	"carts.Cart":            func() interface{} { return &carts.Cart{} },
+	"Receipt":               func() interface{} { return &handlers.Receipt{} },
+	"split.Split":           func() interface{} { return &split.Split{} },
+	"kitchen.TableTickets":  func() interface{} { return &kitchen.TableTickets{} },
+	"TableToken":            func() interface{} { return &handlers.TableToken{} },
+	"SLOsResponse":          func() interface{} { return &handlers.SLOsResponse{} },
+	"data.CouponStats":      func() interface{} { return &data.CouponStats{} },
+	"data.ProductStats":     func() interface{} { return &data.ProductStats{} },
+	"data.PriceHistory":     func() interface{} { return &data.PriceHistory{} },
+}
+
+// loadOperationSpecs parses the swag annotations of every handler
+func loadOperationSpecs(t *testing.T) []*operationSpec {
+	files, err := filepath.Glob(filepath.Join("..", "handlers", "*.go"))
+	require.NoError(t, err)
+
+	var specs []*operationSpec
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		parsed, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+		require.NoError(t, err)
+
+		for _, decl := range parsed.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Doc == nil {
+				continue
+			}
+			if spec := parseOperation(t, fn.Doc); spec != nil {
+				specs = append(specs, spec)
+			}
+		}
+	}
+	require.NotEmpty(t, specs, "no annotated operations found")
+	return specs
+}
+
+// parseOperation extracts an operationSpec from a handler doc comment
+func parseOperation(t *testing.T, doc *ast.CommentGroup) *operationSpec {
+	spec := &operationSpec{responses: make(map[int]responseSpec)}
+	for _, comment := range doc.List {
+		line := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+		if m := routeAnnotation.FindStringSubmatch(line); m != nil {
+			spec.path = m[1]
+			spec.method = strings.ToUpper(m[2])
+			spec.pattern = pathPattern(m[1])
+		}
+		if m := responseAnnotation.FindStringSubmatch(line); m != nil {
+			status, err := strconv.Atoi(m[1])
+			require.NoError(t, err)
+			spec.responses[status] = responseSpec{array: m[2] == "array", typeName: m[3]}
+		}
+	}
+	if spec.path == "" {
+		return nil
+	}
+	return spec
+}
+
+// pathPattern converts an OpenAPI path template into a regular expression
+func pathPattern(template string) *regexp.Regexp {
+	segments := strings.Split(template, "/")
+	for i, segment := range segments {
+		if pathParam.MatchString(segment) {
+			segments[i] = `[^/]+`
+		} else {
+			segments[i] = regexp.QuoteMeta(segment)
+		}
+	}
+	return regexp.MustCompile("^" + strings.Join(segments, "/") + "$")
+}
+
+// findOperation returns the documented operation matching a request. A
+// static path wins over one with parameters, as it does in gin.
+func findOperation(specs []*operationSpec, method, path string) *operationSpec {
+	path, _, _ = strings.Cut(path, "?")
+	var found *operationSpec
+	for _, spec := range specs {
+		if spec.method != method || !spec.pattern.MatchString(path) {
+			continue
+		}
+		if spec.path == path {
+			return spec
+		}
+		if found == nil {
+			found = spec
+		}
+	}
+	return found
+}
+
+func TestContract_DocumentedRoutesAreRegistered(t *testing.T) {
+	srv := testserver.New(t)
+
+	registered := make(map[string]bool)
+	for _, route := range srv.Router.Engine().Routes() {
+		registered[route.Method+" "+route.Path] = true
+	}
+
+	for _, spec := range loadOperationSpecs(t) {
+		ginPath := pathParam.ReplaceAllStringFunc(spec.path, func(param string) string {
+			return ":" + strings.Trim(param, "{}")
+		})
+		assert.True(t, registered[spec.method+" "+ginPath],
+			"documented operation %s %s is not registered on the router", spec.method, spec.path)
+	}
+}
+
+func TestContract_ResponsesMatchDocumentation(t *testing.T) {
+	srv := testserver.New(t, func(cfg *config.Config) {
+		cfg.Orders.TableSecret = "0123456789abcdef0123456789abcdef"
+		cfg.SLOs = []config.SLO{{Group: "ordering", Availability: 0.999, Latency: time.Second, LatencyTarget: 0.99, Window: config.DefaultSLOWindow}}
+	})
+	specs := loadOperationSpecs(t)
+
+	newProduct := `{"id":"prod-3","name":"Test Product 3","price":4.5,"category":"Test Category","image":{` +
+		`"thumbnail":"https://example.com/t.jpg","mobile":"https://example.com/m.jpg",` +
+		`"tablet":"https://example.com/t.jpg","desktop":"https://example.com/d.jpg"}}`
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		body        string
+		contentType string
+		ifMatch     string
+		auth        bool
+		apiKey      string // Authenticates with the API key of another role
+	}{
+		{name: "list products", method: http.MethodGet, path: "/products"},
+		{name: "list products filtered", method: http.MethodGet, path: "/products?dietary=vegan&max_calories=500"},
+		{name: "list products with unknown filter", method: http.MethodGet, path: "/products?dietary=paleo"},
+		{name: "get product", method: http.MethodGet, path: "/products/prod-1"},
+		{name: "get missing product", method: http.MethodGet, path: "/products/missing"},
+		{name: "place order", method: http.MethodPost, path: "/orders", body: `{"items":[{"productId":"prod-1","quantity":2}]}`},
+		{name: "place order dry run", method: http.MethodPost, path: "/orders?dry_run=true", body: `{"items":[{"productId":"prod-1","quantity":2}]}`},
+		{name: "place order with invalid dry run", method: http.MethodPost, path: "/orders?dry_run=maybe", body: `{"items":[{"productId":"prod-1","quantity":2}]}`},
+		{name: "place order with notes", method: http.MethodPost, path: "/orders", body: `{"items":[{"productId":"prod-1","quantity":1,"notes":"no onions"}],"notes":"leave at door"}`},
+		{name: "place order with long note", method: http.MethodPost, path: "/orders", body: `{"items":[{"productId":"prod-1","quantity":1,"notes":"` + strings.Repeat("x", 141) + `"}]}`},
+		{name: "place order with unknown product", method: http.MethodPost, path: "/orders", body: `{"items":[{"productId":"missing","quantity":1}]}`},
+		{name: "place order with stale price", method: http.MethodPost, path: "/orders", body: `{"items":[{"productId":"prod-1","quantity":1,"price":1.5}]}`},
+		{name: "place order with unknown variant", method: http.MethodPost, path: "/orders", body: `{"items":[{"productId":"prod-1","variantId":"large","quantity":1}]}`},
+		{name: "place order with unknown field", method: http.MethodPost, path: "/orders", body: `{"items":[{"productId":"prod-1","quantity":1}],"tip":5}`},
+		{name: "place order too large", method: http.MethodPost, path: "/orders", body: `{"items":[],"couponCode":"` + strings.Repeat("x", 2<<20) + `"}`},
+		{name: "place order as form", method: http.MethodPost, path: "/orders", body: "items=1", contentType: "application/x-www-form-urlencoded"},
+		{name: "place order malformed", method: http.MethodPost, path: "/orders", body: `{"items":`},
+		{name: "place order invalid", method: http.MethodPost, path: "/orders", body: `{"items":[{"productId":"prod-1","quantity":0}]}`},
+		{name: "place order for a business exempt from tax", method: http.MethodPost, path: "/orders", body: `{"items":[{"productId":"prod-1","quantity":1}],"billing":{"companyName":"Acme","country":"AU","taxId":"51824753556","taxExempt":true,"exemptionReason":"Registered charity"}}`},
+		{name: "place order with invalid tax id", method: http.MethodPost, path: "/orders", body: `{"items":[{"productId":"prod-1","quantity":1}],"billing":{"companyName":"Acme","country":"AU","taxId":"123"}}`},
+		{name: "place order paid by card", method: http.MethodPost, path: "/orders", body: `{"items":[{"productId":"prod-1","quantity":1}],"payment":{"method":"card","cardLast4":"4242"}}`},
+		{name: "place order with unknown payment method", method: http.MethodPost, path: "/orders", body: `{"items":[{"productId":"prod-1","quantity":1}],"payment":{"method":"cheque"}}`},
+		{name: "place order with cash short of the total", method: http.MethodPost, path: "/orders", body: `{"items":[{"productId":"prod-1","quantity":1}],"payment":{"method":"cash","cashTendered":1}}`},
+		{name: "place order exempt without reason", method: http.MethodPost, path: "/orders", body: `{"items":[{"productId":"prod-1","quantity":1}],"billing":{"companyName":"Acme","country":"AU","taxId":"51824753556","taxExempt":true}}`},
+		{name: "create product unauthenticated", method: http.MethodPost, path: "/products", body: newProduct},
+		{name: "create product", method: http.MethodPost, path: "/products", body: newProduct, auth: true},
+		{name: "create duplicate product", method: http.MethodPost, path: "/products", body: newProduct, auth: true},
+		{name: "create invalid product", method: http.MethodPost, path: "/products", body: `{"id":"prod-4"}`, auth: true},
+		{name: "update product unauthenticated", method: http.MethodPut, path: "/products/prod-3", body: newProduct},
+		{name: "update product", method: http.MethodPut, path: "/products/prod-3", body: newProduct, auth: true},
+		{name: "update product with stale etag", method: http.MethodPut, path: "/products/prod-3", body: newProduct, ifMatch: `"stale"`, auth: true},
+		{name: "update unknown product", method: http.MethodPut, path: "/products/missing", body: `{"id":"missing"}`, auth: true},
+		{name: "delete product unauthenticated", method: http.MethodDelete, path: "/products/prod-3"},
+		{name: "delete product", method: http.MethodDelete, path: "/products/prod-3", auth: true},
+		{name: "delete deleted product", method: http.MethodDelete, path: "/products/prod-3", auth: true},
+		{name: "get deleted product", method: http.MethodGet, path: "/products/prod-3"},
+		{name: "list products including deleted", method: http.MethodGet, path: "/products?include_deleted=true", auth: true},
+		{name: "list products including deleted unauthenticated", method: http.MethodGet, path: "/products?include_deleted=true"},
+		{name: "check coupon", method: http.MethodGet, path: "/admin/coupons/UNKNOWN1", auth: true},
+		{name: "check coupon unauthenticated", method: http.MethodGet, path: "/admin/coupons/UNKNOWN1"},
+		{name: "coupon stats", method: http.MethodGet, path: "/admin/coupons/stats", auth: true},
+		{name: "coupon stats as support", method: http.MethodGet, path: "/admin/coupons/stats", apiKey: testserver.SupportAPIKey},
+		{name: "product stats", method: http.MethodGet, path: "/admin/products/stats", auth: true},
+		{name: "product stats as support", method: http.MethodGet, path: "/admin/products/stats", apiKey: testserver.SupportAPIKey},
+		{name: "price history", method: http.MethodGet, path: "/admin/products/prod-1/price-history", auth: true},
+		{name: "price history of unknown product", method: http.MethodGet, path: "/admin/products/missing/price-history", auth: true},
+		{name: "price history as support", method: http.MethodGet, path: "/admin/products/prod-1/price-history", apiKey: testserver.SupportAPIKey},
+		{name: "reload", method: http.MethodPost, path: "/admin/reload", auth: true},
+		{name: "order dashboard", method: http.MethodGet, path: "/admin/dashboard/orders", auth: true},
+		{name: "order dashboard over a day", method: http.MethodGet, path: "/admin/dashboard/orders?hours=24", apiKey: testserver.SupportAPIKey},
+		{name: "order dashboard over too many hours", method: http.MethodGet, path: "/admin/dashboard/orders?hours=1000", auth: true},
+		{name: "order dashboard as kitchen", method: http.MethodGet, path: "/admin/dashboard/orders", apiKey: testserver.KitchenAPIKey},
+		{name: "list orders", method: http.MethodGet, path: "/admin/orders?limit=10", auth: true},
+		{name: "list orders with invalid cursor", method: http.MethodGet, path: "/admin/orders?cursor=bogus", auth: true},
+		{name: "list orders as support", method: http.MethodGet, path: "/admin/orders", apiKey: testserver.SupportAPIKey},
+		{name: "list orders as kitchen", method: http.MethodGet, path: "/admin/orders", apiKey: testserver.KitchenAPIKey},
+		{name: "list products a page at a time", method: http.MethodGet, path: "/products?limit=1"},
+		{name: "list products with invalid limit", method: http.MethodGet, path: "/products?limit=0"},
+		{name: "list products sorted", method: http.MethodGet, path: "/products?sort=-price,name"},
+		{name: "list products with unknown sort", method: http.MethodGet, path: "/products?sort=colour"},
+		{name: "list orders sorted", method: http.MethodGet, path: "/admin/orders?sort=-total_amount,created_at", auth: true},
+		{name: "list orders with unknown sort", method: http.MethodGet, path: "/admin/orders?sort=customer", auth: true},
+		{name: "order history of unknown order", method: http.MethodGet, path: "/admin/orders/missing", auth: true},
+		{name: "order history as support", method: http.MethodGet, path: "/admin/orders/missing", apiKey: testserver.SupportAPIKey},
+		{name: "order history as kitchen", method: http.MethodGet, path: "/admin/orders/missing", apiKey: testserver.KitchenAPIKey},
+		{name: "order history unauthenticated", method: http.MethodGet, path: "/admin/orders/missing"},
+		{name: "payment report", method: http.MethodGet, path: "/admin/reports/payments?from=2024-01-01", auth: true},
+		{name: "payment report with invalid range", method: http.MethodGet, path: "/admin/reports/payments?to=soon", auth: true},
+		{name: "payment report as support", method: http.MethodGet, path: "/admin/reports/payments", apiKey: testserver.SupportAPIKey},
+		{name: "accounting export in unknown format", method: http.MethodGet, path: "/admin/reports/accounting?format=sage", auth: true},
+		{name: "accounting export with invalid range", method: http.MethodGet, path: "/admin/reports/accounting?format=quickbooks&to=soon", auth: true},
+		{name: "accounting export as support", method: http.MethodGet, path: "/admin/reports/accounting?format=xero", apiKey: testserver.SupportAPIKey},
+		{name: "invoice sequence", method: http.MethodGet, path: "/admin/invoices", auth: true},
+		{name: "invoice sequence as support", method: http.MethodGet, path: "/admin/invoices", apiKey: testserver.SupportAPIKey},
+		{name: "startup report", method: http.MethodGet, path: "/admin/startup", auth: true},
+		{name: "invalid products", method: http.MethodGet, path: "/admin/products/errors", auth: true},
+		{name: "invalid products as support", method: http.MethodGet, path: "/admin/products/errors", apiKey: testserver.SupportAPIKey},
+		{name: "validate products file", method: http.MethodPost, path: "/admin/products/validate", body: `{"schema_version":2,"products":[` + newProduct + `,{"id":"bad"}]}`, auth: true},
+		{name: "validate products file of newer version", method: http.MethodPost, path: "/admin/products/validate", body: `{"schema_version":99,"products":[]}`, auth: true},
+		{name: "validate malformed products file", method: http.MethodPost, path: "/admin/products/validate", body: `[{`, auth: true},
+		{name: "validate products file as support", method: http.MethodPost, path: "/admin/products/validate", body: `[]`, apiKey: testserver.SupportAPIKey},
+		{name: "startup report as support", method: http.MethodGet, path: "/admin/startup", apiKey: testserver.SupportAPIKey},
+		{name: "routing table", method: http.MethodGet, path: "/admin/routes", auth: true},
+		{name: "routing table as support", method: http.MethodGet, path: "/admin/routes", apiKey: testserver.SupportAPIKey},
+		{name: "slos", method: http.MethodGet, path: "/admin/slos", auth: true},
+		{name: "slos as support", method: http.MethodGet, path: "/admin/slos", apiKey: testserver.SupportAPIKey},
+		{name: "list maintenance", method: http.MethodGet, path: "/admin/maintenance", auth: true},
+		{name: "start maintenance", method: http.MethodPut, path: "/admin/maintenance/pos", body: `{"message":"Stocktake in progress"}`, auth: true},
+		{name: "stock levels in maintenance", method: http.MethodGet, path: "/pos/inventory", auth: true},
+		{name: "end maintenance", method: http.MethodDelete, path: "/admin/maintenance/pos", auth: true},
+		{name: "end maintenance not started", method: http.MethodDelete, path: "/admin/maintenance/pos", auth: true},
+		{name: "start maintenance of unknown group", method: http.MethodPut, path: "/admin/maintenance/admin", body: `{}`, auth: true},
+		{name: "start maintenance ending in the past", method: http.MethodPut, path: "/admin/maintenance/pos", body: `{"until":"2020-01-01T00:00:00Z"}`, auth: true},
+		{name: "start maintenance as support", method: http.MethodPut, path: "/admin/maintenance/pos", body: `{}`, apiKey: testserver.SupportAPIKey},
+		{name: "restore unauthenticated", method: http.MethodPost, path: "/admin/restore", body: "archive"},
+		{name: "restore invalid archive", method: http.MethodPost, path: "/admin/restore", body: "archive", auth: true},
+		{name: "open group cart", method: http.MethodPost, path: "/carts", body: `{"name":"Jane"}`},
+		{name: "open group cart without name", method: http.MethodPost, path: "/carts", body: `{}`},
+		{name: "get unknown group cart", method: http.MethodGet, path: "/carts/missing"},
+		{name: "add to unknown group cart", method: http.MethodPost, path: "/carts/missing/items", body: `{"name":"Sam","items":[{"productId":"prod-1","quantity":1}]}`},
+		{name: "add unknown product to group cart", method: http.MethodPost, path: "/carts/missing/items", body: `{"name":"Sam","items":[{"productId":"missing","quantity":1}]}`},
+		{name: "add nothing to group cart", method: http.MethodPost, path: "/carts/missing/items", body: `{"name":"Sam","items":[]}`},
+		{name: "remove from unknown group cart", method: http.MethodDelete, path: "/carts/missing/items/missing"},
+		{name: "check out unknown group cart", method: http.MethodPost, path: "/carts/missing/checkout", body: `{}`},
+		{name: "eta of unknown order", method: http.MethodGet, path: "/orders/missing/eta"},
+		{name: "split unknown order", method: http.MethodPost, path: "/orders/missing/split", body: `{"mode":"even","people":2}`},
+		{name: "split with unknown mode", method: http.MethodPost, path: "/orders/missing/split", body: `{"mode":"random"}`},
+		{name: "timeline of unknown order", method: http.MethodGet, path: "/orders/missing/timeline"},
+		{name: "tracking of unknown order", method: http.MethodGet, path: "/orders/missing/tracking"},
+		{name: "assign courier to unknown order", method: http.MethodPut, path: "/admin/orders/missing/courier", body: `{"id":"courier-7","name":"Sam"}`, auth: true},
+		{name: "assign courier without id", method: http.MethodPut, path: "/admin/orders/missing/courier", body: `{"name":"Sam"}`, auth: true},
+		{name: "assign courier unauthenticated", method: http.MethodPut, path: "/admin/orders/missing/courier", body: `{"id":"courier-7"}`},
+		{name: "assign courier as support", method: http.MethodPut, path: "/admin/orders/missing/courier", body: `{"id":"courier-7"}`, apiKey: testserver.SupportAPIKey},
+		{name: "move unknown delivery on", method: http.MethodPost, path: "/courier/orders/missing/status", body: `{"status":"picked_up"}`, auth: true},
+		{name: "move delivery to unknown status", method: http.MethodPost, path: "/courier/orders/missing/status", body: `{"status":"lost"}`, auth: true},
+		{name: "move delivery on malformed", method: http.MethodPost, path: "/courier/orders/missing/status", body: `{"status":`, auth: true},
+		{name: "move delivery on as kitchen", method: http.MethodPost, path: "/courier/orders/missing/status", body: `{"status":"picked_up"}`, apiKey: testserver.KitchenAPIKey},
+		{name: "locate unknown delivery", method: http.MethodPut, path: "/courier/orders/missing/location", body: `{"latitude":-33.8688,"longitude":151.2093}`, auth: true},
+		{name: "locate delivery out of range", method: http.MethodPut, path: "/courier/orders/missing/location", body: `{"latitude":91,"longitude":0}`, auth: true},
+		{name: "locate delivery unauthenticated", method: http.MethodPut, path: "/courier/orders/missing/location", body: `{"latitude":0,"longitude":0}`},
+		{name: "list kitchen tickets", method: http.MethodGet, path: "/admin/kitchen/orders", auth: true},
+		{name: "list kitchen tickets unauthenticated", method: http.MethodGet, path: "/admin/kitchen/orders"},
+		{name: "list kitchen tickets as kitchen", method: http.MethodGet, path: "/admin/kitchen/orders", apiKey: testserver.KitchenAPIKey},
+		{name: "list kitchen tickets as support", method: http.MethodGet, path: "/admin/kitchen/orders", apiKey: testserver.SupportAPIKey},
+		{name: "create product as kitchen", method: http.MethodPost, path: "/products", body: `{"name":"Soup","price":5,"category":"Soup"}`, apiKey: testserver.KitchenAPIKey},
+		{name: "list products including deleted as support", method: http.MethodGet, path: "/products?include_deleted=true", apiKey: testserver.SupportAPIKey},
+		{name: "check coupon as support", method: http.MethodGet, path: "/admin/coupons/UNKNOWN1", apiKey: testserver.SupportAPIKey},
+		{name: "reload as support", method: http.MethodPost, path: "/admin/reload", apiKey: testserver.SupportAPIKey},
+		{name: "grant roles to unknown customer", method: http.MethodPut, path: "/admin/customers/missing/roles", body: `{"roles":["kitchen"]}`, auth: true},
+		{name: "grant unknown role", method: http.MethodPut, path: "/admin/customers/missing/roles", body: `{"roles":["owner"]}`, auth: true},
+		{name: "api key usage", method: http.MethodGet, path: "/admin/apikeys/" + apikeys.ConfiguredID(testserver.APIKey) + "/usage", auth: true},
+		{name: "api key usage with invalid range", method: http.MethodGet, path: "/admin/apikeys/" + apikeys.ConfiguredID(testserver.APIKey) + "/usage?from=soon", auth: true},
+		{name: "usage of unknown api key", method: http.MethodGet, path: "/admin/apikeys/000000000000/usage", auth: true},
+		{name: "grant roles as support", method: http.MethodPut, path: "/admin/customers/missing/roles", body: `{"roles":["kitchen"]}`, apiKey: testserver.SupportAPIKey},
+		{name: "list kitchen tables", method: http.MethodGet, path: "/admin/kitchen/tables", auth: true},
+		{name: "list kitchen tables unauthenticated", method: http.MethodGet, path: "/admin/kitchen/tables"},
+		{name: "list kitchen tables as support", method: http.MethodGet, path: "/admin/kitchen/tables", apiKey: testserver.SupportAPIKey},
+		{name: "get table token", method: http.MethodGet, path: "/admin/tables/12/token", auth: true},
+		{name: "get token of invalid table", method: http.MethodGet, path: "/admin/tables/-12/token", auth: true},
+		{name: "get table token unauthenticated", method: http.MethodGet, path: "/admin/tables/12/token"},
+		{name: "get table token as kitchen", method: http.MethodGet, path: "/admin/tables/12/token", apiKey: testserver.KitchenAPIKey},
+		{name: "mark unknown order ready", method: http.MethodPost, path: "/admin/kitchen/orders/missing/ready", auth: true},
+		{name: "mark order ready unauthenticated", method: http.MethodPost, path: "/admin/kitchen/orders/missing/ready"},
+		{name: "upload image unauthenticated", method: http.MethodPost, path: "/admin/products/prod-1/image"},
+		{name: "upload image of unknown product", method: http.MethodPost, path: "/admin/products/missing/image", auth: true},
+		{name: "upload image without file", method: http.MethodPost, path: "/admin/products/prod-1/image", auth: true},
+		{name: "list reviews", method: http.MethodGet, path: "/products/prod-1/reviews"},
+		{name: "review unpurchased product", method: http.MethodPost, path: "/products/prod-1/reviews", body: `{"orderId":"missing","rating":5}`},
+		{name: "review unknown product", method: http.MethodPost, path: "/products/missing/reviews", body: `{"orderId":"missing","rating":5}`},
+		{name: "review as text", method: http.MethodPost, path: "/products/prod-1/reviews", body: `{"orderId":"missing","rating":5}`, contentType: "text/plain"},
+		{name: "review malformed", method: http.MethodPost, path: "/products/prod-1/reviews", body: `{"rating":`},
+		{name: "review invalid", method: http.MethodPost, path: "/products/prod-1/reviews", body: `{"orderId":"missing","rating":6}`},
+		{name: "list reviews for moderation", method: http.MethodGet, path: "/admin/reviews", auth: true},
+		{name: "list reviews with unknown status", method: http.MethodGet, path: "/admin/reviews?status=hidden", auth: true},
+		{name: "list reviews for moderation unauthenticated", method: http.MethodGet, path: "/admin/reviews"},
+		{name: "approve unknown review", method: http.MethodPost, path: "/admin/reviews/missing/approve", auth: true},
+		{name: "reject unknown review", method: http.MethodPost, path: "/admin/reviews/missing/reject", auth: true},
+		{name: "list blocklist", method: http.MethodGet, path: "/admin/blocklist", auth: true},
+		{name: "list blocklist unauthenticated", method: http.MethodGet, path: "/admin/blocklist"},
+		{name: "block api key", method: http.MethodPost, path: "/admin/blocklist", body: `{"type":"api_key","value":"abused-key","reason":"Coupon abuse"}`, auth: true},
+		{name: "block api key again", method: http.MethodPost, path: "/admin/blocklist", body: `{"type":"api_key","value":"abused-key"}`, auth: true},
+		{name: "block invalid range", method: http.MethodPost, path: "/admin/blocklist", body: `{"type":"ip","value":"not-an-ip"}`, auth: true},
+		{name: "block malformed", method: http.MethodPost, path: "/admin/blocklist", body: `{"type":`, auth: true},
+		{name: "unblock unknown entry", method: http.MethodDelete, path: "/admin/blocklist/missing", auth: true},
+		{name: "list blocklist audit", method: http.MethodGet, path: "/admin/blocklist/audit", auth: true},
+		{name: "sign in with unknown provider", method: http.MethodPost, path: "/auth/oidc/facebook", body: `{"idToken":"token"}`},
+		{name: "sign in without token", method: http.MethodPost, path: "/auth/oidc/google", body: `{}`},
+		{name: "sign in malformed", method: http.MethodPost, path: "/auth/oidc/google", body: `{"idToken":`},
+		{name: "signed-in customer unauthenticated", method: http.MethodGet, path: "/auth/me"},
+		{name: "list favorites unauthenticated", method: http.MethodGet, path: "/me/favorites"},
+		{name: "save favorite unauthenticated", method: http.MethodPut, path: "/me/favorites/prod-1"},
+		{name: "remove favorite unauthenticated", method: http.MethodDelete, path: "/me/favorites/prod-1"},
+		{name: "refresh with unknown token", method: http.MethodPost, path: "/auth/refresh", body: `{"refreshToken":"unknown"}`},
+		{name: "refresh without token", method: http.MethodPost, path: "/auth/refresh", body: `{}`},
+		{name: "log out unauthenticated", method: http.MethodPost, path: "/auth/logout"},
+		{name: "register", method: http.MethodPost, path: "/auth/register", body: `{"email":"jane@example.com","password":"correct horse"}`},
+		{name: "register taken address", method: http.MethodPost, path: "/auth/register", body: `{"email":"jane@example.com","password":"correct horse"}`},
+		{name: "register short password", method: http.MethodPost, path: "/auth/register", body: `{"email":"john@example.com","password":"short"}`},
+		{name: "log in", method: http.MethodPost, path: "/auth/login", body: `{"email":"jane@example.com","password":"correct horse"}`},
+		{name: "log in with wrong password", method: http.MethodPost, path: "/auth/login", body: `{"email":"jane@example.com","password":"wrong horse"}`},
+		{name: "log in with wrong password again", method: http.MethodPost, path: "/auth/login", body: `{"email":"jane@example.com","password":"wrong horse"}`},
+		{name: "log in locking account", method: http.MethodPost, path: "/auth/login", body: `{"email":"jane@example.com","password":"wrong horse"}`},
+		{name: "unlock with wrong code", method: http.MethodPost, path: "/auth/unlock", body: `{"code":"wrong"}`},
+		{name: "resend unlock email", method: http.MethodPost, path: "/auth/unlock/email", body: `{"email":"jane@example.com"}`},
+		{name: "verify email with wrong code", method: http.MethodPost, path: "/auth/verify-email", body: `{"code":"wrong"}`},
+		{name: "verify email without code", method: http.MethodPost, path: "/auth/verify-email", body: `{}`},
+		{name: "resend verification unauthenticated", method: http.MethodPost, path: "/auth/verify-email/resend"},
+		{name: "forgot password", method: http.MethodPost, path: "/auth/password/forgot", body: `{"email":"jane@example.com"}`},
+		{name: "reset password with wrong code", method: http.MethodPost, path: "/auth/password/reset", body: `{"code":"wrong","password":"battery staple"}`},
+		{name: "reset password too short", method: http.MethodPost, path: "/auth/password/reset", body: `{"code":"wrong","password":"short"}`},
+		{name: "list api keys", method: http.MethodGet, path: "/admin/apikeys", auth: true},
+		{name: "list api keys as kitchen", method: http.MethodGet, path: "/admin/apikeys", apiKey: testserver.KitchenAPIKey},
+		{name: "create api key", method: http.MethodPost, path: "/admin/apikeys", body: `{"name":"Delivery partner","scopes":["kitchen"]}`, auth: true},
+		{name: "create api key with unknown scope", method: http.MethodPost, path: "/admin/apikeys", body: `{"name":"Delivery partner","scopes":["owner"]}`, auth: true},
+		{name: "create api key malformed", method: http.MethodPost, path: "/admin/apikeys", body: `{"name":`, auth: true},
+		{name: "get api key", method: http.MethodGet, path: "/admin/apikeys/" + apikeys.ConfiguredID(testserver.SupportAPIKey), auth: true},
+		{name: "get unknown api key", method: http.MethodGet, path: "/admin/apikeys/missing", auth: true},
+		{name: "rotate unknown api key", method: http.MethodPost, path: "/admin/apikeys/missing/rotate", auth: true},
+		{name: "disable unknown api key", method: http.MethodPost, path: "/admin/apikeys/missing/disable", auth: true},
+		{name: "list stock levels", method: http.MethodGet, path: "/pos/inventory", auth: true},
+		{name: "list stock levels unauthenticated", method: http.MethodGet, path: "/pos/inventory"},
+		{name: "sync stock levels", method: http.MethodPut, path: "/pos/inventory", body: `{"levels":[{"sku":"WAF-BER","quantity":12}]}`, auth: true},
+		{name: "sync stock levels as kitchen", method: http.MethodPut, path: "/pos/inventory", body: `{"levels":[{"sku":"WAF-BER","quantity":12}]}`, apiKey: testserver.KitchenAPIKey},
+		{name: "sync stock levels malformed", method: http.MethodPut, path: "/pos/inventory", body: `{"levels":`, auth: true},
+		{name: "sync negative stock level", method: http.MethodPut, path: "/pos/inventory", body: `{"levels":[{"sku":"WAF-BER","quantity":-1}]}`, auth: true},
+		{name: "collect orders", method: http.MethodGet, path: "/pos/orders?after=0&limit=10", auth: true},
+		{name: "collect orders with invalid limit", method: http.MethodGet, path: "/pos/orders?limit=5000", auth: true},
+		// These change the support key, so they run last
+		{name: "rotate api key", method: http.MethodPost, path: "/admin/apikeys/" + apikeys.ConfiguredID(testserver.SupportAPIKey) + "/rotate", auth: true},
+		{name: "disable api key", method: http.MethodPost, path: "/admin/apikeys/" + apikeys.ConfiguredID(testserver.SupportAPIKey) + "/disable", auth: true},
+		{name: "rotate disabled api key", method: http.MethodPost, path: "/admin/apikeys/" + apikeys.ConfiguredID(testserver.SupportAPIKey) + "/rotate", auth: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := findOperation(specs, tt.method, tt.path)
+			require.NotNil(t, spec, "no documented operation for %s %s", tt.method, tt.path)
+
+			var opts []testserver.RequestOption
+			if tt.auth {
+				opts = append(opts, testserver.WithAPIKey())
+			}
+			if tt.apiKey != "" {
+				opts = append(opts, testserver.WithHeader("X-API-Key", tt.apiKey))
+			}
+			if tt.contentType != "" {
+				opts = append(opts, testserver.WithHeader("Content-Type", tt.contentType))
+			}
+			if tt.ifMatch != "" {
+				opts = append(opts, testserver.WithHeader("If-Match", tt.ifMatch))
+			}
+			resp := srv.Do(tt.method, tt.path, tt.body, opts...)
+
+			response, documented := spec.responses[resp.StatusCode]
+			require.True(t, documented, "%s %s returned undocumented status %d: %s",
+				spec.method, spec.path, resp.StatusCode, resp.Body)
+			if response.typeName == "" {
+				assert.Empty(t, resp.Body, "documented without a body")
+				return
+			}
+			assert.Contains(t, resp.Header.Get("Content-Type"), "application/json")
+
+			// Decode strictly so undocumented fields are reported as drift
+			newValue, known := schemaTypes[response.typeName]
+			require.True(t, known, "no schema registered for %s", response.typeName)
+			decoder := json.NewDecoder(bytes.NewReader(resp.Body))
+			decoder.DisallowUnknownFields()
+			if response.array {
+				var items []json.RawMessage
+				require.NoError(t, decoder.Decode(&items))
+				for _, item := range items {
+					itemDecoder := json.NewDecoder(bytes.NewReader(item))
+					itemDecoder.DisallowUnknownFields()
+					assert.NoError(t, itemDecoder.Decode(newValue()))
+				}
+				return
+			}
+			value := newValue()
+			require.NoError(t, decoder.Decode(value))
+			if errResp, ok := value.(*models.ErrorResponse); ok {
+				assert.NoError(t, models.Validate(errResp), "error response is missing required fields")
+			}
+		})
+	}
+}
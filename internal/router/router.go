@@ -2,12 +2,34 @@ package router
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/ravibandhu/oolio-food-ordering/internal/data"
+	"github.com/ravibandhu/oolio-food-ordering/internal/adminui"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apikeys"
+	"github.com/ravibandhu/oolio-food-ordering/internal/auth"
+	"github.com/ravibandhu/oolio-food-ordering/internal/blocklist"
+	"github.com/ravibandhu/oolio-food-ordering/internal/carts"
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/couriers"
+	"github.com/ravibandhu/oolio-food-ordering/internal/deprecation"
 	"github.com/ravibandhu/oolio-food-ordering/internal/handlers"
+	"github.com/ravibandhu/oolio-food-ordering/internal/idempotency"
+	"github.com/ravibandhu/oolio-food-ordering/internal/images"
+	"github.com/ravibandhu/oolio-food-ordering/internal/maintenance"
+	"github.com/ravibandhu/oolio-food-ordering/internal/middleware"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/pos"
 	"github.com/ravibandhu/oolio-food-ordering/internal/services"
+	"github.com/ravibandhu/oolio-food-ordering/internal/slo"
+	"github.com/ravibandhu/oolio-food-ordering/internal/split"
+	"github.com/ravibandhu/oolio-food-ordering/internal/startup"
+	"github.com/ravibandhu/oolio-food-ordering/internal/tenant"
+	"github.com/ravibandhu/oolio-food-ordering/internal/usage"
 
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -18,72 +40,303 @@ import (
 
 // Router wraps the underlying router implementation and associated resources
 type Router struct {
-	engine *gin.Engine
-	store  *data.Store
+	engine   *gin.Engine
+	config   *config.Config
+	tenants  *tenant.Registry
+	blocked  *blocklist.List
+	accounts *auth.Accounts
+	keys     *apikeys.Store
+	usage    *usage.Tracker
+	notices  *deprecation.Policy
+	report   *startup.Report
+	routes   []*registeredRoute
+	slos     []*slo.Tracker
+	draining atomic.Bool
+
+	maintainable []string // Names of the route groups that can be put into maintenance
 }
 
-// NewRouter creates a new Router instance
-func NewRouter(ctx context.Context, store *data.Store) *Router {
+// NewRouter creates a new Router instance. Callers the blocklist blocks are
+// refused on every route, customers sign in to accounts, staff and partners
+// authenticate with the API keys in keys, and requests made with them are
+// counted in tracker. Routes and fields deprecated by notices are announced
+// as such, and report is served to admins. The router starts draining when
+// ctx is cancelled.
+func NewRouter(ctx context.Context, cfg *config.Config, tenants *tenant.Registry, blocked *blocklist.List, accounts *auth.Accounts, keys *apikeys.Store, tracker *usage.Tracker, notices *deprecation.Policy, report *startup.Report) *Router {
+	engine := gin.New()
+	engine.Use(middleware.AccessLog(cfg.Logging.AccessFormat), gin.Recovery())
+
 	r := &Router{
-		engine: gin.Default(),
-		store:  store,
+		engine:   engine,
+		config:   cfg,
+		tenants:  tenants,
+		blocked:  blocked,
+		accounts: accounts,
+		keys:     keys,
+		usage:    tracker,
+		notices:  notices,
+		report:   report,
 	}
 
 	// Set up routes
-	r.setupRoutes(ctx)
+	r.setupRoutes()
+	context.AfterFunc(ctx, r.Drain)
 
 	return r
 }
 
 // setupRoutes configures all the routes for the application
-func (r *Router) setupRoutes(ctx context.Context) {
+func (r *Router) setupRoutes() {
+	// Handlers serve the tenant resolved per request, falling back to the default one
+	store := r.tenants.Default().Store
+
 	// Create services
-	orderService := services.NewOrderService(r.store)
+	orderService := services.NewOrderService(store)
 
 	// Create handlers
-	productHandler := handlers.NewProductHandler(r.store)
+	productHandler := handlers.NewProductHandler(store)
 	orderHandler := handlers.NewOrderHandler(orderService)
 	profileHandler := handlers.NewProfileHandler()
+	adminHandler := handlers.NewAdminHandler(store)
+	kitchenHandler := handlers.NewKitchenHandler(r.tenants.Default().Kitchen)
+	courierHandler := handlers.NewCourierHandler()
+	reviewHandler := handlers.NewReviewHandler(store, r.tenants.Default().Reviews)
+	cartHandler := handlers.NewCartHandler(store, r.tenants.Default().Carts, orderService, r.config.Orders.HoldTTL)
+	splitHandler := handlers.NewSplitHandler(r.config.Orders.PaymentLinkURL)
+	tableHandler := handlers.NewTableHandler(r.tenants.Default().Tables)
+	accountingHandler := handlers.NewAccountingHandler(r.config.Accounting)
+	imageHandler := handlers.NewImageHandler(store, images.NewLocalStorage(r.config.Images.Dir, r.config.Images.BaseURL))
+	blocklistHandler := handlers.NewBlocklistHandler(r.blocked)
+	authHandler := handlers.NewAuthHandler(r.accounts)
+	customerHandler := handlers.NewCustomerHandler(r.accounts)
+	favoriteHandler := handlers.NewFavoriteHandler(store, r.accounts)
+	apiKeyHandler := handlers.NewAPIKeyHandler(r.keys, r.usage)
+	posHandler := handlers.NewPOSHandler(r.tenants.Default().Inventory, r.tenants.Default().Exports)
+	startupHandler := handlers.NewStartupHandler(r.report)
 
-	// Swagger documentation
-	r.engine.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	routeHandler := handlers.NewRouteHandler(r.Routes)
+	sloHandler := handlers.NewSLOHandler(r.SLOs)
+	maintenanceHandler := handlers.NewMaintenanceHandler(r.tenants.Default().Maintenance, r.maintenanceGroups)
 
-	// Product routes
-	products := r.engine.Group("/products")
-	{
-		products.GET("", gin.WrapF(productHandler.ListProducts))
-		products.GET("/:id", gin.WrapF(productHandler.GetProduct))
-		// TODO: Add other product routes
-	}
+	// Create middleware
+	limitBody := middleware.BodyLimit(r.config.Server.MaxBodySize, r.config.Server.StrictJSON)
+	requireJSON := middleware.RequireJSON()
+	idempotent := middleware.Idempotency(idempotency.New(idempotency.DefaultTTL)) // Shared by every route placing orders
+
+	// Answer known paths requested with another method with 405 and Allow
+	r.engine.HandleMethodNotAllowed = true
+	r.engine.NoMethod(middleware.MethodNotAllowed)
+
+	// Let browsers on the configured origins read responses, including refusals
+	r.engine.Use(middleware.CORS(r.config.Server.CORSOrigins))
+
+	// Envelope responses for clients that ask for it, including refusals
+	r.engine.Use(middleware.Envelope(r.config.Server.Envelope))
+
+	// Refuse writes while the server drains before stopping, letting reads finish
+	r.engine.Use(middleware.Drain(r.draining.Load))
+
+	// Announce deprecated routes and fields, refusing retired routes
+	r.engine.Use(middleware.Deprecation(r.notices, time.Now))
+
+	// Refuse blocked callers before anything else
+	r.engine.Use(middleware.Blocklist(r.blocked))
+
+	// Resolve the tenant of every request
+	r.engine.Use(middleware.Tenant(r.tenants))
 
-	// Order routes
-	orders := r.engine.Group("/orders")
-	{
-		orders.POST("", gin.WrapF(orderHandler.PlaceOrder))
+	// Record whether responses carry links to related resources
+	r.engine.Use(middleware.Links(r.config.Server.Links))
+
+	// Count the requests made with each API key
+	r.engine.Use(middleware.Usage(r.usage, r.keys, placesOrder))
+
+	publicImages := gin.WrapH(http.StripPrefix("/public/images", images.FileServer(r.config.Images.Dir, r.config.Images.MaxAge)))
+	dashboard := gin.WrapH(http.StripPrefix("/admin", adminui.Handler()))
+
+	groups := []routeGroup{
+		// Swagger documentation and the product images stored by uploads
+		{
+			routes: []route{
+				{method: http.MethodGet, path: "/swagger/*any", handler: ginSwagger.WrapHandler(swaggerFiles.Handler)},
+				{method: http.MethodGet, path: "/public/images/*filepath", handler: publicImages},
+				{method: http.MethodHead, path: "/public/images/*filepath", handler: publicImages},
+			},
+		},
+
+		// Product routes
+		{
+			name:       "catalog",
+			prefix:     "/products",
+			timeout:    r.config.Server.CatalogTimeout,
+			middleware: []gin.HandlerFunc{requireJSON, limitBody},
+			routes: []route{
+				{method: http.MethodGet, path: "", middleware: []gin.HandlerFunc{middleware.RequireRoleIf(includesDeleted, r.keys, r.accounts, auth.RoleAdmin)}, handler: productHandler.ListProducts},
+				{method: http.MethodGet, path: "/:id", handler: productHandler.GetProduct},
+				{method: http.MethodPost, path: "", scope: auth.RoleAdmin, middleware: []gin.HandlerFunc{middleware.Bind[models.Product]()}, handler: productHandler.CreateProduct},
+				{method: http.MethodPut, path: "/:id", scope: auth.RoleAdmin, middleware: []gin.HandlerFunc{middleware.Bind[models.Product](handlers.ProductIDFromPath)}, handler: productHandler.UpdateProduct},
+				{method: http.MethodDelete, path: "/:id", scope: auth.RoleAdmin, handler: productHandler.DeleteProduct},
+				{method: http.MethodGet, path: "/:id/reviews", handler: reviewHandler.ListReviews},
+				{method: http.MethodPost, path: "/:id/reviews", middleware: []gin.HandlerFunc{middleware.Bind[models.ReviewRequest]()}, handler: reviewHandler.SubmitReview},
+			},
+		},
+
+		// Order routes
+		{
+			name:       "ordering",
+			prefix:     "/orders",
+			timeout:    r.config.Server.OrderTimeout,
+			middleware: []gin.HandlerFunc{requireJSON, limitBody, middleware.Client()},
+			routes: []route{
+				{method: http.MethodPost, path: "", middleware: []gin.HandlerFunc{idempotent, middleware.Challenge(r.keys), middleware.Bind[models.OrderRequest]()}, handler: orderHandler.PlaceOrder},
+				{method: http.MethodGet, path: "/:id/eta", handler: kitchenHandler.GetETA},
+				{method: http.MethodGet, path: "/:id/timeline", handler: kitchenHandler.GetTimeline},
+				{method: http.MethodGet, path: "/:id/tracking", handler: courierHandler.GetTracking},
+				{method: http.MethodPost, path: "/:id/split", middleware: []gin.HandlerFunc{middleware.Bind[split.Request]()}, handler: splitHandler.SplitOrder},
+			},
+		},
+
+		// Group cart routes. Checking out places an order, so it is also
+		// refused while ordering is down for maintenance.
+		{
+			name:       "carts",
+			prefix:     "/carts",
+			scope:      scopeGuest,
+			timeout:    r.config.Server.OrderTimeout,
+			middleware: []gin.HandlerFunc{requireJSON, limitBody, middleware.Client()},
+			routes: []route{
+				{method: http.MethodPost, path: "", middleware: []gin.HandlerFunc{middleware.Bind[carts.CartRequest]()}, handler: cartHandler.CreateCart},
+				{method: http.MethodGet, path: "/:id", handler: cartHandler.GetCart},
+				{method: http.MethodPost, path: "/:id/items", middleware: []gin.HandlerFunc{middleware.Bind[carts.ItemRequest]()}, handler: cartHandler.AddItems},
+				{method: http.MethodDelete, path: "/:id/items/:item", handler: cartHandler.RemoveItem},
+				{method: http.MethodPost, path: "/:id/checkout", middleware: []gin.HandlerFunc{middleware.Maintenance("ordering"), idempotent, middleware.Challenge(r.keys), middleware.Bind[carts.CheckoutRequest]()}, handler: cartHandler.Checkout},
+			},
+		},
+
+		// Customer sign-in routes
+		{
+			name:       "accounts",
+			prefix:     "/auth",
+			middleware: []gin.HandlerFunc{requireJSON, limitBody},
+			routes: []route{
+				{method: http.MethodPost, path: "/oidc/:provider", middleware: []gin.HandlerFunc{middleware.Bind[handlers.SignInRequest]()}, handler: authHandler.SignIn},
+				{method: http.MethodPost, path: "/register", middleware: []gin.HandlerFunc{middleware.Bind[handlers.RegisterRequest]()}, handler: authHandler.Register},
+				{method: http.MethodPost, path: "/login", middleware: []gin.HandlerFunc{middleware.Bind[handlers.LoginRequest]()}, handler: authHandler.Login},
+				{method: http.MethodPost, path: "/unlock", middleware: []gin.HandlerFunc{middleware.Bind[handlers.CodeRequest]()}, handler: authHandler.Unlock},
+				{method: http.MethodPost, path: "/unlock/email", middleware: []gin.HandlerFunc{middleware.Bind[handlers.EmailRequest]()}, handler: authHandler.SendUnlockEmail},
+				{method: http.MethodPost, path: "/verify-email", middleware: []gin.HandlerFunc{middleware.Bind[handlers.CodeRequest]()}, handler: authHandler.VerifyEmail},
+				{method: http.MethodPost, path: "/verify-email/resend", scope: scopeCustomer, handler: authHandler.ResendVerification},
+				{method: http.MethodPost, path: "/password/forgot", middleware: []gin.HandlerFunc{middleware.Bind[handlers.EmailRequest]()}, handler: authHandler.ForgotPassword},
+				{method: http.MethodPost, path: "/password/reset", middleware: []gin.HandlerFunc{middleware.Bind[handlers.ResetPasswordRequest]()}, handler: authHandler.ResetPassword},
+				{method: http.MethodPost, path: "/refresh", middleware: []gin.HandlerFunc{middleware.Bind[handlers.RefreshRequest]()}, handler: authHandler.Refresh},
+				{method: http.MethodPost, path: "/logout", scope: scopeCustomer, handler: authHandler.Logout},
+				{method: http.MethodGet, path: "/me", scope: scopeCustomer, handler: authHandler.Me},
+			},
+		},
+
+		// Routes of the signed-in customer
+		{
+			prefix: "/me",
+			scope:  scopeCustomer,
+			routes: []route{
+				{method: http.MethodGet, path: "/favorites", handler: favoriteHandler.ListFavorites},
+				{method: http.MethodPut, path: "/favorites/:id", handler: favoriteHandler.AddFavorite},
+				{method: http.MethodDelete, path: "/favorites/:id", handler: favoriteHandler.RemoveFavorite},
+			},
+		},
+
+		// Point-of-sale routes, authenticated with API keys holding the pos role
+		{
+			name:   "pos",
+			prefix: "/pos",
+			scope:  auth.RolePOS,
+			routes: []route{
+				{method: http.MethodGet, path: "/inventory", handler: posHandler.ListInventory},
+				{method: http.MethodPut, path: "/inventory", middleware: []gin.HandlerFunc{requireJSON, limitBody, middleware.Bind[pos.InventoryRequest]()}, handler: posHandler.SyncInventory},
+				{method: http.MethodGet, path: "/orders", handler: posHandler.ListOrders},
+			},
+		},
+
+		// Courier routes, authenticated with API keys or session tokens
+		// holding the courier role
+		{
+			name:       "couriers",
+			prefix:     "/courier",
+			scope:      auth.RoleCourier,
+			middleware: []gin.HandlerFunc{requireJSON, limitBody},
+			routes: []route{
+				{method: http.MethodPost, path: "/orders/:id/status", middleware: []gin.HandlerFunc{middleware.Bind[couriers.StatusRequest]()}, handler: courierHandler.UpdateStatus},
+				{method: http.MethodPut, path: "/orders/:id/location", middleware: []gin.HandlerFunc{middleware.Bind[couriers.LocationRequest]()}, handler: courierHandler.UpdateLocation},
+			},
+		},
+
+		// Staff routes, including the embedded dashboard. Every staff role can
+		// open the dashboard; each route then requires the role its work needs.
+		{
+			prefix: "/admin",
+			scope:  scopeStaff,
+			routes: []route{
+				{method: http.MethodGet, path: "", handler: dashboard},
+				{method: http.MethodGet, path: "/assets/*filepath", handler: dashboard},
+				{method: http.MethodPost, path: "/reload", scope: auth.RoleAdmin, handler: adminHandler.Reload},
+				{method: http.MethodGet, path: "/startup", scope: auth.RoleAdmin, handler: startupHandler.GetReport},
+				{method: http.MethodGet, path: "/routes", scope: auth.RoleAdmin, handler: routeHandler.ListRoutes},
+				{method: http.MethodGet, path: "/slos", scope: auth.RoleAdmin, handler: sloHandler.ListSLOs},
+				{method: http.MethodGet, path: "/maintenance", scope: auth.RoleAdmin, handler: maintenanceHandler.ListMaintenance},
+				{method: http.MethodPut, path: "/maintenance/:group", scope: auth.RoleAdmin, middleware: []gin.HandlerFunc{requireJSON, limitBody, middleware.Bind[maintenance.Request]()}, handler: maintenanceHandler.StartMaintenance},
+				{method: http.MethodDelete, path: "/maintenance/:group", scope: auth.RoleAdmin, handler: maintenanceHandler.EndMaintenance},
+				{method: http.MethodGet, path: "/backup", scope: auth.RoleAdmin, handler: adminHandler.Backup},
+				{method: http.MethodPost, path: "/restore", scope: auth.RoleAdmin, handler: adminHandler.Restore},
+				{method: http.MethodGet, path: "/coupons/stats", scope: auth.RoleAdmin, handler: adminHandler.CouponStats},
+				{method: http.MethodGet, path: "/coupons/:code", scope: auth.RoleSupport, handler: adminHandler.CheckCoupon},
+				{method: http.MethodGet, path: "/kitchen/orders", scope: auth.RoleKitchen, handler: kitchenHandler.ListTickets},
+				{method: http.MethodPost, path: "/kitchen/orders/:id/ready", scope: auth.RoleKitchen, handler: kitchenHandler.MarkReady},
+				{method: http.MethodGet, path: "/kitchen/tables", scope: auth.RoleKitchen, handler: kitchenHandler.ListTableTickets},
+				{method: http.MethodGet, path: "/tables/:table/token", scope: auth.RoleAdmin, handler: tableHandler.GetToken},
+				{method: http.MethodGet, path: "/products/errors", scope: auth.RoleAdmin, handler: adminHandler.ProductErrors},
+				{method: http.MethodGet, path: "/products/stats", scope: auth.RoleAdmin, handler: adminHandler.ProductStats},
+				{method: http.MethodPost, path: "/products/validate", scope: auth.RoleAdmin, middleware: []gin.HandlerFunc{requireJSON, limitBody, middleware.Bind[json.RawMessage]()}, handler: adminHandler.ValidateProducts},
+				{method: http.MethodPost, path: "/products/:id/image", scope: auth.RoleAdmin, handler: imageHandler.UploadImage},
+				{method: http.MethodGet, path: "/products/:id/price-history", scope: auth.RoleAdmin, handler: adminHandler.PriceHistory},
+				{method: http.MethodPost, path: "/menu/import", scope: auth.RoleAdmin, middleware: []gin.HandlerFunc{requireJSON, limitBody, middleware.Bind[json.RawMessage]()}, handler: adminHandler.ImportMenu},
+				{method: http.MethodGet, path: "/invoices", scope: auth.RoleAdmin, handler: adminHandler.InvoiceStatus},
+				{method: http.MethodGet, path: "/reports/payments", scope: auth.RoleAdmin, handler: adminHandler.PaymentReport},
+				{method: http.MethodGet, path: "/reports/accounting", scope: auth.RoleAdmin, handler: accountingHandler.Export},
+				{method: http.MethodGet, path: "/orders", scope: auth.RoleSupport, handler: adminHandler.ListOrders},
+				{method: http.MethodGet, path: "/orders/:id", scope: auth.RoleSupport, handler: adminHandler.OrderHistory},
+				{method: http.MethodPut, path: "/orders/:id/courier", scope: auth.RoleKitchen, middleware: []gin.HandlerFunc{requireJSON, limitBody, middleware.Bind[couriers.Courier]()}, handler: courierHandler.AssignCourier},
+				{method: http.MethodGet, path: "/dashboard/orders", scope: auth.RoleSupport, handler: adminHandler.OrderDashboard},
+				{method: http.MethodGet, path: "/reviews", scope: auth.RoleSupport, handler: reviewHandler.ListForModeration},
+				{method: http.MethodPost, path: "/reviews/:id/approve", scope: auth.RoleSupport, handler: reviewHandler.Approve},
+				{method: http.MethodPost, path: "/reviews/:id/reject", scope: auth.RoleSupport, handler: reviewHandler.Reject},
+				{method: http.MethodGet, path: "/blocklist", scope: auth.RoleAdmin, handler: blocklistHandler.ListEntries},
+				{method: http.MethodPost, path: "/blocklist", scope: auth.RoleAdmin, middleware: []gin.HandlerFunc{requireJSON, limitBody, middleware.Bind[blocklist.EntryRequest]()}, handler: blocklistHandler.AddEntry},
+				{method: http.MethodDelete, path: "/blocklist/:id", scope: auth.RoleAdmin, handler: blocklistHandler.RemoveEntry},
+				{method: http.MethodGet, path: "/blocklist/audit", scope: auth.RoleAdmin, handler: blocklistHandler.ListAudit},
+				{method: http.MethodPut, path: "/customers/:id/roles", scope: auth.RoleAdmin, middleware: []gin.HandlerFunc{requireJSON, limitBody, middleware.Bind[handlers.RolesRequest]()}, handler: customerHandler.SetRoles},
+				{method: http.MethodGet, path: "/apikeys", scope: auth.RoleAdmin, handler: apiKeyHandler.ListKeys},
+				{method: http.MethodPost, path: "/apikeys", scope: auth.RoleAdmin, middleware: []gin.HandlerFunc{requireJSON, limitBody, middleware.Bind[apikeys.KeyRequest]()}, handler: apiKeyHandler.CreateKey},
+				{method: http.MethodGet, path: "/apikeys/:id", scope: auth.RoleAdmin, handler: apiKeyHandler.GetKey},
+				{method: http.MethodPost, path: "/apikeys/:id/rotate", scope: auth.RoleAdmin, handler: apiKeyHandler.RotateKey},
+				{method: http.MethodPost, path: "/apikeys/:id/disable", scope: auth.RoleAdmin, handler: apiKeyHandler.DisableKey},
+				{method: http.MethodGet, path: "/apikeys/:id/usage", scope: auth.RoleAdmin, handler: apiKeyHandler.GetUsage},
+			},
+		},
 	}
 
 	// Profile routes (protected, should be disabled in production)
 	if gin.Mode() != gin.ReleaseMode {
-		profile := r.engine.Group("/debug/profile")
-		{
-			profile.GET("/cpu", profileHandler.StartCPUProfile)
-			profile.GET("/memory", profileHandler.GetMemoryProfile)
-			profile.GET("/goroutine", profileHandler.GetGoroutineProfile)
-		}
+		groups = append(groups, routeGroup{
+			prefix: "/debug/profile",
+			routes: []route{
+				{method: http.MethodGet, path: "/cpu", handler: profileHandler.StartCPUProfile},
+				{method: http.MethodGet, path: "/memory", handler: profileHandler.GetMemoryProfile},
+				{method: http.MethodGet, path: "/goroutine", handler: profileHandler.GetGoroutineProfile},
+			},
+		})
 	}
 
-	// Add middleware to check context cancellation
-	r.engine.Use(func(c *gin.Context) {
-		select {
-		case <-ctx.Done():
-			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
-				"error": "server is shutting down",
-			})
-			return
-		default:
-			c.Next()
-		}
-	})
+	r.register(groups)
 }
 
 // Engine returns the underlying gin.Engine instance
@@ -91,11 +344,39 @@ func (r *Router) Engine() *gin.Engine {
 	return r.engine
 }
 
-// Shutdown performs cleanup when the router is being shut down
+// Drain starts refusing requests that change anything, with 503
+// SHUTTING_DOWN, while still serving reads, so the requests in flight as
+// the server stops can finish without new orders arriving. Draining cannot
+// be undone.
+func (r *Router) Drain() {
+	r.draining.Store(true)
+}
+
+// Shutdown performs cleanup when the router is being shut down. It closes
+// the stores, so it is called once the HTTP server has stopped serving.
 func (r *Router) Shutdown(ctx context.Context) error {
-	// Close the store
-	if err := r.store.Close(); err != nil {
+	// Save the API key usage counted since the last flush
+	if err := r.usage.Flush(); err != nil {
+		return err
+	}
+
+	// Close the tenant stores
+	if err := r.tenants.Close(); err != nil {
 		return err
 	}
 	return nil
 }
+
+// placesOrder reports whether a request places an order, directly or by
+// checking out a group cart
+func placesOrder(c *gin.Context) bool {
+	return c.Request.Method == http.MethodPost &&
+		(c.FullPath() == "/orders" || c.FullPath() == "/carts/:id/checkout")
+}
+
+// includesDeleted reports whether a product list request asks for deleted
+// products, which only admins may see
+func includesDeleted(c *gin.Context) bool {
+	include, err := strconv.ParseBool(c.Query("include_deleted"))
+	return err == nil && include
+}
@@ -0,0 +1,1740 @@
+package router_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apikeys"
+	"github.com/ravibandhu/oolio-food-ordering/internal/auth"
+	"github.com/ravibandhu/oolio-food-ordering/internal/blocklist"
+	"github.com/ravibandhu/oolio-food-ordering/internal/carts"
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/couriers"
+	"github.com/ravibandhu/oolio-food-ordering/internal/dashboard"
+	"github.com/ravibandhu/oolio-food-ordering/internal/data"
+	"github.com/ravibandhu/oolio-food-ordering/internal/handlers"
+	"github.com/ravibandhu/oolio-food-ordering/internal/invoices"
+	"github.com/ravibandhu/oolio-food-ordering/internal/kitchen"
+	"github.com/ravibandhu/oolio-food-ordering/internal/middleware"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/orders"
+	"github.com/ravibandhu/oolio-food-ordering/internal/pos"
+	"github.com/ravibandhu/oolio-food-ordering/internal/startup"
+	"github.com/ravibandhu/oolio-food-ordering/internal/testutil"
+	"github.com/ravibandhu/oolio-food-ordering/internal/split"
+	"github.com/ravibandhu/oolio-food-ordering/internal/testutil/testserver"
+	"github.com/ravibandhu/oolio-food-ordering/internal/usage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouter_ListAndGetProducts(t *testing.T) {
+	srv := testserver.New(t)
+
+	products, resp := srv.ListProducts()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Len(t, products, 2)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "application/json")
+
+	product, resp := srv.GetProduct("prod-1")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "Test Product 1", product.Name)
+
+	product, resp = srv.GetProduct("missing")
+	assert.Nil(t, product)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, "NOT_FOUND", resp.Error(t).Code)
+}
+
+func TestRouter_PlaceOrder(t *testing.T) {
+	srv := testserver.New(t)
+
+	tests := []struct {
+		name       string
+		req        *models.OrderRequest
+		wantStatus int
+		wantCode   string
+		wantTotal  float64
+	}{
+		{
+			name: "without coupon",
+			req: &models.OrderRequest{
+				Items: []models.OrderItem{{ProductID: "prod-1", Quantity: 2}},
+			},
+			wantStatus: http.StatusCreated,
+			wantTotal:  19.98,
+		},
+		{
+			name: "with valid coupon",
+			req: &models.OrderRequest{
+				CouponCode: testutil.ValidCoupon,
+				Items:      []models.OrderItem{{ProductID: "prod-2", Quantity: 1}},
+			},
+			wantStatus: http.StatusCreated,
+			wantTotal:  17.991,
+		},
+		{
+			name: "with invalid coupon",
+			req: &models.OrderRequest{
+				CouponCode: "TEST10",
+				Items:      []models.OrderItem{{ProductID: "prod-1", Quantity: 1}},
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "INVALID_COUPON",
+		},
+		{
+			name: "unknown product",
+			req: &models.OrderRequest{
+				Items: []models.OrderItem{{ProductID: "missing", Quantity: 1}},
+			},
+			wantStatus: http.StatusNotFound,
+			wantCode:   "INVALID_PRODUCT",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, resp := srv.PlaceOrder(tt.req)
+			require.Equal(t, tt.wantStatus, resp.StatusCode, "body: %s", resp.Body)
+
+			if tt.wantCode != "" {
+				assert.Nil(t, order)
+				assert.Equal(t, tt.wantCode, resp.Error(t).Code)
+				return
+			}
+			assert.NotEmpty(t, order.ID)
+			assert.InDelta(t, tt.wantTotal, order.TotalAmount, 0.001)
+			assert.Equal(t, tt.req.CouponCode, order.CouponCode)
+		})
+	}
+}
+
+func TestRouter_AdminRequiresAPIKey(t *testing.T) {
+	srv := testserver.New(t)
+
+	resp := srv.Do(http.MethodPost, "/admin/reload", nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, "UNAUTHORIZED", resp.Error(t).Code)
+
+	resp = srv.Do(http.MethodPost, "/admin/reload", nil, testserver.WithAPIKey())
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Browsers send cached Basic credentials with forged cross-site writes,
+	// so writes must also send a header such requests cannot
+	basic := func(req *http.Request) { req.SetBasicAuth("", testserver.APIKey) }
+	resp = srv.Do(http.MethodPost, "/admin/reload", nil, basic)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp = srv.Do(http.MethodPost, "/admin/reload", nil, basic, testserver.WithHeader(middleware.RequestedWithHeader, "XMLHttpRequest"))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = srv.Do(http.MethodGet, "/admin/reports/payments", nil, basic)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = srv.Do(http.MethodGet, "/admin/coupons/"+testutil.ValidCoupon, nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var check map[string]interface{}
+	resp.Decode(t, &check)
+	assert.Equal(t, true, check["valid"])
+}
+
+func TestRouter_CouponStats(t *testing.T) {
+	srv := testserver.New(t)
+
+	resp := srv.Do(http.MethodGet, "/admin/coupons/"+testutil.ValidCoupon, nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = srv.Do(http.MethodGet, "/admin/coupons/UNKNOWN1", nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = srv.Do(http.MethodGet, "/admin/coupons/stats", nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	var stats data.CouponStats
+	resp.Decode(t, &stats)
+	assert.Equal(t, 1, stats.Codes)
+	assert.Positive(t, stats.MemoryBytes)
+	assert.Equal(t, 256, stats.Shards.Count)
+	assert.False(t, stats.LoadedAt.IsZero())
+	assert.EqualValues(t, 1, stats.Hits)
+	assert.EqualValues(t, 1, stats.Misses)
+
+	resp = srv.Do(http.MethodGet, "/admin/coupons/stats", nil, testserver.WithHeader("X-API-Key", testserver.SupportAPIKey))
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestRouter_Tenants(t *testing.T) {
+	harbourData := testutil.SetupTestData(t)
+	t.Cleanup(harbourData.Cleanup)
+
+	srv := testserver.New(t, func(cfg *config.Config) {
+		cfg.Tenants = []config.Tenant{{
+			ID:      "harbour",
+			Files:   harbourData.Config.Files,
+			Charges: config.Charges{ServiceFee: 2},
+		}}
+	})
+	harbour := testserver.WithHeader("X-Tenant-ID", "harbour")
+
+	// Products added for one tenant are not visible to others
+	product, resp := srv.GetProduct("prod-1")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	product.ID = "harbour-special"
+	resp = srv.Do(http.MethodPost, "/products", product, testserver.WithAPIKey(), harbour)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+
+	resp = srv.Do(http.MethodGet, "/products/harbour-special", nil, harbour)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "harbour", resp.Header.Get("X-Tenant-ID"))
+	_, resp = srv.GetProduct("harbour-special")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Orders are priced with the tenant's charges
+	order, resp := srv.PlaceOrder(&models.OrderRequest{
+		Items: []models.OrderItem{{ProductID: "prod-1", Quantity: 1}},
+	}, harbour)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+	assert.Equal(t, "harbour", order.TenantID)
+	assert.InDelta(t, 11.99, order.TotalAmount, 0.001)
+
+	// Unknown tenants are rejected
+	resp = srv.Do(http.MethodGet, "/products", nil, testserver.WithHeader("X-Tenant-ID", "missing"))
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, "TENANT_NOT_FOUND", resp.Error(t).Code)
+}
+
+func TestRouter_OrderETA(t *testing.T) {
+	srv := testserver.New(t)
+
+	order, resp := srv.PlaceOrder(&models.OrderRequest{
+		Items: []models.OrderItem{{ProductID: "prod-1", Quantity: 1}},
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+	require.NotNil(t, order.EstimatedReadyAt)
+
+	resp = srv.Do(http.MethodGet, "/orders/"+order.ID+"/eta", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var estimate map[string]interface{}
+	resp.Decode(t, &estimate)
+	assert.Equal(t, order.ID, estimate["order_id"])
+	assert.Equal(t, "preparing", estimate["status"])
+
+	resp = srv.Do(http.MethodPost, "/admin/kitchen/orders/"+order.ID+"/ready", nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Decode(t, &estimate)
+	assert.Equal(t, "ready", estimate["status"])
+}
+
+func TestRouter_OrderTimeline(t *testing.T) {
+	srv := testserver.New(t)
+
+	order, resp := srv.PlaceOrder(&models.OrderRequest{
+		Items: []models.OrderItem{{ProductID: "prod-1", Quantity: 1}},
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+
+	resp = srv.Do(http.MethodPost, "/admin/kitchen/orders/"+order.ID+"/ready", nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = srv.Do(http.MethodGet, "/orders/"+order.ID+"/timeline", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var timeline []models.StatusChange
+	resp.Decode(t, &timeline)
+	require.Len(t, timeline, 3)
+	assert.Equal(t, models.StatusChange{Status: "placed", At: timeline[0].At, Actor: "customer"}, timeline[0])
+	assert.Equal(t, "preparing", timeline[1].Status)
+	assert.Equal(t, "ready", timeline[2].Status)
+	assert.Equal(t, "staff", timeline[2].Actor)
+
+	// Orders the kitchen no longer tracks are read from their history
+	srv.Tenants.Default().Kitchen.Remove(order.ID)
+	resp = srv.Do(http.MethodGet, "/orders/"+order.ID+"/timeline", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	var kept []models.StatusChange
+	resp.Decode(t, &kept)
+	assert.Equal(t, timeline, kept)
+
+	resp = srv.Do(http.MethodGet, "/orders/missing/timeline", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestRouter_ProductReviews(t *testing.T) {
+	srv := testserver.New(t)
+
+	order, resp := srv.PlaceOrder(&models.OrderRequest{
+		Items: []models.OrderItem{{ProductID: "prod-1", Quantity: 1}},
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+
+	resp = srv.Do(http.MethodPost, "/products/prod-1/reviews", map[string]interface{}{
+		"orderId": order.ID,
+		"rating":  4,
+		"text":    "Crispy",
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+	var review models.Review
+	resp.Decode(t, &review)
+
+	// Unmoderated reviews do not rate the product
+	product, _ := srv.GetProduct("prod-1")
+	require.NotNil(t, product)
+	assert.Nil(t, product.Rating)
+
+	resp = srv.Do(http.MethodPost, "/admin/reviews/"+review.ID+"/approve", nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp = srv.Do(http.MethodPost, "/admin/reviews/"+review.ID+"/approve", nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	product, _ = srv.GetProduct("prod-1")
+	require.NotNil(t, product)
+	assert.Equal(t, &models.Rating{Average: 4, Count: 1}, product.Rating)
+
+	products, _ := srv.ListProducts()
+	for _, p := range products {
+		if p.ID == "prod-1" {
+			assert.Equal(t, &models.Rating{Average: 4, Count: 1}, p.Rating)
+		} else {
+			assert.Nil(t, p.Rating)
+		}
+	}
+}
+
+func TestRouter_ProductImages(t *testing.T) {
+	srv := testserver.New(t)
+
+	var photo bytes.Buffer
+	require.NoError(t, png.Encode(&photo, image.NewRGBA(image.Rect(0, 0, 800, 600))))
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("image", "photo.png")
+	require.NoError(t, err)
+	_, err = part.Write(photo.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	resp := srv.Do(http.MethodPost, "/admin/products/prod-1/image", body.Bytes(),
+		testserver.WithAPIKey(), testserver.WithHeader("Content-Type", writer.FormDataContentType()))
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	var uploaded models.Product
+	resp.Decode(t, &uploaded)
+
+	product, _ := srv.GetProduct("prod-1")
+	require.NotNil(t, product)
+	assert.Equal(t, uploaded.Image, product.Image)
+
+	// Renditions are served by the API itself
+	path := strings.TrimPrefix(product.Image.Mobile, "http://localhost")
+	require.True(t, strings.HasPrefix(path, "/public/images/default/prod-1/"), path)
+	resp = srv.Do(http.MethodGet, path, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "image/jpeg", resp.Header.Get("Content-Type"))
+	assert.NotEmpty(t, resp.Header.Get("ETag"))
+	assert.Contains(t, resp.Header.Get("Cache-Control"), "max-age=")
+	rendition, err := jpeg.Decode(bytes.NewReader(resp.Body))
+	require.NoError(t, err)
+	assert.Equal(t, 640, rendition.Bounds().Dx())
+
+	resp = srv.Do(http.MethodGet, "/public/images/default/prod-1/missing.jpg", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestRouter_StrictRequests(t *testing.T) {
+	srv := testserver.New(t)
+
+	t.Run("unsupported method", func(t *testing.T) {
+		resp := srv.Do(http.MethodDelete, "/products", nil)
+		assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+		assert.Equal(t, "GET, POST, OPTIONS", resp.Header.Get("Allow"))
+		assert.Equal(t, "METHOD_NOT_ALLOWED", resp.Error(t).Code)
+	})
+
+	t.Run("non-JSON body", func(t *testing.T) {
+		resp := srv.Do(http.MethodPost, "/orders", `{"items":[{"productId":"prod-1","quantity":1}]}`,
+			testserver.WithHeader("Content-Type", "text/plain"))
+		assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+		assert.Equal(t, "UNSUPPORTED_MEDIA_TYPE", resp.Error(t).Code)
+	})
+
+	t.Run("JSON with charset", func(t *testing.T) {
+		resp := srv.Do(http.MethodPost, "/orders", `{"items":[{"productId":"prod-1","quantity":1}]}`,
+			testserver.WithHeader("Content-Type", "application/json; charset=utf-8"))
+		assert.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+	})
+
+	t.Run("admin uploads are exempt", func(t *testing.T) {
+		resp := srv.Do(http.MethodPost, "/admin/restore", "not an archive",
+			testserver.WithAPIKey(), testserver.WithHeader("Content-Type", "application/gzip"))
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	})
+}
+
+func TestRouter_Blocklist(t *testing.T) {
+	srv := testserver.New(t)
+
+	// Block an abused key; other callers are unaffected
+	resp := srv.Do(http.MethodPost, "/admin/blocklist", map[string]string{"type": "api_key", "value": "abused-key"}, testserver.WithAPIKey())
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+
+	resp = srv.Do(http.MethodGet, "/products", nil, testserver.WithHeader("X-API-Key", "abused-key"))
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, "FORBIDDEN", resp.Error(t).Code)
+	resp = srv.Do(http.MethodGet, "/products", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Block every local caller except the admin key
+	resp = srv.Do(http.MethodPost, "/admin/blocklist", map[string]string{"kind": "allow", "type": "api_key", "value": testserver.APIKey}, testserver.WithAPIKey())
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+	resp = srv.Do(http.MethodPost, "/admin/blocklist", map[string]string{"type": "ip", "value": "127.0.0.0/8"}, testserver.WithAPIKey())
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+	var rangeEntry blocklist.Entry
+	resp.Decode(t, &rangeEntry)
+
+	resp = srv.Do(http.MethodPost, "/orders", map[string]interface{}{"items": []map[string]interface{}{{"productId": "prod-1", "quantity": 1}}})
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = srv.Do(http.MethodDelete, "/admin/blocklist/"+rangeEntry.ID, nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusNoContent, resp.StatusCode, "body: %s", resp.Body)
+	resp = srv.Do(http.MethodGet, "/products", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = srv.Do(http.MethodGet, "/admin/blocklist/audit", nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var audit []blocklist.AuditEvent
+	resp.Decode(t, &audit)
+	require.Len(t, audit, 4)
+	assert.Equal(t, blocklist.ActionRemoved, audit[3].Action)
+	assert.Equal(t, rangeEntry.ID, audit[3].Entry.ID)
+	assert.NotEmpty(t, audit[3].Actor)
+	assert.NotContains(t, string(resp.Body), testserver.APIKey)
+
+	// Entries survive a restart
+	reloaded, err := blocklist.Load(srv.Config.Auth.BlocklistFile)
+	require.NoError(t, err)
+	assert.Len(t, reloaded.Entries(), 2)
+	assert.Len(t, reloaded.Audit(), 4)
+}
+
+func TestRouter_OrderChallenge(t *testing.T) {
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.PostFormValue("response") == "solved" {
+			w.Write([]byte(`{"success":true}`))
+			return
+		}
+		w.Write([]byte(`{"success":false,"error-codes":["invalid-input-response"]}`))
+	}))
+	t.Cleanup(provider.Close)
+
+	srv := testserver.New(t, func(cfg *config.Config) {
+		cfg.Challenge = config.Challenge{
+			Provider:  config.ChallengeTurnstile,
+			Secret:    "secret",
+			VerifyURL: provider.URL,
+			Timeout:   time.Second,
+		}
+	})
+	order := &models.OrderRequest{Items: []models.OrderItem{{ProductID: "prod-1", Quantity: 1}}}
+
+	_, resp := srv.PlaceOrder(order)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, "CHALLENGE_FAILED", resp.Error(t).Code)
+
+	_, resp = srv.PlaceOrder(order, testserver.WithHeader("X-Challenge-Token", "forged"))
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	_, resp = srv.PlaceOrder(order, testserver.WithHeader("X-Challenge-Token", "solved"))
+	assert.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+
+	// Authenticated clients are not challenged
+	_, resp = srv.PlaceOrder(order, testserver.WithAPIKey())
+	assert.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+
+	// Only placing orders is challenged
+	resp = srv.Do(http.MethodGet, "/products", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestRouter_SignIn(t *testing.T) {
+	idp := testutil.NewIdentityProvider(t)
+	srv := testserver.New(t, func(cfg *config.Config) {
+		cfg.Auth.Google = idp.Config()
+	})
+
+	resp := srv.Do(http.MethodPost, "/auth/oidc/google", map[string]string{
+		"idToken": idp.Mint(t, idp.Claims("user-1", nil)),
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	var session auth.Session
+	resp.Decode(t, &session)
+	assert.Equal(t, "Bearer", session.TokenType)
+	assert.Equal(t, "jane@example.com", session.Customer.Email)
+
+	resp = srv.Do(http.MethodGet, "/auth/me", nil, testserver.WithHeader("Authorization", "Bearer "+session.AccessToken))
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	var customer auth.Customer
+	resp.Decode(t, &customer)
+	assert.Equal(t, session.Customer.ID, customer.ID)
+	assert.Equal(t, []auth.Identity{{Provider: "google", Subject: "user-1"}}, customer.Identities)
+
+	// Signing in again finds the same customer
+	resp = srv.Do(http.MethodPost, "/auth/oidc/google", map[string]string{
+		"idToken": idp.Mint(t, idp.Claims("user-1", nil)),
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var again auth.Session
+	resp.Decode(t, &again)
+	assert.Equal(t, session.Customer.ID, again.Customer.ID)
+
+	resp = srv.Do(http.MethodPost, "/auth/oidc/google", map[string]string{
+		"idToken": idp.Mint(t, idp.Claims("user-1", map[string]interface{}{"aud": "someone-else"})),
+	})
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, "INVALID_TOKEN", resp.Error(t).Code)
+
+	resp = srv.Do(http.MethodPost, "/auth/oidc/apple", map[string]string{"idToken": "token"})
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = srv.Do(http.MethodGet, "/auth/me", nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestRouter_RefreshAndLogout(t *testing.T) {
+	idp := testutil.NewIdentityProvider(t)
+	srv := testserver.New(t, func(cfg *config.Config) {
+		cfg.Auth.Google = idp.Config()
+	})
+
+	resp := srv.Do(http.MethodPost, "/auth/oidc/google", map[string]string{
+		"idToken": idp.Mint(t, idp.Claims("user-1", nil)),
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	var session auth.Session
+	resp.Decode(t, &session)
+	require.NotEmpty(t, session.RefreshToken)
+
+	resp = srv.Do(http.MethodPost, "/auth/refresh", map[string]string{"refreshToken": session.RefreshToken})
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	var refreshed auth.Session
+	resp.Decode(t, &refreshed)
+	assert.Equal(t, session.Customer.ID, refreshed.Customer.ID)
+	assert.NotEqual(t, session.RefreshToken, refreshed.RefreshToken)
+
+	// A refresh token can only be used once
+	resp = srv.Do(http.MethodPost, "/auth/refresh", map[string]string{"refreshToken": session.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, "INVALID_TOKEN", resp.Error(t).Code)
+
+	// Reuse ended the session, so sign in again and log out
+	resp = srv.Do(http.MethodPost, "/auth/oidc/google", map[string]string{
+		"idToken": idp.Mint(t, idp.Claims("user-1", nil)),
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Decode(t, &session)
+	bearer := testserver.WithHeader("Authorization", "Bearer "+session.AccessToken)
+
+	resp = srv.Do(http.MethodPost, "/auth/logout", nil, bearer)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode, "body: %s", resp.Body)
+
+	resp = srv.Do(http.MethodGet, "/auth/me", nil, bearer)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp = srv.Do(http.MethodPost, "/auth/refresh", map[string]string{"refreshToken": session.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestRouter_Favorites(t *testing.T) {
+	srv := testserver.New(t)
+
+	resp := srv.Do(http.MethodPost, "/auth/register", map[string]string{
+		"email": "jane@example.com", "password": "correct horse", "name": "Jane",
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+	var session auth.Session
+	resp.Decode(t, &session)
+	bearer := testserver.WithHeader("Authorization", "Bearer "+session.AccessToken)
+
+	favorites := func() []string {
+		t.Helper()
+		resp := srv.Do(http.MethodGet, "/me/favorites", nil, bearer)
+		require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+		assert.NotEmpty(t, resp.Header.Get(handlers.CatalogRevisionHeader))
+		var products []models.Product
+		resp.Decode(t, &products)
+		ids := make([]string, len(products))
+		for i, product := range products {
+			ids[i] = product.ID
+		}
+		return ids
+	}
+
+	// Favorites are listed in the order they were saved
+	assert.Empty(t, favorites())
+	for _, id := range []string{"prod-2", "prod-1", "prod-2"} {
+		resp = srv.Do(http.MethodPut, "/me/favorites/"+id, nil, bearer)
+		require.Equal(t, http.StatusNoContent, resp.StatusCode, "body: %s", resp.Body)
+	}
+	assert.Equal(t, []string{"prod-2", "prod-1"}, favorites())
+	assert.Equal(t, http.StatusNotFound, srv.Do(http.MethodPut, "/me/favorites/missing", nil, bearer).StatusCode)
+
+	// Deleted products cannot be ordered, so they are left out
+	resp = srv.Do(http.MethodDelete, "/products/prod-2", nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusNoContent, resp.StatusCode, "body: %s", resp.Body)
+	assert.Equal(t, []string{"prod-1"}, favorites())
+	assert.Equal(t, http.StatusNotFound, srv.Do(http.MethodPut, "/me/favorites/prod-2", nil, bearer).StatusCode)
+
+	resp = srv.Do(http.MethodDelete, "/me/favorites/prod-1", nil, bearer)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, http.StatusNoContent, srv.Do(http.MethodDelete, "/me/favorites/prod-1", nil, bearer).StatusCode)
+	assert.Empty(t, favorites())
+
+	// Favorites belong to signed-in customers
+	assert.Equal(t, http.StatusUnauthorized, srv.Do(http.MethodGet, "/me/favorites", nil).StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, srv.Do(http.MethodPut, "/me/favorites/prod-1", nil).StatusCode)
+}
+
+func TestRouter_PasswordLockout(t *testing.T) {
+	srv := testserver.New(t)
+
+	resp := srv.Do(http.MethodPost, "/auth/register", map[string]string{
+		"email": "jane@example.com", "password": "correct horse", "name": "Jane",
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+	var session auth.Session
+	resp.Decode(t, &session)
+
+	resp = srv.Do(http.MethodGet, "/auth/me", nil, testserver.WithHeader("Authorization", "Bearer "+session.AccessToken))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	login := func(password string) *testserver.Response {
+		return srv.Do(http.MethodPost, "/auth/login", map[string]string{"email": "jane@example.com", "password": password})
+	}
+	for i := 0; i < srv.Config.Auth.Passwords.MaxFailedLogins-1; i++ {
+		resp = login("wrong horse")
+		assert.Equal(t, "INVALID_CREDENTIALS", resp.Error(t).Code)
+	}
+	resp = login("wrong horse")
+	assert.Equal(t, http.StatusLocked, resp.StatusCode)
+	assert.Equal(t, "ACCOUNT_LOCKED", login("correct horse").Error(t).Code)
+
+	// The emailed code unlocks the account
+	email := srv.Mailbox.Last(t)
+	assert.Equal(t, "jane@example.com", email.To)
+	resp = srv.Do(http.MethodPost, "/auth/unlock", map[string]string{"code": emailedCode(t, email.Body)})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode, "body: %s", resp.Body)
+
+	resp = login("correct horse")
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+}
+
+func TestRouter_EmailVerificationAndPasswordReset(t *testing.T) {
+	srv := testserver.New(t, func(cfg *config.Config) { cfg.Auth.AppURL = "https://app.example.com" })
+
+	resp := srv.Do(http.MethodPost, "/auth/register", map[string]string{
+		"email": "jane@example.com", "password": "correct horse", "name": "Jane",
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+	var session auth.Session
+	resp.Decode(t, &session)
+	bearer := testserver.WithHeader("Authorization", "Bearer "+session.AccessToken)
+
+	// Registering emailed a link to verify the address; resending replaces it
+	assert.Contains(t, srv.Mailbox.Last(t).Body, "https://app.example.com/verify-email?code=")
+	resp = srv.Do(http.MethodPost, "/auth/verify-email/resend", nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp = srv.Do(http.MethodPost, "/auth/verify-email/resend", nil, bearer)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode, "body: %s", resp.Body)
+	require.Len(t, srv.Mailbox.Emails(), 2)
+
+	resp = srv.Do(http.MethodPost, "/auth/verify-email", map[string]string{"code": emailedCode(t, srv.Mailbox.Last(t).Body)})
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	var customer auth.Customer
+	resp.Decode(t, &customer)
+	assert.True(t, customer.EmailVerified)
+
+	// Resetting the password ends the session started with the old one
+	resp = srv.Do(http.MethodPost, "/auth/password/forgot", map[string]string{"email": "jane@example.com"})
+	require.Equal(t, http.StatusAccepted, resp.StatusCode, "body: %s", resp.Body)
+	email := srv.Mailbox.Last(t)
+	assert.Contains(t, email.Body, "https://app.example.com/reset-password?code=")
+	resp = srv.Do(http.MethodPost, "/auth/password/reset", map[string]string{
+		"code": emailedCode(t, email.Body), "password": "battery staple",
+	})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode, "body: %s", resp.Body)
+
+	resp = srv.Do(http.MethodGet, "/auth/me", nil, bearer)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp = srv.Do(http.MethodPost, "/auth/login", map[string]string{"email": "jane@example.com", "password": "battery staple"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+}
+
+func TestRouter_Roles(t *testing.T) {
+	srv := testserver.New(t)
+	kitchenKey := testserver.WithHeader("X-API-Key", testserver.KitchenAPIKey)
+	supportKey := testserver.WithHeader("X-API-Key", testserver.SupportAPIKey)
+
+	// Each staff key reaches the routes of its role only
+	assert.Equal(t, http.StatusOK, srv.Do(http.MethodGet, "/admin/kitchen/orders", nil, kitchenKey).StatusCode)
+	assert.Equal(t, http.StatusForbidden, srv.Do(http.MethodGet, "/admin/reviews", nil, kitchenKey).StatusCode)
+	assert.Equal(t, http.StatusOK, srv.Do(http.MethodGet, "/admin/reviews", nil, supportKey).StatusCode)
+	assert.Equal(t, http.StatusForbidden, srv.Do(http.MethodGet, "/admin/kitchen/orders", nil, supportKey).StatusCode)
+	assert.Equal(t, http.StatusForbidden, srv.Do(http.MethodDelete, "/products/prod-1", nil, supportKey).StatusCode)
+	assert.Equal(t, http.StatusOK, srv.Do(http.MethodGet, "/admin/reviews", nil, testserver.WithAPIKey()).StatusCode)
+
+	// Customers are staff once an admin grants them a role
+	resp := srv.Do(http.MethodPost, "/auth/register", map[string]string{"email": "cook@example.com", "password": "correct horse"})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+	var session auth.Session
+	resp.Decode(t, &session)
+	bearer := testserver.WithHeader("Authorization", "Bearer "+session.AccessToken)
+	assert.Equal(t, http.StatusForbidden, srv.Do(http.MethodGet, "/admin/kitchen/orders", nil, bearer).StatusCode)
+
+	resp = srv.Do(http.MethodPut, "/admin/customers/"+session.Customer.ID+"/roles", map[string][]string{"roles": {"kitchen"}}, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+
+	// Granting roles ends the sessions issued without them
+	assert.Equal(t, http.StatusUnauthorized, srv.Do(http.MethodGet, "/admin/kitchen/orders", nil, bearer).StatusCode)
+	resp = srv.Do(http.MethodPost, "/auth/login", map[string]string{"email": "cook@example.com", "password": "correct horse"})
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	resp.Decode(t, &session)
+	bearer = testserver.WithHeader("Authorization", "Bearer "+session.AccessToken)
+	assert.Equal(t, http.StatusOK, srv.Do(http.MethodGet, "/admin/kitchen/orders", nil, bearer).StatusCode)
+	assert.Equal(t, http.StatusForbidden, srv.Do(http.MethodPost, "/admin/reload", nil, bearer).StatusCode)
+	assert.Equal(t, http.StatusOK, srv.Do(http.MethodGet, "/auth/me", nil, bearer).StatusCode)
+}
+
+func TestRouter_APIKeyUsage(t *testing.T) {
+	srv := testserver.New(t)
+	partner := testserver.WithHeader("X-API-Key", testserver.KitchenAPIKey)
+
+	resp := srv.Do(http.MethodPost, "/orders", `{"items":[{"productId":"prod-1","quantity":1}]}`, partner)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+	srv.Do(http.MethodPost, "/orders", `{"items":[{"productId":"missing","quantity":1}]}`, partner)
+	srv.Do(http.MethodGet, "/products", nil, partner)
+
+	resp = srv.Do(http.MethodGet, "/admin/apikeys/"+apikeys.ConfiguredID(testserver.KitchenAPIKey)+"/usage", nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	var report usage.Report
+	resp.Decode(t, &report)
+	assert.Equal(t, usage.Counts{Requests: 3, ClientErrors: 1, Orders: 1}, report.Total)
+	require.Len(t, report.Days, 1)
+
+	// Only admins see usage, and the request is counted against their key
+	resp = srv.Do(http.MethodGet, "/admin/apikeys/"+apikeys.ConfiguredID(testserver.KitchenAPIKey)+"/usage", nil, partner)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp = srv.Do(http.MethodGet, "/admin/apikeys/"+apikeys.ConfiguredID(testserver.APIKey)+"/usage", nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+
+	// Usage survives a restart once flushed
+	require.NoError(t, srv.Usage.Flush())
+	reloaded, err := usage.Load(srv.Config.Usage.File)
+	require.NoError(t, err)
+	_, err = reloaded.Report(apikeys.ConfiguredID(testserver.KitchenAPIKey), time.Time{}, time.Time{})
+	assert.NoError(t, err)
+}
+
+func TestRouter_APIKeys(t *testing.T) {
+	srv := testserver.New(t)
+
+	// Configured keys are imported, hashed
+	resp := srv.Do(http.MethodGet, "/admin/apikeys", nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	var keys []apikeys.Key
+	resp.Decode(t, &keys)
+	assert.Len(t, keys, 3)
+	raw, err := os.ReadFile(srv.Config.Auth.KeysFile)
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), testserver.APIKey)
+
+	resp = srv.Do(http.MethodPost, "/admin/apikeys", map[string]interface{}{"name": "Delivery partner", "scopes": []string{"kitchen"}}, testserver.WithAPIKey())
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+	var created apikeys.Secret
+	resp.Decode(t, &created)
+	partner := testserver.WithHeader("X-API-Key", created.Secret)
+	assert.Equal(t, http.StatusOK, srv.Do(http.MethodGet, "/admin/kitchen/orders", nil, partner).StatusCode)
+	assert.Equal(t, http.StatusForbidden, srv.Do(http.MethodGet, "/admin/reviews", nil, partner).StatusCode)
+
+	// Rotation refuses the old secret right away
+	resp = srv.Do(http.MethodPost, "/admin/apikeys/"+created.ID+"/rotate", nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	var rotated apikeys.Secret
+	resp.Decode(t, &rotated)
+	assert.Equal(t, http.StatusUnauthorized, srv.Do(http.MethodGet, "/admin/kitchen/orders", nil, partner).StatusCode)
+	partner = testserver.WithHeader("X-API-Key", rotated.Secret)
+	assert.Equal(t, http.StatusOK, srv.Do(http.MethodGet, "/admin/kitchen/orders", nil, partner).StatusCode)
+
+	// Keys survive a restart, and disabled configured keys are not imported again
+	resp = srv.Do(http.MethodPost, "/admin/apikeys/"+apikeys.ConfiguredID(testserver.SupportAPIKey)+"/disable", nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	reloaded, err := apikeys.Load(srv.Config.Auth)
+	require.NoError(t, err)
+	_, ok := reloaded.Authenticate(rotated.Secret)
+	assert.True(t, ok)
+	_, ok = reloaded.Authenticate(testserver.SupportAPIKey)
+	assert.False(t, ok)
+
+	resp = srv.Do(http.MethodPost, "/admin/apikeys/"+created.ID+"/disable", nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	assert.Equal(t, http.StatusUnauthorized, srv.Do(http.MethodGet, "/admin/kitchen/orders", nil, partner).StatusCode)
+
+	// Requests refused for a disabled key are still counted against it
+	resp = srv.Do(http.MethodGet, "/admin/apikeys/"+created.ID+"/usage", nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	var report usage.Report
+	resp.Decode(t, &report)
+	assert.Equal(t, usage.Counts{Requests: 4, ClientErrors: 2}, report.Total)
+
+	// Only admins manage keys
+	kitchen := testserver.WithHeader("X-API-Key", testserver.KitchenAPIKey)
+	assert.Equal(t, http.StatusForbidden, srv.Do(http.MethodGet, "/admin/apikeys", nil, kitchen).StatusCode)
+}
+
+func TestRouter_POS(t *testing.T) {
+	srv := testserver.New(t)
+
+	resp := srv.Do(http.MethodPost, "/admin/apikeys", map[string]interface{}{"name": "Till", "scopes": []string{"pos"}}, testserver.WithAPIKey())
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+	var created apikeys.Secret
+	resp.Decode(t, &created)
+	till := testserver.WithHeader("X-API-Key", created.Secret)
+
+	product, err := srv.Store.GetProduct("prod-1")
+	require.NoError(t, err)
+	product.SKU = "WAF-BER"
+	require.NoError(t, srv.Store.UpdateProduct(product))
+
+	levels := map[string]interface{}{"levels": []map[string]interface{}{{"sku": "WAF-BER", "quantity": 1}}}
+	resp = srv.Do(http.MethodPut, "/pos/inventory", levels, till)
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+
+	// The last unit can be ordered once
+	order := map[string]interface{}{"items": []map[string]interface{}{{"productId": "prod-1", "quantity": 1}}}
+	resp = srv.Do(http.MethodPost, "/orders", order)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+	var placed models.Order
+	resp.Decode(t, &placed)
+	resp = srv.Do(http.MethodPost, "/orders", order)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	var errResp models.ErrorResponse
+	resp.Decode(t, &errResp)
+	assert.Equal(t, "OUT_OF_STOCK", errResp.Code)
+
+	// The POS collects the order it has to account for
+	resp = srv.Do(http.MethodGet, "/pos/orders", nil, till)
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	var page pos.ExportPage
+	resp.Decode(t, &page)
+	require.Len(t, page.Orders, 1)
+	assert.Equal(t, placed.ID, page.Orders[0].OrderID)
+	assert.Equal(t, "WAF-BER", page.Orders[0].Items[0].SKU)
+
+	// Only the POS and admins sync stock
+	kitchen := testserver.WithHeader("X-API-Key", testserver.KitchenAPIKey)
+	assert.Equal(t, http.StatusForbidden, srv.Do(http.MethodPut, "/pos/inventory", levels, kitchen).StatusCode)
+	assert.Equal(t, http.StatusForbidden, srv.Do(http.MethodGet, "/admin/kitchen/orders", nil, till).StatusCode)
+}
+
+func TestRouter_CourierTracking(t *testing.T) {
+	srv := testserver.New(t, func(cfg *config.Config) {
+		cfg.Zones = []config.Zone{{Name: "cbd", Postcodes: []string{"2000"}}}
+	})
+
+	resp := srv.Do(http.MethodPost, "/admin/apikeys", map[string]interface{}{"name": "Courier", "scopes": []string{"courier"}}, testserver.WithAPIKey())
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+	var created apikeys.Secret
+	resp.Decode(t, &created)
+	courier := testserver.WithHeader("X-API-Key", created.Secret)
+	dispatcher := testserver.WithHeader("X-API-Key", testserver.KitchenAPIKey)
+
+	order, resp := srv.PlaceOrder(&models.OrderRequest{
+		Items:           []models.OrderItem{{ProductID: "prod-1", Quantity: 1}},
+		DeliveryAddress: &models.Address{Line1: "1 George St", Postcode: "2000"},
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+	path := "/orders/" + order.ID
+
+	track := func() couriers.Tracking {
+		t.Helper()
+		resp := srv.Do(http.MethodGet, path+"/tracking", nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+		var tracking couriers.Tracking
+		resp.Decode(t, &tracking)
+		return tracking
+	}
+	advance := func(status string) *testserver.Response {
+		return srv.Do(http.MethodPost, "/courier"+path+"/status", map[string]string{"status": status}, courier)
+	}
+
+	// Nothing moves until a courier is assigned, by the kitchen
+	assert.Empty(t, track().Status)
+	resp = advance(couriers.StatusPickedUp)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, "INVALID_DELIVERY_STATUS", resp.Error(t).Code)
+	sam := map[string]string{"id": "courier-7", "name": "Sam"}
+	assert.Equal(t, http.StatusForbidden, srv.Do(http.MethodPut, "/admin"+path+"/courier", sam, courier).StatusCode)
+	resp = srv.Do(http.MethodPut, "/admin"+path+"/courier", sam, dispatcher)
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+
+	resp = srv.Do(http.MethodPut, "/courier"+path+"/location", map[string]float64{"latitude": -33.8688, "longitude": 151.2093}, courier)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode, "body: %s", resp.Body)
+	tracking := track()
+	assert.Equal(t, couriers.StatusAssigned, tracking.Status)
+	assert.Equal(t, &couriers.Courier{ID: "courier-7", Name: "Sam"}, tracking.Courier)
+	require.NotNil(t, tracking.Location)
+	assert.Equal(t, -33.8688, tracking.Location.Latitude)
+
+	// Statuses only move forward
+	for _, status := range []string{couriers.StatusPickedUp, couriers.StatusEnRoute} {
+		resp = advance(status)
+		require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	}
+	assert.Equal(t, http.StatusConflict, advance(couriers.StatusPickedUp).StatusCode)
+	assert.Equal(t, http.StatusConflict, srv.Do(http.MethodPut, "/admin"+path+"/courier", sam, dispatcher).StatusCode)
+	resp = advance(couriers.StatusDelivered)
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+
+	tracking = track()
+	assert.Equal(t, couriers.StatusDelivered, tracking.Status)
+	assert.Nil(t, tracking.Courier)
+	assert.Nil(t, tracking.Location)
+	require.Len(t, tracking.History, 4)
+	assert.Equal(t, models.ActorStaff, tracking.History[0].Actor)
+	assert.Equal(t, models.ActorCourier, tracking.History[3].Actor)
+	resp = srv.Do(http.MethodPut, "/courier"+path+"/location", map[string]float64{"latitude": 0, "longitude": 0}, courier)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	// The statuses are part of the order's timeline, after the kitchen
+	// finished it when it was picked up
+	var timeline []models.StatusChange
+	resp = srv.Do(http.MethodGet, path+"/timeline", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	resp.Decode(t, &timeline)
+	statuses := make([]string, len(timeline))
+	for i, change := range timeline {
+		statuses[i] = change.Status
+	}
+	assert.Equal(t, []string{kitchen.StatusReady, couriers.StatusPickedUp, couriers.StatusEnRoute, couriers.StatusDelivered}, statuses[len(statuses)-4:])
+	assert.Less(t, slices.Index(statuses, couriers.StatusAssigned), slices.Index(statuses, kitchen.StatusReady))
+
+	// Orders that are not delivered have no courier
+	takeaway, resp := srv.PlaceOrder(&models.OrderRequest{Items: []models.OrderItem{{ProductID: "prod-2", Quantity: 1}}})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+	resp = srv.Do(http.MethodPut, "/admin/orders/"+takeaway.ID+"/courier", sam, dispatcher)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, "NOT_DELIVERY", resp.Error(t).Code)
+	assert.Equal(t, http.StatusNotFound, srv.Do(http.MethodGet, "/orders/"+takeaway.ID+"/tracking", nil).StatusCode)
+	assert.Equal(t, http.StatusNotFound, srv.Do(http.MethodGet, "/orders/missing/tracking", nil).StatusCode)
+}
+
+// emailedCode returns the code in an email, given on a line of its own or as
+// the code parameter of a link
+func emailedCode(t *testing.T, body string) string {
+	t.Helper()
+	for _, line := range strings.Split(body, "\n") {
+		if _, code, ok := strings.Cut(line, "?code="); ok {
+			return code
+		}
+		if line != "" && !strings.Contains(line, " ") {
+			return line
+		}
+	}
+	t.Fatalf("no code in %q", body)
+	return ""
+}
+
+func TestRouter_InvoiceNumbers(t *testing.T) {
+	srv := testserver.New(t)
+
+	for _, want := range []string{"INV-000001", "INV-000002"} {
+		resp := srv.Do(http.MethodPost, "/orders", `{"items":[{"productId":"prod-1","quantity":1}]}`)
+		require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+		var order models.Order
+		resp.Decode(t, &order)
+		assert.Equal(t, want, order.InvoiceNumber)
+	}
+
+	resp := srv.Do(http.MethodGet, "/admin/invoices", nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	var status invoices.Status
+	resp.Decode(t, &status)
+	assert.Equal(t, invoices.Status{Prefix: "INV-", Issued: 2, Last: "INV-000002", Next: "INV-000003", Gaps: []invoices.Gap{}}, status)
+
+	// The sequence survives a restart
+	reopened, err := invoices.Open(filepath.Join(srv.Config.Invoices.Dir, config.DefaultTenantID+".jsonl"), "INV-")
+	require.NoError(t, err)
+	assert.Equal(t, "INV-000003", reopened.Status().Next)
+}
+
+func TestRouter_PlaceOrderIdempotency(t *testing.T) {
+	srv := testserver.New(t)
+	body := `{"items":[{"productId":"prod-1","quantity":2}]}`
+	key := testserver.WithHeader(middleware.IdempotencyKeyHeader, "order-attempt-1")
+
+	resp := srv.Do(http.MethodPost, "/orders", body, key)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+	var placed models.Order
+	resp.Decode(t, &placed)
+	assert.Empty(t, resp.Header.Get(middleware.IdempotentReplayedHeader))
+
+	// A retry is answered with the order already placed
+	resp = srv.Do(http.MethodPost, "/orders", body, key)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+	var replayed models.Order
+	resp.Decode(t, &replayed)
+	assert.Equal(t, placed.ID, replayed.ID)
+	assert.Equal(t, "true", resp.Header.Get(middleware.IdempotentReplayedHeader))
+
+	resp = srv.Do(http.MethodGet, "/admin/orders", nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	var listed handlers.OrderListResponse
+	resp.Decode(t, &listed)
+	assert.Len(t, listed.Orders, 1, "the retry places no second order")
+
+	// The key cannot be reused for another order
+	resp = srv.Do(http.MethodPost, "/orders", `{"items":[{"productId":"prod-1","quantity":3}]}`, key)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, apierrors.IdempotencyKeyReused, resp.Error(t).Code)
+
+	// Keys belong to their caller
+	resp = srv.Do(http.MethodPost, "/orders", body, key, testserver.WithAPIKey())
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+	var other models.Order
+	resp.Decode(t, &other)
+	assert.NotEqual(t, placed.ID, other.ID)
+
+	// Failed orders are not remembered, so they can be retried
+	failing := testserver.WithHeader(middleware.IdempotencyKeyHeader, "order-attempt-2")
+	resp = srv.Do(http.MethodPost, "/orders", `{"items":[{"productId":"missing","quantity":1}]}`, failing)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp = srv.Do(http.MethodPost, "/orders", body, failing)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+}
+
+func TestRouter_PlaceOrderDryRun(t *testing.T) {
+	srv := testserver.New(t)
+	body := `{"items":[{"productId":"prod-1","quantity":2}]}`
+
+	for _, opt := range []struct {
+		path    string
+		options []testserver.RequestOption
+	}{
+		{path: "/orders?dry_run=true"},
+		{path: "/orders", options: []testserver.RequestOption{testserver.WithHeader("X-Dry-Run", "true")}},
+	} {
+		resp := srv.Do(http.MethodPost, opt.path, body, opt.options...)
+		require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+		var order models.Order
+		resp.Decode(t, &order)
+		assert.InDelta(t, 19.98, order.TotalAmount, 0.001)
+		assert.Empty(t, order.InvoiceNumber)
+
+		resp = srv.Do(http.MethodGet, "/admin/orders/"+order.ID, nil, testserver.WithAPIKey())
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, "dry runs are not kept")
+	}
+
+	// The query parameter wins over the header
+	resp := srv.Do(http.MethodPost, "/orders?dry_run=false", body, testserver.WithHeader("X-Dry-Run", "true"))
+	assert.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+
+	resp = srv.Do(http.MethodPost, "/orders?dry_run=maybe", body)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "INVALID_REQUEST", resp.Error(t).Code)
+}
+
+func TestRouter_OrderHistory(t *testing.T) {
+	srv := testserver.New(t)
+
+	resp := srv.Do(http.MethodPost, "/orders", `{"items":[{"productId":"prod-1","quantity":1}]}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+	var order models.Order
+	resp.Decode(t, &order)
+
+	resp = srv.Do(http.MethodPost, "/admin/kitchen/orders/"+order.ID+"/ready", nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	resp = srv.Do(http.MethodPost, "/admin/kitchen/orders/"+order.ID+"/ready", nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+
+	resp = srv.Do(http.MethodGet, "/admin/orders/"+order.ID, nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	var history orders.History
+	resp.Decode(t, &history)
+	require.Len(t, history.Events, 3, "marking an order ready twice records it once")
+	assert.Equal(t, orders.EventPlaced, history.Events[0].Type)
+	assert.Equal(t, order.InvoiceNumber, history.Events[0].Order.InvoiceNumber)
+	assert.Equal(t, "preparing", history.Events[1].Change.Status, "the kitchen started the order as it was placed")
+	assert.Equal(t, models.ActorKitchen, history.Events[1].Change.Actor)
+	assert.Equal(t, "ready", history.Events[2].Change.Status)
+	assert.Equal(t, "ready", history.Order.Status)
+	assert.Equal(t, models.ActorStaff, history.Order.StatusHistory[len(history.Order.StatusHistory)-1].Actor)
+
+	// The history survives a restart
+	reopened, err := orders.Open(filepath.Join(srv.Config.Orders.EventsDir, config.DefaultTenantID+".jsonl"))
+	require.NoError(t, err)
+	replayed, ok := reopened.History(order.ID)
+	require.True(t, ok)
+	assert.Equal(t, "ready", replayed.Order.Status)
+}
+
+func TestRouter_OrderHistoryInMemory(t *testing.T) {
+	srv := testserver.New(t, func(cfg *config.Config) { cfg.Orders.EventsDir = "" })
+
+	order, resp := srv.PlaceOrder(&models.OrderRequest{
+		Items: []models.OrderItem{{ProductID: "prod-1", Quantity: 1}},
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+
+	// Orders are kept in memory without an events directory
+	resp = srv.Do(http.MethodGet, "/admin/orders/"+order.ID, nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	var history orders.History
+	resp.Decode(t, &history)
+	require.NotEmpty(t, history.Events)
+	assert.Equal(t, orders.EventPlaced, history.Events[0].Type)
+	assert.Equal(t, order.ID, history.Order.ID)
+	assert.InDelta(t, order.TotalAmount, history.Order.TotalAmount, 0.001)
+}
+
+func TestRouter_OrderDashboard(t *testing.T) {
+	srv := testserver.New(t)
+
+	for range 2 {
+		resp := srv.Do(http.MethodPost, "/orders", `{"items":[{"productId":"prod-1","quantity":1}]}`)
+		require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+	}
+	srv.Tenants.Default().Dashboard.Refresh()
+
+	resp := srv.Do(http.MethodGet, "/admin/dashboard/orders?hours=3", nil, testserver.WithHeader("X-API-Key", testserver.SupportAPIKey))
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	var summary dashboard.Summary
+	resp.Decode(t, &summary)
+	assert.Equal(t, 2, summary.Orders)
+	assert.Equal(t, 2, summary.QueueDepth)
+	assert.Len(t, summary.Hours, 3)
+	assert.Equal(t, 2, summary.Hours[2].Orders)
+}
+
+func TestRouter_ListOrders(t *testing.T) {
+	srv := testserver.New(t)
+
+	var placed []string
+	for range 3 {
+		resp := srv.Do(http.MethodPost, "/orders", `{"items":[{"productId":"prod-1","quantity":1}]}`)
+		require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+		var order models.Order
+		resp.Decode(t, &order)
+		placed = append(placed, order.ID)
+	}
+
+	var listed []string
+	path := "/admin/orders?limit=2"
+	for path != "" {
+		resp := srv.Do(http.MethodGet, path, nil, testserver.WithAPIKey())
+		require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+		var page handlers.OrderListResponse
+		resp.Decode(t, &page)
+		for _, order := range page.Orders {
+			listed = append(listed, order.ID)
+		}
+		path = ""
+		if page.Next != "" {
+			path = "/admin/orders?limit=2&cursor=" + page.Next
+		}
+	}
+	assert.Equal(t, placed, listed)
+
+	// Cursors of one listing are not accepted by another
+	resp := srv.Do(http.MethodGet, "/products?limit=1", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	productCursor := resp.Header.Get(handlers.NextCursorHeader)
+	require.NotEmpty(t, productCursor)
+	resp = srv.Do(http.MethodGet, "/admin/orders?cursor="+productCursor, nil, testserver.WithAPIKey())
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// Newest first, with a cursor that only continues the same sort
+	resp = srv.Do(http.MethodGet, "/admin/orders?sort=-created_at&limit=1", nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	var newest handlers.OrderListResponse
+	resp.Decode(t, &newest)
+	require.Len(t, newest.Orders, 1)
+	assert.Equal(t, placed[2], newest.Orders[0].ID)
+	resp = srv.Do(http.MethodGet, "/admin/orders?sort=-created_at&cursor="+newest.Next, nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	var rest handlers.OrderListResponse
+	resp.Decode(t, &rest)
+	require.Len(t, rest.Orders, 2)
+	assert.Equal(t, placed[1], rest.Orders[0].ID)
+	resp = srv.Do(http.MethodGet, "/admin/orders?cursor="+newest.Next, nil, testserver.WithAPIKey())
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestRouter_Links(t *testing.T) {
+	srv := testserver.New(t, func(cfg *config.Config) {
+		cfg.Server.Links = true
+	})
+
+	product, resp := srv.GetProduct("prod-1")
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	require.NotNil(t, product.Links)
+	assert.Equal(t, "/products/prod-1", product.Links.Self.Href)
+	resp = srv.Do(http.MethodGet, product.Links.Reviews.Href, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	order, resp := srv.PlaceOrder(&models.OrderRequest{Items: []models.OrderItem{{ProductID: "prod-1", Quantity: 2}}})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+	require.NotNil(t, order.Links)
+	require.Len(t, order.Links.Products, 1)
+	resp = srv.Do(http.MethodGet, order.Links.Products[0].Href, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NotNil(t, order.Links.ETA)
+	resp = srv.Do(http.MethodGet, order.Links.ETA.Href, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = srv.Do(http.MethodGet, order.Links.Timeline.Href, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Links are served, never stored
+	stored, err := srv.Tenants.Default().Store.GetProduct("prod-1")
+	require.NoError(t, err)
+	assert.Nil(t, stored.Links)
+
+	// Responses carry no links unless configured to
+	plain, resp := testserver.New(t).GetProduct("prod-1")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Nil(t, plain.Links)
+}
+
+func TestRouter_Envelope(t *testing.T) {
+	srv := testserver.New(t)
+	envelope := testserver.WithHeader(middleware.EnvelopeHeader, "true")
+
+	resp := srv.Do(http.MethodGet, "/products?limit=1", nil, envelope)
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	var env models.Envelope
+	resp.Decode(t, &env)
+	var products []models.Product
+	require.NoError(t, json.Unmarshal(env.Data, &products))
+	require.Len(t, products, 1)
+	assert.NotEmpty(t, env.Meta.RequestID)
+	require.NotNil(t, env.Meta.Pagination)
+	assert.Equal(t, 1, env.Meta.Pagination.Limit)
+	assert.Equal(t, resp.Header.Get(handlers.NextCursorHeader), env.Meta.Pagination.NextCursor)
+
+	resp = srv.Do(http.MethodGet, "/products/missing", nil, envelope)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	env = models.Envelope{}
+	resp.Decode(t, &env)
+	require.Len(t, env.Errors, 1)
+	assert.Equal(t, apierrors.NotFound, env.Errors[0].Code)
+
+	// Servers can envelope every response, leaving clients to opt out
+	srv = testserver.New(t, func(cfg *config.Config) {
+		cfg.Server.Envelope = true
+	})
+	resp = srv.Do(http.MethodGet, "/products/prod-1", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	env = models.Envelope{}
+	resp.Decode(t, &env)
+	assert.Contains(t, string(env.Data), `"prod-1"`)
+	resp = srv.Do(http.MethodGet, "/products/prod-1", nil, testserver.WithHeader(middleware.EnvelopeHeader, "false"))
+	var bare models.Product
+	resp.Decode(t, &bare)
+	assert.Equal(t, "prod-1", bare.ID)
+}
+
+func TestRouter_Deprecation(t *testing.T) {
+	srv := testserver.New(t, func(cfg *config.Config) {
+		cfg.Server.DeprecationsFile = filepath.Join(t.TempDir(), "deprecations.json")
+		require.NoError(t, os.WriteFile(cfg.Server.DeprecationsFile, []byte(`[
+			{"method": "GET", "route": "/products/{id}", "since": "2026-01-01T00:00:00Z", "sunset": "2999-01-01T00:00:00Z"},
+			{"method": "GET", "route": "/products/{id}/reviews", "since": "2026-01-01T00:00:00Z", "sunset": "2026-02-01T00:00:00Z"}
+		]`), 0644))
+	})
+
+	resp := srv.Do(http.MethodGet, "/products/prod-1", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "@1767225600", resp.Header.Get("Deprecation"))
+	assert.NotEmpty(t, resp.Header.Get("Sunset"))
+
+	resp = srv.Do(http.MethodGet, "/products", nil)
+	assert.Empty(t, resp.Header.Get("Deprecation"), "only the noticed route is deprecated")
+
+	resp = srv.Do(http.MethodGet, "/products/prod-1/reviews", nil)
+	require.Equal(t, http.StatusGone, resp.StatusCode)
+	assert.Equal(t, apierrors.Gone, resp.Error(t).Code)
+}
+
+func TestRouter_StartupReport(t *testing.T) {
+	srv := testserver.New(t)
+
+	resp := srv.Do(http.MethodGet, "/admin/startup", nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	var report startup.Report
+	resp.Decode(t, &report)
+	assert.True(t, report.OK)
+	require.Len(t, report.Catalogs, 1)
+	assert.Equal(t, config.DefaultTenantID, report.Catalogs[0].Tenant)
+	assert.Positive(t, report.Catalogs[0].Products)
+
+	resp = srv.Do(http.MethodGet, "/admin/startup", nil, testserver.WithHeader("X-API-Key", testserver.SupportAPIKey))
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestRouter_ProductErrors(t *testing.T) {
+	srv := testserver.New(t, func(cfg *config.Config) {
+		raw, err := os.ReadFile(cfg.Files.ProductsFile)
+		require.NoError(t, err)
+		var records []json.RawMessage
+		require.NoError(t, json.Unmarshal(raw, &records))
+		records = append(records, json.RawMessage(`{"id":"bad-1","name":"Broken","price":"free"}`))
+		raw, err = json.Marshal(records)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(cfg.Files.ProductsFile, raw, 0644))
+		cfg.Products.Lenient = true
+	})
+
+	// The valid products are still served
+	resp := srv.Do(http.MethodGet, "/products/prod-1", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = srv.Do(http.MethodGet, "/products/bad-1", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = srv.Do(http.MethodGet, "/admin/products/errors", nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	var body handlers.ProductErrorsResponse
+	resp.Decode(t, &body)
+	require.Len(t, body.Errors, 1)
+	assert.Equal(t, 2, body.Errors[0].Index)
+	assert.Equal(t, "bad-1", body.Errors[0].ID)
+	assert.NotEmpty(t, body.Errors[0].Error)
+}
+
+func TestRouter_ProductStats(t *testing.T) {
+	srv := testserver.New(t, func(cfg *config.Config) {
+		raw, err := os.ReadFile(cfg.Files.ProductsFile)
+		require.NoError(t, err)
+		var records []json.RawMessage
+		require.NoError(t, json.Unmarshal(raw, &records))
+		records = append(records, json.RawMessage(`{"id":"bad-1","name":"Broken","price":"free"}`))
+		raw, err = json.Marshal(records)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(cfg.Files.ProductsFile, raw, 0644))
+		cfg.Products.Lenient = true
+	})
+
+	resp := srv.Do(http.MethodGet, "/admin/products/stats", nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	var stats data.ProductStats
+	resp.Decode(t, &stats)
+	assert.Equal(t, 2, stats.Products)
+	assert.Equal(t, 1, stats.Quarantined)
+	assert.Equal(t, 19.99, stats.Prices.Max)
+	require.Len(t, stats.Categories, 1)
+	assert.Equal(t, 2, stats.Categories[0].Products)
+	assert.False(t, stats.LoadedAt.IsZero())
+
+	resp = srv.Do(http.MethodGet, "/admin/products/stats", nil, testserver.WithHeader("X-API-Key", testserver.SupportAPIKey))
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestRouter_PriceHistory(t *testing.T) {
+	srv := testserver.New(t)
+
+	product, err := srv.Store.GetProduct("prod-1")
+	require.NoError(t, err)
+	reduced := *product
+	reduced.Price = product.Price - 2
+	resp := srv.Do(http.MethodPut, "/products/prod-1", reduced, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+
+	resp = srv.Do(http.MethodGet, "/admin/products/prod-1/price-history", nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	var history data.PriceHistory
+	resp.Decode(t, &history)
+	assert.Equal(t, "prod-1", history.ProductID)
+	require.Len(t, history.Prices, 2)
+	assert.Equal(t, product.Price, history.Prices[0].Price)
+	assert.Equal(t, reduced.Price, history.Prices[1].Price)
+
+	resp = srv.Do(http.MethodGet, "/admin/products/missing/price-history", nil, testserver.WithAPIKey())
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, apierrors.NotFound, resp.Error(t).Code)
+}
+
+func TestRouter_SLOs(t *testing.T) {
+	srv := testserver.New(t, func(cfg *config.Config) {
+		cfg.SLOs = []config.SLO{
+			{Group: "ordering", Availability: 0.99, Window: config.DefaultSLOWindow},
+			{Group: "catalog", Availability: 0.999, Latency: time.Minute, LatencyTarget: 0.9, Window: config.DefaultSLOWindow},
+		}
+	})
+
+	_, resp := srv.PlaceOrder(&models.OrderRequest{Items: []models.OrderItem{{ProductID: "prod-1", Quantity: 1}}})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+	resp = srv.Do(http.MethodGet, "/products/prod-1", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = srv.Do(http.MethodGet, "/products/missing", nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = srv.Do(http.MethodGet, "/admin/slos", nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	var body handlers.SLOsResponse
+	resp.Decode(t, &body)
+
+	// Groups are listed in the order they are registered
+	require.Len(t, body.SLOs, 2)
+	catalog, ordering := body.SLOs[0], body.SLOs[1]
+	assert.Equal(t, "catalog", catalog.Group)
+	assert.EqualValues(t, 2, catalog.Availability.Requests, "4xx responses do not spend the budget")
+	assert.Zero(t, catalog.Availability.Bad)
+	assert.Equal(t, 1.0, catalog.Availability.BudgetRemaining)
+	require.NotNil(t, catalog.Latency)
+	assert.Equal(t, "1m0s", catalog.Latency.Threshold)
+
+	assert.Equal(t, "ordering", ordering.Group)
+	assert.EqualValues(t, 1, ordering.Availability.Requests)
+	assert.Nil(t, ordering.Latency)
+	assert.Empty(t, ordering.Availability.Alert)
+
+	resp = srv.Do(http.MethodGet, "/admin/slos", nil, testserver.WithHeader("X-API-Key", testserver.SupportAPIKey))
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestRouter_Routes(t *testing.T) {
+	srv := testserver.New(t, func(cfg *config.Config) {
+		cfg.Server.CatalogTimeout = 3 * time.Second
+	})
+
+	resp := srv.Do(http.MethodGet, "/products/prod-1", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = srv.Do(http.MethodGet, "/admin/routes", nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	var body handlers.RoutesResponse
+	resp.Decode(t, &body)
+
+	routes := make(map[string]handlers.RouteInfo)
+	for _, route := range body.Routes {
+		routes[route.Method+" "+route.Path] = route
+	}
+	product := routes["GET /products/:id"]
+	assert.Equal(t, "public", product.Scope)
+	assert.Equal(t, "3s", product.Timeout)
+	assert.EqualValues(t, 1, product.Requests)
+	assert.Zero(t, product.ServerErrors)
+
+	assert.Equal(t, auth.RoleAdmin, routes["POST /products"].Scope)
+	assert.Equal(t, "staff", routes["GET /admin"].Scope)
+	assert.Equal(t, auth.RoleSupport, routes["GET /admin/orders/:id"].Scope)
+	assert.Equal(t, auth.RolePOS, routes["GET /pos/orders"].Scope)
+	assert.Equal(t, auth.RoleCustomer, routes["GET /auth/me"].Scope)
+	assert.Empty(t, routes["GET /auth/me"].Timeout)
+
+	// Every route gin serves is in the table, besides the OPTIONS answered
+	// for each path
+	served := slices.DeleteFunc(srv.Router.Engine().Routes(), func(route gin.RouteInfo) bool {
+		return route.Method == http.MethodOptions
+	})
+	assert.Len(t, body.Routes, len(served))
+}
+
+func TestRouter_Maintenance(t *testing.T) {
+	srv := testserver.New(t)
+
+	resp := srv.Do(http.MethodPut, "/admin/maintenance/ordering", `{"message":"Ordering is paused while we restock"}`, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+
+	_, resp = srv.PlaceOrder(&models.OrderRequest{Items: []models.OrderItem{{ProductID: "prod-1", Quantity: 1}}})
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode, "body: %s", resp.Body)
+	errResp := resp.Error(t)
+	assert.Equal(t, "MAINTENANCE", errResp.Code)
+	assert.Equal(t, "Ordering is paused while we restock", errResp.Message)
+	assert.Equal(t, "300", resp.Header.Get("Retry-After"))
+
+	// Group carts take items, but cannot be checked out
+	var cart handlers.CreatedCart
+	resp = srv.Do(http.MethodPost, "/carts", `{"name":"Jane"}`)
+	resp.Decode(t, &cart)
+	resp = srv.Do(http.MethodPost, "/carts/"+cart.ID+"/items", `{"name":"Jane","items":[{"productId":"prod-1","quantity":1}]}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	resp = srv.Do(http.MethodPost, "/carts/"+cart.ID+"/checkout", `{}`, testserver.WithHeader(handlers.CartTokenHeader, cart.HostToken))
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode, "body: %s", resp.Body)
+	assert.Equal(t, "MAINTENANCE", resp.Error(t).Code)
+
+	// The catalog stays available
+	resp = srv.Do(http.MethodGet, "/products/prod-1", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var listed handlers.MaintenanceResponse
+	resp = srv.Do(http.MethodGet, "/admin/maintenance", nil, testserver.WithAPIKey())
+	resp.Decode(t, &listed)
+	assert.Equal(t, []string{"catalog", "ordering", "carts", "accounts", "pos", "couriers"}, listed.Groups)
+	require.Len(t, listed.Windows, 1)
+	assert.Equal(t, "ordering", listed.Windows[0].Group)
+
+	resp = srv.Do(http.MethodDelete, "/admin/maintenance/ordering", nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	_, resp = srv.PlaceOrder(&models.OrderRequest{Items: []models.OrderItem{{ProductID: "prod-1", Quantity: 1}}})
+	assert.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+}
+
+func TestRouter_GroupCart(t *testing.T) {
+	srv := testserver.New(t)
+
+	var cart handlers.CreatedCart
+	resp := srv.Do(http.MethodPost, "/carts", `{"name":"Jane"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+	resp.Decode(t, &cart)
+	require.NotEmpty(t, cart.HostToken)
+	assert.Equal(t, "Jane", cart.Host.Name)
+
+	resp = srv.Do(http.MethodPost, "/carts/"+cart.ID+"/items", `{"name":"Jane","items":[{"productId":"prod-1","quantity":2}]}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	resp = srv.Do(http.MethodPost, "/carts/"+cart.ID+"/items", `{"name":"Sam","items":[{"productId":"prod-2","quantity":1}]}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+
+	// Anonymous participants must give a name
+	resp = srv.Do(http.MethodPost, "/carts/"+cart.ID+"/items", `{"items":[{"productId":"prod-2","quantity":1}]}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	// Only the host checks out
+	resp = srv.Do(http.MethodPost, "/carts/"+cart.ID+"/checkout", `{}`)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode, "body: %s", resp.Body)
+
+	var receipt handlers.Receipt
+	resp = srv.Do(http.MethodPost, "/carts/"+cart.ID+"/checkout", `{}`, testserver.WithHeader(handlers.CartTokenHeader, cart.HostToken))
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+	resp.Decode(t, &receipt)
+	require.Len(t, receipt.Order.Items, 2)
+	require.Len(t, receipt.Shares, 2)
+	assert.Equal(t, "Jane", receipt.Shares[0].Name)
+	assert.Equal(t, "Sam", receipt.Shares[1].Name)
+	assert.InDelta(t, receipt.Order.TotalAmount, receipt.Shares[0].Total+receipt.Shares[1].Total, 0.011)
+
+	// The cart is closed once checked out
+	resp = srv.Do(http.MethodPost, "/carts/"+cart.ID+"/items", `{"name":"Sam","items":[{"productId":"prod-2","quantity":1}]}`)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, "CART_CLOSED", resp.Error(t).Code)
+	var checkedOut carts.Cart
+	resp = srv.Do(http.MethodGet, "/carts/"+cart.ID, nil)
+	resp.Decode(t, &checkedOut)
+	assert.Equal(t, carts.StatusCheckedOut, checkedOut.Status)
+	assert.Equal(t, receipt.Order.ID, checkedOut.OrderID)
+}
+
+func TestRouter_GroupCartHoldsStock(t *testing.T) {
+	srv := testserver.New(t, func(cfg *config.Config) {
+		cfg.Orders.HoldTTL = 10 * time.Minute
+	})
+
+	product, err := srv.Store.GetProduct("prod-1")
+	require.NoError(t, err)
+	product.SKU = "WAF-BER"
+	require.NoError(t, srv.Store.UpdateProduct(product))
+	levels := map[string]interface{}{"levels": []map[string]interface{}{{"sku": "WAF-BER", "quantity": 2}}}
+	resp := srv.Do(http.MethodPut, "/pos/inventory", levels, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+
+	openCart := func() handlers.CreatedCart {
+		var cart handlers.CreatedCart
+		resp := srv.Do(http.MethodPost, "/carts", `{"name":"Jane"}`)
+		require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+		resp.Decode(t, &cart)
+		return cart
+	}
+	first, second := openCart(), openCart()
+
+	// The first cart holds the last units
+	resp = srv.Do(http.MethodPost, "/carts/"+first.ID+"/items", `{"name":"Jane","items":[{"productId":"prod-1","quantity":2}]}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+
+	// So no one else is promised them
+	resp = srv.Do(http.MethodPost, "/carts/"+second.ID+"/items", `{"name":"Sam","items":[{"productId":"prod-1","quantity":1}]}`)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, "OUT_OF_STOCK", resp.Error(t).Code)
+	resp = srv.Do(http.MethodPost, "/orders", `{"items":[{"productId":"prod-1","quantity":1}]}`)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	var refused carts.Cart
+	srv.Do(http.MethodGet, "/carts/"+second.ID, nil).Decode(t, &refused)
+	assert.Empty(t, refused.Items, "refused items are not added")
+
+	// The first cart's checkout takes what it held
+	resp = srv.Do(http.MethodPost, "/carts/"+first.ID+"/checkout", `{}`, testserver.WithHeader(handlers.CartTokenHeader, first.HostToken))
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+	var inventory []pos.Level
+	srv.Do(http.MethodGet, "/pos/inventory", nil, testserver.WithAPIKey()).Decode(t, &inventory)
+	require.Len(t, inventory, 1)
+	assert.Zero(t, inventory[0].Quantity)
+}
+
+func TestRouter_SplitOrder(t *testing.T) {
+	srv := testserver.New(t, func(cfg *config.Config) {
+		cfg.Orders.PaymentLinkURL = "https://pay.example.com/split"
+	})
+
+	order, resp := srv.PlaceOrder(&models.OrderRequest{Items: []models.OrderItem{
+		{ProductID: "prod-1", Quantity: 2},
+		{ProductID: "prod-2", Quantity: 1},
+	}})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+
+	var bill split.Split
+	resp = srv.Do(http.MethodPost, "/orders/"+order.ID+"/split", `{"mode":"items","paymentLinks":true,"participants":[`+
+		`{"name":"Jane","items":[{"line":0,"quantity":2}]},{"name":"Sam","items":[{"line":1,"quantity":1}]}]}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	resp.Decode(t, &bill)
+	require.Len(t, bill.Shares, 2)
+	assert.InDelta(t, order.TotalAmount, bill.Shares[0].Amount+bill.Shares[1].Amount, 0.001)
+	assert.Contains(t, bill.Shares[1].PaymentURL, "https://pay.example.com/split?")
+	assert.Contains(t, bill.Shares[1].PaymentURL, "order="+order.ID)
+
+	resp = srv.Do(http.MethodPost, "/orders/"+order.ID+"/split", `{"mode":"even","people":1}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	resp = srv.Do(http.MethodPost, "/orders/missing/split", `{"mode":"even","people":2}`)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestRouter_DineIn(t *testing.T) {
+	srv := testserver.New(t, func(cfg *config.Config) {
+		cfg.Orders.TableSecret = "0123456789abcdef0123456789abcdef"
+	})
+
+	var table handlers.TableToken
+	resp := srv.Do(http.MethodGet, "/admin/tables/12/token", nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	resp.Decode(t, &table)
+	assert.Equal(t, "12", table.Table)
+
+	order, resp := srv.PlaceOrder(&models.OrderRequest{
+		Items:      []models.OrderItem{{ProductID: "prod-1", Quantity: 1}},
+		TableToken: table.Token,
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+	assert.Equal(t, "12", order.Table)
+	_, resp = srv.PlaceOrder(&models.OrderRequest{Items: []models.OrderItem{{ProductID: "prod-2", Quantity: 1}}})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+
+	// Dine-in orders are not delivered
+	_, resp = srv.PlaceOrder(&models.OrderRequest{
+		Items:           []models.OrderItem{{ProductID: "prod-1", Quantity: 1}},
+		TableToken:      table.Token,
+		DeliveryAddress: &models.Address{Line1: "1 George St", Postcode: "2000"},
+	})
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, "VALIDATION_ERROR", resp.Error(t).Code)
+
+	_, resp = srv.PlaceOrder(&models.OrderRequest{
+		Items:      []models.OrderItem{{ProductID: "prod-1", Quantity: 1}},
+		TableToken: "12.forged",
+	})
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, "INVALID_TABLE", resp.Error(t).Code)
+
+	// The kitchen sees the table's orders together, without the takeaway one
+	var groups []kitchen.TableTickets
+	resp = srv.Do(http.MethodGet, "/admin/kitchen/tables", nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	resp.Decode(t, &groups)
+	require.Len(t, groups, 1)
+	assert.Equal(t, "12", groups[0].Table)
+	require.Len(t, groups[0].Tickets, 1)
+	assert.Equal(t, order.ID, groups[0].Tickets[0].OrderID)
+}
+
+func TestRouter_AccountingExport(t *testing.T) {
+	srv := testserver.New(t, func(cfg *config.Config) {
+		cfg.Accounting = config.Accounting{SalesAccount: "200", DateFormat: "02/01/2006"}
+	})
+
+	order, resp := srv.PlaceOrder(&models.OrderRequest{Items: []models.OrderItem{
+		{ProductID: "prod-1", Quantity: 2},
+		{ProductID: "prod-2", Quantity: 1},
+	}})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, "body: %s", resp.Body)
+
+	today := order.CreatedAt.UTC().Format(time.DateOnly)
+	resp = srv.Do(http.MethodGet, "/admin/reports/accounting?format=quickbooks&from="+today+"&to="+today, nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	assert.Equal(t, "text/csv; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Contains(t, resp.Header.Get("Content-Disposition"), "orders-default-quickbooks-from-"+today)
+
+	records, err := csv.NewReader(bytes.NewReader(resp.Body)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, "InvoiceNo", records[0][0])
+	assert.Equal(t, order.InvoiceNumber, records[1][0])
+	assert.Equal(t, order.ID, records[1][4])
+	assert.Equal(t, "200", records[1][5])
+
+	// Orders placed outside the range are left out
+	resp = srv.Do(http.MethodGet, "/admin/reports/accounting?format=xero&to=2000-01-01", nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	records, err = csv.NewReader(bytes.NewReader(resp.Body)).ReadAll()
+	require.NoError(t, err)
+	assert.Len(t, records, 1)
+
+	resp = srv.Do(http.MethodGet, "/admin/reports/accounting?format=sage", nil, testserver.WithAPIKey())
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestRouter_Options(t *testing.T) {
+	srv := testserver.New(t, func(cfg *config.Config) {
+		cfg.Server.CORSOrigins = []string{"https://shop.example.com"}
+		cfg.Server.CORSMaxAge = time.Hour
+	})
+	preflight := func(origin, method string) testserver.RequestOption {
+		return func(req *http.Request) {
+			req.Header.Set("Origin", origin)
+			req.Header.Set("Access-Control-Request-Method", method)
+		}
+	}
+
+	resp := srv.Do(http.MethodOptions, "/products/prod-1", nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "GET, PUT, DELETE, OPTIONS", resp.Header.Get("Allow"))
+	assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+
+	// A preflight from an allowed origin is answered from the routing table
+	resp = srv.Do(http.MethodOptions, "/orders", nil, preflight("https://shop.example.com", http.MethodPost))
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "https://shop.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "POST, OPTIONS", resp.Header.Get("Access-Control-Allow-Methods"))
+	assert.Contains(t, resp.Header.Get("Access-Control-Allow-Headers"), "X-API-Key")
+	assert.Equal(t, "3600", resp.Header.Get("Access-Control-Max-Age"))
+
+	// ...but not for methods the path is not served with
+	resp = srv.Do(http.MethodOptions, "/orders", nil, preflight("https://shop.example.com", http.MethodDelete))
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Access-Control-Allow-Methods"))
+
+	// ...or from other origins
+	resp = srv.Do(http.MethodOptions, "/orders", nil, preflight("https://evil.example.com", http.MethodPost))
+	assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, resp.Header.Get("Access-Control-Allow-Methods"))
+
+	// Responses to allowed origins can be read by their scripts
+	resp = srv.Do(http.MethodGet, "/products/prod-1", nil, testserver.WithHeader("Origin", "https://shop.example.com"))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "https://shop.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, resp.Header.Get("Access-Control-Expose-Headers"), "ETag")
+}
+
+func TestRouter_Drain(t *testing.T) {
+	srv := testserver.New(t)
+	srv.Router.Drain()
+
+	_, resp := srv.PlaceOrder(&models.OrderRequest{Items: []models.OrderItem{{ProductID: "prod-1", Quantity: 1}}})
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode, "body: %s", resp.Body)
+	assert.Equal(t, "SHUTTING_DOWN", resp.Error(t).Code)
+
+	// Reads still finish
+	resp = srv.Do(http.MethodGet, "/products/prod-1", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -0,0 +1,215 @@
+package router
+
+import (
+	"log"
+	"net/http"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/auth"
+	"github.com/ravibandhu/oolio-food-ordering/internal/handlers"
+	"github.com/ravibandhu/oolio-food-ordering/internal/middleware"
+	"github.com/ravibandhu/oolio-food-ordering/internal/slo"
+)
+
+// Scopes of routes that do not require a single role. Every other scope is
+// the role a route requires, such as auth.RoleAdmin.
+const (
+	scopePublic   = "public"          // Anyone may call the route
+	scopeCustomer = auth.RoleCustomer // Signed-in customers, with a session token
+	scopeGuest    = "guest"           // Anyone; customers sending a session token are signed in
+	scopeStaff    = "staff"           // Any staff role; browsers are prompted for an API key
+)
+
+// route is one entry of the routing table
+type route struct {
+	method     string
+	path       string // Relative to the group's prefix
+	scope      string // Who may call the route, when narrower than the group's scope
+	timeout    time.Duration
+	middleware []gin.HandlerFunc // Run after the caller is authorized
+	handler    gin.HandlerFunc
+}
+
+// routeGroup is a set of routes sharing a path prefix, a scope, a timeout
+// and middleware. A route's own scope is checked after the group's, and its
+// own timeout replaces the group's. Named groups can be put into
+// maintenance by each tenant.
+type routeGroup struct {
+	name       string
+	prefix     string
+	scope      string
+	timeout    time.Duration
+	middleware []gin.HandlerFunc // Run after the timeout starts and before the scopes are checked
+	routes     []route
+}
+
+// registeredRoute is a route as registered, with the requests it served
+type registeredRoute struct {
+	method    string
+	path      string
+	scope     string
+	timeout   time.Duration
+	requests  atomic.Int64
+	errors    atomic.Int64
+	totalTime atomic.Int64 // Nanoseconds
+	slo       *slo.Tracker // Objective of the route's group; nil when it has none
+}
+
+// register adds the routes of groups to the engine, each with the handler
+// chain its group and entry call for. The requests of named groups with an
+// objective are tracked against it.
+func (r *Router) register(groups []routeGroup) {
+	trackers := make(map[string]*slo.Tracker, len(r.config.SLOs))
+	for _, objective := range r.config.SLOs {
+		trackers[objective.Group] = slo.NewTracker(objective)
+	}
+
+	for _, group := range groups {
+		if group.name != "" {
+			r.maintainable = append(r.maintainable, group.name)
+		}
+		tracker := trackers[group.name]
+		if tracker != nil {
+			r.slos = append(r.slos, tracker)
+			delete(trackers, group.name)
+		}
+		for _, entry := range group.routes {
+			registered := &registeredRoute{
+				method:  entry.method,
+				path:    group.prefix + entry.path,
+				scope:   firstNonEmpty(entry.scope, group.scope, scopePublic),
+				timeout: group.timeout,
+				slo:     tracker,
+			}
+			if entry.timeout > 0 {
+				registered.timeout = entry.timeout
+			}
+
+			chain := []gin.HandlerFunc{registered.measure}
+			if registered.timeout > 0 {
+				chain = append(chain, middleware.Timeout(registered.timeout))
+			}
+			if group.name != "" {
+				chain = append(chain, middleware.Maintenance(group.name))
+			}
+			chain = append(chain, group.middleware...)
+			chain = append(chain, r.authorize(group.scope)...)
+			chain = append(chain, r.authorize(entry.scope)...)
+			chain = append(chain, entry.middleware...)
+			chain = append(chain, entry.handler)
+
+			r.engine.Handle(registered.method, registered.path, chain...)
+			r.routes = append(r.routes, registered)
+		}
+	}
+	r.registerOptions()
+
+	for group := range trackers {
+		log.Printf("Ignoring the SLO of %s: no route group is named so", group)
+	}
+}
+
+// registerOptions answers OPTIONS requests to every path in the routing
+// table, including CORS preflights, with the methods the table serves the
+// path with. Paths the table already serves OPTIONS for are left alone.
+func (r *Router) registerOptions() {
+	var paths []string
+	methods := map[string][]string{}
+	for _, rr := range r.routes {
+		if _, seen := methods[rr.path]; !seen {
+			paths = append(paths, rr.path)
+		}
+		methods[rr.path] = append(methods[rr.path], rr.method)
+	}
+	for _, path := range paths {
+		if slices.Contains(methods[path], http.MethodOptions) {
+			continue
+		}
+		r.engine.OPTIONS(path, middleware.Options(methods[path], r.config.Server.CORSOrigins, r.config.Server.CORSMaxAge))
+	}
+}
+
+// authorize returns the middleware that admits the callers of scope, if
+// any are needed
+func (r *Router) authorize(scope string) []gin.HandlerFunc {
+	switch scope {
+	case "", scopePublic:
+		return nil
+	case scopeCustomer:
+		return []gin.HandlerFunc{middleware.CustomerAuth(r.accounts)}
+	case scopeGuest:
+		return []gin.HandlerFunc{middleware.OptionalCustomerAuth(r.accounts)}
+	case scopeStaff:
+		return []gin.HandlerFunc{middleware.BrowserRequireRole("oolio-admin", r.keys, r.accounts, auth.RoleKitchen, auth.RoleSupport)}
+	default:
+		return []gin.HandlerFunc{middleware.RequireRole(r.keys, r.accounts, scope)}
+	}
+}
+
+// measure counts the requests a route serves and how long they take, and
+// tracks them against the objective of its group
+func (rr *registeredRoute) measure(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+	elapsed := time.Since(start)
+	rr.requests.Add(1)
+	rr.totalTime.Add(int64(elapsed))
+	if c.Writer.Status() >= http.StatusInternalServerError {
+		rr.errors.Add(1)
+	}
+	if rr.slo != nil {
+		rr.slo.Record(c.Writer.Status(), elapsed)
+	}
+}
+
+// Routes returns the routing table, in the order the routes were
+// registered, with the requests each has served
+func (r *Router) Routes() []handlers.RouteInfo {
+	routes := make([]handlers.RouteInfo, len(r.routes))
+	for i, rr := range r.routes {
+		info := handlers.RouteInfo{
+			Method:       rr.method,
+			Path:         rr.path,
+			Scope:        rr.scope,
+			Requests:     rr.requests.Load(),
+			ServerErrors: rr.errors.Load(),
+		}
+		if rr.timeout > 0 {
+			info.Timeout = rr.timeout.String()
+		}
+		if info.Requests > 0 {
+			info.MeanLatencyMs = float64(rr.totalTime.Load()) / float64(info.Requests) / float64(time.Millisecond)
+		}
+		routes[i] = info
+	}
+	return routes
+}
+
+// SLOs returns the state of the objectives of the route groups, in the
+// order the groups were registered
+func (r *Router) SLOs() []slo.Status {
+	statuses := make([]slo.Status, len(r.slos))
+	for i, tracker := range r.slos {
+		statuses[i] = tracker.Status()
+	}
+	return statuses
+}
+
+// maintenanceGroups returns the names of the route groups that can be put
+// into maintenance
+func (r *Router) maintenanceGroups() []string {
+	return r.maintainable
+}
+
+// firstNonEmpty returns the first of values that is not empty
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,289 @@
+// Package schema generates JSON Schemas for the data files the server
+// loads, from the same struct tags the server validates them with, so
+// editors and deploy pipelines can check the files before they are
+// deployed.
+package schema
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/data"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+// Draft is the JSON Schema dialect of the generated schemas
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema, or a part of one
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Not                  *Schema            `json:"not,omitempty"`
+}
+
+// enums are the values custom validations accept, by validation tag
+var enums = map[string][]string{
+	"allergen":       models.Allergens,
+	"dietary":        models.DietaryTags,
+	"payment_method": models.PaymentMethods,
+}
+
+// Names of the schemas Named returns
+var Names = []string{"product", "coupon", "products-file", "coupon-manifest"}
+
+// Named returns the schema called name, one of Names
+func Named(name string) (*Schema, bool) {
+	switch name {
+	case "product":
+		return Product(), true
+	case "coupon":
+		return Coupon(), true
+	case "products-file":
+		return ProductsFile(), true
+	case "coupon-manifest":
+		return CouponManifest(), true
+	}
+	return nil, false
+}
+
+// Product returns the schema of a product
+func Product() *Schema {
+	return document("Product", For(reflect.TypeOf(models.Product{})))
+}
+
+// Coupon returns the schema of a coupon
+func Coupon() *Schema {
+	return document("Coupon", For(reflect.TypeOf(models.Coupon{})))
+}
+
+// ProductsFile returns the schema of a products file in the current schema
+// version. Files of older versions are converted by the server as they are
+// loaded, but are not described.
+func ProductsFile() *Schema {
+	version := float64(data.ProductsSchemaVersion)
+	return document("Products file", &Schema{
+		Description: "The catalog, in products file schema version " + strconv.Itoa(data.ProductsSchemaVersion),
+		Type:        "object",
+		Properties: map[string]*Schema{
+			"schema_version": {Type: "integer", Enum: []any{version}},
+			"products":       {Type: "array", Items: For(reflect.TypeOf(models.Product{}))},
+		},
+		Required: []string{"schema_version", "products"},
+	})
+}
+
+// CouponManifest returns the schema of the manifest in a coupon directory,
+// which lists the lines and SHA-256 each coupon file should hold by name
+func CouponManifest() *Schema {
+	return document("Coupon manifest", &Schema{
+		Type: "object",
+		AdditionalProperties: &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"lines":  {Type: "integer", Minimum: ptr(0.0)},
+				"sha256": {Type: "string", Pattern: "^[0-9a-fA-F]{64}$"},
+			},
+		},
+	})
+}
+
+// document makes s a top-level schema called title
+func document(title string, s *Schema) *Schema {
+	s.Schema = Draft
+	s.Title = title
+	return s
+}
+
+// timeType is described as a date-time string, as it is encoded
+var timeType = reflect.TypeOf(time.Time{})
+
+// For returns the schema of values of type t as encoding/json encodes them,
+// constrained as models.Validate validates them
+func For(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.String:
+		return &Schema{Type: "string"}
+	case t.Kind() == reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return &Schema{Type: "number"}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		return &Schema{Type: "array", Items: For(t.Elem())}
+	case t.Kind() == reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: For(t.Elem())}
+	case t.Kind() == reflect.Struct:
+		return forStruct(t)
+	}
+	return &Schema{}
+}
+
+// forStruct returns the schema of a struct, with a property for each field
+// encoding/json encodes
+func forStruct(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		property := For(field.Type)
+		if constrain(property, field.Tag.Get("validate")) {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = property
+	}
+	sort.Strings(s.Required)
+	return s
+}
+
+// constrain adds the rules of a validate tag to s, reporting whether they
+// require the value. Rules after dive apply to the items of an array or
+// the values of an object. Rules with no JSON Schema equivalent, such as
+// unique or required_if, are left to the server.
+func constrain(s *Schema, tag string) bool {
+	if tag == "" || tag == "-" {
+		return false
+	}
+	rules := strings.Split(tag, ",")
+	required := false
+	for i, rule := range rules {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "dive":
+			item := s.Items
+			if s.Type == "object" {
+				item = s.AdditionalProperties
+			}
+			if item != nil {
+				constrain(item, strings.Join(rules[i+1:], ","))
+			}
+			return required
+		case "required":
+			required = true
+			if s.Type == "string" && s.Format == "" {
+				s.MinLength = maxInt(s.MinLength, 1)
+			}
+		case "gt":
+			s.ExclusiveMinimum = parse(param)
+		case "gte":
+			s.Minimum = parse(param)
+		case "lt":
+			s.ExclusiveMaximum = parse(param)
+		case "lte":
+			s.Maximum = parse(param)
+		case "min", "max", "len":
+			n, err := strconv.Atoi(param)
+			if err != nil {
+				continue
+			}
+			lengthRule(s, name, n)
+		case "url":
+			s.Format = "uri"
+		case "numeric":
+			s.Pattern = "^[0-9]+$"
+		case "alphanum":
+			s.Pattern = "^[a-zA-Z0-9]+$"
+		default:
+			if values, ok := enums[name]; ok {
+				for _, v := range values {
+					s.Enum = append(s.Enum, v)
+				}
+			}
+		}
+	}
+
+	// A required number may not be zero, as Go cannot tell zero from
+	// missing, unless its bounds already rule zero out
+	if required && (s.Type == "number" || s.Type == "integer") && !excludesZero(s) {
+		s.Not = &Schema{Enum: []any{0}}
+	}
+	return required
+}
+
+// excludesZero reports whether the lower bound of s rules out zero
+func excludesZero(s *Schema) bool {
+	return (s.ExclusiveMinimum != nil && *s.ExclusiveMinimum >= 0) || (s.Minimum != nil && *s.Minimum > 0)
+}
+
+// lengthRule applies a min, max or len rule, which bound the value of a
+// number and the length of a string or array
+func lengthRule(s *Schema, rule string, n int) {
+	switch s.Type {
+	case "string":
+		if rule != "max" {
+			s.MinLength = maxInt(s.MinLength, n)
+		}
+		if rule != "min" {
+			s.MaxLength = &n
+		}
+	case "array":
+		if rule != "max" {
+			s.MinItems = &n
+		}
+		if rule != "min" {
+			s.MaxItems = &n
+		}
+	case "number", "integer":
+		f := float64(n)
+		if rule != "max" {
+			s.Minimum = &f
+		}
+		if rule != "min" {
+			s.Maximum = &f
+		}
+	}
+}
+
+// parse returns the number in a rule's parameter, or nil when it is not one
+func parse(param string) *float64 {
+	f, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return nil
+	}
+	return &f
+}
+
+// maxInt returns the larger of the bound held and n
+func maxInt(bound *int, n int) *int {
+	if bound != nil && *bound > n {
+		return bound
+	}
+	return &n
+}
+
+// ptr returns a pointer to v
+func ptr[T any](v T) *T {
+	return &v
+}
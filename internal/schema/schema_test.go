@@ -0,0 +1,73 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProduct(t *testing.T) {
+	s := Product()
+	assert.Equal(t, Draft, s.Schema)
+	assert.Equal(t, "object", s.Type)
+	assert.Subset(t, s.Required, []string{"id", "name", "price", "category", "image"})
+
+	price := s.Properties["price"]
+	require.NotNil(t, price)
+	assert.Equal(t, "number", price.Type)
+	require.NotNil(t, price.ExclusiveMinimum)
+	assert.Equal(t, 0.0, *price.ExclusiveMinimum)
+	assert.Nil(t, price.Not, "the bound already rules out zero")
+
+	name := s.Properties["name"]
+	require.NotNil(t, name.MinLength)
+	assert.Equal(t, 1, *name.MinLength)
+
+	image := s.Properties["image"]
+	require.NotNil(t, image)
+	assert.Equal(t, "uri", image.Properties["thumbnail"].Format)
+
+	allergens := s.Properties["allergens"]
+	require.NotNil(t, allergens)
+	require.NotNil(t, allergens.Items)
+	assert.Len(t, allergens.Items.Enum, len(models.Allergens))
+}
+
+func TestConstrain(t *testing.T) {
+	s := &Schema{Type: "integer"}
+	assert.True(t, constrain(s, "required"))
+	require.NotNil(t, s.Not)
+	assert.Equal(t, []any{0}, s.Not.Enum)
+
+	s = &Schema{Type: "string"}
+	assert.False(t, constrain(s, "omitempty,min=3,max=8,alphanum"))
+	assert.Equal(t, 3, *s.MinLength)
+	assert.Equal(t, 8, *s.MaxLength)
+	assert.Equal(t, "^[a-zA-Z0-9]+$", s.Pattern)
+
+	s = &Schema{Type: "array", Items: &Schema{Type: "string"}}
+	assert.True(t, constrain(s, "required,max=2,dive,required,len=4"))
+	assert.Equal(t, 2, *s.MaxItems)
+	assert.Nil(t, s.MinLength)
+	assert.Equal(t, 4, *s.Items.MinLength)
+	assert.Equal(t, 4, *s.Items.MaxLength)
+}
+
+func TestNamed(t *testing.T) {
+	for _, name := range Names {
+		s, ok := Named(name)
+		require.True(t, ok, name)
+		assert.NotEmpty(t, s.Title)
+		_, err := json.Marshal(s)
+		assert.NoError(t, err)
+	}
+	_, ok := Named("order")
+	assert.False(t, ok)
+
+	file, _ := Named("products-file")
+	assert.Equal(t, []string{"schema_version", "products"}, file.Required)
+	assert.Equal(t, "array", file.Properties["products"].Type)
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/taxid"
+)
+
+// NormalizeBilling checks the tax ID of the business an order is invoiced
+// to against its country's format and returns the billing details as the
+// order records them: the tax ID normalized and the text cleaned up like
+// notes. Exempt orders must give a reason; others keep none.
+func NormalizeBilling(billing *models.BusinessBilling) (*models.BusinessBilling, error) {
+	country := strings.ToUpper(strings.TrimSpace(billing.Country))
+	taxID, err := taxid.Normalize(country, billing.TaxID)
+	if err != nil {
+		errResp := apierrors.New(apierrors.InvalidTaxID, "Invalid business tax ID").
+			AddDetail("country", country).
+			AddDetail("error", err.Error())
+		if errors.Is(err, taxid.ErrUnsupportedCountry) {
+			errResp.AddDetail("countries", taxid.Countries())
+		} else {
+			errResp.AddDetail("example", taxid.Example(country))
+		}
+		return nil, errResp
+	}
+
+	normalized := &models.BusinessBilling{
+		CompanyName: SanitizeNote(billing.CompanyName),
+		Country:     country,
+		TaxID:       taxID,
+		TaxExempt:   billing.TaxExempt,
+	}
+	if normalized.CompanyName == "" {
+		return nil, apierrors.New(apierrors.ValidationError, "Invalid request data").
+			AddDetail("error", "billing company name is required")
+	}
+	if normalized.TaxExempt {
+		normalized.ExemptionReason = SanitizeNote(billing.ExemptionReason)
+		if normalized.ExemptionReason == "" {
+			return nil, apierrors.New(apierrors.ValidationError, "Invalid request data").
+				AddDetail("error", "a reason is required for orders exempt from tax")
+		}
+	}
+	return normalized, nil
+}
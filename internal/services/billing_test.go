@@ -0,0 +1,52 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeBilling(t *testing.T) {
+	billing, err := NormalizeBilling(&models.BusinessBilling{
+		CompanyName:     " Acme\tCatering ",
+		Country:         "au",
+		TaxID:           "51 824 753 556",
+		TaxExempt:       true,
+		ExemptionReason: "Registered charity\n",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &models.BusinessBilling{
+		CompanyName:     "Acme Catering",
+		Country:         "AU",
+		TaxID:           "51824753556",
+		TaxExempt:       true,
+		ExemptionReason: "Registered charity",
+	}, billing)
+
+	// Orders that are not exempt keep no reason
+	billing, err = NormalizeBilling(&models.BusinessBilling{CompanyName: "Acme", Country: "DE", TaxID: "123456789", ExemptionReason: "None"})
+	require.NoError(t, err)
+	assert.Equal(t, "DE123456789", billing.TaxID)
+	assert.Empty(t, billing.ExemptionReason)
+
+	tests := []struct {
+		name    string
+		billing models.BusinessBilling
+		code    string
+	}{
+		{name: "invalid tax id", billing: models.BusinessBilling{CompanyName: "Acme", Country: "AU", TaxID: "12345"}, code: "INVALID_TAX_ID"},
+		{name: "unsupported country", billing: models.BusinessBilling{CompanyName: "Acme", Country: "BR", TaxID: "12345"}, code: "INVALID_TAX_ID"},
+		{name: "blank company name", billing: models.BusinessBilling{CompanyName: "\u200b", Country: "DE", TaxID: "123456789"}, code: "VALIDATION_ERROR"},
+		{name: "exempt without reason", billing: models.BusinessBilling{CompanyName: "Acme", Country: "DE", TaxID: "123456789", TaxExempt: true, ExemptionReason: " \n"}, code: "VALIDATION_ERROR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NormalizeBilling(&tt.billing)
+			var errResp *models.ErrorResponse
+			require.ErrorAs(t, err, &errResp)
+			assert.Equal(t, tt.code, errResp.Code)
+		})
+	}
+}
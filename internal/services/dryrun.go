@@ -0,0 +1,19 @@
+package services
+
+import "context"
+
+type dryRunKey struct{}
+
+// NewDryRunContext returns a copy of ctx under which PlaceOrder runs every
+// check and prices the order, but places nothing: no stock is taken, no
+// kitchen ticket queued, no payment captured, no invoice numbered and no
+// order kept
+func NewDryRunContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+// IsDryRun reports whether ctx is a dry-run context
+func IsDryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+	return dryRun
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+// MergeItems normalizes order items by merging lines for the same product
+// and variant into one, summing their quantities. Lines keep the position of
+// their first occurrence. Variants of a product stay separate lines, as do
+// lines with different notes, which the kitchen prepares differently.
+// Duplicates that disagree on the price the client saw are contradictory and
+// rejected with CONFLICTING_ITEMS.
+func MergeItems(items []models.OrderItem) ([]models.OrderItem, error) {
+	type key struct{ productID, variantID, notes string }
+
+	merged := make([]models.OrderItem, 0, len(items))
+	index := make(map[key]int, len(items))
+	for _, item := range items {
+		k := key{item.ProductID, item.VariantID, item.Notes}
+		i, seen := index[k]
+		if !seen {
+			index[k] = len(merged)
+			merged = append(merged, item)
+			continue
+		}
+
+		if merged[i].Price != item.Price {
+			return nil, apierrors.New(apierrors.ConflictingItems, "The same item is listed with different prices").
+				AddDetail("productId", item.ProductID).
+				AddDetail("variantId", item.VariantID)
+		}
+		merged[i].Quantity += item.Quantity
+	}
+	return merged, nil
+}
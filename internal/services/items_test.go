@@ -0,0 +1,58 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeItems(t *testing.T) {
+	t.Run("duplicates are merged in first position", func(t *testing.T) {
+		merged, err := MergeItems([]models.OrderItem{
+			{ProductID: "prod-1", Quantity: 1},
+			{ProductID: "coffee", VariantID: "large", Quantity: 1},
+			{ProductID: "prod-1", Quantity: 2},
+			{ProductID: "coffee", VariantID: "small", Quantity: 1},
+			{ProductID: "coffee", VariantID: "large", Quantity: 3},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []models.OrderItem{
+			{ProductID: "prod-1", Quantity: 3},
+			{ProductID: "coffee", VariantID: "large", Quantity: 4},
+			{ProductID: "coffee", VariantID: "small", Quantity: 1},
+		}, merged)
+	})
+
+	t.Run("lines with different notes stay separate", func(t *testing.T) {
+		merged, err := MergeItems([]models.OrderItem{
+			{ProductID: "prod-1", Quantity: 1, Notes: "no onions"},
+			{ProductID: "prod-1", Quantity: 1},
+			{ProductID: "prod-1", Quantity: 2, Notes: "no onions"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []models.OrderItem{
+			{ProductID: "prod-1", Quantity: 3, Notes: "no onions"},
+			{ProductID: "prod-1", Quantity: 1},
+		}, merged)
+	})
+
+	t.Run("the request is not modified", func(t *testing.T) {
+		items := []models.OrderItem{{ProductID: "prod-1", Quantity: 1}, {ProductID: "prod-1", Quantity: 1}}
+		_, err := MergeItems(items)
+		require.NoError(t, err)
+		assert.Equal(t, 1, items[0].Quantity)
+	})
+
+	t.Run("duplicates with different prices conflict", func(t *testing.T) {
+		_, err := MergeItems([]models.OrderItem{
+			{ProductID: "prod-1", Quantity: 1, Price: 9.99},
+			{ProductID: "prod-1", Quantity: 1, Price: 8.99},
+		})
+		var errResp *models.ErrorResponse
+		require.ErrorAs(t, err, &errResp)
+		assert.Equal(t, "CONFLICTING_ITEMS", errResp.Code)
+		assert.Equal(t, "prod-1", errResp.Details["productId"])
+	})
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+// Names of the limits reported in ORDER_TOO_LARGE details
+const (
+	LimitMaxQuantity = "max_quantity"
+	LimitMaxItems    = "max_items"
+	LimitMaxTotal    = "max_total"
+)
+
+// CheckItemLimits rejects orders with more of an item than the maximum
+// quantity, counting every line of the item, or more distinct products (and
+// variants) than the restaurant accepts
+func CheckItemLimits(items []models.OrderItem, limits config.Limits) error {
+	if limits.MaxQuantity > 0 {
+		// Lines with different notes are still the same item
+		quantities := make(map[[2]string]int, len(items))
+		for _, item := range items {
+			k := [2]string{item.ProductID, item.VariantID}
+			quantities[k] += item.Quantity
+			if quantities[k] > limits.MaxQuantity {
+				return apierrors.New(apierrors.OrderTooLarge, fmt.Sprintf("At most %d of an item can be ordered", limits.MaxQuantity)).
+					AddDetail("limit", LimitMaxQuantity).
+					AddDetail("max", limits.MaxQuantity).
+					AddDetail("productId", item.ProductID).
+					AddDetail("quantity", quantities[k])
+			}
+		}
+	}
+
+	if limits.MaxItems > 0 {
+		distinct := make(map[[2]string]bool, len(items))
+		for _, item := range items {
+			distinct[[2]string{item.ProductID, item.VariantID}] = true
+		}
+		if len(distinct) > limits.MaxItems {
+			return apierrors.New(apierrors.OrderTooLarge, fmt.Sprintf("At most %d different items can be ordered", limits.MaxItems)).
+				AddDetail("limit", LimitMaxItems).
+				AddDetail("max", limits.MaxItems).
+				AddDetail("items", len(distinct))
+		}
+	}
+
+	return nil
+}
+
+// CheckMinimum rejects orders whose subtotal, after discounts, is under the
+// restaurant's minimum for pickup or delivery, stating how much more is needed
+func CheckMinimum(subtotal float64, delivery bool, limits config.Limits) error {
+	mode, minimum := "pickup", limits.MinPickup
+	if delivery {
+		mode, minimum = "delivery", limits.MinDelivery
+	}
+	// Compare in cents, so rounding errors in the subtotal cannot fail an order
+	short := math.Round(minimum*100) - math.Round(subtotal*100)
+	if short <= 0 {
+		return nil
+	}
+
+	shortfall := short / 100
+	return apierrors.New(apierrors.BelowMinimum, fmt.Sprintf("Add %.2f more to reach the %.2f minimum for %s orders", shortfall, minimum, mode)).
+		AddDetail("mode", mode).
+		AddDetail("minimum", minimum).
+		AddDetail("subtotal", subtotal).
+		AddDetail("shortfall", shortfall)
+}
+
+// CheckTotalLimit rejects orders whose total is over the maximum
+func CheckTotalLimit(total float64, limits config.Limits) error {
+	if limits.MaxTotal > 0 && total > limits.MaxTotal {
+		return apierrors.New(apierrors.OrderTooLarge, fmt.Sprintf("Order total cannot exceed %.2f", limits.MaxTotal)).
+			AddDetail("limit", LimitMaxTotal).
+			AddDetail("max", limits.MaxTotal).
+			AddDetail("total", total)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckItemLimits(t *testing.T) {
+	limits := config.Limits{MaxQuantity: 5, MaxItems: 2}
+
+	tests := []struct {
+		name      string
+		items     []models.OrderItem
+		limits    config.Limits
+		wantLimit string
+	}{
+		{
+			name:   "within limits",
+			limits: limits,
+			items:  []models.OrderItem{{ProductID: "prod-1", Quantity: 5}, {ProductID: "prod-2", Quantity: 1}},
+		},
+		{
+			name:      "quantity over the maximum",
+			limits:    limits,
+			items:     []models.OrderItem{{ProductID: "prod-1", Quantity: 1}, {ProductID: "prod-2", Quantity: 6}},
+			wantLimit: LimitMaxQuantity,
+		},
+		{
+			name:   "lines with different notes share the quantity limit",
+			limits: limits,
+			items: []models.OrderItem{
+				{ProductID: "prod-1", Quantity: 3, Notes: "no onions"},
+				{ProductID: "prod-1", Quantity: 3},
+			},
+			wantLimit: LimitMaxQuantity,
+		},
+		{
+			name:   "repeated products count once",
+			limits: limits,
+			items: []models.OrderItem{
+				{ProductID: "prod-1", Quantity: 1},
+				{ProductID: "prod-2", Quantity: 1},
+				{ProductID: "prod-1", Quantity: 1},
+			},
+		},
+		{
+			name:   "variants count separately",
+			limits: limits,
+			items: []models.OrderItem{
+				{ProductID: "coffee", VariantID: "small", Quantity: 1},
+				{ProductID: "coffee", VariantID: "large", Quantity: 1},
+				{ProductID: "prod-1", Quantity: 1},
+			},
+			wantLimit: LimitMaxItems,
+		},
+		{
+			name:   "zero disables limits",
+			items:  []models.OrderItem{{ProductID: "prod-1", Quantity: 1000}, {ProductID: "prod-2", Quantity: 1}, {ProductID: "prod-3", Quantity: 1}},
+			limits: config.Limits{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckItemLimits(tt.items, tt.limits)
+			if tt.wantLimit == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var errResp *models.ErrorResponse
+			require.ErrorAs(t, err, &errResp)
+			assert.Equal(t, "ORDER_TOO_LARGE", errResp.Code)
+			assert.Equal(t, tt.wantLimit, errResp.Details["limit"])
+		})
+	}
+}
+
+func TestCheckTotalLimit(t *testing.T) {
+	limits := config.Limits{MaxTotal: 100}
+
+	assert.NoError(t, CheckTotalLimit(100, limits))
+	assert.NoError(t, CheckTotalLimit(1e6, config.Limits{}))
+
+	var errResp *models.ErrorResponse
+	require.ErrorAs(t, CheckTotalLimit(100.01, limits), &errResp)
+	assert.Equal(t, "ORDER_TOO_LARGE", errResp.Code)
+	assert.Equal(t, LimitMaxTotal, errResp.Details["limit"])
+	assert.Equal(t, 100.0, errResp.Details["max"])
+}
+
+func TestCheckMinimum(t *testing.T) {
+	limits := config.Limits{MinPickup: 10, MinDelivery: 25}
+
+	tests := []struct {
+		name          string
+		subtotal      float64
+		delivery      bool
+		wantShortfall float64
+	}{
+		{name: "pickup at the minimum", subtotal: 10},
+		{name: "pickup under the minimum", subtotal: 7.5, wantShortfall: 2.5},
+		{name: "delivery has its own minimum", subtotal: 20, delivery: true, wantShortfall: 5},
+		{name: "rounding errors are ignored", subtotal: 0.1 + 0.2 + 24.7, delivery: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckMinimum(tt.subtotal, tt.delivery, limits)
+			if tt.wantShortfall == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			var errResp *models.ErrorResponse
+			require.ErrorAs(t, err, &errResp)
+			assert.Equal(t, "BELOW_MINIMUM", errResp.Code)
+			assert.InDelta(t, tt.wantShortfall, errResp.Details["shortfall"], 1e-9)
+		})
+	}
+
+	var errResp *models.ErrorResponse
+	require.ErrorAs(t, CheckMinimum(20, true, limits), &errResp)
+	assert.Equal(t, "Add 5.00 more to reach the 25.00 minimum for delivery orders", errResp.Message)
+	assert.NoError(t, CheckMinimum(0.5, true, config.Limits{}))
+}
@@ -0,0 +1,23 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SanitizeNote cleans up free-text customer notes before they reach the
+// kitchen. Line breaks and tabs become spaces, other control and formatting
+// characters (such as zero-width or bidi overrides) are dropped, and runs of
+// whitespace are collapsed.
+func SanitizeNote(note string) string {
+	cleaned := strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsSpace(r):
+			return ' '
+		case unicode.IsControl(r), unicode.Is(unicode.Cf, r), r == unicode.ReplacementChar:
+			return -1
+		}
+		return r
+	}, note)
+	return strings.Join(strings.Fields(cleaned), " ")
+}
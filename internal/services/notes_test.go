@@ -0,0 +1,31 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeNote(t *testing.T) {
+	tests := []struct {
+		name string
+		note string
+		want string
+	}{
+		{name: "empty", note: "", want: ""},
+		{name: "plain", note: "no onions", want: "no onions"},
+		{name: "surrounding whitespace", note: "  leave at door \n", want: "leave at door"},
+		{name: "line breaks and tabs", note: "ring twice\r\nthen\tknock", want: "ring twice then knock"},
+		{name: "control characters", note: "no\x00 on\x1bions\x7f", want: "no onions"},
+		{name: "formatting characters", note: "extra\u200b sauce\u202e", want: "extra sauce"},
+		{name: "invalid utf-8", note: "no \xff onions", want: "no onions"},
+		{name: "non-latin text", note: "sans oignons, s'il vous plaît 🙏", want: "sans oignons, s'il vous plaît 🙏"},
+		{name: "only whitespace", note: " \n\t ", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, SanitizeNote(tt.note))
+		})
+	}
+}
@@ -1,66 +1,377 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
 	"github.com/ravibandhu/oolio-food-ordering/internal/data"
+	"github.com/ravibandhu/oolio-food-ordering/internal/delivery"
+	"github.com/ravibandhu/oolio-food-ordering/internal/kitchen"
 	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/payments"
+	"github.com/ravibandhu/oolio-food-ordering/internal/pos"
+	"github.com/ravibandhu/oolio-food-ordering/internal/tables"
+	"github.com/ravibandhu/oolio-food-ordering/internal/tenant"
+	"github.com/ravibandhu/oolio-food-ordering/internal/velocity"
 )
 
 // OrderService defines the interface for order operations
 type OrderService interface {
-	PlaceOrder(req *models.OrderRequest) (*models.Order, error)
+	PlaceOrder(ctx context.Context, req *models.OrderRequest) (*models.Order, error)
 }
 
 // OrderServiceImpl implements the OrderService interface
 type OrderServiceImpl struct {
 	store *data.Store
+	now   func() time.Time
 }
 
 // NewOrderService creates a new OrderService instance
 func NewOrderService(store *data.Store) OrderService {
 	return &OrderServiceImpl{
 		store: store,
+		now:   time.Now,
 	}
 }
 
-// PlaceOrder processes a new order request
-func (s *OrderServiceImpl) PlaceOrder(req *models.OrderRequest) (*models.Order, error) {
-	// Validate products and calculate total
-	var products []models.Product
-	var totalAmount float64
+// PlaceOrder processes a new order request with the tenant carried by ctx,
+// or with the service's store and nothing else configured when there is
+// none. Under a dry-run context the order is checked and priced as it would
+// be placed, and returned without being placed.
+func (s *OrderServiceImpl) PlaceOrder(ctx context.Context, req *models.OrderRequest) (*models.Order, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		t = &tenant.Tenant{Store: s.store}
+	}
+
+	// Reject orders outside opening hours and the order-ahead window
+	if now := s.now(); !t.Hours.AcceptsOrders(now) {
+		errResp := apierrors.New(apierrors.StoreClosed, "The restaurant is not accepting orders right now")
+		if next, ok := t.Hours.NextOpening(now); ok {
+			errResp.AddDetail("next_opening", next.Format(time.RFC3339))
+		}
+		return nil, errResp
+	}
+
+	// Clean up notes, then merge repeated lines, so limits and pricing see
+	// one line per item
+	cleaned := make([]models.OrderItem, len(req.Items))
+	for i, item := range req.Items {
+		item.Notes = SanitizeNote(item.Notes)
+		cleaned[i] = item
+	}
+	requested, err := MergeItems(cleaned)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reject orders the kitchen should not take on
+	if err := CheckItemLimits(requested, t.Limits); err != nil {
+		return nil, err
+	}
 
-	// Validate and collect products
-	for _, item := range req.Items {
-		product, err := s.store.GetProduct(item.ProductID)
+	// Look up every product at once, so all unknown IDs are reported together
+	ids := make([]string, len(requested))
+	for i, item := range requested {
+		ids[i] = item.ProductID
+	}
+	found, missing, err := t.Store.GetProducts(ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		return nil, apierrors.New(apierrors.InvalidProduct, fmt.Sprintf("Invalid product ID: %s", strings.Join(missing, ", "))).
+			AddDetail("productIds", missing)
+	}
+
+	// Refuse products on no menu being served
+	active := t.Menus.Active(s.now())
+	var offMenu []string
+	for _, id := range ids {
+		if !active.Offers(found[id]) && !slices.Contains(offMenu, id) {
+			offMenu = append(offMenu, id)
+		}
+	}
+	if len(offMenu) > 0 {
+		return nil, apierrors.New(apierrors.NotOnMenu, fmt.Sprintf("Not on the menu right now: %s", strings.Join(offMenu, ", "))).
+			AddDetail("productIds", offMenu).
+			AddDetail("menus", active.Names())
+	}
+
+	// Collect products, pricing each item by its variant
+	var products []models.Product
+	var prices []float64
+	for _, item := range requested {
+		product := found[item.ProductID]
+		price, err := UnitPrice(product, item.VariantID)
 		if err != nil {
-			return nil, models.NewErrorResponse("INVALID_PRODUCT", fmt.Sprintf("Invalid product ID: %s", item.ProductID))
+			return nil, apierrors.New(apierrors.InvalidVariant, fmt.Sprintf("Invalid variant for product %s", item.ProductID)).
+				AddDetail("productId", item.ProductID).
+				AddDetail("variantId", item.VariantID).
+				AddDetail("error", err.Error())
 		}
 		products = append(products, *product)
-		totalAmount += product.Price * float64(item.Quantity)
+		prices = append(prices, price)
 	}
 
-	// Apply coupon if provided
-	if req.CouponCode != "" {
-		// Validate coupon
-		if !s.store.ValidateCoupon(req.CouponCode) {
-			return nil, models.NewErrorResponse("INVALID_COUPON", "Invalid coupon code")
+	// Let clients showing out of date prices refresh before ordering
+	var repriced map[string]bool
+	if req.CatalogRevision != 0 {
+		repriced = make(map[string]bool)
+		for _, id := range t.Store.RepricedSince(req.CatalogRevision, ids) {
+			repriced[id] = true
+		}
+	}
+	if changes := PriceChanges(requested, prices, repriced); len(changes) > 0 {
+		return nil, apierrors.New(apierrors.PriceChanged, "Prices have changed since the order was built").
+			AddDetail("items", changes).
+			AddDetail("catalogRevision", t.Store.Revision())
+	}
+
+	// Validate coupon if provided
+	if req.CouponCode != "" && !t.Store.ValidateCoupon(req.CouponCode) {
+		return nil, apierrors.New(apierrors.InvalidCoupon, "Invalid coupon code")
+	}
+
+	// Invoice businesses under their tax ID, charging no tax when exempt
+	charges := t.Charges
+	var billing *models.BusinessBilling
+	if req.Billing != nil {
+		if billing, err = NormalizeBilling(req.Billing); err != nil {
+			return nil, err
+		}
+		if billing.TaxExempt {
+			charges.TaxRate = 0
+		}
+	}
+
+	// Serve dine-in orders to the table whose QR code was scanned
+	var table string
+	if req.TableToken != "" {
+		if table, err = t.Tables.Verify(t.ID, req.TableToken); err != nil {
+			if errors.Is(err, tables.ErrDisabled) {
+				return nil, apierrors.New(apierrors.DineInUnavailable, "This restaurant does not take table orders")
+			}
+			return nil, apierrors.New(apierrors.InvalidTable, "Invalid table token")
+		}
+	}
+
+	// Check the delivery address is inside a zone the restaurant serves
+	var zone *delivery.Zone
+	if req.DeliveryAddress != nil {
+		if t.Zones == nil {
+			return nil, apierrors.New(apierrors.DeliveryUnavailable, "This restaurant does not deliver")
+		}
+		var ok bool
+		if zone, ok = t.Zones.Match(req.DeliveryAddress); !ok {
+			return nil, apierrors.New(apierrors.AddressNotServiceable, "The delivery address is outside the delivery area").
+				AddDetail("postcode", req.DeliveryAddress.Postcode)
 		}
-		// Apply 10% discount
-		totalAmount = totalAmount * 0.90
 	}
 
 	// Create order items with prices
 	var items []models.OrderItem
-	for i, item := range req.Items {
+	for i, item := range requested {
 		items = append(items, models.OrderItem{
 			ProductID: item.ProductID,
+			VariantID: item.VariantID,
 			Quantity:  item.Quantity,
-			Price:     products[i].Price,
+			Price:     prices[i],
+			Notes:     item.Notes,
 		})
 	}
 
+	// Calculate total, applying the promotions in effect and the coupon
+	// discount, then tax and fees. Each amount is rounded by the currency's
+	// rule as it is worked out.
+	adjustments := t.Promotions.Adjustments(s.now(), items, products)
+	subtotal, err := CalculateTotal(items, adjustments, req.CouponCode != "")
+	subtotal = Round(subtotal, t.Rounding)
+	totalAmount := subtotal
+	if err == nil {
+		totalAmount, err = ApplyCharges(subtotal, charges)
+	}
+	if err != nil {
+		return nil, apierrors.New(apierrors.InvalidTotal, "Order total is out of range")
+	}
+	if err := CheckMinimum(subtotal, zone != nil, t.Limits); err != nil {
+		return nil, err
+	}
+
 	// Create and return the order
 	order := models.NewOrder(items, products, totalAmount, req.CouponCode)
+	order.TenantID = t.ID
+	order.Notes = SanitizeNote(req.Notes)
+	order.Adjustments = adjustments
+	order.Billing = billing
+	order.Table = table
+	order.TaxAmount = Round(subtotal*charges.TaxRate, t.Rounding)
+	order.ServiceFee = charges.ServiceFee
+	if req.CouponCode != "" {
+		undiscounted, _ := CalculateTotal(items, adjustments, false)
+		order.Discount = Round(Round(undiscounted, t.Rounding)-subtotal, t.Rounding)
+	}
+	if zone != nil {
+		order.DeliveryAddress = req.DeliveryAddress
+		order.DeliveryZone = zone.Name
+		order.DeliveryFee = zone.Fee
+		order.TotalAmount += zone.Fee
+	}
+	if t.Rounding != nil {
+		// Sum the rounded amounts, so the receipt adds up
+		order.TotalAmount = Round(subtotal+order.TaxAmount+order.ServiceFee+order.DeliveryFee, t.Rounding)
+		order.Rounding = t.Rounding
+	}
+	if err := CheckTotalLimit(order.TotalAmount, t.Limits); err != nil {
+		return nil, err
+	}
+
+	// Check the payment suits its method, now the total is final
+	if req.Payment != nil {
+		if order.Payment, order.ChangeDue, err = NormalizePayment(req.Payment, order.TotalAmount); err != nil {
+			return nil, err
+		}
+	}
+
+	// A dry run checks stock without taking it, and stops short of every
+	// step with effects outside the order
+	if IsDryRun(ctx) {
+		if short := t.Inventory.Short(pos.Needs(requested, found)); len(short) > 0 {
+			return nil, OutOfStock(requested, found, short)
+		}
+		return order, nil
+	}
+
+	// Take the items out of stock, refusing items the POS has too few of
+	// once other checkouts' holds are set aside. From here on every step
+	// that has effects outside the order is undone if a later step fails,
+	// so failed orders leave nothing behind.
+	var placing saga
+	needs := pos.Needs(requested, found)
+	if short := t.Inventory.TakeHeld(pos.HoldFromContext(ctx), needs); len(short) > 0 {
+		return nil, OutOfStock(requested, found, short)
+	}
+	placing.done("stock reservation", func() error {
+		t.Inventory.Restock(needs)
+		return nil
+	})
+
+	// Refuse clients ordering far faster than customers do
+	decision, rule := t.Velocity.Check(velocity.FromContext(ctx), order.TotalAmount)
+	if decision == velocity.Reject {
+		return nil, placing.compensate(apierrors.New(apierrors.TooManyOrders, "Too many orders; please try again later").
+			AddDetail("rule", rule))
+	}
+
+	// Hold borderline orders for review rather than preparing them, and
+	// queue the rest in the kitchen, reporting when they should be ready.
+	// A busy kitchen refuses orders before the payment is taken.
+	if decision == velocity.Hold {
+		order.SetStatus(models.OrderStatusOnHold, models.ActorSystem, order.CreatedAt)
+	} else if t.Kitchen != nil {
+		estimate, err := t.Kitchen.Enqueue(order)
+		if err != nil {
+			return nil, placing.compensate(kitchenError(err, s.now()))
+		}
+		order.EstimatedReadyAt = &estimate.ReadyAt
+		order.EstimatedDeliveryAt = estimate.DeliveryAt
+		placing.done("kitchen ticket", func() error {
+			t.Kitchen.Remove(order.ID)
+			return nil
+		})
+	}
+
+	// Capture the payment, voiding it even if the client has gone by the
+	// time a later step fails
+	if t.Processor != nil && payments.Captured(order.Payment) {
+		reference, err := t.Processor.Capture(ctx, order)
+		if err != nil {
+			return nil, placing.compensate(captureError(order.Payment.Method, err))
+		}
+		order.PaymentReference = reference
+		placing.done("payment "+reference, func() error {
+			return t.Processor.Void(context.WithoutCancel(ctx), reference)
+		})
+	}
+
+	// Give up on orders whose client was already told they timed out, as
+	// the customer would not know the order was taken
+	if err := ctx.Err(); err != nil {
+		return nil, placing.compensate(apierrors.New(apierrors.Timeout, "Order was not placed in time"))
+	}
+
+	// Number the invoice only once nothing can refuse the order, so refused
+	// orders leave no gaps in the sequence
+	if order.InvoiceNumber, err = t.Invoices.Issue(order.ID); err != nil {
+		return nil, placing.compensate(err)
+	}
+
+	// Keep the order, as the customer is told about it, before anything
+	// else sees it, so the order feed and takings only ever include orders
+	// that were kept
+	if t.Orders != nil {
+		if err := t.Orders.Place(order); err != nil {
+			return nil, placing.compensate(err)
+		}
+	}
+
+	// Add the preparation the kitchen started while the order was placed
+	// to its history
+	if t.Kitchen != nil {
+		t.Kitchen.Flush()
+	}
+
+	// Let the POS collect the order, and total it by payment method
+	t.Exports.Record(order)
+	t.Payments.Record(order)
+	if decision == velocity.Hold {
+		return order, nil
+	}
+
+	// Let the customer review what they ordered
+	t.Reviews.RecordPurchase(order)
 	return order, nil
 }
+
+// OutOfStock reports the items of an order whose SKUs are short of stock
+func OutOfStock(requested []models.OrderItem, found map[string]*models.Product, short []string) error {
+	var productIDs []string
+	for _, item := range requested {
+		if sku := pos.SKU(found[item.ProductID], item.VariantID); slices.Contains(short, sku) && !slices.Contains(productIDs, item.ProductID) {
+			productIDs = append(productIDs, item.ProductID)
+		}
+	}
+	return apierrors.New(apierrors.OutOfStock, "Some items are out of stock").
+		AddDetail("productIds", productIDs).
+		AddDetail("skus", short)
+}
+
+// kitchenError reports an order the kitchen refused, suggesting when to
+// retry as of now
+func kitchenError(err error, now time.Time) error {
+	var busy *kitchen.BusyError
+	if !errors.As(err, &busy) {
+		return err
+	}
+	retryAfter := max(busy.RetryAt.Sub(now), time.Second)
+	return apierrors.New(apierrors.KitchenBusy, "The kitchen is busy; please try again later").
+		AddDetail("retry_at", busy.RetryAt.Format(time.RFC3339)).
+		AddDetail("retry_after", int(math.Ceil(retryAfter.Seconds())))
+}
+
+// captureError reports a payment the processor did not capture
+func captureError(method string, err error) error {
+	if errors.Is(err, payments.ErrDeclined) {
+		return apierrors.New(apierrors.PaymentDeclined, "The payment was declined").
+			AddDetail("method", method)
+	}
+	return apierrors.New(apierrors.PaymentUnavailable, "The payment could not be taken; please try again later").
+		AddDetail("method", method).
+		AddDetail("error", err.Error())
+}
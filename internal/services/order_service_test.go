@@ -1,15 +1,31 @@
 package services
 
 import (
+	"context"
+	"errors"
+	"net/http"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
 	"github.com/ravibandhu/oolio-food-ordering/internal/config"
 	"github.com/ravibandhu/oolio-food-ordering/internal/data"
+	"github.com/ravibandhu/oolio-food-ordering/internal/delivery"
+	"github.com/ravibandhu/oolio-food-ordering/internal/hours"
+	"github.com/ravibandhu/oolio-food-ordering/internal/invoices"
+	"github.com/ravibandhu/oolio-food-ordering/internal/kitchen"
+	"github.com/ravibandhu/oolio-food-ordering/internal/menus"
 	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/orders"
+	"github.com/ravibandhu/oolio-food-ordering/internal/payments"
+	"github.com/ravibandhu/oolio-food-ordering/internal/pos"
+	"github.com/ravibandhu/oolio-food-ordering/internal/promotions"
+	"github.com/ravibandhu/oolio-food-ordering/internal/tables"
+	"github.com/ravibandhu/oolio-food-ordering/internal/tenant"
 	"github.com/ravibandhu/oolio-food-ordering/internal/testutil"
+	"github.com/ravibandhu/oolio-food-ordering/internal/velocity"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -322,6 +338,995 @@ func TestPlaceOrder(t *testing.T) {
 	})
 }
 
+func TestOrderServiceImpl_PlaceOrder_Tenant(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+	defer store.Close()
+
+	orderService := NewOrderService(store)
+	request := &models.OrderRequest{
+		CouponCode: testutil.ValidCoupon,
+		Items:      []models.OrderItem{{ProductID: "prod-1", Quantity: 2}},
+	}
+
+	// Without a tenant no charges apply
+	order, err := orderService.PlaceOrder(context.Background(), request)
+	require.NoError(t, err)
+	assert.Empty(t, order.TenantID)
+	assert.InDelta(t, 9.99*2*0.9, order.TotalAmount, 0.001)
+
+	// The tenant's store and charges are used
+	ctx := tenant.NewContext(context.Background(), &tenant.Tenant{
+		ID:      "harbour",
+		Store:   store,
+		Charges: config.Charges{TaxRate: 0.1, ServiceFee: 2},
+	})
+	order, err = orderService.PlaceOrder(ctx, request)
+	require.NoError(t, err)
+	assert.Equal(t, "harbour", order.TenantID)
+	assert.InDelta(t, 9.99*2*0.9*1.1+2, order.TotalAmount, 0.001)
+	assert.InDelta(t, 9.99*2*0.9*0.1, order.TaxAmount, 0.001)
+	assert.InDelta(t, 9.99*2*0.1, order.Discount, 0.001)
+	assert.Equal(t, 2.0, order.ServiceFee)
+
+	// Businesses exempt from tax are charged the fees but no tax
+	request.Billing = &models.BusinessBilling{
+		CompanyName:     "Acme Catering",
+		Country:         "AU",
+		TaxID:           "51 824 753 556",
+		TaxExempt:       true,
+		ExemptionReason: "Registered charity",
+	}
+	order, err = orderService.PlaceOrder(ctx, request)
+	require.NoError(t, err)
+	assert.InDelta(t, 9.99*2*0.9+2, order.TotalAmount, 0.001)
+	assert.Zero(t, order.TaxAmount)
+	require.NotNil(t, order.Billing)
+	assert.Equal(t, "51824753556", order.Billing.TaxID)
+	assert.Equal(t, "Registered charity", order.Billing.ExemptionReason)
+}
+
+func TestOrderServiceImpl_PlaceOrder_Rounding(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+	defer store.Close()
+
+	orderService := NewOrderService(store)
+	rule := &models.RoundingRule{Currency: "CHF", Mode: config.RoundingHalfUp, Increment: 0.05}
+	ctx := tenant.NewContext(context.Background(), &tenant.Tenant{
+		ID:       "harbour",
+		Store:    store,
+		Charges:  config.Charges{TaxRate: 0.1, ServiceFee: 2},
+		Rounding: rule,
+	})
+	order, err := orderService.PlaceOrder(ctx, &models.OrderRequest{
+		CouponCode: testutil.ValidCoupon,
+		Items:      []models.OrderItem{{ProductID: "prod-1", Quantity: 2}},
+	})
+	require.NoError(t, err)
+
+	// 17.982 after the coupon is charged as 18.00, tax on that as 1.80, and
+	// the discount is taken off the rounded 19.98
+	assert.Equal(t, 1.8, order.TaxAmount)
+	assert.Equal(t, 2.0, order.Discount)
+	assert.Equal(t, 21.8, order.TotalAmount)
+	assert.Equal(t, rule, order.Rounding)
+}
+
+func TestOrderServiceImpl_PlaceOrder_Limits(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+	defer store.Close()
+
+	orderService := NewOrderService(store)
+	ctx := tenant.NewContext(context.Background(), &tenant.Tenant{
+		ID:      "harbour",
+		Store:   store,
+		Charges: config.Charges{ServiceFee: 2},
+		Limits:  config.Limits{MaxQuantity: 3, MaxTotal: 25},
+	})
+
+	// The total limit includes charges
+	_, err = orderService.PlaceOrder(ctx, &models.OrderRequest{Items: []models.OrderItem{{ProductID: "prod-1", Quantity: 2}}})
+	require.NoError(t, err)
+	_, err = orderService.PlaceOrder(ctx, &models.OrderRequest{Items: []models.OrderItem{
+		{ProductID: "prod-1", Quantity: 2},
+		{ProductID: "prod-2", Quantity: 1},
+	}})
+	var errResp *models.ErrorResponse
+	require.ErrorAs(t, err, &errResp)
+	assert.Equal(t, "ORDER_TOO_LARGE", errResp.Code)
+	assert.Equal(t, LimitMaxTotal, errResp.Details["limit"])
+
+	_, err = orderService.PlaceOrder(ctx, &models.OrderRequest{Items: []models.OrderItem{{ProductID: "prod-1", Quantity: 4}}})
+	require.ErrorAs(t, err, &errResp)
+	assert.Equal(t, LimitMaxQuantity, errResp.Details["limit"])
+	assert.Equal(t, "prod-1", errResp.Details["productId"])
+
+	// The minimum applies to the discounted subtotal, before charges
+	ctx = tenant.NewContext(context.Background(), &tenant.Tenant{
+		ID:      "harbour",
+		Store:   store,
+		Charges: config.Charges{ServiceFee: 2},
+		Limits:  config.Limits{MinPickup: 10},
+	})
+	_, err = orderService.PlaceOrder(ctx, &models.OrderRequest{Items: []models.OrderItem{{ProductID: "prod-1", Quantity: 1}}})
+	require.ErrorAs(t, err, &errResp)
+	assert.Equal(t, "BELOW_MINIMUM", errResp.Code)
+	assert.Equal(t, "pickup", errResp.Details["mode"])
+	assert.InDelta(t, 0.01, errResp.Details["shortfall"], 1e-9)
+	_, err = orderService.PlaceOrder(ctx, &models.OrderRequest{Items: []models.OrderItem{{ProductID: "prod-1", Quantity: 2}}})
+	assert.NoError(t, err)
+}
+
+func TestOrderServiceImpl_PlaceOrder_CatalogRevision(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+	defer store.Close()
+
+	orderService := NewOrderService(store)
+	rendered := store.Revision()
+	request := &models.OrderRequest{
+		CatalogRevision: rendered,
+		Items:           []models.OrderItem{{ProductID: "prod-1", Quantity: 1}, {ProductID: "prod-2", Quantity: 1}},
+	}
+	_, err = orderService.PlaceOrder(context.Background(), request)
+	require.NoError(t, err)
+
+	// Repricing a product after the cart was built fails the order
+	product, err := store.GetProduct("prod-2")
+	require.NoError(t, err)
+	repriced := *product
+	repriced.Price = 21.5
+	require.NoError(t, store.UpdateProduct(&repriced))
+
+	_, err = orderService.PlaceOrder(context.Background(), request)
+	var errResp *models.ErrorResponse
+	require.ErrorAs(t, err, &errResp)
+	assert.Equal(t, "PRICE_CHANGED", errResp.Code)
+	assert.Equal(t, []PriceChange{{ProductID: "prod-2", CurrentPrice: 21.5}}, errResp.Details["items"])
+	assert.Equal(t, store.Revision(), errResp.Details["catalogRevision"])
+
+	// Refreshing the cart to the new revision lets the order through
+	request.CatalogRevision = store.Revision()
+	_, err = orderService.PlaceOrder(context.Background(), request)
+	assert.NoError(t, err)
+}
+
+func TestOrderServiceImpl_PlaceOrder_OpeningHours(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+	defer store.Close()
+
+	schedule, err := hours.New("", map[string][]string{"monday": {"09:00-17:00"}}, 15*time.Minute)
+	require.NoError(t, err)
+	ctx := tenant.NewContext(context.Background(), &tenant.Tenant{ID: "harbour", Store: store, Hours: schedule})
+
+	request := &models.OrderRequest{
+		Items: []models.OrderItem{{ProductID: "prod-1", Quantity: 1}},
+	}
+
+	tests := []struct {
+		name        string
+		now         time.Time
+		wantClosed  bool
+		nextOpening string
+	}{
+		{name: "open", now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{name: "within order-ahead window", now: time.Date(2024, 1, 1, 8, 50, 0, 0, time.UTC)},
+		{name: "before order-ahead window", now: time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC), wantClosed: true, nextOpening: "2024-01-01T09:00:00Z"},
+		{name: "after closing", now: time.Date(2024, 1, 1, 17, 0, 0, 0, time.UTC), wantClosed: true, nextOpening: "2024-01-08T09:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orderService := &OrderServiceImpl{store: store, now: func() time.Time { return tt.now }}
+
+			order, err := orderService.PlaceOrder(ctx, request)
+			if !tt.wantClosed {
+				require.NoError(t, err)
+				assert.NotNil(t, order)
+				return
+			}
+
+			var errResp *models.ErrorResponse
+			require.ErrorAs(t, err, &errResp)
+			assert.Equal(t, "STORE_CLOSED", errResp.Code)
+			assert.Equal(t, tt.nextOpening, errResp.Details["next_opening"])
+		})
+	}
+}
+
+func TestOrderServiceImpl_PlaceOrder_Promotions(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+	defer store.Close()
+
+	promos, err := promotions.New("", []config.Promotion{
+		{Name: "happy-hour", Percent: -20, Weekly: map[string][]string{"monday": {"17:00-19:00"}}},
+	})
+	require.NoError(t, err)
+	ctx := tenant.NewContext(context.Background(), &tenant.Tenant{
+		ID:         "harbour",
+		Store:      store,
+		Charges:    config.Charges{TaxRate: 0.1},
+		Promotions: promos,
+	})
+	request := &models.OrderRequest{Items: []models.OrderItem{{ProductID: "prod-1", Quantity: 2}}}
+
+	// Item prices stay as listed; the promotion is its own line of the order
+	orderService := &OrderServiceImpl{store: store, now: func() time.Time { return time.Date(2024, 1, 1, 18, 0, 0, 0, time.UTC) }}
+	order, err := orderService.PlaceOrder(ctx, request)
+	require.NoError(t, err)
+	assert.Equal(t, 9.99, order.Items[0].Price)
+	assert.Equal(t, []models.PriceAdjustment{{Name: "happy-hour", Percent: -20, Amount: -4}}, order.Adjustments)
+	assert.InDelta(t, (19.98-4)*1.1, order.TotalAmount, 0.001)
+	assert.InDelta(t, (19.98-4)*0.1, order.TaxAmount, 0.001)
+
+	orderService.now = func() time.Time { return time.Date(2024, 1, 1, 20, 0, 0, 0, time.UTC) }
+	order, err = orderService.PlaceOrder(ctx, request)
+	require.NoError(t, err)
+	assert.Empty(t, order.Adjustments)
+	assert.InDelta(t, 19.98*1.1, order.TotalAmount, 0.001)
+}
+
+func TestOrderServiceImpl_PlaceOrder_Menus(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+	defer store.Close()
+
+	served, err := menus.New("", []config.Menu{
+		{Name: "breakfast", Products: []string{"prod-1"}, Weekly: map[string][]string{"monday": {"07:00-11:00"}}},
+	})
+	require.NoError(t, err)
+	ctx := tenant.NewContext(context.Background(), &tenant.Tenant{ID: "harbour", Store: store, Menus: served})
+	request := &models.OrderRequest{Items: []models.OrderItem{{ProductID: "prod-1", Quantity: 1}}}
+
+	orderService := &OrderServiceImpl{store: store, now: func() time.Time { return time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC) }}
+	_, err = orderService.PlaceOrder(ctx, request)
+	require.NoError(t, err)
+
+	// Products on no menu being served are refused
+	orderService.now = func() time.Time { return time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC) }
+	_, err = orderService.PlaceOrder(ctx, request)
+	var errResp *models.ErrorResponse
+	require.ErrorAs(t, err, &errResp)
+	assert.Equal(t, "NOT_ON_MENU", errResp.Code)
+	assert.Equal(t, []string{"prod-1"}, errResp.Details["productIds"])
+	assert.Equal(t, []string{}, errResp.Details["menus"])
+}
+
+func TestOrderServiceImpl_PlaceOrder_Delivery(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+	defer store.Close()
+
+	zones, err := delivery.New([]config.Zone{{Name: "cbd", Postcodes: []string{"2000"}, Fee: 4.5}})
+	require.NoError(t, err)
+
+	orderService := NewOrderService(store)
+	items := []models.OrderItem{{ProductID: "prod-1", Quantity: 1}}
+
+	tests := []struct {
+		name     string
+		zones    *delivery.Zones
+		address  *models.Address
+		wantCode string
+		wantFee  float64
+	}{
+		{name: "pickup", zones: zones},
+		{name: "address in zone", zones: zones, address: &models.Address{Line1: "1 George St", Postcode: "2000"}, wantFee: 4.5},
+		{name: "address outside zones", zones: zones, address: &models.Address{Line1: "1 Far Rd", Postcode: "2570"}, wantCode: "ADDRESS_NOT_SERVICEABLE"},
+		{name: "restaurant without delivery", address: &models.Address{Line1: "1 George St", Postcode: "2000"}, wantCode: "DELIVERY_UNAVAILABLE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tenant.NewContext(context.Background(), &tenant.Tenant{ID: "harbour", Store: store, Zones: tt.zones})
+
+			order, err := orderService.PlaceOrder(ctx, &models.OrderRequest{Items: items, DeliveryAddress: tt.address})
+			if tt.wantCode != "" {
+				var errResp *models.ErrorResponse
+				require.ErrorAs(t, err, &errResp)
+				assert.Equal(t, tt.wantCode, errResp.Code)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantFee, order.DeliveryFee)
+			assert.InDelta(t, 9.99+tt.wantFee, order.TotalAmount, 0.001)
+			if tt.address != nil {
+				assert.Equal(t, "cbd", order.DeliveryZone)
+				assert.Equal(t, tt.address, order.DeliveryAddress)
+			}
+		})
+	}
+}
+
+func TestOrderServiceImpl_PlaceOrder_DineIn(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+	defer store.Close()
+
+	signer := tables.NewSigner("0123456789abcdef0123456789abcdef")
+	token, err := signer.Sign("harbour", "12")
+	require.NoError(t, err)
+	elsewhere, err := signer.Sign("bayside", "12")
+	require.NoError(t, err)
+
+	orderService := NewOrderService(store)
+	items := []models.OrderItem{{ProductID: "prod-1", Quantity: 1}}
+
+	tests := []struct {
+		name     string
+		signer   *tables.Signer
+		token    string
+		wantCode string
+	}{
+		{name: "table order", signer: signer, token: token},
+		{name: "token of another restaurant", signer: signer, token: elsewhere, wantCode: "INVALID_TABLE"},
+		{name: "forged token", signer: signer, token: "12.forged", wantCode: "INVALID_TABLE"},
+		{name: "restaurant without table orders", token: token, wantCode: "DINE_IN_UNAVAILABLE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tenant.NewContext(context.Background(), &tenant.Tenant{ID: "harbour", Store: store, Tables: tt.signer})
+
+			order, err := orderService.PlaceOrder(ctx, &models.OrderRequest{Items: items, TableToken: tt.token})
+			if tt.wantCode != "" {
+				var errResp *models.ErrorResponse
+				require.ErrorAs(t, err, &errResp)
+				assert.Equal(t, tt.wantCode, errResp.Code)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "12", order.Table)
+			assert.Nil(t, order.DeliveryAddress)
+		})
+	}
+}
+
+func TestOrderServiceImpl_PlaceOrder_Variants(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+	defer store.Close()
+
+	coffee := models.NewProduct("coffee", "Coffee", 4, "Drinks", &models.ProductImage{
+		Thumbnail: "https://example.com/t.jpg",
+		Mobile:    "https://example.com/m.jpg",
+		Tablet:    "https://example.com/t.jpg",
+		Desktop:   "https://example.com/d.jpg",
+	})
+	coffee.Variants = []models.ProductVariant{
+		{ID: "small", Name: "Small", Price: 3.5, SKU: "COF-S"},
+		{ID: "large", Name: "Large", Price: 5, SKU: "COF-L"},
+	}
+	require.NoError(t, store.AddProduct(coffee))
+
+	orderService := NewOrderService(store)
+
+	order, err := orderService.PlaceOrder(context.Background(), &models.OrderRequest{Items: []models.OrderItem{
+		{ProductID: "coffee", VariantID: "large", Quantity: 2},
+		{ProductID: "coffee", VariantID: "small", Quantity: 1},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "large", order.Items[0].VariantID)
+	assert.Equal(t, 5.0, order.Items[0].Price)
+	assert.Equal(t, 3.5, order.Items[1].Price)
+	assert.InDelta(t, 13.5, order.TotalAmount, 0.001)
+
+	// Repeated lines are merged into one
+	order, err = orderService.PlaceOrder(context.Background(), &models.OrderRequest{Items: []models.OrderItem{
+		{ProductID: "coffee", VariantID: "large", Quantity: 1},
+		{ProductID: "prod-1", Quantity: 1},
+		{ProductID: "coffee", VariantID: "large", Quantity: 2},
+	}})
+	require.NoError(t, err)
+	require.Len(t, order.Items, 2)
+	assert.Equal(t, 3, order.Items[0].Quantity)
+	assert.InDelta(t, 15+9.99, order.TotalAmount, 0.001)
+
+	// Stale client prices are reported with the current price
+	_, err = orderService.PlaceOrder(context.Background(), &models.OrderRequest{Items: []models.OrderItem{
+		{ProductID: "coffee", VariantID: "large", Quantity: 1, Price: 4.5},
+		{ProductID: "prod-1", Quantity: 1, Price: 9.99},
+	}})
+	var errResp *models.ErrorResponse
+	require.ErrorAs(t, err, &errResp)
+	assert.Equal(t, "PRICE_CHANGED", errResp.Code)
+	assert.Equal(t, []PriceChange{{ProductID: "coffee", VariantID: "large", Price: 4.5, CurrentPrice: 5}}, errResp.Details["items"])
+
+	for _, item := range []models.OrderItem{
+		{ProductID: "coffee", Quantity: 1},
+		{ProductID: "coffee", VariantID: "medium", Quantity: 1},
+		{ProductID: "prod-1", VariantID: "large", Quantity: 1},
+	} {
+		_, err := orderService.PlaceOrder(context.Background(), &models.OrderRequest{Items: []models.OrderItem{item}})
+		var errResp *models.ErrorResponse
+		require.ErrorAs(t, err, &errResp)
+		assert.Equal(t, "INVALID_VARIANT", errResp.Code)
+		assert.Equal(t, item.ProductID, errResp.Details["productId"])
+	}
+}
+
+func TestOrderServiceImpl_PlaceOrder_UnknownProducts(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+	defer store.Close()
+
+	orderService := NewOrderService(store)
+
+	// Every unknown ID is reported, not just the first
+	_, err = orderService.PlaceOrder(context.Background(), &models.OrderRequest{Items: []models.OrderItem{
+		{ProductID: "missing-1", Quantity: 1},
+		{ProductID: "prod-1", Quantity: 1},
+		{ProductID: "missing-2", Quantity: 1},
+		{ProductID: "missing-1", Quantity: 2},
+	}})
+	var errResp *models.ErrorResponse
+	require.ErrorAs(t, err, &errResp)
+	assert.Equal(t, "INVALID_PRODUCT", errResp.Code)
+	assert.Equal(t, "Invalid product ID: missing-1, missing-2", errResp.Message)
+	assert.Equal(t, []string{"missing-1", "missing-2"}, errResp.Details["productIds"])
+
+	// Deleted products can no longer be ordered
+	require.NoError(t, store.DeleteProduct("prod-1"))
+	_, err = orderService.PlaceOrder(context.Background(), &models.OrderRequest{Items: []models.OrderItem{
+		{ProductID: "prod-1", Quantity: 1},
+	}})
+	require.ErrorAs(t, err, &errResp)
+	assert.Equal(t, "INVALID_PRODUCT", errResp.Code)
+	assert.Equal(t, []string{"prod-1"}, errResp.Details["productIds"])
+}
+
+func TestOrderServiceImpl_PlaceOrder_Notes(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+	defer store.Close()
+
+	queue := kitchen.NewQueue(config.Kitchen{Stations: 1})
+	ctx := tenant.NewContext(context.Background(), &tenant.Tenant{ID: "harbour", Store: store, Kitchen: queue})
+	orderService := NewOrderService(store)
+
+	order, err := orderService.PlaceOrder(ctx, &models.OrderRequest{
+		Notes: " leave at\ndoor ",
+		Items: []models.OrderItem{
+			{ProductID: "prod-1", Quantity: 1, Notes: "no\tonions"},
+			{ProductID: "prod-1", Quantity: 1},
+			{ProductID: "prod-1", Quantity: 1, Notes: "no onions"},
+		},
+	})
+	require.NoError(t, err)
+
+	// Notes are cleaned up, and lines merged by their cleaned notes
+	assert.Equal(t, "leave at door", order.Notes)
+	require.Len(t, order.Items, 2)
+	assert.Equal(t, "no onions", order.Items[0].Notes)
+	assert.Equal(t, 2, order.Items[0].Quantity)
+	assert.Empty(t, order.Items[1].Notes)
+
+	// The kitchen sees them
+	tickets := queue.Tickets()
+	require.Len(t, tickets, 1)
+	assert.Equal(t, order.ID, tickets[0].OrderID)
+	assert.Equal(t, "leave at door", tickets[0].Notes)
+	assert.Equal(t, "no onions", tickets[0].Items[0].Notes)
+}
+
+func TestOrderServiceImpl_PlaceOrder_Velocity(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+	defer store.Close()
+
+	queue := kitchen.NewQueue(config.Kitchen{Stations: 1})
+	ctx := tenant.NewContext(context.Background(), &tenant.Tenant{
+		ID:       "harbour",
+		Store:    store,
+		Kitchen:  queue,
+		Velocity: velocity.NewTracker(config.Velocity{HoldOrdersPerHour: 1, MaxOrdersPerHour: 2}),
+	})
+	ctx = velocity.NewContext(ctx, "192.0.2.10")
+	orderService := NewOrderService(store)
+	request := &models.OrderRequest{Items: []models.OrderItem{{ProductID: "prod-1", Quantity: 1}}}
+
+	order, err := orderService.PlaceOrder(ctx, request)
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderStatusPlaced, order.Status)
+
+	// Borderline orders are accepted but held instead of prepared
+	held, err := orderService.PlaceOrder(ctx, request)
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderStatusOnHold, held.Status)
+	assert.Equal(t, models.ActorSystem, held.StatusHistory[len(held.StatusHistory)-1].Actor)
+	assert.Nil(t, held.EstimatedReadyAt)
+	_, queued := queue.Estimate(held.ID)
+	assert.False(t, queued)
+
+	// Clear abuse is refused
+	_, err = orderService.PlaceOrder(ctx, request)
+	var errResp *models.ErrorResponse
+	require.ErrorAs(t, err, &errResp)
+	assert.Equal(t, "TOO_MANY_ORDERS", errResp.Code)
+	assert.Equal(t, velocity.RuleOrdersPerHour, errResp.Details["rule"])
+
+	// Other clients are unaffected
+	order, err = orderService.PlaceOrder(velocity.NewContext(ctx, "192.0.2.11"), request)
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderStatusPlaced, order.Status)
+}
+
+func TestOrderServiceImpl_PlaceOrder_Stock(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+	defer store.Close()
+
+	coffee := models.NewProduct("coffee", "Coffee", 4, "Drinks", &models.ProductImage{
+		Thumbnail: "https://example.com/t.jpg",
+		Mobile:    "https://example.com/m.jpg",
+		Tablet:    "https://example.com/t.jpg",
+		Desktop:   "https://example.com/d.jpg",
+	})
+	coffee.Variants = []models.ProductVariant{
+		{ID: "small", Name: "Small", Price: 3.5, SKU: "COF-S"},
+		{ID: "large", Name: "Large", Price: 5, SKU: "COF-L"},
+	}
+	require.NoError(t, store.AddProduct(coffee))
+
+	inventory := pos.NewInventory()
+	three, none := 3, 0
+	inventory.Sync([]pos.LevelUpdate{{SKU: "COF-L", Quantity: &three}, {SKU: "COF-S", Quantity: &none}})
+	exports := pos.NewExports()
+	ctx := tenant.NewContext(context.Background(), &tenant.Tenant{
+		ID:        "harbour",
+		Store:     store,
+		Inventory: inventory,
+		Exports:   exports,
+		Velocity:  velocity.NewTracker(config.Velocity{MaxOrdersPerHour: 2}),
+	})
+	ctx = velocity.NewContext(ctx, "192.0.2.10")
+	orderService := NewOrderService(store)
+
+	// Untracked products are not limited
+	order, err := orderService.PlaceOrder(ctx, &models.OrderRequest{Items: []models.OrderItem{
+		{ProductID: "coffee", VariantID: "large", Quantity: 2},
+		{ProductID: "prod-1", Quantity: 50},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, inventory.List()[0].Quantity)
+
+	page := exports.After(0, 10)
+	require.Len(t, page.Orders, 1)
+	assert.Equal(t, order.ID, page.Orders[0].OrderID)
+	assert.Equal(t, "COF-L", page.Orders[0].Items[0].SKU)
+
+	// Nothing is taken when any item is short
+	_, err = orderService.PlaceOrder(ctx, &models.OrderRequest{Items: []models.OrderItem{
+		{ProductID: "coffee", VariantID: "large", Quantity: 1},
+		{ProductID: "coffee", VariantID: "small", Quantity: 1},
+	}})
+	var errResp *models.ErrorResponse
+	require.ErrorAs(t, err, &errResp)
+	assert.Equal(t, "OUT_OF_STOCK", errResp.Code)
+	assert.Equal(t, []string{"coffee"}, errResp.Details["productIds"])
+	assert.Equal(t, []string{"COF-S"}, errResp.Details["skus"])
+	assert.Equal(t, 1, inventory.List()[0].Quantity)
+
+	// Refused orders give their stock back
+	_, err = orderService.PlaceOrder(ctx, &models.OrderRequest{Items: []models.OrderItem{{ProductID: "prod-1", Quantity: 1}}})
+	require.NoError(t, err)
+	_, err = orderService.PlaceOrder(ctx, &models.OrderRequest{Items: []models.OrderItem{{ProductID: "coffee", VariantID: "large", Quantity: 1}}})
+	require.ErrorAs(t, err, &errResp)
+	assert.Equal(t, "TOO_MANY_ORDERS", errResp.Code)
+	assert.Equal(t, 1, inventory.List()[0].Quantity)
+	assert.Len(t, exports.After(0, 10).Orders, 2)
+}
+
+func TestOrderServiceImpl_PlaceOrder_InvoiceNumbers(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+	defer store.Close()
+
+	sequence, err := invoices.Open(filepath.Join(t.TempDir(), "harbour.jsonl"), "HB-")
+	require.NoError(t, err)
+	exports := pos.NewExports()
+	ctx := tenant.NewContext(context.Background(), &tenant.Tenant{
+		ID:       "harbour",
+		Store:    store,
+		Exports:  exports,
+		Invoices: sequence,
+		Velocity: velocity.NewTracker(config.Velocity{HoldOrdersPerHour: 1, MaxOrdersPerHour: 2}),
+	})
+	ctx = velocity.NewContext(ctx, "192.0.2.10")
+	orderService := NewOrderService(store)
+	request := &models.OrderRequest{Items: []models.OrderItem{{ProductID: "prod-1", Quantity: 1}}}
+
+	order, err := orderService.PlaceOrder(ctx, request)
+	require.NoError(t, err)
+	assert.Equal(t, "HB-000001", order.InvoiceNumber)
+	assert.Equal(t, "HB-000001", exports.After(0, 10).Orders[0].InvoiceNumber)
+
+	// Held orders are numbered too
+	held, err := orderService.PlaceOrder(ctx, request)
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderStatusOnHold, held.Status)
+	assert.Equal(t, "HB-000002", held.InvoiceNumber)
+
+	// Refused orders use no number
+	_, err = orderService.PlaceOrder(ctx, request)
+	require.Error(t, err)
+	order, err = orderService.PlaceOrder(velocity.NewContext(ctx, "192.0.2.11"), request)
+	require.NoError(t, err)
+	assert.Equal(t, "HB-000003", order.InvoiceNumber)
+	assert.Empty(t, sequence.Status().Gaps)
+}
+
+func TestOrderServiceImpl_PlaceOrder_KitchenBusy(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+	defer store.Close()
+
+	restaurant := &tenant.Tenant{
+		ID:    "harbour",
+		Store: store,
+		Kitchen: kitchen.NewQueue(config.Kitchen{
+			Stations:        1,
+			DefaultPrepTime: 10 * time.Minute,
+			MaxActive:       1,
+			WhenBusy:        config.KitchenBusyReject,
+		}),
+	}
+	ctx := tenant.NewContext(context.Background(), restaurant)
+	orderService := NewOrderService(store)
+	request := &models.OrderRequest{Items: []models.OrderItem{{ProductID: "prod-1", Quantity: 1}}}
+
+	_, err = orderService.PlaceOrder(ctx, request)
+	require.NoError(t, err)
+
+	// A full kitchen refuses the order, suggesting when to retry
+	_, err = orderService.PlaceOrder(ctx, request)
+	var errResp *models.ErrorResponse
+	require.ErrorAs(t, err, &errResp)
+	assert.Equal(t, "KITCHEN_BUSY", errResp.Code)
+	assert.InDelta(t, 600, errResp.Details["retry_after"], 2)
+	assert.Contains(t, errResp.Details, "retry_at")
+	assert.Equal(t, 1, restaurant.Kitchen.Depth())
+
+	// With the extend policy the order is taken, and quoted later
+	restaurant.Kitchen = kitchen.NewQueue(config.Kitchen{
+		Stations:        1,
+		DefaultPrepTime: 10 * time.Minute,
+		MaxActive:       1,
+		WhenBusy:        config.KitchenBusyExtend,
+		BusyDelay:       5 * time.Minute,
+	})
+	first, err := orderService.PlaceOrder(ctx, request)
+	require.NoError(t, err)
+	second, err := orderService.PlaceOrder(ctx, request)
+	require.NoError(t, err)
+	assert.InDelta(t, 15*time.Minute, second.EstimatedReadyAt.Sub(*first.EstimatedReadyAt), float64(time.Second))
+}
+
+func TestOrderServiceImpl_PlaceOrder_Payment(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+	defer store.Close()
+
+	ledger := payments.NewLedger()
+	ctx := tenant.NewContext(context.Background(), &tenant.Tenant{ID: "harbour", Store: store, Payments: ledger})
+	orderService := NewOrderService(store)
+
+	// Cash is checked against the final total
+	order, err := orderService.PlaceOrder(ctx, &models.OrderRequest{
+		Items:   []models.OrderItem{{ProductID: "prod-1", Quantity: 1}},
+		Payment: &models.Payment{Method: models.PaymentCash, CashTendered: 10},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.PaymentCash, order.Payment.Method)
+	assert.InDelta(t, 0.01, order.ChangeDue, 0.0001)
+
+	_, err = orderService.PlaceOrder(ctx, &models.OrderRequest{
+		Items:   []models.OrderItem{{ProductID: "prod-1", Quantity: 2}},
+		Payment: &models.Payment{Method: models.PaymentCash, CashTendered: 10},
+	})
+	var errResp *models.ErrorResponse
+	require.ErrorAs(t, err, &errResp)
+	assert.Equal(t, "INVALID_PAYMENT", errResp.Code)
+
+	_, err = orderService.PlaceOrder(ctx, &models.OrderRequest{
+		Items:   []models.OrderItem{{ProductID: "prod-1", Quantity: 1}},
+		Payment: &models.Payment{Method: models.PaymentCard, CardLast4: "4242"},
+	})
+	require.NoError(t, err)
+
+	// Only placed orders are totalled
+	report := ledger.Report(time.Time{}, time.Time{})
+	assert.Equal(t, 2, report.Total.Orders)
+	assert.Len(t, report.Methods, 2)
+}
+
+// fakeProcessor captures payments in memory, declining or failing on demand
+type fakeProcessor struct {
+	captured map[string]bool
+	err      error
+}
+
+func (p *fakeProcessor) Capture(ctx context.Context, order *models.Order) (string, error) {
+	if p.err != nil {
+		return "", p.err
+	}
+	reference := "ch_" + order.ID
+	p.captured[reference] = true
+	return reference, nil
+}
+
+func (p *fakeProcessor) Void(ctx context.Context, reference string) error {
+	delete(p.captured, reference)
+	return nil
+}
+
+func TestOrderServiceImpl_PlaceOrder_Compensation(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+	defer store.Close()
+
+	tea := models.NewProduct("tea", "Tea", 4, "Drinks", &models.ProductImage{
+		Thumbnail: "https://example.com/t.jpg",
+		Mobile:    "https://example.com/m.jpg",
+		Tablet:    "https://example.com/t.jpg",
+		Desktop:   "https://example.com/d.jpg",
+	})
+	tea.SKU = "TEA"
+	require.NoError(t, store.AddProduct(tea))
+
+	inventory := pos.NewInventory()
+	five := 5
+	inventory.Sync([]pos.LevelUpdate{{SKU: "TEA", Quantity: &five}})
+	journal := filepath.Join(t.TempDir(), "harbour.jsonl")
+	sequence, err := invoices.Open(journal, "HB-")
+	require.NoError(t, err)
+	processor := &fakeProcessor{captured: make(map[string]bool)}
+	ledger := payments.NewLedger()
+	ctx := tenant.NewContext(context.Background(), &tenant.Tenant{
+		ID:        "harbour",
+		Store:     store,
+		Inventory: inventory,
+		Invoices:  sequence,
+		Payments:  ledger,
+		Processor: processor,
+	})
+	orderService := NewOrderService(store)
+	card := &models.OrderRequest{
+		Items:   []models.OrderItem{{ProductID: "tea", Quantity: 2}},
+		Payment: &models.Payment{Method: models.PaymentCard, CardLast4: "4242"},
+	}
+
+	order, err := orderService.PlaceOrder(ctx, card)
+	require.NoError(t, err)
+	assert.Equal(t, "ch_"+order.ID, order.PaymentReference)
+	assert.Len(t, processor.captured, 1)
+	assert.Equal(t, 3, inventory.List()[0].Quantity)
+
+	// Cash is collected on delivery
+	cash, err := orderService.PlaceOrder(ctx, &models.OrderRequest{
+		Items:   []models.OrderItem{{ProductID: "tea", Quantity: 1}},
+		Payment: &models.Payment{Method: models.PaymentCash},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, cash.PaymentReference)
+	assert.Len(t, processor.captured, 1)
+
+	// A declined payment gives the stock back
+	processor.err = payments.ErrDeclined
+	_, err = orderService.PlaceOrder(ctx, card)
+	var errResp *models.ErrorResponse
+	require.ErrorAs(t, err, &errResp)
+	assert.Equal(t, "PAYMENT_DECLINED", errResp.Code)
+	assert.Equal(t, 2, inventory.List()[0].Quantity)
+
+	processor.err = errors.New("connection refused")
+	_, err = orderService.PlaceOrder(ctx, card)
+	require.ErrorAs(t, err, &errResp)
+	assert.Equal(t, "PAYMENT_UNAVAILABLE", errResp.Code)
+	assert.Equal(t, http.StatusBadGateway, apierrors.Status(errResp.Code))
+	assert.Equal(t, 2, inventory.List()[0].Quantity)
+
+	// An order whose request timed out voids its payment and gives the
+	// stock back
+	processor.err = nil
+	timedOut, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = orderService.PlaceOrder(timedOut, card)
+	require.ErrorAs(t, err, &errResp)
+	assert.Equal(t, "TIMEOUT", errResp.Code)
+	assert.Len(t, processor.captured, 1)
+	assert.Equal(t, 2, inventory.List()[0].Quantity)
+
+	// An order that cannot be numbered voids its payment and gives the
+	// stock back
+	processor.err = nil
+	require.NoError(t, os.Remove(journal))
+	require.NoError(t, os.Mkdir(journal, 0755))
+	_, err = orderService.PlaceOrder(ctx, card)
+	require.Error(t, err)
+	assert.Len(t, processor.captured, 1)
+	assert.Equal(t, 2, inventory.List()[0].Quantity)
+	assert.Equal(t, 2, ledger.Report(time.Time{}, time.Time{}).Total.Orders)
+}
+
+// failingOrders is an order store that cannot keep orders
+type failingOrders struct {
+	orders.Store
+}
+
+func (failingOrders) Place(order *models.Order) error {
+	return errors.New("disk full")
+}
+
+func TestOrderServiceImpl_PlaceOrder_OrderHistory(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+	defer store.Close()
+
+	orderStore, err := orders.Open("")
+	require.NoError(t, err)
+	harbour := &tenant.Tenant{
+		ID:       "harbour",
+		Store:    store,
+		Orders:   orderStore,
+		Exports:  pos.NewExports(),
+		Payments: payments.NewLedger(),
+		Velocity: velocity.NewTracker(config.Velocity{HoldOrdersPerHour: 1}),
+	}
+	ctx := velocity.NewContext(tenant.NewContext(context.Background(), harbour), "192.0.2.10")
+	orderService := NewOrderService(store)
+	request := &models.OrderRequest{Items: []models.OrderItem{{ProductID: "prod-1", Quantity: 1}}}
+
+	order, err := orderService.PlaceOrder(ctx, request)
+	require.NoError(t, err)
+	history, ok := orderStore.History(order.ID)
+	require.True(t, ok)
+	assert.Equal(t, models.OrderStatusPlaced, history.Order.Status)
+
+	// Held orders are kept on hold
+	held, err := orderService.PlaceOrder(ctx, request)
+	require.NoError(t, err)
+	history, ok = orderStore.History(held.ID)
+	require.True(t, ok)
+	assert.Equal(t, models.OrderStatusOnHold, history.Order.Status)
+
+	// Orders that cannot be kept fail, and nothing else sees them
+	harbour.Orders = failingOrders{}
+	_, err = orderService.PlaceOrder(velocity.NewContext(ctx, "192.0.2.11"), request)
+	require.Error(t, err)
+	assert.Len(t, harbour.Exports.After(0, 10).Orders, 2)
+	assert.Equal(t, 2, harbour.Payments.Report(time.Time{}, time.Time{}).Total.Orders)
+}
+
+func TestOrderServiceImpl_PlaceOrder_DryRun(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+
+	store, err := data.NewIsolatedStore(context.Background(), testData.Config)
+	require.NoError(t, err)
+	defer store.Close()
+
+	coffee := models.NewProduct("coffee", "Coffee", 4, "Drinks", &models.ProductImage{
+		Thumbnail: "https://example.com/t.jpg",
+		Mobile:    "https://example.com/m.jpg",
+		Tablet:    "https://example.com/t.jpg",
+		Desktop:   "https://example.com/d.jpg",
+	})
+	coffee.SKU = "COF"
+	require.NoError(t, store.AddProduct(coffee))
+
+	inventory := pos.NewInventory()
+	two := 2
+	inventory.Sync([]pos.LevelUpdate{{SKU: "COF", Quantity: &two}})
+	orderStore, err := orders.Open("")
+	require.NoError(t, err)
+	sequence, err := invoices.Open(filepath.Join(t.TempDir(), "harbour.jsonl"), "HB-")
+	require.NoError(t, err)
+	harbour := &tenant.Tenant{
+		ID:        "harbour",
+		Store:     store,
+		Inventory: inventory,
+		Orders:    orderStore,
+		Invoices:  sequence,
+		Exports:   pos.NewExports(),
+		Payments:  payments.NewLedger(),
+		Velocity:  velocity.NewTracker(config.Velocity{MaxOrdersPerHour: 1}),
+	}
+	ctx := velocity.NewContext(tenant.NewContext(context.Background(), harbour), "192.0.2.10")
+	dryRun := NewDryRunContext(ctx)
+	orderService := NewOrderService(store)
+	request := &models.OrderRequest{Items: []models.OrderItem{{ProductID: "coffee", Quantity: 2}}, CouponCode: testutil.ValidCoupon}
+
+	// The order is priced as it would be placed, but nothing is placed
+	order, err := orderService.PlaceOrder(dryRun, request)
+	require.NoError(t, err)
+	assert.InDelta(t, 7.2, order.TotalAmount, 0.001)
+	assert.Empty(t, order.InvoiceNumber)
+	_, kept := orderStore.History(order.ID)
+	assert.False(t, kept)
+	assert.Equal(t, 2, inventory.List()[0].Quantity)
+	assert.Empty(t, harbour.Exports.After(0, 10).Orders)
+	assert.Zero(t, harbour.Payments.Report(time.Time{}, time.Time{}).Total.Orders)
+
+	// Every check still runs
+	_, err = orderService.PlaceOrder(dryRun, &models.OrderRequest{Items: []models.OrderItem{{ProductID: "coffee", Quantity: 3}}})
+	var errResp *models.ErrorResponse
+	require.ErrorAs(t, err, &errResp)
+	assert.Equal(t, "OUT_OF_STOCK", errResp.Code)
+	_, err = orderService.PlaceOrder(dryRun, &models.OrderRequest{Items: []models.OrderItem{{ProductID: "coffee", Quantity: 1}}, CouponCode: "NOTACOUPON"})
+	require.ErrorAs(t, err, &errResp)
+	assert.Equal(t, "INVALID_COUPON", errResp.Code)
+
+	// Dry runs do not count towards the client's orders
+	order, err = orderService.PlaceOrder(ctx, request)
+	require.NoError(t, err)
+	assert.Equal(t, "HB-000001", order.InvoiceNumber)
+	assert.Zero(t, inventory.List()[0].Quantity)
+}
+
 func TestOrderService_Interface(t *testing.T) {
 	// Verify OrderServiceImpl implements OrderService interface
 	var _ OrderService = (*OrderServiceImpl)(nil)
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/apierrors"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+// NormalizePayment checks the details given for the payment method of an
+// order totalling total and returns the payment as the order records it,
+// with gift card codes cut to their last four characters, along with the
+// change due on cash. Each method requires its own details and rejects the
+// details of the others.
+func NormalizePayment(payment *models.Payment, total float64) (*models.Payment, float64, error) {
+	// Which method each detail belongs to
+	details := []struct {
+		field  string
+		method string
+		given  bool
+	}{
+		{"cashTendered", models.PaymentCash, payment.CashTendered != 0},
+		{"cardLast4", models.PaymentCard, payment.CardLast4 != ""},
+		{"giftCardCode", models.PaymentGiftCard, payment.GiftCardCode != ""},
+		{"points", models.PaymentPoints, payment.Points != 0},
+	}
+	for _, detail := range details {
+		if detail.given && detail.method != payment.Method {
+			return nil, 0, invalidPayment(payment.Method, fmt.Sprintf("%s is only accepted with %s", detail.field, detail.method))
+		}
+		if !detail.given && detail.method == payment.Method && detail.method != models.PaymentCash {
+			return nil, 0, invalidPayment(payment.Method, fmt.Sprintf("%s is required with %s", detail.field, detail.method))
+		}
+	}
+
+	normalized := *payment
+	var change float64
+	switch payment.Method {
+	case models.PaymentCash:
+		// Compare in cents, as the customer hands over cash
+		if payment.CashTendered != 0 {
+			tendered, due := math.Round(payment.CashTendered*100), math.Round(total*100)
+			if tendered < due {
+				return nil, 0, invalidPayment(payment.Method, "cashTendered is less than the total").
+					AddDetail("total", due/100)
+			}
+			change = (tendered - due) / 100
+		}
+	case models.PaymentGiftCard:
+		normalized.GiftCardCode = payment.GiftCardCode[len(payment.GiftCardCode)-4:]
+	}
+	return &normalized, change, nil
+}
+
+// invalidPayment reports payment details that do not suit their method
+func invalidPayment(method, reason string) *models.ErrorResponse {
+	return apierrors.New(apierrors.InvalidPayment, "Invalid payment details").
+		AddDetail("method", method).
+		AddDetail("error", reason)
+}
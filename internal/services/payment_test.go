@@ -0,0 +1,47 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizePayment(t *testing.T) {
+	tests := []struct {
+		name    string
+		payment models.Payment
+		want    models.Payment
+		change  float64
+		invalid string
+	}{
+		{name: "cash", payment: models.Payment{Method: models.PaymentCash}, want: models.Payment{Method: models.PaymentCash}},
+		{name: "cash with change", payment: models.Payment{Method: models.PaymentCash, CashTendered: 50}, want: models.Payment{Method: models.PaymentCash, CashTendered: 50}, change: 30.01},
+		{name: "cash exact", payment: models.Payment{Method: models.PaymentCash, CashTendered: 19.99}, want: models.Payment{Method: models.PaymentCash, CashTendered: 19.99}},
+		{name: "cash short", payment: models.Payment{Method: models.PaymentCash, CashTendered: 19.98}, invalid: "cashTendered is less than the total"},
+		{name: "card", payment: models.Payment{Method: models.PaymentCard, CardLast4: "4242"}, want: models.Payment{Method: models.PaymentCard, CardLast4: "4242"}},
+		{name: "card without digits", payment: models.Payment{Method: models.PaymentCard}, invalid: "cardLast4 is required with card"},
+		{name: "gift card", payment: models.Payment{Method: models.PaymentGiftCard, GiftCardCode: "GC7Q2X9K4242"}, want: models.Payment{Method: models.PaymentGiftCard, GiftCardCode: "4242"}},
+		{name: "gift card without code", payment: models.Payment{Method: models.PaymentGiftCard}, invalid: "giftCardCode is required with gift_card"},
+		{name: "points", payment: models.Payment{Method: models.PaymentPoints, Points: 1999}, want: models.Payment{Method: models.PaymentPoints, Points: 1999}},
+		{name: "points with card digits", payment: models.Payment{Method: models.PaymentPoints, Points: 1999, CardLast4: "4242"}, invalid: "cardLast4 is only accepted with card"},
+		{name: "card with cash", payment: models.Payment{Method: models.PaymentCard, CardLast4: "4242", CashTendered: 20}, invalid: "cashTendered is only accepted with cash"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payment, change, err := NormalizePayment(&tt.payment, 19.99)
+			if tt.invalid != "" {
+				var errResp *models.ErrorResponse
+				require.ErrorAs(t, err, &errResp)
+				assert.Equal(t, "INVALID_PAYMENT", errResp.Code)
+				assert.Equal(t, tt.invalid, errResp.Details["error"])
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, *payment)
+			assert.InDelta(t, tt.change, change, 0.0001)
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"errors"
+	"math"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+// CouponDiscount is the fraction taken off the total by a valid coupon
+const CouponDiscount = 0.10
+
+// ErrInvalidTotal is returned when an order total cannot be represented,
+// e.g. because a price is negative or not finite, or the total overflows
+var ErrInvalidTotal = errors.New("invalid order total")
+
+var (
+	// ErrVariantRequired is returned when a product with variants is ordered without one
+	ErrVariantRequired = errors.New("product must be ordered by variant")
+	// ErrUnknownVariant is returned when the ordered variant does not exist
+	ErrUnknownVariant = errors.New("unknown product variant")
+)
+
+// UnitPrice returns the price of one unit of product in the given variant.
+// Products with variants are priced by variant; others by their own price.
+func UnitPrice(product *models.Product, variantID string) (float64, error) {
+	if len(product.Variants) == 0 {
+		if variantID != "" {
+			return 0, ErrUnknownVariant
+		}
+		return product.Price, nil
+	}
+	if variantID == "" {
+		return 0, ErrVariantRequired
+	}
+	variant, ok := product.Variant(variantID)
+	if !ok {
+		return 0, ErrUnknownVariant
+	}
+	return variant.Price, nil
+}
+
+// PriceChange is an order item whose client-supplied price no longer
+// matches the catalog
+type PriceChange struct {
+	ProductID    string  `json:"productId"`
+	VariantID    string  `json:"variantId,omitempty"`
+	Price        float64 `json:"price"`        // Price the client sent
+	CurrentPrice float64 `json:"currentPrice"` // Price the order would be charged
+}
+
+// PriceChanges returns the items whose prices changed since the client
+// built the order: those whose product is in repriced, and those sent with a
+// price other than the current unit price, compared in cents.
+func PriceChanges(items []models.OrderItem, prices []float64, repriced map[string]bool) []PriceChange {
+	var changes []PriceChange
+	for i, item := range items {
+		stale := repriced[item.ProductID] ||
+			(item.Price != 0 && math.Round(item.Price*100) != math.Round(prices[i]*100))
+		if !stale {
+			continue
+		}
+		changes = append(changes, PriceChange{
+			ProductID:    item.ProductID,
+			VariantID:    item.VariantID,
+			Price:        item.Price,
+			CurrentPrice: prices[i],
+		})
+	}
+	return changes
+}
+
+// CalculateTotal sums price times quantity over the items, adds the amounts
+// of the price adjustments, never going below zero, and applies the coupon
+// discount when requested
+func CalculateTotal(items []models.OrderItem, adjustments []models.PriceAdjustment, discounted bool) (float64, error) {
+	var total float64
+	for _, item := range items {
+		if item.Quantity <= 0 || item.Price < 0 || math.IsNaN(item.Price) || math.IsInf(item.Price, 0) {
+			return 0, ErrInvalidTotal
+		}
+		total += item.Price * float64(item.Quantity)
+	}
+	for _, adjustment := range adjustments {
+		total += adjustment.Amount
+	}
+	total = max(total, 0)
+
+	if discounted {
+		total = total * (1 - CouponDiscount)
+	}
+
+	// Guard against overflow to +Inf from large prices or quantities
+	if math.IsInf(total, 0) || math.IsNaN(total) {
+		return 0, ErrInvalidTotal
+	}
+	return total, nil
+}
+
+// ApplyCharges adds tax on the subtotal and the flat service fee
+func ApplyCharges(subtotal float64, charges config.Charges) (float64, error) {
+	total := subtotal*(1+charges.TaxRate) + charges.ServiceFee
+	if math.IsInf(total, 0) || math.IsNaN(total) || total < 0 {
+		return 0, ErrInvalidTotal
+	}
+	return total, nil
+}
+
+// Round rounds amount to the increment of rule, breaking ties as its mode
+// says. Amounts are left alone when rule is nil.
+func Round(amount float64, rule *models.RoundingRule) float64 {
+	if rule == nil || rule.Increment <= 0 {
+		return amount
+	}
+	// Drop the noise of binary fractions first, so 2.675 is a tie in cents
+	// rather than just below one
+	units := cleanFloat(amount / rule.Increment)
+	if rule.Mode == config.RoundingHalfEven {
+		units = math.RoundToEven(units)
+	} else {
+		units = math.Round(units)
+	}
+	return cleanFloat(units * rule.Increment)
+}
+
+// cleanFloat rounds x to nine decimal places
+func cleanFloat(x float64) float64 {
+	return math.Round(x*1e9) / 1e9
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateTotal(t *testing.T) {
+	tests := []struct {
+		name        string
+		items       []models.OrderItem
+		adjustments []models.PriceAdjustment
+		discounted  bool
+		want        float64
+		wantErr     bool
+	}{
+		{
+			name:  "single item",
+			items: []models.OrderItem{{ProductID: "p1", Quantity: 2, Price: 10.0}},
+			want:  20.0,
+		},
+		{
+			name: "multiple items with discount",
+			items: []models.OrderItem{
+				{ProductID: "p1", Quantity: 1, Price: 10.0},
+				{ProductID: "p2", Quantity: 3, Price: 5.0},
+			},
+			discounted: true,
+			want:       22.5,
+		},
+		{
+			name:        "adjusted before the discount",
+			items:       []models.OrderItem{{ProductID: "p1", Quantity: 2, Price: 10.0}},
+			adjustments: []models.PriceAdjustment{{Name: "happy-hour", Percent: -20, Amount: -4}, {Name: "late-night", Percent: 10, Amount: 2}},
+			discounted:  true,
+			want:        16.2,
+		},
+		{
+			name:        "adjusted below zero",
+			items:       []models.OrderItem{{ProductID: "p1", Quantity: 1, Price: 10.0}},
+			adjustments: []models.PriceAdjustment{{Name: "a", Percent: -60, Amount: -6}, {Name: "b", Percent: -60, Amount: -6}},
+			want:        0,
+		},
+		{
+			name:  "no items",
+			items: nil,
+			want:  0,
+		},
+		{
+			name:    "negative price",
+			items:   []models.OrderItem{{ProductID: "p1", Quantity: 1, Price: -1}},
+			wantErr: true,
+		},
+		{
+			name:    "zero quantity",
+			items:   []models.OrderItem{{ProductID: "p1", Quantity: 0, Price: 1}},
+			wantErr: true,
+		},
+		{
+			name:    "NaN price",
+			items:   []models.OrderItem{{ProductID: "p1", Quantity: 1, Price: math.NaN()}},
+			wantErr: true,
+		},
+		{
+			name:    "overflowing total",
+			items:   []models.OrderItem{{ProductID: "p1", Quantity: math.MaxInt64, Price: math.MaxFloat64}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total, err := CalculateTotal(tt.items, tt.adjustments, tt.discounted)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidTotal)
+				return
+			}
+			assert.NoError(t, err)
+			assert.InDelta(t, tt.want, total, 1e-9)
+		})
+	}
+}
+
+func TestApplyCharges(t *testing.T) {
+	tests := []struct {
+		name     string
+		subtotal float64
+		charges  config.Charges
+		want     float64
+		wantErr  bool
+	}{
+		{name: "no charges", subtotal: 20, want: 20},
+		{name: "tax", subtotal: 20, charges: config.Charges{TaxRate: 0.1}, want: 22},
+		{name: "tax and fee", subtotal: 20, charges: config.Charges{TaxRate: 0.1, ServiceFee: 1.5}, want: 23.5},
+		{name: "overflow", subtotal: math.MaxFloat64, charges: config.Charges{TaxRate: 1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total, err := ApplyCharges(tt.subtotal, tt.charges)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidTotal)
+				return
+			}
+			assert.NoError(t, err)
+			assert.InDelta(t, tt.want, total, 1e-9)
+		})
+	}
+}
+
+func TestRound(t *testing.T) {
+	cents := &models.RoundingRule{Currency: "USD", Mode: config.RoundingHalfUp, Increment: 0.01}
+	bankers := &models.RoundingRule{Currency: "EUR", Mode: config.RoundingHalfEven, Increment: 0.01}
+	rappen := &models.RoundingRule{Currency: "CHF", Mode: config.RoundingHalfUp, Increment: 0.05}
+
+	tests := []struct {
+		name   string
+		amount float64
+		rule   *models.RoundingRule
+		want   float64
+	}{
+		{name: "no rule", amount: 17.991, want: 17.991},
+		{name: "cents", amount: 17.991, rule: cents, want: 17.99},
+		{name: "half up tie", amount: 2.675, rule: cents, want: 2.68},
+		{name: "half even tie down", amount: 2.665, rule: bankers, want: 2.66},
+		{name: "half even tie up", amount: 2.675, rule: bankers, want: 2.68},
+		{name: "half even off tie", amount: 2.6651, rule: bankers, want: 2.67},
+		{name: "to 0.05 down", amount: 17.982, rule: rappen, want: 18},
+		{name: "to 0.05 up", amount: 1.024, rule: rappen, want: 1},
+		{name: "to 0.05 tie", amount: 1.025, rule: rappen, want: 1.05},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Round(tt.amount, tt.rule))
+		})
+	}
+}
+
+func TestUnitPrice(t *testing.T) {
+	plain := &models.Product{ID: "waffle", Price: 6.5}
+	sized := &models.Product{ID: "coffee", Price: 4, Variants: []models.ProductVariant{
+		{ID: "small", Name: "Small", Price: 3.5, SKU: "COF-S"},
+		{ID: "large", Name: "Large", Price: 5, SKU: "COF-L"},
+	}}
+
+	tests := []struct {
+		name      string
+		product   *models.Product
+		variantID string
+		want      float64
+		wantErr   error
+	}{
+		{name: "product without variants", product: plain, want: 6.5},
+		{name: "variant of product without variants", product: plain, variantID: "large", wantErr: ErrUnknownVariant},
+		{name: "variant price replaces product price", product: sized, variantID: "large", want: 5},
+		{name: "missing variant", product: sized, wantErr: ErrVariantRequired},
+		{name: "unknown variant", product: sized, variantID: "medium", wantErr: ErrUnknownVariant},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, err := UnitPrice(tt.product, tt.variantID)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, price)
+		})
+	}
+}
+
+func FuzzCalculateTotal(f *testing.F) {
+	f.Add(9.99, 2, 19.99, 1, false)
+	f.Add(0.0, 1, 0.0, 1, true)
+	f.Add(math.MaxFloat64, math.MaxInt64, 1.0, 1, true)
+	f.Add(-1.0, 1, 1.0, -1, false)
+
+	f.Fuzz(func(t *testing.T, price1 float64, qty1 int, price2 float64, qty2 int, discounted bool) {
+		items := []models.OrderItem{
+			{ProductID: "p1", Quantity: qty1, Price: price1},
+			{ProductID: "p2", Quantity: qty2, Price: price2},
+		}
+
+		total, err := CalculateTotal(items, nil, discounted)
+		if err != nil {
+			return
+		}
+		if math.IsNaN(total) || math.IsInf(total, 0) {
+			t.Fatalf("non-finite total %v for %+v", total, items)
+		}
+		if total < 0 {
+			t.Fatalf("negative total %v for %+v", total, items)
+		}
+		if discounted {
+			undiscounted, err := CalculateTotal(items, nil, false)
+			if err == nil && total > undiscounted {
+				t.Fatalf("discounted total %v exceeds undiscounted %v", total, undiscounted)
+			}
+		}
+	})
+}
+
+func TestPriceChanges(t *testing.T) {
+	items := []models.OrderItem{
+		{ProductID: "prod-1", Quantity: 1},
+		{ProductID: "prod-2", Quantity: 1, Price: 19.99},
+		{ProductID: "coffee", VariantID: "large", Quantity: 1, Price: 4.5},
+		{ProductID: "prod-3", Quantity: 1, Price: 0.1 + 0.2},
+	}
+	prices := []float64{9.99, 19.99, 5, 0.3}
+
+	assert.Equal(t, []PriceChange{
+		{ProductID: "coffee", VariantID: "large", Price: 4.5, CurrentPrice: 5},
+	}, PriceChanges(items, prices, nil))
+	assert.Empty(t, PriceChanges(items[:2], prices[:2], nil))
+
+	// Repriced products are stale whatever price was sent
+	assert.Equal(t, []PriceChange{
+		{ProductID: "prod-1", CurrentPrice: 9.99},
+		{ProductID: "coffee", VariantID: "large", Price: 4.5, CurrentPrice: 5},
+	}, PriceChanges(items, prices, map[string]bool{"prod-1": true}))
+}
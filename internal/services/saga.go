@@ -0,0 +1,36 @@
+package services
+
+import "log"
+
+// compensation undoes a completed step of placing an order
+type compensation struct {
+	step string
+	undo func() error
+}
+
+// saga tracks the steps of placing an order that have effects outside the
+// order, such as taking stock or capturing a payment, so that an order
+// failing at a later step can undo them and nothing is kept of it
+type saga struct {
+	completed []compensation
+}
+
+// done records that step completed, and how to undo it
+func (s *saga) done(step string, undo func() error) {
+	s.completed = append(s.completed, compensation{step: step, undo: undo})
+}
+
+// compensate undoes the completed steps, latest first, after the order
+// failed with cause, and returns cause. Every step is undone even when
+// undoing another fails; failures are logged, as they need to be undone
+// by hand.
+func (s *saga) compensate(cause error) error {
+	for i := len(s.completed) - 1; i >= 0; i-- {
+		c := s.completed[i]
+		if err := c.undo(); err != nil {
+			log.Printf("Failed to undo %s after order failed (%v): %v", c.step, cause, err)
+		}
+	}
+	s.completed = nil
+	return cause
+}
@@ -0,0 +1,34 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaga_Compensate(t *testing.T) {
+	var undone []string
+	var placing saga
+	placing.done("stock", func() error {
+		undone = append(undone, "stock")
+		return nil
+	})
+	placing.done("payment", func() error {
+		undone = append(undone, "payment")
+		return errors.New("provider unreachable")
+	})
+	placing.done("invoice", func() error {
+		undone = append(undone, "invoice")
+		return nil
+	})
+
+	// Steps are undone latest first, past failures
+	cause := errors.New("order failed")
+	assert.Equal(t, cause, placing.compensate(cause))
+	assert.Equal(t, []string{"invoice", "payment", "stock"}, undone)
+
+	// Nothing is undone twice
+	placing.compensate(cause)
+	assert.Len(t, undone, 3)
+}
@@ -0,0 +1,216 @@
+// Package slo tracks the service level objectives of route groups: how
+// much of each group's error budget is left and how fast it is burning, so
+// alerts can fire well before the budget of a path such as ordering runs
+// out.
+package slo
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+)
+
+// Burn rates alerts fire at, after the multiwindow alerts of the Google SRE
+// workbook. A burn rate of 1 spends the budget exactly over the window.
+const (
+	FastBurn = 14.4 // Spends 2% of a 30-day budget in an hour
+	SlowBurn = 6    // Spends 5% of a 30-day budget in six hours
+)
+
+// Alerts an objective raises
+const (
+	AlertFastBurn  = "fast-burn" // Burning at FastBurn over both the last hour and the last 5 minutes
+	AlertSlowBurn  = "slow-burn" // Burning at SlowBurn over both the last 6 hours and the last 30 minutes
+	AlertExhausted = "exhausted" // No budget left over the window
+)
+
+// Windows are the periods burn rates are reported over
+var Windows = []time.Duration{5 * time.Minute, 30 * time.Minute, time.Hour, 6 * time.Hour}
+
+// BurnRate is how fast an objective spent its error budget over a window
+type BurnRate struct {
+	// @example 1h0m0s
+	Window string `json:"window"`
+
+	// Requests answered in the window
+	// @example 1200
+	Requests int64 `json:"requests"`
+
+	// Requests that missed the objective
+	// @example 3
+	Bad int64 `json:"bad"`
+
+	// Share of bad requests over the share the objective allows; above 1
+	// the budget runs out before the end of the SLO window
+	// @example 2.5
+	Rate float64 `json:"rate"`
+}
+
+// Objective is the state of an availability or latency objective
+type Objective struct {
+	// Share of requests to answer well
+	// @example 0.999
+	Target float64 `json:"target"`
+
+	// Time requests are to be answered within, for latency objectives
+	// @example 500ms
+	Threshold string `json:"threshold,omitempty"`
+
+	// Requests answered over the SLO window
+	// @example 52000
+	Requests int64 `json:"requests"`
+
+	// Requests that missed the objective over the SLO window
+	// @example 12
+	Bad int64 `json:"bad"`
+
+	// Share of the error budget left over the SLO window; negative once
+	// overspent
+	// @example 0.77
+	BudgetRemaining float64 `json:"budgetRemaining"`
+
+	// Burn rates over the last 5 minutes, 30 minutes, hour and 6 hours
+	BurnRates []BurnRate `json:"burnRates"`
+
+	// fast-burn, slow-burn or exhausted when the objective calls for an
+	// alert; empty otherwise
+	// @example fast-burn
+	Alert string `json:"alert,omitempty"`
+}
+
+// Status is the state of a route group's objectives
+type Status struct {
+	// Route group the objectives cover
+	// @example ordering
+	Group string `json:"group"`
+
+	// Period the error budget is spent over
+	// @example 720h0m0s
+	Window string `json:"window"`
+
+	// Requests answered without a 5xx status
+	Availability Objective `json:"availability"`
+
+	// Requests answered within the latency threshold; absent when the
+	// group has no latency objective
+	Latency *Objective `json:"latency,omitempty"`
+}
+
+// bucket counts the requests answered in a minute
+type bucket struct {
+	minute int64 // Minutes since the Unix epoch
+	total  int64
+	errors int64 // Answered with a 5xx status
+	slow   int64 // Answered after the latency threshold
+}
+
+// Tracker counts the requests of a route group by minute over its SLO
+// window. It is safe for concurrent use.
+type Tracker struct {
+	objective config.SLO
+	now       func() time.Time
+
+	mu      sync.Mutex
+	buckets []bucket // Ring of the minutes of the window
+}
+
+// NewTracker returns a tracker of objective
+func NewTracker(objective config.SLO) *Tracker {
+	return &Tracker{
+		objective: objective,
+		now:       time.Now,
+		buckets:   make([]bucket, max(int(objective.Window/time.Minute), 1)),
+	}
+}
+
+// Group returns the route group the tracker's objective covers
+func (t *Tracker) Group() string {
+	return t.objective.Group
+}
+
+// Record counts a request answered with status after latency
+func (t *Tracker) Record(status int, latency time.Duration) {
+	minute := t.now().Unix() / 60
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b := &t.buckets[minute%int64(len(t.buckets))]
+	if b.minute != minute {
+		*b = bucket{minute: minute}
+	}
+	b.total++
+	if status >= http.StatusInternalServerError {
+		b.errors++
+	}
+	if t.objective.Latency > 0 && latency > t.objective.Latency {
+		b.slow++
+	}
+}
+
+// Status returns the state of the tracker's objectives
+func (t *Tracker) Status() Status {
+	now := t.now().Unix() / 60
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	status := Status{
+		Group:        t.objective.Group,
+		Window:       t.objective.Window.String(),
+		Availability: t.objectiveStatus(now, t.objective.Availability, func(b bucket) int64 { return b.errors }),
+	}
+	if t.objective.Latency > 0 {
+		latency := t.objectiveStatus(now, t.objective.LatencyTarget, func(b bucket) int64 { return b.slow })
+		latency.Threshold = t.objective.Latency.String()
+		status.Latency = &latency
+	}
+	return status
+}
+
+// objectiveStatus returns the state of an objective of target, counting
+// the requests bad returns of each bucket as bad, as of minute now
+func (t *Tracker) objectiveStatus(now int64, target float64, bad func(bucket) int64) Objective {
+	allowed := 1 - target
+	objective := Objective{Target: target, BudgetRemaining: 1}
+
+	objective.Requests, objective.Bad = t.sum(now, int64(len(t.buckets)), bad)
+	if objective.Requests > 0 {
+		objective.BudgetRemaining = 1 - float64(objective.Bad)/(float64(objective.Requests)*allowed)
+	}
+
+	rates := make(map[time.Duration]float64, len(Windows))
+	for _, window := range Windows {
+		rate := BurnRate{Window: window.String()}
+		rate.Requests, rate.Bad = t.sum(now, int64(window/time.Minute), bad)
+		if rate.Requests > 0 {
+			rate.Rate = float64(rate.Bad) / float64(rate.Requests) / allowed
+		}
+		rates[window] = rate.Rate
+		objective.BurnRates = append(objective.BurnRates, rate)
+	}
+
+	switch {
+	case rates[time.Hour] >= FastBurn && rates[5*time.Minute] >= FastBurn:
+		objective.Alert = AlertFastBurn
+	case rates[6*time.Hour] >= SlowBurn && rates[30*time.Minute] >= SlowBurn:
+		objective.Alert = AlertSlowBurn
+	case objective.BudgetRemaining <= 0:
+		objective.Alert = AlertExhausted
+	}
+	return objective
+}
+
+// sum returns the requests of the last minutes before and including now,
+// and how many of them bad counts. Minutes before the window are gone.
+func (t *Tracker) sum(now, minutes int64, bad func(bucket) int64) (int64, int64) {
+	minutes = min(minutes, int64(len(t.buckets)))
+	var total, badTotal int64
+	for _, b := range t.buckets {
+		if b.minute > now-minutes && b.minute <= now {
+			total += b.total
+			badTotal += bad(b)
+		}
+	}
+	return total, badTotal
+}
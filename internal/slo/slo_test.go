@@ -0,0 +1,116 @@
+package slo
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testTracker(now *time.Time) *Tracker {
+	tracker := NewTracker(config.SLO{
+		Group:         "ordering",
+		Availability:  0.99,
+		Latency:       500 * time.Millisecond,
+		LatencyTarget: 0.9,
+		Window:        24 * time.Hour,
+	})
+	tracker.now = func() time.Time { return *now }
+	return tracker
+}
+
+func TestTracker(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tracker := testTracker(&now)
+
+	// 100 requests two hours ago, 2 of them failing
+	now = now.Add(-2 * time.Hour)
+	for i := range 100 {
+		status := http.StatusCreated
+		if i < 2 {
+			status = http.StatusServiceUnavailable
+		}
+		tracker.Record(status, 100*time.Millisecond)
+	}
+	// 10 requests now, 1 of them slow and 1 refused with a 4xx status
+	now = now.Add(2 * time.Hour)
+	for i := range 10 {
+		latency := 100 * time.Millisecond
+		if i == 0 {
+			latency = time.Second
+		}
+		status := http.StatusOK
+		if i == 1 {
+			status = http.StatusUnprocessableEntity
+		}
+		tracker.Record(status, latency)
+	}
+
+	status := tracker.Status()
+	assert.Equal(t, "ordering", status.Group)
+	assert.Equal(t, "24h0m0s", status.Window)
+
+	availability := status.Availability
+	assert.Equal(t, 0.99, availability.Target)
+	assert.Equal(t, int64(110), availability.Requests)
+	assert.Equal(t, int64(2), availability.Bad)
+	assert.InDelta(t, 1-2/1.1, availability.BudgetRemaining, 0.0001)
+	require.Len(t, availability.BurnRates, len(Windows))
+	assert.Equal(t, BurnRate{Window: "5m0s", Requests: 10}, availability.BurnRates[0])
+	assert.Equal(t, BurnRate{Window: "6h0m0s", Requests: 110, Bad: 2, Rate: 1.8182}, roundRate(availability.BurnRates[3]))
+	assert.Equal(t, AlertExhausted, availability.Alert)
+
+	require.NotNil(t, status.Latency)
+	assert.Equal(t, "500ms", status.Latency.Threshold)
+	assert.Equal(t, int64(1), status.Latency.Bad)
+	assert.Equal(t, BurnRate{Window: "5m0s", Requests: 10, Bad: 1, Rate: 1}, roundRate(status.Latency.BurnRates[0]))
+	assert.Empty(t, status.Latency.Alert)
+}
+
+func TestTracker_Alerts(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tracker := testTracker(&now)
+
+	// Failing a fifth of requests burns the budget 20 times too fast
+	for i := range 50 {
+		status := http.StatusOK
+		if i%5 == 0 {
+			status = http.StatusInternalServerError
+		}
+		tracker.Record(status, time.Millisecond)
+	}
+	assert.Equal(t, AlertFastBurn, tracker.Status().Availability.Alert)
+
+	// Once the last 5 minutes are quiet, the last 6 hours and 30 minutes
+	// are still burning
+	now = now.Add(20 * time.Minute)
+	for range 10 {
+		tracker.Record(http.StatusOK, time.Millisecond)
+	}
+	assert.Equal(t, AlertSlowBurn, tracker.Status().Availability.Alert)
+}
+
+func TestTracker_Expires(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tracker := testTracker(&now)
+	tracker.Record(http.StatusInternalServerError, time.Millisecond)
+
+	// A day later the failure has left the window
+	now = now.Add(24 * time.Hour)
+	tracker.Record(http.StatusOK, time.Millisecond)
+
+	status := tracker.Status()
+	assert.Equal(t, int64(1), status.Availability.Requests)
+	assert.Zero(t, status.Availability.Bad)
+	assert.Equal(t, 1.0, status.Availability.BudgetRemaining)
+	assert.Empty(t, status.Availability.Alert)
+}
+
+// roundRate rounds a burn rate to be compared
+func roundRate(rate BurnRate) BurnRate {
+	rate.Rate = float64(int(rate.Rate*10000+0.5)) / 10000
+	return rate
+}
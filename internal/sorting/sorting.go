@@ -0,0 +1,131 @@
+// Package sorting orders listings by the fields clients ask for, such as
+// ?sort=price,-created_at. Each listing names the fields it can be sorted
+// by, so clients cannot sort by fields the store keeps no order for, and
+// keeps each order it is listed in as an index, rebuilt only when the items
+// change rather than on every request.
+package sorting
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrInvalid is returned for sorts that name unknown fields or name a field
+// twice
+var ErrInvalid = errors.New("invalid sort")
+
+// maxIndexes bounds how many orders of a listing are kept at once; the
+// least recently built are dropped first
+const maxIndexes = 16
+
+// Key is a field a listing is sorted by
+type Key struct {
+	Field string
+	Desc  bool // Largest first
+}
+
+// Order is the fields a listing is sorted by, most significant first
+type Order []Key
+
+// String returns the order as a sort parameter, e.g. price,-created_at
+func (o Order) String() string {
+	parts := make([]string, len(o))
+	for i, key := range o {
+		parts[i] = key.Field
+		if key.Desc {
+			parts[i] = "-" + key.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// Fields compares items by each field a listing can be sorted by, by name
+type Fields[T any] map[string]func(a, b T) int
+
+// Names returns the names of the fields, sorted
+func (f Fields[T]) Names() []string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Parse reads a comma-separated list of fields, each prefixed with - to sort
+// largest first
+func (f Fields[T]) Parse(raw string) (Order, error) {
+	var order Order
+	for _, part := range strings.Split(raw, ",") {
+		key := Key{Field: strings.TrimSpace(part)}
+		if field, ok := strings.CutPrefix(key.Field, "-"); ok {
+			key.Field, key.Desc = field, true
+		}
+		if _, ok := f[key.Field]; !ok {
+			return nil, fmt.Errorf("%w: %q is not one of %s", ErrInvalid, key.Field, strings.Join(f.Names(), ", "))
+		}
+		if slices.ContainsFunc(order, func(k Key) bool { return k.Field == key.Field }) {
+			return nil, fmt.Errorf("%w: %q is given twice", ErrInvalid, key.Field)
+		}
+		order = append(order, key)
+	}
+	return order, nil
+}
+
+// Compare returns a comparison of items in order, breaking ties with
+// tiebreak so every item has one place. Fields of the order must be fields
+// of f.
+func (f Fields[T]) Compare(order Order, tiebreak func(a, b T) int) func(a, b T) int {
+	return func(a, b T) int {
+		for _, key := range order {
+			c := f[key.Field](a, b)
+			if key.Desc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return tiebreak(a, b)
+	}
+}
+
+// Index keeps the items of a listing sorted in each order they were listed
+// in, for one version of the items. The zero Index is empty and ready to
+// use.
+type Index[T any] struct {
+	mu      sync.Mutex
+	version uint64
+	lists   map[string][]T // By order
+	built   []string       // Orders, least recently built first
+}
+
+// Sorted returns the items of version sorted by compare, which sorts them
+// in order. The items are loaded and sorted only when they are not already
+// kept in order for version. The list is shared and must not be changed.
+func (ix *Index[T]) Sorted(version uint64, order Order, compare func(a, b T) int, load func() []T) []T {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	if ix.lists == nil || ix.version != version {
+		ix.version, ix.lists, ix.built = version, make(map[string][]T), nil
+	}
+	name := order.String()
+	if list, ok := ix.lists[name]; ok {
+		return list
+	}
+
+	list := load()
+	slices.SortFunc(list, compare)
+	if len(ix.built) == maxIndexes {
+		delete(ix.lists, ix.built[0])
+		ix.built = ix.built[1:]
+	}
+	ix.lists[name] = list
+	ix.built = append(ix.built, name)
+	return list
+}
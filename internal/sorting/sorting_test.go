@@ -0,0 +1,67 @@
+package sorting
+
+import (
+	"cmp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type dish struct {
+	name  string
+	price int
+}
+
+var dishFields = Fields[dish]{
+	"name":  func(a, b dish) int { return cmp.Compare(a.name, b.name) },
+	"price": func(a, b dish) int { return cmp.Compare(a.price, b.price) },
+}
+
+func byName(a, b dish) int { return cmp.Compare(a.name, b.name) }
+
+func TestParse(t *testing.T) {
+	order, err := dishFields.Parse("price, -name")
+	require.NoError(t, err)
+	assert.Equal(t, Order{{Field: "price"}, {Field: "name", Desc: true}}, order)
+	assert.Equal(t, "price,-name", order.String())
+
+	for _, raw := range []string{"", "colour", "price,", "price,-price", "--price"} {
+		_, err := dishFields.Parse(raw)
+		assert.ErrorIs(t, err, ErrInvalid, raw)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	dishes := []dish{{"soup", 8}, {"pie", 12}, {"salad", 8}, {"tart", 12}}
+	order, err := dishFields.Parse("-price")
+	require.NoError(t, err)
+
+	var ix Index[dish]
+	sorted := ix.Sorted(1, order, dishFields.Compare(order, byName), func() []dish { return dishes })
+	assert.Equal(t, []dish{{"pie", 12}, {"tart", 12}, {"salad", 8}, {"soup", 8}}, sorted)
+}
+
+func TestIndex(t *testing.T) {
+	loads := 0
+	load := func() []dish {
+		loads++
+		return []dish{{"soup", 8}, {"pie", 12}}
+	}
+	byPrice, err := dishFields.Parse("price")
+	require.NoError(t, err)
+	byNameDesc, err := dishFields.Parse("-name")
+	require.NoError(t, err)
+
+	var ix Index[dish]
+	ix.Sorted(1, byPrice, dishFields.Compare(byPrice, byName), load)
+	ix.Sorted(1, byPrice, dishFields.Compare(byPrice, byName), load)
+	assert.Equal(t, 1, loads, "each order is sorted once per version")
+
+	sorted := ix.Sorted(1, byNameDesc, dishFields.Compare(byNameDesc, byName), load)
+	assert.Equal(t, []dish{{"soup", 8}, {"pie", 12}}, sorted)
+	assert.Equal(t, 2, loads)
+
+	ix.Sorted(2, byPrice, dishFields.Compare(byPrice, byName), load)
+	assert.Equal(t, 3, loads, "a new version is sorted again")
+}
@@ -0,0 +1,263 @@
+// Package split shares the bill of an order among the people it was for,
+// evenly or by the items each of them had.
+package split
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+// Split modes
+const (
+	ModeEven  = "even"  // Everyone pays the same
+	ModeItems = "items" // Everyone pays for the items they had
+)
+
+// MaxPeople is the most people a bill is split among
+const MaxPeople = 50
+
+var (
+	// ErrInvalid is returned when a split request does not fit the order
+	ErrInvalid = errors.New("invalid split")
+	// ErrNoPaymentLinks is returned when payment links are asked for and no
+	// payment page is configured
+	ErrNoPaymentLinks = errors.New("payment links are not configured")
+)
+
+// Request represents the request body for splitting an order's bill
+type Request struct {
+	// How to split the bill: even or items
+	// @required
+	// @example items
+	Mode string `json:"mode" validate:"required,oneof=even items"`
+
+	// How many people to split the bill among evenly; even only
+	// @minimum 2
+	// @example 3
+	People int `json:"people,omitempty" validate:"omitempty,min=2,max=50"`
+
+	// The people to split the bill among by item; items only. Items no one
+	// claims are shared evenly.
+	Participants []Participant `json:"participants,omitempty" validate:"omitempty,max=50,dive"`
+
+	// Whether to link each person to a payment page for their part
+	// @example true
+	PaymentLinks bool `json:"paymentLinks,omitempty"`
+}
+
+// Participant is a person the bill is split among by item
+type Participant struct {
+	// The person's name
+	// @required
+	// @example Sam
+	Name string `json:"name" validate:"required,max=60"`
+
+	// The items the person had
+	Items []Claim `json:"items,omitempty" validate:"dive"`
+}
+
+// Claim is part of an order line a person had
+type Claim struct {
+	// The index of the line in the order's items, from 0
+	// @minimum 0
+	// @example 0
+	Line int `json:"line" validate:"gte=0"`
+
+	// How many of the line's quantity the person had
+	// @required
+	// @minimum 1
+	// @example 1
+	Quantity int `json:"quantity" validate:"required,gt=0"`
+}
+
+// Share is what one person pays
+type Share struct {
+	// The person's name
+	// @example Sam
+	Name string `json:"name"`
+
+	// The items the person claimed, at the prices the order charged
+	Items []models.OrderItem `json:"items,omitempty"`
+
+	// What the person pays, including their part of any adjustments,
+	// discount, tax and fees
+	// @example 14.67
+	Amount float64 `json:"amount"`
+
+	// The payment page for the person's part, when asked for
+	// @example https://pay.example.com/split?amount=14.67&order=order-0000-0000-0000-0000&share=1
+	PaymentURL string `json:"payment_url,omitempty"`
+}
+
+// Split is an order's bill shared among people. The amounts add up to the
+// order total to the cent.
+type Split struct {
+	// The order split
+	// @example order-0000-0000-0000-0000
+	OrderID string `json:"order_id"`
+
+	// How the bill was split: even or items
+	// @example items
+	Mode string `json:"mode"`
+
+	// The order total split
+	// @example 44
+	Total float64 `json:"total"`
+
+	// What each person pays, in the order they were given
+	Shares []Share `json:"shares"`
+}
+
+// New splits the bill of order as req asks. Payment links go to
+// paymentLinkURL, with the order, the person's number from 1 and the amount
+// in the query.
+func New(order *models.Order, req *Request, paymentLinkURL string) (*Split, error) {
+	if req.PaymentLinks && paymentLinkURL == "" {
+		return nil, ErrNoPaymentLinks
+	}
+
+	var shares []Share
+	var weights []float64
+	switch req.Mode {
+	case ModeEven:
+		if req.People < 2 {
+			return nil, fmt.Errorf("%w: people must be from 2 to %d", ErrInvalid, MaxPeople)
+		}
+		shares = make([]Share, req.People)
+		weights = make([]float64, req.People)
+		for i := range shares {
+			shares[i].Name = "Person " + strconv.Itoa(i+1)
+			weights[i] = 1
+		}
+	case ModeItems:
+		var err error
+		shares, weights, err = byItems(order, req.Participants)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown mode %s", ErrInvalid, req.Mode)
+	}
+
+	amounts := allocate(int64(math.Round(order.TotalAmount*100)), weights)
+	for i := range shares {
+		shares[i].Amount = float64(amounts[i]) / 100
+		if req.PaymentLinks {
+			shares[i].PaymentURL = paymentURL(paymentLinkURL, order.ID, i+1, shares[i].Amount)
+		}
+	}
+
+	return &Split{
+		OrderID: order.ID,
+		Mode:    req.Mode,
+		Total:   order.TotalAmount,
+		Shares:  shares,
+	}, nil
+}
+
+// byItems returns the share of each participant with the items they
+// claimed, weighted by what those items cost. Quantities no one claimed are
+// shared evenly.
+func byItems(order *models.Order, participants []Participant) ([]Share, []float64, error) {
+	if len(participants) < 2 || len(participants) > MaxPeople {
+		return nil, nil, fmt.Errorf("%w: participants must number from 2 to %d", ErrInvalid, MaxPeople)
+	}
+
+	unclaimed := make([]int, len(order.Items))
+	for i, item := range order.Items {
+		unclaimed[i] = item.Quantity
+	}
+
+	shares := make([]Share, len(participants))
+	weights := make([]float64, len(participants))
+	for i, p := range participants {
+		shares[i].Name = p.Name
+		for _, claim := range p.Items {
+			if claim.Line < 0 || claim.Line >= len(order.Items) {
+				return nil, nil, fmt.Errorf("%w: the order has no line %d", ErrInvalid, claim.Line)
+			}
+			if claim.Quantity > unclaimed[claim.Line] {
+				return nil, nil, fmt.Errorf("%w: more of line %d are claimed than were ordered", ErrInvalid, claim.Line)
+			}
+			unclaimed[claim.Line] -= claim.Quantity
+
+			item := order.Items[claim.Line]
+			item.Quantity = claim.Quantity
+			shares[i].Items = append(shares[i].Items, item)
+			weights[i] += item.Price * float64(claim.Quantity)
+		}
+	}
+
+	var rest float64
+	for i, quantity := range unclaimed {
+		rest += order.Items[i].Price * float64(quantity)
+	}
+	for i := range weights {
+		weights[i] += rest / float64(len(weights))
+	}
+	return shares, weights, nil
+}
+
+// allocate divides cents in proportion to weights, giving the cents left by
+// rounding down to the largest remainders, and the first of equal ones.
+// Without any weight, cents are divided evenly.
+func allocate(cents int64, weights []float64) []int64 {
+	var sum float64
+	for _, w := range weights {
+		sum += w
+	}
+	if sum <= 0 {
+		weights = slices.Repeat([]float64{1}, len(weights))
+		sum = float64(len(weights))
+	}
+
+	amounts := make([]int64, len(weights))
+	remainders := make([]float64, len(weights))
+	left := cents
+	for i, w := range weights {
+		exact := float64(cents) * w / sum
+		amounts[i] = int64(math.Floor(exact))
+		remainders[i] = exact - float64(amounts[i])
+		left -= amounts[i]
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		switch {
+		case remainders[a] > remainders[b]:
+			return -1
+		case remainders[a] < remainders[b]:
+			return 1
+		}
+		return 0
+	})
+	for i := 0; left > 0; i++ {
+		amounts[order[i%len(order)]]++
+		left--
+	}
+	return amounts
+}
+
+// paymentURL links to the payment page at base for a person's part of an
+// order, keeping any query base already has
+func paymentURL(base, orderID string, share int, amount float64) string {
+	u, err := url.Parse(base)
+	if err != nil {
+		return ""
+	}
+	q := u.Query()
+	q.Set("order", orderID)
+	q.Set("share", strconv.Itoa(share))
+	q.Set("amount", strconv.FormatFloat(amount, 'f', 2, 64))
+	u.RawQuery = q.Encode()
+	return u.String()
+}
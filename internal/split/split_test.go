@@ -0,0 +1,108 @@
+package split
+
+import (
+	"testing"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	order := &models.Order{
+		ID: "order-1",
+		Items: []models.OrderItem{
+			{ProductID: "burger", Quantity: 2, Price: 15},
+			{ProductID: "chips", Quantity: 1, Price: 6},
+			{ProductID: "soda", Quantity: 1, Price: 4},
+		},
+		TotalAmount: 44,
+	}
+
+	tests := []struct {
+		name    string
+		req     Request
+		want    []float64
+		wantErr error
+	}{
+		{name: "even", req: Request{Mode: ModeEven, People: 3}, want: []float64{14.67, 14.67, 14.66}},
+		{
+			name: "by items, sharing what no one claimed",
+			req: Request{Mode: ModeItems, Participants: []Participant{
+				{Name: "Jane", Items: []Claim{{Line: 0, Quantity: 1}, {Line: 2, Quantity: 1}}},
+				{Name: "Sam", Items: []Claim{{Line: 0, Quantity: 1}}},
+			}},
+			// Jane had 19 and Sam 15 of the 40 subtotal, sharing the chips
+			want: []float64{24.2, 19.8},
+		},
+		{name: "even among one", req: Request{Mode: ModeEven, People: 1}, wantErr: ErrInvalid},
+		{name: "by items among one", req: Request{Mode: ModeItems, Participants: []Participant{{Name: "Jane"}}}, wantErr: ErrInvalid},
+		{
+			name: "unknown line",
+			req: Request{Mode: ModeItems, Participants: []Participant{
+				{Name: "Jane", Items: []Claim{{Line: 3, Quantity: 1}}}, {Name: "Sam"},
+			}},
+			wantErr: ErrInvalid,
+		},
+		{
+			name: "line claimed more than ordered",
+			req: Request{Mode: ModeItems, Participants: []Participant{
+				{Name: "Jane", Items: []Claim{{Line: 1, Quantity: 1}}}, {Name: "Sam", Items: []Claim{{Line: 1, Quantity: 1}}},
+			}},
+			wantErr: ErrInvalid,
+		},
+		{name: "payment links without a payment page", req: Request{Mode: ModeEven, People: 2, PaymentLinks: true}, wantErr: ErrNoPaymentLinks},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(order, &tt.req, "")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "order-1", s.OrderID)
+			assert.Equal(t, 44.0, s.Total)
+			var amounts []float64
+			for _, share := range s.Shares {
+				amounts = append(amounts, share.Amount)
+			}
+			assert.Equal(t, tt.want, amounts)
+		})
+	}
+}
+
+func TestNew_ClaimedItems(t *testing.T) {
+	order := &models.Order{
+		ID:          "order-1",
+		Items:       []models.OrderItem{{ProductID: "burger", Quantity: 2, Price: 15, Notes: "no pickles"}},
+		TotalAmount: 30,
+	}
+	s, err := New(order, &Request{Mode: ModeItems, Participants: []Participant{
+		{Name: "Jane", Items: []Claim{{Line: 0, Quantity: 2}}},
+		{Name: "Sam"},
+	}}, "")
+	require.NoError(t, err)
+
+	assert.Equal(t, []models.OrderItem{{ProductID: "burger", Quantity: 2, Price: 15, Notes: "no pickles"}}, s.Shares[0].Items)
+	assert.Equal(t, 30.0, s.Shares[0].Amount)
+	assert.Empty(t, s.Shares[1].Items)
+	assert.Equal(t, 0.0, s.Shares[1].Amount)
+}
+
+func TestNew_PaymentLinks(t *testing.T) {
+	order := &models.Order{ID: "order-1", TotalAmount: 10}
+	s, err := New(order, &Request{Mode: ModeEven, People: 2, PaymentLinks: true}, "https://pay.example.com/split?restaurant=default")
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://pay.example.com/split?amount=5.00&order=order-1&restaurant=default&share=1", s.Shares[0].PaymentURL)
+	assert.Equal(t, "https://pay.example.com/split?amount=5.00&order=order-1&restaurant=default&share=2", s.Shares[1].PaymentURL)
+}
+
+func TestAllocate(t *testing.T) {
+	assert.Equal(t, []int64{34, 33, 33}, allocate(100, []float64{1, 1, 1}))
+	assert.Equal(t, []int64{1, 2}, allocate(3, []float64{1, 2}))
+	assert.Equal(t, []int64{2, 1}, allocate(3, []float64{0, 0}))
+	assert.Equal(t, []int64{0, 0}, allocate(0, []float64{1, 1}))
+}
@@ -0,0 +1,321 @@
+// Package startup checks, as the server starts, what it loaded and what it
+// depends on, and sums the results up in one report. The report is logged
+// as a single line and served to admins, so a failed deploy can be
+// diagnosed from one log line rather than pieced together from many.
+package startup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/challenge"
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/data"
+	"github.com/ravibandhu/oolio-food-ordering/internal/tenant"
+)
+
+// Check statuses
+const (
+	StatusOK     = "ok"
+	StatusFailed = "failed"
+)
+
+// dialTimeout bounds how long each dependency is given to accept a
+// connection
+const dialTimeout = 2 * time.Second
+
+// defaultPorts are the ports dependencies listen on when their URL names
+// none, by scheme
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"nats":  "4222",
+}
+
+// Check is the outcome of one self-check
+type Check struct {
+	// What was checked, e.g. catalog:default, bind or dependency:nats
+	// @example dependency:nats
+	Name string `json:"name"`
+
+	// ok or failed
+	// @example ok
+	Status string `json:"status"`
+
+	// What the check found
+	// @example nats.internal:4222
+	Detail string `json:"detail,omitempty"`
+
+	// Why the check failed
+	// @example dial tcp: connection refused
+	Error string `json:"error,omitempty"`
+
+	// How long the check took, in milliseconds
+	// @example 3
+	DurationMS int64 `json:"durationMs"`
+}
+
+// Catalog describes what a restaurant's store loaded
+type Catalog struct {
+	// @example default
+	Tenant string `json:"tenant"`
+
+	data.Stats
+}
+
+// Summary describes the configuration the server started with. It names
+// which features are on and how they are tuned, never secrets.
+type Summary struct {
+	// @example :8080
+	Port string `json:"port"`
+
+	// Restaurants served, including the default one
+	// @example 1
+	Tenants int `json:"tenants"`
+
+	// @example 15s
+	ReadTimeout string `json:"readTimeout"`
+
+	// @example 15s
+	WriteTimeout string `json:"writeTimeout"`
+
+	// @example 2s
+	CatalogTimeout string `json:"catalogTimeout"`
+
+	// @example 5s
+	OrderTimeout string `json:"orderTimeout"`
+
+	// @example 5s
+	ProductCacheTTL string `json:"productCacheTtl"`
+
+	StrictJSON bool `json:"strictJson"`
+	Links      bool `json:"links"`
+	Envelope   bool `json:"envelope"`
+
+	// Whether orders are kept in an event log
+	OrderEvents bool `json:"orderEvents"`
+
+	// Whether product updates are consumed from the upstream catalog service
+	CatalogSync bool `json:"catalogSync"`
+
+	// Bot challenge orders must pass; empty when orders are not challenged
+	// @example turnstile
+	Challenge string `json:"challenge,omitempty"`
+
+	// How emails to customers are sent: smtp, or log when no server is set
+	// @example smtp
+	Email string `json:"email"`
+
+	// How image URLs are signed; empty when they are served unsigned
+	// @example cloudfront
+	ImageSigning string `json:"imageSigning,omitempty"`
+}
+
+// Report sums up the self-checks run as the server started
+type Report struct {
+	// When the server started
+	// @example 2024-01-01T00:00:00Z
+	StartedAt time.Time `json:"startedAt"`
+
+	// Whether every check passed
+	// @example true
+	OK bool `json:"ok"`
+
+	Config Summary `json:"config"`
+
+	// What each restaurant's store loaded, by tenant ID
+	Catalogs []Catalog `json:"catalogs"`
+
+	// Every check, in the order they were run
+	Checks []Check `json:"checks"`
+}
+
+// New starts a report on the configuration and the catalog and coupons
+// each tenant loaded. A tenant with no products on its menu fails its
+// check, as an empty menu is almost always a catalog that did not parse,
+// and so does one that skipped invalid products or has a coupon file that
+// was not read in full.
+func New(cfg *config.Config, tenants *tenant.Registry, now time.Time) *Report {
+	all := tenants.All()
+	r := &Report{StartedAt: now.UTC(), OK: true, Config: summarize(cfg, len(all))}
+	for _, t := range all {
+		stats := t.Store.Stats()
+		r.Catalogs = append(r.Catalogs, Catalog{Tenant: t.ID, Stats: stats})
+		r.Run("catalog:"+t.ID, func() (string, error) {
+			if stats.Products == 0 {
+				return "", errors.New("no products on the menu")
+			}
+			detail := fmt.Sprintf("%d products, %d deleted, %d coupons", stats.Products, stats.Deleted, stats.Coupons)
+			if stats.Invalid > 0 {
+				return detail, fmt.Errorf("%d invalid products skipped, see /admin/products/errors", stats.Invalid)
+			}
+			return detail, nil
+		})
+		r.Run("coupons:"+t.ID, func() (string, error) {
+			return checkCouponFiles(stats.CouponFiles)
+		})
+	}
+	return r
+}
+
+// Run runs a check, recording what fn found or why it failed
+func (r *Report) Run(name string, fn func() (string, error)) {
+	start := time.Now()
+	detail, err := fn()
+	r.add(name, detail, err, time.Since(start))
+}
+
+// add records the outcome of a check
+func (r *Report) add(name, detail string, err error, took time.Duration) {
+	check := Check{Name: name, Status: StatusOK, Detail: detail, DurationMS: took.Milliseconds()}
+	if err != nil {
+		check.Status, check.Error = StatusFailed, err.Error()
+		r.OK = false
+	}
+	r.Checks = append(r.Checks, check)
+}
+
+// checkCouponFiles sums up what was read from each coupon file, failing
+// when any was not read in full or does not match its manifest
+func checkCouponFiles(files []data.CouponFile) (string, error) {
+	verified := 0
+	var problems []string
+	for _, file := range files {
+		if file.Verified {
+			verified++
+		}
+		if file.Error != "" {
+			problems = append(problems, file.Name+": "+file.Error)
+		}
+	}
+	detail := fmt.Sprintf("%d files, %d verified", len(files), verified)
+	if len(problems) > 0 {
+		return detail, errors.New(strings.Join(problems, "; "))
+	}
+	return detail, nil
+}
+
+// PingDependencies checks that every service the configuration names, such
+// as the NATS server, SMTP server, identity providers and challenge
+// provider, accepts connections. The services are dialled at once, so a
+// slow one holds the start up by dialTimeout at most.
+func (r *Report) PingDependencies(ctx context.Context, cfg *config.Config) {
+	deps := dependencies(cfg)
+	errs := make([]error, len(deps))
+	took := make([]time.Duration, len(deps))
+	var wg sync.WaitGroup
+	for i, dep := range deps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			errs[i] = dial(ctx, dep.addr)
+			took[i] = time.Since(start)
+		}()
+	}
+	wg.Wait()
+	for i, dep := range deps {
+		r.add("dependency:"+dep.name, dep.addr, errs[i], took[i])
+	}
+}
+
+// String returns the report as one line of JSON
+func (r *Report) String() string {
+	raw, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Sprintf(`{"error":%q}`, err.Error())
+	}
+	return string(raw)
+}
+
+// dependency is a service the server connects to
+type dependency struct {
+	name string
+	addr string // host:port
+}
+
+// dependencies lists the services cfg names. A URL that does not parse is
+// listed as it is, so its check fails saying why.
+func dependencies(cfg *config.Config) []dependency {
+	var deps []dependency
+	if cfg.Catalog.URL != "" {
+		deps = append(deps, dependency{"nats", hostPort(cfg.Catalog.URL)})
+	}
+	if cfg.Email.SMTPAddr != "" {
+		deps = append(deps, dependency{"smtp", cfg.Email.SMTPAddr})
+	}
+	if len(cfg.Auth.Google.ClientIDs) > 0 {
+		deps = append(deps, dependency{"google", hostPort(cfg.Auth.Google.JWKSURL)})
+	}
+	if len(cfg.Auth.Apple.ClientIDs) > 0 {
+		deps = append(deps, dependency{"apple", hostPort(cfg.Auth.Apple.JWKSURL)})
+	}
+	if cfg.Challenge.Provider != "" {
+		verifyURL := cfg.Challenge.VerifyURL
+		if verifyURL == "" {
+			verifyURL = challenge.TurnstileURL
+			if cfg.Challenge.Provider == config.ChallengeRecaptcha {
+				verifyURL = challenge.RecaptchaURL
+			}
+		}
+		deps = append(deps, dependency{"challenge", hostPort(verifyURL)})
+	}
+	return deps
+}
+
+// hostPort returns the host and port rawURL points at
+func hostPort(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return rawURL
+	}
+	port := u.Port()
+	if port == "" {
+		port = defaultPorts[u.Scheme]
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// dial checks that addr accepts TCP connections
+func dial(ctx context.Context, addr string) error {
+	ctx, cancel := context.WithTimeout(ctx, dialTimeout)
+	defer cancel()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// summarize describes cfg, serving tenants restaurants
+func summarize(cfg *config.Config, tenants int) Summary {
+	s := Summary{
+		Port:            cfg.Server.Port,
+		Tenants:         tenants,
+		ReadTimeout:     cfg.Server.ReadTimeout.String(),
+		WriteTimeout:    cfg.Server.WriteTimeout.String(),
+		CatalogTimeout:  cfg.Server.CatalogTimeout.String(),
+		OrderTimeout:    cfg.Server.OrderTimeout.String(),
+		ProductCacheTTL: cfg.Cache.ProductTTL.String(),
+		StrictJSON:      cfg.Server.StrictJSON,
+		Links:           cfg.Server.Links,
+		Envelope:        cfg.Server.Envelope,
+		OrderEvents:     cfg.Orders.EventsDir != "",
+		CatalogSync:     cfg.Catalog.URL != "",
+		Challenge:       cfg.Challenge.Provider,
+		Email:           "log",
+		ImageSigning:    cfg.Images.Signing.Type,
+	}
+	if cfg.Email.SMTPAddr != "" {
+		s.Email = "smtp"
+	}
+	return s
+}
@@ -0,0 +1,50 @@
+package tables
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigner(t *testing.T) {
+	s := NewSigner("0123456789abcdef0123456789abcdef")
+
+	token, err := s.Sign("default", "patio-4")
+	require.NoError(t, err)
+	table, err := s.Verify("default", token)
+	require.NoError(t, err)
+	assert.Equal(t, "patio-4", table)
+
+	// Tokens are only valid at the restaurant they were signed for
+	_, err = s.Verify("other", token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// Tokens cannot be moved to another table
+	_, sig, _ := strings.Cut(token, ".")
+	_, err = s.Verify("default", "12."+sig)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// Nor verified with another secret
+	_, err = NewSigner("fedcba9876543210fedcba9876543210").Verify("default", token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	for _, bad := range []string{"", "patio-4", ".", "patio 4.sig"} {
+		_, err = s.Verify("default", bad)
+		assert.ErrorIs(t, err, ErrInvalidToken, bad)
+	}
+
+	_, err = s.Sign("default", "-patio")
+	assert.ErrorIs(t, err, ErrInvalidTable)
+}
+
+func TestSigner_Disabled(t *testing.T) {
+	s := NewSigner("")
+	assert.Nil(t, s)
+
+	_, err := s.Sign("default", "12")
+	assert.ErrorIs(t, err, ErrDisabled)
+	_, err = s.Verify("default", "12.sig")
+	assert.ErrorIs(t, err, ErrDisabled)
+}
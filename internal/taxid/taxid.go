@@ -0,0 +1,100 @@
+// Package taxid checks business tax IDs against the format each country
+// issues them in, so orders invoiced to a business, and orders exempt from
+// tax, carry an ID that can be verified later.
+package taxid
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrUnsupportedCountry is returned for countries without a known format
+	ErrUnsupportedCountry = errors.New("tax IDs of this country are not supported")
+	// ErrInvalid is returned for IDs not in their country's format
+	ErrInvalid = errors.New("tax ID is not in the country's format")
+)
+
+// rule is the format of a country's tax IDs, after normalizing
+type rule struct {
+	name     string // What the ID is called
+	pattern  *regexp.Regexp
+	prefixed bool   // Whether IDs start with the country code, as EU VAT numbers do
+	example  string // A valid ID, shown when one is rejected
+	checksum func(id string) bool
+}
+
+// rules holds the format of each supported country, by ISO 3166-1 alpha-2
+// code
+var rules = map[string]rule{
+	"AU": {name: "ABN", pattern: regexp.MustCompile(`^\d{11}$`), example: "51824753556", checksum: validABN},
+	"NZ": {name: "GST number", pattern: regexp.MustCompile(`^\d{8,9}$`), example: "123456789"},
+	"US": {name: "EIN", pattern: regexp.MustCompile(`^\d{9}$`), example: "123456789"},
+	"GB": {name: "VAT number", pattern: regexp.MustCompile(`^GB(\d{9}|\d{12}|GD\d{3}|HA\d{3})$`), prefixed: true, example: "GB123456789"},
+	"DE": {name: "VAT number", pattern: regexp.MustCompile(`^DE\d{9}$`), prefixed: true, example: "DE123456789"},
+	"FR": {name: "VAT number", pattern: regexp.MustCompile(`^FR[0-9A-HJ-NP-Z]{2}\d{9}$`), prefixed: true, example: "FR12345678901"},
+	"NL": {name: "VAT number", pattern: regexp.MustCompile(`^NL\d{9}B\d{2}$`), prefixed: true, example: "NL123456789B01"},
+	"IE": {name: "VAT number", pattern: regexp.MustCompile(`^IE(\d{7}[A-W][A-IW]?|\d[A-Z+*]\d{5}[A-W])$`), prefixed: true, example: "IE1234567T"},
+	"ES": {name: "VAT number", pattern: regexp.MustCompile(`^ES[0-9A-Z]\d{7}[0-9A-Z]$`), prefixed: true, example: "ESX1234567X"},
+	"IT": {name: "VAT number", pattern: regexp.MustCompile(`^IT\d{11}$`), prefixed: true, example: "IT12345678901"},
+}
+
+// separators are the characters IDs are commonly written with, which are
+// not part of the ID
+var separators = strings.NewReplacer(" ", "", "-", "", ".", "", "/", "")
+
+// Normalize checks that id is a tax ID in the format of country and returns
+// it in its canonical form: upper case, without spaces or separators, and
+// prefixed with the country code where the country's IDs are.
+func Normalize(country, id string) (string, error) {
+	country = strings.ToUpper(strings.TrimSpace(country))
+	r, ok := rules[country]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedCountry, country)
+	}
+
+	id = separators.Replace(strings.ToUpper(strings.TrimSpace(id)))
+	if r.prefixed && !strings.HasPrefix(id, country) {
+		id = country + id
+	}
+	if !r.pattern.MatchString(id) || (r.checksum != nil && !r.checksum(id)) {
+		return "", fmt.Errorf("%w: expected a %s such as %s", ErrInvalid, r.name, r.example)
+	}
+	return id, nil
+}
+
+// Example returns a valid tax ID of country, or "" for unsupported countries
+func Example(country string) string {
+	return rules[strings.ToUpper(strings.TrimSpace(country))].example
+}
+
+// Countries returns the supported countries, sorted
+func Countries() []string {
+	countries := make([]string, 0, len(rules))
+	for country := range rules {
+		countries = append(countries, country)
+	}
+	sort.Strings(countries)
+	return countries
+}
+
+// abnWeights are the weights of the digits of an ABN in its checksum
+var abnWeights = [11]int{10, 1, 3, 5, 7, 9, 11, 13, 15, 17, 19}
+
+// validABN checks the checksum of an Australian Business Number: with one
+// taken off the first digit, the weighted sum of the digits is a multiple
+// of 89
+func validABN(id string) bool {
+	sum := 0
+	for i, weight := range abnWeights {
+		digit := int(id[i] - '0')
+		if i == 0 {
+			digit--
+		}
+		sum += digit * weight
+	}
+	return sum%89 == 0
+}
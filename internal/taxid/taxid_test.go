@@ -0,0 +1,50 @@
+package taxid
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		country string
+		id      string
+		want    string
+		err     error
+	}{
+		{name: "abn with spaces", country: "AU", id: "51 824 753 556", want: "51824753556"},
+		{name: "abn with bad checksum", country: "AU", id: "51 824 753 557", err: ErrInvalid},
+		{name: "abn too short", country: "AU", id: "5182475355", err: ErrInvalid},
+		{name: "nz gst number", country: "nz", id: "123-456-789", want: "123456789"},
+		{name: "ein", country: "US", id: "12-3456789", want: "123456789"},
+		{name: "uk vat number", country: "GB", id: "GB 123 4567 89", want: "GB123456789"},
+		{name: "vat number without prefix", country: "DE", id: "123456789", want: "DE123456789"},
+		{name: "vat number in lower case", country: "nl", id: "nl123456789b01", want: "NL123456789B01"},
+		{name: "vat number of another country", country: "GB", id: "DE123456789", err: ErrInvalid},
+		{name: "irish vat number", country: "IE", id: "IE1234567T", want: "IE1234567T"},
+		{name: "unsupported country", country: "BR", id: "12345678000195", err: ErrUnsupportedCountry},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.country, tt.id)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestExamplesAreValid(t *testing.T) {
+	for _, country := range Countries() {
+		_, err := Normalize(country, Example(country))
+		assert.NoError(t, err, country)
+	}
+	assert.Empty(t, Example("BR"))
+}
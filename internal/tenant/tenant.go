@@ -0,0 +1,410 @@
+// Package tenant resolves which restaurant a request is for and holds the
+// data and configuration each restaurant is served from.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/carts"
+	"github.com/ravibandhu/oolio-food-ordering/internal/challenge"
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/couriers"
+	"github.com/ravibandhu/oolio-food-ordering/internal/dashboard"
+	"github.com/ravibandhu/oolio-food-ordering/internal/data"
+	"github.com/ravibandhu/oolio-food-ordering/internal/delivery"
+	"github.com/ravibandhu/oolio-food-ordering/internal/hours"
+	"github.com/ravibandhu/oolio-food-ordering/internal/images"
+	"github.com/ravibandhu/oolio-food-ordering/internal/invoices"
+	"github.com/ravibandhu/oolio-food-ordering/internal/kitchen"
+	"github.com/ravibandhu/oolio-food-ordering/internal/maintenance"
+	"github.com/ravibandhu/oolio-food-ordering/internal/menus"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/orders"
+	"github.com/ravibandhu/oolio-food-ordering/internal/payments"
+	"github.com/ravibandhu/oolio-food-ordering/internal/pos"
+	"github.com/ravibandhu/oolio-food-ordering/internal/promotions"
+	"github.com/ravibandhu/oolio-food-ordering/internal/reviews"
+	"github.com/ravibandhu/oolio-food-ordering/internal/tables"
+	"github.com/ravibandhu/oolio-food-ordering/internal/velocity"
+)
+
+// Tenant is a restaurant with its own catalog, coupon set, charges, order
+// limits, velocity rules, hours, delivery zones, table tokens, promotions,
+// menus, kitchen, product reviews, group carts, stock levels, order feed, invoice
+// numbers, takings by payment method, order history, order dashboards,
+// couriers, rounding rule and route groups down for maintenance
+type Tenant struct {
+	ID          string
+	Store       *data.Store
+	Charges     config.Charges
+	Limits      config.Limits
+	Velocity    *velocity.Tracker
+	Hours       *hours.Schedule        // nil when always open
+	Zones       *delivery.Zones        // nil when the restaurant does not deliver
+	Tables      *tables.Signer         // nil when the restaurant takes no table orders
+	Promotions  *promotions.Promotions // nil when prices are never modified
+	Menus       *menus.Menus           // nil when every product is always offered
+	Kitchen     *kitchen.Queue
+	Reviews     *reviews.Store
+	Carts       *carts.Store
+	Inventory   *pos.Inventory
+	Exports     *pos.Exports
+	Invoices    *invoices.Sequence
+	Payments    *payments.Ledger
+	Processor   payments.Processor   // nil when payments are recorded but not captured
+	Orders      orders.Store         // Kept in memory when no events directory is set
+	Dashboard   *dashboard.Projector // Projected from Orders
+	Couriers    *couriers.Tracker    // Records delivery statuses to Orders
+	Locale      string               // Language of the catalog's untranslated fields
+	Rounding    *models.RoundingRule // nil when amounts are not rounded
+	Images      *images.Signer       // nil when image URLs are served unsigned
+	Challenge   challenge.Verifier   // nil when orders are not challenged
+	Maintenance *maintenance.Switch
+}
+
+// Registry holds every configured tenant
+type Registry struct {
+	tenants map[string]*Tenant
+	hosts   map[string]*Tenant
+	def     *Tenant
+}
+
+// NewRegistry creates a new Registry. The default tenant is served from
+// defaultStore; every configured tenant gets an isolated store of its own.
+func NewRegistry(ctx context.Context, cfg *config.Config, defaultStore *data.Store) (*Registry, error) {
+	defHours, err := newSchedule(cfg.Hours)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hours: %w", err)
+	}
+	defZones, err := delivery.New(cfg.Zones)
+	if err != nil {
+		return nil, fmt.Errorf("invalid zones: %w", err)
+	}
+	defPromotions, err := promotions.New(cfg.Hours.Timezone, cfg.Promotions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid promotions: %w", err)
+	}
+	defMenus, err := menus.New(cfg.Hours.Timezone, cfg.Menus)
+	if err != nil {
+		return nil, fmt.Errorf("invalid menus: %w", err)
+	}
+	tableSigner := tables.NewSigner(cfg.Orders.TableSecret)
+	signer, err := images.NewSigner(cfg.Images.Signing)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image signing: %w", err)
+	}
+	verifier, err := challenge.NewVerifier(cfg.Challenge)
+	if err != nil {
+		return nil, fmt.Errorf("invalid challenge: %w", err)
+	}
+	defInvoices, err := openInvoices(cfg.Invoices.Dir, config.DefaultTenantID, cfg.Invoices.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	defOrders, err := openOrders(cfg.Orders.EventsDir, config.DefaultTenantID)
+	if err != nil {
+		return nil, err
+	}
+	def := &Tenant{
+		ID:          config.DefaultTenantID,
+		Store:       defaultStore,
+		Charges:     cfg.Charges,
+		Limits:      cfg.Limits,
+		Velocity:    velocity.NewTracker(cfg.Velocity),
+		Hours:       defHours,
+		Zones:       defZones,
+		Tables:      tableSigner,
+		Promotions:  defPromotions,
+		Menus:       defMenus,
+		Kitchen:     kitchen.NewQueue(cfg.Kitchen),
+		Reviews:     reviews.NewStore(),
+		Carts:       carts.NewStore(),
+		Inventory:   pos.NewInventory(),
+		Exports:     pos.NewExports(),
+		Invoices:    defInvoices,
+		Payments:    payments.NewLedger(),
+		Locale:      cfg.Locale,
+		Rounding:    roundingRule(cfg, cfg.Currency),
+		Images:      signer,
+		Challenge:   verifier,
+		Maintenance: maintenance.New(),
+	}
+
+	def.keepOrders(defOrders)
+
+	r := &Registry{
+		tenants: map[string]*Tenant{def.ID: def},
+		hosts:   make(map[string]*Tenant),
+		def:     def,
+	}
+
+	for _, tc := range cfg.Tenants {
+		schedule, err := newSchedule(tc.Hours)
+		if err != nil {
+			r.closeTenants()
+			return nil, fmt.Errorf("invalid hours for tenant %s: %w", tc.ID, err)
+		}
+		zones, err := delivery.New(tc.Zones)
+		if err != nil {
+			r.closeTenants()
+			return nil, fmt.Errorf("invalid zones for tenant %s: %w", tc.ID, err)
+		}
+		promos, err := promotions.New(tc.Hours.Timezone, tc.Promotions)
+		if err != nil {
+			r.closeTenants()
+			return nil, fmt.Errorf("invalid promotions for tenant %s: %w", tc.ID, err)
+		}
+		tenantMenus, err := menus.New(tc.Hours.Timezone, tc.Menus)
+		if err != nil {
+			r.closeTenants()
+			return nil, fmt.Errorf("invalid menus for tenant %s: %w", tc.ID, err)
+		}
+
+		sequence, err := openInvoices(cfg.Invoices.Dir, tc.ID, tc.InvoicePrefix)
+		if err != nil {
+			r.closeTenants()
+			return nil, err
+		}
+		orderStore, err := openOrders(cfg.Orders.EventsDir, tc.ID)
+		if err != nil {
+			r.closeTenants()
+			return nil, err
+		}
+
+		// Each tenant reloads from its own files
+		tenantCfg := *cfg
+		tenantCfg.Files = tc.Files
+
+		store, err := data.NewIsolatedStore(ctx, &tenantCfg)
+		if err != nil {
+			r.closeTenants()
+			return nil, fmt.Errorf("failed to load tenant %s: %w", tc.ID, err)
+		}
+
+		t := &Tenant{
+			ID:          tc.ID,
+			Store:       store,
+			Charges:     tc.Charges,
+			Limits:      tc.Limits,
+			Velocity:    velocity.NewTracker(tc.Velocity),
+			Hours:       schedule,
+			Zones:       zones,
+			Tables:      tableSigner,
+			Promotions:  promos,
+			Menus:       tenantMenus,
+			Kitchen:     kitchen.NewQueue(tc.Kitchen),
+			Reviews:     reviews.NewStore(),
+			Carts:       carts.NewStore(),
+			Inventory:   pos.NewInventory(),
+			Exports:     pos.NewExports(),
+			Invoices:    sequence,
+			Payments:    payments.NewLedger(),
+			Locale:      tc.Locale,
+			Rounding:    roundingRule(cfg, tc.Currency),
+			Images:      signer,
+			Challenge:   verifier,
+			Maintenance: maintenance.New(),
+		}
+		t.keepOrders(orderStore)
+		r.tenants[t.ID] = t
+		for _, host := range tc.Hosts {
+			r.hosts[host] = t
+		}
+	}
+
+	return r, nil
+}
+
+// RunDashboards keeps the dashboards of every tenant up to date, refreshing
+// them every interval until ctx is done
+func (r *Registry) RunDashboards(ctx context.Context, interval time.Duration) {
+	for _, t := range r.tenants {
+		go t.Dashboard.Run(ctx, interval)
+	}
+}
+
+// ArchiveOrders archives the orders of every tenant created more than
+// retention ago to archiveDir, as <tenant>.jsonl, or discards them when it
+// is empty. It archives them at once and then every interval until ctx is
+// done.
+func (r *Registry) ArchiveOrders(ctx context.Context, retention, interval time.Duration, archiveDir string) {
+	for _, t := range r.tenants {
+		path := ""
+		if archiveDir != "" {
+			path = filepath.Join(archiveDir, t.ID+".jsonl")
+		}
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				t.archiveOrders(time.Now().Add(-retention), path)
+				select {
+				case <-ticker.C:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+}
+
+// archiveOrders archives t's orders created before cutoff to archivePath,
+// logging how many
+func (t *Tenant) archiveOrders(cutoff time.Time, archivePath string) {
+	archived, err := t.Orders.Archive(cutoff, archivePath)
+	if err != nil {
+		log.Printf("Failed to archive the orders of tenant %s: %v", t.ID, err)
+		return
+	}
+	if archived > 0 {
+		log.Printf("Archived %d orders of tenant %s created before %s", archived, t.ID, cutoff.UTC().Format(time.RFC3339))
+	}
+}
+
+// Default returns the tenant served when a request names no other tenant
+func (r *Registry) Default() *Tenant {
+	return r.def
+}
+
+// Get returns the tenant with the given ID
+func (r *Registry) Get(id string) (*Tenant, bool) {
+	t, ok := r.tenants[id]
+	return t, ok
+}
+
+// All returns every tenant, including the default one, sorted by ID
+func (r *Registry) All() []*Tenant {
+	all := make([]*Tenant, 0, len(r.tenants))
+	for _, t := range r.tenants {
+		all = append(all, t)
+	}
+	slices.SortFunc(all, func(a, b *Tenant) int { return strings.Compare(a.ID, b.ID) })
+	return all
+}
+
+// ForHost returns the tenant serving a request Host header, ignoring case
+// and port, or the default tenant when no tenant claims the host
+func (r *Registry) ForHost(host string) *Tenant {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if t, ok := r.hosts[strings.ToLower(host)]; ok {
+		return t
+	}
+	return r.def
+}
+
+// Close closes every tenant store, including the default one
+func (r *Registry) Close() error {
+	err := r.closeTenants()
+	if cerr := r.def.Store.Close(); cerr != nil {
+		err = errors.Join(err, cerr)
+	}
+	return err
+}
+
+// closeTenants closes the stores of every tenant except the default one
+func (r *Registry) closeTenants() error {
+	var errs []error
+	for id, t := range r.tenants {
+		if id == r.def.ID {
+			continue
+		}
+		if err := t.Store.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("tenant %s: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// newSchedule builds the opening hours schedule described by cfg
+func newSchedule(cfg config.Hours) (*hours.Schedule, error) {
+	return hours.New(cfg.Timezone, cfg.Weekly, cfg.OrderAhead)
+}
+
+// roundingRule returns the rule amounts charged in currency are rounded
+// by, or nil when no currency is set
+func roundingRule(cfg *config.Config, currency string) *models.RoundingRule {
+	rule, ok := cfg.RoundingFor(currency)
+	if !ok {
+		return nil
+	}
+	return &models.RoundingRule{Currency: rule.Currency, Mode: rule.Mode, Increment: rule.Increment}
+}
+
+// openInvoices opens the invoice sequence of a tenant, journaled in dir
+func openInvoices(dir, tenantID, prefix string) (*invoices.Sequence, error) {
+	path := ""
+	if dir != "" {
+		path = filepath.Join(dir, tenantID+".jsonl")
+	}
+	sequence, err := invoices.Open(path, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("tenant %s: %w", tenantID, err)
+	}
+	return sequence, nil
+}
+
+// openOrders opens the order event log of a tenant in dir. When dir is
+// empty, the orders are kept in memory and lost on restart.
+func openOrders(dir, tenantID string) (*orders.EventStore, error) {
+	if dir == "" {
+		return orders.Open("")
+	}
+	store, err := orders.Open(filepath.Join(dir, tenantID+".jsonl"))
+	if err != nil {
+		return nil, fmt.Errorf("tenant %s: %w", tenantID, err)
+	}
+	return store, nil
+}
+
+// keepOrders keeps t's orders in store, projecting its dashboards from the
+// events. The orders placed before a restart or crash are replayed into the
+// views placing an order feeds, as the order service feeds them: its takings
+// by payment method, the POS feed, so the POS collects every order that was
+// accepted, and the purchases customers may review. What the kitchen does
+// with each order is added to its history, as are the delivery statuses
+// its courier reports.
+func (t *Tenant) keepOrders(store *orders.EventStore) {
+	t.Orders = store
+	t.Dashboard = dashboard.New(store)
+	t.Couriers = couriers.NewTracker(store, t.Kitchen)
+	t.Kitchen.RecordTo(func(orderID string, change models.StatusChange) error {
+		err := store.ChangeStatus(orderID, change)
+		if err != nil && !errors.Is(err, orders.ErrNotFound) {
+			log.Printf("Failed to record order %s as %s: %v", orderID, change.Status, err)
+		}
+		return err
+	})
+	store.Replay(func(event orders.Event) {
+		if event.Type != orders.EventPlaced {
+			return
+		}
+		t.Exports.Record(event.Order)
+		t.Payments.Record(event.Order)
+		if event.Order.Status != models.OrderStatusOnHold {
+			t.Reviews.RecordPurchase(event.Order)
+		}
+	})
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying t
+func NewContext(ctx context.Context, t *Tenant) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext returns the tenant carried by ctx, if any
+func FromContext(ctx context.Context) (*Tenant, bool) {
+	t, ok := ctx.Value(contextKey{}).(*Tenant)
+	return t, ok
+}
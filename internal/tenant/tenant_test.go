@@ -0,0 +1,191 @@
+package tenant
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/data"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/orders"
+	"github.com/ravibandhu/oolio-food-ordering/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupRegistry creates a registry with the default tenant and a "harbour"
+// tenant served from separate files
+func setupRegistry(t *testing.T) *Registry {
+	defaultData := testutil.SetupTestData(t)
+	t.Cleanup(defaultData.Cleanup)
+	harbourData := testutil.SetupTestData(t)
+	t.Cleanup(harbourData.Cleanup)
+
+	cfg := defaultData.Config
+	cfg.Charges = config.Charges{TaxRate: 0.1}
+	cfg.Tenants = []config.Tenant{{
+		ID:      "harbour",
+		Hosts:   []string{"harbour.example.com"},
+		Files:   harbourData.Config.Files,
+		Charges: config.Charges{ServiceFee: 2},
+		Hours: config.Hours{
+			Timezone: "Australia/Sydney",
+			Weekly:   map[string][]string{"monday": {"09:00-17:00"}},
+		},
+	}}
+
+	store, err := data.NewIsolatedStore(context.Background(), cfg)
+	require.NoError(t, err)
+
+	registry, err := NewRegistry(context.Background(), cfg, store)
+	require.NoError(t, err)
+	t.Cleanup(func() { registry.Close() })
+	return registry
+}
+
+func TestNewRegistry(t *testing.T) {
+	registry := setupRegistry(t)
+
+	def := registry.Default()
+	assert.Equal(t, config.DefaultTenantID, def.ID)
+	assert.Equal(t, 0.1, def.Charges.TaxRate)
+
+	harbour, ok := registry.Get("harbour")
+	require.True(t, ok)
+	assert.Equal(t, 2.0, harbour.Charges.ServiceFee)
+	assert.NotNil(t, harbour.Hours)
+	assert.Nil(t, def.Hours, "default tenant without hours is always open")
+	assert.NotSame(t, def.Store, harbour.Store)
+
+	_, ok = registry.Get("missing")
+	assert.False(t, ok)
+
+	all := registry.All()
+	require.Len(t, all, 2)
+	assert.Same(t, def, all[0])
+	assert.Same(t, harbour, all[1])
+}
+
+func TestNewRegistry_OrderEvents(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	t.Cleanup(testData.Cleanup)
+	cfg := testData.Config
+	cfg.Orders.EventsDir = t.TempDir()
+
+	// Takings are rebuilt from the orders already logged, once each
+	events, err := orders.Open(filepath.Join(cfg.Orders.EventsDir, config.DefaultTenantID+".jsonl"))
+	require.NoError(t, err)
+	require.NoError(t, events.Place(&models.Order{ID: "order-1", TotalAmount: 12.5, Payment: &models.Payment{Method: models.PaymentCard}}))
+	require.NoError(t, events.ChangeStatus("order-1", models.StatusChange{Status: "ready", Actor: models.ActorStaff}))
+
+	store, err := data.NewIsolatedStore(context.Background(), cfg)
+	require.NoError(t, err)
+	registry, err := NewRegistry(context.Background(), cfg, store)
+	require.NoError(t, err)
+	t.Cleanup(func() { registry.Close() })
+
+	def := registry.Default()
+	require.NotNil(t, def.Orders)
+	_, ok := def.Orders.History("order-1")
+	assert.True(t, ok)
+	report := def.Payments.Report(time.Time{}, time.Time{})
+	assert.Equal(t, 1, report.Total.Orders)
+	assert.Equal(t, 12.5, report.Total.Amount)
+
+	// So is the POS feed, so orders accepted before a crash are collected
+	page := def.Exports.After(0, 10)
+	require.Len(t, page.Orders, 1)
+	assert.Equal(t, "order-1", page.Orders[0].OrderID)
+}
+
+func TestRegistry_ArchiveOrders(t *testing.T) {
+	registry := setupRegistry(t)
+	def := registry.Default()
+	now := time.Now()
+	require.NoError(t, def.Orders.Place(&models.Order{ID: "order-1", CreatedAt: now.Add(-48 * time.Hour)}))
+	require.NoError(t, def.Orders.Place(&models.Order{ID: "order-2", CreatedAt: now}))
+
+	// Orders past retention are archived at once, to the tenant's file
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dir := t.TempDir()
+	registry.ArchiveOrders(ctx, 24*time.Hour, time.Hour, dir)
+	assert.Eventually(t, func() bool {
+		_, ok := def.Orders.History("order-1")
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+	_, ok := def.Orders.History("order-2")
+	assert.True(t, ok)
+	assert.FileExists(t, filepath.Join(dir, config.DefaultTenantID+".jsonl"))
+}
+
+func TestNewRegistry_InvalidTenantFiles(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+
+	cfg := testData.Config
+	cfg.Tenants = []config.Tenant{{
+		ID:    "broken",
+		Files: config.Files{ProductsFile: "nonexistent.json", CouponsDir: cfg.Files.CouponsDir},
+	}}
+
+	_, err := NewRegistry(context.Background(), cfg, nil)
+	assert.ErrorContains(t, err, "tenant broken")
+}
+
+func TestNewRegistry_InvalidHours(t *testing.T) {
+	testData := testutil.SetupTestData(t)
+	defer testData.Cleanup()
+
+	cfg := testData.Config
+	cfg.Hours = config.Hours{Weekly: map[string][]string{"funday": {"09:00-17:00"}}}
+
+	_, err := NewRegistry(context.Background(), cfg, nil)
+	assert.ErrorContains(t, err, "invalid hours")
+}
+
+func TestRegistry_Isolation(t *testing.T) {
+	registry := setupRegistry(t)
+	harbour, _ := registry.Get("harbour")
+
+	product := *harbour.Store.GetAllProducts()[0]
+	product.ID = "harbour-special"
+	require.NoError(t, harbour.Store.AddProduct(&product))
+
+	_, err := harbour.Store.GetProduct("harbour-special")
+	assert.NoError(t, err)
+	_, err = registry.Default().Store.GetProduct("harbour-special")
+	assert.ErrorIs(t, err, data.ErrProductNotFound)
+}
+
+func TestRegistry_ForHost(t *testing.T) {
+	registry := setupRegistry(t)
+
+	tests := []struct {
+		host string
+		want string
+	}{
+		{host: "harbour.example.com", want: "harbour"},
+		{host: "Harbour.Example.com:8080", want: "harbour"},
+		{host: "localhost:8080", want: config.DefaultTenantID},
+		{host: "", want: config.DefaultTenantID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			assert.Equal(t, tt.want, registry.ForHost(tt.host).ID)
+		})
+	}
+}
+
+func TestContext(t *testing.T) {
+	_, ok := FromContext(context.Background())
+	assert.False(t, ok)
+
+	want := &Tenant{ID: "harbour"}
+	got, ok := FromContext(NewContext(context.Background(), want))
+	require.True(t, ok)
+	assert.Same(t, want, got)
+}
@@ -0,0 +1,40 @@
+package testutil
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/notify"
+)
+
+// Mailbox is a notify.Sender that keeps the emails sent through it
+type Mailbox struct {
+	mu     sync.Mutex
+	emails []notify.Email
+}
+
+// Send implements notify.Sender
+func (m *Mailbox) Send(_ context.Context, email notify.Email) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.emails = append(m.emails, email)
+	return nil
+}
+
+// Emails returns the emails sent so far
+func (m *Mailbox) Emails() []notify.Email {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]notify.Email(nil), m.emails...)
+}
+
+// Last returns the most recent email, failing the test when none was sent
+func (m *Mailbox) Last(t *testing.T) notify.Email {
+	t.Helper()
+	emails := m.Emails()
+	if len(emails) == 0 {
+		t.Fatal("no email was sent")
+	}
+	return emails[len(emails)-1]
+}
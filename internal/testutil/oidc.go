@@ -0,0 +1,143 @@
+package testutil
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+// IdentityIssuer is the issuer of ID tokens minted by an IdentityProvider
+const IdentityIssuer = "https://issuer.example.com"
+
+// IdentityClientID is a client ID accepted by an IdentityProvider's config
+const IdentityClientID = "web-client"
+
+// IdentityProvider is an OpenID Connect provider that publishes its RSA
+// signing key over HTTP and mints ID tokens with it
+type IdentityProvider struct {
+	server  *httptest.Server
+	fetches atomic.Int32
+
+	mu  sync.Mutex
+	key *rsa.PrivateKey
+	kid string
+	n   int
+}
+
+// NewIdentityProvider starts an identity provider, closed when the test
+// finishes
+func NewIdentityProvider(t *testing.T) *IdentityProvider {
+	t.Helper()
+	p := &IdentityProvider{}
+	p.Rotate(t)
+	p.server = httptest.NewServer(http.HandlerFunc(p.serveKeys))
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+// Config returns provider settings that accept the provider's ID tokens
+func (p *IdentityProvider) Config() config.OIDCProvider {
+	return config.OIDCProvider{
+		ClientIDs: []string{IdentityClientID, "ios-client"},
+		Issuer:    IdentityIssuer,
+		JWKSURL:   p.server.URL,
+	}
+}
+
+// Close stops serving the signing keys
+func (p *IdentityProvider) Close() {
+	p.server.Close()
+}
+
+// Fetches returns how often the signing keys were fetched
+func (p *IdentityProvider) Fetches() int {
+	return int(p.fetches.Load())
+}
+
+// Rotate replaces the signing key with a new one under a new key ID
+func (p *IdentityProvider) Rotate(t *testing.T) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.n++
+	p.key, p.kid = key, fmt.Sprintf("key-%d", p.n)
+}
+
+// Claims returns claims of a valid ID token for subject, with overrides
+// applied
+func (p *IdentityProvider) Claims(subject string, overrides map[string]interface{}) map[string]interface{} {
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss":            IdentityIssuer,
+		"sub":            subject,
+		"aud":            IdentityClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"email":          "Jane@Example.com",
+		"email_verified": true,
+		"name":           "Jane Doe",
+	}
+	for k, v := range overrides {
+		claims[k] = v
+	}
+	return claims
+}
+
+// Mint signs claims as an RS256 ID token
+func (p *IdentityProvider) Mint(t *testing.T, claims map[string]interface{}) string {
+	t.Helper()
+	p.mu.Lock()
+	key, kid := p.key, p.kid
+	p.mu.Unlock()
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": kid})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// serveKeys publishes the current signing key as a JWK set, alongside a key
+// of another type that verifiers must skip
+func (p *IdentityProvider) serveKeys(w http.ResponseWriter, r *http.Request) {
+	p.fetches.Add(1)
+	p.mu.Lock()
+	key, kid := p.key, p.kid
+	p.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{
+			{"kty": "EC", "kid": "ec-key", "crv": "P-256"},
+			{
+				"kty": "RSA",
+				"kid": kid,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			},
+		},
+	})
+}
@@ -11,6 +11,10 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+// ValidCoupon is a coupon code present in every test coupon file, and
+// therefore accepted by stores loaded from SetupTestData
+const ValidCoupon = "HAPPYHRS"
+
 // TestData holds test data and cleanup function
 type TestData struct {
 	TempDir      string
@@ -35,7 +39,7 @@ func SetupTestData(t *testing.T) *TestData {
 	couponFiles := []string{"coupons1.txt", "coupons2.txt", "coupons3.txt"}
 	for _, file := range couponFiles {
 		couponFile := filepath.Join(couponsDir, file)
-		err = os.WriteFile(couponFile, []byte("TEST10\nTEST20\n"), 0644)
+		err = os.WriteFile(couponFile, []byte("TEST10\nTEST20\n"+ValidCoupon+"\n"), 0644)
 		assert.NoError(t, err)
 	}
 
@@ -0,0 +1,249 @@
+// Package testserver boots the real router on temporary test data so
+// end-to-end tests exercise routing, middleware and JSON wiring together.
+package testserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ravibandhu/oolio-food-ordering/internal/apikeys"
+	"github.com/ravibandhu/oolio-food-ordering/internal/auth"
+	"github.com/ravibandhu/oolio-food-ordering/internal/blocklist"
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/ravibandhu/oolio-food-ordering/internal/data"
+	"github.com/ravibandhu/oolio-food-ordering/internal/deprecation"
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+	"github.com/ravibandhu/oolio-food-ordering/internal/router"
+	"github.com/ravibandhu/oolio-food-ordering/internal/startup"
+	"github.com/ravibandhu/oolio-food-ordering/internal/tenant"
+	"github.com/ravibandhu/oolio-food-ordering/internal/testutil"
+	"github.com/ravibandhu/oolio-food-ordering/internal/usage"
+	"github.com/stretchr/testify/require"
+)
+
+// API keys configured on every test server, one for each staff role
+const (
+	APIKey        = "test-api-key" // Holds the admin role
+	KitchenAPIKey = "test-kitchen-key"
+	SupportAPIKey = "test-support-key"
+)
+
+// Server is a running API server backed by temporary test data
+type Server struct {
+	*httptest.Server
+	Config    *config.Config
+	Store     *data.Store
+	Tenants   *tenant.Registry
+	Blocklist *blocklist.List
+	Accounts  *auth.Accounts
+	Keys      *apikeys.Store
+	Usage     *usage.Tracker
+	Mailbox   *testutil.Mailbox // Emails sent to customers
+	Router    *router.Router
+
+	t *testing.T
+}
+
+// Option customizes the configuration before the server boots
+type Option func(*config.Config)
+
+// New boots a server on fresh test data. Everything is torn down when the
+// test finishes.
+func New(t *testing.T, opts ...Option) *Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	testData := testutil.SetupTestData(t)
+	t.Cleanup(testData.Cleanup)
+
+	cfg := testData.Config
+	cfg.Auth.APIKeys = []string{APIKey}
+	cfg.Auth.KitchenKeys = []string{KitchenAPIKey}
+	cfg.Auth.SupportKeys = []string{SupportAPIKey}
+	cfg.Auth.KeysFile = filepath.Join(t.TempDir(), "apikeys.json")
+	cfg.Auth.BlocklistFile = filepath.Join(t.TempDir(), "blocklist.json")
+	cfg.Auth.CustomersFile = filepath.Join(t.TempDir(), "customers.json")
+	cfg.Usage = config.Usage{File: filepath.Join(t.TempDir(), "usage.json"), FlushInterval: time.Minute}
+	cfg.Invoices = config.Invoices{Dir: t.TempDir(), Prefix: config.DefaultInvoicePrefix}
+	cfg.Orders = config.Orders{EventsDir: t.TempDir()}
+	cfg.Auth.SessionTTL = time.Hour
+	cfg.Auth.RefreshTTL = time.Hour
+	cfg.Auth.Passwords = config.Passwords{
+		Hash: config.PasswordArgon2id, MaxFailedLogins: 3,
+		UnlockTTL: time.Hour, VerifyTTL: time.Hour, ResetTTL: time.Hour,
+	}
+	cfg.Images = config.Images{Dir: t.TempDir(), BaseURL: "http://localhost/public/images"}
+	cfg.Server.MaxBodySize = 1 << 20
+	cfg.Server.StrictJSON = true
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	store, err := data.NewStore(ctx, cfg)
+	require.NoError(t, err)
+
+	tenants, err := tenant.NewRegistry(ctx, cfg, store)
+	require.NoError(t, err)
+
+	blocked, err := blocklist.Load(cfg.Auth.BlocklistFile)
+	require.NoError(t, err)
+
+	mailbox := &testutil.Mailbox{}
+	accounts, err := auth.Load(cfg.Auth, mailbox)
+	require.NoError(t, err)
+
+	keys, err := apikeys.Load(cfg.Auth)
+	require.NoError(t, err)
+
+	tracker, err := usage.Load(cfg.Usage.File)
+	require.NoError(t, err)
+
+	notices, err := deprecation.Load(cfg.Server.DeprecationsFile)
+	require.NoError(t, err)
+
+	report := startup.New(cfg, tenants, time.Now())
+	report.PingDependencies(ctx, cfg)
+
+	r := router.NewRouter(ctx, cfg, tenants, blocked, accounts, keys, tracker, notices, report)
+	srv := httptest.NewServer(r.Engine())
+	t.Cleanup(srv.Close)
+
+	return &Server{
+		Server:    srv,
+		Config:    cfg,
+		Store:     store,
+		Tenants:   tenants,
+		Blocklist: blocked,
+		Accounts:  accounts,
+		Keys:      keys,
+		Usage:     tracker,
+		Mailbox:   mailbox,
+		Router:    r,
+		t:         t,
+	}
+}
+
+// Response is a fully read HTTP response
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Decode unmarshals the response body into v, failing the test on error
+func (r *Response) Decode(t *testing.T, v interface{}) {
+	t.Helper()
+	require.NoError(t, json.Unmarshal(r.Body, v), "response body: %s", r.Body)
+}
+
+// Error decodes the response body as an API error
+func (r *Response) Error(t *testing.T) *models.ErrorResponse {
+	t.Helper()
+	var errResp models.ErrorResponse
+	r.Decode(t, &errResp)
+	return &errResp
+}
+
+// RequestOption customizes a request sent by Do
+type RequestOption func(*http.Request)
+
+// WithAPIKey authenticates the request with the server's API key
+func WithAPIKey() RequestOption {
+	return WithHeader("X-API-Key", APIKey)
+}
+
+// WithHeader sets a request header
+func WithHeader(key, value string) RequestOption {
+	return func(req *http.Request) {
+		req.Header.Set(key, value)
+	}
+}
+
+// Do sends a request to the server. A string or []byte body is sent as-is;
+// any other non-nil body is encoded as JSON.
+func (s *Server) Do(method, path string, body interface{}, opts ...RequestOption) *Response {
+	s.t.Helper()
+
+	var reqBody io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		reqBody = bytes.NewReader([]byte(b))
+	case []byte:
+		reqBody = bytes.NewReader(b)
+	default:
+		encoded, err := json.Marshal(b)
+		require.NoError(s.t, err)
+		reqBody = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, s.URL+path, reqBody)
+	require.NoError(s.t, err)
+	if reqBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for _, opt := range opts {
+		opt(req)
+	}
+
+	resp, err := s.Client().Do(req)
+	require.NoError(s.t, err)
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	require.NoError(s.t, err)
+
+	return &Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       content,
+	}
+}
+
+// ListProducts fetches the product catalog
+func (s *Server) ListProducts() ([]models.Product, *Response) {
+	s.t.Helper()
+	resp := s.Do(http.MethodGet, "/products", nil)
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp
+	}
+	var products []models.Product
+	resp.Decode(s.t, &products)
+	return products, resp
+}
+
+// GetProduct fetches a single product. The product is nil unless the
+// request succeeded.
+func (s *Server) GetProduct(id string) (*models.Product, *Response) {
+	s.t.Helper()
+	resp := s.Do(http.MethodGet, "/products/"+id, nil)
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp
+	}
+	var product models.Product
+	resp.Decode(s.t, &product)
+	return &product, resp
+}
+
+// PlaceOrder submits an order. The order is nil unless it was created.
+func (s *Server) PlaceOrder(req *models.OrderRequest, opts ...RequestOption) (*models.Order, *Response) {
+	s.t.Helper()
+	resp := s.Do(http.MethodPost, "/orders", req, opts...)
+	if resp.StatusCode != http.StatusCreated {
+		return nil, resp
+	}
+	var order models.Order
+	resp.Decode(s.t, &order)
+	return &order, resp
+}
@@ -0,0 +1,270 @@
+// Package usage counts the requests each API key makes, how many of them
+// fail, and how many orders they place, by day, so partner integrations can
+// be monitored and billed. Counts are kept in memory and written to a JSON
+// file periodically.
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned for API keys that have made no requests
+var ErrNotFound = errors.New("no usage recorded")
+
+// dateLayout is the layout of the days usage is counted by, in UTC
+const dateLayout = "2006-01-02"
+
+// Counts are the requests made with an API key over some period
+type Counts struct {
+	// Requests made
+	// @example 1200
+	Requests int64 `json:"requests"`
+
+	// Requests refused with a 4xx status
+	// @example 30
+	ClientErrors int64 `json:"clientErrors"`
+
+	// Requests that failed with a 5xx status
+	// @example 2
+	ServerErrors int64 `json:"serverErrors"`
+
+	// Orders placed
+	// @example 250
+	Orders int64 `json:"orders"`
+}
+
+// add adds other's counts to c
+func (c *Counts) add(other Counts) {
+	c.Requests += other.Requests
+	c.ClientErrors += other.ClientErrors
+	c.ServerErrors += other.ServerErrors
+	c.Orders += other.Orders
+}
+
+// ErrorRate returns the share of requests that got a 4xx or 5xx status
+func (c Counts) ErrorRate() float64 {
+	if c.Requests == 0 {
+		return 0
+	}
+	return float64(c.ClientErrors+c.ServerErrors) / float64(c.Requests)
+}
+
+// Day is the usage of an API key on one day, in UTC
+type Day struct {
+	// The day, in UTC
+	// @example 2024-01-01
+	Date string `json:"date"`
+
+	Counts
+}
+
+// Report is the usage of an API key over a range of days
+type Report struct {
+	// ID of the API key
+	// @example 9f86d081884c
+	KeyID string `json:"keyId"`
+
+	// First and last day covered, in UTC; empty when the range is open
+	// @example 2024-01-01
+	From string `json:"from,omitempty"`
+	// @example 2024-01-31
+	To string `json:"to,omitempty"`
+
+	// Usage over the whole range
+	Total Counts `json:"total"`
+
+	// Share of requests in the range that got a 4xx or 5xx status
+	// @example 0.0267
+	ErrorRate float64 `json:"errorRate"`
+
+	// Usage on each day of the range with any requests, oldest first
+	Days []Day `json:"days"`
+}
+
+// file is the persisted form of a Tracker
+type file struct {
+	Keys map[string][]Day `json:"keys"` // By key ID, oldest day first
+}
+
+// Tracker counts the usage of each API key, by key ID and day
+type Tracker struct {
+	mu    sync.Mutex
+	path  string
+	keys  map[string]map[string]*Counts // By key ID, then date
+	dirty bool                          // Whether counts changed since they were last saved
+	now   func() time.Time
+}
+
+// Load reads the usage persisted at path. A missing file holds no usage,
+// and an empty path keeps usage in memory only.
+func Load(path string) (*Tracker, error) {
+	t := &Tracker{path: path, keys: make(map[string]map[string]*Counts), now: time.Now}
+	if path == "" {
+		return t, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage: %w", err)
+	}
+	var f file
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse usage: %w", err)
+	}
+	for keyID, days := range f.Keys {
+		byDate := make(map[string]*Counts, len(days))
+		for _, day := range days {
+			if _, err := time.Parse(dateLayout, day.Date); err != nil {
+				return nil, fmt.Errorf("invalid usage date %q of key %s", day.Date, keyID)
+			}
+			counts := day.Counts
+			byDate[day.Date] = &counts
+		}
+		t.keys[keyID] = byDate
+	}
+	return t, nil
+}
+
+// Record counts a request made with an API key, the status it got, and
+// whether it placed an order
+func (t *Tracker) Record(keyID string, status int, order bool) {
+	date := t.now().UTC().Format(dateLayout)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	byDate, ok := t.keys[keyID]
+	if !ok {
+		byDate = make(map[string]*Counts)
+		t.keys[keyID] = byDate
+	}
+	counts, ok := byDate[date]
+	if !ok {
+		counts = &Counts{}
+		byDate[date] = counts
+	}
+	counts.Requests++
+	switch {
+	case status >= http.StatusInternalServerError:
+		counts.ServerErrors++
+	case status >= http.StatusBadRequest:
+		counts.ClientErrors++
+	}
+	if order {
+		counts.Orders++
+	}
+	t.dirty = true
+}
+
+// Report returns the usage of an API key from one day to another, both
+// included. A zero from or to leaves that end of the range open.
+func (t *Tracker) Report(keyID string, from, to time.Time) (Report, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	byDate, ok := t.keys[keyID]
+	if !ok {
+		return Report{}, fmt.Errorf("%w: %s", ErrNotFound, keyID)
+	}
+
+	report := Report{KeyID: keyID, Days: []Day{}}
+	if !from.IsZero() {
+		report.From = from.UTC().Format(dateLayout)
+	}
+	if !to.IsZero() {
+		report.To = to.UTC().Format(dateLayout)
+	}
+	for date, counts := range byDate {
+		// Dates sort lexically in the same order as chronologically
+		if (report.From != "" && date < report.From) || (report.To != "" && date > report.To) {
+			continue
+		}
+		report.Days = append(report.Days, Day{Date: date, Counts: *counts})
+		report.Total.add(*counts)
+	}
+	sort.Slice(report.Days, func(i, j int) bool { return report.Days[i].Date < report.Days[j].Date })
+	report.ErrorRate = report.Total.ErrorRate()
+	return report, nil
+}
+
+// Flush writes the counts to the file if they changed since the last flush
+func (t *Tracker) Flush() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.path == "" || !t.dirty {
+		return nil
+	}
+	f := file{Keys: make(map[string][]Day, len(t.keys))}
+	for keyID, byDate := range t.keys {
+		days := make([]Day, 0, len(byDate))
+		for date, counts := range byDate {
+			days = append(days, Day{Date: date, Counts: *counts})
+		}
+		sort.Slice(days, func(i, j int) bool { return days[i].Date < days[j].Date })
+		f.Keys[keyID] = days
+	}
+	if err := writeFile(t.path, f); err != nil {
+		return fmt.Errorf("failed to save usage: %w", err)
+	}
+	t.dirty = false
+	return nil
+}
+
+// Run flushes the counts every interval until ctx is done, then flushes
+// them one last time
+func (t *Tracker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := t.Flush(); err != nil {
+				log.Printf("Failed to flush API key usage: %v", err)
+			}
+		case <-ctx.Done():
+			if err := t.Flush(); err != nil {
+				log.Printf("Failed to flush API key usage: %v", err)
+			}
+			return
+		}
+	}
+}
+
+// writeFile writes v to path as indented JSON, through a temporary file
+// renamed into place so usage is never left half-written
+func writeFile(path string, v interface{}) error {
+	raw, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
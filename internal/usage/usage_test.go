@@ -0,0 +1,110 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracker(t *testing.T) {
+	tracker, err := Load("")
+	require.NoError(t, err)
+	now := time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+
+	tracker.Record("key-1", http.StatusCreated, true)
+	tracker.Record("key-1", http.StatusOK, false)
+	tracker.Record("key-1", http.StatusNotFound, false)
+	now = now.Add(2 * time.Hour)
+	tracker.Record("key-1", http.StatusInternalServerError, false)
+	tracker.Record("key-2", http.StatusOK, false)
+
+	report, err := tracker.Report("key-1", time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, Counts{Requests: 4, ClientErrors: 1, ServerErrors: 1, Orders: 1}, report.Total)
+	assert.Equal(t, 0.5, report.ErrorRate)
+	assert.Equal(t, []Day{
+		{Date: "2024-01-01", Counts: Counts{Requests: 3, ClientErrors: 1, Orders: 1}},
+		{Date: "2024-01-02", Counts: Counts{Requests: 1, ServerErrors: 1}},
+	}, report.Days)
+
+	report, err = tracker.Report("key-1", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01-02", report.From)
+	assert.Equal(t, Counts{Requests: 1, ServerErrors: 1}, report.Total)
+
+	report, err = tracker.Report("key-1", time.Time{}, time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Empty(t, report.Days)
+	assert.Zero(t, report.ErrorRate)
+
+	_, err = tracker.Report("key-3", time.Time{}, time.Time{})
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestTracker_Flush(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	tracker, err := Load(path)
+	require.NoError(t, err)
+
+	// Nothing is written until there is usage
+	require.NoError(t, tracker.Flush())
+	_, err = os.Stat(path)
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+
+	tracker.Record("key-1", http.StatusCreated, true)
+	require.NoError(t, tracker.Flush())
+
+	reloaded, err := Load(path)
+	require.NoError(t, err)
+	report, err := reloaded.Report("key-1", time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, Counts{Requests: 1, Orders: 1}, report.Total)
+
+	// Counting resumes from the persisted usage
+	reloaded.Record("key-1", http.StatusOK, false)
+	report, err = reloaded.Report("key-1", time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), report.Total.Requests)
+}
+
+func TestTracker_RunFlushesOnStop(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	tracker, err := Load(path)
+	require.NoError(t, err)
+	tracker.Record("key-1", http.StatusOK, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		tracker.Run(ctx, time.Hour)
+		close(done)
+	}()
+	cancel()
+	<-done
+
+	reloaded, err := Load(path)
+	require.NoError(t, err)
+	_, err = reloaded.Report("key-1", time.Time{}, time.Time{})
+	assert.NoError(t, err)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"malformed.json": `{"keys":`,
+		"bad_date.json":  `{"keys":{"key-1":[{"date":"yesterday","requests":1}]}}`,
+	} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+		_, err := Load(path)
+		assert.Error(t, err, name)
+	}
+}
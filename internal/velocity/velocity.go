@@ -0,0 +1,134 @@
+// Package velocity tracks how often and how much each client orders, so
+// unusually fast ordering can be held for review or refused.
+package velocity
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+)
+
+// Decision is the outcome of checking an order against the velocity rules
+type Decision int
+
+// Decisions, from least to most severe
+const (
+	Allow Decision = iota
+	Hold
+	Reject
+)
+
+// Rules reported with Hold and Reject decisions
+const (
+	RuleOrdersPerHour = "orders_per_hour"
+	RuleTotalPerDay   = "total_per_day"
+)
+
+// window is how long orders count toward the rules
+const window = 24 * time.Hour
+
+// order is an order counted toward a client's velocity
+type order struct {
+	at    time.Time
+	total float64
+}
+
+// Tracker applies velocity rules to the orders of each client
+type Tracker struct {
+	mu        sync.Mutex
+	rules     config.Velocity
+	now       func() time.Time
+	orders    map[string][]order
+	lastSweep time.Time
+}
+
+// NewTracker creates a new Tracker instance
+func NewTracker(rules config.Velocity) *Tracker {
+	return &Tracker{
+		rules:  rules,
+		now:    time.Now,
+		orders: make(map[string][]order),
+	}
+}
+
+// Check decides whether client may place an order of total, and names the
+// rule behind a Hold or Reject. Allowed and held orders count toward later
+// checks; refused ones do not. A nil Tracker or an unknown client allows
+// every order.
+func (t *Tracker) Check(client string, total float64) (Decision, string) {
+	if t == nil || client == "" {
+		return Allow, ""
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.prune(client, now)
+	if now.Sub(t.lastSweep) >= time.Hour {
+		for other := range t.orders {
+			t.prune(other, now)
+		}
+		t.lastSweep = now
+	}
+
+	// Count the orders of the last hour and the spend of the last day,
+	// including this order
+	ordersPerHour, totalPerDay := 1, total
+	for _, o := range t.orders[client] {
+		if now.Sub(o.at) < time.Hour {
+			ordersPerHour++
+		}
+		totalPerDay += o.total
+	}
+
+	decision, rule := Allow, ""
+	check := func(value, hold, max float64, name string) {
+		switch {
+		case max > 0 && value > max:
+			decision, rule = Reject, name
+		case hold > 0 && value > hold && decision < Hold:
+			decision, rule = Hold, name
+		}
+	}
+	check(float64(ordersPerHour), float64(t.rules.HoldOrdersPerHour), float64(t.rules.MaxOrdersPerHour), RuleOrdersPerHour)
+	if decision != Reject {
+		check(totalPerDay, t.rules.HoldTotalPerDay, t.rules.MaxTotalPerDay, RuleTotalPerDay)
+	}
+
+	if decision != Reject {
+		t.orders[client] = append(t.orders[client], order{at: now, total: total})
+	}
+	return decision, rule
+}
+
+// prune drops the orders of client that no longer count toward any rule,
+// forgetting the client once none are left
+func (t *Tracker) prune(client string, now time.Time) {
+	kept := t.orders[client][:0]
+	for _, o := range t.orders[client] {
+		if now.Sub(o.at) < window {
+			kept = append(kept, o)
+		}
+	}
+	if len(kept) == 0 {
+		delete(t.orders, client)
+		return
+	}
+	t.orders[client] = kept
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx identifying the client placing orders
+func NewContext(ctx context.Context, client string) context.Context {
+	return context.WithValue(ctx, contextKey{}, client)
+}
+
+// FromContext returns the client identified by ctx, if any
+func FromContext(ctx context.Context) string {
+	client, _ := ctx.Value(contextKey{}).(string)
+	return client
+}
@@ -0,0 +1,105 @@
+package velocity
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTracker(rules config.Velocity) (*Tracker, *time.Time) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	t := NewTracker(rules)
+	t.now = func() time.Time { return now }
+	return t, &now
+}
+
+func TestTracker_OrdersPerHour(t *testing.T) {
+	tracker, now := newTestTracker(config.Velocity{HoldOrdersPerHour: 2, MaxOrdersPerHour: 3})
+
+	check := func() Decision {
+		decision, _ := tracker.Check("10.0.0.1", 10)
+		return decision
+	}
+	assert.Equal(t, Allow, check())
+	assert.Equal(t, Allow, check())
+	decision, rule := tracker.Check("10.0.0.1", 10)
+	assert.Equal(t, Hold, decision)
+	assert.Equal(t, RuleOrdersPerHour, rule)
+	decision, rule = tracker.Check("10.0.0.1", 10)
+	assert.Equal(t, Reject, decision)
+	assert.Equal(t, RuleOrdersPerHour, rule)
+
+	// Refused orders do not count, and clients are tracked separately
+	assert.Equal(t, Reject, check())
+	decision, _ = tracker.Check("10.0.0.2", 10)
+	assert.Equal(t, Allow, decision)
+
+	// Orders older than an hour no longer count
+	*now = now.Add(time.Hour)
+	assert.Equal(t, Allow, check())
+}
+
+func TestTracker_TotalPerDay(t *testing.T) {
+	tracker, now := newTestTracker(config.Velocity{HoldTotalPerDay: 100, MaxTotalPerDay: 150})
+
+	decision, _ := tracker.Check("10.0.0.1", 60)
+	assert.Equal(t, Allow, decision)
+	decision, rule := tracker.Check("10.0.0.1", 60)
+	assert.Equal(t, Hold, decision)
+	assert.Equal(t, RuleTotalPerDay, rule)
+	decision, rule = tracker.Check("10.0.0.1", 60)
+	assert.Equal(t, Reject, decision)
+	assert.Equal(t, RuleTotalPerDay, rule)
+
+	// Spend counts for a day
+	*now = now.Add(23 * time.Hour)
+	decision, _ = tracker.Check("10.0.0.1", 40)
+	assert.Equal(t, Reject, decision)
+	*now = now.Add(time.Hour)
+	decision, _ = tracker.Check("10.0.0.1", 40)
+	assert.Equal(t, Allow, decision)
+}
+
+func TestTracker_RefusalOutranksHold(t *testing.T) {
+	tracker, _ := newTestTracker(config.Velocity{HoldOrdersPerHour: 1, MaxTotalPerDay: 50})
+
+	tracker.Check("10.0.0.1", 10)
+	decision, rule := tracker.Check("10.0.0.1", 60)
+	assert.Equal(t, Reject, decision)
+	assert.Equal(t, RuleTotalPerDay, rule)
+}
+
+func TestTracker_Disabled(t *testing.T) {
+	tracker, _ := newTestTracker(config.Velocity{})
+	for i := 0; i < 100; i++ {
+		decision, _ := tracker.Check("10.0.0.1", 1000)
+		assert.Equal(t, Allow, decision)
+	}
+
+	// Without a tracker or a known client every order is allowed
+	var none *Tracker
+	decision, _ := none.Check("10.0.0.1", 10)
+	assert.Equal(t, Allow, decision)
+	strict, _ := newTestTracker(config.Velocity{MaxOrdersPerHour: 1})
+	strict.Check("", 10)
+	decision, _ = strict.Check("", 10)
+	assert.Equal(t, Allow, decision)
+}
+
+func TestTracker_ForgetsIdleClients(t *testing.T) {
+	tracker, now := newTestTracker(config.Velocity{MaxOrdersPerHour: 5})
+	tracker.Check("10.0.0.1", 10)
+	tracker.Check("10.0.0.2", 10)
+
+	*now = now.Add(window)
+	tracker.Check("10.0.0.3", 10)
+	assert.Len(t, tracker.orders, 1)
+}
+
+func TestContext(t *testing.T) {
+	assert.Empty(t, FromContext(context.Background()))
+	assert.Equal(t, "10.0.0.1", FromContext(NewContext(context.Background(), "10.0.0.1")))
+}
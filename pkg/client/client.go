@@ -0,0 +1,361 @@
+// Package client provides a typed Go client for the Oolio Food Ordering API
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/models"
+)
+
+// API types shared with the server
+type (
+	Product      = models.Product
+	ProductImage = models.ProductImage
+	Order        = models.Order
+	OrderItem    = models.OrderItem
+	OrderRequest = models.OrderRequest
+	Address      = models.Address
+)
+
+// Default client settings
+const (
+	DefaultTimeout             = 10 * time.Second
+	DefaultMaxRetries          = 2
+	DefaultBackoff             = 200 * time.Millisecond
+	DefaultMaxIdleConnsPerHost = 16
+)
+
+// IdempotencyKeyHeader is the header carrying a client-chosen idempotency key
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// APIError is returned when the API responds with a non-2xx status
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	Details    map[string]interface{}
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("api error: HTTP %d", e.StatusCode)
+	}
+	return fmt.Sprintf("api error: HTTP %d: %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Client is a typed client for the Oolio Food Ordering API. It is safe for
+// concurrent use.
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+	maxRetries int
+	backoff    time.Duration
+	hedgeDelay time.Duration // 0 disables hedging
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithAPIKey sets the key sent in the X-API-Key header
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithHTTPClient replaces the underlying HTTP client
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTimeout sets the overall timeout of a single HTTP attempt
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.httpClient.Timeout = timeout
+	}
+}
+
+// WithConnectionPool replaces the transport of the underlying HTTP client with
+// one keeping up to maxIdlePerHost idle connections per host and opening at
+// most maxPerHost connections per host (0 for no limit)
+func WithConnectionPool(maxIdlePerHost, maxPerHost int) Option {
+	return func(c *Client) {
+		c.httpClient.Transport = newTransport(maxIdlePerHost, maxPerHost)
+	}
+}
+
+// WithHedging sends a second copy of a GET request when the first has not
+// completed after delay, and uses whichever response succeeds first. This
+// trims tail latency of lookups at the cost of extra load. A delay around
+// the API's 95th percentile latency is a good starting point.
+func WithHedging(delay time.Duration) Option {
+	return func(c *Client) {
+		c.hedgeDelay = delay
+	}
+}
+
+// WithRetries sets how many times a failed request is retried and the base
+// backoff between attempts. Backoff doubles on every attempt and is jittered.
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.backoff = backoff
+	}
+}
+
+// New creates a new Client for the API at baseURL
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{
+			Timeout:   DefaultTimeout,
+			Transport: newTransport(DefaultMaxIdleConnsPerHost, 0),
+		},
+		maxRetries: DefaultMaxRetries,
+		backoff:    DefaultBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// RequestOption configures a single API call
+type RequestOption func(*requestOptions)
+
+type requestOptions struct {
+	idempotencyKey string
+}
+
+// WithIdempotencyKey attaches an idempotency key to a mutating request. Mutating
+// requests are only retried when they carry a key: the API answers a retry
+// of a request that succeeded with the original response, so an order
+// whose response was lost is not placed twice.
+func WithIdempotencyKey(key string) RequestOption {
+	return func(o *requestOptions) {
+		o.idempotencyKey = key
+	}
+}
+
+// ListProducts returns all available products
+func (c *Client) ListProducts(ctx context.Context) ([]Product, error) {
+	var products []Product
+	if err := c.do(ctx, http.MethodGet, "/products", nil, &products, requestOptions{}); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// GetProduct returns a single product by ID
+func (c *Client) GetProduct(ctx context.Context, id string) (*Product, error) {
+	var product Product
+	if err := c.do(ctx, http.MethodGet, "/products/"+url.PathEscape(id), nil, &product, requestOptions{}); err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// PlaceOrder places a new order
+func (c *Client) PlaceOrder(ctx context.Context, req *OrderRequest, opts ...RequestOption) (*Order, error) {
+	var options requestOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	var order Order
+	if err := c.do(ctx, http.MethodPost, "/orders", req, &order, options); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// GetOrder returns a previously placed order by ID, as its status changes
+// leave it. Orders are served to staff, so the client's API key needs the
+// support or admin role.
+func (c *Client) GetOrder(ctx context.Context, id string) (*Order, error) {
+	var history struct {
+		Order *Order `json:"order"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/admin/orders/"+url.PathEscape(id), nil, &history, requestOptions{}); err != nil {
+		return nil, err
+	}
+	if history.Order == nil {
+		return nil, fmt.Errorf("error decoding response: no order")
+	}
+	return history.Order, nil
+}
+
+// do performs a request with retries and decodes the JSON response into out
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}, options requestOptions) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("error encoding request: %w", err)
+		}
+	}
+
+	retryable := method == http.MethodGet || options.idempotencyKey != ""
+
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		var content []byte
+		if method == http.MethodGet && c.hedgeDelay > 0 {
+			content, lastErr = c.hedged(ctx, method, path, body, options)
+		} else {
+			content, lastErr = c.attempt(ctx, method, path, body, options)
+		}
+		if lastErr == nil {
+			if out != nil {
+				if err := json.Unmarshal(content, out); err != nil {
+					return fmt.Errorf("error decoding response: %w", err)
+				}
+			}
+			return nil
+		}
+		if !retryable || attempt >= c.maxRetries || !shouldRetry(lastErr) || ctx.Err() != nil {
+			return lastErr
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w (last error: %v)", ctx.Err(), lastErr)
+		case <-time.After(c.backoffFor(attempt)):
+		}
+	}
+}
+
+// hedged performs an attempt, starting a second one if the first has not
+// completed after the hedge delay. The first success wins and the other
+// attempt is cancelled; if both fail, the first error is returned.
+func (c *Client) hedged(ctx context.Context, method, path string, body []byte, options requestOptions) ([]byte, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		content []byte
+		err     error
+	}
+	results := make(chan result, 2)
+	launch := func() {
+		go func() {
+			content, err := c.attempt(ctx, method, path, body, options)
+			results <- result{content: content, err: err}
+		}()
+	}
+
+	launch()
+	timer := time.NewTimer(c.hedgeDelay)
+	defer timer.Stop()
+	select {
+	case r := <-results:
+		return r.content, r.err
+	case <-timer.C:
+		launch()
+	}
+
+	first := <-results
+	if first.err == nil {
+		return first.content, nil
+	}
+	if second := <-results; second.err == nil {
+		return second.content, nil
+	}
+	return nil, first.err
+}
+
+// attempt performs a single HTTP round trip and returns the body of a
+// successful response
+func (c *Client) attempt(ctx context.Context, method, path string, body []byte, options requestOptions) ([]byte, error) {
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	if options.idempotencyKey != "" {
+		req.Header.Set(IdempotencyKeyHeader, options.idempotencyKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		var errResp models.ErrorResponse
+		if json.Unmarshal(content, &errResp) == nil {
+			apiErr.Code = errResp.Code
+			apiErr.Message = errResp.Message
+			apiErr.Details = errResp.Details
+		}
+		return nil, apiErr
+	}
+	return content, nil
+}
+
+// backoffFor returns the jittered delay before the retry following attempt
+func (c *Client) backoffFor(attempt int) time.Duration {
+	delay := c.backoff << attempt
+	if delay <= 0 {
+		return 0
+	}
+	// Equal jitter: a random delay in [delay/2, delay]
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// newTransport returns a copy of the default transport with the given
+// per-host connection limits
+func newTransport(maxIdlePerHost, maxPerHost int) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = maxIdlePerHost
+	transport.MaxConnsPerHost = maxPerHost
+	return transport
+}
+
+// shouldRetry reports whether an error is transient
+func shouldRetry(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+
+	// Transport errors (connection refused, reset, client timeout)
+	return true
+}
@@ -0,0 +1,254 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ravibandhu/oolio-food-ordering/internal/testutil/testserver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_ListAndGetProducts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/products":
+			json.NewEncoder(w).Encode([]Product{{ID: "1", Name: "Waffle", Price: 6.5}})
+		case "/products/1":
+			json.NewEncoder(w).Encode(Product{ID: "1", Name: "Waffle", Price: 6.5})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code":"NOT_FOUND","message":"Product not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	c := New(server.URL)
+	ctx := context.Background()
+
+	products, err := c.ListProducts(ctx)
+	require.NoError(t, err)
+	assert.Len(t, products, 1)
+
+	product, err := c.GetProduct(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "Waffle", product.Name)
+
+	_, err = c.GetProduct(ctx, "missing")
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, "NOT_FOUND", apiErr.Code)
+}
+
+func TestClient_RetriesTransientErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode([]Product{})
+	}))
+	defer server.Close()
+
+	c := New(server.URL, WithRetries(2, time.Millisecond))
+	_, err := c.ListProducts(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestClient_DoesNotRetryClientErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	c := New(server.URL, WithRetries(3, time.Millisecond))
+	_, err := c.ListProducts(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestClient_PlaceOrderIdempotency(t *testing.T) {
+	var calls int32
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(IdempotencyKeyHeader))
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		assert.Equal(t, "secret", r.Header.Get("X-API-Key"))
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(Order{ID: "order-1"})
+	}))
+	defer server.Close()
+
+	c := New(server.URL, WithAPIKey("secret"), WithRetries(2, time.Millisecond))
+	req := &OrderRequest{Items: []OrderItem{{ProductID: "1", Quantity: 1}}}
+
+	t.Run("retried with key", func(t *testing.T) {
+		order, err := c.PlaceOrder(context.Background(), req, WithIdempotencyKey("key-1"))
+		require.NoError(t, err)
+		assert.Equal(t, "order-1", order.ID)
+		assert.Equal(t, []string{"key-1", "key-1"}, keys)
+	})
+
+	t.Run("not retried without key", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		keys = nil
+		_, err := c.PlaceOrder(context.Background(), req)
+		assert.Error(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+}
+
+// dropFirstResponse sends every request on, but loses the response to the
+// first, as a dropped connection would
+type dropFirstResponse struct {
+	dropped atomic.Bool
+}
+
+func (d *dropFirstResponse) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err == nil && d.dropped.CompareAndSwap(false, true) {
+		resp.Body.Close()
+		return nil, errors.New("connection reset by peer")
+	}
+	return resp, err
+}
+
+func TestClient_PlaceOrderRetriedAgainstServer(t *testing.T) {
+	srv := testserver.New(t)
+	c := New(srv.URL,
+		WithAPIKey(testserver.APIKey),
+		WithRetries(2, time.Millisecond),
+		WithHTTPClient(&http.Client{Transport: &dropFirstResponse{}}))
+
+	order, err := c.PlaceOrder(context.Background(), &OrderRequest{
+		Items: []OrderItem{{ProductID: "prod-1", Quantity: 1}},
+	}, WithIdempotencyKey("key-1"))
+	require.NoError(t, err)
+
+	// The retry got the order the first attempt placed
+	resp := srv.Do(http.MethodGet, "/admin/orders", nil, testserver.WithAPIKey())
+	require.Equal(t, http.StatusOK, resp.StatusCode, "body: %s", resp.Body)
+	var listed struct {
+		Orders []Order `json:"orders"`
+	}
+	resp.Decode(t, &listed)
+	require.Len(t, listed.Orders, 1)
+	assert.Equal(t, order.ID, listed.Orders[0].ID)
+}
+
+func TestClient_GetOrder(t *testing.T) {
+	srv := testserver.New(t)
+	c := New(srv.URL, WithAPIKey(testserver.APIKey))
+
+	placed, err := c.PlaceOrder(context.Background(), &OrderRequest{
+		Items: []OrderItem{{ProductID: "prod-1", Quantity: 2}},
+	})
+	require.NoError(t, err)
+
+	order, err := c.GetOrder(context.Background(), placed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, placed.ID, order.ID)
+	assert.Equal(t, placed.TotalAmount, order.TotalAmount)
+	require.Len(t, order.Items, 1)
+	assert.Equal(t, "prod-1", order.Items[0].ProductID)
+
+	_, err = c.GetOrder(context.Background(), "missing")
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, "NOT_FOUND", apiErr.Code)
+
+	// Orders are not served without an API key
+	_, err = New(srv.URL).GetOrder(context.Background(), placed.ID)
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+}
+
+func TestClient_ContextCancellation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	c := New(server.URL, WithRetries(100, 20*time.Millisecond))
+	_, err := c.ListProducts(ctx)
+	assert.Error(t, err)
+}
+
+func TestClient_Hedging(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first request stalls; the hedged copy answers at once
+		if atomic.AddInt32(&calls, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(2 * time.Second):
+			}
+			return
+		}
+		json.NewEncoder(w).Encode(Product{ID: "1", Name: "Waffle"})
+	}))
+	defer server.Close()
+
+	c := New(server.URL, WithHedging(20*time.Millisecond))
+	start := time.Now()
+	product, err := c.GetProduct(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, "Waffle", product.Name)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	t.Run("fast responses are not hedged", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 1)
+		_, err := c.GetProduct(context.Background(), "1")
+		require.NoError(t, err)
+		time.Sleep(40 * time.Millisecond)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("orders are not hedged", func(t *testing.T) {
+		var posts int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&posts, 1)
+			time.Sleep(60 * time.Millisecond)
+			json.NewEncoder(w).Encode(Order{ID: "order-1"})
+		}))
+		defer server.Close()
+
+		c := New(server.URL, WithHedging(10*time.Millisecond))
+		_, err := c.PlaceOrder(context.Background(), &OrderRequest{}, WithIdempotencyKey("key-1"))
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&posts))
+	})
+}
+
+func TestClient_ConnectionPool(t *testing.T) {
+	c := New("http://localhost:8080")
+	transport, ok := c.httpClient.Transport.(*http.Transport)
+	require.True(t, ok)
+	assert.Equal(t, DefaultMaxIdleConnsPerHost, transport.MaxIdleConnsPerHost)
+	assert.Zero(t, transport.MaxConnsPerHost)
+
+	c = New("http://localhost:8080", WithConnectionPool(4, 8))
+	transport = c.httpClient.Transport.(*http.Transport)
+	assert.Equal(t, 4, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, 8, transport.MaxConnsPerHost)
+}